@@ -2,7 +2,15 @@ package archive
 
 import (
 	"archive/tar"
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 // TarFileInBuffer will create a tar archive containing a single file named via fileName and using the content
@@ -34,3 +42,143 @@ func TarFileInBuffer(fileContent []byte, fileName string, mode int64) ([]byte, e
 
 	return buffer.Bytes(), nil
 }
+
+// ExtractLimits are the size limits of the content extracted from an archive, a zero value disables a limit
+type ExtractLimits struct {
+	// Maximum size of a file of the archive, in bytes
+	MaxFileSize int64
+	// Maximum size of all the files of the archive, in bytes
+	MaxTotalSize int64
+}
+
+// ExtractTarArchive will extract a tar archive, optionally gzip compressed, into the dest destination folder on disk.
+// Only directories and regular files are extracted, entries that would be written outside of dest are rejected.
+// The extraction fails as soon as a file, or all the files, exceed the size limits.
+func ExtractTarArchive(r io.Reader, dest string, limits ExtractLimits) error {
+	bufferedReader := bufio.NewReader(r)
+
+	var reader io.Reader = bufferedReader
+	magic, err := bufferedReader.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(bufferedReader)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	var totalSize int64
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := ResolveArchivePath(dest, header.Name)
+		if err != nil {
+			return fmt.Errorf("Invalid archive entry %s: outside of the destination folder", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg, tar.TypeRegA:
+			if limits.MaxFileSize > 0 && header.Size > limits.MaxFileSize {
+				return fmt.Errorf("Invalid archive entry %s: file exceeds the maximum size of %d bytes", header.Name, limits.MaxFileSize)
+			}
+
+			totalSize += header.Size
+			if limits.MaxTotalSize > 0 && totalSize > limits.MaxTotalSize {
+				return fmt.Errorf("Invalid archive: content exceeds the maximum size of %d bytes", limits.MaxTotalSize)
+			}
+
+			err = extractFileFromTarArchive(tarReader, target, header.FileInfo().Mode())
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractFileFromTarArchive(reader io.Reader, target string, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	outFile, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(outFile, reader)
+	if err != nil {
+		outFile.Close()
+		return err
+	}
+
+	return outFile.Close()
+}
+
+// TarDirectory will write a tar archive containing the directories and regular files of the directory to the specified writer.
+// Files are streamed one at a time from disk so that the archive is never fully loaded in memory.
+func TarDirectory(w io.Writer, directory string) error {
+	tarWriter := tar.NewWriter(w)
+
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == directory {
+			return err
+		}
+
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relativePath)
+
+		err = tarWriter.WriteHeader(header)
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tarWriter.Close()
+}
+
+// ResolveArchivePath returns the path of an entry relative to the root folder of an extracted archive,
+// an error is returned when the entry is outside of the root folder
+func ResolveArchivePath(root, relativePath string) (string, error) {
+	target := filepath.Join(root, filepath.FromSlash(relativePath))
+	if target != filepath.Clean(root) && !strings.HasPrefix(target, filepath.Clean(root)+string(os.PathSeparator)) {
+		return "", errors.New("Invalid path: outside of the archive")
+	}
+	return target, nil
+}
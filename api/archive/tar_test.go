@@ -0,0 +1,33 @@
+package archive
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExtractTarArchive_shouldEnforceTheSizeLimits(t *testing.T) {
+	content, err := TarFileInBuffer(bytes.Repeat([]byte("a"), 1024), "Dockerfile", 0644)
+	assert.NoError(t, err)
+
+	dest, err := ioutil.TempDir("", "portainer-archive-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	err = ExtractTarArchive(bytes.NewReader(content), dest, ExtractLimits{MaxFileSize: 512})
+	assert.Error(t, err, "files larger than the maximum file size are rejected")
+
+	err = ExtractTarArchive(bytes.NewReader(content), dest, ExtractLimits{MaxTotalSize: 512})
+	assert.Error(t, err, "archives larger than the maximum total size are rejected")
+
+	err = ExtractTarArchive(bytes.NewReader(content), dest, ExtractLimits{MaxFileSize: 1024, MaxTotalSize: 1024})
+	assert.NoError(t, err)
+
+	extracted, err := ioutil.ReadFile(filepath.Join(dest, "Dockerfile"))
+	assert.NoError(t, err)
+	assert.Len(t, extracted, 1024)
+}
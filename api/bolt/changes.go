@@ -0,0 +1,43 @@
+package bolt
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+)
+
+// ApplyChanges applies the changes in a single transaction, none of the changes is applied when one of them fails
+func (store *Store) ApplyChanges(changes []portainer.DataStoreChange) error {
+	return internal.Update(store.db, func(tx *bolt.Tx) error {
+		for _, change := range changes {
+			bucket := tx.Bucket([]byte(change.Bucket))
+			if bucket == nil {
+				return fmt.Errorf("Unknown bucket %s", change.Bucket)
+			}
+
+			key := internal.Itob(change.ID)
+
+			if change.Object == nil {
+				err := bucket.Delete(key)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			data, err := internal.MarshalObject(change.Object)
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put(key, data)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,94 @@
+package containerjob
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "container_jobs"
+)
+
+// Service represents a service for managing container job data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// ContainerJobs returns a list of container jobs
+func (service *Service) ContainerJobs() ([]portainer.ContainerJob, error) {
+	var jobs = make([]portainer.ContainerJob, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var job portainer.ContainerJob
+			err := internal.UnmarshalObject(v, &job)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+		}
+
+		return nil
+	})
+
+	return jobs, err
+}
+
+// ContainerJob returns a container job by ID
+func (service *Service) ContainerJob(ID portainer.ContainerJobID) (*portainer.ContainerJob, error) {
+	var job portainer.ContainerJob
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.db, BucketName, identifier, &job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// CreateContainerJob assigns an ID to a new container job and saves it
+func (service *Service) CreateContainerJob(job *portainer.ContainerJob) error {
+	return internal.Update(service.db, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		job.ID = portainer.ContainerJobID(id)
+
+		data, err := internal.MarshalObject(job)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(job.ID)), data)
+	})
+}
+
+// UpdateContainerJob updates a container job by ID
+func (service *Service) UpdateContainerJob(ID portainer.ContainerJobID, job *portainer.ContainerJob) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.db, BucketName, identifier, job)
+}
+
+// DeleteContainerJob deletes a container job by ID
+func (service *Service) DeleteContainerJob(ID portainer.ContainerJobID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.db, BucketName, identifier)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/boltdb/bolt"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/containerjob"
 	"github.com/portainer/portainer/api/bolt/customtemplate"
 	"github.com/portainer/portainer/api/bolt/dockerhub"
 	"github.com/portainer/portainer/api/bolt/edgegroup"
@@ -22,14 +23,17 @@ import (
 	"github.com/portainer/portainer/api/bolt/resourcecontrol"
 	"github.com/portainer/portainer/api/bolt/role"
 	"github.com/portainer/portainer/api/bolt/schedule"
+	"github.com/portainer/portainer/api/bolt/secret"
 	"github.com/portainer/portainer/api/bolt/settings"
 	"github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/stackversion"
 	"github.com/portainer/portainer/api/bolt/tag"
 	"github.com/portainer/portainer/api/bolt/team"
 	"github.com/portainer/portainer/api/bolt/teammembership"
 	"github.com/portainer/portainer/api/bolt/tunnelserver"
 	"github.com/portainer/portainer/api/bolt/user"
 	"github.com/portainer/portainer/api/bolt/version"
+	"github.com/portainer/portainer/api/bolt/volumebackup"
 	"github.com/portainer/portainer/api/bolt/webhook"
 	"github.com/portainer/portainer/api/internal/authorization"
 )
@@ -45,6 +49,7 @@ type Store struct {
 	db                      *bolt.DB
 	isNew                   bool
 	fileService             portainer.FileService
+	ContainerJobService     *containerjob.Service
 	CustomTemplateService   *customtemplate.Service
 	DockerHubService        *dockerhub.Service
 	EdgeGroupService        *edgegroup.Service
@@ -58,14 +63,17 @@ type Store struct {
 	ResourceControlService  *resourcecontrol.Service
 	RoleService             *role.Service
 	ScheduleService         *schedule.Service
+	SecretService           *secret.Service
 	SettingsService         *settings.Service
 	StackService            *stack.Service
+	StackVersionService     *stackversion.Service
 	TagService              *tag.Service
 	TeamMembershipService   *teammembership.Service
 	TeamService             *team.Service
 	TunnelServerService     *tunnelserver.Service
 	UserService             *user.Service
 	VersionService          *version.Service
+	VolumeBackupService     *volumebackup.Service
 	WebhookService          *webhook.Service
 }
 
@@ -187,6 +195,12 @@ func (store *Store) initServices() error {
 	}
 	store.RoleService = authorizationsetService
 
+	containerJobService, err := containerjob.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.ContainerJobService = containerJobService
+
 	customTemplateService, err := customtemplate.NewService(store.db)
 	if err != nil {
 		return err
@@ -253,6 +267,12 @@ func (store *Store) initServices() error {
 	}
 	store.ResourceControlService = resourcecontrolService
 
+	secretService, err := secret.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.SecretService = secretService
+
 	settingsService, err := settings.NewService(store.db)
 	if err != nil {
 		return err
@@ -265,6 +285,12 @@ func (store *Store) initServices() error {
 	}
 	store.StackService = stackService
 
+	stackVersionService, err := stackversion.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.StackVersionService = stackVersionService
+
 	tagService, err := tag.NewService(store.db)
 	if err != nil {
 		return err
@@ -301,6 +327,12 @@ func (store *Store) initServices() error {
 	}
 	store.VersionService = versionService
 
+	volumeBackupService, err := volumebackup.NewService(store.db)
+	if err != nil {
+		return err
+	}
+	store.VolumeBackupService = volumeBackupService
+
 	webhookService, err := webhook.NewService(store.db)
 	if err != nil {
 		return err
@@ -316,6 +348,11 @@ func (store *Store) initServices() error {
 	return nil
 }
 
+// ContainerJob gives access to the ContainerJob data management layer
+func (store *Store) ContainerJob() portainer.ContainerJobService {
+	return store.ContainerJobService
+}
+
 // CustomTemplate gives access to the CustomTemplate data management layer
 func (store *Store) CustomTemplate() portainer.CustomTemplateService {
 	return store.CustomTemplateService
@@ -371,6 +408,11 @@ func (store *Store) Role() portainer.RoleService {
 	return store.RoleService
 }
 
+// Secret gives access to the Secret data management layer
+func (store *Store) Secret() portainer.SecretService {
+	return store.SecretService
+}
+
 // Settings gives access to the Settings data management layer
 func (store *Store) Settings() portainer.SettingsService {
 	return store.SettingsService
@@ -381,6 +423,11 @@ func (store *Store) Stack() portainer.StackService {
 	return store.StackService
 }
 
+// StackVersion gives access to the StackVersion data management layer
+func (store *Store) StackVersion() portainer.StackVersionService {
+	return store.StackVersionService
+}
+
 // Tag gives access to the Tag data management layer
 func (store *Store) Tag() portainer.TagService {
 	return store.TagService
@@ -411,6 +458,11 @@ func (store *Store) Version() portainer.VersionService {
 	return store.VersionService
 }
 
+// VolumeBackupSchedule gives access to the VolumeBackupSchedule data management layer
+func (store *Store) VolumeBackupSchedule() portainer.VolumeBackupScheduleService {
+	return store.VolumeBackupService
+}
+
 // Webhook gives access to the Webhook data management layer
 func (store *Store) Webhook() portainer.WebhookService {
 	return store.WebhookService
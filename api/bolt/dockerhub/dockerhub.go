@@ -11,6 +11,7 @@ const (
 	// BucketName represents the name of the bucket where this service stores data.
 	BucketName   = "dockerhub"
 	dockerHubKey = "DOCKERHUB"
+	rateLimitKey = "RATE_LIMIT_SAMPLES"
 )
 
 // Service represents a service for managing Dockerhub data.
@@ -46,3 +47,20 @@ func (service *Service) DockerHub() (*portainer.DockerHub, error) {
 func (service *Service) UpdateDockerHub(dockerhub *portainer.DockerHub) error {
 	return internal.UpdateObject(service.db, BucketName, []byte(dockerHubKey), dockerhub)
 }
+
+// RateLimitSamples returns the recorded samples of the DockerHub pull rate limit.
+func (service *Service) RateLimitSamples() ([]portainer.DockerHubRateLimitSample, error) {
+	var samples []portainer.DockerHubRateLimitSample
+
+	err := internal.GetObject(service.db, BucketName, []byte(rateLimitKey), &samples)
+	if err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+// UpdateRateLimitSamples replaces the recorded samples of the DockerHub pull rate limit.
+func (service *Service) UpdateRateLimitSamples(samples []portainer.DockerHubRateLimitSample) error {
+	return internal.UpdateObject(service.db, BucketName, []byte(rateLimitKey), samples)
+}
@@ -0,0 +1,39 @@
+package bolt
+
+import (
+	"time"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+)
+
+const healthBucketName = "health"
+
+// Health returns the write health of the database. The database is degraded when
+// a write failed because the disk hosting it is full or read-only.
+func (store *Store) Health() portainer.DataStoreHealth {
+	degraded, reason, since := internal.Health()
+	if !degraded {
+		return portainer.DataStoreHealth{}
+	}
+
+	return portainer.DataStoreHealth{
+		Degraded: true,
+		Reason:   reason,
+		Since:    since.Unix(),
+	}
+}
+
+// ProbeWrite writes a timestamp in a dedicated bucket of the database. A successful
+// probe ends the degraded mode.
+func (store *Store) ProbeWrite() error {
+	return internal.Update(store.db, func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(healthBucketName))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte("probe"), []byte(time.Now().Format(time.RFC3339)))
+	})
+}
@@ -26,26 +26,7 @@ func (store *Store) Init() error {
 
 	_, err = store.SettingsService.Settings()
 	if err == errors.ErrObjectNotFound {
-		defaultSettings := &portainer.Settings{
-			AuthenticationMethod: portainer.AuthenticationInternal,
-			BlackListedLabels:    make([]portainer.Pair, 0),
-			LDAPSettings: portainer.LDAPSettings{
-				AnonymousMode:   true,
-				AutoCreateUsers: true,
-				TLSConfig:       portainer.TLSConfiguration{},
-				SearchSettings: []portainer.LDAPSearchSettings{
-					portainer.LDAPSearchSettings{},
-				},
-				GroupSearchSettings: []portainer.LDAPGroupSearchSettings{
-					portainer.LDAPGroupSearchSettings{},
-				},
-			},
-			OAuthSettings: portainer.OAuthSettings{},
-
-			EdgeAgentCheckinInterval: portainer.DefaultEdgeAgentCheckinIntervalInSeconds,
-			TemplatesURL:             portainer.DefaultTemplatesURL,
-			UserSessionTimeout:       portainer.DefaultUserSessionTimeout,
-		}
+		defaultSettings := portainer.SettingsDefault()
 
 		err = store.SettingsService.UpdateSettings(defaultSettings)
 		if err != nil {
@@ -18,7 +18,7 @@ func Itob(v int) []byte {
 
 // CreateBucket is a generic function used to create a bucket inside a bolt database.
 func CreateBucket(db *bolt.DB, bucketName string) error {
-	return db.Update(func(tx *bolt.Tx) error {
+	return Update(db, func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists([]byte(bucketName))
 		if err != nil {
 			return err
@@ -53,7 +53,7 @@ func GetObject(db *bolt.DB, bucketName string, key []byte, object interface{}) e
 
 // UpdateObject is a generic function used to update an object inside a bolt database.
 func UpdateObject(db *bolt.DB, bucketName string, key []byte, object interface{}) error {
-	return db.Update(func(tx *bolt.Tx) error {
+	return Update(db, func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 
 		data, err := MarshalObject(object)
@@ -72,7 +72,7 @@ func UpdateObject(db *bolt.DB, bucketName string, key []byte, object interface{}
 
 // DeleteObject is a generic function used to delete an object inside a bolt database.
 func DeleteObject(db *bolt.DB, bucketName string, key []byte) error {
-	return db.Update(func(tx *bolt.Tx) error {
+	return Update(db, func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		return bucket.Delete(key)
 	})
@@ -82,7 +82,7 @@ func DeleteObject(db *bolt.DB, bucketName string, key []byte) error {
 func GetNextIdentifier(db *bolt.DB, bucketName string) int {
 	var identifier int
 
-	db.Update(func(tx *bolt.Tx) error {
+	Update(db, func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		id, err := bucket.NextSequence()
 		if err != nil {
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// writeHealth tracks the storage failures of the write transactions. The database is degraded
+// after a write failed because the disk is full or read-only and it recovers on the next successful write.
+type writeHealth struct {
+	mu       sync.RWMutex
+	degraded bool
+	reason   string
+	since    time.Time
+}
+
+var health writeHealth
+
+// Update is a generic function used to run a read-write transaction inside a bolt database.
+// It must be used for all the writes so that the degraded mode reflects the storage failures.
+func Update(db *bolt.DB, fn func(tx *bolt.Tx) error) error {
+	err := db.Update(fn)
+	recordWrite(err)
+	return err
+}
+
+// Health returns whether the database is degraded, the cause of the degradation and when it started
+func Health() (bool, string, time.Time) {
+	health.mu.RLock()
+	defer health.mu.RUnlock()
+
+	return health.degraded, health.reason, health.since
+}
+
+// IsStorageFailure returns true when the error is caused by a full or read-only storage
+func IsStorageFailure(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) ||
+		errors.Is(err, syscall.EDQUOT) ||
+		errors.Is(err, syscall.EROFS) ||
+		err == bolt.ErrDatabaseReadOnly
+}
+
+func recordWrite(err error) {
+	if err != nil && !IsStorageFailure(err) {
+		return
+	}
+
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	if err == nil {
+		if health.degraded {
+			log.Printf("[INFO] [bolt] [message: database writes succeed again, leaving degraded mode] [degraded_since: %s]", health.since.Format(time.RFC3339))
+			health.degraded = false
+			health.reason = ""
+			health.since = time.Time{}
+		}
+		return
+	}
+
+	if !health.degraded {
+		log.Printf("[ERROR] [bolt] [message: unable to write to the database, entering degraded mode: reads are served, writes are rejected] [error: %s]", err)
+		health.degraded = true
+		health.since = time.Now()
+	}
+	health.reason = err.Error()
+}
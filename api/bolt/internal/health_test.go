@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsStorageFailure(t *testing.T) {
+	assert.True(t, IsStorageFailure(syscall.ENOSPC))
+	assert.True(t, IsStorageFailure(&os.PathError{Op: "write", Path: "portainer.db", Err: syscall.EROFS}))
+	assert.True(t, IsStorageFailure(fmt.Errorf("commit: %w", syscall.ENOSPC)))
+	assert.True(t, IsStorageFailure(bolt.ErrDatabaseReadOnly))
+	assert.False(t, IsStorageFailure(bolt.ErrBucketNotFound))
+}
+
+func Test_recordWrite(t *testing.T) {
+	recordWrite(bolt.ErrBucketNotFound)
+	degraded, _, _ := Health()
+	assert.False(t, degraded)
+
+	recordWrite(syscall.ENOSPC)
+	degraded, reason, since := Health()
+	assert.True(t, degraded)
+	assert.Equal(t, syscall.ENOSPC.Error(), reason)
+	assert.False(t, since.IsZero())
+
+	recordWrite(bolt.ErrBucketNotFound)
+	degraded, _, _ = Health()
+	assert.True(t, degraded)
+
+	recordWrite(nil)
+	degraded, _, _ = Health()
+	assert.False(t, degraded)
+}
@@ -0,0 +1,94 @@
+package secret
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "secrets"
+)
+
+// Service represents a service for managing secret data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// Secrets returns a list of secrets
+func (service *Service) Secrets() ([]portainer.Secret, error) {
+	var secrets = make([]portainer.Secret, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var secret portainer.Secret
+			err := internal.UnmarshalObject(v, &secret)
+			if err != nil {
+				return err
+			}
+			secrets = append(secrets, secret)
+		}
+
+		return nil
+	})
+
+	return secrets, err
+}
+
+// Secret returns a secret by ID
+func (service *Service) Secret(ID portainer.SecretID) (*portainer.Secret, error) {
+	var secret portainer.Secret
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.db, BucketName, identifier, &secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// CreateSecret assigns an ID to a new secret and saves it
+func (service *Service) CreateSecret(secret *portainer.Secret) error {
+	return internal.Update(service.db, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		secret.ID = portainer.SecretID(id)
+
+		data, err := internal.MarshalObject(secret)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(secret.ID)), data)
+	})
+}
+
+// UpdateSecret updates a secret by ID
+func (service *Service) UpdateSecret(ID portainer.SecretID, secret *portainer.Secret) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.db, BucketName, identifier, secret)
+}
+
+// DeleteSecret deletes a secret by ID
+func (service *Service) DeleteSecret(ID portainer.SecretID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.db, BucketName, identifier)
+}
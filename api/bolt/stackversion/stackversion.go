@@ -0,0 +1,133 @@
+package stackversion
+
+import (
+	"sort"
+
+	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "stack_versions"
+)
+
+// Service represents a service for managing stack version data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// StackVersions returns the versions of a stack, ordered by version number.
+func (service *Service) StackVersions(stackID portainer.StackID) ([]portainer.StackVersion, error) {
+	var versions = make([]portainer.StackVersion, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		var err error
+		versions, err = stackVersions(tx.Bucket([]byte(BucketName)), stackID)
+		return err
+	})
+
+	return versions, err
+}
+
+// StackVersion returns a specific version of a stack.
+func (service *Service) StackVersion(stackID portainer.StackID, version int) (*portainer.StackVersion, error) {
+	versions, err := service.StackVersions(stackID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stackVersion := range versions {
+		if stackVersion.Version == version {
+			return &stackVersion, nil
+		}
+	}
+
+	return nil, errors.ErrObjectNotFound
+}
+
+// CreateStackVersion assigns an ID and the next version number of the stack to a new stack version and saves it.
+func (service *Service) CreateStackVersion(stackVersion *portainer.StackVersion) error {
+	return internal.Update(service.db, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		versions, err := stackVersions(bucket, stackVersion.StackID)
+		if err != nil {
+			return err
+		}
+
+		stackVersion.Version = 1
+		if len(versions) > 0 {
+			stackVersion.Version = versions[len(versions)-1].Version + 1
+		}
+
+		id, _ := bucket.NextSequence()
+		stackVersion.ID = portainer.StackVersionID(id)
+
+		data, err := internal.MarshalObject(stackVersion)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(stackVersion.ID)), data)
+	})
+}
+
+// DeleteStackVersions deletes all the versions of a stack.
+func (service *Service) DeleteStackVersions(stackID portainer.StackID) error {
+	return internal.Update(service.db, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		versions, err := stackVersions(bucket, stackID)
+		if err != nil {
+			return err
+		}
+
+		for _, stackVersion := range versions {
+			err := bucket.Delete(internal.Itob(int(stackVersion.ID)))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func stackVersions(bucket *bolt.Bucket, stackID portainer.StackID) ([]portainer.StackVersion, error) {
+	var versions = make([]portainer.StackVersion, 0)
+
+	cursor := bucket.Cursor()
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		var stackVersion portainer.StackVersion
+		err := internal.UnmarshalObject(v, &stackVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		if stackVersion.StackID == stackID {
+			versions = append(versions, stackVersion)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	return versions, nil
+}
@@ -0,0 +1,94 @@
+package volumebackup
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/internal"
+)
+
+const (
+	// BucketName represents the name of the bucket where this service stores data.
+	BucketName = "volume_backup_schedules"
+)
+
+// Service represents a service for managing volume backup schedule data.
+type Service struct {
+	db *bolt.DB
+}
+
+// NewService creates a new instance of a service.
+func NewService(db *bolt.DB) (*Service, error) {
+	err := internal.CreateBucket(db, BucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db: db,
+	}, nil
+}
+
+// VolumeBackupSchedules returns a list of volume backup schedules
+func (service *Service) VolumeBackupSchedules() ([]portainer.VolumeBackupSchedule, error) {
+	var schedules = make([]portainer.VolumeBackupSchedule, 0)
+
+	err := service.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var schedule portainer.VolumeBackupSchedule
+			err := internal.UnmarshalObject(v, &schedule)
+			if err != nil {
+				return err
+			}
+			schedules = append(schedules, schedule)
+		}
+
+		return nil
+	})
+
+	return schedules, err
+}
+
+// VolumeBackupSchedule returns a volume backup schedule by ID
+func (service *Service) VolumeBackupSchedule(ID portainer.VolumeBackupScheduleID) (*portainer.VolumeBackupSchedule, error) {
+	var schedule portainer.VolumeBackupSchedule
+	identifier := internal.Itob(int(ID))
+
+	err := internal.GetObject(service.db, BucketName, identifier, &schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// CreateVolumeBackupSchedule assigns an ID to a new volume backup schedule and saves it
+func (service *Service) CreateVolumeBackupSchedule(schedule *portainer.VolumeBackupSchedule) error {
+	return internal.Update(service.db, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		id, _ := bucket.NextSequence()
+		schedule.ID = portainer.VolumeBackupScheduleID(id)
+
+		data, err := internal.MarshalObject(schedule)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(internal.Itob(int(schedule.ID)), data)
+	})
+}
+
+// UpdateVolumeBackupSchedule updates a volume backup schedule by ID
+func (service *Service) UpdateVolumeBackupSchedule(ID portainer.VolumeBackupScheduleID, schedule *portainer.VolumeBackupSchedule) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.db, BucketName, identifier, schedule)
+}
+
+// DeleteVolumeBackupSchedule deletes a volume backup schedule by ID
+func (service *Service) DeleteVolumeBackupSchedule(ID portainer.VolumeBackupScheduleID) error {
+	identifier := internal.Itob(int(ID))
+	return internal.DeleteObject(service.db, BucketName, identifier)
+}
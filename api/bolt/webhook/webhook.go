@@ -128,6 +128,43 @@ func (service *Service) WebhookByToken(token string) (*portainer.Webhook, error)
 	return webhook, err
 }
 
+// UpdateWebhook updates a webhook.
+func (service *Service) UpdateWebhook(ID portainer.WebhookID, webhook *portainer.Webhook) error {
+	identifier := internal.Itob(int(ID))
+	return internal.UpdateObject(service.db, BucketName, identifier, webhook)
+}
+
+// RecordWebhookInvocation increments the invocation count of a webhook and sets its last invocation date
+// in a single transaction, so that concurrent invocations are all counted.
+func (service *Service) RecordWebhookInvocation(ID portainer.WebhookID, invocationDate int64) error {
+	identifier := internal.Itob(int(ID))
+
+	return internal.Update(service.db, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BucketName))
+
+		value := bucket.Get(identifier)
+		if value == nil {
+			return errors.ErrObjectNotFound
+		}
+
+		var webhook portainer.Webhook
+		err := internal.UnmarshalObject(value, &webhook)
+		if err != nil {
+			return err
+		}
+
+		webhook.InvocationCount++
+		webhook.LastInvocationDate = invocationDate
+
+		data, err := internal.MarshalObject(webhook)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(identifier, data)
+	})
+}
+
 // DeleteWebhook deletes a webhook.
 func (service *Service) DeleteWebhook(ID portainer.WebhookID) error {
 	identifier := internal.Itob(int(ID))
@@ -136,7 +173,7 @@ func (service *Service) DeleteWebhook(ID portainer.WebhookID) error {
 
 // CreateWebhook assign an ID to a new webhook and saves it.
 func (service *Service) CreateWebhook(webhook *portainer.Webhook) error {
-	return service.db.Update(func(tx *bolt.Tx) error {
+	return internal.Update(service.db, func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(BucketName))
 
 		id, _ := bucket.NextSequence()
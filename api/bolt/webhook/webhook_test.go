@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService(t *testing.T) (*Service, func()) {
+	dir, err := ioutil.TempDir("", "portainer-webhook")
+	assert.NoError(t, err)
+
+	db, err := bolt.Open(path.Join(dir, "portainer.db"), 0600, nil)
+	assert.NoError(t, err)
+
+	service, err := NewService(db)
+	assert.NoError(t, err)
+
+	return service, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_RecordWebhookInvocation_shouldCountTheConcurrentInvocations(t *testing.T) {
+	service, teardown := newTestService(t)
+	defer teardown()
+
+	webhook := &portainer.Webhook{Token: "abc", InvocationCount: 2}
+	assert.NoError(t, service.CreateWebhook(webhook))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(date int64) {
+			defer wg.Done()
+			assert.NoError(t, service.RecordWebhookInvocation(webhook.ID, date))
+		}(int64(i))
+	}
+	wg.Wait()
+
+	recorded, err := service.Webhook(webhook.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 22, recorded.InvocationCount)
+	assert.Equal(t, "abc", recorded.Token)
+}
+
+func Test_RecordWebhookInvocation_shouldFailForAnUnknownWebhook(t *testing.T) {
+	service, teardown := newTestService(t)
+	defer teardown()
+
+	assert.Equal(t, errors.ErrObjectNotFound, service.RecordWebhookInvocation(1, 1587399600))
+}
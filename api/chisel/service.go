@@ -3,7 +3,9 @@ package chisel
 import (
 	"fmt"
 	"log"
+	"math/rand"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/dchest/uniuri"
@@ -11,12 +13,14 @@ import (
 	cmap "github.com/orcaman/concurrent-map"
 	"github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/scheduler"
 )
 
 const (
-	tunnelCleanupInterval = 10 * time.Second
-	requiredTimeout       = 15 * time.Second
-	activeTimeout         = 4*time.Minute + 30*time.Second
+	tunnelCleanupInterval   = 10 * time.Second
+	tunnelVerificationJobID = "edge_tunnel_verification"
+	requiredTimeout         = 15 * time.Second
+	activeTimeout           = 4*time.Minute + 30*time.Second
 )
 
 // Service represents a service to manage the state of multiple reverse tunnels.
@@ -26,16 +30,32 @@ type Service struct {
 	serverFingerprint string
 	serverPort        string
 	tunnelDetailsMap  cmap.ConcurrentMap
+	reconnectsMap     cmap.ConcurrentMap
 	dataStore         portainer.DataStore
 	snapshotService   portainer.SnapshotService
 	chiselServer      *chserver.Server
+	scheduler         *scheduler.Scheduler
+	reconnectJitter   time.Duration
+	startedAt         time.Time
+	mu                sync.RWMutex
+	drainDeadline     time.Time
 }
 
-// NewService returns a pointer to a new instance of Service
-func NewService(dataStore portainer.DataStore) *Service {
+// recoveryWindow is the duration after Portainer starts during which the Edge agents are asked to check in
+// more frequently, so that the tunnels dropped by the restart are opened again quickly
+const recoveryWindow = 2 * time.Minute
+
+// NewService returns a pointer to a new instance of Service.
+// The reconnect jitter is the maximum delay suggested to the Edge agents to check in again while the tunnels
+// are drained and during the recovery window after Portainer starts, it spreads their check-ins.
+func NewService(dataStore portainer.DataStore, scheduler *scheduler.Scheduler, reconnectJitter time.Duration) *Service {
 	return &Service{
 		tunnelDetailsMap: cmap.New(),
+		reconnectsMap:    cmap.New(),
 		dataStore:        dataStore,
+		scheduler:        scheduler,
+		reconnectJitter:  reconnectJitter,
+		startedAt:        time.Now(),
 	}
 }
 
@@ -78,9 +98,85 @@ func (service *Service) StartTunnelServer(addr, port string, snapshotService por
 	}
 
 	service.snapshotService = snapshotService
-	go service.startTunnelVerificationLoop()
 
-	return nil
+	log.Printf("[DEBUG] [chisel, monitoring] [check_interval_seconds: %f] [message: starting tunnel management process]", tunnelCleanupInterval.Seconds())
+	return service.scheduler.Register(scheduler.Job{
+		ID:          tunnelVerificationJobID,
+		Description: "Verify the status of Edge agent tunnels",
+		Interval:    tunnelCleanupInterval,
+		Run:         service.checkTunnels,
+	})
+}
+
+// Drain asks the Edge agents checking in to reconnect later during the timeout, so that they reconnect soon after
+// Portainer restarts instead of waiting for their tunnel to time out. The tunnels are closed and the tunnel server
+// is stopped once the timeout is elapsed.
+func (service *Service) Drain(timeout time.Duration) {
+	service.mu.Lock()
+	service.drainDeadline = time.Now().Add(timeout)
+	service.mu.Unlock()
+
+	log.Printf("[INFO] [chisel,shutdown] [timeout_seconds: %f] [message: draining Edge tunnels]", timeout.Seconds())
+	time.Sleep(timeout)
+
+	for item := range service.tunnelDetailsMap.IterBuffered() {
+		endpointID, err := strconv.Atoi(item.Key)
+		if err != nil {
+			continue
+		}
+		service.SetTunnelStatusToIdle(portainer.EndpointID(endpointID))
+	}
+
+	if service.chiselServer != nil {
+		err := service.chiselServer.Close()
+		if err != nil {
+			log.Printf("[ERROR] [chisel,shutdown] [message: unable to stop the tunnel server] [error: %s]", err)
+		}
+	}
+}
+
+// IsDraining returns whether the tunnels are being drained before Portainer stops
+func (service *Service) IsDraining() bool {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+	return !service.drainDeadline.IsZero()
+}
+
+// ReconnectDelay returns the delay after which the Edge agents checking in while the tunnels are drained should check in again.
+// The agents do not wait for the end of the drain, as Portainer is not available anymore at that time, they check in again
+// after a random delay of at most the reconnect jitter, so that they reach the restarted instance as soon as it is available
+// without all the agents checking in at once. The delay is at least one second.
+func (service *Service) ReconnectDelay() time.Duration {
+	return service.jitteredDelay()
+}
+
+// CheckinInterval returns the check-in interval in seconds suggested to the Edge agents. During the recovery window after
+// Portainer starts, the agents are asked to check in again after a random delay of at most the reconnect jitter, so that
+// the tunnels dropped by the restart are opened again quickly without all the agents checking in at once.
+// The interval is never longer than the check-in interval of the endpoint.
+func (service *Service) CheckinInterval(interval int) int {
+	if time.Since(service.startedAt) >= recoveryWindow {
+		return interval
+	}
+
+	recoveryInterval := int(service.jitteredDelay().Seconds())
+	if interval > 0 && recoveryInterval > interval {
+		return interval
+	}
+	return recoveryInterval
+}
+
+func (service *Service) jitteredDelay() time.Duration {
+	delay := time.Duration(0)
+	if service.reconnectJitter > 0 {
+		delay = time.Duration(rand.Int63n(int64(service.reconnectJitter)))
+	}
+
+	if delay < time.Second {
+		delay = time.Second
+	}
+
+	return delay.Round(time.Second)
 }
 
 func (service *Service) retrievePrivateKeySeed() (string, error) {
@@ -105,23 +201,7 @@ func (service *Service) retrievePrivateKeySeed() (string, error) {
 	return serverInfo.PrivateKeySeed, nil
 }
 
-func (service *Service) startTunnelVerificationLoop() {
-	log.Printf("[DEBUG] [chisel, monitoring] [check_interval_seconds: %f] [message: starting tunnel management process]", tunnelCleanupInterval.Seconds())
-	ticker := time.NewTicker(tunnelCleanupInterval)
-	stopSignal := make(chan struct{})
-
-	for {
-		select {
-		case <-ticker.C:
-			service.checkTunnels()
-		case <-stopSignal:
-			ticker.Stop()
-			return
-		}
-	}
-}
-
-func (service *Service) checkTunnels() {
+func (service *Service) checkTunnels() error {
 	for item := range service.tunnelDetailsMap.IterBuffered() {
 		tunnel := item.Val.(*portainer.TunnelDetails)
 
@@ -167,6 +247,8 @@ func (service *Service) checkTunnels() {
 		}
 
 	}
+
+	return nil
 }
 
 func (service *Service) snapshotEnvironment(endpointID portainer.EndpointID, tunnelPort int) error {
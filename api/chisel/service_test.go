@@ -0,0 +1,67 @@
+package chisel
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReconnectDelay_shouldNotWaitForTheEndOfTheDrain(t *testing.T) {
+	service := NewService(nil, nil, 5*time.Second)
+	service.drainDeadline = time.Now().Add(time.Minute)
+
+	for i := 0; i < 100; i++ {
+		delay := service.ReconnectDelay()
+		assert.True(t, delay >= time.Second && delay <= 5*time.Second, delay)
+	}
+}
+
+func Test_ReconnectDelay_shouldBeAtLeastOneSecond(t *testing.T) {
+	service := NewService(nil, nil, 0)
+
+	assert.Equal(t, time.Second, service.ReconnectDelay())
+}
+
+func Test_CheckinInterval(t *testing.T) {
+	service := NewService(nil, nil, 5*time.Second)
+
+	for i := 0; i < 100; i++ {
+		interval := service.CheckinInterval(60)
+		assert.True(t, interval >= 1 && interval <= 5, interval)
+	}
+	assert.True(t, service.CheckinInterval(2) <= 2, "the recovery interval should not exceed the interval of the endpoint")
+
+	service.startedAt = time.Now().Add(-recoveryWindow)
+	assert.Equal(t, 60, service.CheckinInterval(60), "the interval of the endpoint should be used after the recovery window")
+}
+
+func Test_SetTunnelStatusToActive_shouldRecordTheReconnection(t *testing.T) {
+	service := NewService(nil, nil, 0)
+	endpointID := portainer.EndpointID(1)
+
+	service.SetTunnelStatusToActive(endpointID)
+	assert.True(t, service.GetTunnelDetails(endpointID).LastReconnect.IsZero())
+
+	service.GetTunnelDetails(endpointID).Status = portainer.EdgeAgentManagementRequired
+	service.SetTunnelStatusToActive(endpointID)
+
+	service.SetTunnelStatusToIdle(endpointID)
+	tunnel := service.GetTunnelDetails(endpointID)
+	assert.Equal(t, portainer.EdgeAgentIdle, tunnel.Status)
+	assert.False(t, tunnel.LastReconnect.IsZero(), "the reconnection should be kept once the tunnel is idle")
+}
+
+func Test_Drain_shouldCloseTheTunnels(t *testing.T) {
+	service := NewService(nil, nil, 0)
+	endpointID := portainer.EndpointID(1)
+	service.SetTunnelStatusToActive(endpointID)
+
+	assert.False(t, service.IsDraining())
+
+	service.Drain(0)
+
+	assert.True(t, service.IsDraining())
+	assert.Equal(t, portainer.EdgeAgentIdle, service.GetTunnelDetails(endpointID).Status)
+}
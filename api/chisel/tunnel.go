@@ -48,23 +48,36 @@ func (service *Service) GetTunnelDetails(endpointID portainer.EndpointID) *porta
 	}
 
 	jobs := make([]portainer.EdgeJob, 0)
-	return &portainer.TunnelDetails{
+	tunnelDetails := &portainer.TunnelDetails{
 		Status:      portainer.EdgeAgentIdle,
 		Port:        0,
 		Jobs:        jobs,
 		Credentials: "",
 	}
+
+	if item, ok := service.reconnectsMap.Get(key); ok {
+		tunnelDetails.LastReconnect = item.(time.Time)
+	}
+
+	return tunnelDetails
 }
 
 // SetTunnelStatusToActive update the status of the tunnel associated to the specified endpoint.
 // It sets the status to ACTIVE.
+// It records the reconnection of the agent when the tunnel was required.
 func (service *Service) SetTunnelStatusToActive(endpointID portainer.EndpointID) {
 	tunnel := service.GetTunnelDetails(endpointID)
+	key := strconv.Itoa(int(endpointID))
+
+	if tunnel.Status == portainer.EdgeAgentManagementRequired {
+		tunnel.LastReconnect = time.Now()
+		service.reconnectsMap.Set(key, tunnel.LastReconnect)
+	}
+
 	tunnel.Status = portainer.EdgeAgentActive
 	tunnel.Credentials = ""
 	tunnel.LastActivity = time.Now()
 
-	key := strconv.Itoa(int(endpointID))
 	service.tunnelDetailsMap.Set(key, tunnel)
 }
 
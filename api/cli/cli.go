@@ -21,7 +21,12 @@ var (
 	errInvalidEndpointProtocol       = errors.New("Invalid endpoint protocol: Portainer only supports unix://, npipe:// or tcp://")
 	errSocketOrNamedPipeNotFound     = errors.New("Unable to locate Unix socket or named pipe")
 	errInvalidSnapshotInterval       = errors.New("Invalid snapshot interval")
+	errInvalidDegradedProbeInterval  = errors.New("Invalid degraded mode probe interval")
 	errAdminPassExcludeAdminPassFile = errors.New("Cannot use --admin-password with --admin-password-file")
+	errInvalidJSONLimit              = errors.New("Invalid JSON request body limit: --json-max-depth and --json-max-tokens cannot be negative")
+	errInvalidRegistryTokenCacheSize = errors.New("Invalid registry token cache size: --registry-token-cache-size cannot be negative")
+	errInvalidEdgeDrainTimeout       = errors.New("Invalid Edge drain timeout: --edge-drain-timeout must be a valid duration and cannot be negative")
+	errInvalidEdgeReconnectJitter    = errors.New("Invalid Edge reconnect jitter: --edge-reconnect-jitter must be a valid duration and cannot be negative")
 )
 
 // ParseFlags parse the CLI flags and return a portainer.Flags struct
@@ -46,15 +51,23 @@ func (*Service) ParseFlags(version string) (*portainer.CLIFlags, error) {
 		SSLCert:                   kingpin.Flag("sslcert", "Path to the SSL certificate used to secure the Portainer instance").Default(defaultSSLCertPath).String(),
 		SSLKey:                    kingpin.Flag("sslkey", "Path to the SSL key used to secure the Portainer instance").Default(defaultSSLKeyPath).String(),
 		SnapshotInterval:          kingpin.Flag("snapshot-interval", "Duration between each endpoint snapshot job").Default(defaultSnapshotInterval).String(),
+		DegradedProbeInterval:     kingpin.Flag("degraded-probe-interval", "Duration between each write attempt on the database while it is in degraded mode (disk full or read-only)").Default(defaultDegradedProbeInterval).String(),
 		AdminPassword:             kingpin.Flag("admin-password", "Hashed admin password").String(),
 		AdminPasswordFile:         kingpin.Flag("admin-password-file", "Path to the file containing the password for the admin user").String(),
 		Labels:                    pairs(kingpin.Flag("hide-label", "Hide containers with a specific label in the UI").Short('l')),
 		Logo:                      kingpin.Flag("logo", "URL for the logo displayed in the UI").String(),
 		Templates:                 kingpin.Flag("templates", "URL to the templates definitions.").Short('t').String(),
+		JSONMaxDepth:              kingpin.Flag("json-max-depth", "Maximum nesting depth of the JSON request bodies, 0 to disable the limit").Default(defaultJSONMaxDepth).Int(),
+		JSONMaxTokens:             kingpin.Flag("json-max-tokens", "Maximum number of tokens (keys and values) of the JSON request bodies, 0 to disable the limit").Default(defaultJSONMaxTokens).Int(),
+		RegistryTokenCacheSize:    kingpin.Flag("registry-token-cache-size", "Maximum number of registry authentication tokens kept until they expire, 0 to disable the cache").Default(defaultRegistryTokenCacheSize).Int(),
+		EdgeDrainTimeout:          kingpin.Flag("edge-drain-timeout", "Duration during which the Edge agents are asked to check in again later before Portainer stops, 0 to stop immediately. It must be lower than the stop timeout of the container").Default(defaultEdgeDrainTimeout).String(),
+		EdgeReconnectJitter:       kingpin.Flag("edge-reconnect-jitter", "Maximum random delay after which the Edge agents check in again while Portainer stops and after it starts").Default(defaultEdgeReconnectJitter).String(),
 	}
 
 	kingpin.Parse()
 
+	flags.Sources = flagSources(os.Args[1:])
+
 	if !filepath.IsAbs(*flags.Assets) {
 		ex, err := os.Executable()
 		if err != nil {
@@ -66,6 +79,34 @@ func (*Service) ParseFlags(version string) (*portainer.CLIFlags, error) {
 	return flags, nil
 }
 
+// flagSources returns the source of the value of each flag: the flags specified on the command line,
+// the flags defined by their environment variable and the flags using their default value
+func flagSources(args []string) map[string]portainer.SettingSource {
+	sources := make(map[string]portainer.SettingSource)
+
+	for _, flag := range kingpin.CommandLine.Model().Flags {
+		sources[flag.Name] = portainer.SettingSourceDefault
+		if flag.Envar != "" {
+			if _, ok := os.LookupEnv(flag.Envar); ok {
+				sources[flag.Name] = portainer.SettingSourceEnv
+			}
+		}
+	}
+
+	context, err := kingpin.CommandLine.ParseContext(args)
+	if err != nil {
+		return sources
+	}
+
+	for _, element := range context.Elements {
+		if flag, ok := element.Clause.(*kingpin.FlagClause); ok {
+			sources[flag.Model().Name] = portainer.SettingSourceFlag
+		}
+	}
+
+	return sources
+}
+
 // ValidateFlags validates the values of the flags.
 func (*Service) ValidateFlags(flags *portainer.CLIFlags) error {
 
@@ -81,6 +122,27 @@ func (*Service) ValidateFlags(flags *portainer.CLIFlags) error {
 		return err
 	}
 
+	err = validateDegradedProbeInterval(*flags.DegradedProbeInterval)
+	if err != nil {
+		return err
+	}
+
+	if *flags.JSONMaxDepth < 0 || *flags.JSONMaxTokens < 0 {
+		return errInvalidJSONLimit
+	}
+
+	if *flags.RegistryTokenCacheSize < 0 {
+		return errInvalidRegistryTokenCacheSize
+	}
+
+	if !isPositiveOrZeroDuration(*flags.EdgeDrainTimeout) {
+		return errInvalidEdgeDrainTimeout
+	}
+
+	if !isPositiveOrZeroDuration(*flags.EdgeReconnectJitter) {
+		return errInvalidEdgeReconnectJitter
+	}
+
 	if *flags.AdminPassword != "" && *flags.AdminPasswordFile != "" {
 		return errAdminPassExcludeAdminPassFile
 	}
@@ -114,6 +176,19 @@ func validateEndpointURL(endpointURL string) error {
 	return nil
 }
 
+func validateDegradedProbeInterval(probeInterval string) error {
+	interval, err := time.ParseDuration(probeInterval)
+	if err != nil || interval <= 0 {
+		return errInvalidDegradedProbeInterval
+	}
+	return nil
+}
+
+func isPositiveOrZeroDuration(value string) bool {
+	duration, err := time.ParseDuration(value)
+	return err == nil && duration >= 0
+}
+
 func validateSnapshotInterval(snapshotInterval string) error {
 	if snapshotInterval != defaultSnapshotInterval {
 		_, err := time.ParseDuration(snapshotInterval)
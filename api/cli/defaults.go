@@ -1,20 +1,27 @@
+//go:build !windows
 // +build !windows
 
 package cli
 
 const (
-	defaultBindAddress         = ":9000"
-	defaultTunnelServerAddress = "0.0.0.0"
-	defaultTunnelServerPort    = "8000"
-	defaultDataDirectory       = "/data"
-	defaultAssetsDirectory     = "./"
-	defaultTLS                 = "false"
-	defaultTLSSkipVerify       = "false"
-	defaultTLSCACertPath       = "/certs/ca.pem"
-	defaultTLSCertPath         = "/certs/cert.pem"
-	defaultTLSKeyPath          = "/certs/key.pem"
-	defaultSSL                 = "false"
-	defaultSSLCertPath         = "/certs/portainer.crt"
-	defaultSSLKeyPath          = "/certs/portainer.key"
-	defaultSnapshotInterval    = "5m"
+	defaultBindAddress            = ":9000"
+	defaultTunnelServerAddress    = "0.0.0.0"
+	defaultTunnelServerPort       = "8000"
+	defaultDataDirectory          = "/data"
+	defaultAssetsDirectory        = "./"
+	defaultTLS                    = "false"
+	defaultTLSSkipVerify          = "false"
+	defaultTLSCACertPath          = "/certs/ca.pem"
+	defaultTLSCertPath            = "/certs/cert.pem"
+	defaultTLSKeyPath             = "/certs/key.pem"
+	defaultSSL                    = "false"
+	defaultSSLCertPath            = "/certs/portainer.crt"
+	defaultSSLKeyPath             = "/certs/portainer.key"
+	defaultSnapshotInterval       = "5m"
+	defaultDegradedProbeInterval  = "30s"
+	defaultJSONMaxDepth           = "64"
+	defaultJSONMaxTokens          = "100000"
+	defaultRegistryTokenCacheSize = "1000"
+	defaultEdgeDrainTimeout       = "5s"
+	defaultEdgeReconnectJitter    = "5s"
 )
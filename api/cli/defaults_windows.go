@@ -1,18 +1,24 @@
 package cli
 
 const (
-	defaultBindAddress         = ":9000"
-	defaultTunnelServerAddress = "0.0.0.0"
-	defaultTunnelServerPort    = "8000"
-	defaultDataDirectory       = "C:\\data"
-	defaultAssetsDirectory     = "./"
-	defaultTLS                 = "false"
-	defaultTLSSkipVerify       = "false"
-	defaultTLSCACertPath       = "C:\\certs\\ca.pem"
-	defaultTLSCertPath         = "C:\\certs\\cert.pem"
-	defaultTLSKeyPath          = "C:\\certs\\key.pem"
-	defaultSSL                 = "false"
-	defaultSSLCertPath         = "C:\\certs\\portainer.crt"
-	defaultSSLKeyPath          = "C:\\certs\\portainer.key"
-	defaultSnapshotInterval    = "5m"
+	defaultBindAddress            = ":9000"
+	defaultTunnelServerAddress    = "0.0.0.0"
+	defaultTunnelServerPort       = "8000"
+	defaultDataDirectory          = "C:\\data"
+	defaultAssetsDirectory        = "./"
+	defaultTLS                    = "false"
+	defaultTLSSkipVerify          = "false"
+	defaultTLSCACertPath          = "C:\\certs\\ca.pem"
+	defaultTLSCertPath            = "C:\\certs\\cert.pem"
+	defaultTLSKeyPath             = "C:\\certs\\key.pem"
+	defaultSSL                    = "false"
+	defaultSSLCertPath            = "C:\\certs\\portainer.crt"
+	defaultSSLKeyPath             = "C:\\certs\\portainer.key"
+	defaultSnapshotInterval       = "5m"
+	defaultDegradedProbeInterval  = "30s"
+	defaultJSONMaxDepth           = "64"
+	defaultJSONMaxTokens          = "100000"
+	defaultRegistryTokenCacheSize = "1000"
+	defaultEdgeDrainTimeout       = "5s"
+	defaultEdgeReconnectJitter    = "5s"
 )
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"crypto/sha256"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	portainer "github.com/portainer/portainer/api"
@@ -20,7 +24,25 @@ import (
 	"github.com/portainer/portainer/api/http/client"
 	"github.com/portainer/portainer/api/http/proxy"
 	kubeproxy "github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
+	"github.com/portainer/portainer/api/internal/containercleanup"
+	"github.com/portainer/portainer/api/internal/containerjob"
+	"github.com/portainer/portainer/api/internal/crashloop"
+	"github.com/portainer/portainer/api/internal/dockerhublimit"
+	"github.com/portainer/portainer/api/internal/imagetrust"
+	"github.com/portainer/portainer/api/internal/jsonlimit"
+	"github.com/portainer/portainer/api/internal/logs"
+	"github.com/portainer/portainer/api/internal/mailer"
+	"github.com/portainer/portainer/api/internal/registryclient"
+	"github.com/portainer/portainer/api/internal/scheduler"
+	"github.com/portainer/portainer/api/internal/secret"
+	"github.com/portainer/portainer/api/internal/servicecanary"
 	"github.com/portainer/portainer/api/internal/snapshot"
+	"github.com/portainer/portainer/api/internal/stackdeploy"
+	"github.com/portainer/portainer/api/internal/stackdrift"
+	"github.com/portainer/portainer/api/internal/stackmonitor"
+	"github.com/portainer/portainer/api/internal/stackrestart"
+	"github.com/portainer/portainer/api/internal/tlsexpiry"
+	"github.com/portainer/portainer/api/internal/volumebackup"
 	"github.com/portainer/portainer/api/jwt"
 	"github.com/portainer/portainer/api/kubernetes"
 	kubecli "github.com/portainer/portainer/api/kubernetes/cli"
@@ -29,6 +51,15 @@ import (
 	"github.com/portainer/portainer/api/oauth"
 )
 
+// logBufferCapacity is the number of recent log entries kept in memory for support bundles
+const logBufferCapacity = 1000
+
+func initLogBuffer() *logs.Buffer {
+	logBuffer := logs.NewBuffer(logBufferCapacity)
+	log.SetOutput(io.MultiWriter(os.Stderr, logBuffer))
+	return logBuffer
+}
+
 func initCLI() *portainer.CLIFlags {
 	var cliService portainer.CLIService = &cli.Service{}
 	flags, err := cliService.ParseFlags(portainer.APIVersion)
@@ -74,17 +105,17 @@ func initDataStore(dataStorePath string, fileService portainer.FileService) port
 	return store
 }
 
-func initComposeStackManager(assetsPath string, dataStorePath string, reverseTunnelService portainer.ReverseTunnelService, proxyManager *proxy.Manager) portainer.ComposeStackManager {
-	composeWrapper := exec.NewComposeWrapper(assetsPath, proxyManager)
+func initComposeStackManager(assetsPath string, dataStorePath string, reverseTunnelService portainer.ReverseTunnelService, proxyManager *proxy.Manager, dataStore portainer.DataStore) portainer.ComposeStackManager {
+	composeWrapper := exec.NewComposeWrapper(assetsPath, proxyManager, dataStore)
 	if composeWrapper != nil {
 		return composeWrapper
 	}
 
-	return libcompose.NewComposeStackManager(dataStorePath, reverseTunnelService)
+	return libcompose.NewComposeStackManager(dataStorePath, reverseTunnelService, dataStore)
 }
 
-func initSwarmStackManager(assetsPath string, dataStorePath string, signatureService portainer.DigitalSignatureService, fileService portainer.FileService, reverseTunnelService portainer.ReverseTunnelService) (portainer.SwarmStackManager, error) {
-	return exec.NewSwarmStackManager(assetsPath, dataStorePath, signatureService, fileService, reverseTunnelService)
+func initSwarmStackManager(assetsPath string, dataStorePath string, signatureService portainer.DigitalSignatureService, fileService portainer.FileService, reverseTunnelService portainer.ReverseTunnelService, dataStore portainer.DataStore) (portainer.SwarmStackManager, error) {
+	return exec.NewSwarmStackManager(assetsPath, dataStorePath, signatureService, fileService, reverseTunnelService, dataStore)
 }
 
 func initKubernetesDeployer(assetsPath string) portainer.KubernetesDeployer {
@@ -136,11 +167,49 @@ func initKubernetesClientFactory(signatureService portainer.DigitalSignatureServ
 	return kubecli.NewClientFactory(signatureService, reverseTunnelService, instanceID)
 }
 
-func initSnapshotService(snapshotInterval string, dataStore portainer.DataStore, dockerClientFactory *docker.ClientFactory, kubernetesClientFactory *kubecli.ClientFactory) (portainer.SnapshotService, error) {
-	dockerSnapshotter := docker.NewSnapshotter(dockerClientFactory)
+func restorePausedSubsystems(dataStore portainer.DataStore, jobScheduler *scheduler.Scheduler) {
+	settings, err := dataStore.Settings().Settings()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, subsystem := range settings.PausedSubsystems {
+		err := jobScheduler.PauseSubsystem(subsystem)
+		if err != nil {
+			log.Printf("Warning: unable to pause subsystem %s: %s\n", subsystem, err)
+			continue
+		}
+		log.Printf("Subsystem %s is paused, its scheduled runs will be skipped until it is resumed\n", subsystem)
+	}
+}
+
+func initDataStoreHealthProbe(dataStore portainer.DataStore, jobScheduler *scheduler.Scheduler, probeInterval string) {
+	interval, err := time.ParseDuration(probeInterval)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = jobScheduler.Register(scheduler.Job{
+		ID:          "datastore_health_probe",
+		Description: "Attempt a write on the database while it is in degraded mode to detect its recovery",
+		Interval:    interval,
+		Run: func() error {
+			if !dataStore.Health().Degraded {
+				return nil
+			}
+			return dataStore.ProbeWrite()
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initSnapshotService(snapshotInterval string, dataStore portainer.DataStore, dockerClientFactory *docker.ClientFactory, kubernetesClientFactory *kubecli.ClientFactory, jobScheduler *scheduler.Scheduler) (portainer.SnapshotService, error) {
+	dockerSnapshotter := docker.NewSnapshotter(dockerClientFactory, dataStore)
 	kubernetesSnapshotter := kubernetes.NewSnapshotter(kubernetesClientFactory)
 
-	snapshotService, err := snapshot.NewService(snapshotInterval, dataStore, dockerSnapshotter, kubernetesSnapshotter)
+	snapshotService, err := snapshot.NewService(snapshotInterval, dataStore, dockerSnapshotter, kubernetesSnapshotter, jobScheduler)
 	if err != nil {
 		return nil, err
 	}
@@ -175,18 +244,7 @@ func updateSettingsFromFlags(dataStore portainer.DataStore, flags *portainer.CLI
 		return err
 	}
 
-	settings.LogoURL = *flags.Logo
-	settings.SnapshotInterval = *flags.SnapshotInterval
-	settings.EnableEdgeComputeFeatures = *flags.EnableEdgeComputeFeatures
-	settings.EnableTelemetry = true
-
-	if *flags.Templates != "" {
-		settings.TemplatesURL = *flags.Templates
-	}
-
-	if *flags.Labels != nil {
-		settings.BlackListedLabels = *flags.Labels
-	}
+	portainer.ApplyFlagsToSettings(settings, flags)
 
 	return dataStore.Settings().UpdateSettings(settings)
 }
@@ -220,6 +278,18 @@ func initKeyPair(fileService portainer.FileService, signatureService portainer.D
 	return generateAndStoreKeyPair(fileService, signatureService)
 }
 
+// initEncryptionKey derives the key used to encrypt secrets stored in the database from the
+// private key of the instance, which is stored outside of the database.
+func initEncryptionKey(fileService portainer.FileService) ([]byte, error) {
+	private, _, err := fileService.LoadKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	key := sha256.Sum256(private)
+	return key[:], nil
+}
+
 func createTLSSecuredEndpoint(flags *portainer.CLIFlags, dataStore portainer.DataStore, snapshotService portainer.SnapshotService) error {
 	tlsConfiguration := portainer.TLSConfiguration{
 		TLS:           *flags.TLS,
@@ -368,7 +438,24 @@ func terminateIfNoAdminCreated(dataStore portainer.DataStore) {
 	}
 }
 
+// drainOnShutdown drains the Edge tunnels when Portainer receives a termination signal, then closes the database and exits
+func drainOnShutdown(reverseTunnelService *chisel.Service, dataStore portainer.DataStore, drainTimeout time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	reverseTunnelService.Drain(drainTimeout)
+
+	err := dataStore.Close()
+	if err != nil {
+		log.Printf("Unable to close the database: %s", err)
+	}
+	os.Exit(0)
+}
+
 func main() {
+	logBuffer := initLogBuffer()
+
 	flags := initCLI()
 
 	fileService := initFileService(*flags.Data)
@@ -400,7 +487,19 @@ func main() {
 		log.Fatal(err)
 	}
 
-	reverseTunnelService := chisel.NewService(dataStore)
+	encryptionKey, err := initEncryptionKey(fileService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jobScheduler := scheduler.NewScheduler()
+	restorePausedSubsystems(dataStore, jobScheduler)
+	initDataStoreHealthProbe(dataStore, jobScheduler, *flags.DegradedProbeInterval)
+
+	registryclient.SetTokenCacheSize(*flags.RegistryTokenCacheSize)
+
+	edgeReconnectJitter, _ := time.ParseDuration(*flags.EdgeReconnectJitter)
+	reverseTunnelService := chisel.NewService(dataStore, jobScheduler, edgeReconnectJitter)
 
 	instanceID, err := dataStore.Version().InstanceID()
 	if err != nil {
@@ -410,20 +509,59 @@ func main() {
 	dockerClientFactory := initDockerClientFactory(digitalSignatureService, reverseTunnelService)
 	kubernetesClientFactory := initKubernetesClientFactory(digitalSignatureService, reverseTunnelService, instanceID)
 
-	snapshotService, err := initSnapshotService(*flags.SnapshotInterval, dataStore, dockerClientFactory, kubernetesClientFactory)
+	snapshotService, err := initSnapshotService(*flags.SnapshotInterval, dataStore, dockerClientFactory, kubernetesClientFactory, jobScheduler)
 	if err != nil {
 		log.Fatal(err)
 	}
 	snapshotService.Start()
 
-	swarmStackManager, err := initSwarmStackManager(*flags.Assets, *flags.Data, digitalSignatureService, fileService, reverseTunnelService)
+	containerJobService := containerjob.NewService(dataStore, dockerClientFactory, jobScheduler)
+	containerJobService.Start()
+
+	mailerService := mailer.NewService(dataStore, encryptionKey)
+
+	crashLoopService := crashloop.NewService(dataStore, dockerClientFactory, mailerService, jobScheduler)
+	crashLoopService.Start()
+
+	containerCleanupService := containercleanup.NewService(dataStore, dockerClientFactory, jobScheduler)
+	containerCleanupService.Start()
+
+	tlsExpiryService := tlsexpiry.NewService(dataStore, mailerService, jobScheduler)
+	tlsExpiryService.Start()
+
+	secretService := secret.NewService(dataStore, dockerClientFactory, encryptionKey, jobScheduler)
+	secretService.Start()
+
+	volumeBackupService := volumebackup.NewService(dataStore, dockerClientFactory, mailerService, jobScheduler, encryptionKey, *flags.Data)
+	volumeBackupService.Start()
+
+	swarmStackManager, err := initSwarmStackManager(*flags.Assets, *flags.Data, digitalSignatureService, fileService, reverseTunnelService, dataStore)
 	if err != nil {
 		log.Fatal(err)
 	}
 	kubernetesTokenCacheManager := kubeproxy.NewTokenCacheManager()
-	proxyManager := proxy.NewManager(dataStore, digitalSignatureService, reverseTunnelService, dockerClientFactory, kubernetesClientFactory, kubernetesTokenCacheManager)
+	imageVerifier := imagetrust.NewVerifier(dataStore)
+
+	proxyManager := proxy.NewManager(dataStore, digitalSignatureService, reverseTunnelService, dockerClientFactory, kubernetesClientFactory, kubernetesTokenCacheManager, imageVerifier)
+
+	composeStackManager := initComposeStackManager(*flags.Assets, *flags.Data, reverseTunnelService, proxyManager, dataStore)
 
-	composeStackManager := initComposeStackManager(*flags.Assets, *flags.Data, reverseTunnelService, proxyManager)
+	stackDeployService := stackdeploy.NewService(dataStore, fileService, dockerClientFactory, composeStackManager, swarmStackManager, imageVerifier, secretService)
+
+	stackDriftService := stackdrift.NewService(dataStore, dockerClientFactory, fileService, stackDeployService, jobScheduler)
+	stackDriftService.Start()
+
+	stackMonitorService := stackmonitor.NewService(dataStore, dockerClientFactory, fileService, stackDeployService, crashLoopService, mailerService, jobScheduler)
+	stackMonitorService.Start()
+
+	serviceCanaryService := servicecanary.NewService(dataStore, dockerClientFactory, jobScheduler)
+	serviceCanaryService.Start()
+
+	stackRestartService := stackrestart.NewService(dataStore, dockerClientFactory, jobScheduler)
+	stackRestartService.Start()
+
+	dockerHubRateLimitService := dockerhublimit.NewService(dataStore, jobScheduler)
+	dockerHubRateLimitService.Start()
 
 	kubernetesDeployer := initKubernetesDeployer(*flags.Assets)
 
@@ -489,6 +627,9 @@ func main() {
 		log.Fatal(err)
 	}
 
+	edgeDrainTimeout, _ := time.ParseDuration(*flags.EdgeDrainTimeout)
+	go drainOnShutdown(reverseTunnelService, dataStore, edgeDrainTimeout)
+
 	var server portainer.Server = &http.Server{
 		ReverseTunnelService:        reverseTunnelService,
 		Status:                      applicationStatus,
@@ -513,6 +654,24 @@ func main() {
 		SSLKey:                      *flags.SSLKey,
 		DockerClientFactory:         dockerClientFactory,
 		KubernetesClientFactory:     kubernetesClientFactory,
+		LogBuffer:                   logBuffer,
+		ImageVerifier:               imageVerifier,
+		Scheduler:                   jobScheduler,
+		Mailer:                      mailerService,
+		ContainerJobService:         containerJobService,
+		CrashLoopService:            crashLoopService,
+		ContainerCleanupService:     containerCleanupService,
+		SecretService:               secretService,
+		VolumeBackupService:         volumeBackupService,
+		StackDeployService:          stackDeployService,
+		StackDriftService:           stackDriftService,
+		StackMonitorService:         stackMonitorService,
+		DockerHubRateLimitService:   dockerHubRateLimitService,
+		Flags:                       flags,
+		JSONLimits: jsonlimit.Limits{
+			MaxDepth:  *flags.JSONMaxDepth,
+			MaxTokens: *flags.JSONMaxTokens,
+		},
 	}
 
 	log.Printf("Starting Portainer %s on %s", portainer.APIVersion, *flags.Addr)
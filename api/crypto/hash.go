@@ -1,6 +1,9 @@
 package crypto
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -20,3 +23,10 @@ func (*Service) Hash(data string) (string, error) {
 func (*Service) CompareHashAndData(hash string, data string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(data))
 }
+
+// HashToken hashes a randomly generated token using the SHA-256 algorithm. Unlike Hash, it is fast enough
+// to verify the tokens sent on each request, it must not be used for passwords.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
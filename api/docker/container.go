@@ -0,0 +1,213 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+const containerLabelForDockerServiceID = "com.docker.swarm.service.id"
+
+// ErrServiceTaskContainer is returned when trying to recreate a container managed by a Swarm service
+var ErrServiceTaskContainer = errors.New("Container is managed by a Swarm service and cannot be recreated")
+
+// ContainerConfigurator is used to alter the configuration of a container before it is recreated
+type ContainerConfigurator func(config *container.Config, hostConfig *container.HostConfig)
+
+// RecreateContainer replaces a container with a new container using the same configuration,
+// altered by the configure function. The new container is connected to the same networks and
+// started if the original container was running. The original container is only removed
+// once the new container is created and started, it is restored on failure.
+// It returns the identifier of the new container.
+func RecreateContainer(cli *client.Client, containerID string, configure ContainerConfigurator) (string, error) {
+	ctx := context.Background()
+
+	original, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", err
+	}
+
+	if original.Config == nil || original.HostConfig == nil {
+		return "", fmt.Errorf("Unable to retrieve the configuration of container %s", containerID)
+	}
+
+	if original.Config.Labels[containerLabelForDockerServiceID] != "" {
+		return "", ErrServiceTaskContainer
+	}
+
+	config, hostConfig, networkingConfig, extraNetworks := recreateConfiguration(&original)
+	if configure != nil {
+		configure(config, hostConfig)
+	}
+
+	name := strings.TrimPrefix(original.Name, "/")
+	wasRunning := original.State != nil && original.State.Running
+
+	err = cli.ContainerRename(ctx, original.ID, fmt.Sprintf("%s-portainer-recreate-%d", name, time.Now().Unix()))
+	if err != nil {
+		return "", err
+	}
+
+	restore := func(newContainerID string) {
+		if newContainerID != "" {
+			cli.ContainerRemove(ctx, newContainerID, types.ContainerRemoveOptions{Force: true})
+		}
+		cli.ContainerRename(ctx, original.ID, name)
+		if wasRunning {
+			cli.ContainerStart(ctx, original.ID, types.ContainerStartOptions{})
+		}
+	}
+
+	created, err := cli.ContainerCreate(ctx, config, hostConfig, networkingConfig, name)
+	if err != nil {
+		restore("")
+		return "", err
+	}
+
+	if wasRunning {
+		err = cli.ContainerStop(ctx, original.ID, nil)
+		if err != nil {
+			restore(created.ID)
+			return "", err
+		}
+	}
+
+	for networkName, settings := range extraNetworks {
+		err = cli.NetworkConnect(ctx, networkName, created.ID, settings)
+		if err != nil {
+			restore(created.ID)
+			return "", err
+		}
+	}
+
+	if wasRunning {
+		err = cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{})
+		if err != nil {
+			restore(created.ID)
+			return "", err
+		}
+	}
+
+	err = cli.ContainerRemove(ctx, original.ID, types.ContainerRemoveOptions{RemoveVolumes: false, Force: true})
+	if err != nil {
+		return created.ID, err
+	}
+
+	return created.ID, nil
+}
+
+// recreateConfiguration extracts the configuration required to recreate a container.
+// The new container uses the image of the original container, even when its tag now references another image,
+// and the volumes of the original container, including the anonymous volumes.
+// Only one network can be specified when creating a container, any other network
+// is returned separately to be connected after the creation.
+func recreateConfiguration(original *types.ContainerJSON) (*container.Config, *container.HostConfig, *network.NetworkingConfig, map[string]*network.EndpointSettings) {
+	config := *original.Config
+	hostConfig := *original.HostConfig
+
+	if original.Image != "" {
+		config.Image = original.Image
+	}
+	hostConfig.Binds = preservedBinds(original)
+
+	shortID := original.ID
+	if len(shortID) > 12 {
+		shortID = shortID[:12]
+	}
+
+	// the hostname defaults to the short identifier of the container and must be generated again
+	if config.Hostname == shortID {
+		config.Hostname = ""
+	}
+
+	networkingConfig := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{},
+	}
+	extraNetworks := map[string]*network.EndpointSettings{}
+
+	if original.NetworkSettings == nil {
+		return &config, &hostConfig, networkingConfig, extraNetworks
+	}
+
+	for networkName, settings := range original.NetworkSettings.Networks {
+		if settings == nil {
+			continue
+		}
+
+		aliases := make([]string, 0)
+		for _, alias := range settings.Aliases {
+			if alias != shortID {
+				aliases = append(aliases, alias)
+			}
+		}
+
+		endpointSettings := &network.EndpointSettings{
+			IPAMConfig: settings.IPAMConfig,
+			Links:      settings.Links,
+			Aliases:    aliases,
+			DriverOpts: settings.DriverOpts,
+		}
+
+		if networkName == string(hostConfig.NetworkMode) || (hostConfig.NetworkMode.IsDefault() && networkName == "bridge") {
+			networkingConfig.EndpointsConfig[networkName] = endpointSettings
+		} else {
+			extraNetworks[networkName] = endpointSettings
+		}
+	}
+
+	return &config, &hostConfig, networkingConfig, extraNetworks
+}
+
+// preservedBinds returns the binds of the container, completed with the volumes and the bind mounts of the container
+// which are not declared in its host configuration, e.g. the anonymous volumes of its image
+func preservedBinds(original *types.ContainerJSON) []string {
+	binds := append([]string{}, original.HostConfig.Binds...)
+
+	declared := map[string]bool{}
+	for _, bind := range binds {
+		parts := strings.Split(bind, ":")
+		if len(parts) > 1 {
+			declared[parts[1]] = true
+		}
+	}
+	for _, hostMount := range original.HostConfig.Mounts {
+		declared[hostMount.Target] = true
+	}
+
+	for _, mountPoint := range original.Mounts {
+		if declared[mountPoint.Destination] {
+			continue
+		}
+
+		var bind string
+		switch mountPoint.Type {
+		case mount.TypeVolume:
+			if mountPoint.Name == "" {
+				continue
+			}
+			bind = mountPoint.Name + ":" + mountPoint.Destination
+		case mount.TypeBind:
+			bind = mountPoint.Source + ":" + mountPoint.Destination
+		default:
+			continue
+		}
+
+		if !mountPoint.RW {
+			bind += ":ro"
+		}
+		binds = append(binds, bind)
+	}
+
+	if len(binds) == 0 {
+		return original.HostConfig.Binds
+	}
+	return binds
+}
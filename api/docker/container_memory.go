@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"errors"
+
+	"github.com/docker/docker/api/types/container"
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	minOomScoreAdj = -1000
+	maxOomScoreAdj = 1000
+)
+
+var (
+	errNegativeMemory           = errors.New("Invalid memory settings. Memory and memory reservation cannot be negative")
+	errInvalidMemorySwap        = errors.New("Invalid memory settings. Memory swap must be -1 (unbounded), 0 or greater than or equal to the memory limit")
+	errMemorySwapWithoutMemory  = errors.New("Invalid memory settings. A memory limit is required to set a memory swap limit")
+	errInvalidMemoryReservation = errors.New("Invalid memory settings. Memory reservation must be lower than or equal to the memory limit")
+	errInvalidOomScoreAdj       = errors.New("Invalid memory settings. OOM score adjustment must be between -1000 and 1000")
+)
+
+// ValidateMemorySettings verifies the memory limits and the OOM-killer settings of a container
+func ValidateMemorySettings(settings *portainer.ContainerMemorySettings) error {
+	if settings.Memory < 0 || settings.MemoryReservation < 0 {
+		return errNegativeMemory
+	}
+
+	if settings.MemorySwap > 0 {
+		if settings.Memory == 0 {
+			return errMemorySwapWithoutMemory
+		}
+		if settings.MemorySwap < settings.Memory {
+			return errInvalidMemorySwap
+		}
+	} else if settings.MemorySwap < -1 {
+		return errInvalidMemorySwap
+	}
+
+	if settings.Memory > 0 && settings.MemoryReservation > settings.Memory {
+		return errInvalidMemoryReservation
+	}
+
+	if settings.OomScoreAdj < minOomScoreAdj || settings.OomScoreAdj > maxOomScoreAdj {
+		return errInvalidOomScoreAdj
+	}
+
+	return nil
+}
+
+// MemorySettingsWarnings returns the warnings associated to valid but risky memory settings
+func MemorySettingsWarnings(settings *portainer.ContainerMemorySettings) []string {
+	warnings := make([]string, 0)
+
+	if settings.OomKillDisable && settings.Memory == 0 {
+		warnings = append(warnings, "The OOM-killer is disabled without a memory limit, the container can exhaust the memory of the host and make it unresponsive")
+	}
+
+	return warnings
+}
+
+// MemorySettingsFromHostConfig returns the memory settings of a container
+func MemorySettingsFromHostConfig(hostConfig *container.HostConfig) *portainer.ContainerMemorySettings {
+	settings := &portainer.ContainerMemorySettings{}
+	if hostConfig == nil {
+		return settings
+	}
+
+	settings.Memory = hostConfig.Memory
+	settings.MemorySwap = hostConfig.MemorySwap
+	settings.MemoryReservation = hostConfig.MemoryReservation
+	settings.OomScoreAdj = hostConfig.OomScoreAdj
+	if hostConfig.OomKillDisable != nil {
+		settings.OomKillDisable = *hostConfig.OomKillDisable
+	}
+
+	return settings
+}
+
+// ApplyMemorySettings sets the memory settings on the host configuration of a container
+func ApplyMemorySettings(hostConfig *container.HostConfig, settings *portainer.ContainerMemorySettings) {
+	oomKillDisable := settings.OomKillDisable
+
+	hostConfig.Memory = settings.Memory
+	hostConfig.MemorySwap = settings.MemorySwap
+	hostConfig.MemoryReservation = settings.MemoryReservation
+	hostConfig.OomKillDisable = &oomKillDisable
+	hostConfig.OomScoreAdj = settings.OomScoreAdj
+}
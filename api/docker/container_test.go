@@ -0,0 +1,52 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_recreateConfiguration_shouldUseTheImageOfTheContainer(t *testing.T) {
+	original := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         "0123456789abcdef",
+			Image:      "sha256:4bb46517cac397bdb0bab6eba09b0e1f8e90ddd17cf99662997c3253531136f8",
+			HostConfig: &container.HostConfig{},
+		},
+		Config: &container.Config{Image: "nginx:latest", Hostname: "0123456789ab"},
+	}
+
+	config, _, _, _ := recreateConfiguration(original)
+	assert.Equal(t, "sha256:4bb46517cac397bdb0bab6eba09b0e1f8e90ddd17cf99662997c3253531136f8", config.Image, "the tag might reference another image")
+	assert.Empty(t, config.Hostname, "the generated hostname is generated again")
+	assert.Equal(t, "nginx:latest", original.Config.Image, "the original configuration is not modified")
+}
+
+func Test_recreateConfiguration_shouldKeepTheVolumesOfTheContainer(t *testing.T) {
+	original := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID: "0123456789abcdef",
+			HostConfig: &container.HostConfig{
+				Binds:  []string{"data:/data"},
+				Mounts: []mount.Mount{{Type: mount.TypeBind, Source: "/etc/app", Target: "/etc/app"}},
+			},
+		},
+		Config: &container.Config{Image: "postgres"},
+		Mounts: []types.MountPoint{
+			{Type: mount.TypeVolume, Name: "data", Destination: "/data", RW: true},
+			{Type: mount.TypeBind, Source: "/etc/app", Destination: "/etc/app", RW: true},
+			{Type: mount.TypeVolume, Name: "3f2a1c", Destination: "/var/lib/postgresql/data", RW: true},
+			{Type: mount.TypeBind, Source: "/srv/certs", Destination: "/certs"},
+		},
+		NetworkSettings: &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{}},
+	}
+
+	_, hostConfig, _, _ := recreateConfiguration(original)
+	assert.Equal(t, []string{"data:/data", "3f2a1c:/var/lib/postgresql/data", "/srv/certs:/certs:ro"}, hostConfig.Binds)
+	assert.Equal(t, original.HostConfig.Mounts, hostConfig.Mounts)
+	assert.Equal(t, []string{"data:/data"}, original.HostConfig.Binds, "the original configuration is not modified")
+}
@@ -0,0 +1,31 @@
+package docker
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/docker/docker/client"
+)
+
+// handlerTransport sends the requests of a Docker client to an HTTP handler
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (transport *handlerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	transport.handler.ServeHTTP(recorder, request)
+	return recorder.Result(), nil
+}
+
+// CreateHandlerClient returns a Docker client sending its requests to an HTTP handler, such as the proxy of an endpoint.
+// The responses are buffered, the client must not be used for streams.
+func CreateHandlerClient(handler http.Handler) (*client.Client, error) {
+	return client.NewClientWithOpts(
+		client.WithHost("http://docker.proxy"),
+		client.WithVersion(dockerClientVersion),
+		client.WithHTTPClient(&http.Client{
+			Transport: &handlerTransport{handler: handler},
+		}),
+	)
+}
@@ -15,12 +15,14 @@ import (
 // Snapshotter represents a service used to create endpoint snapshots
 type Snapshotter struct {
 	clientFactory *ClientFactory
+	dataStore     portainer.DataStore
 }
 
 // NewSnapshotter returns a new Snapshotter instance
-func NewSnapshotter(clientFactory *ClientFactory) *Snapshotter {
+func NewSnapshotter(clientFactory *ClientFactory, dataStore portainer.DataStore) *Snapshotter {
 	return &Snapshotter{
 		clientFactory: clientFactory,
+		dataStore:     dataStore,
 	}
 }
 
@@ -32,7 +34,19 @@ func (snapshotter *Snapshotter) CreateSnapshot(endpoint *portainer.Endpoint) (*p
 	}
 	defer cli.Close()
 
-	return snapshot(cli, endpoint)
+	dockerSnapshot, err := snapshot(cli, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := snapshotter.dataStore.Settings().Settings()
+	if err != nil {
+		log.Printf("[WARN] [docker,snapshot] [message: unable to retrieve settings, container stats are not sampled] [endpoint: %s] [err: %s]", endpoint.Name, err)
+	} else if settings.MetricsSettings.Enabled {
+		snapshotContainerStats(dockerSnapshot, cli, endpoint)
+	}
+
+	return dockerSnapshot, nil
 }
 
 func snapshot(cli *client.Client, endpoint *portainer.Endpoint) (*portainer.DockerSnapshot, error) {
@@ -92,10 +106,17 @@ func snapshot(cli *client.Client, endpoint *portainer.Endpoint) (*portainer.Dock
 }
 
 func snapshotInfo(snapshot *portainer.DockerSnapshot, cli *client.Client) error {
+	requestTime := time.Now()
 	info, err := cli.Info(context.Background())
 	if err != nil {
 		return err
 	}
+	responseTime := time.Now()
+
+	skew, err := clockSkew(info.SystemTime, requestTime, responseTime)
+	if err == nil {
+		snapshot.ClockSkew = &skew
+	}
 
 	snapshot.Swarm = info.Swarm.ControlAvailable
 	snapshot.DockerVersion = info.ServerVersion
@@ -105,6 +126,18 @@ func snapshotInfo(snapshot *portainer.DockerSnapshot, cli *client.Client) error
 	return nil
 }
 
+// clockSkew returns the difference in milliseconds between the system time reported by the daemon and the host clock.
+// The system time is compared against the middle of the request to compensate the latency of the endpoint.
+func clockSkew(systemTime string, requestTime, responseTime time.Time) (int64, error) {
+	daemonTime, err := time.Parse(time.RFC3339Nano, systemTime)
+	if err != nil {
+		return 0, err
+	}
+
+	hostTime := requestTime.Add(responseTime.Sub(requestTime) / 2)
+	return int64(daemonTime.Sub(hostTime) / time.Millisecond), nil
+}
+
 func snapshotNodes(snapshot *portainer.DockerSnapshot, cli *client.Client) error {
 	nodes, err := cli.NodeList(context.Background(), types.NodeListOptions{})
 	if err != nil {
@@ -0,0 +1,141 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// maxConcurrentStatsRequests limits the number of stats requests sent in parallel to an endpoint
+	maxConcurrentStatsRequests = 5
+	statsRequestTimeout        = 10 * time.Second
+)
+
+// snapshotContainerStats samples the resource usage of the running containers of the snapshot.
+// The containers for which the stats cannot be retrieved are left out of the snapshot.
+func snapshotContainerStats(snapshot *portainer.DockerSnapshot, cli *client.Client, endpoint *portainer.Endpoint) {
+	containers, ok := snapshot.SnapshotRaw.Containers.([]types.Container)
+	if !ok {
+		return
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		semaphore = make(chan struct{}, maxConcurrentStatsRequests)
+		stats     = make([]portainer.DockerContainerStats, 0, len(containers))
+	)
+
+	for _, container := range containers {
+		if container.State != "running" {
+			continue
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(container types.Container) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			containerStats, err := sampleContainerStats(cli, container)
+			if err != nil {
+				log.Printf("[WARN] [docker,snapshot] [message: unable to sample container stats] [endpoint: %s] [container: %s] [err: %s]", endpoint.Name, container.ID, err)
+				return
+			}
+
+			mu.Lock()
+			stats = append(stats, *containerStats)
+			mu.Unlock()
+		}(container)
+	}
+	wg.Wait()
+
+	snapshot.SnapshotRaw.ContainerStats = stats
+}
+
+func sampleContainerStats(cli *client.Client, container types.Container) (*portainer.DockerContainerStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsRequestTimeout)
+	defer cancel()
+
+	response, err := cli.ContainerStats(ctx, container.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var raw types.StatsJSON
+	err = json.NewDecoder(response.Body).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &portainer.DockerContainerStats{
+		ContainerID:   container.ID,
+		ContainerName: containerName(container),
+		Stack:         containerStack(container),
+		CPUPercent:    cpuPercent(&raw),
+		MemoryUsage:   raw.MemoryStats.Usage,
+		MemoryLimit:   raw.MemoryStats.Limit,
+	}
+
+	// the page cache is excluded from the memory usage, as reported by the docker CLI
+	if cache := raw.MemoryStats.Stats["cache"]; cache < stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	}
+
+	for _, network := range raw.Networks {
+		stats.NetworkRxBytes += network.RxBytes
+		stats.NetworkTxBytes += network.TxBytes
+	}
+
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockIOReadBytes += entry.Value
+		case "write":
+			stats.BlockIOWriteBytes += entry.Value
+		}
+	}
+
+	return stats, nil
+}
+
+// cpuPercent computes the CPU usage of the container between the two samples of the stats,
+// using the same computation as the docker CLI
+func cpuPercent(stats *types.StatsJSON) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
+
+func containerName(container types.Container) string {
+	if len(container.Names) == 0 {
+		return container.ID
+	}
+	return strings.TrimPrefix(container.Names[0], "/")
+}
+
+func containerStack(container types.Container) string {
+	if stack, ok := container.Labels["com.docker.compose.project"]; ok {
+		return stack
+	}
+	return container.Labels["com.docker.stack.namespace"]
+}
@@ -11,16 +11,18 @@ import (
 
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/proxy"
+	"github.com/portainer/portainer/api/internal/stackutils"
 )
 
 // ComposeWrapper is a wrapper for docker-compose binary
 type ComposeWrapper struct {
 	binaryPath   string
 	proxyManager *proxy.Manager
+	dataStore    portainer.DataStore
 }
 
 // NewComposeWrapper returns a docker-compose wrapper if corresponding binary present, otherwise nil
-func NewComposeWrapper(binaryPath string, proxyManager *proxy.Manager) *ComposeWrapper {
+func NewComposeWrapper(binaryPath string, proxyManager *proxy.Manager, dataStore portainer.DataStore) *ComposeWrapper {
 	if !IsBinaryPresent(programPath(binaryPath, "docker-compose")) {
 		return nil
 	}
@@ -28,6 +30,7 @@ func NewComposeWrapper(binaryPath string, proxyManager *proxy.Manager) *ComposeW
 	return &ComposeWrapper{
 		binaryPath:   binaryPath,
 		proxyManager: proxyManager,
+		dataStore:    dataStore,
 	}
 }
 
@@ -36,9 +39,25 @@ func (w *ComposeWrapper) ComposeSyntaxMaxVersion() string {
 	return portainer.ComposeSyntaxMaxVersion
 }
 
-// Up builds, (re)creates and starts containers in the background. Wraps `docker-compose up -d` command
-func (w *ComposeWrapper) Up(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
-	_, err := w.command([]string{"up", "-d"}, stack, endpoint)
+// Up builds, (re)creates and starts containers in the background. Wraps `docker-compose up -d` command.
+// When services are specified, only these services and their dependencies are started
+func (w *ComposeWrapper) Up(stack *portainer.Stack, endpoint *portainer.Endpoint, services ...string) error {
+	if endpoint == nil {
+		return errors.New("cannot call a compose command on an empty endpoint")
+	}
+
+	containerDefaults, err := stackutils.EndpointContainerDefaults(w.dataStore, endpoint)
+	if err != nil {
+		return err
+	}
+
+	overrideFilePaths, err := stackutils.CreateDeploymentOverrides(stack, endpoint, containerDefaults)
+	if err != nil {
+		return err
+	}
+	defer stackutils.RemoveOverrideFiles(overrideFilePaths)
+
+	_, err = w.command(append([]string{"up", "-d"}, services...), stack, endpoint, overrideFilePaths...)
 	return err
 }
 
@@ -48,7 +67,7 @@ func (w *ComposeWrapper) Down(stack *portainer.Stack, endpoint *portainer.Endpoi
 	return err
 }
 
-func (w *ComposeWrapper) command(command []string, stack *portainer.Stack, endpoint *portainer.Endpoint) ([]byte, error) {
+func (w *ComposeWrapper) command(command []string, stack *portainer.Stack, endpoint *portainer.Endpoint, overrideFilePaths ...string) ([]byte, error) {
 	if endpoint == nil {
 		return nil, errors.New("cannot call a compose command on an empty endpoint")
 	}
@@ -56,6 +75,9 @@ func (w *ComposeWrapper) command(command []string, stack *portainer.Stack, endpo
 	program := programPath(w.binaryPath, "docker-compose")
 
 	options := setComposeFile(stack)
+	for _, overrideFilePath := range overrideFilePaths {
+		options = append(options, "-f", overrideFilePath)
+	}
 
 	options = addProjectNameOption(options, stack)
 	options, err := addEnvFileOption(options, stack)
@@ -11,6 +11,7 @@ import (
 	"runtime"
 
 	"github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/stackutils"
 )
 
 // SwarmStackManager represents a service for managing stacks.
@@ -20,17 +21,19 @@ type SwarmStackManager struct {
 	signatureService     portainer.DigitalSignatureService
 	fileService          portainer.FileService
 	reverseTunnelService portainer.ReverseTunnelService
+	dataStore            portainer.DataStore
 }
 
 // NewSwarmStackManager initializes a new SwarmStackManager service.
 // It also updates the configuration of the Docker CLI binary.
-func NewSwarmStackManager(binaryPath, dataPath string, signatureService portainer.DigitalSignatureService, fileService portainer.FileService, reverseTunnelService portainer.ReverseTunnelService) (*SwarmStackManager, error) {
+func NewSwarmStackManager(binaryPath, dataPath string, signatureService portainer.DigitalSignatureService, fileService portainer.FileService, reverseTunnelService portainer.ReverseTunnelService, dataStore portainer.DataStore) (*SwarmStackManager, error) {
 	manager := &SwarmStackManager{
 		binaryPath:           binaryPath,
 		dataPath:             dataPath,
 		signatureService:     signatureService,
 		fileService:          fileService,
 		reverseTunnelService: reverseTunnelService,
+		dataStore:            dataStore,
 	}
 
 	err := manager.updateDockerCLIConfiguration(dataPath)
@@ -64,17 +67,35 @@ func (manager *SwarmStackManager) Logout(endpoint *portainer.Endpoint) error {
 	return runCommandAndCaptureStdErr(command, args, nil, "")
 }
 
-// Deploy executes the docker stack deploy command.
+// Deploy executes the docker stack deploy command. The images are always resolved to their digest on the registry,
+// so that every node of the swarm runs the current image of a tag rather than the image it has cached.
+// The container defaults of the endpoint group are applied to the services of the stack.
 func (manager *SwarmStackManager) Deploy(stack *portainer.Stack, prune bool, endpoint *portainer.Endpoint) error {
 	stackFilePath := path.Join(stack.ProjectPath, stack.EntryPoint)
 	command, args := manager.prepareDockerCommandAndArgs(manager.binaryPath, manager.dataPath, endpoint)
 
+	args = append(args, "stack", "deploy", "--with-registry-auth", "--resolve-image", "always", "--compose-file", stackFilePath)
 	if prune {
-		args = append(args, "stack", "deploy", "--prune", "--with-registry-auth", "--compose-file", stackFilePath, stack.Name)
-	} else {
-		args = append(args, "stack", "deploy", "--with-registry-auth", "--compose-file", stackFilePath, stack.Name)
+		args = append(args, "--prune")
 	}
 
+	containerDefaults, err := stackutils.EndpointContainerDefaults(manager.dataStore, endpoint)
+	if err != nil {
+		return err
+	}
+
+	overrideFilePaths, err := stackutils.CreateDeploymentOverrides(stack, endpoint, containerDefaults)
+	if err != nil {
+		return err
+	}
+	defer stackutils.RemoveOverrideFiles(overrideFilePaths)
+
+	for _, overrideFilePath := range overrideFilePaths {
+		args = append(args, "--compose-file", overrideFilePath)
+	}
+
+	args = append(args, stack.Name)
+
 	env := make([]string, 0)
 	for _, envvar := range stack.Env {
 		env = append(env, envvar.Name+"="+envvar.Value)
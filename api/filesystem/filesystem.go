@@ -126,6 +126,11 @@ func (service *Service) StoreStackFileFromBytes(stackIdentifier, fileName string
 	return path.Join(service.fileStorePath, stackStorePath), nil
 }
 
+// DeleteStackFile removes a file from the subfolder of a stack in the ComposeStorePath.
+func (service *Service) DeleteStackFile(stackIdentifier, fileName string) error {
+	return os.Remove(path.Join(service.fileStorePath, ComposeStorePath, stackIdentifier, fileName))
+}
+
 // GetEdgeStackProjectPath returns the absolute path on the FS for a edge stack based
 // on its identifier.
 func (service *Service) GetEdgeStackProjectPath(edgeStackIdentifier string) string {
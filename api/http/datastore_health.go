@@ -0,0 +1,77 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+)
+
+const degradedModeMessage = "Portainer is in degraded mode: the database cannot be written because the disk hosting the data folder is full or read-only. " +
+	"Reads are still served and writes will work again as soon as space is freed"
+
+// degradedModeHandler replaces the server errors of the write requests that fail while the database
+// is degraded by a 507 Insufficient Storage response explaining the situation.
+func degradedModeHandler(next http.Handler, dataStore portainer.DataStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(&degradedModeResponseWriter{ResponseWriter: w, dataStore: dataStore}, r)
+	})
+}
+
+type degradedModeResponseWriter struct {
+	http.ResponseWriter
+	dataStore   portainer.DataStore
+	wroteHeader bool
+	intercepted bool
+}
+
+func (w *degradedModeResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if statusCode >= http.StatusInternalServerError {
+		health := w.dataStore.Health()
+		if health.Degraded {
+			w.intercepted = true
+			httperror.WriteError(w.ResponseWriter, http.StatusInsufficientStorage, degradedModeMessage, errors.New(health.Reason))
+			return
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *degradedModeResponseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.intercepted {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *degradedModeResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *degradedModeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("The response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
@@ -0,0 +1,109 @@
+package containerjobs
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/containerjob"
+)
+
+type containerJobCreatePayload struct {
+	// Endpoint identifier. Reference the endpoint where the job container is run
+	EndpointID portainer.EndpointID `example:"1" validate:"required"`
+	// Image used to create the job container
+	Image string `example:"alpine:latest" validate:"required"`
+	// Command run by the job container. The default command of the image is used when empty
+	Command []string `example:"echo,hello"`
+	// A list of environment variables set in the job container
+	Env []portainer.Pair
+	// Lifecycle of the job. Empty values fall back to the lifecycle defined in the settings
+	Lifecycle portainer.ContainerJobLifecycle
+}
+
+func (payload *containerJobCreatePayload) Validate(r *http.Request) error {
+	if payload.EndpointID == 0 {
+		return errors.New("Invalid endpoint identifier")
+	}
+	if govalidator.IsNull(payload.Image) {
+		return errors.New("Invalid image")
+	}
+	return containerjob.ValidateLifecycle(&payload.Lifecycle)
+}
+
+// @id ContainerJobCreate
+// @summary Run a one-off container job
+// @description Run a container on a Docker endpoint and keep track of its execution.
+// @description Once the container exits, it is removed after the auto-remove delay and its logs are kept with the job record.
+// @description The job record is purged after the record retention period.
+// @description The job container is created with the permissions of the user and is subject to the same security settings and policies as the containers created through the Docker API.
+// @description **Access policy**: restricted
+// @tags container_jobs
+// @security jwt
+// @accept json
+// @produce json
+// @param body body containerJobCreatePayload true "Job details"
+// @success 200 {object} portainer.ContainerJob "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /container_jobs [post]
+func (handler *Handler) containerJobCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload containerJobCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(payload.EndpointID)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	if endpoint.Type != portainer.DockerEnvironment && endpoint.Type != portainer.AgentOnDockerEnvironment && endpoint.Type != portainer.EdgeAgentOnDockerEnvironment {
+		return &httperror.HandlerError{http.StatusBadRequest, "Container jobs can only be run on Docker endpoints", errors.New("Unsupported endpoint type")}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	job := &portainer.ContainerJob{
+		EndpointID: endpoint.ID,
+		Image:      payload.Image,
+		Command:    payload.Command,
+		Env:        payload.Env,
+		Lifecycle:  payload.Lifecycle,
+		CreatedBy:  securityContext.UserID,
+	}
+
+	dockerClient, err := handler.ProxyManager.CreateEndpointDockerClient(endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create a Docker client for the endpoint", err}
+	}
+	defer dockerClient.Close()
+
+	err = handler.ContainerJobService.Run(r.Context(), dockerClient, job)
+	if err != nil && job.ID == 0 {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the container job inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to run the container job", err}
+	}
+
+	return response.JSON(w, job)
+}
@@ -0,0 +1,35 @@
+package containerjobs
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id ContainerJobDelete
+// @summary Remove a one-off container job
+// @description Remove the job container, even if it is still running, and the job record.
+// @description **Access policy**: restricted
+// @tags container_jobs
+// @security jwt
+// @param id path int true "Container job identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Container job not found"
+// @failure 500 "Server error"
+// @router /container_jobs/{id} [delete]
+func (handler *Handler) containerJobDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	job, handlerErr := handler.retrieveContainerJob(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	err := handler.ContainerJobService.Remove(job)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the container job", err}
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,65 @@
+package containerjobs
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// @id ContainerJobInspect
+// @summary Inspect a one-off container job
+// @description Retrieve details about a container job. The logs of the job are not included, use the logs operation to retrieve them.
+// @description **Access policy**: restricted
+// @tags container_jobs
+// @security jwt
+// @produce json
+// @param id path int true "Container job identifier"
+// @success 200 {object} portainer.ContainerJob "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Container job not found"
+// @failure 500 "Server error"
+// @router /container_jobs/{id} [get]
+func (handler *Handler) containerJobInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	job, handlerErr := handler.retrieveContainerJob(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	job.Logs = ""
+
+	return response.JSON(w, job)
+}
+
+// retrieveContainerJob returns the container job referenced by the id route variable,
+// if the user issuing the request can access it.
+func (handler *Handler) retrieveContainerJob(r *http.Request) (*portainer.ContainerJob, *httperror.HandlerError) {
+	jobID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid container job identifier route variable", err}
+	}
+
+	job, err := handler.DataStore.ContainerJob().ContainerJob(portainer.ContainerJobID(jobID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a container job with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a container job with the specified identifier inside the database", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	if !userCanAccessJob(securityContext, job) {
+		return nil, &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	return job, nil
+}
@@ -0,0 +1,57 @@
+package containerjobs
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// @id ContainerJobList
+// @summary List one-off container jobs
+// @description List the container jobs. Non-administrator users can only list the jobs they ran.
+// @description The logs of the jobs are not included, use the logs operation to retrieve them.
+// @description **Access policy**: restricted
+// @tags container_jobs
+// @security jwt
+// @produce json
+// @param endpointId query int false "Only list the jobs run on this endpoint"
+// @success 200 {array} portainer.ContainerJob "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /container_jobs [get]
+func (handler *Handler) containerJobList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericQueryParameter(r, "endpointId", true)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: endpointId", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	jobs, err := handler.DataStore.ContainerJob().ContainerJobs()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve container jobs from the database", err}
+	}
+
+	filteredJobs := make([]portainer.ContainerJob, 0)
+	for _, job := range jobs {
+		if endpointID != 0 && job.EndpointID != portainer.EndpointID(endpointID) {
+			continue
+		}
+
+		if !userCanAccessJob(securityContext, &job) {
+			continue
+		}
+
+		job.Logs = ""
+		filteredJobs = append(filteredJobs, job)
+	}
+
+	return response.JSON(w, filteredJobs)
+}
@@ -0,0 +1,41 @@
+package containerjobs
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+type containerJobLogsResponse struct {
+	// Logs of the job container (stdout and stderr)
+	Logs string `json:"Logs" example:"hello"`
+}
+
+// @id ContainerJobLogs
+// @summary Retrieve the logs of a one-off container job
+// @description Retrieve the logs of the job container. Once the container is removed, the logs collected before its removal are returned.
+// @description **Access policy**: restricted
+// @tags container_jobs
+// @security jwt
+// @produce json
+// @param id path int true "Container job identifier"
+// @success 200 {object} containerJobLogsResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Container job not found"
+// @failure 500 "Server error"
+// @router /container_jobs/{id}/logs [get]
+func (handler *Handler) containerJobLogs(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	job, handlerErr := handler.retrieveContainerJob(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	logs, err := handler.ContainerJobService.Logs(job)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the logs of the job container", err}
+	}
+
+	return response.JSON(w, &containerJobLogsResponse{Logs: logs})
+}
@@ -0,0 +1,45 @@
+package containerjobs
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/proxy"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/containerjob"
+)
+
+// Handler is the HTTP handler used to handle one-off container job operations.
+type Handler struct {
+	*mux.Router
+	requestBouncer      *security.RequestBouncer
+	DataStore           portainer.DataStore
+	ContainerJobService *containerjob.Service
+	ProxyManager        *proxy.Manager
+}
+
+// NewHandler creates a handler to manage one-off container job operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router:         mux.NewRouter(),
+		requestBouncer: bouncer,
+	}
+	h.Handle("/container_jobs",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.containerJobCreate))).Methods(http.MethodPost)
+	h.Handle("/container_jobs",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.containerJobList))).Methods(http.MethodGet)
+	h.Handle("/container_jobs/{id}",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.containerJobInspect))).Methods(http.MethodGet)
+	h.Handle("/container_jobs/{id}",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.containerJobDelete))).Methods(http.MethodDelete)
+	h.Handle("/container_jobs/{id}/logs",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.containerJobLogs))).Methods(http.MethodGet)
+	return h
+}
+
+// userCanAccessJob returns true when the user is an administrator or ran the job
+func userCanAccessJob(securityContext *security.RestrictedRequestContext, job *portainer.ContainerJob) bool {
+	return securityContext.IsAdmin || job.CreatedBy == securityContext.UserID
+}
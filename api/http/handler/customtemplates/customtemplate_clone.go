@@ -0,0 +1,152 @@
+package customtemplates
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/filesystem"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
+)
+
+type customTemplateClonePayload struct {
+	// Title of the clone
+	Title string `example:"Nginx (staging)" validate:"required"`
+	// Description of the clone. The description of the template is kept when not specified
+	Description string `example:"High performance web server for staging"`
+	// Default values replacing the default values of the parameters of the template, per parameter name
+	ParameterDefaults map[string]string `example:"REPLICAS:1"`
+}
+
+func (payload *customTemplateClonePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Title) {
+		return errors.New("Invalid custom template title")
+	}
+	return nil
+}
+
+// @id CustomTemplateClone
+// @summary Clone a custom template
+// @description Create a new custom template from the stack file and the parameters of an existing template,
+// @description with a new title and optionally new default values for the parameters.
+// @description The clone is independent from the original template, it is owned by the user cloning the template
+// @description and is private to this user.
+// @description **Access policy**: authenticated
+// @tags custom_templates
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Template identifier"
+// @param body body customTemplateClonePayload true "Clone details"
+// @success 200 {object} portainer.CustomTemplate "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied to access template"
+// @failure 404 "Template not found"
+// @failure 409 "Template name exists"
+// @failure 500 "Server error"
+// @router /custom_templates/{id}/clone [post]
+func (handler *Handler) customTemplateClone(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	customTemplateID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid Custom template identifier route variable", err}
+	}
+
+	var payload customTemplateClonePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	customTemplate, err := handler.DataStore.CustomTemplate().CustomTemplate(portainer.CustomTemplateID(customTemplateID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a custom template with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a custom template with the specified identifier inside the database", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(strconv.Itoa(customTemplateID), portainer.CustomTemplateResourceControl)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the custom template", err}
+	}
+
+	if !userCanAccessTemplate(*customTemplate, securityContext, resourceControl) {
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	parameters, err := overrideParameterDefaults(customTemplate.Parameters, payload.ParameterDefaults)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	customTemplates, err := handler.DataStore.CustomTemplate().CustomTemplates()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve custom templates from the database", err}
+	}
+
+	for _, existingTemplate := range customTemplates {
+		if existingTemplate.Title == payload.Title {
+			return &httperror.HandlerError{http.StatusConflict, "Template name must be unique", errors.New("Template name must be unique")}
+		}
+	}
+
+	fileContent, err := handler.FileService.GetFileContent(path.Join(customTemplate.ProjectPath, customTemplate.EntryPoint))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve custom template file from disk", err}
+	}
+
+	description := customTemplate.Description
+	if !govalidator.IsNull(payload.Description) {
+		description = payload.Description
+	}
+
+	cloneID := handler.DataStore.CustomTemplate().GetNextIdentifier()
+	clone := &portainer.CustomTemplate{
+		ID:              portainer.CustomTemplateID(cloneID),
+		Title:           payload.Title,
+		Description:     description,
+		Note:            customTemplate.Note,
+		Platform:        customTemplate.Platform,
+		Type:            customTemplate.Type,
+		Logo:            customTemplate.Logo,
+		EntryPoint:      filesystem.ComposeFileDefaultName,
+		Parameters:      parameters,
+		CreatedByUserID: securityContext.UserID,
+		ClonedFromID:    customTemplate.ID,
+	}
+
+	projectPath, err := handler.FileService.StoreCustomTemplateFileFromBytes(strconv.Itoa(cloneID), clone.EntryPoint, fileContent)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist custom template file on disk", err}
+	}
+	clone.ProjectPath = projectPath
+
+	err = handler.DataStore.CustomTemplate().CreateCustomTemplate(clone)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create custom template", err}
+	}
+
+	cloneResourceControl := authorization.NewPrivateResourceControl(strconv.Itoa(cloneID), portainer.CustomTemplateResourceControl, securityContext.UserID)
+
+	err = handler.DataStore.ResourceControl().CreateResourceControl(cloneResourceControl)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist resource control inside the database", err}
+	}
+
+	clone.ResourceControl = cloneResourceControl
+
+	return response.JSON(w, clone)
+}
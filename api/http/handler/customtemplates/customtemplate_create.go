@@ -32,6 +32,7 @@ import (
 // @param Platform formData int false "Platform associated to the template (1 - 'linux', 2 - 'windows'). required when method is file" Enums(1,2)
 // @param Type formData int false "Type of created stack (1 - swarm, 2 - compose), required when method is file" Enums(1,2)
 // @param file formData file false "required when method is file"
+// @param Parameters formData string false "Typed parameters of the template, represented as a JSON array [{'Name': 'REPLICAS', 'Label': 'Replicas', 'Type': 'number', 'DefaultValue': '2'}]"
 // @success 200 {object} portainer.CustomTemplate
 // @failure 400 "Invalid request"
 // @failure 500 "Server error"
@@ -110,6 +111,8 @@ type customTemplateFromFileContentPayload struct {
 	Type portainer.StackType `example:"1" enums:"1,2" validate:"required"`
 	// Content of stack file
 	FileContent string `validate:"required"`
+	// Typed parameters of the template
+	Parameters []portainer.CustomTemplateParameter
 }
 
 func (payload *customTemplateFromFileContentPayload) Validate(r *http.Request) error {
@@ -128,7 +131,7 @@ func (payload *customTemplateFromFileContentPayload) Validate(r *http.Request) e
 	if payload.Type != portainer.DockerSwarmStack && payload.Type != portainer.DockerComposeStack {
 		return errors.New("Invalid custom template type")
 	}
-	return nil
+	return validateParameters(payload.Parameters)
 }
 
 func (handler *Handler) createCustomTemplateFromFileContent(r *http.Request) (*portainer.CustomTemplate, error) {
@@ -148,6 +151,7 @@ func (handler *Handler) createCustomTemplateFromFileContent(r *http.Request) (*p
 		Platform:    (payload.Platform),
 		Type:        (payload.Type),
 		Logo:        payload.Logo,
+		Parameters:  payload.Parameters,
 	}
 
 	templateFolder := strconv.Itoa(customTemplateID)
@@ -174,6 +178,8 @@ type customTemplateFromGitRepositoryPayload struct {
 	Platform portainer.CustomTemplatePlatform `example:"1" enums:"1,2" validate:"required"`
 	// Type of created stack (1 - swarm, 2 - compose)
 	Type portainer.StackType `example:"1" enums:"1,2" validate:"required"`
+	// Typed parameters of the template
+	Parameters []portainer.CustomTemplateParameter
 
 	// URL of a Git repository hosting the Stack file
 	RepositoryURL string `example:"https://github.com/openfaas/faas" validate:"required"`
@@ -211,7 +217,7 @@ func (payload *customTemplateFromGitRepositoryPayload) Validate(r *http.Request)
 	if payload.Type != portainer.DockerSwarmStack && payload.Type != portainer.DockerComposeStack {
 		return errors.New("Invalid custom template type")
 	}
-	return nil
+	return validateParameters(payload.Parameters)
 }
 
 func (handler *Handler) createCustomTemplateFromGitRepository(r *http.Request) (*portainer.CustomTemplate, error) {
@@ -231,6 +237,7 @@ func (handler *Handler) createCustomTemplateFromGitRepository(r *http.Request) (
 		Platform:    payload.Platform,
 		Type:        payload.Type,
 		Logo:        payload.Logo,
+		Parameters:  payload.Parameters,
 	}
 
 	projectPath := handler.FileService.GetCustomTemplateProjectPath(strconv.Itoa(customTemplateID))
@@ -261,6 +268,7 @@ type customTemplateFromFileUploadPayload struct {
 	Platform    portainer.CustomTemplatePlatform
 	Type        portainer.StackType
 	FileContent []byte
+	Parameters  []portainer.CustomTemplateParameter
 }
 
 func (payload *customTemplateFromFileUploadPayload) Validate(r *http.Request) error {
@@ -300,7 +308,14 @@ func (payload *customTemplateFromFileUploadPayload) Validate(r *http.Request) er
 	}
 	payload.FileContent = composeFileContent
 
-	return nil
+	var parameters []portainer.CustomTemplateParameter
+	err = request.RetrieveMultiPartFormJSONValue(r, "Parameters", &parameters, true)
+	if err != nil {
+		return errors.New("Invalid Parameters parameter")
+	}
+	payload.Parameters = parameters
+
+	return validateParameters(payload.Parameters)
 }
 
 func (handler *Handler) createCustomTemplateFromFileUpload(r *http.Request) (*portainer.CustomTemplate, error) {
@@ -320,6 +335,7 @@ func (handler *Handler) createCustomTemplateFromFileUpload(r *http.Request) (*po
 		Type:        payload.Type,
 		Logo:        payload.Logo,
 		EntryPoint:  filesystem.ComposeFileDefaultName,
+		Parameters:  payload.Parameters,
 	}
 
 	templateFolder := strconv.Itoa(customTemplateID)
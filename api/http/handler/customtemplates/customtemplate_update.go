@@ -32,6 +32,8 @@ type customTemplateUpdatePayload struct {
 	Type portainer.StackType `example:"1" enums:"1,2" validate:"required"`
 	// Content of stack file
 	FileContent string `validate:"required"`
+	// Typed parameters of the template. The existing parameters are kept when not specified
+	Parameters []portainer.CustomTemplateParameter
 }
 
 func (payload *customTemplateUpdatePayload) Validate(r *http.Request) error {
@@ -50,7 +52,7 @@ func (payload *customTemplateUpdatePayload) Validate(r *http.Request) error {
 	if govalidator.IsNull(payload.Description) {
 		return errors.New("Invalid custom template description")
 	}
-	return nil
+	return validateParameters(payload.Parameters)
 }
 
 // @id CustomTemplateUpdate
@@ -121,6 +123,9 @@ func (handler *Handler) customTemplateUpdate(w http.ResponseWriter, r *http.Requ
 	customTemplate.Note = payload.Note
 	customTemplate.Platform = payload.Platform
 	customTemplate.Type = payload.Type
+	if payload.Parameters != nil {
+		customTemplate.Parameters = payload.Parameters
+	}
 
 	err = handler.DataStore.CustomTemplate().UpdateCustomTemplate(customTemplate.ID, customTemplate)
 	if err != nil {
@@ -29,6 +29,8 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.customTemplateList))).Methods(http.MethodGet)
 	h.Handle("/custom_templates/{id}",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.customTemplateInspect))).Methods(http.MethodGet)
+	h.Handle("/custom_templates/{id}/clone",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.customTemplateClone))).Methods(http.MethodPost)
 	h.Handle("/custom_templates/{id}/file",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.customTemplateFile))).Methods(http.MethodGet)
 	h.Handle("/custom_templates/{id}",
@@ -0,0 +1,80 @@
+package customtemplates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+var parameterNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// validateParameters verifies that the parameters have unique environment variable names, a supported type
+// and a default value matching their type
+func validateParameters(parameters []portainer.CustomTemplateParameter) error {
+	names := make(map[string]bool, len(parameters))
+	for _, parameter := range parameters {
+		if !parameterNameRe.MatchString(parameter.Name) {
+			return fmt.Errorf("Invalid custom template parameter name %s. Must be a valid environment variable name", parameter.Name)
+		}
+		if names[parameter.Name] {
+			return fmt.Errorf("Invalid custom template parameter %s. Names must be unique", parameter.Name)
+		}
+		names[parameter.Name] = true
+
+		err := validateParameterValue(&parameter, parameter.DefaultValue)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateParameterValue verifies that a value matches the type of the parameter, an empty value is always valid
+func validateParameterValue(parameter *portainer.CustomTemplateParameter, value string) error {
+	switch parameter.Type {
+	case portainer.CustomTemplateParameterTypeString:
+		return nil
+	case portainer.CustomTemplateParameterTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); value != "" && err != nil {
+			return fmt.Errorf("Invalid value for custom template parameter %s. Value must be a number", parameter.Name)
+		}
+	case portainer.CustomTemplateParameterTypeBoolean:
+		if value != "" && value != "true" && value != "false" {
+			return fmt.Errorf("Invalid value for custom template parameter %s. Value must be true or false", parameter.Name)
+		}
+	default:
+		return fmt.Errorf("Invalid type for custom template parameter %s. Type must be one of: string, number or boolean", parameter.Name)
+	}
+	return nil
+}
+
+// overrideParameterDefaults returns a copy of the parameters where the default values are replaced by the overrides,
+// per parameter name. Overrides of unknown parameters are rejected.
+func overrideParameterDefaults(parameters []portainer.CustomTemplateParameter, overrides map[string]string) ([]portainer.CustomTemplateParameter, error) {
+	result := make([]portainer.CustomTemplateParameter, len(parameters))
+	copy(result, parameters)
+
+	for name, value := range overrides {
+		found := false
+		for idx := range result {
+			if result[idx].Name != name {
+				continue
+			}
+
+			err := validateParameterValue(&result[idx], value)
+			if err != nil {
+				return nil, err
+			}
+			result[idx].DefaultValue = value
+			found = true
+		}
+
+		if !found {
+			return nil, fmt.Errorf("Invalid parameter default override. The template has no parameter named %s", name)
+		}
+	}
+
+	return result, nil
+}
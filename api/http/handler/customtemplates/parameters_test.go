@@ -0,0 +1,31 @@
+package customtemplates
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_overrideParameterDefaults(t *testing.T) {
+	parameters := []portainer.CustomTemplateParameter{
+		{Name: "REPLICAS", Type: portainer.CustomTemplateParameterTypeNumber, DefaultValue: "3"},
+		{Name: "DEBUG", Type: portainer.CustomTemplateParameterTypeBoolean, DefaultValue: "false"},
+	}
+	assert.NoError(t, validateParameters(parameters))
+
+	overridden, err := overrideParameterDefaults(parameters, map[string]string{"REPLICAS": "1", "DEBUG": "true"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", overridden[0].DefaultValue)
+	assert.Equal(t, "true", overridden[1].DefaultValue)
+	assert.Equal(t, "3", parameters[0].DefaultValue)
+
+	_, err = overrideParameterDefaults(parameters, map[string]string{"REPLICAS": "one"})
+	assert.Error(t, err)
+
+	_, err = overrideParameterDefaults(parameters, map[string]string{"MISSING": "1"})
+	assert.Error(t, err)
+
+	assert.Error(t, validateParameters([]portainer.CustomTemplateParameter{{Name: "1INVALID", Type: portainer.CustomTemplateParameterTypeString}}))
+	assert.Error(t, validateParameters([]portainer.CustomTemplateParameter{{Name: "VALUE", Type: "date"}}))
+}
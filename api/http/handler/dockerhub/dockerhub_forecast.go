@@ -0,0 +1,25 @@
+package dockerhub
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id DockerHubForecast
+// @summary Forecast the exhaustion of the DockerHub pull rate limit
+// @description Compute the recent consumption rate of the DockerHub pull rate limit from the samples recorded by Portainer,
+// @description and the estimated time until the remaining pulls are exhausted at this rate.
+// @description The confidence of the forecast is lowered when the samples are sparse or outdated.
+// @description No rate is computed when no limit applies to the DockerHub account.
+// @description **Access policy**: authenticated
+// @tags dockerhub
+// @security jwt
+// @produce json
+// @success 200 {object} portainer.DockerHubRateLimitForecast "Success"
+// @failure 500 "Server error"
+// @router /dockerhub/forecast [get]
+func (handler *Handler) dockerhubForecast(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return response.JSON(w, handler.RateLimitService.Forecast())
+}
@@ -7,6 +7,7 @@ import (
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/dockerhublimit"
 )
 
 func hideFields(dockerHub *portainer.DockerHub) {
@@ -16,7 +17,8 @@ func hideFields(dockerHub *portainer.DockerHub) {
 // Handler is the HTTP handler used to handle DockerHub operations.
 type Handler struct {
 	*mux.Router
-	DataStore portainer.DataStore
+	DataStore        portainer.DataStore
+	RateLimitService *dockerhublimit.Service
 }
 
 // NewHandler creates a handler to manage Dockerhub operations.
@@ -28,6 +30,8 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.dockerhubInspect))).Methods(http.MethodGet)
 	h.Handle("/dockerhub",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.dockerhubUpdate))).Methods(http.MethodPut)
+	h.Handle("/dockerhub/forecast",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.dockerhubForecast))).Methods(http.MethodGet)
 
 	return h
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/containerdefaults"
 )
 
 type endpointGroupCreatePayload struct {
@@ -20,6 +21,8 @@ type endpointGroupCreatePayload struct {
 	AssociatedEndpoints []portainer.EndpointID `example:"1,3"`
 	// List of tag identifiers to which this endpoint group is associated
 	TagIDs []portainer.TagID `example:"1,2"`
+	// Ulimits and sysctls applied to the containers created on the endpoints of the group when not explicitly specified
+	ContainerDefaults *portainer.EndpointGroupContainerDefaults
 }
 
 func (payload *endpointGroupCreatePayload) Validate(r *http.Request) error {
@@ -29,11 +32,19 @@ func (payload *endpointGroupCreatePayload) Validate(r *http.Request) error {
 	if payload.TagIDs == nil {
 		payload.TagIDs = []portainer.TagID{}
 	}
+	if payload.ContainerDefaults != nil {
+		err := containerdefaults.Validate(payload.ContainerDefaults)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // @summary Create an Endpoint Group
 // @description Create a new endpoint group.
+// @description The container defaults of the group are applied to the containers and Compose stacks created on its endpoints
+// @description when not explicitly specified, the effective values are visible in the HostConfig of the container inspect.
 // @description **Access policy**: administrator
 // @tags endpoint_groups
 // @security jwt
@@ -59,6 +70,10 @@ func (handler *Handler) endpointGroupCreate(w http.ResponseWriter, r *http.Reque
 		TagIDs:             payload.TagIDs,
 	}
 
+	if payload.ContainerDefaults != nil {
+		endpointGroup.ContainerDefaults = *payload.ContainerDefaults
+	}
+
 	err = handler.DataStore.EndpointGroup().CreateEndpointGroup(endpointGroup)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the endpoint group inside the database", err}
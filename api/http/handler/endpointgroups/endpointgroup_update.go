@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/containerdefaults"
 	"github.com/portainer/portainer/api/internal/tag"
 )
 
@@ -21,15 +22,22 @@ type endpointGroupUpdatePayload struct {
 	TagIDs             []portainer.TagID `example:"3,4"`
 	UserAccessPolicies portainer.UserAccessPolicies
 	TeamAccessPolicies portainer.TeamAccessPolicies
+	// Ulimits and sysctls applied to the containers created on the endpoints of the group when not explicitly specified
+	ContainerDefaults *portainer.EndpointGroupContainerDefaults
 }
 
 func (payload *endpointGroupUpdatePayload) Validate(r *http.Request) error {
+	if payload.ContainerDefaults != nil {
+		return containerdefaults.Validate(payload.ContainerDefaults)
+	}
 	return nil
 }
 
 // @id EndpointGroupUpdate
 // @summary Update an endpoint group
 // @description Update an endpoint group.
+// @description The container defaults only apply to the containers created after the update,
+// @description the effective values are visible in the HostConfig of the container inspect.
 // @description **Access policy**: administrator
 // @tags endpoint_groups
 // @security jwt
@@ -117,6 +125,10 @@ func (handler *Handler) endpointGroupUpdate(w http.ResponseWriter, r *http.Reque
 		endpointGroup.TeamAccessPolicies = payload.TeamAccessPolicies
 	}
 
+	if payload.ContainerDefaults != nil {
+		endpointGroup.ContainerDefaults = *payload.ContainerDefaults
+	}
+
 	err = handler.DataStore.EndpointGroup().UpdateEndpointGroup(endpointGroup.ID, endpointGroup)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist endpoint group changes inside the database", err}
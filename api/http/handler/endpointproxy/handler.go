@@ -6,6 +6,8 @@ import (
 	"github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/concurrency"
+	"github.com/portainer/portainer/api/internal/streams"
 )
 
 // Handler is the HTTP handler used to proxy requests to external APIs.
@@ -15,6 +17,8 @@ type Handler struct {
 	requestBouncer       *security.RequestBouncer
 	ProxyManager         *proxy.Manager
 	ReverseTunnelService portainer.ReverseTunnelService
+	StreamLimiter        *streams.Limiter
+	ConcurrencyLimiter   *concurrency.Limiter
 }
 
 // NewHandler creates a handler to proxy requests to external APIs.
@@ -3,12 +3,16 @@ package endpointproxy
 import (
 	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/concurrency"
+	"github.com/portainer/portainer/api/internal/streams"
 
 	"net/http"
 )
@@ -65,6 +69,48 @@ func (handler *Handler) proxyRequestsToDockerAPI(w http.ResponseWriter, r *http.
 	}
 
 	id := strconv.Itoa(endpointID)
-	http.StripPrefix("/"+id+"/docker", proxy).ServeHTTP(w, r)
+	prefix := "/" + id + "/docker"
+
+	streamType := streams.DockerStreamType(r, strings.TrimPrefix(r.URL.Path, prefix))
+	if streamType == streams.NotAStream {
+		release, err := handler.ConcurrencyLimiter.Acquire(r.Context(), endpoint.ID, &endpoint.RequestConcurrency)
+		if err == concurrency.ErrQueueTimeout {
+			security.WriteRateLimitHeaders(w, security.RateLimit{Limit: endpoint.RequestConcurrency.MaxConcurrentRequests, Reset: time.Second})
+			return &httperror.HandlerError{http.StatusServiceUnavailable, "Unable to proxy the request to the endpoint", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusServiceUnavailable, "Request cancelled while waiting to be proxied to the endpoint", err}
+		}
+		defer release()
+
+		http.StripPrefix(prefix, proxy).ServeHTTP(w, r)
+		return nil
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve settings from the database", err}
+	}
+
+	release, err := handler.StreamLimiter.Acquire(endpoint.ID, &settings.StreamSettings)
+	if err != nil {
+		security.WriteRateLimitHeaders(w, streamRateLimit(&settings.StreamSettings, err))
+		return &httperror.HandlerError{http.StatusTooManyRequests, "Unable to open a new stream", err}
+	}
+	defer release()
+
+	streamWriter := streams.NewWriterFromSettings(w, &settings.StreamSettings, streamType.Coalescable())
+	defer streamWriter.Close()
+
+	http.StripPrefix(prefix, proxy).ServeHTTP(streamWriter, r)
 	return nil
 }
+
+// streamRateLimit returns the stream limit that rejected a stream. A slot can be released at any time,
+// the clients are told to retry after a second.
+func streamRateLimit(settings *portainer.StreamSettings, err error) security.RateLimit {
+	limit := settings.MaxConcurrentStreams
+	if err == streams.ErrEndpointLimitReached {
+		limit = settings.MaxConcurrentStreamsPerEndpoint
+	}
+	return security.RateLimit{Limit: limit, Reset: time.Second}
+}
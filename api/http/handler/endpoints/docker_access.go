@@ -0,0 +1,132 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+const (
+	containerLabelForDockerSwarmStackName   = "com.docker.stack.namespace"
+	containerLabelForDockerServiceID        = "com.docker.swarm.service.id"
+	containerLabelForDockerComposeStackName = "com.docker.compose.project"
+)
+
+// inspectAuthorizedContainer retrieves a container from the endpoint and verifies that the user
+// associated to the request can access it, based on the container resource control or
+// the resource control inherited from its service or stack.
+// A nil container is returned when the user is not authorized to access the container.
+func (handler *Handler) inspectAuthorizedContainer(r *http.Request, dockerClient *client.Client, endpoint *portainer.Endpoint, containerID string) (*types.ContainerJSON, error) {
+	container, err := dockerClient.ContainerInspect(context.Background(), containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if securityContext.IsAdmin {
+		return &container, nil
+	}
+
+	resourceControls, err := handler.DataStore.ResourceControl().ResourceControls()
+	if err != nil {
+		return nil, err
+	}
+
+	resourceControl := findContainerResourceControl(endpoint.ID, &container, resourceControls)
+	if resourceControl == nil {
+		return nil, nil
+	}
+
+	if !authorization.UserCanAccessResource(securityContext.UserID, teamIDs(securityContext), resourceControl) {
+		return nil, nil
+	}
+
+	return &container, nil
+}
+
+func findContainerResourceControl(endpointID portainer.EndpointID, container *types.ContainerJSON, resourceControls []portainer.ResourceControl) *portainer.ResourceControl {
+	resourceControl := authorization.GetResourceControlByResourceIDAndType(container.ID, portainer.ContainerResourceControl, resourceControls)
+	if resourceControl != nil || container.Config == nil {
+		return resourceControl
+	}
+
+	labels := container.Config.Labels
+
+	if labels[containerLabelForDockerServiceID] != "" {
+		resourceControl = authorization.GetResourceControlByResourceIDAndType(labels[containerLabelForDockerServiceID], portainer.ServiceResourceControl, resourceControls)
+		if resourceControl != nil {
+			return resourceControl
+		}
+	}
+
+	if labels[containerLabelForDockerSwarmStackName] != "" {
+		stackResourceID := stackutils.ResourceControlID(endpointID, labels[containerLabelForDockerSwarmStackName])
+		resourceControl = authorization.GetResourceControlByResourceIDAndType(stackResourceID, portainer.StackResourceControl, resourceControls)
+		if resourceControl != nil {
+			return resourceControl
+		}
+	}
+
+	if labels[containerLabelForDockerComposeStackName] != "" {
+		stackResourceID := stackutils.ResourceControlID(endpointID, labels[containerLabelForDockerComposeStackName])
+		return authorization.GetResourceControlByResourceIDAndType(stackResourceID, portainer.StackResourceControl, resourceControls)
+	}
+
+	return nil
+}
+
+// inspectAuthorizedService retrieves a service from the endpoint and verifies that the user
+// associated to the request can access it, based on the service resource control or
+// the resource control inherited from its stack.
+// A nil service is returned when the user is not authorized to access the service.
+func (handler *Handler) inspectAuthorizedService(r *http.Request, dockerClient *client.Client, endpoint *portainer.Endpoint, serviceID string) (*swarm.Service, error) {
+	service, _, err := dockerClient.ServiceInspectWithRaw(context.Background(), serviceID, types.ServiceInspectOptions{InsertDefaults: true})
+	if err != nil {
+		return nil, err
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if securityContext.IsAdmin {
+		return &service, nil
+	}
+
+	resourceControls, err := handler.DataStore.ResourceControl().ResourceControls()
+	if err != nil {
+		return nil, err
+	}
+
+	resourceControl := authorization.GetResourceControlByResourceIDAndType(service.ID, portainer.ServiceResourceControl, resourceControls)
+	if resourceControl == nil && service.Spec.Labels[containerLabelForDockerSwarmStackName] != "" {
+		stackResourceID := stackutils.ResourceControlID(endpoint.ID, service.Spec.Labels[containerLabelForDockerSwarmStackName])
+		resourceControl = authorization.GetResourceControlByResourceIDAndType(stackResourceID, portainer.StackResourceControl, resourceControls)
+	}
+
+	if resourceControl == nil || !authorization.UserCanAccessResource(securityContext.UserID, teamIDs(securityContext), resourceControl) {
+		return nil, nil
+	}
+
+	return &service, nil
+}
+
+func teamIDs(securityContext *security.RestrictedRequestContext) []portainer.TeamID {
+	userTeamIDs := make([]portainer.TeamID, 0)
+	for _, membership := range securityContext.UserMemberships {
+		userTeamIDs = append(userTeamIDs, membership.TeamID)
+	}
+	return userTeamIDs
+}
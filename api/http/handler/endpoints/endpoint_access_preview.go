@@ -0,0 +1,100 @@
+package endpoints
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+type (
+	endpointAccessPreviewResponse struct {
+		UserID   portainer.UserID `json:"UserId" example:"2"`
+		Username string           `json:"Username" example:"bob"`
+		// Endpoints the user can access
+		Endpoints []endpointAccess `json:"Endpoints"`
+	}
+
+	endpointAccess struct {
+		EndpointID   portainer.EndpointID      `json:"EndpointId" example:"1"`
+		EndpointName string                    `json:"EndpointName" example:"my-endpoint"`
+		GroupID      portainer.EndpointGroupID `json:"GroupId" example:"1"`
+		// Access policies granting the user access to the endpoint
+		Paths []security.EndpointAccessPath `json:"Paths"`
+	}
+)
+
+// @id EndpointAccessPreview
+// @summary Preview the endpoints a user can access
+// @description List the endpoints a user can access and the access policies granting the access: a policy of the user or of one of
+// @description their teams, on the endpoint or on its group, or the administrator role. The access is resolved the same way as when the user sends a request.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param userId query int true "User identifier"
+// @success 200 {object} endpointAccessPreviewResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /endpoints/access-preview [get]
+func (handler *Handler) endpointAccessPreview(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	userID, err := request.RetrieveNumericQueryParameter(r, "userId", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: userId", err}
+	}
+
+	user, err := handler.DataStore.User().User(portainer.UserID(userID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a user with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a user with the specified identifier inside the database", err}
+	}
+
+	memberships, err := handler.DataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the team memberships of the user from the database", err}
+	}
+
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from the database", err}
+	}
+
+	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoint groups from the database", err}
+	}
+
+	groups := make(map[portainer.EndpointGroupID]*portainer.EndpointGroup)
+	for idx := range endpointGroups {
+		groups[endpointGroups[idx].ID] = &endpointGroups[idx]
+	}
+
+	accesses := make([]endpointAccess, 0)
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+
+		paths := security.EndpointAccessPaths(endpoint, groups[endpoint.GroupID], user, memberships)
+		if len(paths) == 0 {
+			continue
+		}
+
+		accesses = append(accesses, endpointAccess{
+			EndpointID:   endpoint.ID,
+			EndpointName: endpoint.Name,
+			GroupID:      endpoint.GroupID,
+			Paths:        paths,
+		})
+	}
+
+	return response.JSON(w, &endpointAccessPreviewResponse{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Endpoints: accesses,
+	})
+}
@@ -0,0 +1,30 @@
+package endpoints
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/internal/clockskew"
+)
+
+// @id EndpointClockSkewList
+// @summary Summarize the endpoints with a clock skew
+// @description List the endpoints whose system time, measured during their last snapshot, differs from the Portainer host clock
+// @description by more than their clock skew threshold, the largest skew first. A clock skew breaks the TLS handshakes and the log timestamps.
+// @description The system time is only available on Docker endpoints.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security jwt
+// @produce json
+// @success 200 {object} clockskew.Summary "Success"
+// @failure 500 "Server error"
+// @router /endpoints/clock-skew [get]
+func (handler *Handler) endpointClockSkewList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from the database", err}
+	}
+
+	return response.JSON(w, clockskew.Summarize(endpoints))
+}
@@ -0,0 +1,44 @@
+package endpoints
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id EndpointConcurrencyInspect
+// @summary Inspect the concurrent requests of an endpoint
+// @description Retrieve the number of Docker API requests currently proxied to an endpoint and the number of requests
+// @description waiting for a slot, to help tuning the request concurrency limit of the endpoint.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @success 200 {object} concurrency.Stats "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/concurrency [get]
+func (handler *Handler) endpointConcurrencyInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	stats := handler.ConcurrencyLimiter.Stats(endpoint.ID)
+	stats.MaxConcurrentRequests = endpoint.RequestConcurrency.MaxConcurrentRequests
+
+	return response.JSON(w, stats)
+}
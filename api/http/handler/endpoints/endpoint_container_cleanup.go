@@ -0,0 +1,69 @@
+package endpoints
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/containercleanup"
+)
+
+// @id EndpointContainerCleanupPreview
+// @summary Preview the container cleanup of an endpoint
+// @description List the containers of a Docker endpoint that the container cleanup policy removes, without removing them.
+// @description Containers are removed when they are exited for longer than the maximum age of the policy and match its label filter.
+// @description Containers with a restart policy and containers managed by a Swarm service are never removed.
+// @description The maximum age and the label filter of the policy of the endpoint can be overridden to preview a policy before enabling it.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param maxAge query string false "Maximum age overriding the policy of the endpoint, e.g. 24h"
+// @param labelFilter query string false "Label filter overriding the policy of the endpoint, using the key or key=value format"
+// @success 200 {array} containercleanup.Candidate "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/cleanup [get]
+func (handler *Handler) endpointContainerCleanupPreview(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	policy := endpoint.ContainerCleanupPolicy
+	policy.Enabled = true
+
+	maxAge, _ := request.RetrieveQueryParameter(r, "maxAge", true)
+	if maxAge != "" {
+		policy.MaxAge = maxAge
+	}
+
+	labelFilter, _ := request.RetrieveQueryParameter(r, "labelFilter", true)
+	if labelFilter != "" {
+		policy.LabelFilter = labelFilter
+	}
+
+	err = containercleanup.ValidatePolicy(&policy)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, err.Error(), err}
+	}
+
+	candidates, err := handler.ContainerCleanupService.Candidates(endpoint, &policy)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the exited containers", err}
+	}
+
+	return response.JSON(w, candidates)
+}
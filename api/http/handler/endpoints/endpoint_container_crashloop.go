@@ -0,0 +1,95 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/crashloop"
+)
+
+// @id EndpointContainerCrashLoopList
+// @summary List the crash-looping containers of an endpoint
+// @description List the containers of a Docker endpoint that exited more often than the restart threshold of their crash-loop policy
+// @description within the window of the policy. The policy of the stack of a container takes precedence over the policy of the settings.
+// @description The exits are only tracked when the crash-loop detection is enabled in the settings.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @success 200 {array} crashloop.Offender "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/crashlooping [get]
+func (handler *Handler) endpointContainerCrashLoopList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	offenders, err := handler.CrashLoopService.Offenders(endpoint.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the crash-looping containers", err}
+	}
+
+	offenders, err = handler.filterAuthorizedOffenders(r, endpoint, offenders)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to verify user authorizations to validate container access", err}
+	}
+
+	return response.JSON(w, offenders)
+}
+
+// filterAuthorizedOffenders removes the containers that the user associated to the request cannot access
+func (handler *Handler) filterAuthorizedOffenders(r *http.Request, endpoint *portainer.Endpoint, offenders []crashloop.Offender) ([]crashloop.Offender, error) {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if securityContext.IsAdmin {
+		return offenders, nil
+	}
+
+	resourceControls, err := handler.DataStore.ResourceControl().ResourceControls()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizedOffenders := make([]crashloop.Offender, 0, len(offenders))
+	for _, offender := range offenders {
+		containerJSON := &types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{ID: offender.ContainerID},
+			Config:            &container.Config{Labels: offender.Labels},
+		}
+
+		resourceControl := findContainerResourceControl(endpoint.ID, containerJSON, resourceControls)
+		if resourceControl != nil && authorization.UserCanAccessResource(securityContext.UserID, teamIDs(securityContext), resourceControl) {
+			authorizedOffenders = append(authorizedOffenders, offender)
+		}
+	}
+
+	return authorizedOffenders, nil
+}
@@ -0,0 +1,205 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/authorization"
+)
+
+const (
+	containerLabelsStatusPending   = "pending"
+	containerLabelsStatusRecreated = "recreated"
+	containerLabelsStatusUnchanged = "unchanged"
+	containerLabelsStatusFailed    = "failed"
+)
+
+type containerLabelsUpdatePayload struct {
+	// Docker container list filters used to select the containers to update
+	Filters map[string][]string `example:"label:[costcenter=42]"`
+	// Labels to add or update on the matching containers
+	Labels map[string]string `example:"costcenter:43"`
+	// Only list the containers that would be recreated, without recreating them
+	DryRun bool `example:"true"`
+}
+
+type containerLabelsUpdateResult struct {
+	// Identifier of the matching container
+	ContainerID string `json:"ContainerId" example:"1f2d2d3e4c5b"`
+	// Name of the matching container
+	Name string `json:"Name" example:"/web"`
+	// Identifier of the container created with the updated labels
+	NewContainerID string `json:"NewContainerId,omitempty" example:"7a8b9c0d1e2f"`
+	// Result of the operation: pending (dry-run), recreated, unchanged or failed
+	Status string `json:"Status" example:"recreated"`
+	// Reason of the failure
+	Error string `json:"Error,omitempty"`
+}
+
+func (payload *containerLabelsUpdatePayload) Validate(r *http.Request) error {
+	if len(payload.Filters) == 0 {
+		return errors.New("Invalid filters. At least one filter is required")
+	}
+	if len(payload.Labels) == 0 {
+		return errors.New("Invalid labels. At least one label is required")
+	}
+	for key := range payload.Labels {
+		if strings.TrimSpace(key) == "" {
+			return errors.New("Invalid labels. Label keys cannot be empty")
+		}
+	}
+	return nil
+}
+
+// @id EndpointContainerLabelsUpdate
+// @summary Update the labels of multiple containers
+// @description Add or update labels on the containers of a Docker endpoint matching the specified filters.
+// @description As Docker does not support updating the labels of an existing container, each matching container is recreated
+// @description with the same configuration and the merged labels. Use the dry-run option to list the containers that would be recreated.
+// @description Containers managed by a Swarm service cannot be recreated and are reported as failed.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param body body containerLabelsUpdatePayload true "Container filters and labels"
+// @success 200 {array} containerLabelsUpdateResult "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/labels [put]
+func (handler *Handler) endpointContainerLabelsUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	var payload containerLabelsUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+	defer dockerClient.Close()
+
+	containerFilters := filters.NewArgs()
+	for key, values := range payload.Filters {
+		for _, value := range values {
+			containerFilters.Add(key, value)
+		}
+	}
+
+	containers, err := dockerClient.ContainerList(context.Background(), types.ContainerListOptions{All: true, Filters: containerFilters})
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to list containers", err}
+	}
+
+	results := make([]containerLabelsUpdateResult, 0)
+	for _, listedContainer := range containers {
+		inspectedContainer, err := handler.inspectAuthorizedContainer(r, dockerClient, endpoint, listedContainer.ID)
+		if client.IsErrNotFound(err) || (err == nil && inspectedContainer == nil) {
+			continue
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect container", err}
+		}
+
+		result := containerLabelsUpdateResult{
+			ContainerID: inspectedContainer.ID,
+			Name:        inspectedContainer.Name,
+			Status:      containerLabelsStatusPending,
+		}
+
+		if inspectedContainer.Config != nil && labelsIncluded(inspectedContainer.Config.Labels, payload.Labels) {
+			result.Status = containerLabelsStatusUnchanged
+		} else if !payload.DryRun {
+			handler.recreateContainerWithLabels(dockerClient, &result, payload.Labels)
+		}
+
+		results = append(results, result)
+	}
+
+	return response.JSON(w, results)
+}
+
+func (handler *Handler) recreateContainerWithLabels(dockerClient *client.Client, result *containerLabelsUpdateResult, labels map[string]string) {
+	newContainerID, err := docker.RecreateContainer(dockerClient, result.ContainerID, func(config *container.Config, hostConfig *container.HostConfig) {
+		if config.Labels == nil {
+			config.Labels = map[string]string{}
+		}
+		for key, value := range labels {
+			config.Labels[key] = value
+		}
+	})
+	result.NewContainerID = newContainerID
+	if newContainerID != "" {
+		transferErr := handler.transferContainerResourceControl(result.ContainerID, newContainerID)
+		if err == nil {
+			err = transferErr
+		}
+	}
+
+	if err != nil {
+		result.Status = containerLabelsStatusFailed
+		result.Error = err.Error()
+		return
+	}
+
+	result.Status = containerLabelsStatusRecreated
+}
+
+// transferContainerResourceControl associates the resource control of a container to the container
+// that replaced it, preserving the ownership of recreated containers.
+func (handler *Handler) transferContainerResourceControl(oldContainerID, newContainerID string) error {
+	resourceControls, err := handler.DataStore.ResourceControl().ResourceControls()
+	if err != nil {
+		return err
+	}
+
+	resourceControl := authorization.GetResourceControlByResourceIDAndType(oldContainerID, portainer.ContainerResourceControl, resourceControls)
+	if resourceControl == nil {
+		return nil
+	}
+
+	resourceControl.ResourceID = newContainerID
+	return handler.DataStore.ResourceControl().UpdateResourceControl(resourceControl.ID, resourceControl)
+}
+
+func labelsIncluded(labels, expected map[string]string) bool {
+	for key, value := range expected {
+		current, ok := labels[key]
+		if !ok || current != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/internal/logdriver"
+)
+
+// @id EndpointContainerLoggingInspect
+// @summary Inspect the logging driver of a container
+// @description Retrieve the logging driver of a container of a Docker endpoint and the options of the driver.
+// @description The options holding credentials are redacted.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param containerId path string true "Container identifier"
+// @success 200 {object} portainer.LogConfig "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or container not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/{containerId}/logging [get]
+func (handler *Handler) endpointContainerLoggingInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	containerID, err := request.RetrieveRouteVariableValue(r, "containerId")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid container identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+	defer dockerClient.Close()
+
+	container, err := handler.inspectAuthorizedContainer(r, dockerClient, endpoint, containerID)
+	if client.IsErrNotFound(err) {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a container with the specified identifier", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect container", err}
+	}
+
+	if container == nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	logConfig := portainer.LogConfig{}
+	if container.HostConfig != nil {
+		logConfig.Driver = container.HostConfig.LogConfig.Type
+		logConfig.Options = container.HostConfig.LogConfig.Config
+	}
+
+	return response.JSON(w, logdriver.Redact(logConfig))
+}
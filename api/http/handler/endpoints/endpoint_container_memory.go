@@ -0,0 +1,234 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/docker"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+)
+
+type containerMemoryUpdatePayload struct {
+	// Memory limit in bytes, 0 for unbounded. The current value is kept when not specified
+	Memory *int64 `example:"536870912"`
+	// Total memory limit (memory and swap) in bytes, 0 for twice the memory limit and -1 for unbounded swap.
+	// The current value is kept when not specified
+	MemorySwap *int64 `example:"1073741824"`
+	// Memory soft limit in bytes, 0 for none. The current value is kept when not specified
+	MemoryReservation *int64 `example:"268435456"`
+	// Disable the OOM-killer for the container. The current value is kept when not specified
+	OomKillDisable *bool `example:"false"`
+	// Preference of the OOM-killer for the container, between -1000 and 1000. The current value is kept when not specified
+	OomScoreAdj *int `example:"-500"`
+}
+
+func (payload *containerMemoryUpdatePayload) Validate(r *http.Request) error {
+	return nil
+}
+
+type containerMemoryResponse struct {
+	// Identifier of the container
+	ContainerID string `json:"ContainerId" example:"1f2d2d3e4c5b"`
+	// Memory settings of the container
+	Settings *portainer.ContainerMemorySettings `json:"Settings"`
+	// Warnings associated to the memory settings, e.g. the OOM-killer disabled without a memory limit
+	Warnings []string `json:"Warnings"`
+}
+
+// @id EndpointContainerMemoryInspect
+// @summary Inspect the memory settings of a container
+// @description Retrieve the memory limits and the OOM-killer settings of a container of a Docker endpoint.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param containerId path string true "Container identifier"
+// @success 200 {object} containerMemoryResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or container not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/{containerId}/memory [get]
+func (handler *Handler) endpointContainerMemoryInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	dockerClient, inspectedContainer, handlerErr := handler.memoryContainerFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer dockerClient.Close()
+
+	settings := docker.MemorySettingsFromHostConfig(inspectedContainer.HostConfig)
+
+	return response.JSON(w, &containerMemoryResponse{
+		ContainerID: inspectedContainer.ID,
+		Settings:    settings,
+		Warnings:    docker.MemorySettingsWarnings(settings),
+	})
+}
+
+// @id EndpointContainerMemoryUpdate
+// @summary Update the memory settings of a container
+// @description Update the memory limits and the OOM-killer settings of a container of a Docker endpoint.
+// @description The memory limits are updated in place. As Docker does not support updating the OOM-killer settings of an existing container,
+// @description nor removing a memory limit, the container is recreated with the same configuration and the updated settings
+// @description in these cases, the identifier of the new container is returned.
+// @description The memory swap limit must be greater than or equal to the memory limit when both are set.
+// @description A warning is returned when the OOM-killer is disabled without a memory limit.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param containerId path string true "Container identifier"
+// @param body body containerMemoryUpdatePayload true "Memory settings"
+// @success 200 {object} containerMemoryResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or container not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/{containerId}/memory [put]
+func (handler *Handler) endpointContainerMemoryUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload containerMemoryUpdatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	dockerClient, inspectedContainer, handlerErr := handler.memoryContainerFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer dockerClient.Close()
+
+	current := docker.MemorySettingsFromHostConfig(inspectedContainer.HostConfig)
+	settings := *current
+	if payload.Memory != nil {
+		settings.Memory = *payload.Memory
+	}
+	if payload.MemorySwap != nil {
+		settings.MemorySwap = *payload.MemorySwap
+	}
+	if payload.MemoryReservation != nil {
+		settings.MemoryReservation = *payload.MemoryReservation
+	}
+	if payload.OomKillDisable != nil {
+		settings.OomKillDisable = *payload.OomKillDisable
+	}
+	if payload.OomScoreAdj != nil {
+		settings.OomScoreAdj = *payload.OomScoreAdj
+	}
+
+	err = docker.ValidateMemorySettings(&settings)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, err.Error(), err}
+	}
+
+	newContainerID, err := updateContainerMemory(dockerClient, inspectedContainer.ID, current, &settings)
+	if newContainerID != "" && newContainerID != inspectedContainer.ID {
+		transferErr := handler.transferContainerResourceControl(inspectedContainer.ID, newContainerID)
+		if err == nil {
+			err = transferErr
+		}
+	}
+	if err == docker.ErrServiceTaskContainer {
+		return &httperror.HandlerError{http.StatusBadRequest, err.Error(), err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to update the memory settings of the container", err}
+	}
+
+	return response.JSON(w, &containerMemoryResponse{
+		ContainerID: newContainerID,
+		Settings:    &settings,
+		Warnings:    docker.MemorySettingsWarnings(&settings),
+	})
+}
+
+// updateContainerMemory applies the memory settings to a container, through a container update when possible
+// or by recreating the container otherwise. It returns the identifier of the container, which changes when the
+// container is recreated.
+func updateContainerMemory(dockerClient *client.Client, containerID string, current, settings *portainer.ContainerMemorySettings) (string, error) {
+	if memoryUpdateRequiresRecreation(current, settings) {
+		return docker.RecreateContainer(dockerClient, containerID, func(config *container.Config, hostConfig *container.HostConfig) {
+			docker.ApplyMemorySettings(hostConfig, settings)
+		})
+	}
+
+	_, err := dockerClient.ContainerUpdate(context.Background(), containerID, container.UpdateConfig{
+		Resources: container.Resources{
+			Memory:            settings.Memory,
+			MemorySwap:        settings.MemorySwap,
+			MemoryReservation: settings.MemoryReservation,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return containerID, nil
+}
+
+// memoryUpdateRequiresRecreation returns whether the memory settings cannot be applied through a container update:
+// Docker does not support updating the OOM-killer settings of a container and ignores the limits set to 0 on update
+func memoryUpdateRequiresRecreation(current, settings *portainer.ContainerMemorySettings) bool {
+	if settings.OomKillDisable != current.OomKillDisable || settings.OomScoreAdj != current.OomScoreAdj {
+		return true
+	}
+
+	return (settings.Memory == 0 && current.Memory != 0) ||
+		(settings.MemorySwap == 0 && current.MemorySwap != 0) ||
+		(settings.MemoryReservation == 0 && current.MemoryReservation != 0)
+}
+
+func (handler *Handler) memoryContainerFromRequest(r *http.Request) (*client.Client, *types.ContainerJSON, *httperror.HandlerError) {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	containerID, err := request.RetrieveRouteVariableValue(r, "containerId")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid container identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+
+	inspectedContainer, err := handler.inspectAuthorizedContainer(r, dockerClient, endpoint, containerID)
+	if client.IsErrNotFound(err) {
+		dockerClient.Close()
+		return nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a container with the specified identifier", err}
+	} else if err != nil {
+		dockerClient.Close()
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect container", err}
+	}
+
+	if inspectedContainer == nil {
+		dockerClient.Close()
+		return nil, nil, &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	return dockerClient, inspectedContainer, nil
+}
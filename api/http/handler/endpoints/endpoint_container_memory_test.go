@@ -0,0 +1,67 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_memoryUpdateRequiresRecreation(t *testing.T) {
+	current := &portainer.ContainerMemorySettings{Memory: 512, MemorySwap: 1024, MemoryReservation: 256, OomScoreAdj: 0}
+
+	assert.False(t, memoryUpdateRequiresRecreation(current, &portainer.ContainerMemorySettings{Memory: 1024, MemorySwap: 2048, MemoryReservation: 256}))
+	assert.True(t, memoryUpdateRequiresRecreation(current, &portainer.ContainerMemorySettings{Memory: 512, MemorySwap: 1024, MemoryReservation: 256, OomKillDisable: true}))
+	assert.True(t, memoryUpdateRequiresRecreation(current, &portainer.ContainerMemorySettings{Memory: 512, MemorySwap: 1024, MemoryReservation: 256, OomScoreAdj: -500}))
+	assert.True(t, memoryUpdateRequiresRecreation(current, &portainer.ContainerMemorySettings{Memory: 0, MemorySwap: 1024, MemoryReservation: 256}), "limits cannot be removed through an update")
+}
+
+func Test_updateContainerMemory_shouldUpdateTheLimitsInPlace(t *testing.T) {
+	var paths []string
+	var update container.UpdateConfig
+
+	dockerClient, err := docker.CreateHandlerClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/containers/abc/update") {
+			json.NewDecoder(r.Body).Decode(&update)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Warnings":[]}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	assert.NoError(t, err)
+
+	current := &portainer.ContainerMemorySettings{Memory: 512, MemorySwap: 1024}
+	settings := &portainer.ContainerMemorySettings{Memory: 1024, MemorySwap: 2048, MemoryReservation: 256}
+
+	containerID, err := updateContainerMemory(dockerClient, "abc", current, settings)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", containerID, "the container is not recreated")
+	assert.Equal(t, []string{"POST /v1.37/containers/abc/update"}, paths)
+	assert.Equal(t, int64(1024), update.Memory)
+	assert.Equal(t, int64(2048), update.MemorySwap)
+	assert.Equal(t, int64(256), update.MemoryReservation)
+}
+
+func Test_updateContainerMemory_shouldRecreateTheContainerForTheOOMKillerSettings(t *testing.T) {
+	var paths []string
+
+	dockerClient, err := docker.CreateHandlerClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	assert.NoError(t, err)
+
+	current := &portainer.ContainerMemorySettings{Memory: 512}
+	settings := &portainer.ContainerMemorySettings{Memory: 512, OomKillDisable: true}
+
+	_, err = updateContainerMemory(dockerClient, "abc", current, settings)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"GET /v1.37/containers/abc/json"}, paths, "the container is inspected to be recreated")
+}
@@ -0,0 +1,402 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+)
+
+var (
+	errNetworkNotUserDefined  = errors.New("Static IP addresses can only be assigned on user-defined networks")
+	errContainerNotConnected  = errors.New("Container is not connected to the network")
+	errIPAddressOutOfSubnet   = errors.New("IP address is not part of the subnets of the network")
+	errIPAddressInUse         = errors.New("IP address is already in use on the network")
+	errIPAddressIsGateway     = errors.New("IP address is the gateway of the network")
+	errStaticIPAddressMissing = errors.New("At least one of IPv4Address or IPv6Address must be specified")
+)
+
+type containerNetwork struct {
+	// Network identifier
+	NetworkID string `json:"NetworkId" example:"a4b8ba0d5d3f"`
+	// Network name
+	NetworkName string `json:"NetworkName" example:"backend"`
+	// Static IPv4 address reserved for the container, empty when the address is assigned dynamically
+	IPv4Address string `json:"IPv4Address" example:"172.20.0.10"`
+	// Static IPv6 address reserved for the container, empty when the address is assigned dynamically
+	IPv6Address string `json:"IPv6Address" example:""`
+	// IPv4 address currently assigned to the container
+	IPAddress string `json:"IPAddress" example:"172.20.0.10"`
+	// IPv6 address currently assigned to the container
+	GlobalIPv6Address string `json:"GlobalIPv6Address" example:""`
+	// IPv4 gateway of the network
+	Gateway string `json:"Gateway" example:"172.20.0.1"`
+	// IPv6 gateway of the network
+	IPv6Gateway string `json:"IPv6Gateway" example:""`
+	// Network aliases of the container
+	Aliases []string `json:"Aliases" example:"db"`
+	// MAC address of the container on the network
+	MacAddress string `json:"MacAddress" example:"02:42:ac:14:00:0a"`
+}
+
+type containerNetworkUpdatePayload struct {
+	// Static IPv4 address to reserve for the container. The current static IPv4 address is kept when not specified
+	IPv4Address string `example:"172.20.0.10"`
+	// Static IPv6 address to reserve for the container. The current static IPv6 address is kept when not specified
+	IPv6Address string `example:""`
+	// Network aliases of the container. The current aliases are kept when not specified
+	Aliases []string `example:"db"`
+}
+
+func (payload *containerNetworkUpdatePayload) Validate(r *http.Request) error {
+	if payload.IPv4Address == "" && payload.IPv6Address == "" {
+		return errStaticIPAddressMissing
+	}
+	if payload.IPv4Address != "" {
+		ip := net.ParseIP(payload.IPv4Address)
+		if ip == nil || ip.To4() == nil {
+			return errors.New("Invalid IPv4 address")
+		}
+	}
+	if payload.IPv6Address != "" {
+		ip := net.ParseIP(payload.IPv6Address)
+		if ip == nil || ip.To4() != nil {
+			return errors.New("Invalid IPv6 address")
+		}
+	}
+	return nil
+}
+
+// @id EndpointContainerNetworkList
+// @summary List the networks of a container
+// @description List the networks a container of a Docker endpoint is connected to, with the IP addresses
+// @description reserved for the container (IPAM configuration), the addresses currently assigned, the gateways and the aliases.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param containerId path string true "Container identifier"
+// @success 200 {array} containerNetwork "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or container not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/{containerId}/networks [get]
+func (handler *Handler) endpointContainerNetworkList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	dockerClient, container, handlerErr := handler.retrieveAuthorizedContainer(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer dockerClient.Close()
+
+	networks := make([]containerNetwork, 0)
+	if container.NetworkSettings != nil {
+		for name, settings := range container.NetworkSettings.Networks {
+			networks = append(networks, newContainerNetwork(name, settings))
+		}
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].NetworkName < networks[j].NetworkName
+	})
+
+	return response.JSON(w, networks)
+}
+
+// @id EndpointContainerNetworkUpdate
+// @summary Change the static IP address of a container
+// @description Change the IP addresses reserved for a container on a user-defined network. The container is disconnected
+// @description from the network and reconnected with the new IPAM configuration, the static address of the IP family that is not
+// @description specified is kept. The addresses must be part of the subnets of the network and must not be used by another container.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param containerId path string true "Container identifier"
+// @param networkId path string true "Network identifier or name"
+// @param body body containerNetworkUpdatePayload true "Static IP addresses"
+// @success 200 {object} containerNetwork "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint, container or network not found"
+// @failure 409 "IP address already in use"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/{containerId}/networks/{networkId} [put]
+func (handler *Handler) endpointContainerNetworkUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	networkID, err := request.RetrieveRouteVariableValue(r, "networkId")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid network identifier route variable", err}
+	}
+
+	var payload containerNetworkUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	dockerClient, container, handlerErr := handler.retrieveAuthorizedContainer(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer dockerClient.Close()
+
+	networkResource, err := dockerClient.NetworkInspect(context.Background(), networkID, types.NetworkInspectOptions{})
+	if client.IsErrNotFound(err) {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a network with the specified identifier", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect network", err}
+	}
+
+	if !isUserDefinedNetwork(&networkResource) {
+		return &httperror.HandlerError{http.StatusBadRequest, "Unable to assign a static IP address on this network", errNetworkNotUserDefined}
+	}
+
+	var current *network.EndpointSettings
+	if container.NetworkSettings != nil {
+		current = container.NetworkSettings.Networks[networkResource.Name]
+	}
+	if current == nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Unable to change the IP address of the container on this network", errContainerNotConnected}
+	}
+
+	for _, address := range []string{payload.IPv4Address, payload.IPv6Address} {
+		if address == "" {
+			continue
+		}
+
+		err = validateStaticIPAddress(address, &networkResource, container.ID)
+		if err == errIPAddressInUse {
+			return &httperror.HandlerError{http.StatusConflict, fmt.Sprintf("IP address %s is already in use on network %s", address, networkResource.Name), err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, fmt.Sprintf("Invalid IP address %s", address), err}
+		}
+	}
+
+	aliases := payload.Aliases
+	if aliases == nil {
+		aliases = current.Aliases
+	}
+
+	settings := &network.EndpointSettings{
+		IPAMConfig: mergeIPAMConfig(current.IPAMConfig, payload.IPv4Address, payload.IPv6Address),
+		Aliases:    userDefinedAliases(aliases, container.ID),
+		Links:      current.Links,
+		DriverOpts: current.DriverOpts,
+	}
+
+	err = reconnectContainer(dockerClient, networkResource.ID, container.ID, current, settings)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to reconnect the container to the network", err}
+	}
+
+	updated, err := dockerClient.ContainerInspect(context.Background(), container.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect container", err}
+	}
+
+	updatedSettings := updated.NetworkSettings.Networks[networkResource.Name]
+	if updatedSettings == nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find the network of the container after the update", errContainerNotConnected}
+	}
+
+	return response.JSON(w, newContainerNetwork(networkResource.Name, updatedSettings))
+}
+
+// retrieveAuthorizedContainer retrieves the container of the request when the user is allowed to access it.
+// The caller must close the returned Docker client.
+func (handler *Handler) retrieveAuthorizedContainer(r *http.Request) (*client.Client, *types.ContainerJSON, *httperror.HandlerError) {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	containerID, err := request.RetrieveRouteVariableValue(r, "containerId")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid container identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+
+	container, err := handler.inspectAuthorizedContainer(r, dockerClient, endpoint, containerID)
+	if err != nil {
+		dockerClient.Close()
+		if client.IsErrNotFound(err) {
+			return nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a container with the specified identifier", err}
+		}
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect container", err}
+	}
+
+	if container == nil {
+		dockerClient.Close()
+		return nil, nil, &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	return dockerClient, container, nil
+}
+
+// reconnectContainer disconnects the container from the network and connects it with the new settings.
+// The previous settings are restored when the container cannot be connected with the new settings.
+func reconnectContainer(dockerClient *client.Client, networkID, containerID string, current, settings *network.EndpointSettings) error {
+	err := dockerClient.NetworkDisconnect(context.Background(), networkID, containerID, false)
+	if err != nil {
+		return err
+	}
+
+	err = dockerClient.NetworkConnect(context.Background(), networkID, containerID, settings)
+	if err == nil {
+		return nil
+	}
+
+	previous := &network.EndpointSettings{
+		IPAMConfig: current.IPAMConfig,
+		Aliases:    userDefinedAliases(current.Aliases, containerID),
+		Links:      current.Links,
+		DriverOpts: current.DriverOpts,
+	}
+
+	restoreErr := dockerClient.NetworkConnect(context.Background(), networkID, containerID, previous)
+	if restoreErr != nil {
+		return fmt.Errorf("%s (unable to restore the previous network settings: %s)", err, restoreErr)
+	}
+
+	return err
+}
+
+// mergeIPAMConfig returns the IPAM configuration of the container with the static addresses that are specified,
+// the static address of the other IP family and the link-local addresses are kept
+func mergeIPAMConfig(current *network.EndpointIPAMConfig, ipv4Address, ipv6Address string) *network.EndpointIPAMConfig {
+	merged := &network.EndpointIPAMConfig{}
+	if current != nil {
+		merged.IPv4Address = current.IPv4Address
+		merged.IPv6Address = current.IPv6Address
+		merged.LinkLocalIPs = current.LinkLocalIPs
+	}
+
+	if ipv4Address != "" {
+		merged.IPv4Address = ipv4Address
+	}
+	if ipv6Address != "" {
+		merged.IPv6Address = ipv6Address
+	}
+
+	return merged
+}
+
+// validateStaticIPAddress verifies that the address is part of a subnet of the network, that it is not the gateway
+// of the network and that it is not assigned to another container of the network.
+func validateStaticIPAddress(address string, networkResource *types.NetworkResource, containerID string) error {
+	ip := net.ParseIP(address)
+
+	inSubnet := false
+	for _, config := range networkResource.IPAM.Config {
+		_, subnet, err := net.ParseCIDR(config.Subnet)
+		if err != nil || !subnet.Contains(ip) {
+			continue
+		}
+		inSubnet = true
+
+		if config.Gateway != "" && net.ParseIP(config.Gateway).Equal(ip) {
+			return errIPAddressIsGateway
+		}
+	}
+
+	if !inSubnet {
+		return errIPAddressOutOfSubnet
+	}
+
+	for id, endpoint := range networkResource.Containers {
+		if id == containerID {
+			continue
+		}
+
+		for _, assigned := range []string{endpoint.IPv4Address, endpoint.IPv6Address} {
+			assignedIP, _, err := net.ParseCIDR(assigned)
+			if err == nil && assignedIP.Equal(ip) {
+				return errIPAddressInUse
+			}
+		}
+	}
+
+	return nil
+}
+
+func isUserDefinedNetwork(networkResource *types.NetworkResource) bool {
+	switch networkResource.Name {
+	case "bridge", "host", "none":
+		return false
+	}
+
+	if networkResource.Ingress || networkResource.ConfigOnly {
+		return false
+	}
+
+	return networkResource.Scope != "swarm" || networkResource.Attachable
+}
+
+// userDefinedAliases removes the alias Docker automatically adds for the short container identifier,
+// which cannot be specified when connecting a container to a network
+func userDefinedAliases(aliases []string, containerID string) []string {
+	result := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		if len(alias) >= 12 && strings.HasPrefix(containerID, alias) {
+			continue
+		}
+		result = append(result, alias)
+	}
+	return result
+}
+
+func newContainerNetwork(name string, settings *network.EndpointSettings) containerNetwork {
+	result := containerNetwork{
+		NetworkID:         settings.NetworkID,
+		NetworkName:       name,
+		IPAddress:         settings.IPAddress,
+		GlobalIPv6Address: settings.GlobalIPv6Address,
+		Gateway:           settings.Gateway,
+		IPv6Gateway:       settings.IPv6Gateway,
+		Aliases:           settings.Aliases,
+		MacAddress:        settings.MacAddress,
+	}
+
+	if settings.IPAMConfig != nil {
+		result.IPv4Address = settings.IPAMConfig.IPv4Address
+		result.IPv6Address = settings.IPAMConfig.IPv6Address
+	}
+
+	if result.Aliases == nil {
+		result.Aliases = []string{}
+	}
+
+	return result
+}
@@ -0,0 +1,61 @@
+package endpoints
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mergeIPAMConfig_shouldKeepTheAddressOfTheOtherIPFamily(t *testing.T) {
+	current := &network.EndpointIPAMConfig{IPv4Address: "172.20.0.10", IPv6Address: "fd00::10", LinkLocalIPs: []string{"169.254.0.10"}}
+
+	merged := mergeIPAMConfig(current, "172.20.0.20", "")
+	assert.Equal(t, &network.EndpointIPAMConfig{IPv4Address: "172.20.0.20", IPv6Address: "fd00::10", LinkLocalIPs: []string{"169.254.0.10"}}, merged)
+
+	merged = mergeIPAMConfig(current, "", "fd00::20")
+	assert.Equal(t, &network.EndpointIPAMConfig{IPv4Address: "172.20.0.10", IPv6Address: "fd00::20", LinkLocalIPs: []string{"169.254.0.10"}}, merged)
+
+	merged = mergeIPAMConfig(nil, "172.20.0.20", "")
+	assert.Equal(t, &network.EndpointIPAMConfig{IPv4Address: "172.20.0.20"}, merged)
+}
+
+func Test_validateStaticIPAddress(t *testing.T) {
+	networkResource := &types.NetworkResource{
+		IPAM: network.IPAM{Config: []network.IPAMConfig{{Subnet: "172.20.0.0/16", Gateway: "172.20.0.1"}, {Subnet: "fd00::/64"}}},
+		Containers: map[string]types.EndpointResource{
+			"abc": {IPv4Address: "172.20.0.10/16"},
+			"def": {IPv4Address: "172.20.0.11/16", IPv6Address: "fd00::11/64"},
+		},
+	}
+
+	assert.NoError(t, validateStaticIPAddress("172.20.0.12", networkResource, "abc"))
+	assert.NoError(t, validateStaticIPAddress("172.20.0.10", networkResource, "abc"), "the address of the container itself can be reserved")
+	assert.NoError(t, validateStaticIPAddress("fd00::12", networkResource, "abc"))
+	assert.Equal(t, errIPAddressInUse, validateStaticIPAddress("172.20.0.11", networkResource, "abc"))
+	assert.Equal(t, errIPAddressInUse, validateStaticIPAddress("fd00::11", networkResource, "abc"))
+	assert.Equal(t, errIPAddressIsGateway, validateStaticIPAddress("172.20.0.1", networkResource, "abc"))
+	assert.Equal(t, errIPAddressOutOfSubnet, validateStaticIPAddress("10.0.0.1", networkResource, "abc"))
+}
+
+func Test_isUserDefinedNetwork(t *testing.T) {
+	assert.False(t, isUserDefinedNetwork(&types.NetworkResource{Name: "bridge"}))
+	assert.False(t, isUserDefinedNetwork(&types.NetworkResource{Name: "ingress", Ingress: true, Scope: "swarm"}))
+	assert.False(t, isUserDefinedNetwork(&types.NetworkResource{Name: "overlay", Scope: "swarm"}))
+	assert.True(t, isUserDefinedNetwork(&types.NetworkResource{Name: "overlay", Scope: "swarm", Attachable: true}))
+	assert.True(t, isUserDefinedNetwork(&types.NetworkResource{Name: "backend", Scope: "local"}))
+}
+
+func Test_userDefinedAliases(t *testing.T) {
+	containerID := "0123456789abcdef0123456789abcdef"
+
+	assert.Equal(t, []string{"db", "postgres"}, userDefinedAliases([]string{"db", "0123456789ab", "postgres"}, containerID))
+}
+
+func Test_containerNetworkUpdatePayload_Validate(t *testing.T) {
+	assert.Equal(t, errStaticIPAddressMissing, (&containerNetworkUpdatePayload{}).Validate(nil))
+	assert.Error(t, (&containerNetworkUpdatePayload{IPv4Address: "fd00::10"}).Validate(nil))
+	assert.Error(t, (&containerNetworkUpdatePayload{IPv6Address: "172.20.0.10"}).Validate(nil))
+	assert.NoError(t, (&containerNetworkUpdatePayload{IPv4Address: "172.20.0.10", IPv6Address: "fd00::10"}).Validate(nil))
+}
@@ -0,0 +1,70 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id EndpointContainerSecretsInspect
+// @summary Inspect the secrets of a container
+// @description Retrieve the Portainer secrets referenced by a standalone container of a Docker endpoint,
+// @description with the secret files folders where the secrets volume is not mounted and the referenced secrets that do not exist.
+// @description Only the containers of the services of a stack referencing secrets are inspected, a container with the
+// @description secrets label that is not a container of such a service is reported as untrusted.
+// @description The values of the secrets are never returned.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param containerId path string true "Container identifier"
+// @success 200 {object} secret.ContainerSecrets "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or container not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/{containerId}/secrets [get]
+func (handler *Handler) endpointContainerSecretsInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	containerID, err := request.RetrieveRouteVariableValue(r, "containerId")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid container identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+	defer dockerClient.Close()
+
+	containerSecrets, err := handler.SecretService.InspectContainer(dockerClient, endpoint.ID, containerID)
+	if client.IsErrNotFound(err) {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a container with the specified identifier", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect the secrets of the container", err}
+	}
+
+	return response.JSON(w, containerSecrets)
+}
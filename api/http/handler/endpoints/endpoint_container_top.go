@@ -0,0 +1,125 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+)
+
+const maxPsArgsLength = 64
+
+var (
+	errContainerNotRunning = errors.New("Container is not running")
+	errInvalidPsArgs       = errors.New("Invalid ps_args query parameter. Only ps options composed of letters, digits, commas, equal signs and dashes are supported")
+
+	// psArgsPattern restricts ps_args to a sequence of ps options (e.g. "-ef", "aux", "-o pid,comm")
+	// and rejects any shell metacharacters or path-like values.
+	psArgsPattern = regexp.MustCompile(`^[a-zA-Z0-9,=\- ]*$`)
+)
+
+type containerTopResponse struct {
+	// Column headers of the process table
+	Columns []string `json:"Columns" example:"UID,PID,PPID,C,STIME,TTY,TIME,CMD"`
+	// Process table rows, each row having one value per column
+	Rows [][]string `json:"Rows"`
+}
+
+// @id EndpointContainerTop
+// @summary List the processes running inside a container
+// @description List the processes running inside a container of a Docker endpoint.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param containerId path string true "Container identifier"
+// @param ps_args query string false "Arguments to pass to ps (e.g. -ef)"
+// @success 200 {object} containerTopResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or container not found"
+// @failure 409 "Container is not running"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/containers/{containerId}/top [get]
+func (handler *Handler) endpointContainerTop(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	containerID, err := request.RetrieveRouteVariableValue(r, "containerId")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid container identifier route variable", err}
+	}
+
+	psArgs, _ := request.RetrieveQueryParameter(r, "ps_args", true)
+	if !isValidPsArgs(psArgs) {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: ps_args", errInvalidPsArgs}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+	defer dockerClient.Close()
+
+	container, err := handler.inspectAuthorizedContainer(r, dockerClient, endpoint, containerID)
+	if client.IsErrNotFound(err) {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a container with the specified identifier", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect container", err}
+	}
+
+	if container == nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	if container.State == nil || !container.State.Running {
+		return &httperror.HandlerError{http.StatusConflict, "Unable to list processes of a container that is not running", errContainerNotRunning}
+	}
+
+	var arguments []string
+	if psArgs != "" {
+		arguments = append(arguments, psArgs)
+	}
+
+	top, err := dockerClient.ContainerTop(context.Background(), container.ID, arguments)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to list container processes", err}
+	}
+
+	rows := top.Processes
+	if rows == nil {
+		rows = [][]string{}
+	}
+
+	return response.JSON(w, &containerTopResponse{
+		Columns: top.Titles,
+		Rows:    rows,
+	})
+}
+
+func isValidPsArgs(psArgs string) bool {
+	return len(psArgs) <= maxPsArgsLength && psArgsPattern.MatchString(psArgs)
+}
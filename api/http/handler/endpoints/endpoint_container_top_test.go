@@ -0,0 +1,52 @@
+package endpoints
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/stackutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isValidPsArgs(t *testing.T) {
+	for _, psArgs := range []string{"", "-ef", "aux", "-o pid,comm", "-o pid=PID"} {
+		assert.True(t, isValidPsArgs(psArgs), psArgs)
+	}
+
+	for _, psArgs := range []string{"-ef; rm -rf /", "aux | sh", "$(id)", "`id`", "../../bin/sh", "-o\npid", strings.Repeat("a", maxPsArgsLength+1)} {
+		assert.False(t, isValidPsArgs(psArgs), psArgs)
+	}
+}
+
+func Test_findContainerResourceControl(t *testing.T) {
+	resourceControls := []portainer.ResourceControl{
+		{ID: 1, ResourceID: "abc", Type: portainer.ContainerResourceControl},
+		{ID: 2, ResourceID: "svc", Type: portainer.ServiceResourceControl},
+		{ID: 3, ResourceID: stackutils.ResourceControlID(1, "web"), Type: portainer.StackResourceControl},
+	}
+
+	newContainer := func(id string, labels map[string]string) *types.ContainerJSON {
+		return &types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{ID: id},
+			Config:            &container.Config{Labels: labels},
+		}
+	}
+
+	resourceControl := findContainerResourceControl(1, newContainer("abc", nil), resourceControls)
+	assert.Equal(t, portainer.ResourceControlID(1), resourceControl.ID)
+
+	resourceControl = findContainerResourceControl(1, newContainer("def", map[string]string{containerLabelForDockerServiceID: "svc"}), resourceControls)
+	assert.Equal(t, portainer.ResourceControlID(2), resourceControl.ID)
+
+	resourceControl = findContainerResourceControl(1, newContainer("def", map[string]string{containerLabelForDockerSwarmStackName: "web"}), resourceControls)
+	assert.Equal(t, portainer.ResourceControlID(3), resourceControl.ID)
+
+	resourceControl = findContainerResourceControl(1, newContainer("def", map[string]string{containerLabelForDockerComposeStackName: "web"}), resourceControls)
+	assert.Equal(t, portainer.ResourceControlID(3), resourceControl.ID)
+
+	assert.Nil(t, findContainerResourceControl(2, newContainer("def", map[string]string{containerLabelForDockerComposeStackName: "web"}), resourceControls), "the stack is deployed on another endpoint")
+	assert.Nil(t, findContainerResourceControl(1, newContainer("def", nil), resourceControls))
+}
@@ -150,6 +150,7 @@ func (payload *endpointCreatePayload) Validate(r *http.Request) error {
 // @id EndpointCreate
 // @summary Create a new endpoint
 // @description  Create a new endpoint that will be used to manage an environment.
+// @description The endpoint must be associated to a tag of each key listed in the required endpoint tag keys of the settings.
 // @description **Access policy**: administrator
 // @tags endpoints
 // @security jwt
@@ -182,6 +183,11 @@ func (handler *Handler) endpointCreate(w http.ResponseWriter, r *http.Request) *
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
 	}
 
+	policyErr := handler.checkRequiredEndpointTags(payload.TagIDs)
+	if policyErr != nil {
+		return policyErr
+	}
+
 	endpoint, endpointCreationError := handler.createEndpoint(payload)
 	if endpointCreationError != nil {
 		return endpointCreationError
@@ -441,6 +447,8 @@ func (handler *Handler) createTLSSecuredEndpoint(payload *endpointCreatePayload,
 		return nil, err
 	}
 
+	handler.updateTLSCertExpiry(endpoint)
+
 	err = handler.snapshotAndPersistEndpoint(endpoint)
 	if err != nil {
 		return nil, err
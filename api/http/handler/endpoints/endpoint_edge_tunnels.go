@@ -0,0 +1,81 @@
+package endpoints
+
+import (
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+type (
+	edgeTunnelListResponse struct {
+		// Whether the tunnels are being drained because Portainer is stopping
+		Draining bool `json:"Draining" example:"false"`
+		// Tunnel of each Edge endpoint
+		Tunnels []edgeTunnelState `json:"Tunnels"`
+	}
+
+	edgeTunnelState struct {
+		EndpointID   portainer.EndpointID `json:"EndpointId" example:"1"`
+		EndpointName string               `json:"EndpointName" example:"my-edge-endpoint"`
+		// Status of the tunnel: IDLE, REQUIRED or ACTIVE
+		Status string `json:"Status" example:"ACTIVE"`
+		// Port of the tunnel on the tunnel server, 0 when the tunnel is idle
+		Port int `json:"Port" example:"52143"`
+		// The date in unix time of the last activity on the tunnel, 0 when none
+		LastActivity int64 `json:"LastActivity" example:"1587399600"`
+		// The date in unix time when the agent last opened the tunnel, 0 when the tunnel was not opened since Portainer started
+		LastReconnect int64 `json:"LastReconnect" example:"1587399600"`
+		// The date in unix time of the last check-in of the agent
+		LastCheckInDate int64 `json:"LastCheckInDate" example:"1587399600"`
+	}
+)
+
+// @id EndpointEdgeTunnelList
+// @summary List the state of the Edge tunnels
+// @description List the state of the reverse tunnel of each Edge endpoint, with the last time its agent checked in and opened the tunnel.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security jwt
+// @produce json
+// @success 200 {object} edgeTunnelListResponse "Success"
+// @failure 500 "Server error"
+// @router /endpoints/edge-tunnels [get]
+func (handler *Handler) endpointEdgeTunnelList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from the database", err}
+	}
+
+	tunnels := make([]edgeTunnelState, 0)
+	for _, endpoint := range endpoints {
+		if endpoint.Type != portainer.EdgeAgentOnDockerEnvironment && endpoint.Type != portainer.EdgeAgentOnKubernetesEnvironment {
+			continue
+		}
+
+		tunnel := handler.ReverseTunnelService.GetTunnelDetails(endpoint.ID)
+		tunnels = append(tunnels, edgeTunnelState{
+			EndpointID:      endpoint.ID,
+			EndpointName:    endpoint.Name,
+			Status:          tunnel.Status,
+			Port:            tunnel.Port,
+			LastActivity:    unixTime(tunnel.LastActivity),
+			LastReconnect:   unixTime(tunnel.LastReconnect),
+			LastCheckInDate: endpoint.LastCheckInDate,
+		})
+	}
+
+	return response.JSON(w, &edgeTunnelListResponse{
+		Draining: handler.ReverseTunnelService.IsDraining(),
+		Tunnels:  tunnels,
+	})
+}
+
+func unixTime(date time.Time) int64 {
+	if date.IsZero() {
+		return 0
+	}
+	return date.Unix()
+}
@@ -0,0 +1,136 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+var errInvalidImageMaxAge = errors.New("Invalid maximum age. Must be a valid positive duration")
+
+type (
+	imageAgeReport struct {
+		// Maximum age used to flag the stale images, empty when no maximum age applies
+		MaxAge string `json:"MaxAge" example:"2160h"`
+		// Images of the endpoint, the oldest first
+		Images []imageAge `json:"Images"`
+	}
+
+	imageAge struct {
+		ID       string   `json:"Id" example:"sha256:4cdc5dd7eaadff5080649e8d0014f2f8d36d4ddf2eff2fdf577dd13da85c5d2f"`
+		RepoTags []string `json:"RepoTags" example:"nginx:1.19"`
+		// The date in unix time when the image was built
+		Created int64 `json:"Created" example:"1587399600"`
+		// Age of the image in seconds
+		Age int64 `json:"Age" example:"7776000"`
+		// Whether the image is older than the maximum age
+		Stale bool `json:"Stale" example:"true"`
+	}
+)
+
+// @id EndpointImageAgeReport
+// @summary Report the age of the images of an endpoint
+// @description List the images of a Docker endpoint with their age, the oldest first, to find the stale images to rebuild.
+// @description The images older than the maximum age of the image age policy of the settings, or of the maxAge query parameter, are flagged as stale.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param maxAge query string false "Maximum age overriding the image age policy, e.g. 2160h"
+// @success 200 {object} imageAgeReport "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/images/age-report [get]
+func (handler *Handler) endpointImageAgeReport(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	maxAge, _ := request.RetrieveQueryParameter(r, "maxAge", true)
+	if maxAge == "" {
+		settings, err := handler.DataStore.Settings().Settings()
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+		}
+
+		if settings.ImageAgePolicy.Enabled {
+			maxAge = settings.ImageAgePolicy.MaxAge
+		}
+	}
+
+	var maxAgeDuration time.Duration
+	if maxAge != "" {
+		maxAgeDuration, err = time.ParseDuration(maxAge)
+		if err != nil || maxAgeDuration <= 0 {
+			return &httperror.HandlerError{http.StatusBadRequest, errInvalidImageMaxAge.Error(), errInvalidImageMaxAge}
+		}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to connect to the Docker endpoint", err}
+	}
+	defer dockerClient.Close()
+
+	images, err := dockerClient.ImageList(context.Background(), types.ImageListOptions{})
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the images of the endpoint", err}
+	}
+
+	return response.JSON(w, &imageAgeReport{
+		MaxAge: maxAge,
+		Images: imageAges(images, maxAgeDuration, time.Now()),
+	})
+}
+
+// imageAges returns the age of each image, the oldest first. The images are never stale when maxAge is 0.
+func imageAges(images []types.ImageSummary, maxAge time.Duration, now time.Time) []imageAge {
+	ages := make([]imageAge, 0, len(images))
+	for _, image := range images {
+		age := now.Sub(time.Unix(image.Created, 0))
+
+		repoTags := image.RepoTags
+		if repoTags == nil {
+			repoTags = []string{}
+		}
+
+		ages = append(ages, imageAge{
+			ID:       image.ID,
+			RepoTags: repoTags,
+			Created:  image.Created,
+			Age:      int64(age.Seconds()),
+			Stale:    maxAge > 0 && age > maxAge,
+		})
+	}
+
+	sort.Slice(ages, func(i, j int) bool {
+		return ages[i].Created < ages[j].Created
+	})
+
+	return ages
+}
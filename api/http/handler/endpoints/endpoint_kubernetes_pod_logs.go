@@ -0,0 +1,213 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// allContainers is the container query parameter value selecting all the containers of a pod
+	allContainers = "*"
+
+	// defaultPodLogTailLines is the number of lines returned for each container when the logs are not followed
+	// and no number of lines is requested
+	defaultPodLogTailLines = 1000
+	// maxPodLogBytes is the maximum size of the logs returned for each container when the logs are not followed
+	maxPodLogBytes = 10 * 1024 * 1024
+)
+
+// @id EndpointKubernetesPodLogs
+// @summary Retrieve the logs of a Kubernetes pod
+// @description Retrieve the logs of one or all the containers of a pod, each line tagged with the container that logged it.
+// @description When following the logs, the lines are streamed as server-sent events, one JSON encoded line per event, until the client disconnects.
+// @description Otherwise the lines are returned at once, sorted by date across the containers when timestamps are requested,
+// @description with at most 10MB of logs per container.
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @produce json,text/event-stream
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param pod path string true "Pod name"
+// @param container query string false "Name of the container, all the containers of the pod when empty or *"
+// @param follow query boolean false "Stream the new log lines"
+// @param tailLines query int false "Number of lines from the end of the logs to return for each container, 1000 when the logs are not followed"
+// @param sinceSeconds query int false "Only return the lines logged within this number of seconds"
+// @param previous query boolean false "Return the logs of the previous instance of the containers"
+// @param timestamps query boolean false "Prefix each line with its RFC3339 timestamp"
+// @success 200 {array} portainer.KubernetesPodLogLine "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or pod not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/pods/{pod}/logs [get]
+func (handler *Handler) endpointKubernetesPodLogs(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	podName, err := request.RetrieveRouteVariableValue(r, "pod")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid pod route variable", err}
+	}
+
+	options, err := podLogOptions(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameters", err}
+	}
+
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	container, _ := request.RetrieveQueryParameter(r, "container", true)
+
+	containers := []string{container}
+	if container == "" || container == allContainers {
+		containers, err = kubeClient.GetPodContainers(namespace, podName)
+		if err != nil {
+			return kubernetesResourceError(err, "Unable to retrieve the containers of the pod")
+		}
+	}
+
+	if options.Follow {
+		streamPodLogs(w, r, kubeClient, namespace, podName, containers, options)
+		return nil
+	}
+
+	lines := make([]portainer.KubernetesPodLogLine, 0)
+	for _, containerName := range containers {
+		err = kubeClient.StreamPodLogs(r.Context(), namespace, podName, containerName, options, func(line string) {
+			lines = append(lines, portainer.KubernetesPodLogLine{Container: containerName, Line: line})
+		})
+		if err != nil {
+			return kubernetesResourceError(err, fmt.Sprintf("Unable to retrieve the logs of container %s", containerName))
+		}
+	}
+
+	if options.Timestamps && len(containers) > 1 {
+		sortLogLinesByTimestamp(lines)
+	}
+
+	return response.JSON(w, lines)
+}
+
+func podLogOptions(r *http.Request) (*portainer.KubernetesPodLogOptions, error) {
+	follow, _ := request.RetrieveBooleanQueryParameter(r, "follow", true)
+	previous, _ := request.RetrieveBooleanQueryParameter(r, "previous", true)
+	timestamps, _ := request.RetrieveBooleanQueryParameter(r, "timestamps", true)
+
+	tailLines, _ := request.RetrieveNumericQueryParameter(r, "tailLines", true)
+	if tailLines < 0 {
+		return nil, errors.New("tailLines must be a positive number")
+	}
+
+	sinceSeconds, _ := request.RetrieveNumericQueryParameter(r, "sinceSeconds", true)
+	if sinceSeconds < 0 {
+		return nil, errors.New("sinceSeconds must be a positive number")
+	}
+
+	options := &portainer.KubernetesPodLogOptions{
+		Follow:       follow,
+		TailLines:    int64(tailLines),
+		SinceSeconds: int64(sinceSeconds),
+		Previous:     previous,
+		Timestamps:   timestamps,
+	}
+
+	// the logs returned at once are buffered, they are bounded to the end of the logs
+	if !follow {
+		if options.TailLines == 0 {
+			options.TailLines = defaultPodLogTailLines
+		}
+		options.LimitBytes = maxPodLogBytes
+	}
+
+	return options, nil
+}
+
+// streamPodLogs streams the log lines of the containers as server-sent events until all the streams end
+// or the client disconnects. A stream failing for a container is reported as an error event.
+func streamPodLogs(w http.ResponseWriter, r *http.Request, kubeClient portainer.KubeClient, namespace, podName string, containers []string, options *portainer.KubernetesPodLogOptions) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+
+	var mu sync.Mutex
+	writeEvent := func(event string, line portainer.KubernetesPodLogLine) {
+		data, err := json.Marshal(line)
+		if err != nil {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if event != "" {
+			fmt.Fprintf(w, "event: %s\n", event)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, containerName := range containers {
+		wg.Add(1)
+		go func(containerName string) {
+			defer wg.Done()
+
+			err := kubeClient.StreamPodLogs(r.Context(), namespace, podName, containerName, options, func(line string) {
+				writeEvent("", portainer.KubernetesPodLogLine{Container: containerName, Line: line})
+			})
+			if err != nil {
+				log.Printf("[WARN] [http,endpoints] [pod: %s/%s] [container: %s] [message: unable to stream the container logs] [error: %s]", namespace, podName, containerName, err)
+				writeEvent("error", portainer.KubernetesPodLogLine{Container: containerName, Line: err.Error()})
+			}
+		}(containerName)
+	}
+	wg.Wait()
+}
+
+// sortLogLinesByTimestamp sorts the lines by their timestamp prefix, a line without timestamp stays after the line preceding it
+func sortLogLinesByTimestamp(lines []portainer.KubernetesPodLogLine) {
+	type timestampedLine struct {
+		timestamp time.Time
+		line      portainer.KubernetesPodLogLine
+	}
+
+	entries := make([]timestampedLine, len(lines))
+
+	var previous time.Time
+	for idx, line := range lines {
+		timestamp := previous
+		if separator := strings.Index(line.Line, " "); separator != -1 {
+			if parsed, err := time.Parse(time.RFC3339Nano, line.Line[:separator]); err == nil {
+				timestamp = parsed
+			}
+		}
+
+		entries[idx] = timestampedLine{timestamp: timestamp, line: line}
+		previous = timestamp
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].timestamp.Before(entries[j].timestamp)
+	})
+
+	for idx := range entries {
+		lines[idx] = entries[idx].line
+	}
+}
@@ -0,0 +1,45 @@
+package endpoints
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_podLogOptions_shouldBoundTheLogsReturnedAtOnce(t *testing.T) {
+	options, err := podLogOptions(httptest.NewRequest("GET", "/logs", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(defaultPodLogTailLines), options.TailLines)
+	assert.Equal(t, int64(maxPodLogBytes), options.LimitBytes)
+
+	options, err = podLogOptions(httptest.NewRequest("GET", "/logs?tailLines=50", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), options.TailLines)
+	assert.Equal(t, int64(maxPodLogBytes), options.LimitBytes)
+}
+
+func Test_podLogOptions_shouldNotBoundTheFollowedLogs(t *testing.T) {
+	options, err := podLogOptions(httptest.NewRequest("GET", "/logs?follow=true", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), options.TailLines)
+	assert.Equal(t, int64(0), options.LimitBytes)
+
+	_, err = podLogOptions(httptest.NewRequest("GET", "/logs?tailLines=-1", nil))
+	assert.Error(t, err)
+}
+
+func Test_sortLogLinesByTimestamp(t *testing.T) {
+	lines := []portainer.KubernetesPodLogLine{
+		{Container: "app", Line: "2021-01-01T10:00:02Z second"},
+		{Container: "app", Line: "continuation"},
+		{Container: "sidecar", Line: "2021-01-01T10:00:01Z first"},
+		{Container: "sidecar", Line: "2021-01-01T10:00:03Z third"},
+	}
+
+	sortLogLinesByTimestamp(lines)
+
+	assert.Equal(t, []string{"2021-01-01T10:00:01Z first", "2021-01-01T10:00:02Z second", "continuation", "2021-01-01T10:00:03Z third"},
+		[]string{lines[0].Line, lines[1].Line, lines[2].Line, lines[3].Line})
+}
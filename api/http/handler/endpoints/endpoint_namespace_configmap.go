@@ -0,0 +1,236 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+type configMapCreatePayload struct {
+	// Name of the config map
+	Name string `example:"app-config" validate:"required"`
+	// Labels of the config map
+	Labels map[string]string
+	// Configuration data, per key
+	Data map[string]string
+}
+
+func (payload *configMapCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return errors.New("Invalid config map name")
+	}
+	return nil
+}
+
+type configMapUpdatePayload struct {
+	// Labels of the config map, replacing the existing labels
+	Labels map[string]string
+	// Configuration data, per key, replacing the existing data
+	Data map[string]string
+}
+
+func (payload *configMapUpdatePayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// @id EndpointNamespaceConfigMapList
+// @summary List the ConfigMaps of a Kubernetes namespace
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @success 200 {array} portainer.KubernetesConfigMap "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/configmaps [get]
+func (handler *Handler) endpointNamespaceConfigMapList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	configMaps, err := kubeClient.GetConfigMaps(namespace)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to retrieve the config maps of the namespace")
+	}
+
+	return response.JSON(w, configMaps)
+}
+
+// @id EndpointNamespaceConfigMapInspect
+// @summary Inspect a ConfigMap of a Kubernetes namespace
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param name path string true "ConfigMap name"
+// @success 200 {object} portainer.KubernetesConfigMap "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or config map not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/configmaps/{name} [get]
+func (handler *Handler) endpointNamespaceConfigMapInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid config map name route variable", err}
+	}
+
+	configMap, err := kubeClient.GetConfigMap(namespace, name)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to retrieve the config map")
+	}
+
+	return response.JSON(w, configMap)
+}
+
+// @id EndpointNamespaceConfigMapCreate
+// @summary Create a ConfigMap inside a Kubernetes namespace
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param body body configMapCreatePayload true "ConfigMap details"
+// @success 200 {object} portainer.KubernetesConfigMap "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 409 "A config map with the same name already exists"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/configmaps [post]
+func (handler *Handler) endpointNamespaceConfigMapCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload configMapCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	configMap := &portainer.KubernetesConfigMap{
+		Name:      payload.Name,
+		Namespace: namespace,
+		Labels:    payload.Labels,
+		Data:      payload.Data,
+	}
+
+	err = kubeClient.CreateConfigMap(configMap)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to create the config map")
+	}
+
+	createdConfigMap, err := kubeClient.GetConfigMap(namespace, payload.Name)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to retrieve the config map")
+	}
+
+	return response.JSON(w, createdConfigMap)
+}
+
+// @id EndpointNamespaceConfigMapUpdate
+// @summary Update a ConfigMap of a Kubernetes namespace
+// @description Replace the data and the labels of a ConfigMap.
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param name path string true "ConfigMap name"
+// @param body body configMapUpdatePayload true "ConfigMap details"
+// @success 200 {object} portainer.KubernetesConfigMap "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or config map not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/configmaps/{name} [put]
+func (handler *Handler) endpointNamespaceConfigMapUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload configMapUpdatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid config map name route variable", err}
+	}
+
+	err = kubeClient.UpdateConfigMap(&portainer.KubernetesConfigMap{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    payload.Labels,
+		Data:      payload.Data,
+	})
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to update the config map")
+	}
+
+	configMap, err := kubeClient.GetConfigMap(namespace, name)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to retrieve the config map")
+	}
+
+	return response.JSON(w, configMap)
+}
+
+// @id EndpointNamespaceConfigMapDelete
+// @summary Remove a ConfigMap of a Kubernetes namespace
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param name path string true "ConfigMap name"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or config map not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/configmaps/{name} [delete]
+func (handler *Handler) endpointNamespaceConfigMapDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid config map name route variable", err}
+	}
+
+	err = kubeClient.DeleteConfigMap(namespace, name)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to remove the config map")
+	}
+
+	return response.Empty(w)
+}
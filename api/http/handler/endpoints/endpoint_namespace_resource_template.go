@@ -0,0 +1,107 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+type namespaceResourceTemplatePayload struct {
+	// Name of the resource template defined in the settings
+	Template string `example:"small" validate:"required"`
+}
+
+func (payload *namespaceResourceTemplatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Template) {
+		return errors.New("Invalid resource template name")
+	}
+	return nil
+}
+
+// @id EndpointNamespaceResourceTemplateApply
+// @summary Apply a resource template to a Kubernetes namespace
+// @description Create or update the resource quota and the limit range managed by Portainer inside the namespace
+// @description so that they match a resource template defined in the settings. The name of the template is recorded
+// @description as an annotation of the namespace. Applying the same template several times has no further effect.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security jwt
+// @accept json
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param body body namespaceResourceTemplatePayload true "Resource template"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Endpoint or resource template not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/resource_template [post]
+func (handler *Handler) endpointNamespaceResourceTemplateApply(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	namespace, err := request.RetrieveRouteVariableValue(r, "namespace")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid namespace route variable", err}
+	}
+
+	var payload namespaceResourceTemplatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	if !isKubernetesEndpoint(endpoint) {
+		return &httperror.HandlerError{http.StatusBadRequest, "Resource templates can only be applied on Kubernetes endpoints", errors.New("Unsupported endpoint type")}
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	var template *portainer.KubernetesResourceTemplate
+	for idx := range settings.KubernetesResourceTemplates {
+		if settings.KubernetesResourceTemplates[idx].Name == payload.Template {
+			template = &settings.KubernetesResourceTemplates[idx]
+			break
+		}
+	}
+	if template == nil {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a resource template with the specified name inside the settings", errors.New("Resource template not found")}
+	}
+
+	kubeClient, err := handler.KubernetesClientFactory.GetKubeClient(endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Kubernetes client", err}
+	}
+
+	err = kubeClient.ApplyResourceTemplate(namespace, template)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to apply the resource template to the namespace", err}
+	}
+
+	return response.Empty(w)
+}
+
+func isKubernetesEndpoint(endpoint *portainer.Endpoint) bool {
+	switch endpoint.Type {
+	case portainer.KubernetesLocalEnvironment, portainer.AgentOnKubernetesEnvironment, portainer.EdgeAgentOnKubernetesEnvironment:
+		return true
+	}
+	return false
+}
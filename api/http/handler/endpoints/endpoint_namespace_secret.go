@@ -0,0 +1,277 @@
+package endpoints
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/kubernetes/cli"
+)
+
+type secretCreatePayload struct {
+	// Name of the secret
+	Name string `example:"app-secret" validate:"required"`
+	// Type of the secret. Valid values are: Opaque (default), kubernetes.io/dockerconfigjson or kubernetes.io/tls
+	Type portainer.KubernetesSecretType `example:"Opaque"`
+	// Labels of the secret
+	Labels map[string]string
+	// Secret data, per key. A kubernetes.io/dockerconfigjson secret requires the .dockerconfigjson key,
+	// a kubernetes.io/tls secret requires the tls.crt and tls.key keys
+	Data map[string]string
+}
+
+func (payload *secretCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Name) {
+		return errors.New("Invalid secret name")
+	}
+
+	if payload.Type == "" {
+		payload.Type = portainer.KubernetesSecretOpaque
+	}
+
+	return cli.ValidateSecretData(payload.Type, payload.Data)
+}
+
+type secretUpdatePayload struct {
+	// Labels of the secret, replacing the existing labels
+	Labels map[string]string
+	// Secret data, per key, replacing the existing data. The existing data is kept when not specified
+	Data map[string]string
+}
+
+func (payload *secretUpdatePayload) Validate(r *http.Request) error {
+	return nil
+}
+
+// @id EndpointNamespaceSecretList
+// @summary List the Secrets of a Kubernetes namespace
+// @description Only the metadata and the keys of the secrets are returned, never their values.
+// @description The service account tokens are not listed.
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @success 200 {array} portainer.KubernetesSecret "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/secrets [get]
+func (handler *Handler) endpointNamespaceSecretList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	secrets, err := kubeClient.GetSecrets(namespace)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to retrieve the secrets of the namespace")
+	}
+
+	return response.JSON(w, secrets)
+}
+
+// @id EndpointNamespaceSecretInspect
+// @summary Inspect a Secret of a Kubernetes namespace
+// @description Only the metadata and the keys of the secret are returned, never its values.
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param name path string true "Secret name"
+// @success 200 {object} portainer.KubernetesSecret "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or secret not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/secrets/{name} [get]
+func (handler *Handler) endpointNamespaceSecretInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid secret name route variable", err}
+	}
+
+	secret, err := kubeClient.GetSecret(namespace, name)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to retrieve the secret")
+	}
+
+	return response.JSON(w, secret)
+}
+
+// @id EndpointNamespaceSecretCreate
+// @summary Create a Secret inside a Kubernetes namespace
+// @description Create an Opaque, kubernetes.io/dockerconfigjson or kubernetes.io/tls secret. The metadata of the secret is returned.
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param body body secretCreatePayload true "Secret details"
+// @success 200 {object} portainer.KubernetesSecret "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 409 "A secret with the same name already exists"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/secrets [post]
+func (handler *Handler) endpointNamespaceSecretCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload secretCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	secret := &portainer.KubernetesSecret{
+		Name:      payload.Name,
+		Namespace: namespace,
+		Type:      payload.Type,
+		Labels:    payload.Labels,
+	}
+
+	err = kubeClient.CreateSecret(secret, payload.Data)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to create the secret")
+	}
+
+	createdSecret, err := kubeClient.GetSecret(namespace, payload.Name)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to retrieve the secret")
+	}
+
+	return response.JSON(w, createdSecret)
+}
+
+// @id EndpointNamespaceSecretUpdate
+// @summary Update a Secret of a Kubernetes namespace
+// @description Replace the labels of a Secret, and its data when specified. The type of a secret cannot be updated.
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param name path string true "Secret name"
+// @param body body secretUpdatePayload true "Secret details"
+// @success 200 {object} portainer.KubernetesSecret "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or secret not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/secrets/{name} [put]
+func (handler *Handler) endpointNamespaceSecretUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload secretUpdatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid secret name route variable", err}
+	}
+
+	secret, err := kubeClient.GetSecret(namespace, name)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to retrieve the secret")
+	}
+
+	if payload.Data != nil {
+		err = cli.ValidateSecretData(secret.Type, payload.Data)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		}
+	}
+
+	secret.Labels = payload.Labels
+	err = kubeClient.UpdateSecret(secret, payload.Data)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to update the secret")
+	}
+
+	updatedSecret, err := kubeClient.GetSecret(namespace, name)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to retrieve the secret")
+	}
+
+	return response.JSON(w, updatedSecret)
+}
+
+// @id EndpointNamespaceSecretDelete
+// @summary Remove a Secret of a Kubernetes namespace
+// @description The removal of a secret referenced by pods that are not terminated is rejected with a 409 listing the pods,
+// @description unless the force query parameter is set.
+// @description **Access policy**: restricted, the user must have access to the namespace
+// @tags endpoints
+// @security jwt
+// @param id path int true "Endpoint identifier"
+// @param namespace path string true "Namespace name"
+// @param name path string true "Secret name"
+// @param force query boolean false "Remove the secret even when it is referenced by pods"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or secret not found"
+// @failure 409 "Secret referenced by pods"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/namespaces/{namespace}/secrets/{name} [delete]
+func (handler *Handler) endpointNamespaceSecretDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	kubeClient, namespace, handlerErr := handler.namespaceKubeClient(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	name, err := request.RetrieveRouteVariableValue(r, "name")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid secret name route variable", err}
+	}
+
+	force, _ := request.RetrieveBooleanQueryParameter(r, "force", true)
+
+	if !force {
+		consumers, err := kubeClient.GetSecretConsumers(namespace, name)
+		if err != nil {
+			return kubernetesResourceError(err, "Unable to retrieve the pods referencing the secret")
+		}
+
+		if len(consumers) > 0 {
+			errorMessage := fmt.Sprintf("The secret is referenced by the pods %s. Use the force parameter to remove it anyway", strings.Join(consumers, ", "))
+			return &httperror.HandlerError{http.StatusConflict, errorMessage, errors.New(errorMessage)}
+		}
+	}
+
+	err = kubeClient.DeleteSecret(namespace, name)
+	if err != nil {
+		return kubernetesResourceError(err, "Unable to remove the secret")
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,323 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/internal/servicecanary"
+)
+
+type (
+	serviceCanaryUpdatePayload struct {
+		// Image used by the canary tasks
+		Image string `example:"nginx:1.19"`
+		// Number of tasks to update during the canary phase
+		Replicas int `example:"1"`
+		// Percentage of the service replicas to update during the canary phase, used when Replicas is not specified
+		Percentage int `example:"10"`
+		// Duration of the canary phase, the rollout is completed at the end of the bake time
+		BakeTime string `example:"5m"`
+		// Whether the rollout requires a manual promotion during the bake time. The canary update is aborted
+		// at the end of the bake time when it is not promoted
+		ManualPromotion bool `example:"false"`
+	}
+
+	serviceCanaryStatusResponse struct {
+		// Whether a canary update is in progress
+		InProgress bool `example:"true"`
+		// Whether the canary requires a manual promotion
+		ManualPromotion bool `example:"true"`
+		// Number of tasks updated during the canary phase
+		CanaryReplicas int `example:"1"`
+		// Unix timestamp of the end of the bake time
+		Deadline int64 `example:"1587399600"`
+		// Image targeted by the rollout
+		Image string `example:"nginx:1.19"`
+		// Number of running tasks using the targeted image
+		UpdatedTasks int `example:"1"`
+		// Number of running tasks
+		RunningTasks int `example:"4"`
+		// Status of the Swarm service update
+		UpdateStatus *swarm.UpdateStatus
+	}
+)
+
+func (payload *serviceCanaryUpdatePayload) Validate(r *http.Request) error {
+	if payload.Image == "" {
+		return errors.New("Invalid image")
+	}
+	if payload.Replicas < 0 || payload.Percentage < 0 || payload.Percentage > 100 {
+		return errors.New("Invalid canary size. Replicas must be positive and percentage must be between 1 and 100")
+	}
+	if payload.Replicas == 0 && payload.Percentage == 0 {
+		return errors.New("Invalid canary size. Either replicas or percentage must be specified")
+	}
+	bakeTime, err := time.ParseDuration(payload.BakeTime)
+	if err != nil || bakeTime <= 0 {
+		return errors.New("Invalid bake time")
+	}
+	return nil
+}
+
+// @id EndpointServiceCanaryUpdate
+// @summary Start a canary update of a Swarm service
+// @description Update a subset of the service tasks to a new image. The rest of the rollout is completed
+// @description when the canary is promoted or at the end of the bake time. Canary updates requiring a manual promotion,
+// @description or whose canary tasks failed, are aborted at the end of the bake time.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param serviceId path string true "Service identifier"
+// @param body body serviceCanaryUpdatePayload true "Canary update details"
+// @success 200 {object} serviceCanaryStatusResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or service not found"
+// @failure 409 "A canary update is already in progress"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/services/{serviceId}/canary [post]
+func (handler *Handler) endpointServiceCanaryUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload serviceCanaryUpdatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	dockerClient, service, handlerErr := handler.authorizedServiceFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer dockerClient.Close()
+
+	if service.Spec.Labels[servicecanary.LabelReplicas] != "" {
+		return &httperror.HandlerError{http.StatusConflict, "A canary update is already in progress for this service", servicecanary.ErrCanaryInProgress}
+	}
+
+	if service.Spec.Mode.Replicated == nil || service.Spec.Mode.Replicated.Replicas == nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Unable to start a canary update on this service", servicecanary.ErrServiceNotReplicated}
+	}
+
+	canaryReplicas := canaryReplicaCount(int(*service.Spec.Mode.Replicated.Replicas), payload.Replicas, payload.Percentage)
+	bakeTime, _ := time.ParseDuration(payload.BakeTime)
+
+	spec, err := servicecanary.CanarySpec(service.Spec, payload.Image, canaryReplicas, bakeTime, payload.ManualPromotion, time.Now())
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to save service update configuration", err}
+	}
+
+	_, err = dockerClient.ServiceUpdate(context.Background(), service.ID, service.Version, spec, types.ServiceUpdateOptions{QueryRegistry: true})
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to start canary update", err}
+	}
+
+	return handler.writeServiceCanaryStatus(w, dockerClient, service.ID)
+}
+
+// @id EndpointServiceCanaryInspect
+// @summary Inspect the canary update of a Swarm service
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param serviceId path string true "Service identifier"
+// @success 200 {object} serviceCanaryStatusResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or service not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/services/{serviceId}/canary [get]
+func (handler *Handler) endpointServiceCanaryInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	dockerClient, service, handlerErr := handler.authorizedServiceFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer dockerClient.Close()
+
+	return handler.writeServiceCanaryStatus(w, dockerClient, service.ID)
+}
+
+// @id EndpointServiceCanaryPromote
+// @summary Promote the canary update of a Swarm service
+// @description Complete the rollout of a canary update using the update configuration and the labels of the service
+// @description defined before the canary started.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param serviceId path string true "Service identifier"
+// @success 200 {object} serviceCanaryStatusResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or service not found"
+// @failure 409 "No canary update in progress"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/services/{serviceId}/canary/promote [post]
+func (handler *Handler) endpointServiceCanaryPromote(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	dockerClient, service, handlerErr := handler.authorizedServiceFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer dockerClient.Close()
+
+	if service.Spec.Labels[servicecanary.LabelReplicas] == "" {
+		return &httperror.HandlerError{http.StatusConflict, "No canary update in progress for this service", servicecanary.ErrNoCanaryInProgress}
+	}
+
+	err := servicecanary.Promote(dockerClient, service)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to promote canary update", err}
+	}
+
+	return handler.writeServiceCanaryStatus(w, dockerClient, service.ID)
+}
+
+// @id EndpointServiceCanaryAbort
+// @summary Abort the canary update of a Swarm service
+// @description Restore the image, the update configuration and the labels of the service defined before the canary update.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @param serviceId path string true "Service identifier"
+// @success 200 {object} serviceCanaryStatusResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint or service not found"
+// @failure 409 "No canary update in progress"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/services/{serviceId}/canary/abort [post]
+func (handler *Handler) endpointServiceCanaryAbort(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	dockerClient, service, handlerErr := handler.authorizedServiceFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+	defer dockerClient.Close()
+
+	if service.Spec.Labels[servicecanary.LabelReplicas] == "" {
+		return &httperror.HandlerError{http.StatusConflict, "No canary update in progress for this service", servicecanary.ErrNoCanaryInProgress}
+	}
+
+	err := servicecanary.Abort(dockerClient, service)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to abort canary update", err}
+	}
+
+	return handler.writeServiceCanaryStatus(w, dockerClient, service.ID)
+}
+
+func (handler *Handler) authorizedServiceFromRequest(r *http.Request) (*client.Client, *swarm.Service, *httperror.HandlerError) {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	serviceID, err := request.RetrieveRouteVariableValue(r, "serviceId")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid service identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+
+	service, err := handler.inspectAuthorizedService(r, dockerClient, endpoint, serviceID)
+	if err != nil {
+		dockerClient.Close()
+		if client.IsErrNotFound(err) {
+			return nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a service with the specified identifier", err}
+		}
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect service", err}
+	}
+
+	if service == nil {
+		dockerClient.Close()
+		return nil, nil, &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	return dockerClient, service, nil
+}
+
+func (handler *Handler) writeServiceCanaryStatus(w http.ResponseWriter, dockerClient *client.Client, serviceID string) *httperror.HandlerError {
+	service, _, err := dockerClient.ServiceInspectWithRaw(context.Background(), serviceID, types.ServiceInspectOptions{})
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect service", err}
+	}
+
+	tasks, err := dockerClient.TaskList(context.Background(), types.TaskListOptions{
+		Filters: filters.NewArgs(filters.Arg("service", service.ID), filters.Arg("desired-state", "running")),
+	})
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve service tasks", err}
+	}
+
+	status := &serviceCanaryStatusResponse{
+		InProgress:      service.Spec.Labels[servicecanary.LabelReplicas] != "",
+		ManualPromotion: service.Spec.Labels[servicecanary.LabelManualPromotion] == "true",
+		Image:           service.Spec.TaskTemplate.ContainerSpec.Image,
+		RunningTasks:    len(tasks),
+		UpdateStatus:    service.UpdateStatus,
+	}
+	status.CanaryReplicas, _ = strconv.Atoi(service.Spec.Labels[servicecanary.LabelReplicas])
+	if status.InProgress {
+		status.Deadline = servicecanary.Deadline(service.Spec).Unix()
+	}
+
+	for _, task := range tasks {
+		if task.Spec.ContainerSpec != nil && task.Spec.ContainerSpec.Image == status.Image {
+			status.UpdatedTasks++
+		}
+	}
+
+	return response.JSON(w, status)
+}
+
+// canaryReplicaCount returns the number of tasks updated during the canary phase. The count
+// is computed from the percentage of the service replicas when no explicit count is specified
+// and is always kept between 1 and the number of replicas.
+func canaryReplicaCount(serviceReplicas, replicas, percentage int) int {
+	count := replicas
+	if count == 0 {
+		count = int(math.Ceil(float64(serviceReplicas) * float64(percentage) / 100))
+	}
+
+	if count > serviceReplicas {
+		count = serviceReplicas
+	}
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
@@ -1,13 +1,19 @@
 package endpoints
 
 import (
+	"errors"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
-	"github.com/portainer/portainer/api/bolt/errors"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/clockskew"
+	"github.com/portainer/portainer/api/internal/containercleanup"
 )
 
 type endpointSettingsUpdatePayload struct {
@@ -27,9 +33,76 @@ type endpointSettingsUpdatePayload struct {
 	AllowContainerCapabilitiesForRegularUsers *bool `json:"allowContainerCapabilitiesForRegularUsers" example:"true"`
 	// Whether host management features are enabled
 	EnableHostManagementFeatures *bool `json:"enableHostManagementFeatures" example:"true"`
+	// Network settings injected into the containers and stacks created on the endpoint when not specified
+	NetworkDefaults *portainer.EndpointNetworkDefaults `json:"networkDefaults"`
+	// Limit of concurrent Docker API requests proxied to the endpoint
+	RequestConcurrency *portainer.EndpointRequestConcurrency `json:"requestConcurrency"`
+	// Automatic removal of the containers exited for longer than a maximum age and matching a label filter
+	ContainerCleanupPolicy *portainer.EndpointContainerCleanupPolicy `json:"containerCleanupPolicy"`
+	// Maximum difference tolerated between the system time of the endpoint and the Portainer host clock, empty for the default (30s)
+	ClockSkewThreshold *string `json:"clockSkewThreshold" example:"30s"`
 }
 
 func (payload *endpointSettingsUpdatePayload) Validate(r *http.Request) error {
+	if payload.NetworkDefaults != nil {
+		err := validateNetworkDefaults(payload.NetworkDefaults)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.RequestConcurrency != nil {
+		err := validateRequestConcurrency(payload.RequestConcurrency)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.ContainerCleanupPolicy != nil {
+		err := containercleanup.ValidatePolicy(payload.ContainerCleanupPolicy)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.ClockSkewThreshold != nil {
+		return clockskew.ValidateThreshold(*payload.ClockSkewThreshold)
+	}
+	return nil
+}
+
+func validateRequestConcurrency(concurrency *portainer.EndpointRequestConcurrency) error {
+	if concurrency.MaxConcurrentRequests < 0 {
+		return errors.New("Invalid maximum number of concurrent requests. Must be positive or 0 for unlimited")
+	}
+
+	if concurrency.QueueTimeout != "" {
+		timeout, err := time.ParseDuration(concurrency.QueueTimeout)
+		if err != nil || timeout <= 0 {
+			return errors.New("Invalid queue timeout. Must be a valid positive duration")
+		}
+	}
+
+	return nil
+}
+
+func validateNetworkDefaults(defaults *portainer.EndpointNetworkDefaults) error {
+	for _, dns := range defaults.DNS {
+		if net.ParseIP(dns) == nil {
+			return errors.New("Invalid DNS server. Must be a valid IP address")
+		}
+	}
+
+	for _, domain := range defaults.DNSSearch {
+		if strings.TrimSpace(domain) == "" {
+			return errors.New("Invalid DNS search domain. Cannot be empty")
+		}
+	}
+
+	for _, extraHost := range defaults.ExtraHosts {
+		parts := strings.SplitN(extraHost, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || net.ParseIP(parts[1]) == nil {
+			return errors.New("Invalid extra host. Must use the host:ip format")
+		}
+	}
+
 	return nil
 }
 
@@ -61,7 +134,7 @@ func (handler *Handler) endpointSettingsUpdate(w http.ResponseWriter, r *http.Re
 	}
 
 	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
-	if err == errors.ErrObjectNotFound {
+	if err == bolterrors.ErrObjectNotFound {
 		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
 	} else if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
@@ -103,6 +176,23 @@ func (handler *Handler) endpointSettingsUpdate(w http.ResponseWriter, r *http.Re
 
 	endpoint.SecuritySettings = securitySettings
 
+	if payload.NetworkDefaults != nil {
+		endpoint.NetworkDefaults = *payload.NetworkDefaults
+	}
+
+	if payload.RequestConcurrency != nil {
+		endpoint.RequestConcurrency = *payload.RequestConcurrency
+	}
+
+	if payload.ContainerCleanupPolicy != nil {
+		endpoint.ContainerCleanupPolicy = *payload.ContainerCleanupPolicy
+	}
+
+	if payload.ClockSkewThreshold != nil {
+		endpoint.ClockSkewThreshold = *payload.ClockSkewThreshold
+		endpoint.ClockSkew.Exceeded = clockskew.Exceeded(endpoint)
+	}
+
 	err = handler.DataStore.Endpoint().UpdateEndpoint(portainer.EndpointID(endpointID), endpoint)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Failed persisting endpoint in database", err}
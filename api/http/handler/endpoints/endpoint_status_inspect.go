@@ -14,6 +14,8 @@ import (
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
 )
 
+var errTunnelServerDraining = errors.New("The Edge tunnels are being drained")
+
 type stackStatusResponse struct {
 	// EdgeStack Identifier
 	ID portainer.EdgeStackID `example:"1"`
@@ -61,8 +63,14 @@ type endpointStatusInspectResponse struct {
 // @failure 403 "Permission denied to access endpoint"
 // @failure 404 "Endpoint not found"
 // @failure 500 "Server error"
+// @failure 503 "Portainer is stopping, the agent should check in again after the delay of the Retry-After header"
 // @router /endpoints/{id}/status [get]
 func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	if handler.ReverseTunnelService.IsDraining() {
+		w.Header().Set("Retry-After", strconv.Itoa(int(handler.ReverseTunnelService.ReconnectDelay().Seconds())))
+		return &httperror.HandlerError{http.StatusServiceUnavailable, "Portainer is stopping, check in again later", errTunnelServerDraining}
+	}
+
 	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
 	if err != nil {
 		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
@@ -121,6 +129,7 @@ func (handler *Handler) endpointStatusInspect(w http.ResponseWriter, r *http.Req
 	if endpoint.EdgeCheckinInterval != 0 {
 		checkinInterval = endpoint.EdgeCheckinInterval
 	}
+	checkinInterval = handler.ReverseTunnelService.CheckinInterval(checkinInterval)
 
 	schedules := []edgeJobResponse{}
 	for _, job := range tunnel.Jobs {
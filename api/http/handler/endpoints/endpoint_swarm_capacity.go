@@ -0,0 +1,220 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+var errNotSwarmManager = errors.New("The endpoint is not a Swarm manager")
+
+type (
+	// swarmResources represents an amount of CPU, in nano CPUs, and of memory, in bytes
+	swarmResources struct {
+		NanoCPUs    int64 `example:"4000000000"`
+		MemoryBytes int64 `example:"8589934592"`
+	}
+
+	swarmNodeCapacity struct {
+		ID           string `example:"jpofkc0i9uo9wtx1zesuk649w"`
+		Hostname     string `example:"worker-1"`
+		Role         string `example:"worker"`
+		Availability string `example:"active"`
+		State        string `example:"ready"`
+		// Whether new tasks can be scheduled on the node, i.e. the node is ready and active
+		Schedulable bool `example:"true"`
+		// Resources of the node
+		Total swarmResources
+		// Resources reserved by the tasks placed on the node, running or being started
+		Reserved swarmResources
+		// Resources that can still be reserved by new tasks
+		Available swarmResources
+		// Number of tasks placed on the node, running or being started
+		Tasks int `example:"5"`
+	}
+
+	swarmCapacityResponse struct {
+		// Resources of the schedulable nodes
+		Total swarmResources
+		// Resources reserved by the tasks placed on the schedulable nodes, running or being started
+		Reserved swarmResources
+		// Resources that can still be reserved on the schedulable nodes
+		Available swarmResources
+		// Largest amount of CPU and of memory available on a single schedulable node.
+		// A task reserving more than these amounts cannot be scheduled, even when the cluster-wide headroom is sufficient
+		LargestNodeAvailable swarmResources
+		// Capacity of each node, sorted by hostname
+		Nodes []swarmNodeCapacity
+	}
+)
+
+// @id EndpointSwarmCapacityInspect
+// @summary Inspect the resource capacity of a Swarm cluster
+// @description Aggregate the CPU and memory of the Swarm nodes and the resources reserved by the tasks placed on the nodes,
+// @description running or being started (assigned, accepted, preparing, ready or starting), to report the headroom available for new deployments on each node and cluster-wide.
+// @description Only resource reservations are accounted for, as the Swarm scheduler places tasks on their reservations.
+// @description **Access policy**: restricted
+// @tags endpoints
+// @security jwt
+// @produce json
+// @param id path int true "Endpoint identifier"
+// @success 200 {object} swarmCapacityResponse "Success"
+// @failure 400 "Invalid request or endpoint is not a Swarm manager"
+// @failure 403 "Permission denied"
+// @failure 404 "Endpoint not found"
+// @failure 500 "Server error"
+// @router /endpoints/{id}/swarm/capacity [get]
+func (handler *Handler) endpointSwarmCapacityInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+	defer dockerClient.Close()
+
+	info, err := dockerClient.Info(context.Background())
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve Docker information", err}
+	}
+
+	if !info.Swarm.ControlAvailable {
+		return &httperror.HandlerError{http.StatusBadRequest, "Unable to inspect the Swarm capacity", errNotSwarmManager}
+	}
+
+	nodes, err := dockerClient.NodeList(context.Background(), types.NodeListOptions{})
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve Swarm nodes", err}
+	}
+
+	tasks, err := dockerClient.TaskList(context.Background(), types.TaskListOptions{
+		Filters: filters.NewArgs(filters.Arg("desired-state", string(swarm.TaskStateRunning))),
+	})
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve Swarm tasks", err}
+	}
+
+	return response.JSON(w, swarmCapacity(nodes, tasks))
+}
+
+// swarmCapacity computes the resources reserved by the tasks placed on each node and the headroom left
+func swarmCapacity(nodes []swarm.Node, tasks []swarm.Task) *swarmCapacityResponse {
+	reserved := make(map[string]*swarmResources)
+	taskCount := make(map[string]int)
+	for _, task := range tasks {
+		if task.NodeID == "" || !reservesResources(task.Status.State) {
+			continue
+		}
+
+		taskCount[task.NodeID]++
+
+		if task.Spec.Resources == nil || task.Spec.Resources.Reservations == nil {
+			continue
+		}
+
+		nodeReserved, ok := reserved[task.NodeID]
+		if !ok {
+			nodeReserved = &swarmResources{}
+			reserved[task.NodeID] = nodeReserved
+		}
+		nodeReserved.NanoCPUs += task.Spec.Resources.Reservations.NanoCPUs
+		nodeReserved.MemoryBytes += task.Spec.Resources.Reservations.MemoryBytes
+	}
+
+	capacity := &swarmCapacityResponse{
+		Nodes: make([]swarmNodeCapacity, 0, len(nodes)),
+	}
+
+	for _, node := range nodes {
+		nodeCapacity := swarmNodeCapacity{
+			ID:           node.ID,
+			Hostname:     node.Description.Hostname,
+			Role:         string(node.Spec.Role),
+			Availability: string(node.Spec.Availability),
+			State:        string(node.Status.State),
+			Schedulable:  node.Status.State == swarm.NodeStateReady && node.Spec.Availability == swarm.NodeAvailabilityActive,
+			Total: swarmResources{
+				NanoCPUs:    node.Description.Resources.NanoCPUs,
+				MemoryBytes: node.Description.Resources.MemoryBytes,
+			},
+			Tasks: taskCount[node.ID],
+		}
+
+		if nodeReserved, ok := reserved[node.ID]; ok {
+			nodeCapacity.Reserved = *nodeReserved
+		}
+
+		if nodeCapacity.Schedulable {
+			nodeCapacity.Available = swarmResources{
+				NanoCPUs:    headroom(nodeCapacity.Total.NanoCPUs, nodeCapacity.Reserved.NanoCPUs),
+				MemoryBytes: headroom(nodeCapacity.Total.MemoryBytes, nodeCapacity.Reserved.MemoryBytes),
+			}
+
+			capacity.Total.add(nodeCapacity.Total)
+			capacity.Reserved.add(nodeCapacity.Reserved)
+			capacity.Available.add(nodeCapacity.Available)
+
+			if nodeCapacity.Available.NanoCPUs > capacity.LargestNodeAvailable.NanoCPUs {
+				capacity.LargestNodeAvailable.NanoCPUs = nodeCapacity.Available.NanoCPUs
+			}
+			if nodeCapacity.Available.MemoryBytes > capacity.LargestNodeAvailable.MemoryBytes {
+				capacity.LargestNodeAvailable.MemoryBytes = nodeCapacity.Available.MemoryBytes
+			}
+		}
+
+		capacity.Nodes = append(capacity.Nodes, nodeCapacity)
+	}
+
+	sort.Slice(capacity.Nodes, func(i, j int) bool {
+		return capacity.Nodes[i].Hostname < capacity.Nodes[j].Hostname
+	})
+
+	return capacity
+}
+
+// reservesResources returns true when a task in the state holds its reservations on its node: the scheduler
+// reserves the resources as soon as the task is assigned to the node, before its containers are started
+func reservesResources(state swarm.TaskState) bool {
+	switch state {
+	case swarm.TaskStateAssigned, swarm.TaskStateAccepted, swarm.TaskStatePreparing, swarm.TaskStateReady, swarm.TaskStateStarting, swarm.TaskStateRunning:
+		return true
+	}
+	return false
+}
+
+func (resources *swarmResources) add(other swarmResources) {
+	resources.NanoCPUs += other.NanoCPUs
+	resources.MemoryBytes += other.MemoryBytes
+}
+
+func headroom(total, reserved int64) int64 {
+	if reserved >= total {
+		return 0
+	}
+	return total - reserved
+}
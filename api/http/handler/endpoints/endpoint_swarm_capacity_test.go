@@ -0,0 +1,68 @@
+package endpoints
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCapacityTask(nodeID string, state swarm.TaskState, nanoCPUs, memoryBytes int64) swarm.Task {
+	return swarm.Task{
+		NodeID: nodeID,
+		Spec: swarm.TaskSpec{
+			Resources: &swarm.ResourceRequirements{
+				Reservations: &swarm.Resources{NanoCPUs: nanoCPUs, MemoryBytes: memoryBytes},
+			},
+		},
+		Status: swarm.TaskStatus{State: state},
+	}
+}
+
+func newCapacityNode(id, hostname string, availability swarm.NodeAvailability, nanoCPUs, memoryBytes int64) swarm.Node {
+	node := swarm.Node{ID: id}
+	node.Description.Hostname = hostname
+	node.Description.Resources = swarm.Resources{NanoCPUs: nanoCPUs, MemoryBytes: memoryBytes}
+	node.Spec.Availability = availability
+	node.Status.State = swarm.NodeStateReady
+	return node
+}
+
+func Test_swarmCapacity_shouldCountTheTasksBeingStarted(t *testing.T) {
+	nodes := []swarm.Node{newCapacityNode("n1", "worker-1", swarm.NodeAvailabilityActive, 4000000000, 8000)}
+	tasks := []swarm.Task{
+		newCapacityTask("n1", swarm.TaskStateRunning, 1000000000, 1000),
+		newCapacityTask("n1", swarm.TaskStateStarting, 1000000000, 1000),
+		newCapacityTask("n1", swarm.TaskStatePreparing, 500000000, 500),
+		newCapacityTask("n1", swarm.TaskStateAssigned, 500000000, 500),
+		newCapacityTask("n1", swarm.TaskStateShutdown, 1000000000, 1000),
+		newCapacityTask("n1", swarm.TaskStateFailed, 1000000000, 1000),
+		newCapacityTask("", swarm.TaskStatePending, 1000000000, 1000),
+	}
+
+	capacity := swarmCapacity(nodes, tasks)
+
+	assert.Equal(t, 4, capacity.Nodes[0].Tasks)
+	assert.Equal(t, swarmResources{NanoCPUs: 3000000000, MemoryBytes: 3000}, capacity.Nodes[0].Reserved)
+	assert.Equal(t, swarmResources{NanoCPUs: 1000000000, MemoryBytes: 5000}, capacity.Available)
+}
+
+func Test_swarmCapacity_shouldOnlyAggregateTheSchedulableNodes(t *testing.T) {
+	nodes := []swarm.Node{
+		newCapacityNode("n2", "worker-2", swarm.NodeAvailabilityDrain, 4000000000, 8000),
+		newCapacityNode("n1", "worker-1", swarm.NodeAvailabilityActive, 2000000000, 4000),
+		newCapacityNode("n3", "worker-3", swarm.NodeAvailabilityActive, 2000000000, 4000),
+	}
+	tasks := []swarm.Task{
+		newCapacityTask("n1", swarm.TaskStateRunning, 3000000000, 1000),
+		newCapacityTask("n3", swarm.TaskStateRunning, 500000000, 3000),
+	}
+
+	capacity := swarmCapacity(nodes, tasks)
+
+	assert.Equal(t, []string{"worker-1", "worker-2", "worker-3"}, []string{capacity.Nodes[0].Hostname, capacity.Nodes[1].Hostname, capacity.Nodes[2].Hostname})
+	assert.Equal(t, swarmResources{}, capacity.Nodes[1].Available)
+	assert.Equal(t, swarmResources{NanoCPUs: 4000000000, MemoryBytes: 8000}, capacity.Total)
+	assert.Equal(t, swarmResources{NanoCPUs: 1500000000, MemoryBytes: 4000}, capacity.Available)
+	assert.Equal(t, swarmResources{NanoCPUs: 1500000000, MemoryBytes: 3000}, capacity.LargestNodeAvailable)
+}
@@ -0,0 +1,44 @@
+package endpoints
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/tag"
+)
+
+// checkRequiredEndpointTags verifies that the tags include a key/value tag for each key required by the settings
+func (handler *Handler) checkRequiredEndpointTags(tagIDs []portainer.TagID) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	if len(settings.RequiredEndpointTagKeys) == 0 {
+		return nil
+	}
+
+	tags := make([]portainer.Tag, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		endpointTag, err := handler.DataStore.Tag().Tag(tagID)
+		if err == bolterrors.ErrObjectNotFound {
+			return &httperror.HandlerError{http.StatusBadRequest, "Unable to find a tag with the specified identifier inside the database", err}
+		} else if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a tag inside the database", err}
+		}
+		tags = append(tags, *endpointTag)
+	}
+
+	missingKeys := tag.MissingKeys(settings.RequiredEndpointTagKeys, tags)
+	if len(missingKeys) > 0 {
+		errorMessage := fmt.Sprintf("Missing required endpoint tags: %s", strings.Join(missingKeys, ", "))
+		return &httperror.HandlerError{http.StatusBadRequest, errorMessage, errors.New(errorMessage)}
+	}
+
+	return nil
+}
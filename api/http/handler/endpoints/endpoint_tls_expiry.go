@@ -0,0 +1,46 @@
+package endpoints
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/tlsexpiry"
+)
+
+// @id EndpointTLSExpiryList
+// @summary Summarize the expiry of the endpoint TLS client certificates
+// @description List the TLS client certificates of the endpoints with their expiry date, the certificates expiring first first.
+// @description A certificate is reported as expiring within the warning days of the TLS certificate expiry settings.
+// @description **Access policy**: administrator
+// @tags endpoints
+// @security jwt
+// @produce json
+// @success 200 {object} tlsexpiry.Summary "Success"
+// @failure 500 "Server error"
+// @router /endpoints/tls-expiry [get]
+func (handler *Handler) endpointTLSExpiryList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve endpoints from the database", err}
+	}
+
+	return response.JSON(w, tlsexpiry.Summarize(endpoints, &settings.TLSCertExpirySettings, time.Now()))
+}
+
+// updateTLSCertExpiry sets the expiry date of the TLS client certificate of the endpoint. A certificate that cannot be
+// read does not prevent the endpoint from being saved, its expiry date is retried by the expiry check job.
+func (handler *Handler) updateTLSCertExpiry(endpoint *portainer.Endpoint) {
+	err := tlsexpiry.UpdateEndpoint(endpoint)
+	if err != nil {
+		log.Printf("Warning: unable to read the TLS client certificate of endpoint %s: %s\n", endpoint.Name, err)
+	}
+}
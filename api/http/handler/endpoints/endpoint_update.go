@@ -56,6 +56,7 @@ func (payload *endpointUpdatePayload) Validate(r *http.Request) error {
 // @id EndpointUpdate
 // @summary Update an endpoint
 // @description Update an endpoint.
+// @description When the tags are updated, the endpoint must be associated to a tag of each key listed in the required endpoint tag keys of the settings.
 // @description **Access policy**: administrator
 // @security jwt
 // @tags endpoints
@@ -119,6 +120,11 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 		tagsChanged = len(union) > len(intersection)
 
 		if tagsChanged {
+			policyErr := handler.checkRequiredEndpointTags(payload.TagIDs)
+			if policyErr != nil {
+				return policyErr
+			}
+
 			removeTags := tag.Difference(endpointTagSet, payloadTagSet)
 
 			for tagID := range removeTags {
@@ -243,6 +249,8 @@ func (handler *Handler) endpointUpdate(w http.ResponseWriter, r *http.Request) *
 			endpoint.TLSConfig.TLS = true
 			endpoint.TLSConfig.TLSSkipVerify = true
 		}
+
+		handler.updateTLSCertExpiry(endpoint)
 	}
 
 	if payload.URL != nil || payload.TLS != nil || endpoint.Type == portainer.AzureEnvironment {
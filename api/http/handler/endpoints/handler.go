@@ -3,8 +3,14 @@ package endpoints
 import (
 	httperror "github.com/portainer/libhttp/error"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/concurrency"
+	"github.com/portainer/portainer/api/internal/containercleanup"
+	"github.com/portainer/portainer/api/internal/crashloop"
+	"github.com/portainer/portainer/api/internal/secret"
+	"github.com/portainer/portainer/api/kubernetes/cli"
 
 	"net/http"
 
@@ -21,13 +27,19 @@ func hideFields(endpoint *portainer.Endpoint) {
 // Handler is the HTTP handler used to handle endpoint operations.
 type Handler struct {
 	*mux.Router
-	requestBouncer       *security.RequestBouncer
-	DataStore            portainer.DataStore
-	FileService          portainer.FileService
-	ProxyManager         *proxy.Manager
-	ReverseTunnelService portainer.ReverseTunnelService
-	SnapshotService      portainer.SnapshotService
-	ComposeStackManager  portainer.ComposeStackManager
+	requestBouncer          *security.RequestBouncer
+	DataStore               portainer.DataStore
+	DockerClientFactory     *docker.ClientFactory
+	FileService             portainer.FileService
+	ProxyManager            *proxy.Manager
+	ReverseTunnelService    portainer.ReverseTunnelService
+	SnapshotService         portainer.SnapshotService
+	ComposeStackManager     portainer.ComposeStackManager
+	KubernetesClientFactory *cli.ClientFactory
+	ConcurrencyLimiter      *concurrency.Limiter
+	CrashLoopService        *crashloop.Service
+	ContainerCleanupService *containercleanup.Service
+	SecretService           *secret.Service
 }
 
 // NewHandler creates a handler to manage endpoint operations.
@@ -45,12 +57,78 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointSnapshots))).Methods(http.MethodPost)
 	h.Handle("/endpoints",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointList))).Methods(http.MethodGet)
+	h.Handle("/endpoints/access-preview",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointAccessPreview))).Methods(http.MethodGet)
+	h.Handle("/endpoints/clock-skew",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointClockSkewList))).Methods(http.MethodGet)
+	h.Handle("/endpoints/edge-tunnels",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointEdgeTunnelList))).Methods(http.MethodGet)
+	h.Handle("/endpoints/tls-expiry",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointTLSExpiryList))).Methods(http.MethodGet)
 	h.Handle("/endpoints/{id}",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointInspect))).Methods(http.MethodGet)
 	h.Handle("/endpoints/{id}",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointUpdate))).Methods(http.MethodPut)
 	h.Handle("/endpoints/{id}",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointDelete))).Methods(http.MethodDelete)
+	h.Handle("/endpoints/{id}/images/age-report",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointImageAgeReport))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/containers/cleanup",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointContainerCleanupPreview))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/containers/crashlooping",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointContainerCrashLoopList))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/containers/labels",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointContainerLabelsUpdate))).Methods(http.MethodPut)
+	h.Handle("/endpoints/{id}/containers/{containerId}/logging",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointContainerLoggingInspect))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/containers/{containerId}/memory",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointContainerMemoryInspect))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/containers/{containerId}/memory",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointContainerMemoryUpdate))).Methods(http.MethodPut)
+	h.Handle("/endpoints/{id}/containers/{containerId}/networks",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointContainerNetworkList))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/containers/{containerId}/networks/{networkId}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointContainerNetworkUpdate))).Methods(http.MethodPut)
+	h.Handle("/endpoints/{id}/containers/{containerId}/secrets",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointContainerSecretsInspect))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/containers/{containerId}/top",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointContainerTop))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/services/{serviceId}/canary",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointServiceCanaryInspect))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/services/{serviceId}/canary",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointServiceCanaryUpdate))).Methods(http.MethodPost)
+	h.Handle("/endpoints/{id}/services/{serviceId}/canary/promote",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointServiceCanaryPromote))).Methods(http.MethodPost)
+	h.Handle("/endpoints/{id}/services/{serviceId}/canary/abort",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointServiceCanaryAbort))).Methods(http.MethodPost)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/pods/{pod}/logs",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointKubernetesPodLogs))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/configmaps",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceConfigMapList))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/configmaps",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceConfigMapCreate))).Methods(http.MethodPost)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/configmaps/{name}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceConfigMapInspect))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/configmaps/{name}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceConfigMapUpdate))).Methods(http.MethodPut)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/configmaps/{name}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceConfigMapDelete))).Methods(http.MethodDelete)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/secrets",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceSecretList))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/secrets",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceSecretCreate))).Methods(http.MethodPost)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/secrets/{name}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceSecretInspect))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/secrets/{name}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceSecretUpdate))).Methods(http.MethodPut)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/secrets/{name}",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointNamespaceSecretDelete))).Methods(http.MethodDelete)
+	h.Handle("/endpoints/{id}/namespaces/{namespace}/resource_template",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointNamespaceResourceTemplateApply))).Methods(http.MethodPost)
+	h.Handle("/endpoints/{id}/swarm/capacity",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointSwarmCapacityInspect))).Methods(http.MethodGet)
+	h.Handle("/endpoints/{id}/concurrency",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.endpointConcurrencyInspect))).Methods(http.MethodGet)
 	h.Handle("/endpoints/{id}/extensions",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.endpointExtensionAdd))).Methods(http.MethodPost)
 	h.Handle("/endpoints/{id}/extensions/{extensionType}",
@@ -0,0 +1,88 @@
+package endpoints
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// namespaceKubeClient returns a client of the Kubernetes endpoint and the namespace of the request
+// after verifying that the user associated to the request can access the namespace.
+// Administrators can access every namespace, the other users the default namespace and the namespaces
+// defined in their access policies.
+func (handler *Handler) namespaceKubeClient(r *http.Request) (portainer.KubeClient, string, *httperror.HandlerError) {
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, "", &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	namespace, err := request.RetrieveRouteVariableValue(r, "namespace")
+	if err != nil {
+		return nil, "", &httperror.HandlerError{http.StatusBadRequest, "Invalid namespace route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, "", &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, "", &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	if !isKubernetesEndpoint(endpoint) {
+		return nil, "", &httperror.HandlerError{http.StatusBadRequest, "This operation is only available on Kubernetes endpoints", errors.New("Unsupported endpoint type")}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return nil, "", &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, "", &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	kubeClient, err := handler.KubernetesClientFactory.GetKubeClient(endpoint)
+	if err != nil {
+		return nil, "", &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Kubernetes client", err}
+	}
+
+	if !securityContext.IsAdmin {
+		userTeamIDs := make([]int, 0, len(securityContext.UserMemberships))
+		for _, membership := range securityContext.UserMemberships {
+			userTeamIDs = append(userTeamIDs, int(membership.TeamID))
+		}
+
+		hasAccess, err := kubeClient.HasNamespaceAccess(int(securityContext.UserID), userTeamIDs, namespace)
+		if err != nil {
+			return nil, "", &httperror.HandlerError{http.StatusInternalServerError, "Unable to verify user authorizations to validate namespace access", err}
+		}
+		if !hasAccess {
+			return nil, "", &httperror.HandlerError{http.StatusForbidden, "Permission denied to access namespace", httperrors.ErrResourceAccessDenied}
+		}
+	}
+
+	return kubeClient, namespace, nil
+}
+
+// kubernetesResourceError returns the handler error associated to an error returned by the Kubernetes API
+func kubernetesResourceError(err error, message string) *httperror.HandlerError {
+	switch {
+	case k8serrors.IsNotFound(err):
+		return &httperror.HandlerError{http.StatusNotFound, message, err}
+	case k8serrors.IsAlreadyExists(err), k8serrors.IsConflict(err):
+		return &httperror.HandlerError{http.StatusConflict, message, err}
+	case k8serrors.IsInvalid(err), k8serrors.IsBadRequest(err):
+		return &httperror.HandlerError{http.StatusBadRequest, message, err}
+	case k8serrors.IsForbidden(err):
+		return &httperror.HandlerError{http.StatusForbidden, message, err}
+	}
+	return &httperror.HandlerError{http.StatusInternalServerError, message, err}
+}
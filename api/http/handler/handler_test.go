@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/portainer/portainer/api/http/handler/endpointproxy"
+	"github.com/portainer/portainer/api/http/handler/endpoints"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEndpointsTestHandler() *Handler {
+	proxyRouter := mux.NewRouter()
+	proxyRouter.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	bouncer := security.NewRequestBouncer(testhelpers.NewDatastore(), nil)
+
+	return &Handler{
+		EndpointHandler:      endpoints.NewHandler(bouncer),
+		EndpointProxyHandler: &endpointproxy.Handler{Router: proxyRouter},
+	}
+}
+
+func Test_ServeHTTP_shouldRouteThePodLogsToTheEndpointHandler(t *testing.T) {
+	handler := newEndpointsTestHandler()
+
+	for _, path := range []string{
+		"/api/endpoints/1/namespaces/default/pods/web-0/logs",
+		"/api/endpoints/1/namespaces/kubernetes/pods/docker/logs",
+		"/api/endpoints/1/namespaces/docker/secrets",
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, "%s is handled by the authenticated endpoint handler", path)
+	}
+}
+
+func Test_ServeHTTP_shouldRouteTheEndpointAPIsToTheProxy(t *testing.T) {
+	handler := newEndpointsTestHandler()
+
+	for _, path := range []string{
+		"/api/endpoints/1/docker/containers/json",
+		"/api/endpoints/1/kubernetes/api/v1/namespaces/default/pods/web-0/log",
+		"/api/endpoints/1/azure/subscriptions",
+		"/api/endpoints/1/storidge/profiles",
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusTeapot, rr.Code, "%s is proxied", path)
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/crypto"
+)
+
+// @id MetricsEndpoint
+// @summary Retrieve the container metrics of an endpoint
+// @description Retrieve the resource usage of the running containers of a Docker endpoint in the Prometheus text format.
+// @description The values are sampled during the endpoint snapshots, containers for which the stats could not be collected are omitted.
+// @description **Access policy**: bearer token of the metrics settings
+// @tags metrics
+// @produce plain
+// @param id path int true "Endpoint identifier"
+// @success 200 "Success"
+// @failure 400 "Invalid request"
+// @failure 401 "Invalid bearer token"
+// @failure 404 "Metrics are disabled or endpoint not found"
+// @failure 500 "Server error"
+// @router /metrics/endpoints/{id} [get]
+func (handler *Handler) endpointMetrics(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	if !settings.MetricsSettings.Enabled || settings.MetricsSettings.BearerTokenHash == "" {
+		return &httperror.HandlerError{http.StatusNotFound, "Metrics are disabled", errors.New("Metrics are disabled")}
+	}
+
+	if !isAuthorized(r, settings.MetricsSettings.BearerTokenHash) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		return &httperror.HandlerError{http.StatusUnauthorized, "Invalid bearer token", errors.New("Unauthorized")}
+	}
+
+	endpointID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid endpoint identifier route variable", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(endpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, err = w.Write([]byte(formatEndpointMetrics(endpoint)))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to write the metrics", err}
+	}
+
+	return nil
+}
+
+// isAuthorized returns whether the bearer token of the request matches the hash of the bearer token of the settings
+func isAuthorized(r *http.Request, bearerTokenHash string) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(crypto.HashToken(token)), []byte(bearerTokenHash)) == 1
+}
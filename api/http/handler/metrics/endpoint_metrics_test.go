@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isAuthorized_shouldCompareTheTokenToItsHash(t *testing.T) {
+	bearerTokenHash := crypto.HashToken("s3cr3t")
+
+	request := httptest.NewRequest("GET", "/metrics/endpoints/1", nil)
+	request.Header.Set("Authorization", "Bearer s3cr3t")
+	assert.True(t, isAuthorized(request, bearerTokenHash))
+
+	request.Header.Set("Authorization", "Bearer "+bearerTokenHash)
+	assert.False(t, isAuthorized(request, bearerTokenHash), "the hash should not be accepted as a token")
+
+	request.Header.Del("Authorization")
+	assert.False(t, isAuthorized(request, bearerTokenHash))
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+type gauge struct {
+	name  string
+	help  string
+	value func(stats *portainer.DockerContainerStats) float64
+}
+
+var containerGauges = []gauge{
+	{"portainer_container_cpu_usage_percent", "CPU usage of the container in percent of a single CPU",
+		func(stats *portainer.DockerContainerStats) float64 { return stats.CPUPercent }},
+	{"portainer_container_memory_usage_bytes", "Memory usage of the container, excluding the page cache",
+		func(stats *portainer.DockerContainerStats) float64 { return float64(stats.MemoryUsage) }},
+	{"portainer_container_memory_limit_bytes", "Memory limit of the container",
+		func(stats *portainer.DockerContainerStats) float64 { return float64(stats.MemoryLimit) }},
+	{"portainer_container_network_receive_bytes", "Bytes received by the container on all its networks",
+		func(stats *portainer.DockerContainerStats) float64 { return float64(stats.NetworkRxBytes) }},
+	{"portainer_container_network_transmit_bytes", "Bytes sent by the container on all its networks",
+		func(stats *portainer.DockerContainerStats) float64 { return float64(stats.NetworkTxBytes) }},
+	{"portainer_container_blkio_read_bytes", "Bytes read by the container from block devices",
+		func(stats *portainer.DockerContainerStats) float64 { return float64(stats.BlockIOReadBytes) }},
+	{"portainer_container_blkio_write_bytes", "Bytes written by the container to block devices",
+		func(stats *portainer.DockerContainerStats) float64 { return float64(stats.BlockIOWriteBytes) }},
+}
+
+// formatEndpointMetrics renders the container stats of the latest snapshot of the endpoint in the Prometheus text format.
+// Only the containers with sampled stats are part of the output, no value is emitted for the other containers.
+func formatEndpointMetrics(endpoint *portainer.Endpoint) string {
+	if len(endpoint.Snapshots) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	snapshot := &endpoint.Snapshots[0]
+	endpointLabel := strconv.Itoa(int(endpoint.ID))
+
+	fmt.Fprintf(&builder, "# HELP portainer_snapshot_timestamp_seconds Time of the snapshot the container metrics are sampled from\n")
+	fmt.Fprintf(&builder, "# TYPE portainer_snapshot_timestamp_seconds gauge\n")
+	fmt.Fprintf(&builder, "portainer_snapshot_timestamp_seconds{endpoint=\"%s\"} %d\n", endpointLabel, snapshot.Time)
+
+	stats := snapshot.SnapshotRaw.ContainerStats
+	if len(stats) == 0 {
+		return builder.String()
+	}
+
+	for _, g := range containerGauges {
+		fmt.Fprintf(&builder, "# HELP %s %s\n", g.name, g.help)
+		fmt.Fprintf(&builder, "# TYPE %s gauge\n", g.name)
+		for idx := range stats {
+			fmt.Fprintf(&builder, "%s{endpoint=\"%s\",container=\"%s\",stack=\"%s\"} %s\n",
+				g.name, endpointLabel, escapeLabelValue(stats[idx].ContainerName), escapeLabelValue(stats[idx].Stack),
+				strconv.FormatFloat(g.value(&stats[idx]), 'g', -1, 64))
+		}
+	}
+
+	return builder.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
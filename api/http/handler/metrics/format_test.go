@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_formatEndpointMetrics(t *testing.T) {
+	endpoint := &portainer.Endpoint{
+		ID: 1,
+		Snapshots: []portainer.DockerSnapshot{
+			{
+				Time: 1600000000,
+				SnapshotRaw: portainer.DockerSnapshotRaw{
+					ContainerStats: []portainer.DockerContainerStats{
+						{ContainerName: "web", Stack: "shop", CPUPercent: 12.5, MemoryUsage: 1024},
+					},
+				},
+			},
+		},
+	}
+
+	output := formatEndpointMetrics(endpoint)
+	assert.Contains(t, output, `portainer_snapshot_timestamp_seconds{endpoint="1"} 1600000000`)
+	assert.Contains(t, output, `portainer_container_cpu_usage_percent{endpoint="1",container="web",stack="shop"} 12.5`)
+	assert.Contains(t, output, `portainer_container_memory_usage_bytes{endpoint="1",container="web",stack="shop"} 1024`)
+	assert.Contains(t, output, "# TYPE portainer_container_blkio_write_bytes gauge")
+
+	endpoint.Snapshots[0].SnapshotRaw.ContainerStats = nil
+	assert.False(t, strings.Contains(formatEndpointMetrics(endpoint), "portainer_container_"))
+
+	assert.Equal(t, `a\"b\\c`, escapeLabelValue(`a"b\c`))
+}
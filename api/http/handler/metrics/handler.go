@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// Handler is the HTTP handler used to expose Prometheus metrics.
+type Handler struct {
+	*mux.Router
+	DataStore portainer.DataStore
+}
+
+// NewHandler creates a handler to expose Prometheus metrics.
+// The metrics are protected by the bearer token of the metrics settings instead of a user session
+// so that they can be scraped by Prometheus.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/metrics/endpoints/{id}",
+		bouncer.PublicAccess(httperror.LoggerHandler(h.endpointMetrics))).Methods(http.MethodGet)
+
+	return h
+}
@@ -0,0 +1,76 @@
+package registries
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/registryclient"
+)
+
+const catalogCacheDuration = time.Minute
+
+type (
+	// catalogCache keeps the repository and tag listings of the registries for a short period of time,
+	// to avoid querying the registries each time a list of tags is displayed
+	catalogCache struct {
+		mu      sync.Mutex
+		entries map[portainer.RegistryID]map[string]catalogCacheEntry
+	}
+
+	catalogCacheEntry struct {
+		page      *registryclient.Page
+		expiresAt time.Time
+	}
+)
+
+func newCatalogCache() *catalogCache {
+	return &catalogCache{
+		entries: make(map[portainer.RegistryID]map[string]catalogCacheEntry),
+	}
+}
+
+func catalogCacheKey(repository string, size int, last string) string {
+	return fmt.Sprintf("%s|%d|%s", repository, size, last)
+}
+
+func (cache *catalogCache) get(registryID portainer.RegistryID, key string) *registryclient.Page {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry, ok := cache.entries[registryID][key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil
+	}
+	return entry.page
+}
+
+func (cache *catalogCache) set(registryID portainer.RegistryID, key string, page *registryclient.Page) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := time.Now()
+
+	entries, ok := cache.entries[registryID]
+	if !ok {
+		entries = make(map[string]catalogCacheEntry)
+		cache.entries[registryID] = entries
+	}
+
+	for entryKey, entry := range entries {
+		if now.After(entry.expiresAt) {
+			delete(entries, entryKey)
+		}
+	}
+
+	entries[key] = catalogCacheEntry{page: page, expiresAt: now.Add(catalogCacheDuration)}
+}
+
+// invalidate removes the listings of a registry, used when the registry is updated or removed
+func (cache *catalogCache) invalidate(registryID portainer.RegistryID) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	delete(cache.entries, registryID)
+}
@@ -2,6 +2,7 @@ package registries
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	httperror "github.com/portainer/libhttp/error"
@@ -15,6 +16,8 @@ func hideFields(registry *portainer.Registry) {
 	registry.ManagementConfiguration = nil
 }
 
+const registryRequestTimeout = 30 * time.Second
+
 // Handler is the HTTP handler used to handle registry operations.
 type Handler struct {
 	*mux.Router
@@ -22,13 +25,18 @@ type Handler struct {
 	DataStore      portainer.DataStore
 	FileService    portainer.FileService
 	ProxyManager   *proxy.Manager
+
+	catalogCache       *catalogCache
+	registryHTTPClient *http.Client
 }
 
 // NewHandler creates a handler to manage registry operations.
 func NewHandler(bouncer *security.RequestBouncer) *Handler {
 	h := &Handler{
-		Router:         mux.NewRouter(),
-		requestBouncer: bouncer,
+		Router:             mux.NewRouter(),
+		requestBouncer:     bouncer,
+		catalogCache:       newCatalogCache(),
+		registryHTTPClient: &http.Client{Timeout: registryRequestTimeout},
 	}
 
 	h.Handle("/registries",
@@ -39,6 +47,12 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.registryInspect))).Methods(http.MethodGet)
 	h.Handle("/registries/{id}",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.registryUpdate))).Methods(http.MethodPut)
+	h.Handle("/registries/{id}/credentials",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.registryCredentialsUpdate))).Methods(http.MethodPut)
+	h.Handle("/registries/{id}/repositories",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.registryRepositoryList))).Methods(http.MethodGet)
+	h.Handle("/registries/{id}/repositories/{repository:.+}/tags",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.registryRepositoryTagList))).Methods(http.MethodGet)
 	h.Handle("/registries/{id}/configure",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.registryConfigure))).Methods(http.MethodPost)
 	h.Handle("/registries/{id}",
@@ -0,0 +1,244 @@
+package registries
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/registryclient"
+)
+
+const (
+	credentialsAssociationRegistry           = "registry"
+	credentialsAssociationRegistryManagement = "registry_management"
+	credentialsAssociationDockerHub          = "dockerhub"
+)
+
+type registryCredentialsUpdatePayload struct {
+	// Username used to authenticate against the registry
+	Username string `example:"registry_user" validate:"required"`
+	// Password used to authenticate against the registry
+	Password string `example:"registry_password" validate:"required"`
+	// Also update the other places using the previous credentials of the registry for the same registry host:
+	// the registry management configurations, the other registries and the DockerHub
+	Cascade bool `example:"true"`
+}
+
+func (payload *registryCredentialsUpdatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Username) {
+		return errors.New("Invalid username")
+	}
+	if govalidator.IsNull(payload.Password) {
+		return errors.New("Invalid password")
+	}
+	return nil
+}
+
+type (
+	// credentialsAssociation represents a place where the credentials of a registry are stored
+	credentialsAssociation struct {
+		// Type of the association. Valid values are: registry, registry_management or dockerhub
+		Type string `json:"Type" example:"registry"`
+		// Identifier of the registry, 0 for the DockerHub
+		RegistryID portainer.RegistryID `json:"RegistryId" example:"1"`
+		Name       string               `json:"Name" example:"my-registry"`
+	}
+
+	registryCredentialsUpdateResponse struct {
+		// Places where the credentials were updated
+		Updated []credentialsAssociation `json:"Updated"`
+	}
+)
+
+// @id RegistryCredentialsUpdate
+// @summary Rotate the credentials of a registry
+// @description Update the credentials of a registry and, when cascade is set, of all the places storing the previous credentials
+// @description of the registry for the same registry host: the registry management configurations, the other registries and the DockerHub.
+// @description The new credentials are verified against the registry before any change is saved, nothing is updated when the registry
+// @description rejects them. The changes already saved are rolled back when one of the updates fails.
+// @description The registry credentials are only stored on the registries and the DockerHub, the endpoints only hold access policies
+// @description to the registries and have no credentials of their own to update.
+// @description **Access policy**: administrator
+// @tags registries
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Registry identifier"
+// @param body body registryCredentialsUpdatePayload true "Registry credentials"
+// @success 200 {object} registryCredentialsUpdateResponse "Success"
+// @failure 400 "Invalid request or credentials rejected by the registry"
+// @failure 404 "Registry not found"
+// @failure 500 "Server error"
+// @router /registries/{id}/credentials [put]
+func (handler *Handler) registryCredentialsUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	registryID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid registry identifier route variable", err}
+	}
+
+	var payload registryCredentialsUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	registry, err := handler.DataStore.Registry().Registry(portainer.RegistryID(registryID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a registry with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a registry with the specified identifier inside the database", err}
+	}
+
+	credentials := &registryclient.Credentials{Username: payload.Username, Password: payload.Password}
+	err = registryclient.NewClient(handler.registryHTTPClient, registry.URL, credentials).Ping()
+	if err == registryclient.ErrForbidden {
+		return &httperror.HandlerError{http.StatusBadRequest, "The new credentials are rejected by the registry", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Unable to verify the new credentials against the registry", err}
+	}
+
+	registries := []portainer.Registry{*registry}
+	var dockerhub *portainer.DockerHub
+
+	if payload.Cascade && registry.Authentication {
+		registries, dockerhub, err = handler.registriesSharingCredentials(registry)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the registries from the database", err}
+		}
+	}
+
+	updated, err := handler.rotateCredentials(registry, registries, dockerhub, payload.Username, payload.Password, payload.Cascade)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the credentials inside the database, the changes were rolled back", err}
+	}
+
+	return response.JSON(w, &registryCredentialsUpdateResponse{Updated: updated})
+}
+
+// registriesSharingCredentials returns the registries of the same registry host using the credentials of the registry,
+// starting with the registry, and the DockerHub when it uses them
+func (handler *Handler) registriesSharingCredentials(registry *portainer.Registry) ([]portainer.Registry, *portainer.DockerHub, error) {
+	host := registryclient.NormalizeRegistry(registry.URL)
+
+	allRegistries, err := handler.DataStore.Registry().Registries()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	registries := []portainer.Registry{*registry}
+	for _, candidate := range allRegistries {
+		if candidate.ID == registry.ID || !strings.EqualFold(registryclient.NormalizeRegistry(candidate.URL), host) {
+			continue
+		}
+
+		if usesCredentials(&candidate, registry.Username, registry.Password) {
+			registries = append(registries, candidate)
+		}
+	}
+
+	if host != registryclient.DockerHubRegistry {
+		return registries, nil, nil
+	}
+
+	dockerhub, err := handler.DataStore.DockerHub().DockerHub()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !dockerhub.Authentication || dockerhub.Username != registry.Username || dockerhub.Password != registry.Password {
+		dockerhub = nil
+	}
+
+	return registries, dockerhub, nil
+}
+
+func usesCredentials(registry *portainer.Registry, username, password string) bool {
+	if registry.Authentication && registry.Username == username && registry.Password == password {
+		return true
+	}
+
+	return usesManagementCredentials(registry, username, password)
+}
+
+func usesManagementCredentials(registry *portainer.Registry, username, password string) bool {
+	configuration := registry.ManagementConfiguration
+	return configuration != nil && configuration.Authentication && configuration.Username == username && configuration.Password == password
+}
+
+// rotateCredentials saves the new credentials in the registries and the DockerHub. The registry is always updated,
+// the other registries and the management configurations only when cascading, where they use the previous credentials of the registry.
+// The registries already saved are restored when an update fails, the DockerHub is updated last.
+// The endpoints are left untouched: they reference the registries by identifier and store no registry credentials.
+func (handler *Handler) rotateCredentials(registry *portainer.Registry, registries []portainer.Registry, dockerhub *portainer.DockerHub, username, password string, cascade bool) ([]credentialsAssociation, error) {
+	previousUsername, previousPassword := registry.Username, registry.Password
+	previousAuthentication := registry.Authentication
+
+	updated := make([]credentialsAssociation, 0)
+	saved := make([]portainer.Registry, 0, len(registries))
+
+	for _, original := range registries {
+		target := original
+		if original.ManagementConfiguration != nil {
+			configuration := *original.ManagementConfiguration
+			target.ManagementConfiguration = &configuration
+		}
+
+		if target.ID == registry.ID || (target.Authentication && target.Username == previousUsername && target.Password == previousPassword) {
+			target.Authentication = true
+			target.Username = username
+			target.Password = password
+			updated = append(updated, credentialsAssociation{Type: credentialsAssociationRegistry, RegistryID: target.ID, Name: target.Name})
+		}
+
+		if cascade && previousAuthentication && usesManagementCredentials(&original, previousUsername, previousPassword) {
+			target.ManagementConfiguration.Username = username
+			target.ManagementConfiguration.Password = password
+			updated = append(updated, credentialsAssociation{Type: credentialsAssociationRegistryManagement, RegistryID: target.ID, Name: target.Name})
+		}
+
+		err := handler.DataStore.Registry().UpdateRegistry(target.ID, &target)
+		if err != nil {
+			handler.restoreCredentials(saved)
+			return nil, err
+		}
+		saved = append(saved, original)
+	}
+
+	if dockerhub != nil {
+		dockerhub.Username = username
+		dockerhub.Password = password
+
+		err := handler.DataStore.DockerHub().UpdateDockerHub(dockerhub)
+		if err != nil {
+			handler.restoreCredentials(saved)
+			return nil, err
+		}
+		updated = append(updated, credentialsAssociation{Type: credentialsAssociationDockerHub, Name: "DockerHub"})
+
+		registryclient.InvalidateTokens(registryclient.DockerHubRegistry)
+	}
+
+	for _, target := range registries {
+		handler.catalogCache.invalidate(target.ID)
+		registryclient.InvalidateTokens(target.URL)
+	}
+
+	return updated, nil
+}
+
+// restoreCredentials saves the registries as they were before the rotation
+func (handler *Handler) restoreCredentials(registries []portainer.Registry) {
+	for idx := range registries {
+		err := handler.DataStore.Registry().UpdateRegistry(registries[idx].ID, &registries[idx])
+		if err != nil {
+			log.Printf("[ERROR] [http,registries] [registry: %s] [message: unable to roll back the registry credentials] [error: %s]", registries[idx].Name, err)
+		}
+	}
+}
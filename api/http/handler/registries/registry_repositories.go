@@ -0,0 +1,183 @@
+package registries
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/internal/registryclient"
+)
+
+const (
+	defaultCatalogPageSize = 100
+	maxCatalogPageSize     = 1000
+)
+
+type registryRepositoriesResponse struct {
+	// Names of the repositories
+	Repositories []string `json:"Repositories" example:"library/nginx"`
+	// Name of the last repository of the page, to use as the last parameter to retrieve the next page. Empty when there is no next page
+	Next string `json:"Next" example:"library/nginx"`
+}
+
+type registryTagsResponse struct {
+	// Name of the repository
+	Name string `json:"Name" example:"library/nginx"`
+	// Tags of the repository
+	Tags []string `json:"Tags" example:"latest"`
+	// Last tag of the page, to use as the last parameter to retrieve the next page. Empty when there is no next page
+	Next string `json:"Next" example:"latest"`
+}
+
+// @id RegistryRepositoryList
+// @summary List the repositories of a registry
+// @description List the repositories of a registry using the catalog API of the registry and the credentials of the registry.
+// @description Results are cached for a short period of time. Registries that do not allow catalog listing return a 403 or a 501 error.
+// @description **Access policy**: restricted
+// @tags registries
+// @security jwt
+// @produce json
+// @param id path int true "Registry identifier"
+// @param n query int false "Maximum number of repositories to return (default 100, maximum 1000)"
+// @param last query string false "Return the repositories after this repository, used for pagination"
+// @success 200 {object} registryRepositoriesResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied to access registry or catalog listing forbidden by the registry"
+// @failure 404 "Registry not found"
+// @failure 500 "Server error"
+// @failure 501 "Catalog listing not supported by the registry"
+// @router /registries/{id}/repositories [get]
+func (handler *Handler) registryRepositoryList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	registry, size, last, handlerErr := handler.retrieveCatalogParameters(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	cacheKey := catalogCacheKey("", size, last)
+	page := handler.catalogCache.get(registry.ID, cacheKey)
+	if page == nil {
+		var err error
+		page, err = handler.registryClient(registry).Catalog(size, last)
+		if err != nil {
+			return registryError(err, http.StatusNotImplemented, "Catalog listing is not supported by the registry")
+		}
+		handler.catalogCache.set(registry.ID, cacheKey, page)
+	}
+
+	repositories := page.Items
+	if repositories == nil {
+		repositories = []string{}
+	}
+
+	return response.JSON(w, &registryRepositoriesResponse{Repositories: repositories, Next: page.Next})
+}
+
+// @id RegistryRepositoryTagList
+// @summary List the tags of a repository
+// @description List the tags of a repository of a registry, using the credentials of the registry.
+// @description The repository name must match the repository name grammar of the registry API (e.g. library/nginx).
+// @description Results are cached for a short period of time.
+// @description **Access policy**: restricted
+// @tags registries
+// @security jwt
+// @produce json
+// @param id path int true "Registry identifier"
+// @param repository path string true "Repository name"
+// @param n query int false "Maximum number of tags to return (default 100, maximum 1000)"
+// @param last query string false "Return the tags after this tag, used for pagination"
+// @success 200 {object} registryTagsResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied to access registry or repository"
+// @failure 404 "Registry or repository not found"
+// @failure 500 "Server error"
+// @router /registries/{id}/repositories/{repository}/tags [get]
+func (handler *Handler) registryRepositoryTagList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	repository, err := request.RetrieveRouteVariableValue(r, "repository")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid repository route variable", err}
+	}
+
+	err = registryclient.ValidateRepositoryName(repository)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid repository route variable. Must be a valid repository name", err}
+	}
+
+	registry, size, last, handlerErr := handler.retrieveCatalogParameters(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	cacheKey := catalogCacheKey(repository, size, last)
+	page := handler.catalogCache.get(registry.ID, cacheKey)
+	if page == nil {
+		page, err = handler.registryClient(registry).Tags(repository, size, last)
+		if err != nil {
+			return registryError(err, http.StatusNotFound, "Unable to find the repository inside the registry")
+		}
+		handler.catalogCache.set(registry.ID, cacheKey, page)
+	}
+
+	tags := page.Items
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return response.JSON(w, &registryTagsResponse{Name: repository, Tags: tags, Next: page.Next})
+}
+
+func (handler *Handler) retrieveCatalogParameters(r *http.Request) (*portainer.Registry, int, string, *httperror.HandlerError) {
+	registryID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, 0, "", &httperror.HandlerError{http.StatusBadRequest, "Invalid registry identifier route variable", err}
+	}
+
+	size, err := request.RetrieveNumericQueryParameter(r, "n", true)
+	if err != nil || size < 0 || size > maxCatalogPageSize {
+		return nil, 0, "", &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: n. Must be between 1 and 1000", errors.New("Invalid page size")}
+	}
+	if size == 0 {
+		size = defaultCatalogPageSize
+	}
+
+	last, _ := request.RetrieveQueryParameter(r, "last", true)
+
+	registry, err := handler.DataStore.Registry().Registry(portainer.RegistryID(registryID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, 0, "", &httperror.HandlerError{http.StatusNotFound, "Unable to find a registry with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, 0, "", &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a registry with the specified identifier inside the database", err}
+	}
+
+	err = handler.requestBouncer.RegistryAccess(r, registry)
+	if err != nil {
+		return nil, 0, "", &httperror.HandlerError{http.StatusForbidden, "Permission denied to access registry", httperrors.ErrEndpointAccessDenied}
+	}
+
+	return registry, size, last, nil
+}
+
+func (handler *Handler) registryClient(registry *portainer.Registry) *registryclient.Client {
+	var credentials *registryclient.Credentials
+	if registry.Authentication {
+		credentials = &registryclient.Credentials{Username: registry.Username, Password: registry.Password}
+	}
+
+	return registryclient.NewClient(handler.registryHTTPClient, registry.URL, credentials)
+}
+
+// registryError converts an error returned by the registry, a resource not found by the registry
+// is reported using notFoundStatus and notFoundMessage.
+func registryError(err error, notFoundStatus int, notFoundMessage string) *httperror.HandlerError {
+	switch err {
+	case registryclient.ErrForbidden:
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied by the registry with the registry credentials", err}
+	case registryclient.ErrNotFound:
+		return &httperror.HandlerError{notFoundStatus, notFoundMessage, err}
+	}
+	return &httperror.HandlerError{http.StatusInternalServerError, "Unable to query the registry", err}
+}
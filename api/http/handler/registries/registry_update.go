@@ -9,6 +9,7 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/registryclient"
 )
 
 type registryUpdatePayload struct {
@@ -64,6 +65,7 @@ func (handler *Handler) registryUpdate(w http.ResponseWriter, r *http.Request) *
 	} else if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a registry with the specified identifier inside the database", err}
 	}
+	previousURL := registry.URL
 
 	if payload.Name != nil {
 		registry.Name = *payload.Name
@@ -114,6 +116,9 @@ func (handler *Handler) registryUpdate(w http.ResponseWriter, r *http.Request) *
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist registry changes inside the database", err}
 	}
+	handler.catalogCache.invalidate(registry.ID)
+	registryclient.InvalidateTokens(previousURL)
+	registryclient.InvalidateTokens(registry.URL)
 
 	return response.JSON(w, registry)
 }
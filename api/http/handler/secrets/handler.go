@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/secret"
+)
+
+// Handler is the HTTP handler used to handle secret operations.
+type Handler struct {
+	*mux.Router
+	DataStore     portainer.DataStore
+	SecretService *secret.Service
+}
+
+// NewHandler creates a handler to manage secret operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/secrets",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.secretCreate))).Methods(http.MethodPost)
+	h.Handle("/secrets",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.secretList))).Methods(http.MethodGet)
+	h.Handle("/secrets/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.secretInspect))).Methods(http.MethodGet)
+	h.Handle("/secrets/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.secretUpdate))).Methods(http.MethodPut)
+	h.Handle("/secrets/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.secretDelete))).Methods(http.MethodDelete)
+
+	return h
+}
+
+// hideFields removes the encrypted value of a secret from the responses
+func hideFields(secret *portainer.Secret) {
+	secret.Value = ""
+}
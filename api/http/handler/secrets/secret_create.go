@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/secret"
+)
+
+type secretCreatePayload struct {
+	// Name of the secret, used to reference the secret from the containers
+	Name string `validate:"required" example:"db_password"`
+	// Value of the secret
+	Value string `validate:"required" example:"s3cr3t"`
+}
+
+func (payload *secretCreatePayload) Validate(r *http.Request) error {
+	err := secret.ValidateName(payload.Name)
+	if err != nil {
+		return err
+	}
+	if govalidator.IsNull(payload.Value) {
+		return errors.New("Invalid secret value")
+	}
+	return nil
+}
+
+// @id SecretCreate
+// @summary Create a new secret
+// @description Create a new secret. The value is encrypted before being stored and is never returned by the API.
+// @description The secret can then be materialized as a file of a tmpfs volume of the containers referencing it.
+// @description **Access policy**: administrator
+// @tags secrets
+// @security jwt
+// @accept json
+// @produce json
+// @param body body secretCreatePayload true "Secret details"
+// @success 200 {object} portainer.Secret "Success"
+// @failure 400 "Invalid request"
+// @failure 409 "Secret name exists"
+// @failure 500 "Server error"
+// @router /secrets [post]
+func (handler *Handler) secretCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload secretCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	secrets, err := handler.DataStore.Secret().Secrets()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve secrets from the database", err}
+	}
+
+	for _, existingSecret := range secrets {
+		if existingSecret.Name == payload.Name {
+			return &httperror.HandlerError{http.StatusConflict, "This name is already associated to a secret", errors.New("A secret already exists with this name")}
+		}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
+	}
+
+	value, err := handler.SecretService.EncryptValue(payload.Value)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to encrypt the secret value", err}
+	}
+
+	now := time.Now().Unix()
+	newSecret := &portainer.Secret{
+		Name:         payload.Name,
+		Value:        value,
+		CreationDate: now,
+		UpdateDate:   now,
+		CreatedBy:    tokenData.Username,
+	}
+
+	err = handler.DataStore.Secret().CreateSecret(newSecret)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the secret inside the database", err}
+	}
+
+	hideFields(newSecret)
+	return response.JSON(w, newSecret)
+}
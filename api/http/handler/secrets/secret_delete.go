@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id SecretDelete
+// @summary Remove a secret
+// @description Remove a secret. A secret referenced by a stack cannot be removed.
+// @description **Access policy**: administrator
+// @tags secrets
+// @security jwt
+// @param id path int true "Secret identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Secret not found"
+// @failure 409 "Secret referenced by a stack"
+// @failure 500 "Server error"
+// @router /secrets/{id} [delete]
+func (handler *Handler) secretDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	secretID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid secret identifier route variable", err}
+	}
+
+	secret, err := handler.DataStore.Secret().Secret(portainer.SecretID(secretID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a secret with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a secret with the specified identifier inside the database", err}
+	}
+
+	stacks, err := handler.DataStore.Stack().Stacks()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stacks from the database", err}
+	}
+
+	for _, stack := range stacks {
+		for _, references := range stack.Secrets {
+			for _, reference := range references {
+				if reference.Name == secret.Name {
+					errorMessage := fmt.Sprintf("The secret is referenced by the stack %s", stack.Name)
+					return &httperror.HandlerError{http.StatusConflict, errorMessage, errors.New(errorMessage)}
+				}
+			}
+		}
+	}
+
+	err = handler.DataStore.Secret().DeleteSecret(secret.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the secret from the database", err}
+	}
+
+	return response.Empty(w)
+}
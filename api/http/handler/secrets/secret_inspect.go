@@ -0,0 +1,41 @@
+package secrets
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id SecretInspect
+// @summary Inspect a secret
+// @description Retrieve details about a secret, without its value.
+// @description **Access policy**: administrator
+// @tags secrets
+// @security jwt
+// @produce json
+// @param id path int true "Secret identifier"
+// @success 200 {object} portainer.Secret "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Secret not found"
+// @failure 500 "Server error"
+// @router /secrets/{id} [get]
+func (handler *Handler) secretInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	secretID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid secret identifier route variable", err}
+	}
+
+	secret, err := handler.DataStore.Secret().Secret(portainer.SecretID(secretID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a secret with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a secret with the specified identifier inside the database", err}
+	}
+
+	hideFields(secret)
+	return response.JSON(w, secret)
+}
@@ -0,0 +1,31 @@
+package secrets
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id SecretList
+// @summary List secrets
+// @description List the secrets, without their value.
+// @description **Access policy**: administrator
+// @tags secrets
+// @security jwt
+// @produce json
+// @success 200 {array} portainer.Secret "Success"
+// @failure 500 "Server error"
+// @router /secrets [get]
+func (handler *Handler) secretList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	secrets, err := handler.DataStore.Secret().Secrets()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve secrets from the database", err}
+	}
+
+	for idx := range secrets {
+		hideFields(&secrets[idx])
+	}
+
+	return response.JSON(w, secrets)
+}
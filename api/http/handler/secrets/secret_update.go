@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+type secretUpdatePayload struct {
+	// New value of the secret
+	Value string `validate:"required" example:"s3cr3t"`
+}
+
+func (payload *secretUpdatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Value) {
+		return errors.New("Invalid secret value")
+	}
+	return nil
+}
+
+// @id SecretUpdate
+// @summary Update the value of a secret
+// @description Update the value of a secret. The new value is materialized again in the running containers referencing the secret.
+// @description **Access policy**: administrator
+// @tags secrets
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Secret identifier"
+// @param body body secretUpdatePayload true "Secret value"
+// @success 200 {object} portainer.Secret "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Secret not found"
+// @failure 500 "Server error"
+// @router /secrets/{id} [put]
+func (handler *Handler) secretUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	secretID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid secret identifier route variable", err}
+	}
+
+	var payload secretUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	secret, err := handler.DataStore.Secret().Secret(portainer.SecretID(secretID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a secret with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a secret with the specified identifier inside the database", err}
+	}
+
+	secret.Value, err = handler.SecretService.EncryptValue(payload.Value)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to encrypt the secret value", err}
+	}
+	secret.UpdateDate = time.Now().Unix()
+
+	err = handler.DataStore.Secret().UpdateSecret(secret.ID, secret)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the secret changes inside the database", err}
+	}
+
+	handler.SecretService.Refresh()
+
+	hideFields(secret)
+	return response.JSON(w, secret)
+}
@@ -7,11 +7,15 @@ import (
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/mailer"
 )
 
 func hideFields(settings *portainer.Settings) {
 	settings.LDAPSettings.Password = ""
 	settings.OAuthSettings.ClientSecret = ""
+	settings.SMTPSettings.Password = ""
+	settings.MetricsSettings.BearerToken = ""
+	settings.MetricsSettings.BearerTokenHash = ""
 }
 
 // Handler is the HTTP handler used to handle settings operations.
@@ -22,6 +26,8 @@ type Handler struct {
 	JWTService      portainer.JWTService
 	LDAPService     portainer.LDAPService
 	SnapshotService portainer.SnapshotService
+	Mailer          *mailer.Service
+	Flags           *portainer.CLIFlags
 }
 
 // NewHandler creates a handler to manage settings operations.
@@ -33,10 +39,14 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsInspect))).Methods(http.MethodGet)
 	h.Handle("/settings",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsUpdate))).Methods(http.MethodPut)
+	h.Handle("/settings/effective",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsEffective))).Methods(http.MethodGet)
 	h.Handle("/settings/public",
 		bouncer.PublicAccess(httperror.LoggerHandler(h.settingsPublic))).Methods(http.MethodGet)
 	h.Handle("/settings/authentication/checkLDAP",
 		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsLDAPCheck))).Methods(http.MethodPut)
+	h.Handle("/settings/smtp/test",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.settingsSMTPTest))).Methods(http.MethodPost)
 
 	return h
 }
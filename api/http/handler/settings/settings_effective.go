@@ -0,0 +1,221 @@
+package settings
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+)
+
+// agentSecretEnvVar is the environment variable defining the secret shared with the agents
+const agentSecretEnvVar = "AGENT_SECRET"
+
+// redactedSettings are the secrets of the settings stored in the database
+var redactedSettings = map[string]bool{
+	"LDAPSettings.Password":           true,
+	"OAuthSettings.ClientSecret":      true,
+	"SMTPSettings.Password":           true,
+	"MetricsSettings.BearerToken":     true,
+	"MetricsSettings.BearerTokenHash": true,
+}
+
+// startupFlagSettings are the settings overwritten by a CLI flag on each start of the instance, per setting name
+var startupFlagSettings = map[string]string{
+	"LogoURL":                   "logo",
+	"SnapshotInterval":          "snapshot-interval",
+	"EnableEdgeComputeFeatures": "edge-compute",
+	"TemplatesURL":              "templates",
+	"BlackListedLabels":         "hide-label",
+}
+
+// @id SettingsEffective
+// @summary Retrieve the effective settings
+// @description Retrieve the effective value of the CLI flags, environment variables and settings stored in the database,
+// @description with the source each value was resolved from (default, env, flag or db) and whether it can be updated at runtime.
+// @description Secrets are redacted.
+// @description **Access policy**: administrator
+// @tags settings
+// @security jwt
+// @produce json
+// @success 200 {array} portainer.EffectiveSetting "Success"
+// @failure 500 "Server error"
+// @router /settings/effective [get]
+func (handler *Handler) settingsEffective(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	effectiveSettings := flagEffectiveSettings(handler.Flags)
+	effectiveSettings = append(effectiveSettings, envEffectiveSettings()...)
+
+	databaseSettings, err := databaseEffectiveSettings(settings, handler.Flags)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to resolve the effective settings", err}
+	}
+	effectiveSettings = append(effectiveSettings, databaseSettings...)
+
+	return response.JSON(w, effectiveSettings)
+}
+
+// flagEffectiveSettings returns the value of the CLI flags, which can only be changed by restarting the instance
+func flagEffectiveSettings(flags *portainer.CLIFlags) []portainer.EffectiveSetting {
+	values := []struct {
+		name   string
+		value  interface{}
+		secret bool
+	}{
+		{"bind", *flags.Addr, false},
+		{"tunnel-addr", *flags.TunnelAddr, false},
+		{"tunnel-port", *flags.TunnelPort, false},
+		{"assets", *flags.Assets, false},
+		{"data", *flags.Data, false},
+		{"host", *flags.EndpointURL, false},
+		{"edge-compute", *flags.EnableEdgeComputeFeatures, false},
+		{"no-analytics", *flags.NoAnalytics, false},
+		{"tlsverify", *flags.TLS, false},
+		{"tlsskipverify", *flags.TLSSkipVerify, false},
+		{"tlscacert", *flags.TLSCacert, false},
+		{"tlscert", *flags.TLSCert, false},
+		{"tlskey", *flags.TLSKey, false},
+		{"ssl", *flags.SSL, false},
+		{"sslcert", *flags.SSLCert, false},
+		{"sslkey", *flags.SSLKey, false},
+		{"snapshot-interval", *flags.SnapshotInterval, false},
+		{"degraded-probe-interval", *flags.DegradedProbeInterval, false},
+		{"admin-password", *flags.AdminPassword, true},
+		{"admin-password-file", *flags.AdminPasswordFile, false},
+		{"hide-label", *flags.Labels, false},
+		{"logo", *flags.Logo, false},
+		{"templates", *flags.Templates, false},
+	}
+
+	effectiveSettings := make([]portainer.EffectiveSetting, 0, len(values))
+	for _, flag := range values {
+		setting := portainer.EffectiveSetting{
+			Name:   "--" + flag.name,
+			Value:  flag.value,
+			Source: flagSource(flags, flag.name),
+		}
+
+		if flag.secret {
+			setting.Value = ""
+			setting.Redacted = true
+		}
+
+		effectiveSettings = append(effectiveSettings, setting)
+	}
+
+	return effectiveSettings
+}
+
+// envEffectiveSettings returns the settings only defined through environment variables
+func envEffectiveSettings() []portainer.EffectiveSetting {
+	source := portainer.SettingSourceDefault
+	if _, ok := os.LookupEnv(agentSecretEnvVar); ok {
+		source = portainer.SettingSourceEnv
+	}
+
+	return []portainer.EffectiveSetting{
+		{Name: agentSecretEnvVar, Value: "", Source: source, Redacted: true},
+	}
+}
+
+// databaseEffectiveSettings returns the settings stored in the database, sorted by name. A setting equal to the value
+// it had when the instance started is reported with the source of that value: the CLI flag overwriting it on start
+// or the default settings. Any other value has been updated at runtime and is reported as coming from the database.
+func databaseEffectiveSettings(settings *portainer.Settings, flags *portainer.CLIFlags) ([]portainer.EffectiveSetting, error) {
+	startupSettings := portainer.SettingsDefault()
+	portainer.ApplyFlagsToSettings(startupSettings, flags)
+
+	values, err := flattenSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	startupValues, err := flattenSettings(startupSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	effectiveSettings := make([]portainer.EffectiveSetting, 0, len(names))
+	for _, name := range names {
+		setting := portainer.EffectiveSetting{
+			Name:        name,
+			Value:       values[name],
+			Source:      portainer.SettingSourceDatabase,
+			Overridable: true,
+			Flag:        startupFlagSettings[name],
+		}
+
+		if startupValue, ok := startupValues[name]; ok && reflect.DeepEqual(startupValue, values[name]) {
+			setting.Source = portainer.SettingSourceDefault
+			if setting.Flag != "" {
+				setting.Source = flagSource(flags, setting.Flag)
+			}
+		}
+
+		if redactedSettings[name] {
+			setting.Value = ""
+			setting.Redacted = true
+		}
+
+		effectiveSettings = append(effectiveSettings, setting)
+	}
+
+	return effectiveSettings, nil
+}
+
+func flagSource(flags *portainer.CLIFlags, name string) portainer.SettingSource {
+	if source, ok := flags.Sources[name]; ok {
+		return source
+	}
+	return portainer.SettingSourceDefault
+}
+
+// flattenSettings returns the JSON representation of the settings where the nested objects are flattened,
+// the values are indexed by their path, e.g. LDAPSettings.TLSConfig.TLS
+func flattenSettings(settings *portainer.Settings) (map[string]interface{}, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]interface{}
+	err = json.Unmarshal(data, &object)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	flattenObject("", object, values)
+
+	return values, nil
+}
+
+func flattenObject(prefix string, object map[string]interface{}, values map[string]interface{}) {
+	for key, value := range object {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenObject(name, nested, values)
+			continue
+		}
+
+		values[name] = value
+	}
+}
@@ -0,0 +1,43 @@
+package settings
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_databaseEffectiveSettings(t *testing.T) {
+	logo, snapshotInterval, templates := "", "5m", ""
+	edgeCompute := false
+	labels := []portainer.Pair(nil)
+	flags := &portainer.CLIFlags{
+		Logo:                      &logo,
+		SnapshotInterval:          &snapshotInterval,
+		EnableEdgeComputeFeatures: &edgeCompute,
+		Templates:                 &templates,
+		Labels:                    &labels,
+		Sources:                   map[string]portainer.SettingSource{"snapshot-interval": portainer.SettingSourceFlag},
+	}
+
+	settings := portainer.SettingsDefault()
+	portainer.ApplyFlagsToSettings(settings, flags)
+	settings.UserSessionTimeout = "1h"
+	settings.SMTPSettings.Password = "secret"
+
+	effectiveSettings, err := databaseEffectiveSettings(settings, flags)
+	assert.NoError(t, err)
+
+	byName := map[string]portainer.EffectiveSetting{}
+	for _, setting := range effectiveSettings {
+		byName[setting.Name] = setting
+	}
+
+	assert.Equal(t, portainer.SettingSourceFlag, byName["SnapshotInterval"].Source)
+	assert.Equal(t, "snapshot-interval", byName["SnapshotInterval"].Flag)
+	assert.Equal(t, portainer.SettingSourceDatabase, byName["UserSessionTimeout"].Source)
+	assert.Equal(t, portainer.SettingSourceDefault, byName["TemplatesURL"].Source)
+	assert.Equal(t, portainer.SettingSourceDefault, byName["LDAPSettings.AnonymousMode"].Source)
+	assert.True(t, byName["SMTPSettings.Password"].Redacted)
+	assert.Equal(t, "", byName["SMTPSettings.Password"].Value)
+}
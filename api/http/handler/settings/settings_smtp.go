@@ -0,0 +1,131 @@
+package settings
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/mailer"
+)
+
+type settingsSMTPTestPayload struct {
+	// Address the test email is sent to
+	Recipient string `example:"admin@mydomain.tld" validate:"required"`
+	// SMTP settings to test. The stored settings are used when not specified.
+	// The stored password is used when no password is specified.
+	SMTPSettings *portainer.SMTPSettings
+}
+
+type settingsSMTPTestResponse struct {
+	// Response of the SMTP server to the submission of the test email
+	Response string `example:"250 2.0.0 Ok: queued as 5C1F1A8E2"`
+}
+
+func (payload *settingsSMTPTestPayload) Validate(r *http.Request) error {
+	if !govalidator.IsEmail(payload.Recipient) {
+		return errors.New("Invalid recipient. Must be a valid email address")
+	}
+	if payload.SMTPSettings != nil {
+		return validateSMTPSettings(payload.SMTPSettings)
+	}
+	return nil
+}
+
+func validateSMTPSettings(settings *portainer.SMTPSettings) error {
+	if settings.Host == "" {
+		return nil
+	}
+	if settings.Port < 1 || settings.Port > 65535 {
+		return errors.New("Invalid SMTP port. Must be between 1 and 65535")
+	}
+	if settings.Security != portainer.SMTPSecurityNone && settings.Security != portainer.SMTPSecurityTLS && settings.Security != portainer.SMTPSecuritySTARTTLS {
+		return errors.New("Invalid SMTP security value. Value must be one of: 1 (none), 2 (TLS) or 3 (STARTTLS)")
+	}
+	if !govalidator.IsEmail(settings.FromAddress) {
+		return errors.New("Invalid SMTP from address. Must be a valid email address")
+	}
+	if settings.Authentication && govalidator.IsNull(settings.Username) {
+		return errors.New("Invalid SMTP username. Required when authentication is enabled")
+	}
+	return nil
+}
+
+// @id SettingsSMTPTest
+// @summary Test the SMTP settings
+// @description Send a test email using the specified SMTP settings, or the stored settings when none are specified,
+// @description and return the response of the SMTP server.
+// @description **Access policy**: administrator
+// @tags settings
+// @security jwt
+// @accept json
+// @produce json
+// @param body body settingsSMTPTestPayload true "Test details"
+// @success 200 {object} settingsSMTPTestResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /settings/smtp/test [post]
+func (handler *Handler) settingsSMTPTest(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload settingsSMTPTestPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	smtpSettings := &settings.SMTPSettings
+	if payload.SMTPSettings != nil {
+		smtpSettings, err = handler.mergeSMTPSettings(payload.SMTPSettings, &settings.SMTPSettings)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to encrypt the SMTP password", err}
+		}
+	}
+
+	if smtpSettings.Host == "" {
+		return &httperror.HandlerError{http.StatusBadRequest, "No SMTP server configured", mailer.ErrNotConfigured}
+	}
+
+	message := &mailer.Message{
+		To:      []string{payload.Recipient},
+		Subject: "Portainer test email",
+		Body:    "This is a test email sent by Portainer to verify the SMTP settings.",
+	}
+
+	serverResponse, err := handler.Mailer.SendWithSettings(smtpSettings, message)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to send the test email: " + err.Error(), err}
+	}
+
+	return response.JSON(w, &settingsSMTPTestResponse{Response: serverResponse})
+}
+
+// mergeSMTPSettings returns the SMTP settings to store based on the specified settings, with an encrypted password.
+// The current password is kept when no password is specified.
+func (handler *Handler) mergeSMTPSettings(settings *portainer.SMTPSettings, current *portainer.SMTPSettings) (*portainer.SMTPSettings, error) {
+	merged := *settings
+
+	if !merged.Authentication {
+		merged.Password = ""
+		return &merged, nil
+	}
+
+	if merged.Password == "" {
+		merged.Password = current.Password
+		return &merged, nil
+	}
+
+	password, err := handler.Mailer.EncryptPassword(merged.Password)
+	if err != nil {
+		return nil, err
+	}
+	merged.Password = password
+
+	return &merged, nil
+}
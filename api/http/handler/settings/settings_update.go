@@ -2,6 +2,7 @@ package settings
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -10,7 +11,14 @@ import (
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
 	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/internal/containerjob"
+	"github.com/portainer/portainer/api/internal/crashloop"
+	"github.com/portainer/portainer/api/internal/imagetrust"
+	"github.com/portainer/portainer/api/internal/stackutils"
+	"github.com/portainer/portainer/api/internal/tag"
+	"github.com/portainer/portainer/api/kubernetes/cli"
 )
 
 type settingsUpdatePayload struct {
@@ -34,6 +42,34 @@ type settingsUpdatePayload struct {
 	UserSessionTimeout *string `example:"5m"`
 	// Whether telemetry is enabled
 	EnableTelemetry *bool `example:"false"`
+	// Resource quotas applied to each non-administrator user
+	UserResourceQuotas *portainer.ResourceQuotas
+	// Resource quotas applied to each team
+	TeamResourceQuotas *portainer.ResourceQuotas
+	// Limits applied to streaming subscriptions (events, logs, stats)
+	StreamSettings *portainer.StreamSettings
+	// Image signature verification policy applied before deploying containers, services and stacks
+	ImageTrustPolicy *portainer.ImageTrustPolicy
+	// Policy applied to the images built too long ago, before deploying containers, services and stacks
+	ImageAgePolicy *portainer.ImageAgePolicy
+	// SMTP server used to send email notifications. The current password is kept when no password is specified
+	SMTPSettings *portainer.SMTPSettings
+	// Default lifecycle of the one-off container jobs
+	ContainerJobLifecycle *portainer.ContainerJobLifecycle
+	// Named resource quota and limit range templates that can be applied to Kubernetes namespaces
+	KubernetesResourceTemplates []portainer.KubernetesResourceTemplate
+	// Prometheus metrics exposed for the containers of the Docker endpoints. The current bearer token is kept when no token is specified
+	MetricsSettings *portainer.MetricsSettings
+	// Detection of the crash-looping containers of the Docker endpoints
+	CrashLoopSettings *portainer.CrashLoopSettings
+	// Compose keys and values allowed in the stacks of non-administrator users
+	ComposePolicy *portainer.ComposePolicy
+	// Keys of the key/value tags that every endpoint must be associated to when it is created or its tags are updated
+	RequiredEndpointTagKeys []string `example:"environment,owner"`
+	// Notifications of the endpoint TLS client certificates about to expire
+	TLSCertExpirySettings *portainer.TLSCertExpirySettings
+	// Whether the images of every stack are pulled before each deployment
+	AlwaysPullImages *bool `example:"false"`
 }
 
 func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
@@ -52,6 +88,154 @@ func (payload *settingsUpdatePayload) Validate(r *http.Request) error {
 			return errors.New("Invalid user session timeout")
 		}
 	}
+	if payload.UserResourceQuotas != nil && !isValidResourceQuotas(payload.UserResourceQuotas) {
+		return errors.New("Invalid user resource quotas. Values must be positive or 0 for unlimited")
+	}
+	if payload.TeamResourceQuotas != nil && !isValidResourceQuotas(payload.TeamResourceQuotas) {
+		return errors.New("Invalid team resource quotas. Values must be positive or 0 for unlimited")
+	}
+	if payload.StreamSettings != nil && !isValidStreamSettings(payload.StreamSettings) {
+		return errors.New("Invalid stream settings. Limits and buffer size must be positive or 0 for default, slow client threshold must be a valid positive duration")
+	}
+	if payload.ImageTrustPolicy != nil {
+		err := validateImageTrustPolicy(payload.ImageTrustPolicy)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.ImageAgePolicy != nil {
+		err := imagetrust.ValidateAgePolicy(payload.ImageAgePolicy)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.SMTPSettings != nil {
+		err := validateSMTPSettings(payload.SMTPSettings)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.ContainerJobLifecycle != nil {
+		err := containerjob.ValidateLifecycle(payload.ContainerJobLifecycle)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.CrashLoopSettings != nil {
+		err := validateCrashLoopSettings(payload.CrashLoopSettings)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.ComposePolicy != nil {
+		err := stackutils.ValidateComposePolicy(payload.ComposePolicy)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.TLSCertExpirySettings != nil {
+		err := validateTLSCertExpirySettings(payload.TLSCertExpirySettings)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.RequiredEndpointTagKeys != nil {
+		err := tag.ValidateRequiredKeys(payload.RequiredEndpointTagKeys)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.KubernetesResourceTemplates != nil {
+		err := validateKubernetesResourceTemplates(payload.KubernetesResourceTemplates)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateCrashLoopSettings(settings *portainer.CrashLoopSettings) error {
+	err := crashloop.ValidatePolicy(&settings.Policy)
+	if err != nil {
+		return err
+	}
+
+	for _, recipient := range settings.NotificationRecipients {
+		if !govalidator.IsEmail(recipient) {
+			return fmt.Errorf("Invalid crash-loop notification recipient: %s", recipient)
+		}
+	}
+	return nil
+}
+
+func validateTLSCertExpirySettings(settings *portainer.TLSCertExpirySettings) error {
+	if settings.WarningDays < 0 {
+		return errors.New("Invalid TLS certificate expiry warning days. Value must be positive or 0 for default")
+	}
+
+	for _, recipient := range settings.NotificationRecipients {
+		if !govalidator.IsEmail(recipient) {
+			return fmt.Errorf("Invalid TLS certificate expiry notification recipient: %s", recipient)
+		}
+	}
+	return nil
+}
+
+func validateKubernetesResourceTemplates(templates []portainer.KubernetesResourceTemplate) error {
+	names := make(map[string]bool, len(templates))
+	for idx := range templates {
+		name := templates[idx].Name
+		if govalidator.IsNull(name) {
+			return errors.New("Invalid Kubernetes resource template. Name is required")
+		}
+		if names[name] {
+			return fmt.Errorf("Invalid Kubernetes resource template %s. Names must be unique", name)
+		}
+		names[name] = true
+
+		err := cli.ValidateResourceTemplate(&templates[idx])
+		if err != nil {
+			return fmt.Errorf("Invalid Kubernetes resource template %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func isValidResourceQuotas(quotas *portainer.ResourceQuotas) bool {
+	return quotas.MaxOwnedStacks >= 0 && quotas.MaxOwnedContainers >= 0 && quotas.MaxOwnedWebhooks >= 0
+}
+
+func isValidStreamSettings(settings *portainer.StreamSettings) bool {
+	if settings.MaxConcurrentStreams < 0 || settings.MaxConcurrentStreamsPerEndpoint < 0 || settings.BufferSize < 0 {
+		return false
+	}
+
+	if settings.SlowClientThreshold != "" {
+		threshold, err := time.ParseDuration(settings.SlowClientThreshold)
+		if err != nil || threshold <= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateImageTrustPolicy(policy *portainer.ImageTrustPolicy) error {
+	for _, trustRoot := range policy.TrustRoots {
+		if govalidator.IsNull(trustRoot.Registry) {
+			return errors.New("Invalid image trust root. Registry must be a registry URL or * to match any registry")
+		}
+
+		if len(trustRoot.PublicKeys) == 0 {
+			return errors.New("Invalid image trust root. At least one public key is required")
+		}
+
+		_, err := imagetrust.ParsePublicKeys(trustRoot.PublicKeys)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -147,6 +331,79 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		settings.EnableTelemetry = *payload.EnableTelemetry
 	}
 
+	if payload.UserResourceQuotas != nil {
+		settings.UserResourceQuotas = *payload.UserResourceQuotas
+	}
+
+	if payload.TeamResourceQuotas != nil {
+		settings.TeamResourceQuotas = *payload.TeamResourceQuotas
+	}
+
+	if payload.StreamSettings != nil {
+		settings.StreamSettings = *payload.StreamSettings
+	}
+
+	if payload.ImageTrustPolicy != nil {
+		settings.ImageTrustPolicy = *payload.ImageTrustPolicy
+	}
+
+	if payload.ImageAgePolicy != nil {
+		settings.ImageAgePolicy = *payload.ImageAgePolicy
+		if settings.ImageAgePolicy.Mode == "" {
+			settings.ImageAgePolicy.Mode = portainer.ImageAgePolicyWarn
+		}
+	}
+
+	if payload.ContainerJobLifecycle != nil {
+		settings.ContainerJobLifecycle = *payload.ContainerJobLifecycle
+	}
+
+	if payload.KubernetesResourceTemplates != nil {
+		settings.KubernetesResourceTemplates = payload.KubernetesResourceTemplates
+	}
+
+	if payload.CrashLoopSettings != nil {
+		settings.CrashLoopSettings = *payload.CrashLoopSettings
+	}
+
+	if payload.ComposePolicy != nil {
+		settings.ComposePolicy = *payload.ComposePolicy
+	}
+
+	if payload.RequiredEndpointTagKeys != nil {
+		settings.RequiredEndpointTagKeys = payload.RequiredEndpointTagKeys
+	}
+
+	if payload.TLSCertExpirySettings != nil {
+		settings.TLSCertExpirySettings = *payload.TLSCertExpirySettings
+	}
+
+	if payload.AlwaysPullImages != nil {
+		settings.AlwaysPullImages = *payload.AlwaysPullImages
+	}
+
+	if payload.MetricsSettings != nil {
+		bearerTokenHash := settings.MetricsSettings.BearerTokenHash
+		if payload.MetricsSettings.BearerToken != "" {
+			bearerTokenHash = crypto.HashToken(payload.MetricsSettings.BearerToken)
+		}
+		if payload.MetricsSettings.Enabled && bearerTokenHash == "" {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid metrics settings", errors.New("A bearer token is required to enable the metrics")}
+		}
+		settings.MetricsSettings = portainer.MetricsSettings{
+			Enabled:         payload.MetricsSettings.Enabled,
+			BearerTokenHash: bearerTokenHash,
+		}
+	}
+
+	if payload.SMTPSettings != nil {
+		smtpSettings, err := handler.mergeSMTPSettings(payload.SMTPSettings, &settings.SMTPSettings)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to encrypt the SMTP password", err}
+		}
+		settings.SMTPSettings = *smtpSettings
+	}
+
 	tlsError := handler.updateTLS(settings)
 	if tlsError != nil {
 		return tlsError
@@ -157,6 +414,7 @@ func (handler *Handler) settingsUpdate(w http.ResponseWriter, r *http.Request) *
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist settings changes inside the database", err}
 	}
 
+	hideFields(settings)
 	return response.JSON(w, settings)
 }
 
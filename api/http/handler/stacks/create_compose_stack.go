@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,7 +14,7 @@ import (
 	"github.com/portainer/libhttp/request"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
-	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/stackutils"
 )
 
 // this is coming from libcompose
@@ -32,6 +31,14 @@ type composeStackFromFileContentPayload struct {
 	StackFileContent string `example:"version: 3\n services:\n web:\n image:nginx" validate:"required"`
 	// A list of environment variables used during stack deployment
 	Env []portainer.Pair `example:""`
+	// Healthchecks applied to the services of the stack in place of the ones defined by their images, per service name
+	HealthcheckOverrides map[string]portainer.HealthcheckOverride
+	// Logging drivers applied to the services of the stack in place of the ones defined by the stack file, per service name
+	LoggingOverrides map[string]portainer.LogConfig
+	// Groups of services started one after the other, each group waiting for the gate of the previous one
+	StartupOrder []portainer.StackStartupGroup
+	// Maximum number of services updated at the same time during a redeploy, 0 for unbounded
+	UpdateConcurrency int `example:"1"`
 }
 
 func (payload *composeStackFromFileContentPayload) Validate(r *http.Request) error {
@@ -42,7 +49,19 @@ func (payload *composeStackFromFileContentPayload) Validate(r *http.Request) err
 	if govalidator.IsNull(payload.StackFileContent) {
 		return errors.New("Invalid stack file content")
 	}
-	return nil
+	err := stackutils.ValidateHealthcheckOverrides(payload.HealthcheckOverrides)
+	if err != nil {
+		return err
+	}
+	err = stackutils.ValidateLoggingOverrides(payload.LoggingOverrides)
+	if err != nil {
+		return err
+	}
+	err = stackutils.ValidateUpdateConcurrency(payload.UpdateConcurrency)
+	if err != nil {
+		return err
+	}
+	return stackutils.ValidateStartupOrder(payload.StartupOrder)
 }
 
 func (handler *Handler) createComposeStackFromFileContent(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
@@ -63,14 +82,18 @@ func (handler *Handler) createComposeStackFromFileContent(w http.ResponseWriter,
 
 	stackID := handler.DataStore.Stack().GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
-		Name:         payload.Name,
-		Type:         portainer.DockerComposeStack,
-		EndpointID:   endpoint.ID,
-		EntryPoint:   filesystem.ComposeFileDefaultName,
-		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
-		CreationDate: time.Now().Unix(),
+		ID:                   portainer.StackID(stackID),
+		Name:                 payload.Name,
+		Type:                 portainer.DockerComposeStack,
+		EndpointID:           endpoint.ID,
+		EntryPoint:           filesystem.ComposeFileDefaultName,
+		Env:                  payload.Env,
+		HealthcheckOverrides: payload.HealthcheckOverrides,
+		LoggingOverrides:     payload.LoggingOverrides,
+		StartupOrder:         payload.StartupOrder,
+		UpdateConcurrency:    payload.UpdateConcurrency,
+		Status:               portainer.StackStatusActive,
+		CreationDate:         time.Now().Unix(),
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -83,23 +106,25 @@ func (handler *Handler) createComposeStackFromFileContent(w http.ResponseWriter,
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
 
-	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deployComposeStack(config)
+	err = handler.DeployService.DeployComposeStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
-	stack.CreatedBy = config.user.Username
+	stack.CreatedBy = config.User.Username
 
 	err = handler.DataStore.Stack().CreateStack(stack)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
 	}
 
+	handler.recordStackVersionOrWarn(stack, config.User.Username)
+
 	doCleanUp = false
 	return handler.decorateStackResponse(w, stack, userID)
 }
@@ -123,6 +148,14 @@ type composeStackFromGitRepositoryPayload struct {
 
 	// A list of environment variables used during stack deployment
 	Env []portainer.Pair
+	// Healthchecks applied to the services of the stack in place of the ones defined by their images, per service name
+	HealthcheckOverrides map[string]portainer.HealthcheckOverride
+	// Logging drivers applied to the services of the stack in place of the ones defined by the stack file, per service name
+	LoggingOverrides map[string]portainer.LogConfig
+	// Groups of services started one after the other, each group waiting for the gate of the previous one
+	StartupOrder []portainer.StackStartupGroup
+	// Maximum number of services updated at the same time during a redeploy, 0 for unbounded
+	UpdateConcurrency int `example:"1"`
 }
 
 func (payload *composeStackFromGitRepositoryPayload) Validate(r *http.Request) error {
@@ -139,7 +172,19 @@ func (payload *composeStackFromGitRepositoryPayload) Validate(r *http.Request) e
 	if govalidator.IsNull(payload.ComposeFilePathInRepository) {
 		payload.ComposeFilePathInRepository = filesystem.ComposeFileDefaultName
 	}
-	return nil
+	err := stackutils.ValidateHealthcheckOverrides(payload.HealthcheckOverrides)
+	if err != nil {
+		return err
+	}
+	err = stackutils.ValidateLoggingOverrides(payload.LoggingOverrides)
+	if err != nil {
+		return err
+	}
+	err = stackutils.ValidateUpdateConcurrency(payload.UpdateConcurrency)
+	if err != nil {
+		return err
+	}
+	return stackutils.ValidateStartupOrder(payload.StartupOrder)
 }
 
 func (handler *Handler) createComposeStackFromGitRepository(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
@@ -160,14 +205,18 @@ func (handler *Handler) createComposeStackFromGitRepository(w http.ResponseWrite
 
 	stackID := handler.DataStore.Stack().GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
-		Name:         payload.Name,
-		Type:         portainer.DockerComposeStack,
-		EndpointID:   endpoint.ID,
-		EntryPoint:   payload.ComposeFilePathInRepository,
-		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
-		CreationDate: time.Now().Unix(),
+		ID:                   portainer.StackID(stackID),
+		Name:                 payload.Name,
+		Type:                 portainer.DockerComposeStack,
+		EndpointID:           endpoint.ID,
+		EntryPoint:           payload.ComposeFilePathInRepository,
+		Env:                  payload.Env,
+		HealthcheckOverrides: payload.HealthcheckOverrides,
+		LoggingOverrides:     payload.LoggingOverrides,
+		StartupOrder:         payload.StartupOrder,
+		UpdateConcurrency:    payload.UpdateConcurrency,
+		Status:               portainer.StackStatusActive,
+		CreationDate:         time.Now().Unix(),
 	}
 
 	projectPath := handler.FileService.GetStackProjectPath(strconv.Itoa(int(stack.ID)))
@@ -190,31 +239,37 @@ func (handler *Handler) createComposeStackFromGitRepository(w http.ResponseWrite
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to clone git repository", err}
 	}
 
-	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deployComposeStack(config)
+	err = handler.DeployService.DeployComposeStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
-	stack.CreatedBy = config.user.Username
+	stack.CreatedBy = config.User.Username
 
 	err = handler.DataStore.Stack().CreateStack(stack)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
 	}
 
+	handler.recordStackVersionOrWarn(stack, config.User.Username)
+
 	doCleanUp = false
 	return handler.decorateStackResponse(w, stack, userID)
 }
 
 type composeStackFromFileUploadPayload struct {
-	Name             string
-	StackFileContent []byte
-	Env              []portainer.Pair
+	Name                 string
+	StackFileContent     []byte
+	Env                  []portainer.Pair
+	HealthcheckOverrides map[string]portainer.HealthcheckOverride
+	LoggingOverrides     map[string]portainer.LogConfig
+	StartupOrder         []portainer.StackStartupGroup
+	UpdateConcurrency    int
 }
 
 func (payload *composeStackFromFileUploadPayload) Validate(r *http.Request) error {
@@ -236,7 +291,49 @@ func (payload *composeStackFromFileUploadPayload) Validate(r *http.Request) erro
 		return errors.New("Invalid Env parameter")
 	}
 	payload.Env = env
-	return nil
+
+	var healthcheckOverrides map[string]portainer.HealthcheckOverride
+	err = request.RetrieveMultiPartFormJSONValue(r, "HealthcheckOverrides", &healthcheckOverrides, true)
+	if err != nil {
+		return errors.New("Invalid HealthcheckOverrides parameter")
+	}
+	payload.HealthcheckOverrides = healthcheckOverrides
+
+	var loggingOverrides map[string]portainer.LogConfig
+	err = request.RetrieveMultiPartFormJSONValue(r, "LoggingOverrides", &loggingOverrides, true)
+	if err != nil {
+		return errors.New("Invalid LoggingOverrides parameter")
+	}
+	payload.LoggingOverrides = loggingOverrides
+
+	var startupOrder []portainer.StackStartupGroup
+	err = request.RetrieveMultiPartFormJSONValue(r, "StartupOrder", &startupOrder, true)
+	if err != nil {
+		return errors.New("Invalid StartupOrder parameter")
+	}
+	payload.StartupOrder = startupOrder
+
+	updateConcurrency, _ := request.RetrieveMultiPartFormValue(r, "UpdateConcurrency", true)
+	if updateConcurrency != "" {
+		payload.UpdateConcurrency, err = strconv.Atoi(updateConcurrency)
+		if err != nil {
+			return errors.New("Invalid UpdateConcurrency parameter")
+		}
+	}
+
+	err = stackutils.ValidateHealthcheckOverrides(payload.HealthcheckOverrides)
+	if err != nil {
+		return err
+	}
+	err = stackutils.ValidateLoggingOverrides(payload.LoggingOverrides)
+	if err != nil {
+		return err
+	}
+	err = stackutils.ValidateUpdateConcurrency(payload.UpdateConcurrency)
+	if err != nil {
+		return err
+	}
+	return stackutils.ValidateStartupOrder(payload.StartupOrder)
 }
 
 func (handler *Handler) createComposeStackFromFileUpload(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
@@ -257,14 +354,18 @@ func (handler *Handler) createComposeStackFromFileUpload(w http.ResponseWriter,
 
 	stackID := handler.DataStore.Stack().GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
-		Name:         payload.Name,
-		Type:         portainer.DockerComposeStack,
-		EndpointID:   endpoint.ID,
-		EntryPoint:   filesystem.ComposeFileDefaultName,
-		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
-		CreationDate: time.Now().Unix(),
+		ID:                   portainer.StackID(stackID),
+		Name:                 payload.Name,
+		Type:                 portainer.DockerComposeStack,
+		EndpointID:           endpoint.ID,
+		EntryPoint:           filesystem.ComposeFileDefaultName,
+		Env:                  payload.Env,
+		HealthcheckOverrides: payload.HealthcheckOverrides,
+		LoggingOverrides:     payload.LoggingOverrides,
+		StartupOrder:         payload.StartupOrder,
+		UpdateConcurrency:    payload.UpdateConcurrency,
+		Status:               portainer.StackStatusActive,
+		CreationDate:         time.Now().Unix(),
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -277,111 +378,25 @@ func (handler *Handler) createComposeStackFromFileUpload(w http.ResponseWriter,
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
 
-	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deployComposeStack(config)
+	err = handler.DeployService.DeployComposeStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
-	stack.CreatedBy = config.user.Username
+	stack.CreatedBy = config.User.Username
 
 	err = handler.DataStore.Stack().CreateStack(stack)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
 	}
 
+	handler.recordStackVersionOrWarn(stack, config.User.Username)
+
 	doCleanUp = false
 	return handler.decorateStackResponse(w, stack, userID)
 }
-
-type composeStackDeploymentConfig struct {
-	stack      *portainer.Stack
-	endpoint   *portainer.Endpoint
-	dockerhub  *portainer.DockerHub
-	registries []portainer.Registry
-	isAdmin    bool
-	user       *portainer.User
-}
-
-func (handler *Handler) createComposeDeployConfig(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) (*composeStackDeploymentConfig, *httperror.HandlerError) {
-	securityContext, err := security.RetrieveRestrictedRequestContext(r)
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
-	}
-
-	dockerhub, err := handler.DataStore.DockerHub().DockerHub()
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve DockerHub details from the database", err}
-	}
-
-	registries, err := handler.DataStore.Registry().Registries()
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve registries from the database", err}
-	}
-	filteredRegistries := security.FilterRegistries(registries, securityContext)
-
-	user, err := handler.DataStore.User().User(securityContext.UserID)
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to load user information from the database", err}
-	}
-
-	config := &composeStackDeploymentConfig{
-		stack:      stack,
-		endpoint:   endpoint,
-		dockerhub:  dockerhub,
-		registries: filteredRegistries,
-		isAdmin:    securityContext.IsAdmin,
-		user:       user,
-	}
-
-	return config, nil
-}
-
-// TODO: libcompose uses credentials store into a config.json file to pull images from
-// private registries. Right now the only solution is to re-use the embedded Docker binary
-// to login/logout, which will generate the required data in the config.json file and then
-// clean it. Hence the use of the mutex.
-// We should contribute to libcompose to support authentication without using the config.json file.
-func (handler *Handler) deployComposeStack(config *composeStackDeploymentConfig) error {
-	isAdminOrEndpointAdmin, err := handler.userIsAdminOrEndpointAdmin(config.user, config.endpoint.ID)
-	if err != nil {
-		return err
-	}
-
-	securitySettings := &config.endpoint.SecuritySettings
-
-	if (!securitySettings.AllowBindMountsForRegularUsers ||
-		!securitySettings.AllowPrivilegedModeForRegularUsers ||
-		!securitySettings.AllowHostNamespaceForRegularUsers ||
-		!securitySettings.AllowDeviceMappingForRegularUsers ||
-		!securitySettings.AllowContainerCapabilitiesForRegularUsers) &&
-		!isAdminOrEndpointAdmin {
-
-		composeFilePath := path.Join(config.stack.ProjectPath, config.stack.EntryPoint)
-		stackContent, err := handler.FileService.GetFileContent(composeFilePath)
-		if err != nil {
-			return err
-		}
-
-		err = handler.isValidStackFile(stackContent, securitySettings)
-		if err != nil {
-			return err
-		}
-	}
-
-	handler.stackCreationMutex.Lock()
-	defer handler.stackCreationMutex.Unlock()
-
-	handler.SwarmStackManager.Login(config.dockerhub, config.registries, config.endpoint)
-
-	err = handler.ComposeStackManager.Up(config.stack, config.endpoint)
-	if err != nil {
-		return err
-	}
-
-	return handler.SwarmStackManager.Logout(config.endpoint)
-}
@@ -0,0 +1,345 @@
+package stacks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/archive"
+	"github.com/portainer/portainer/api/filesystem"
+	"github.com/portainer/portainer/api/internal/stackutils"
+	"github.com/portainer/portainer/api/internal/streams"
+)
+
+// defaultArchiveComposeFiles are the Compose files looked up at the root of the archive
+// when the path to the Compose file is not specified
+var defaultArchiveComposeFiles = []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"}
+
+// tarballExtractLimits are the size limits of the content extracted from the archive of a stack,
+// to prevent compressed archives from filling the disk of the Portainer host
+var tarballExtractLimits = archive.ExtractLimits{
+	MaxFileSize:  512 * 1024 * 1024,
+	MaxTotalSize: 1024 * 1024 * 1024,
+}
+
+type composeStackFromTarballPayload struct {
+	Name                     string
+	Archive                  []byte
+	ComposeFilePathInArchive string
+	Env                      []portainer.Pair
+}
+
+func (payload *composeStackFromTarballPayload) Validate(r *http.Request) error {
+	name, err := request.RetrieveMultiPartFormValue(r, "Name", false)
+	if err != nil {
+		return errors.New("Invalid stack name")
+	}
+	payload.Name = normalizeStackName(name)
+
+	archiveContent, _, err := request.RetrieveMultiPartFormFile(r, "file")
+	if err != nil {
+		return errors.New("Invalid archive. Ensure that the archive is uploaded correctly")
+	}
+	payload.Archive = archiveContent
+
+	composeFilePath, _ := request.RetrieveMultiPartFormValue(r, "ComposeFilePathInArchive", true)
+	payload.ComposeFilePathInArchive = composeFilePath
+
+	var env []portainer.Pair
+	err = request.RetrieveMultiPartFormJSONValue(r, "Env", &env, true)
+	if err != nil {
+		return errors.New("Invalid Env parameter")
+	}
+	payload.Env = env
+
+	return nil
+}
+
+// tarballDeploymentMessage is a message of the progress stream of a tarball deployment
+type tarballDeploymentMessage struct {
+	Stage   string           `json:"stage"`
+	Service string           `json:"service,omitempty"`
+	Message string           `json:"message,omitempty"`
+	Error   string           `json:"error,omitempty"`
+	Stack   *portainer.Stack `json:"stack,omitempty"`
+}
+
+// dockerBuildMessage is a message of the progress stream returned by the Docker build API
+type dockerBuildMessage struct {
+	Stream string `json:"stream"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// createComposeStackFromTarball deploys a Compose stack from an archive containing the Compose file and the build
+// contexts of its services. The images of the services are built on the endpoint first and the stack is only deployed
+// once all the builds succeeded. Errors detected before the first build are returned as regular HTTP errors,
+// the progress of the builds and of the deployment is then streamed to the client.
+func (handler *Handler) createComposeStackFromTarball(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
+	payload := &composeStackFromTarballPayload{}
+	err := payload.Validate(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	isUnique, err := handler.checkUniqueName(endpoint, payload.Name, 0, false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to check for name collision", err}
+	}
+	if !isUnique {
+		errorMessage := fmt.Sprintf("A stack with the name '%s' is already running", payload.Name)
+		return &httperror.HandlerError{http.StatusConflict, errorMessage, errors.New(errorMessage)}
+	}
+
+	archiveFolder, err := ioutil.TempDir("", "portainer-stack-archive")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create temporary folder", err}
+	}
+	defer os.RemoveAll(archiveFolder)
+
+	err = archive.ExtractTarArchive(bytes.NewReader(payload.Archive), archiveFolder, tarballExtractLimits)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Unable to extract archive", err}
+	}
+
+	composeFilePath, err := findArchiveComposeFile(archiveFolder, payload.ComposeFilePathInArchive)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Unable to find the Compose file inside the archive", err}
+	}
+
+	composeFileContent, err := ioutil.ReadFile(composeFilePath)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to read the Compose file", err}
+	}
+
+	builds, err := stackutils.ComposeFileBuilds(composeFileContent, payload.Name, payload.Env)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid Compose file", err}
+	}
+
+	buildContexts := make([]string, len(builds))
+	for idx, build := range builds {
+		relativeContext, err := filepath.Rel(archiveFolder, filepath.Join(filepath.Dir(composeFilePath), build.Context))
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid build context", err}
+		}
+
+		buildContexts[idx], err = archive.ResolveArchivePath(archiveFolder, relativeContext)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, fmt.Sprintf("Invalid build context for service %s", build.Service), err}
+		}
+	}
+
+	stackFileContent, err := stackutils.ReplaceComposeFileBuilds(composeFileContent, builds)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid Compose file", err}
+	}
+
+	localImages := make([]string, len(builds))
+	for idx, build := range builds {
+		localImages[idx] = build.Image
+	}
+
+	stackID := handler.DataStore.Stack().GetNextIdentifier()
+	stack := &portainer.Stack{
+		ID:           portainer.StackID(stackID),
+		Name:         payload.Name,
+		Type:         portainer.DockerComposeStack,
+		EndpointID:   endpoint.ID,
+		EntryPoint:   filesystem.ComposeFileDefaultName,
+		Env:          payload.Env,
+		LocalImages:  localImages,
+		Status:       portainer.StackStatusActive,
+		CreationDate: time.Now().Unix(),
+	}
+
+	stackFolder := strconv.Itoa(int(stack.ID))
+	projectPath, err := handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, stackFileContent)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist Compose file on disk", err}
+	}
+	stack.ProjectPath = projectPath
+
+	doCleanUp := true
+	defer handler.cleanUp(stack, &doCleanUp)
+
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
+	if configErr != nil {
+		return configErr
+	}
+
+	// the stack is validated before building to avoid running the builds of a rejected stack,
+	// it is validated again when it is deployed
+	err = handler.DeployService.Validate(config)
+	if err != nil {
+		return stackDeploymentError(err)
+	}
+
+	cli, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to connect to the Docker endpoint", err}
+	}
+	defer cli.Close()
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	stream := streams.NewWriterFromSettings(w, &settings.StreamSettings, false)
+	defer stream.Close()
+	encoder := json.NewEncoder(stream)
+
+	for idx, build := range builds {
+		encoder.Encode(&tarballDeploymentMessage{Stage: "build", Service: build.Service, Message: fmt.Sprintf("Building image %s", build.Image)})
+
+		err = buildServiceImage(r.Context(), cli, &build, buildContexts[idx], config.Registries, func(message string) {
+			encoder.Encode(&tarballDeploymentMessage{Stage: "build", Service: build.Service, Message: message})
+		})
+		if err != nil {
+			encoder.Encode(&tarballDeploymentMessage{Stage: "error", Service: build.Service, Error: err.Error()})
+			return nil
+		}
+	}
+
+	encoder.Encode(&tarballDeploymentMessage{Stage: "deploy", Message: "Deploying stack"})
+
+	err = handler.DeployService.DeployComposeStack(config)
+	if err != nil {
+		encoder.Encode(&tarballDeploymentMessage{Stage: "error", Error: err.Error()})
+		return nil
+	}
+
+	stack.CreatedBy = config.User.Username
+
+	err = handler.DataStore.Stack().CreateStack(stack)
+	if err != nil {
+		encoder.Encode(&tarballDeploymentMessage{Stage: "error", Error: "Unable to persist the stack inside the database"})
+		return nil
+	}
+
+	handler.recordStackVersionOrWarn(stack, config.User.Username)
+
+	doCleanUp = false
+
+	handlerErr := handler.createStackResourceControl(stack, userID)
+	if handlerErr != nil {
+		encoder.Encode(&tarballDeploymentMessage{Stage: "error", Error: handlerErr.Message})
+		return nil
+	}
+
+	encoder.Encode(&tarballDeploymentMessage{Stage: "done", Stack: stack})
+	return nil
+}
+
+// findArchiveComposeFile returns the path of the Compose file inside the extracted archive
+func findArchiveComposeFile(archiveFolder, composeFilePath string) (string, error) {
+	if composeFilePath != "" {
+		path, err := archive.ResolveArchivePath(archiveFolder, composeFilePath)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("Compose file %s not found in the archive", composeFilePath)
+		}
+
+		return path, nil
+	}
+
+	for _, fileName := range defaultArchiveComposeFiles {
+		path := filepath.Join(archiveFolder, fileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", errors.New("No Compose file found at the root of the archive")
+}
+
+// buildServiceImage builds the image of a service on the endpoint, the build context is streamed from disk.
+// The progress messages of the build are passed to the progress function.
+func buildServiceImage(ctx context.Context, cli *client.Client, build *stackutils.ServiceBuild, buildContext string, registries []portainer.Registry, progress func(string)) error {
+	if govalidator.IsNull(build.Dockerfile) {
+		build.Dockerfile = "Dockerfile"
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(archive.TarDirectory(writer, buildContext))
+	}()
+	defer reader.Close()
+
+	options := types.ImageBuildOptions{
+		Tags:        []string{build.Image},
+		Dockerfile:  filepath.ToSlash(build.Dockerfile),
+		Target:      build.Target,
+		BuildArgs:   build.Args,
+		Remove:      true,
+		AuthConfigs: registryAuthConfigs(registries),
+	}
+
+	response, err := cli.ImageBuild(ctx, reader, options)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var message dockerBuildMessage
+		err := decoder.Decode(&message)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+
+		if message.Stream != "" {
+			progress(message.Stream)
+		} else if message.Status != "" {
+			progress(message.Status)
+		}
+	}
+}
+
+// registryAuthConfigs returns the credentials of the registries requiring authentication,
+// used by the build to pull the base images
+func registryAuthConfigs(registries []portainer.Registry) map[string]types.AuthConfig {
+	authConfigs := map[string]types.AuthConfig{}
+	for _, registry := range registries {
+		if !registry.Authentication {
+			continue
+		}
+
+		authConfigs[registry.URL] = types.AuthConfig{
+			Username:      registry.Username,
+			Password:      registry.Password,
+			ServerAddress: registry.URL,
+		}
+	}
+	return authConfigs
+}
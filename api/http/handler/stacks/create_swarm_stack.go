@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"path"
 	"strconv"
 	"time"
 
@@ -13,7 +12,7 @@ import (
 	"github.com/portainer/libhttp/request"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/filesystem"
-	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/stackutils"
 )
 
 type swarmStackFromFileContentPayload struct {
@@ -25,6 +24,10 @@ type swarmStackFromFileContentPayload struct {
 	StackFileContent string `example:"version: 3\n services:\n web:\n image:nginx" validate:"required"`
 	// A list of environment variables used during stack deployment
 	Env []portainer.Pair
+	// Healthchecks applied to the services of the stack in place of the ones defined by their images, per service name
+	HealthcheckOverrides map[string]portainer.HealthcheckOverride
+	// Logging drivers applied to the services of the stack in place of the ones defined by the stack file, per service name
+	LoggingOverrides map[string]portainer.LogConfig
 }
 
 func (payload *swarmStackFromFileContentPayload) Validate(r *http.Request) error {
@@ -37,7 +40,11 @@ func (payload *swarmStackFromFileContentPayload) Validate(r *http.Request) error
 	if govalidator.IsNull(payload.StackFileContent) {
 		return errors.New("Invalid stack file content")
 	}
-	return nil
+	err := stackutils.ValidateHealthcheckOverrides(payload.HealthcheckOverrides)
+	if err != nil {
+		return err
+	}
+	return stackutils.ValidateLoggingOverrides(payload.LoggingOverrides)
 }
 
 func (handler *Handler) createSwarmStackFromFileContent(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
@@ -58,15 +65,17 @@ func (handler *Handler) createSwarmStackFromFileContent(w http.ResponseWriter, r
 
 	stackID := handler.DataStore.Stack().GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
-		Name:         payload.Name,
-		Type:         portainer.DockerSwarmStack,
-		SwarmID:      payload.SwarmID,
-		EndpointID:   endpoint.ID,
-		EntryPoint:   filesystem.ComposeFileDefaultName,
-		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
-		CreationDate: time.Now().Unix(),
+		ID:                   portainer.StackID(stackID),
+		Name:                 payload.Name,
+		Type:                 portainer.DockerSwarmStack,
+		SwarmID:              payload.SwarmID,
+		EndpointID:           endpoint.ID,
+		EntryPoint:           filesystem.ComposeFileDefaultName,
+		Env:                  payload.Env,
+		HealthcheckOverrides: payload.HealthcheckOverrides,
+		LoggingOverrides:     payload.LoggingOverrides,
+		Status:               portainer.StackStatusActive,
+		CreationDate:         time.Now().Unix(),
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -79,23 +88,25 @@ func (handler *Handler) createSwarmStackFromFileContent(w http.ResponseWriter, r
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
 
-	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deploySwarmStack(config)
+	err = handler.DeployService.DeploySwarmStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
-	stack.CreatedBy = config.user.Username
+	stack.CreatedBy = config.User.Username
 
 	err = handler.DataStore.Stack().CreateStack(stack)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
 	}
 
+	handler.recordStackVersionOrWarn(stack, config.User.Username)
+
 	doCleanUp = false
 	return handler.decorateStackResponse(w, stack, userID)
 }
@@ -107,6 +118,10 @@ type swarmStackFromGitRepositoryPayload struct {
 	SwarmID string `example:"jpofkc0i9uo9wtx1zesuk649w" validate:"required"`
 	// A list of environment variables used during stack deployment
 	Env []portainer.Pair
+	// Healthchecks applied to the services of the stack in place of the ones defined by their images, per service name
+	HealthcheckOverrides map[string]portainer.HealthcheckOverride
+	// Logging drivers applied to the services of the stack in place of the ones defined by the stack file, per service name
+	LoggingOverrides map[string]portainer.LogConfig
 
 	// URL of a Git repository hosting the Stack file
 	RepositoryURL string `example:"https://github.com/openfaas/faas" validate:"required"`
@@ -138,7 +153,11 @@ func (payload *swarmStackFromGitRepositoryPayload) Validate(r *http.Request) err
 	if govalidator.IsNull(payload.ComposeFilePathInRepository) {
 		payload.ComposeFilePathInRepository = filesystem.ComposeFileDefaultName
 	}
-	return nil
+	err := stackutils.ValidateHealthcheckOverrides(payload.HealthcheckOverrides)
+	if err != nil {
+		return err
+	}
+	return stackutils.ValidateLoggingOverrides(payload.LoggingOverrides)
 }
 
 func (handler *Handler) createSwarmStackFromGitRepository(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
@@ -159,15 +178,17 @@ func (handler *Handler) createSwarmStackFromGitRepository(w http.ResponseWriter,
 
 	stackID := handler.DataStore.Stack().GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
-		Name:         payload.Name,
-		Type:         portainer.DockerSwarmStack,
-		SwarmID:      payload.SwarmID,
-		EndpointID:   endpoint.ID,
-		EntryPoint:   payload.ComposeFilePathInRepository,
-		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
-		CreationDate: time.Now().Unix(),
+		ID:                   portainer.StackID(stackID),
+		Name:                 payload.Name,
+		Type:                 portainer.DockerSwarmStack,
+		SwarmID:              payload.SwarmID,
+		EndpointID:           endpoint.ID,
+		EntryPoint:           payload.ComposeFilePathInRepository,
+		Env:                  payload.Env,
+		HealthcheckOverrides: payload.HealthcheckOverrides,
+		LoggingOverrides:     payload.LoggingOverrides,
+		Status:               portainer.StackStatusActive,
+		CreationDate:         time.Now().Unix(),
 	}
 
 	projectPath := handler.FileService.GetStackProjectPath(strconv.Itoa(int(stack.ID)))
@@ -190,32 +211,36 @@ func (handler *Handler) createSwarmStackFromGitRepository(w http.ResponseWriter,
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to clone git repository", err}
 	}
 
-	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deploySwarmStack(config)
+	err = handler.DeployService.DeploySwarmStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
-	stack.CreatedBy = config.user.Username
+	stack.CreatedBy = config.User.Username
 
 	err = handler.DataStore.Stack().CreateStack(stack)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
 	}
 
+	handler.recordStackVersionOrWarn(stack, config.User.Username)
+
 	doCleanUp = false
 	return handler.decorateStackResponse(w, stack, userID)
 }
 
 type swarmStackFromFileUploadPayload struct {
-	Name             string
-	SwarmID          string
-	StackFileContent []byte
-	Env              []portainer.Pair
+	Name                 string
+	SwarmID              string
+	StackFileContent     []byte
+	Env                  []portainer.Pair
+	HealthcheckOverrides map[string]portainer.HealthcheckOverride
+	LoggingOverrides     map[string]portainer.LogConfig
 }
 
 func (payload *swarmStackFromFileUploadPayload) Validate(r *http.Request) error {
@@ -243,7 +268,26 @@ func (payload *swarmStackFromFileUploadPayload) Validate(r *http.Request) error
 		return errors.New("Invalid Env parameter")
 	}
 	payload.Env = env
-	return nil
+
+	var healthcheckOverrides map[string]portainer.HealthcheckOverride
+	err = request.RetrieveMultiPartFormJSONValue(r, "HealthcheckOverrides", &healthcheckOverrides, true)
+	if err != nil {
+		return errors.New("Invalid HealthcheckOverrides parameter")
+	}
+	payload.HealthcheckOverrides = healthcheckOverrides
+
+	var loggingOverrides map[string]portainer.LogConfig
+	err = request.RetrieveMultiPartFormJSONValue(r, "LoggingOverrides", &loggingOverrides, true)
+	if err != nil {
+		return errors.New("Invalid LoggingOverrides parameter")
+	}
+	payload.LoggingOverrides = loggingOverrides
+
+	err = stackutils.ValidateHealthcheckOverrides(payload.HealthcheckOverrides)
+	if err != nil {
+		return err
+	}
+	return stackutils.ValidateLoggingOverrides(payload.LoggingOverrides)
 }
 
 func (handler *Handler) createSwarmStackFromFileUpload(w http.ResponseWriter, r *http.Request, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
@@ -264,15 +308,17 @@ func (handler *Handler) createSwarmStackFromFileUpload(w http.ResponseWriter, r
 
 	stackID := handler.DataStore.Stack().GetNextIdentifier()
 	stack := &portainer.Stack{
-		ID:           portainer.StackID(stackID),
-		Name:         payload.Name,
-		Type:         portainer.DockerSwarmStack,
-		SwarmID:      payload.SwarmID,
-		EndpointID:   endpoint.ID,
-		EntryPoint:   filesystem.ComposeFileDefaultName,
-		Env:          payload.Env,
-		Status:       portainer.StackStatusActive,
-		CreationDate: time.Now().Unix(),
+		ID:                   portainer.StackID(stackID),
+		Name:                 payload.Name,
+		Type:                 portainer.DockerSwarmStack,
+		SwarmID:              payload.SwarmID,
+		EndpointID:           endpoint.ID,
+		EntryPoint:           filesystem.ComposeFileDefaultName,
+		Env:                  payload.Env,
+		HealthcheckOverrides: payload.HealthcheckOverrides,
+		LoggingOverrides:     payload.LoggingOverrides,
+		Status:               portainer.StackStatusActive,
+		CreationDate:         time.Now().Unix(),
 	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
@@ -285,108 +331,25 @@ func (handler *Handler) createSwarmStackFromFileUpload(w http.ResponseWriter, r
 	doCleanUp := true
 	defer handler.cleanUp(stack, &doCleanUp)
 
-	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, false)
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err = handler.deploySwarmStack(config)
+	err = handler.DeployService.DeploySwarmStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
-	stack.CreatedBy = config.user.Username
+	stack.CreatedBy = config.User.Username
 
 	err = handler.DataStore.Stack().CreateStack(stack)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack inside the database", err}
 	}
 
+	handler.recordStackVersionOrWarn(stack, config.User.Username)
+
 	doCleanUp = false
 	return handler.decorateStackResponse(w, stack, userID)
 }
-
-type swarmStackDeploymentConfig struct {
-	stack      *portainer.Stack
-	endpoint   *portainer.Endpoint
-	dockerhub  *portainer.DockerHub
-	registries []portainer.Registry
-	prune      bool
-	isAdmin    bool
-	user       *portainer.User
-}
-
-func (handler *Handler) createSwarmDeployConfig(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint, prune bool) (*swarmStackDeploymentConfig, *httperror.HandlerError) {
-	securityContext, err := security.RetrieveRestrictedRequestContext(r)
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
-	}
-
-	dockerhub, err := handler.DataStore.DockerHub().DockerHub()
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve DockerHub details from the database", err}
-	}
-
-	registries, err := handler.DataStore.Registry().Registries()
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve registries from the database", err}
-	}
-	filteredRegistries := security.FilterRegistries(registries, securityContext)
-
-	user, err := handler.DataStore.User().User(securityContext.UserID)
-	if err != nil {
-		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to load user information from the database", err}
-	}
-
-	config := &swarmStackDeploymentConfig{
-		stack:      stack,
-		endpoint:   endpoint,
-		dockerhub:  dockerhub,
-		registries: filteredRegistries,
-		prune:      prune,
-		isAdmin:    securityContext.IsAdmin,
-		user:       user,
-	}
-
-	return config, nil
-}
-
-func (handler *Handler) deploySwarmStack(config *swarmStackDeploymentConfig) error {
-	isAdminOrEndpointAdmin, err := handler.userIsAdminOrEndpointAdmin(config.user, config.endpoint.ID)
-	if err != nil {
-		return err
-	}
-
-	settings := &config.endpoint.SecuritySettings
-
-	if !settings.AllowBindMountsForRegularUsers && !isAdminOrEndpointAdmin {
-		composeFilePath := path.Join(config.stack.ProjectPath, config.stack.EntryPoint)
-
-		stackContent, err := handler.FileService.GetFileContent(composeFilePath)
-		if err != nil {
-			return err
-		}
-
-		err = handler.isValidStackFile(stackContent, settings)
-		if err != nil {
-			return err
-		}
-	}
-
-	handler.stackCreationMutex.Lock()
-	defer handler.stackCreationMutex.Unlock()
-
-	handler.SwarmStackManager.Login(config.dockerhub, config.registries, config.endpoint)
-
-	err = handler.SwarmStackManager.Deploy(config.stack, config.prune, config.endpoint)
-	if err != nil {
-		return err
-	}
-
-	err = handler.SwarmStackManager.Logout(config.endpoint)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
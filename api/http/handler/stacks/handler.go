@@ -14,11 +14,17 @@ import (
 	"github.com/portainer/portainer/api/docker"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/imagetrust"
+	"github.com/portainer/portainer/api/internal/secret"
+	"github.com/portainer/portainer/api/internal/stackdeploy"
+	"github.com/portainer/portainer/api/internal/stackdrift"
+	"github.com/portainer/portainer/api/internal/stackmonitor"
 )
 
 var (
-	errStackAlreadyExists = errors.New("A stack already exists with this name")
-	errStackNotExternal   = errors.New("Not an external stack")
+	errStackAlreadyExists        = errors.New("A stack already exists with this name")
+	errStackNotExternal          = errors.New("Not an external stack")
+	errStackRollbackNotSupported = errors.New("Stack rollback is not supported for this stack type")
 )
 
 // Handler is the HTTP handler used to handle stack operations.
@@ -34,6 +40,11 @@ type Handler struct {
 	SwarmStackManager   portainer.SwarmStackManager
 	ComposeStackManager portainer.ComposeStackManager
 	KubernetesDeployer  portainer.KubernetesDeployer
+	ImageVerifier       *imagetrust.Verifier
+	SecretService       *secret.Service
+	DeployService       *stackdeploy.Service
+	DriftService        *stackdrift.Service
+	MonitorService      *stackmonitor.Service
 }
 
 // NewHandler creates a handler to manage stack operations.
@@ -56,12 +67,38 @@ func NewHandler(bouncer *security.RequestBouncer) *Handler {
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackUpdate))).Methods(http.MethodPut)
 	h.Handle("/stacks/{id}/file",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackFile))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/versions",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackVersionList))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/versions/diff",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackVersionDiff))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/rollback",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackRollback))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}/migrate",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackMigrate))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/deploy-multi",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackDeployMulti))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}/start",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackStart))).Methods(http.MethodPost)
 	h.Handle("/stacks/{id}/stop",
 		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackStop))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/crashloop_policy",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackCrashLoopPolicyUpdate))).Methods(http.MethodPut)
+	h.Handle("/stacks/{id}/secrets",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.stackSecretsUpdate))).Methods(http.MethodPut)
+	h.Handle("/stacks/{id}/drift",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackDriftInspect))).Methods(http.MethodGet)
+	h.Handle("/stacks/{id}/reconcile",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackReconcile))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/monitoring_policy",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackMonitoringPolicyUpdate))).Methods(http.MethodPut)
+	h.Handle("/stacks/{id}/monitoring/commit",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackMonitoringCommit))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/restart_dependencies",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackRestartDependenciesUpdate))).Methods(http.MethodPut)
+	h.Handle("/stacks/{id}/lock",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackLock))).Methods(http.MethodPost)
+	h.Handle("/stacks/{id}/lock",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.stackUnlock))).Methods(http.MethodDelete)
 	return h
 }
 
@@ -116,7 +153,7 @@ func (handler *Handler) checkUniqueName(endpoint *portainer.Endpoint, name strin
 	}
 
 	for _, stack := range stacks {
-		if strings.EqualFold(stack.Name, name) && (stackID == 0 || stackID != stack.ID) && stack.EndpointID == endpoint.ID {
+		if strings.EqualFold(stack.Name, name) && (stackID == 0 || stackID != stack.ID) && (stack.EndpointID == endpoint.ID || findStackDeployment(&stack, endpoint.ID) != nil) {
 			return false, nil
 		}
 	}
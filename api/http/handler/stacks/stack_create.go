@@ -5,8 +5,6 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/docker/cli/cli/compose/loader"
-	"github.com/docker/cli/cli/compose/types"
 	httperror "github.com/portainer/libhttp/error"
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
@@ -15,6 +13,8 @@ import (
 	httperrors "github.com/portainer/portainer/api/http/errors"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/quota"
+	"github.com/portainer/portainer/api/internal/stackdeploy"
 	"github.com/portainer/portainer/api/internal/stackutils"
 )
 
@@ -33,13 +33,15 @@ func (handler *Handler) cleanUp(stack *portainer.Stack, doCleanUp *bool) error {
 // @id StackCreate
 // @summary Deploy a new stack
 // @description Deploy a new stack into a Docker environment specified via the endpoint identifier.
+// @description When using method=tarball, the images of the services with a build section are built on the endpoint before the stack is deployed,
+// @description the progress is streamed as newline-delimited JSON messages, the last message containing the created stack or the error.
 // @description **Access policy**: restricted
 // @tags stacks
 // @security jwt
 // @accept json, multipart/form-data
 // @produce json
 // @param type query int true "Stack deployment type. Possible values: 1 (Swarm stack) or 2 (Compose stack)." Enums(1,2)
-// @param method query string true "Stack deployment method. Possible values: file, string or repository." Enums(string, file, repository)
+// @param method query string true "Stack deployment method. Possible values: file, string, repository or tarball (Compose stacks only)." Enums(string, file, repository, tarball)
 // @param endpointId query int true "Identifier of the endpoint that will be used to deploy the stack"
 // @param body_swarm_string body swarmStackFromFileContentPayload false "Required when using method=string and type=1"
 // @param body_swarm_repository body swarmStackFromGitRepositoryPayload false "Required when using method=repository and type=1"
@@ -48,9 +50,11 @@ func (handler *Handler) cleanUp(stack *portainer.Stack, doCleanUp *bool) error {
 // @param Name formData string false "Name of the stack. required when method is file"
 // @param SwarmID formData string false "Swarm cluster identifier. Required when method equals file and type equals 1. required when method is file"
 // @param Env formData string false "Environment variables passed during deployment, represented as a JSON array [{'name': 'name', 'value': 'value'}]. Optional, used when method equals file and type equals 1."
-// @param file formData file false "Stack file. required when method is file"
+// @param file formData file false "Stack file. required when method is file. Tar archive (optionally gzip compressed) containing the Compose file and the build contexts when method is tarball"
+// @param ComposeFilePathInArchive formData string false "Path to the Compose file inside the archive. Optional, used when method equals tarball. Defaults to docker-compose.yml, docker-compose.yaml, compose.yml or compose.yaml"
 // @success 200 {object} portainer.CustomTemplate
 // @failure 400 "Invalid request"
+// @failure 403 "Permission denied or resource quota exceeded"
 // @failure 500 "Server error"
 // @router /stacks [post]
 func (handler *Handler) stackCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -104,6 +108,13 @@ func (handler *Handler) stackCreate(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
 	}
 
+	err = quota.CheckUserQuota(handler.DataStore, tokenData.ID, portainer.StackResourceControl)
+	if err == quota.ErrQuotaExceeded {
+		return &httperror.HandlerError{http.StatusForbidden, "Maximum number of created stacks reached", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to verify user resource quotas", err}
+	}
+
 	switch portainer.StackType(stackType) {
 	case portainer.DockerSwarmStack:
 		return handler.createSwarmStack(w, r, method, endpoint, tokenData.ID)
@@ -129,9 +140,11 @@ func (handler *Handler) createComposeStack(w http.ResponseWriter, r *http.Reques
 		return handler.createComposeStackFromGitRepository(w, r, endpoint, userID)
 	case "file":
 		return handler.createComposeStackFromFileUpload(w, r, endpoint, userID)
+	case "tarball":
+		return handler.createComposeStackFromTarball(w, r, endpoint, userID)
 	}
 
-	return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: method. Value must be one of: string, repository or file", errors.New(request.ErrInvalidQueryParameter)}
+	return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: method. Value must be one of: string, repository, file or tarball", errors.New(request.ErrInvalidQueryParameter)}
 }
 
 func (handler *Handler) createSwarmStack(w http.ResponseWriter, r *http.Request, method string, endpoint *portainer.Endpoint, userID portainer.UserID) *httperror.HandlerError {
@@ -147,60 +160,54 @@ func (handler *Handler) createSwarmStack(w http.ResponseWriter, r *http.Request,
 	return &httperror.HandlerError{http.StatusBadRequest, "Invalid value for query parameter: method. Value must be one of: string, repository or file", errors.New(request.ErrInvalidQueryParameter)}
 }
 
-func (handler *Handler) isValidStackFile(stackFileContent []byte, securitySettings *portainer.EndpointSecuritySettings) error {
-	composeConfigYAML, err := loader.ParseYAML(stackFileContent)
+// createDeployConfig returns the configuration of the deployment of a stack on behalf of the user issuing the request
+func (handler *Handler) createDeployConfig(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint, prune bool) (*stackdeploy.Config, *httperror.HandlerError) {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
 	if err != nil {
-		return err
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
 	}
 
-	composeConfigFile := types.ConfigFile{
-		Config: composeConfigYAML,
+	dockerhub, err := handler.DataStore.DockerHub().DockerHub()
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve DockerHub details from the database", err}
 	}
 
-	composeConfigDetails := types.ConfigDetails{
-		ConfigFiles: []types.ConfigFile{composeConfigFile},
-		Environment: map[string]string{},
+	registries, err := handler.DataStore.Registry().Registries()
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve registries from the database", err}
 	}
+	filteredRegistries := security.FilterRegistries(registries, securityContext)
 
-	composeConfig, err := loader.Load(composeConfigDetails, func(options *loader.Options) {
-		options.SkipValidation = true
-		options.SkipInterpolation = true
-	})
+	user, err := handler.DataStore.User().User(securityContext.UserID)
 	if err != nil {
-		return err
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to load user information from the database", err}
 	}
 
-	for key := range composeConfig.Services {
-		service := composeConfig.Services[key]
-		if !securitySettings.AllowBindMountsForRegularUsers {
-			for _, volume := range service.Volumes {
-				if volume.Type == "bind" {
-					return errors.New("bind-mount disabled for non administrator users")
-				}
-			}
-		}
-
-		if !securitySettings.AllowPrivilegedModeForRegularUsers && service.Privileged == true {
-			return errors.New("privileged mode disabled for non administrator users")
-		}
-
-		if !securitySettings.AllowHostNamespaceForRegularUsers && service.Pid == "host" {
-			return errors.New("pid host disabled for non administrator users")
-		}
+	config := &stackdeploy.Config{
+		Stack:      stack,
+		Endpoint:   endpoint,
+		DockerHub:  dockerhub,
+		Registries: filteredRegistries,
+		Prune:      prune,
+		IsAdmin:    securityContext.IsAdmin,
+		User:       user,
+	}
 
-		if !securitySettings.AllowDeviceMappingForRegularUsers && service.Devices != nil && len(service.Devices) > 0 {
-			return errors.New("device mapping disabled for non administrator users")
-		}
+	return config, nil
+}
 
-		if !securitySettings.AllowContainerCapabilitiesForRegularUsers && (len(service.CapAdd) > 0 || len(service.CapDrop) > 0) {
-			return errors.New("container capabilities disabled for non administrator users")
-		}
+func (handler *Handler) decorateStackResponse(w http.ResponseWriter, stack *portainer.Stack, userID portainer.UserID) *httperror.HandlerError {
+	handlerErr := handler.createStackResourceControl(stack, userID)
+	if handlerErr != nil {
+		return handlerErr
 	}
 
-	return nil
+	return response.JSON(w, stack)
 }
 
-func (handler *Handler) decorateStackResponse(w http.ResponseWriter, stack *portainer.Stack, userID portainer.UserID) *httperror.HandlerError {
+// createStackResourceControl creates the resource control of a newly created stack, restricted to the
+// administrators when created by an administrator and private to its creator otherwise
+func (handler *Handler) createStackResourceControl(stack *portainer.Stack, userID portainer.UserID) *httperror.HandlerError {
 	var resourceControl *portainer.ResourceControl
 
 	isAdmin, err := handler.userIsAdmin(userID)
@@ -213,6 +220,7 @@ func (handler *Handler) decorateStackResponse(w http.ResponseWriter, stack *port
 	} else {
 		resourceControl = authorization.NewPrivateResourceControl(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl, userID)
 	}
+	resourceControl.CreatedBy = userID
 
 	err = handler.DataStore.ResourceControl().CreateResourceControl(resourceControl)
 	if err != nil {
@@ -220,5 +228,5 @@ func (handler *Handler) decorateStackResponse(w http.ResponseWriter, stack *port
 	}
 
 	stack.ResourceControl = resourceControl
-	return response.JSON(w, stack)
+	return nil
 }
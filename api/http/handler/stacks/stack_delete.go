@@ -101,11 +101,18 @@ func (handler *Handler) stackDelete(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
 	}
 
+	handler.removeStackDeployments(stack)
+
 	err = handler.DataStore.Stack().DeleteStack(portainer.StackID(id))
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the stack from the database", err}
 	}
 
+	err = handler.DataStore.StackVersion().DeleteStackVersions(portainer.StackID(id))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the stack versions from the database", err}
+	}
+
 	if resourceControl != nil {
 		err = handler.DataStore.ResourceControl().DeleteResourceControl(resourceControl.ID)
 		if err != nil {
@@ -169,5 +176,15 @@ func (handler *Handler) deleteStack(stack *portainer.Stack, endpoint *portainer.
 		return handler.SwarmStackManager.Remove(stack, endpoint)
 	}
 
-	return handler.ComposeStackManager.Down(stack, endpoint)
+	return handler.downComposeStack(stack, endpoint)
+}
+
+// downComposeStack removes the containers of a Compose stack, then the holders and the volumes of its secrets
+func (handler *Handler) downComposeStack(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	err := handler.ComposeStackManager.Down(stack, endpoint)
+	if err != nil || handler.SecretService == nil {
+		return err
+	}
+
+	return handler.SecretService.RemoveStack(endpoint, stack.Name)
 }
@@ -0,0 +1,28 @@
+package stacks
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/portainer/api/internal/imagetrust"
+	"github.com/portainer/portainer/api/internal/stackdeploy"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+// stackDeploymentError returns the HTTP error associated to a failed stack deployment.
+// Images rejected by the image trust policy or the image age policy, stack files rejected by the Compose policy and stacks referencing
+// secrets deployed by regular users are reported with a 403.
+// Images that cannot be pulled while the always pull policy applies are reported with a 502.
+func stackDeploymentError(err error) *httperror.HandlerError {
+	if err == stackdeploy.ErrStackSecretsAdminOnly {
+		return &httperror.HandlerError{http.StatusForbidden, err.Error(), err}
+	}
+
+	switch err.(type) {
+	case *imagetrust.VerificationError, *imagetrust.AgeError, *stackutils.ComposePolicyViolation:
+		return &httperror.HandlerError{http.StatusForbidden, err.Error(), err}
+	case *stackdeploy.ImagePullError:
+		return &httperror.HandlerError{http.StatusBadGateway, err.Error(), err}
+	}
+	return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+}
@@ -0,0 +1,221 @@
+package stacks
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+type stackDeployMultiPayload struct {
+	// List of endpoint identifiers where the stack will be deployed
+	EndpointIDs []portainer.EndpointID `example:"2,3"`
+	// Deploy the stack on the endpoints associated to at least one of these tags, directly or through their endpoint group
+	TagIDs []portainer.TagID `example:"1"`
+	// Deploy the stack on the endpoints of these endpoint groups
+	EndpointGroupIDs []portainer.EndpointGroupID `example:"2"`
+	// Environment variables overriding the stack environment variables, per endpoint identifier
+	EnvOverrides map[portainer.EndpointID][]portainer.Pair
+	// Prune services that are no longer referenced (only available for Swarm stacks)
+	Prune bool `example:"false"`
+}
+
+func (payload *stackDeployMultiPayload) Validate(r *http.Request) error {
+	if len(payload.EndpointIDs) == 0 && len(payload.TagIDs) == 0 && len(payload.EndpointGroupIDs) == 0 {
+		return errors.New("Invalid targets. At least one endpoint, tag or endpoint group must be specified")
+	}
+	return nil
+}
+
+// @id StackDeployMulti
+// @summary Deploy a stack on multiple endpoints
+// @description Deploy the stack definition on a list of endpoints, selected by identifier, tag or endpoint group.
+// @description The deployments are run concurrently and a failure on an endpoint does not prevent the deployment on the others.
+// @description The stack keeps track of the endpoints where it is deployed, subsequent updates of the stack are deployed on all of them.
+// @description The endpoint of the stack is excluded from the targets, use the stack update operation to deploy on it.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Stack identifier"
+// @param body body stackDeployMultiPayload true "Deployment targets"
+// @success 200 {array} stackDeploymentResult "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/deploy-multi [post]
+func (handler *Handler) stackDeployMulti(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid stack identifier route variable", err}
+	}
+
+	var payload stackDeployMultiPayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	stack, err := handler.DataStore.Stack().Stack(portainer.StackID(stackID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
+	}
+
+	if stack.Type == portainer.KubernetesStack {
+		return &httperror.HandlerError{http.StatusBadRequest, "Multi-endpoint deployment is not supported for Kubernetes stacks", errors.New("Unsupported stack type")}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find the endpoint associated to the stack inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find the endpoint associated to the stack inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	access, err := handler.userCanAccessStack(securityContext, endpoint.ID, resourceControl)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to verify user authorizations to validate stack access", err}
+	}
+	if !access {
+		return &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
+	}
+
+	targets, err := handler.selectDeploymentTargets(r, stack, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the deployment targets from the database", err}
+	}
+	if len(targets) == 0 {
+		return &httperror.HandlerError{http.StatusBadRequest, "No endpoint matches the specified targets", errors.New("No deployment target")}
+	}
+
+	results, deployErr := handler.deployStackOnTargets(r, stack, targets, payload.Prune)
+	if deployErr != nil {
+		return deployErr
+	}
+
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, results)
+}
+
+// selectDeploymentTargets returns the endpoints matching the payload selectors, excluding the stack endpoint.
+// Endpoints that do not exist or that cannot be accessed by the user are returned with an error.
+func (handler *Handler) selectDeploymentTargets(r *http.Request, stack *portainer.Stack, payload *stackDeployMultiPayload) ([]stackDeploymentTarget, error) {
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	groupTags := make(map[portainer.EndpointGroupID][]portainer.TagID)
+	for _, endpointGroup := range endpointGroups {
+		groupTags[endpointGroup.ID] = endpointGroup.TagIDs
+	}
+
+	endpointsByID := make(map[portainer.EndpointID]*portainer.Endpoint)
+	for idx := range endpoints {
+		endpointsByID[endpoints[idx].ID] = &endpoints[idx]
+	}
+
+	selected := make([]portainer.EndpointID, 0)
+	isSelected := make(map[portainer.EndpointID]bool)
+	selectEndpoint := func(endpointID portainer.EndpointID) {
+		if endpointID == stack.EndpointID || isSelected[endpointID] {
+			return
+		}
+		isSelected[endpointID] = true
+		selected = append(selected, endpointID)
+	}
+
+	for _, endpointID := range payload.EndpointIDs {
+		selectEndpoint(endpointID)
+	}
+
+	for _, endpoint := range endpoints {
+		if containsEndpointGroupID(payload.EndpointGroupIDs, endpoint.GroupID) ||
+			containsAnyTagID(payload.TagIDs, endpoint.TagIDs) ||
+			containsAnyTagID(payload.TagIDs, groupTags[endpoint.GroupID]) {
+			selectEndpoint(endpoint.ID)
+		}
+	}
+
+	targets := make([]stackDeploymentTarget, 0, len(selected))
+	for _, endpointID := range selected {
+		target := stackDeploymentTarget{endpointID: endpointID, env: payload.EnvOverrides[endpointID]}
+
+		endpoint, ok := endpointsByID[endpointID]
+		if !ok {
+			target.err = errors.New("Unable to find an endpoint with the specified identifier inside the database")
+		} else if err := handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint); err != nil {
+			target.err = errors.New("Permission denied to access endpoint")
+		} else if !isDockerEndpoint(endpoint) {
+			target.err = errors.New("The stack type is not supported on this endpoint")
+		}
+		target.endpoint = endpoint
+
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+func isDockerEndpoint(endpoint *portainer.Endpoint) bool {
+	switch endpoint.Type {
+	case portainer.DockerEnvironment, portainer.AgentOnDockerEnvironment, portainer.EdgeAgentOnDockerEnvironment:
+		return true
+	}
+	return false
+}
+
+func containsEndpointGroupID(endpointGroupIDs []portainer.EndpointGroupID, endpointGroupID portainer.EndpointGroupID) bool {
+	for _, id := range endpointGroupIDs {
+		if id == endpointGroupID {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyTagID(tagIDs, candidates []portainer.TagID) bool {
+	for _, tagID := range tagIDs {
+		for _, candidate := range candidates {
+			if tagID == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package stacks
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+var errStackNameNotUniqueOnEndpoint = errors.New("A stack with the same name already exists on the endpoint")
+
+type (
+	// stackDeploymentTarget is an endpoint where a stack is deployed in addition to the stack endpoint
+	stackDeploymentTarget struct {
+		endpointID portainer.EndpointID
+		endpoint   *portainer.Endpoint
+		env        []portainer.Pair
+		err        error
+	}
+
+	stackDeploymentResult struct {
+		// Endpoint identifier
+		EndpointID portainer.EndpointID `json:"EndpointId" example:"3"`
+		// Whether the stack was successfully deployed on the endpoint
+		Success bool `json:"Success" example:"true"`
+		// Reason of the failure
+		Error string `json:"Error,omitempty" example:""`
+	}
+)
+
+// deployStackOnTargets deploys the stack on each target concurrently, using the stack environment variables
+// merged with the environment variables of the target. A failure on a target does not prevent the deployment
+// on the other targets. The deployments of the stack are updated with the results, it is up to the caller to persist the stack.
+func (handler *Handler) deployStackOnTargets(r *http.Request, stack *portainer.Stack, targets []stackDeploymentTarget, prune bool) ([]stackDeploymentResult, *httperror.HandlerError) {
+	deploy, configErr := handler.createTargetDeployFunc(r, stack, prune)
+	if configErr != nil {
+		return nil, configErr
+	}
+
+	return handler.runStackDeployments(stack, targets, deploy), nil
+}
+
+// runStackDeployments deploys the stack on each target concurrently with the deploy function
+// and records the deployments of the targets where the deployment was attempted
+func (handler *Handler) runStackDeployments(stack *portainer.Stack, targets []stackDeploymentTarget, deploy func(stack *portainer.Stack, endpoint *portainer.Endpoint) error) []stackDeploymentResult {
+	results := make([]stackDeploymentResult, len(targets))
+	attempted := make([]bool, len(targets))
+
+	var wg sync.WaitGroup
+	for idx := range targets {
+		target := &targets[idx]
+		results[idx].EndpointID = target.endpointID
+
+		if target.err != nil {
+			results[idx].Error = target.err.Error()
+			continue
+		}
+
+		wg.Add(1)
+		go func(idx int, target *stackDeploymentTarget) {
+			defer wg.Done()
+
+			err := handler.deployStackOnTarget(stack, target, deploy)
+			if err == errStackNameNotUniqueOnEndpoint {
+				results[idx].Error = err.Error()
+				return
+			}
+
+			attempted[idx] = true
+			if err != nil {
+				results[idx].Error = err.Error()
+				return
+			}
+			results[idx].Success = true
+		}(idx, target)
+	}
+	wg.Wait()
+
+	now := time.Now().Unix()
+	for idx, target := range targets {
+		if !attempted[idx] {
+			continue
+		}
+
+		deployment := portainer.StackDeployment{
+			EndpointID:     target.endpointID,
+			Env:            target.env,
+			DeploymentDate: now,
+			Error:          results[idx].Error,
+		}
+
+		if existing := findStackDeployment(stack, target.endpointID); existing != nil {
+			*existing = deployment
+		} else {
+			stack.Deployments = append(stack.Deployments, deployment)
+		}
+	}
+
+	return results
+}
+
+// createTargetDeployFunc returns a function deploying a stack on an endpoint, using the credentials and
+// the permissions of the user issuing the request.
+func (handler *Handler) createTargetDeployFunc(r *http.Request, stack *portainer.Stack, prune bool) (func(stack *portainer.Stack, endpoint *portainer.Endpoint) error, *httperror.HandlerError) {
+	config, configErr := handler.createDeployConfig(r, stack, nil, prune)
+	if configErr != nil {
+		return nil, configErr
+	}
+
+	return func(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+		targetConfig := *config
+		targetConfig.Stack = stack
+		targetConfig.Endpoint = endpoint
+		return handler.DeployService.Deploy(&targetConfig)
+	}, nil
+}
+
+func (handler *Handler) deployStackOnTarget(stack *portainer.Stack, target *stackDeploymentTarget, deploy func(stack *portainer.Stack, endpoint *portainer.Endpoint) error) error {
+	if findStackDeployment(stack, target.endpointID) == nil {
+		isUnique, err := handler.checkUniqueName(target.endpoint, stack.Name, stack.ID, stack.Type == portainer.DockerSwarmStack)
+		if err != nil {
+			return err
+		}
+		if !isUnique {
+			return errStackNameNotUniqueOnEndpoint
+		}
+	}
+
+	targetStack := *stack
+	targetStack.EndpointID = target.endpointID
+	targetStack.Env = stackutils.MergeStackEnv(stack.Env, target.env)
+
+	return deploy(&targetStack, target.endpoint)
+}
+
+// redeployStackDeployments deploys the stack on all the endpoints where it was previously deployed, so that
+// an update of the stack is applied on all its endpoints.
+func (handler *Handler) redeployStackDeployments(r *http.Request, stack *portainer.Stack, prune bool) *httperror.HandlerError {
+	if len(stack.Deployments) == 0 {
+		return nil
+	}
+
+	targets := make([]stackDeploymentTarget, 0, len(stack.Deployments))
+	for _, deployment := range stack.Deployments {
+		target := stackDeploymentTarget{endpointID: deployment.EndpointID, env: deployment.Env}
+
+		target.endpoint, target.err = handler.DataStore.Endpoint().Endpoint(deployment.EndpointID)
+		if target.err == nil {
+			target.err = handler.requestBouncer.AuthorizedEndpointOperation(r, target.endpoint)
+		}
+
+		targets = append(targets, target)
+	}
+
+	results, deployErr := handler.deployStackOnTargets(r, stack, targets, prune)
+	if deployErr != nil {
+		return deployErr
+	}
+
+	for _, result := range results {
+		if !result.Success {
+			log.Printf("Warning: unable to deploy stack %s on endpoint %d: %s\n", stack.Name, result.EndpointID, result.Error)
+		}
+	}
+
+	return nil
+}
+
+// removeStackDeployments removes the stack from all the endpoints where it was deployed in addition to the stack endpoint.
+// Failures are logged, as the endpoints might not be reachable anymore.
+func (handler *Handler) removeStackDeployments(stack *portainer.Stack) {
+	for _, deployment := range stack.Deployments {
+		endpoint, err := handler.DataStore.Endpoint().Endpoint(deployment.EndpointID)
+		if err != nil {
+			log.Printf("Warning: unable to retrieve endpoint %d to remove stack %s: %s\n", deployment.EndpointID, stack.Name, err)
+			continue
+		}
+
+		targetStack := *stack
+		targetStack.EndpointID = endpoint.ID
+
+		err = handler.deleteStack(&targetStack, endpoint)
+		if err != nil {
+			log.Printf("Warning: unable to remove stack %s from endpoint %d: %s\n", stack.Name, endpoint.ID, err)
+		}
+	}
+}
+
+func findStackDeployment(stack *portainer.Stack, endpointID portainer.EndpointID) *portainer.StackDeployment {
+	for idx := range stack.Deployments {
+		if stack.Deployments[idx].EndpointID == endpointID {
+			return &stack.Deployments[idx]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package stacks
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_runStackDeployments(t *testing.T) {
+	stack := &portainer.Stack{
+		ID:         1,
+		Name:       "web",
+		EndpointID: 1,
+		Env:        []portainer.Pair{{Name: "REPLICAS", Value: "1"}, {Name: "TAG", Value: "latest"}},
+		Deployments: []portainer.StackDeployment{
+			{EndpointID: 2, Error: "previous failure"},
+			{EndpointID: 3},
+		},
+	}
+	handler := &Handler{DataStore: testhelpers.NewDatastore(testhelpers.WithStacks([]portainer.Stack{
+		*stack,
+		{ID: 2, Name: "web", EndpointID: 5},
+	}))}
+
+	targets := []stackDeploymentTarget{
+		{endpointID: 2, endpoint: &portainer.Endpoint{ID: 2}, env: []portainer.Pair{{Name: "REPLICAS", Value: "3"}}},
+		{endpointID: 3, endpoint: &portainer.Endpoint{ID: 3}},
+		{endpointID: 4, err: errors.New("Permission denied to access endpoint")},
+		{endpointID: 5, endpoint: &portainer.Endpoint{ID: 5}},
+	}
+
+	var mu sync.Mutex
+	deployed := make(map[portainer.EndpointID]*portainer.Stack)
+	deploy := func(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		deployed[endpoint.ID] = stack
+		if endpoint.ID == 3 {
+			return errors.New("deployment failure")
+		}
+		return nil
+	}
+
+	results := handler.runStackDeployments(stack, targets, deploy)
+
+	assert.Equal(t, []stackDeploymentResult{
+		{EndpointID: 2, Success: true},
+		{EndpointID: 3, Error: "deployment failure"},
+		{EndpointID: 4, Error: "Permission denied to access endpoint"},
+		{EndpointID: 5, Error: errStackNameNotUniqueOnEndpoint.Error()},
+	}, results)
+
+	assert.Len(t, deployed, 2, "the deployment is not attempted on the endpoints in error")
+	assert.Equal(t, portainer.EndpointID(2), deployed[2].EndpointID)
+	assert.Equal(t, []portainer.Pair{{Name: "REPLICAS", Value: "3"}, {Name: "TAG", Value: "latest"}}, deployed[2].Env)
+	assert.Equal(t, portainer.EndpointID(1), stack.EndpointID, "the stack itself is not modified by the deployments")
+
+	assert.Len(t, stack.Deployments, 2, "the endpoints where the deployment is not attempted are not recorded")
+	assert.Equal(t, portainer.EndpointID(2), stack.Deployments[0].EndpointID)
+	assert.Empty(t, stack.Deployments[0].Error)
+	assert.NotZero(t, stack.Deployments[0].DeploymentDate)
+	assert.Equal(t, []portainer.Pair{{Name: "REPLICAS", Value: "3"}}, stack.Deployments[0].Env)
+	assert.Equal(t, "deployment failure", stack.Deployments[1].Error)
+}
+
+func Test_containsAnyTagID(t *testing.T) {
+	assert.True(t, containsAnyTagID([]portainer.TagID{1, 2}, []portainer.TagID{3, 2}))
+	assert.False(t, containsAnyTagID([]portainer.TagID{1, 2}, []portainer.TagID{3}))
+	assert.False(t, containsAnyTagID(nil, []portainer.TagID{1}))
+}
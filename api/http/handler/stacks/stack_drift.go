@@ -0,0 +1,121 @@
+package stacks
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/stackdrift"
+)
+
+var errStackNotActive = errors.New("Stack is not active")
+
+// @id StackDriftInspect
+// @summary Inspect the drift of a stack
+// @description Compare the definition of a stack against the containers (Compose) or the services (Swarm) running on its endpoint.
+// @description The services missing, stopped, running another image or not part of the definition are reported.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @success 200 {object} portainer.StackDrift "Success"
+// @failure 400 "Invalid request or stack is not active"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack or endpoint not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/drift [get]
+func (handler *Handler) stackDriftInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stack, endpoint, handlerErr := handler.retrieveDriftStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	drift, err := handler.DriftService.Detect(stack, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to detect the stack drift", err}
+	}
+
+	return response.JSON(w, drift)
+}
+
+// @id StackReconcile
+// @summary Reconcile a stack
+// @description Redeploy a stack when the containers or the services running on its endpoint drifted from its definition,
+// @description e.g. after a host reboot. The stack is not redeployed when no drift is detected.
+// @description The drift remaining after the reconciliation is returned and recorded in the stack.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @success 200 {object} portainer.StackDrift "Success"
+// @failure 400 "Invalid request or stack is not active"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack or endpoint not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/reconcile [post]
+func (handler *Handler) stackReconcile(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stack, endpoint, handlerErr := handler.retrieveDriftStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	drift, err := handler.DriftService.Detect(stack, endpoint)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to detect the stack drift", err}
+	}
+
+	if len(drift.Items) > 0 {
+		if stack.Type == portainer.DockerSwarmStack {
+			config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
+			if configErr != nil {
+				return configErr
+			}
+			err = handler.DeployService.DeploySwarmStack(config)
+		} else {
+			config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
+			if configErr != nil {
+				return configErr
+			}
+			err = handler.DeployService.DeployComposeStack(config)
+		}
+		if err != nil {
+			return stackDeploymentError(err)
+		}
+
+		drift, err = handler.DriftService.Detect(stack, endpoint)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to detect the stack drift", err}
+		}
+		drift.ReconcileDate = time.Now().Unix()
+	}
+
+	stack.Drift = drift
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, drift)
+}
+
+func (handler *Handler) retrieveDriftStack(r *http.Request) (*portainer.Stack, *portainer.Endpoint, *httperror.HandlerError) {
+	stack, endpoint, handlerErr := handler.retrieveAuthorizedStack(r)
+	if handlerErr != nil {
+		return nil, nil, handlerErr
+	}
+
+	if stack.Type == portainer.KubernetesStack {
+		return nil, nil, &httperror.HandlerError{http.StatusBadRequest, stackdrift.ErrUnsupportedStack.Error(), stackdrift.ErrUnsupportedStack}
+	}
+
+	if stack.Status != portainer.StackStatusActive {
+		return nil, nil, &httperror.HandlerError{http.StatusBadRequest, errStackNotActive.Error(), errStackNotActive}
+	}
+
+	return stack, endpoint, nil
+}
@@ -0,0 +1,147 @@
+package stacks
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+var (
+	errStackLocked    = errors.New("The stack is locked by another user")
+	errStackNotLocked = errors.New("The stack is not locked")
+)
+
+type stackLockPayload struct {
+	// Duration of the lock, at most 24h. Defaults to 30m
+	Timeout string `example:"1h"`
+	// Why the stack is locked
+	Reason string `example:"Hotfix in progress"`
+}
+
+func (payload *stackLockPayload) Validate(r *http.Request) error {
+	_, err := stackutils.LockTimeout(payload.Timeout)
+	return err
+}
+
+// @id StackLock
+// @summary Lock a stack
+// @description Acquire an advisory edit lock on a stack, or extend the lock already held by the user. While the stack is locked,
+// @description the automated deployments of the stack are skipped: the reconciliation of a drift, the revert of an update failing
+// @description within its monitoring window and the cascade of restart dependencies. The lock expires after its timeout.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Stack identifier"
+// @param body body stackLockPayload false "Lock details"
+// @success 200 {object} portainer.Stack "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 409 "Stack locked by another user"
+// @failure 500 "Server error"
+// @router /stacks/{id}/lock [post]
+func (handler *Handler) stackLock(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload stackLockPayload
+	if r.ContentLength != 0 {
+		err := request.DecodeAndValidateJSONPayload(r, &payload)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		}
+	}
+
+	stack, _, handlerErr := handler.retrieveAuthorizedStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
+	}
+
+	now := time.Now()
+	lock := stackutils.ActiveLock(stack, now)
+	if lock != nil && lock.LockedBy != tokenData.Username {
+		return &httperror.HandlerError{http.StatusConflict, stackutils.LockedStatus(lock), errStackLocked}
+	}
+
+	timeout, _ := stackutils.LockTimeout(payload.Timeout)
+
+	lockDate := now.Unix()
+	if lock != nil {
+		lockDate = lock.LockDate
+	}
+
+	stack.Lock = &portainer.StackLock{
+		LockedBy:   tokenData.Username,
+		LockDate:   lockDate,
+		ExpiryDate: now.Add(timeout).Unix(),
+		Reason:     payload.Reason,
+	}
+
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, stack)
+}
+
+// @id StackUnlock
+// @summary Unlock a stack
+// @description Release the edit lock of a stack, the automated deployments of the stack are resumed.
+// @description **Access policy**: restricted, only the user holding the lock or an administrator can release it
+// @tags stacks
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @success 200 {object} portainer.Stack "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 409 "Stack not locked"
+// @failure 500 "Server error"
+// @router /stacks/{id}/lock [delete]
+func (handler *Handler) stackUnlock(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stack, _, handlerErr := handler.retrieveAuthorizedStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
+	}
+
+	lock := stackutils.ActiveLock(stack, time.Now())
+	if lock == nil {
+		return &httperror.HandlerError{http.StatusConflict, errStackNotLocked.Error(), errStackNotLocked}
+	}
+
+	if lock.LockedBy != tokenData.Username && !securityContext.IsAdmin {
+		return &httperror.HandlerError{http.StatusForbidden, "Only the user holding the lock or an administrator can release it", errStackLocked}
+	}
+
+	stack.Lock = nil
+
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, stack)
+}
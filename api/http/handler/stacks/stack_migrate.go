@@ -161,28 +161,28 @@ func (handler *Handler) migrateStack(r *http.Request, stack *portainer.Stack, ne
 }
 
 func (handler *Handler) migrateComposeStack(r *http.Request, stack *portainer.Stack, next *portainer.Endpoint) *httperror.HandlerError {
-	config, configErr := handler.createComposeDeployConfig(r, stack, next)
+	config, configErr := handler.createDeployConfig(r, stack, next, false)
 	if configErr != nil {
 		return configErr
 	}
 
-	err := handler.deployComposeStack(config)
+	err := handler.DeployService.DeployComposeStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
 	return nil
 }
 
 func (handler *Handler) migrateSwarmStack(r *http.Request, stack *portainer.Stack, next *portainer.Endpoint) *httperror.HandlerError {
-	config, configErr := handler.createSwarmDeployConfig(r, stack, next, true)
+	config, configErr := handler.createDeployConfig(r, stack, next, true)
 	if configErr != nil {
 		return configErr
 	}
 
-	err := handler.deploySwarmStack(config)
+	err := handler.DeployService.DeploySwarmStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
 	return nil
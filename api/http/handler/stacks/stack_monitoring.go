@@ -0,0 +1,144 @@
+package stacks
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/stackmonitor"
+)
+
+type stackMonitoringPolicyUpdatePayload struct {
+	// Monitoring window applied after each update of the stack. No window is applied when null
+	Policy *portainer.StackMonitoringPolicy
+}
+
+func (payload *stackMonitoringPolicyUpdatePayload) Validate(r *http.Request) error {
+	if payload.Policy != nil {
+		return stackmonitor.ValidatePolicy(payload.Policy)
+	}
+	return nil
+}
+
+// @id StackMonitoringPolicyUpdate
+// @summary Update the monitoring policy of a stack
+// @description Define the monitoring window applied after each update of a Compose or Swarm stack. An update is reverted
+// @description to the previous version of the stack when a container of the stack becomes unhealthy or crash-loops within the window,
+// @description and promoted once the window passes cleanly. The crash-looping containers are only detected when the crash-loop detection is enabled.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Stack identifier"
+// @param body body stackMonitoringPolicyUpdatePayload true "Monitoring policy"
+// @success 200 {object} portainer.Stack "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/monitoring_policy [put]
+func (handler *Handler) stackMonitoringPolicyUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload stackMonitoringPolicyUpdatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	stack, _, handlerErr := handler.retrieveAuthorizedStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	if stack.Type == portainer.KubernetesStack {
+		return &httperror.HandlerError{http.StatusBadRequest, "Monitoring windows are not supported for Kubernetes stacks", errStackRollbackNotSupported}
+	}
+
+	stack.MonitoringPolicy = payload.Policy
+
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, stack)
+}
+
+// @id StackMonitoringCommit
+// @summary Promote the update of a stack before the end of its monitoring window
+// @description Stop monitoring the last update of a stack, the update is no longer reverted when a container of the stack fails.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @success 200 {object} portainer.Stack "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 409 "Stack not within a monitoring window"
+// @failure 500 "Server error"
+// @router /stacks/{id}/monitoring/commit [post]
+func (handler *Handler) stackMonitoringCommit(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stack, _, handlerErr := handler.retrieveAuthorizedStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
+	}
+
+	stack, err = handler.MonitorService.Commit(stack.ID, tokenData.Username)
+	if err == stackmonitor.ErrNotMonitoring {
+		return &httperror.HandlerError{http.StatusConflict, err.Error(), err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, stack)
+}
+
+// latestStackVersion returns the number of the last version recorded in the deployment history of a stack, 0 when there is none
+func (handler *Handler) latestStackVersion(stackID portainer.StackID) int {
+	versions, err := handler.DataStore.StackVersion().StackVersions(stackID)
+	if err != nil || len(versions) == 0 {
+		return 0
+	}
+	return versions[len(versions)-1].Version
+}
+
+// recordStackVersionAndMonitor records the definition of an updated stack in its deployment history and opens
+// the monitoring window of the update when the stack has a monitoring policy.
+// Failures are logged, as the stack is already deployed at this point.
+func (handler *Handler) recordStackVersionAndMonitor(stack *portainer.Stack, previousVersion int) {
+	stackVersion, err := handler.recordStackVersion(stack, stack.UpdatedBy, 0)
+	if err != nil {
+		log.Printf("Warning: unable to record the deployed version of stack %s: %s\n", stack.Name, err.Error())
+		return
+	}
+
+	handler.beginStackMonitoring(stack, previousVersion, stackVersion.Version)
+}
+
+// beginStackMonitoring opens the monitoring window of a deployed version of the stack when the stack has a monitoring policy,
+// the deployment is reverted to the previous version when it fails within the window.
+// Failures are logged, as the stack is already deployed at this point.
+func (handler *Handler) beginStackMonitoring(stack *portainer.Stack, previousVersion, version int) {
+	monitoring := stackmonitor.Begin(stack.MonitoringPolicy, previousVersion, version, time.Now())
+	if monitoring == nil {
+		return
+	}
+
+	stack.Monitoring = monitoring
+	err := handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		log.Printf("Warning: unable to open the monitoring window of stack %s: %s\n", stack.Name, err.Error())
+	}
+}
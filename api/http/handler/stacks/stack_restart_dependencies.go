@@ -0,0 +1,97 @@
+package stacks
+
+import (
+	"errors"
+	"net/http"
+	"path"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+var errRestartDependenciesNotSupported = errors.New("Restart dependencies are only supported for Compose stacks")
+
+type stackRestartDependenciesUpdatePayload struct {
+	// Restarts cascaded from a service to the services depending on it. Ignored when FromCompose is set
+	Dependencies []portainer.StackRestartDependency
+	// Import the dependencies from the depends_on relations of the compose file: a service is restarted
+	// after the services it depends on restart
+	FromCompose bool `example:"false"`
+}
+
+func (payload *stackRestartDependenciesUpdatePayload) Validate(r *http.Request) error {
+	if payload.FromCompose {
+		return nil
+	}
+	return stackutils.ValidateRestartDependencies(payload.Dependencies)
+}
+
+// @id StackRestartDependenciesUpdate
+// @summary Update the restart dependencies of a stack
+// @description Define the services of a Compose stack restarted after a service of the stack restarts. The dependent services are
+// @description restarted in order, each one once the services it depends on are running again, and healthy when they define a healthcheck.
+// @description A service that is not ready within the timeout of its dependency stops the cascade. Dependencies forming a cycle are rejected.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Stack identifier"
+// @param body body stackRestartDependenciesUpdatePayload true "Restart dependencies"
+// @success 200 {object} portainer.Stack "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/restart_dependencies [put]
+func (handler *Handler) stackRestartDependenciesUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload stackRestartDependenciesUpdatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	stack, _, handlerErr := handler.retrieveAuthorizedStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	if stack.Type != portainer.DockerComposeStack {
+		return &httperror.HandlerError{http.StatusBadRequest, errRestartDependenciesNotSupported.Error(), errRestartDependenciesNotSupported}
+	}
+
+	stackContent, err := handler.FileService.GetFileContent(path.Join(stack.ProjectPath, stack.EntryPoint))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the stack file", err}
+	}
+
+	dependencies := payload.Dependencies
+	if payload.FromCompose {
+		dependencies, err = stackutils.RestartDependenciesFromCompose(stackContent)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Unable to read the dependencies of the compose file", err}
+		}
+
+		err = stackutils.ValidateRestartDependencies(dependencies)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid dependencies in the compose file", err}
+		}
+	}
+
+	err = stackutils.CheckRestartDependencies(stackContent, dependencies)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid restart dependencies", err}
+	}
+
+	stack.RestartDependencies = dependencies
+
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, stack)
+}
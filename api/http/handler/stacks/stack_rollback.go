@@ -0,0 +1,198 @@
+package stacks
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+type stackRollbackResponse struct {
+	// The redeployed stack
+	Stack *portainer.Stack `json:"Stack"`
+	// The new version recorded in the deployment history of the stack
+	Version *portainer.StackVersion `json:"Version"`
+}
+
+// @id StackRollback
+// @summary Rollback a stack to a previous version
+// @description Redeploy a stack using the definition (Stack file and environment variables) of a previous version.
+// @description The rollback is recorded as a new version in the deployment history of the stack, referencing the restored version.
+// @description When the stack has a monitoring policy, the rollback is monitored like an update.
+// @description The current Stack file is restored when the deployment of the previous version fails on the stack endpoint.
+// @description Once deployed on the stack endpoint, the stack and the new version are recorded even when the redeployment
+// @description on the other endpoints of the stack fails.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @param version query int true "Version to rollback to"
+// @success 200 {object} stackRollbackResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack, endpoint or version not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/rollback [post]
+func (handler *Handler) stackRollback(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	version, err := request.RetrieveNumericQueryParameter(r, "version", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: version", err}
+	}
+
+	stack, endpoint, handlerErr := handler.retrieveAuthorizedStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	if stack.Type == portainer.KubernetesStack {
+		return &httperror.HandlerError{http.StatusBadRequest, "Rollback is not supported for Kubernetes stacks", errStackRollbackNotSupported}
+	}
+
+	stackVersion, err := handler.DataStore.StackVersion().StackVersion(stack.ID, version)
+	if err == bolterrors.ErrObjectNotFound {
+		return handler.stackVersionNotFoundError(stack.ID, version, err)
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stack version from the database", err}
+	}
+
+	previousVersion := handler.latestStackVersion(stack.ID)
+
+	restoreStackFile, err := handler.replaceStackFile(stack, stackVersion.EntryPoint, []byte(stackVersion.FileContent))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist Compose file on disk", err}
+	}
+
+	stack.EntryPoint = stackVersion.EntryPoint
+	stack.Env = stackVersion.Env
+	stack.HealthcheckOverrides = stackVersion.HealthcheckOverrides
+	stack.LoggingOverrides = stackVersion.LoggingOverrides
+	stack.StartupOrder = stackVersion.StartupOrder
+	// a manual rollback replaces the update being monitored
+	if stack.Monitoring != nil && stack.Monitoring.Status == portainer.StackMonitoringActive {
+		stack.Monitoring = nil
+	}
+
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
+	if configErr != nil {
+		restoreStackFile()
+		return configErr
+	}
+
+	deploy := func() error {
+		if stack.Type == portainer.DockerSwarmStack {
+			return handler.DeployService.DeploySwarmStack(config)
+		}
+		return handler.DeployService.DeployComposeStack(config)
+	}
+
+	redeploy := func() *httperror.HandlerError {
+		return handler.redeployStackDeployments(r, stack, false)
+	}
+
+	newVersion, deployErr := handler.deployStackRollback(stack, config.User.Username, stackVersion.Version, restoreStackFile, deploy, redeploy)
+	if newVersion != nil {
+		handler.beginStackMonitoring(stack, previousVersion, newVersion.Version)
+	}
+	if deployErr != nil {
+		return deployErr
+	}
+	newVersion.FileContent = ""
+
+	return response.JSON(w, &stackRollbackResponse{Stack: stack, Version: newVersion})
+}
+
+// deployStackRollback deploys the rolled back stack on its endpoint with deploy, then on the other endpoints of its
+// deployments with redeploy. The Stack file is restored when the deployment on the stack endpoint fails. Once the stack
+// is deployed on its endpoint, the stack and its new version are persisted even when the redeployment fails, so that
+// they match the Stack file and the definition deployed on the stack endpoint.
+// The new version is returned when it is recorded.
+func (handler *Handler) deployStackRollback(stack *portainer.Stack, username string, rollbackOf int, restoreStackFile func(), deploy func() error, redeploy func() *httperror.HandlerError) (*portainer.StackVersion, *httperror.HandlerError) {
+	err := deploy()
+	if err != nil {
+		restoreStackFile()
+		return nil, stackDeploymentError(err)
+	}
+
+	redeployErr := redeploy()
+
+	stack.UpdateDate = time.Now().Unix()
+	stack.UpdatedBy = username
+
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	newVersion, err := handler.recordStackVersion(stack, username, rollbackOf)
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to record the stack version inside the database", err}
+	}
+
+	return newVersion, redeployErr
+}
+
+// replaceStackFile stores the content as the entry point of the stack and returns a function restoring
+// the Stack files of the stack, to call when the deployment of the new content fails: the previous content
+// of the entry point is restored, or the entry point is removed when the file did not exist
+func (handler *Handler) replaceStackFile(stack *portainer.Stack, entryPoint string, content []byte) (func(), error) {
+	stackFolder := strconv.Itoa(int(stack.ID))
+	filePath := path.Join(stack.ProjectPath, entryPoint)
+
+	exists, err := handler.FileService.FileExists(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var previousContent []byte
+	if exists {
+		previousContent, err = handler.FileService.GetFileContent(filePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = handler.FileService.StoreStackFileFromBytes(stackFolder, entryPoint, content)
+	if err != nil {
+		return nil, err
+	}
+
+	restore := func() {
+		var err error
+		if exists {
+			_, err = handler.FileService.StoreStackFileFromBytes(stackFolder, entryPoint, previousContent)
+		} else {
+			err = handler.FileService.DeleteStackFile(stackFolder, entryPoint)
+		}
+
+		if err != nil {
+			log.Printf("Warning: unable to restore the Stack file of stack %s after a failed rollback: %s\n", stack.Name, err)
+		}
+	}
+
+	return restore, nil
+}
+
+func (handler *Handler) stackVersionNotFoundError(stackID portainer.StackID, version int, err error) *httperror.HandlerError {
+	versions, versionsErr := handler.DataStore.StackVersion().StackVersions(stackID)
+	if versionsErr != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stack versions from the database", versionsErr}
+	}
+
+	available := make([]string, 0, len(versions))
+	for _, stackVersion := range versions {
+		available = append(available, strconv.Itoa(stackVersion.Version))
+	}
+
+	message := fmt.Sprintf("Unable to find version %d of the stack. Available versions: [%s]", version, strings.Join(available, ", "))
+	return &httperror.HandlerError{http.StatusNotFound, message, err}
+}
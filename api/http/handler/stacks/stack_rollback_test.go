@@ -0,0 +1,147 @@
+package stacks
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubFileService struct {
+	portainer.FileService
+	files map[string][]byte
+}
+
+func (service *stubFileService) FileExists(filePath string) (bool, error) {
+	_, ok := service.files[filePath]
+	return ok, nil
+}
+
+func (service *stubFileService) GetFileContent(filePath string) ([]byte, error) {
+	content, ok := service.files[filePath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return content, nil
+}
+
+func (service *stubFileService) StoreStackFileFromBytes(stackIdentifier, fileName string, data []byte) (string, error) {
+	filePath := path.Join("/data/compose", stackIdentifier, fileName)
+	service.files[filePath] = data
+	return filePath, nil
+}
+
+func (service *stubFileService) DeleteStackFile(stackIdentifier, fileName string) error {
+	filePath := path.Join("/data/compose", stackIdentifier, fileName)
+	if _, ok := service.files[filePath]; !ok {
+		return os.ErrNotExist
+	}
+	delete(service.files, filePath)
+	return nil
+}
+
+func Test_replaceStackFile_shouldRestoreTheCurrentStackFile(t *testing.T) {
+	fileService := &stubFileService{files: map[string][]byte{"/data/compose/1/docker-compose.yml": []byte("current")}}
+	handler := &Handler{FileService: fileService}
+	stack := &portainer.Stack{ID: 1, Name: "web", EntryPoint: "docker-compose.yml", ProjectPath: "/data/compose/1"}
+
+	restore, err := handler.replaceStackFile(stack, "docker-compose.yml", []byte("previous version"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("previous version"), fileService.files["/data/compose/1/docker-compose.yml"])
+
+	restore()
+	assert.Equal(t, []byte("current"), fileService.files["/data/compose/1/docker-compose.yml"])
+}
+
+func Test_replaceStackFile_shouldRestoreTheCurrentEntryPoint(t *testing.T) {
+	fileService := &stubFileService{files: map[string][]byte{"/data/compose/1/stack.yml": []byte("current")}}
+	handler := &Handler{FileService: fileService}
+	stack := &portainer.Stack{ID: 1, Name: "web", EntryPoint: "stack.yml", ProjectPath: "/data/compose/1"}
+
+	restore, err := handler.replaceStackFile(stack, "docker-compose.yml", []byte("previous version"))
+	assert.NoError(t, err)
+
+	restore()
+	assert.Equal(t, []byte("current"), fileService.files["/data/compose/1/stack.yml"])
+}
+
+func Test_replaceStackFile_shouldRemoveTheCreatedEntryPoint(t *testing.T) {
+	fileService := &stubFileService{files: map[string][]byte{"/data/compose/1/stack.yml": []byte("current")}}
+	handler := &Handler{FileService: fileService}
+	stack := &portainer.Stack{ID: 1, Name: "web", EntryPoint: "stack.yml", ProjectPath: "/data/compose/1"}
+
+	restore, err := handler.replaceStackFile(stack, "docker-compose.yml", []byte("previous version"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("previous version"), fileService.files["/data/compose/1/docker-compose.yml"])
+
+	restore()
+	assert.Equal(t, map[string][]byte{"/data/compose/1/stack.yml": []byte("current")}, fileService.files)
+}
+
+func Test_deployStackRollback_shouldRestoreTheStackFileWhenTheDeploymentFails(t *testing.T) {
+	dataStore := testhelpers.NewDatastore(
+		testhelpers.WithStacks([]portainer.Stack{{ID: 1, Name: "web", EntryPoint: "stack.yml"}}),
+		testhelpers.WithStackVersions([]portainer.StackVersion{}),
+	)
+	handler := &Handler{DataStore: dataStore, FileService: &stubFileService{files: map[string][]byte{}}}
+	stack := &portainer.Stack{ID: 1, Name: "web", EntryPoint: "docker-compose.yml", ProjectPath: "/data/compose/1"}
+
+	restored := false
+	newVersion, handlerErr := handler.deployStackRollback(stack, "admin", 1,
+		func() { restored = true },
+		func() error { return errors.New("deployment failed") },
+		func() *httperror.HandlerError { t.Fatal("the stack must not be redeployed"); return nil },
+	)
+
+	assert.NotNil(t, handlerErr)
+	assert.Nil(t, newVersion)
+	assert.True(t, restored)
+
+	persisted, err := dataStore.Stack().Stack(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "stack.yml", persisted.EntryPoint)
+
+	versions, err := dataStore.StackVersion().StackVersions(1)
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func Test_deployStackRollback_shouldPersistTheDeployedStackWhenTheRedeploymentFails(t *testing.T) {
+	dataStore := testhelpers.NewDatastore(
+		testhelpers.WithStacks([]portainer.Stack{{ID: 1, Name: "web", EntryPoint: "stack.yml"}}),
+		testhelpers.WithStackVersions([]portainer.StackVersion{}),
+	)
+	fileService := &stubFileService{files: map[string][]byte{"/data/compose/1/docker-compose.yml": []byte("previous version")}}
+	handler := &Handler{DataStore: dataStore, FileService: fileService}
+	stack := &portainer.Stack{ID: 1, Name: "web", EntryPoint: "docker-compose.yml", ProjectPath: "/data/compose/1"}
+
+	redeployErr := &httperror.HandlerError{http.StatusInternalServerError, "Unable to redeploy the stack", errors.New("redeployment failed")}
+
+	restored := false
+	newVersion, handlerErr := handler.deployStackRollback(stack, "admin", 1,
+		func() { restored = true },
+		func() error { return nil },
+		func() *httperror.HandlerError { return redeployErr },
+	)
+
+	assert.Equal(t, redeployErr, handlerErr)
+	assert.False(t, restored)
+	assert.Equal(t, []byte("previous version"), fileService.files["/data/compose/1/docker-compose.yml"])
+
+	persisted, err := dataStore.Stack().Stack(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "docker-compose.yml", persisted.EntryPoint)
+	assert.Equal(t, "admin", persisted.UpdatedBy)
+
+	if assert.NotNil(t, newVersion) {
+		assert.Equal(t, 1, newVersion.RollbackOf)
+		assert.Equal(t, "docker-compose.yml", newVersion.EntryPoint)
+		assert.Equal(t, "previous version", newVersion.FileContent)
+	}
+}
@@ -0,0 +1,109 @@
+package stacks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+type stackSecretsUpdatePayload struct {
+	// Secrets materialized into the containers of the services of the stack, per service name
+	Secrets map[string][]portainer.SecretReference
+}
+
+func (payload *stackSecretsUpdatePayload) Validate(r *http.Request) error {
+	return stackutils.ValidateSecretReferences(payload.Secrets)
+}
+
+// @id StackSecretsUpdate
+// @summary Update the secrets of a stack
+// @description Update the secrets materialized into the containers of the services of a Compose stack and redeploy the stack.
+// @description The folder of each secret file is a tmpfs volume mounted read-only, the secrets are written into the volumes
+// @description before the containers are started and are never written on the disk of the host nor in the stack file.
+// @description **Access policy**: administrator
+// @tags stacks
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Stack identifier"
+// @param body body stackSecretsUpdatePayload true "Secrets of the services"
+// @success 200 {object} portainer.Stack "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Stack not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/secrets [put]
+func (handler *Handler) stackSecretsUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid stack identifier route variable", err}
+	}
+
+	var payload stackSecretsUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	stack, err := handler.DataStore.Stack().Stack(portainer.StackID(stackID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
+	}
+
+	if stack.Type != portainer.DockerComposeStack {
+		return &httperror.HandlerError{http.StatusBadRequest, "Secrets are only available for Compose stacks", errors.New("Invalid stack type")}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	secrets, err := handler.DataStore.Secret().Secrets()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve secrets from the database", err}
+	}
+
+	existing := make(map[string]bool)
+	for _, existingSecret := range secrets {
+		existing[existingSecret.Name] = true
+	}
+
+	for service, references := range payload.Secrets {
+		for _, reference := range references {
+			if !existing[reference.Name] {
+				errorMessage := fmt.Sprintf("Secret %s referenced by service %s does not exist", reference.Name, service)
+				return &httperror.HandlerError{http.StatusBadRequest, errorMessage, errors.New(errorMessage)}
+			}
+		}
+	}
+
+	stack.Secrets = payload.Secrets
+
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
+	if configErr != nil {
+		return configErr
+	}
+
+	err = handler.DeployService.DeployComposeStack(config)
+	if err != nil {
+		return stackDeploymentError(err)
+	}
+
+	err = handler.DataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
+	}
+
+	return response.JSON(w, stack)
+}
@@ -22,13 +22,39 @@ type updateComposeStackPayload struct {
 	StackFileContent string `example:"version: 3\n services:\n web:\n image:nginx"`
 	// A list of environment variables used during stack deployment
 	Env []portainer.Pair
+	// Healthchecks applied to the services of the stack, per service name. Existing overrides are kept when not specified
+	HealthcheckOverrides map[string]portainer.HealthcheckOverride
+	// Logging drivers applied to the services of the stack, per service name. Existing overrides are kept when not specified
+	LoggingOverrides map[string]portainer.LogConfig
+	// Groups of services started one after the other. The existing startup order is kept when not specified
+	StartupOrder []portainer.StackStartupGroup
+	// Maximum number of services updated at the same time, 0 for unbounded. The existing value is kept when not specified
+	UpdateConcurrency *int `example:"1"`
+	// Pull the images of the stack before each deployment. The current policy is kept when not specified
+	AlwaysPullImages *bool `example:"true"`
+	// Redeploy the stack when a drift is detected after its endpoint becomes reachable again. The current value is kept when not specified
+	AutoReconcile *bool `example:"true"`
 }
 
 func (payload *updateComposeStackPayload) Validate(r *http.Request) error {
 	if govalidator.IsNull(payload.StackFileContent) {
 		return errors.New("Invalid stack file content")
 	}
-	return nil
+	err := stackutils.ValidateHealthcheckOverrides(payload.HealthcheckOverrides)
+	if err != nil {
+		return err
+	}
+	err = stackutils.ValidateLoggingOverrides(payload.LoggingOverrides)
+	if err != nil {
+		return err
+	}
+	if payload.UpdateConcurrency != nil {
+		err = stackutils.ValidateUpdateConcurrency(*payload.UpdateConcurrency)
+		if err != nil {
+			return err
+		}
+	}
+	return stackutils.ValidateStartupOrder(payload.StartupOrder)
 }
 
 type updateSwarmStackPayload struct {
@@ -38,13 +64,23 @@ type updateSwarmStackPayload struct {
 	Env []portainer.Pair
 	// Prune services that are no longer referenced (only available for Swarm stacks)
 	Prune bool `example:"true"`
+	// Healthchecks applied to the services of the stack, per service name. Existing overrides are kept when not specified
+	HealthcheckOverrides map[string]portainer.HealthcheckOverride
+	// Logging drivers applied to the services of the stack, per service name. Existing overrides are kept when not specified
+	LoggingOverrides map[string]portainer.LogConfig
+	// Redeploy the stack when a drift is detected after its endpoint becomes reachable again. The current value is kept when not specified
+	AutoReconcile *bool `example:"true"`
 }
 
 func (payload *updateSwarmStackPayload) Validate(r *http.Request) error {
 	if govalidator.IsNull(payload.StackFileContent) {
 		return errors.New("Invalid stack file content")
 	}
-	return nil
+	err := stackutils.ValidateHealthcheckOverrides(payload.HealthcheckOverrides)
+	if err != nil {
+		return err
+	}
+	return stackutils.ValidateLoggingOverrides(payload.LoggingOverrides)
 }
 
 // @id StackUpdate
@@ -118,6 +154,8 @@ func (handler *Handler) stackUpdate(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", httperrors.ErrResourceAccessDenied}
 	}
 
+	previousVersion := handler.latestStackVersion(stack.ID)
+
 	updateError := handler.updateAndDeployStack(r, stack, endpoint)
 	if updateError != nil {
 		return updateError
@@ -128,6 +166,8 @@ func (handler *Handler) stackUpdate(w http.ResponseWriter, r *http.Request) *htt
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the stack changes inside the database", err}
 	}
 
+	handler.recordStackVersionAndMonitor(stack, previousVersion)
+
 	return response.JSON(w, stack)
 }
 
@@ -146,6 +186,24 @@ func (handler *Handler) updateComposeStack(r *http.Request, stack *portainer.Sta
 	}
 
 	stack.Env = payload.Env
+	if payload.HealthcheckOverrides != nil {
+		stack.HealthcheckOverrides = payload.HealthcheckOverrides
+	}
+	if payload.LoggingOverrides != nil {
+		stack.LoggingOverrides = payload.LoggingOverrides
+	}
+	if payload.StartupOrder != nil {
+		stack.StartupOrder = payload.StartupOrder
+	}
+	if payload.UpdateConcurrency != nil {
+		stack.UpdateConcurrency = *payload.UpdateConcurrency
+	}
+	if payload.AlwaysPullImages != nil {
+		stack.AlwaysPullImages = *payload.AlwaysPullImages
+	}
+	if payload.AutoReconcile != nil {
+		stack.AutoReconcile = *payload.AutoReconcile
+	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
 	_, err = handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
@@ -153,20 +211,20 @@ func (handler *Handler) updateComposeStack(r *http.Request, stack *portainer.Sta
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist updated Compose file on disk", err}
 	}
 
-	config, configErr := handler.createComposeDeployConfig(r, stack, endpoint)
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, false)
 	if configErr != nil {
 		return configErr
 	}
 
 	stack.UpdateDate = time.Now().Unix()
-	stack.UpdatedBy = config.user.Username
+	stack.UpdatedBy = config.User.Username
 
-	err = handler.deployComposeStack(config)
+	err = handler.DeployService.DeployComposeStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
-	return nil
+	return handler.redeployStackDeployments(r, stack, false)
 }
 
 func (handler *Handler) updateSwarmStack(r *http.Request, stack *portainer.Stack, endpoint *portainer.Endpoint) *httperror.HandlerError {
@@ -177,6 +235,15 @@ func (handler *Handler) updateSwarmStack(r *http.Request, stack *portainer.Stack
 	}
 
 	stack.Env = payload.Env
+	if payload.HealthcheckOverrides != nil {
+		stack.HealthcheckOverrides = payload.HealthcheckOverrides
+	}
+	if payload.LoggingOverrides != nil {
+		stack.LoggingOverrides = payload.LoggingOverrides
+	}
+	if payload.AutoReconcile != nil {
+		stack.AutoReconcile = *payload.AutoReconcile
+	}
 
 	stackFolder := strconv.Itoa(int(stack.ID))
 	_, err = handler.FileService.StoreStackFileFromBytes(stackFolder, stack.EntryPoint, []byte(payload.StackFileContent))
@@ -184,18 +251,18 @@ func (handler *Handler) updateSwarmStack(r *http.Request, stack *portainer.Stack
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist updated Compose file on disk", err}
 	}
 
-	config, configErr := handler.createSwarmDeployConfig(r, stack, endpoint, payload.Prune)
+	config, configErr := handler.createDeployConfig(r, stack, endpoint, payload.Prune)
 	if configErr != nil {
 		return configErr
 	}
 
 	stack.UpdateDate = time.Now().Unix()
-	stack.UpdatedBy = config.user.Username
+	stack.UpdatedBy = config.User.Username
 
-	err = handler.deploySwarmStack(config)
+	err = handler.DeployService.DeploySwarmStack(config)
 	if err != nil {
-		return &httperror.HandlerError{http.StatusInternalServerError, err.Error(), err}
+		return stackDeploymentError(err)
 	}
 
-	return nil
+	return handler.redeployStackDeployments(r, stack, payload.Prune)
 }
@@ -0,0 +1,136 @@
+package stacks
+
+import (
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+// @id StackVersionList
+// @summary List the deployment history of a stack
+// @description List the versions of the definition (Stack file and environment variables) of a stack, one version being
+// @description recorded on each deployment. The content of the Stack file is not included.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @success 200 {array} portainer.StackVersion "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/versions [get]
+func (handler *Handler) stackVersionList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	stack, _, handlerErr := handler.retrieveAuthorizedStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	versions, err := handler.DataStore.StackVersion().StackVersions(stack.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stack versions from the database", err}
+	}
+
+	for idx := range versions {
+		versions[idx].FileContent = ""
+	}
+
+	return response.JSON(w, versions)
+}
+
+// retrieveAuthorizedStack retrieves the stack and the endpoint targeted by the request
+// and verifies that the user associated to the request can access them.
+func (handler *Handler) retrieveAuthorizedStack(r *http.Request) (*portainer.Stack, *portainer.Endpoint, *httperror.HandlerError) {
+	stackID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid stack identifier route variable", err}
+	}
+
+	stack, err := handler.DataStore.Stack().Stack(portainer.StackID(stackID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a stack with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a stack with the specified identifier inside the database", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(stack.EndpointID)
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find the endpoint associated to the stack inside the database", err}
+	} else if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find the endpoint associated to the stack inside the database", err}
+	}
+
+	err = handler.requestBouncer.AuthorizedEndpointOperation(r, endpoint)
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusForbidden, "Permission denied to access endpoint", err}
+	}
+
+	resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve a resource control associated to the stack", err}
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve info from request context", err}
+	}
+
+	access, err := handler.userCanAccessStack(securityContext, endpoint.ID, resourceControl)
+	if err != nil {
+		return nil, nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to verify user authorizations to validate stack access", err}
+	}
+	if !access {
+		return nil, nil, &httperror.HandlerError{http.StatusForbidden, "Access denied to resource", errors.ErrResourceAccessDenied}
+	}
+
+	return stack, endpoint, nil
+}
+
+// recordStackVersion stores the current definition of a stack in its deployment history.
+// It must be called after each successful deployment of the stack.
+func (handler *Handler) recordStackVersion(stack *portainer.Stack, username string, rollbackOf int) (*portainer.StackVersion, error) {
+	fileContent, err := handler.FileService.GetFileContent(path.Join(stack.ProjectPath, stack.EntryPoint))
+	if err != nil {
+		return nil, err
+	}
+
+	stackVersion := &portainer.StackVersion{
+		StackID:              stack.ID,
+		EntryPoint:           stack.EntryPoint,
+		FileContent:          string(fileContent),
+		Env:                  stack.Env,
+		HealthcheckOverrides: stack.HealthcheckOverrides,
+		LoggingOverrides:     stack.LoggingOverrides,
+		StartupOrder:         stack.StartupOrder,
+		RollbackOf:           rollbackOf,
+		CreationDate:         time.Now().Unix(),
+		CreatedBy:            username,
+	}
+
+	err = handler.DataStore.StackVersion().CreateStackVersion(stackVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return stackVersion, nil
+}
+
+// recordStackVersionOrWarn stores the current definition of a stack in its deployment history
+// and logs a warning on failure, as the stack is already deployed at this point.
+func (handler *Handler) recordStackVersionOrWarn(stack *portainer.Stack, username string) {
+	_, err := handler.recordStackVersion(stack, username, 0)
+	if err != nil {
+		log.Printf("Warning: unable to record the deployed version of stack %s: %s\n", stack.Name, err.Error())
+	}
+}
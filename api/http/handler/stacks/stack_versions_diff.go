@@ -0,0 +1,86 @@
+package stacks
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+type stackVersionDiffResponse struct {
+	// Oldest compared version
+	From int `json:"From" example:"1"`
+	// Newest compared version
+	To int `json:"To" example:"3"`
+	// Whether the Stack file was renamed between the two versions
+	EntryPointChanged bool `json:"EntryPointChanged" example:"false"`
+	// Structural changes of the Stack file
+	FileChanges []stackutils.Change `json:"FileChanges"`
+	// Changes of the environment variables
+	EnvChanges []stackutils.Change `json:"EnvChanges"`
+}
+
+// @id StackVersionDiff
+// @summary Compare two versions of a stack
+// @description Return a structural diff between two versions of the definition of a stack.
+// @description Stack files are normalized before being compared: formatting, key ordering and the list or map
+// @description forms of environment variables and labels do not produce changes.
+// @description **Access policy**: restricted
+// @tags stacks
+// @security jwt
+// @produce json
+// @param id path int true "Stack identifier"
+// @param from query int true "Version to compare from"
+// @param to query int true "Version to compare to"
+// @success 200 {object} stackVersionDiffResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "Stack or version not found"
+// @failure 500 "Server error"
+// @router /stacks/{id}/versions/diff [get]
+func (handler *Handler) stackVersionDiff(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	from, err := request.RetrieveNumericQueryParameter(r, "from", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: from", err}
+	}
+
+	to, err := request.RetrieveNumericQueryParameter(r, "to", false)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid query parameter: to", err}
+	}
+
+	stack, _, handlerErr := handler.retrieveAuthorizedStack(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	fromVersion, err := handler.DataStore.StackVersion().StackVersion(stack.ID, from)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find the from version of the stack", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stack version from the database", err}
+	}
+
+	toVersion, err := handler.DataStore.StackVersion().StackVersion(stack.ID, to)
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find the to version of the stack", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stack version from the database", err}
+	}
+
+	fileChanges, err := stackutils.DiffComposeFiles([]byte(fromVersion.FileContent), []byte(toVersion.FileContent))
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to compare the Stack files", err}
+	}
+
+	return response.JSON(w, &stackVersionDiffResponse{
+		From:              fromVersion.Version,
+		To:                toVersion.Version,
+		EntryPointChanged: fromVersion.EntryPoint != toVersion.EntryPoint,
+		FileChanges:       fileChanges,
+		EnvChanges:        stackutils.DiffEnv(fromVersion.Env, toVersion.Env),
+	})
+}
@@ -0,0 +1,31 @@
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id StatusHealth
+// @summary Check the health of the database
+// @description Retrieve the write health of the database. The database is degraded when the disk hosting it is full or read-only:
+// @description reads are still served while writes are rejected with a 507 status code until space is freed.
+// @description **Access policy**: public
+// @tags status
+// @produce json
+// @success 200 {object} portainer.DataStoreHealth "The database is healthy"
+// @failure 503 {object} portainer.DataStoreHealth "The database is degraded"
+// @router /status/health [get]
+func (handler *Handler) statusHealth(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	health := handler.DataStore.Health()
+	if !health.Degraded {
+		return response.JSON(w, health)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(health)
+	return nil
+}
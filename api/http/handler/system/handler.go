@@ -0,0 +1,54 @@
+package system
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/logs"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+// Handler is the HTTP handler used to handle system operations.
+type Handler struct {
+	*mux.Router
+	DataStore            portainer.DataStore
+	FileService          portainer.FileService
+	ReverseTunnelService portainer.ReverseTunnelService
+	LogBuffer            *logs.Buffer
+	Scheduler            *scheduler.Scheduler
+	DockerClientFactory  *docker.ClientFactory
+	orphansMu            sync.Mutex
+	subsystemsMu         sync.Mutex
+}
+
+// NewHandler creates a handler to manage system operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/system/support-bundle",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.supportBundle))).Methods(http.MethodGet)
+	h.Handle("/system/schedules",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.scheduleList))).Methods(http.MethodGet)
+	h.Handle("/system/schedules/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.scheduleUpdate))).Methods(http.MethodPut)
+	h.Handle("/system/schedules/{id}/run",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.scheduleRun))).Methods(http.MethodPost)
+	h.Handle("/system/subsystems",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.subsystemList))).Methods(http.MethodGet)
+	h.Handle("/system/subsystems/pause",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.subsystemPause))).Methods(http.MethodPost)
+	h.Handle("/system/subsystems/resume",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.subsystemResume))).Methods(http.MethodPost)
+	h.Handle("/system/orphans",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.orphanList))).Methods(http.MethodGet)
+	h.Handle("/system/orphans/cleanup",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.orphanCleanup))).Methods(http.MethodPost)
+
+	return h
+}
@@ -0,0 +1,251 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	stackbucket "github.com/portainer/portainer/api/bolt/stack"
+)
+
+type orphanCleanupPayload struct {
+	// Identifiers of the orphans to remove, as returned by the orphans scan
+	Orphans []string `example:"stack_endpoint:3,team_membership:7" validate:"required"`
+	// Only report the orphans that would be removed
+	DryRun bool `example:"true"`
+}
+
+type orphanCleanupFailure struct {
+	orphan
+	// Reason of the failure
+	Error string `json:"Error"`
+}
+
+type orphanCleanupResponse struct {
+	// Orphans removed, or that would be removed during a dry run
+	Removed []orphan `json:"Removed"`
+	// Orphans whose removal could not be prepared, they are not removed
+	Failed []orphanCleanupFailure `json:"Failed"`
+	// Selected identifiers that do not match any orphan, either because they were already cleaned up
+	// or because the inconsistency was fixed in the meantime
+	NotFound []string `json:"NotFound"`
+	DryRun   bool     `json:"DryRun"`
+}
+
+func (payload *orphanCleanupPayload) Validate(r *http.Request) error {
+	if len(payload.Orphans) == 0 {
+		return errors.New("Invalid orphans. At least one orphan identifier must be specified")
+	}
+	return nil
+}
+
+// @id SystemOrphanCleanup
+// @summary Remove dangling Portainer resources
+// @description Remove a selection of the dangling resources reported by the orphans scan.
+// @description The database is scanned again before the cleanup, so that only resources that are still
+// @description orphaned are removed. Use the DryRun option to get a summary of the cleanup without removing anything.
+// @description The selected orphans are removed from the database in a single transaction, none of them is removed when the transaction fails.
+// @description The orphans whose removal cannot be prepared are reported as failed in the response and are not removed.
+// @description **Access policy**: administrator
+// @tags system
+// @security jwt
+// @accept json
+// @produce json
+// @param body body orphanCleanupPayload true "Orphans to remove"
+// @success 200 {object} orphanCleanupResponse "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /system/orphans/cleanup [post]
+func (handler *Handler) orphanCleanup(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload orphanCleanupPayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	handler.orphansMu.Lock()
+	defer handler.orphansMu.Unlock()
+
+	orphans, err := handler.scanOrphans()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to scan the database for orphaned resources", err}
+	}
+
+	orphansByID := make(map[string]orphan, len(orphans))
+	for _, item := range orphans {
+		orphansByID[item.ID] = item
+	}
+
+	result := orphanCleanupResponse{
+		Removed:  make([]orphan, 0),
+		Failed:   make([]orphanCleanupFailure, 0),
+		NotFound: make([]string, 0),
+		DryRun:   payload.DryRun,
+	}
+
+	cleanup := newOrphanCleanup(handler.DataStore)
+
+	selected := make(map[string]bool, len(payload.Orphans))
+	for _, id := range payload.Orphans {
+		if selected[id] {
+			continue
+		}
+		selected[id] = true
+
+		item, ok := orphansByID[id]
+		if !ok {
+			result.NotFound = append(result.NotFound, id)
+			continue
+		}
+
+		err := item.remove(cleanup)
+		if err != nil {
+			result.Failed = append(result.Failed, orphanCleanupFailure{orphan: item, Error: err.Error()})
+			continue
+		}
+
+		result.Removed = append(result.Removed, item)
+	}
+
+	if payload.DryRun {
+		return response.JSON(w, result)
+	}
+
+	err = handler.DataStore.ApplyChanges(cleanup.changes)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the orphaned resources from the database", err}
+	}
+
+	for _, directory := range cleanup.directories {
+		err := handler.FileService.RemoveDirectory(directory)
+		if err != nil {
+			log.Printf("[WARN] [http,system] [directory: %s] [message: unable to remove the project directory of an orphaned stack] [error: %s]", directory, err)
+		}
+	}
+
+	return response.JSON(w, result)
+}
+
+// orphanCleanup records the changes of the database removing a selection of orphans, so that they are applied
+// in a single transaction. The resources holding several orphans are loaded once and updated in place.
+type orphanCleanup struct {
+	dataStore      portainer.DataStore
+	changes        []portainer.DataStoreChange
+	changeIndexes  map[string]int
+	stacks         map[portainer.StackID]*portainer.Stack
+	endpoints      map[portainer.EndpointID]*portainer.Endpoint
+	endpointGroups map[portainer.EndpointGroupID]*portainer.EndpointGroup
+	registries     map[portainer.RegistryID]*portainer.Registry
+	// project directories of the stacks removed once the changes are applied
+	directories []string
+}
+
+func newOrphanCleanup(dataStore portainer.DataStore) *orphanCleanup {
+	return &orphanCleanup{
+		dataStore:      dataStore,
+		changes:        make([]portainer.DataStoreChange, 0),
+		changeIndexes:  make(map[string]int),
+		stacks:         make(map[portainer.StackID]*portainer.Stack),
+		endpoints:      make(map[portainer.EndpointID]*portainer.Endpoint),
+		endpointGroups: make(map[portainer.EndpointGroupID]*portainer.EndpointGroup),
+		registries:     make(map[portainer.RegistryID]*portainer.Registry),
+		directories:    make([]string, 0),
+	}
+}
+
+// update records the update of an object, unless the object is removed by the cleanup
+func (cleanup *orphanCleanup) update(bucket string, ID int, object interface{}) {
+	key := fmt.Sprintf("%s:%d", bucket, ID)
+	if idx, ok := cleanup.changeIndexes[key]; ok {
+		if cleanup.changes[idx].Object != nil {
+			cleanup.changes[idx].Object = object
+		}
+		return
+	}
+
+	cleanup.changeIndexes[key] = len(cleanup.changes)
+	cleanup.changes = append(cleanup.changes, portainer.DataStoreChange{Bucket: bucket, ID: ID, Object: object})
+}
+
+// delete records the removal of an object, replacing a previous update of the object
+func (cleanup *orphanCleanup) delete(bucket string, ID int) {
+	key := fmt.Sprintf("%s:%d", bucket, ID)
+	if idx, ok := cleanup.changeIndexes[key]; ok {
+		cleanup.changes[idx].Object = nil
+		return
+	}
+
+	cleanup.changeIndexes[key] = len(cleanup.changes)
+	cleanup.changes = append(cleanup.changes, portainer.DataStoreChange{Bucket: bucket, ID: ID})
+}
+
+// removeStackDeployment records the update of the stack without its deployment on the endpoint
+func (cleanup *orphanCleanup) removeStackDeployment(stackID portainer.StackID, endpointID portainer.EndpointID) error {
+	stack, ok := cleanup.stacks[stackID]
+	if !ok {
+		var err error
+		stack, err = cleanup.dataStore.Stack().Stack(stackID)
+		if err != nil {
+			return err
+		}
+		cleanup.stacks[stackID] = stack
+	}
+
+	deployments := make([]portainer.StackDeployment, 0, len(stack.Deployments))
+	for _, deployment := range stack.Deployments {
+		if deployment.EndpointID != endpointID {
+			deployments = append(deployments, deployment)
+		}
+	}
+	stack.Deployments = deployments
+
+	cleanup.update(stackbucket.BucketName, int(stackID), stack)
+	return nil
+}
+
+func (cleanup *orphanCleanup) endpoint(ID portainer.EndpointID) (*portainer.Endpoint, error) {
+	if endpoint, ok := cleanup.endpoints[ID]; ok {
+		return endpoint, nil
+	}
+
+	endpoint, err := cleanup.dataStore.Endpoint().Endpoint(ID)
+	if err != nil {
+		return nil, err
+	}
+	cleanup.endpoints[ID] = endpoint
+
+	return endpoint, nil
+}
+
+func (cleanup *orphanCleanup) endpointGroup(ID portainer.EndpointGroupID) (*portainer.EndpointGroup, error) {
+	if endpointGroup, ok := cleanup.endpointGroups[ID]; ok {
+		return endpointGroup, nil
+	}
+
+	endpointGroup, err := cleanup.dataStore.EndpointGroup().EndpointGroup(ID)
+	if err != nil {
+		return nil, err
+	}
+	cleanup.endpointGroups[ID] = endpointGroup
+
+	return endpointGroup, nil
+}
+
+func (cleanup *orphanCleanup) registry(ID portainer.RegistryID) (*portainer.Registry, error) {
+	if registry, ok := cleanup.registries[ID]; ok {
+		return registry, nil
+	}
+
+	registry, err := cleanup.dataStore.Registry().Registry(ID)
+	if err != nil {
+		return nil, err
+	}
+	cleanup.registries[ID] = registry
+
+	return registry, nil
+}
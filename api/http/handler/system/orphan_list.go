@@ -0,0 +1,30 @@
+package system
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id SystemOrphanList
+// @summary List dangling Portainer resources
+// @description Scan the database for Portainer resources referencing resources that do not exist anymore,
+// @description such as stacks associated to removed endpoints, webhooks of removed services,
+// @description team memberships of removed users and access policies of removed users or teams.
+// @description Service webhooks of unreachable endpoints are not verified.
+// @description **Access policy**: administrator
+// @tags system
+// @security jwt
+// @produce json
+// @success 200 {array} orphan "Success"
+// @failure 500 "Server error"
+// @router /system/orphans [get]
+func (handler *Handler) orphanList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	orphans, err := handler.scanOrphans()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to scan the database for orphaned resources", err}
+	}
+
+	return response.JSON(w, orphans)
+}
@@ -0,0 +1,376 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	endpointbucket "github.com/portainer/portainer/api/bolt/endpoint"
+	"github.com/portainer/portainer/api/bolt/endpointgroup"
+	registrybucket "github.com/portainer/portainer/api/bolt/registry"
+	"github.com/portainer/portainer/api/bolt/resourcecontrol"
+	stackbucket "github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/stackversion"
+	"github.com/portainer/portainer/api/bolt/teammembership"
+	webhookbucket "github.com/portainer/portainer/api/bolt/webhook"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+const (
+	orphanStackEndpoint             = "stack_endpoint"
+	orphanStackDeploymentEndpoint   = "stack_deployment_endpoint"
+	orphanWebhookEndpoint           = "webhook_endpoint"
+	orphanWebhookService            = "webhook_service"
+	orphanTeamMembership            = "team_membership"
+	orphanEndpointAccessPolicy      = "endpoint_access_policy"
+	orphanEndpointGroupAccessPolicy = "endpoint_group_access_policy"
+	orphanRegistryAccessPolicy      = "registry_access_policy"
+)
+
+type orphan struct {
+	// Orphan identifier, used to select the orphan when running a cleanup
+	ID string `json:"Id" example:"stack_endpoint:3"`
+	// Type of inconsistency
+	Type string `json:"Type" example:"stack_endpoint"`
+	// Identifier of the Portainer resource holding the dangling reference
+	ResourceID string `json:"ResourceId" example:"3"`
+	// Human readable description of the inconsistency
+	Description string `json:"Description" example:"Stack myapp references the missing endpoint 2"`
+
+	remove func(cleanup *orphanCleanup) error
+}
+
+// orphanScan holds the Portainer resources loaded from the database to look for dangling references.
+type orphanScan struct {
+	endpoints map[portainer.EndpointID]portainer.Endpoint
+	users     map[portainer.UserID]bool
+	teams     map[portainer.TeamID]bool
+	orphans   []orphan
+}
+
+// scanOrphans looks for Portainer resources referencing resources that do not exist anymore.
+// The returned orphans can be removed using their remove function, which records the changes removing
+// the orphan in a cleanup, and must only be called while holding the orphans lock.
+func (handler *Handler) scanOrphans() ([]orphan, error) {
+	scan := &orphanScan{
+		endpoints: make(map[portainer.EndpointID]portainer.Endpoint),
+		users:     make(map[portainer.UserID]bool),
+		teams:     make(map[portainer.TeamID]bool),
+		orphans:   make([]orphan, 0),
+	}
+
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range endpoints {
+		scan.endpoints[endpoint.ID] = endpoint
+	}
+
+	users, err := handler.DataStore.User().Users()
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		scan.users[user.ID] = true
+	}
+
+	teams, err := handler.DataStore.Team().Teams()
+	if err != nil {
+		return nil, err
+	}
+	for _, team := range teams {
+		scan.teams[team.ID] = true
+	}
+
+	scanners := []func(scan *orphanScan) error{
+		handler.scanStackOrphans,
+		handler.scanWebhookOrphans,
+		handler.scanTeamMembershipOrphans,
+		handler.scanAccessPolicyOrphans,
+	}
+
+	for _, scanner := range scanners {
+		err := scanner(scan)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(scan.orphans, func(i, j int) bool { return scan.orphans[i].ID < scan.orphans[j].ID })
+
+	return scan.orphans, nil
+}
+
+// add reports an orphan. The reference identifies the dangling reference when a resource can hold several of them.
+func (scan *orphanScan) add(orphanType, resourceID, reference, description string, remove func(cleanup *orphanCleanup) error) {
+	id := fmt.Sprintf("%s:%s", orphanType, resourceID)
+	if reference != "" {
+		id += ":" + reference
+	}
+
+	scan.orphans = append(scan.orphans, orphan{
+		ID:          id,
+		Type:        orphanType,
+		ResourceID:  resourceID,
+		Description: description,
+		remove:      remove,
+	})
+}
+
+func (handler *Handler) scanStackOrphans(scan *orphanScan) error {
+	stacks, err := handler.DataStore.Stack().Stacks()
+	if err != nil {
+		return err
+	}
+
+	for idx := range stacks {
+		stack := stacks[idx]
+
+		// stacks created with Portainer versions >= 1.17.1 can be missing the endpoint identifier
+		if stack.EndpointID == 0 {
+			continue
+		}
+
+		if _, ok := scan.endpoints[stack.EndpointID]; !ok {
+			scan.add(orphanStackEndpoint, fmt.Sprint(stack.ID), "",
+				fmt.Sprintf("Stack %s references the missing endpoint %d", stack.Name, stack.EndpointID),
+				func(cleanup *orphanCleanup) error { return handler.removeOrphanStack(cleanup, &stack) })
+			continue
+		}
+
+		for _, deployment := range stack.Deployments {
+			if _, ok := scan.endpoints[deployment.EndpointID]; ok {
+				continue
+			}
+
+			endpointID := deployment.EndpointID
+			scan.add(orphanStackDeploymentEndpoint, fmt.Sprint(stack.ID), fmt.Sprint(endpointID),
+				fmt.Sprintf("Stack %s is deployed on the missing endpoint %d", stack.Name, endpointID),
+				func(cleanup *orphanCleanup) error { return cleanup.removeStackDeployment(stack.ID, endpointID) })
+		}
+	}
+
+	return nil
+}
+
+// removeOrphanStack records the removal of the stack, its versions and its resource control.
+// The project directory of the stack is removed once the changes are applied.
+func (handler *Handler) removeOrphanStack(cleanup *orphanCleanup, stack *portainer.Stack) error {
+	resourceControl, err := handler.DataStore.ResourceControl().ResourceControlByResourceIDAndType(stackutils.ResourceControlID(stack.EndpointID, stack.Name), portainer.StackResourceControl)
+	if err != nil {
+		return err
+	}
+
+	versions, err := handler.DataStore.StackVersion().StackVersions(stack.ID)
+	if err != nil {
+		return err
+	}
+
+	cleanup.delete(stackbucket.BucketName, int(stack.ID))
+	for _, version := range versions {
+		cleanup.delete(stackversion.BucketName, int(version.ID))
+	}
+	if resourceControl != nil {
+		cleanup.delete(resourcecontrol.BucketName, int(resourceControl.ID))
+	}
+	cleanup.directories = append(cleanup.directories, stack.ProjectPath)
+
+	return nil
+}
+
+func (handler *Handler) scanWebhookOrphans(scan *orphanScan) error {
+	webhooks, err := handler.DataStore.Webhook().Webhooks()
+	if err != nil {
+		return err
+	}
+
+	serviceWebhooks := make(map[portainer.EndpointID][]portainer.Webhook)
+
+	for idx := range webhooks {
+		webhook := webhooks[idx]
+		remove := func(cleanup *orphanCleanup) error {
+			cleanup.delete(webhookbucket.BucketName, int(webhook.ID))
+			return nil
+		}
+
+		if _, ok := scan.endpoints[webhook.EndpointID]; !ok {
+			scan.add(orphanWebhookEndpoint, fmt.Sprint(webhook.ID), "",
+				fmt.Sprintf("Webhook %d references the missing endpoint %d", webhook.ID, webhook.EndpointID),
+				remove)
+			continue
+		}
+
+		if webhook.WebhookType == portainer.ServiceWebhook {
+			serviceWebhooks[webhook.EndpointID] = append(serviceWebhooks[webhook.EndpointID], webhook)
+		}
+	}
+
+	for endpointID, webhooks := range serviceWebhooks {
+		endpoint := scan.endpoints[endpointID]
+		handler.scanServiceWebhookOrphans(scan, &endpoint, webhooks)
+	}
+
+	return nil
+}
+
+// scanServiceWebhookOrphans looks for webhooks associated to services that were removed from the endpoint.
+// Endpoints that cannot be reached are skipped, as the existence of the services cannot be verified.
+func (handler *Handler) scanServiceWebhookOrphans(scan *orphanScan, endpoint *portainer.Endpoint, webhooks []portainer.Webhook) {
+	if endpoint.Status != portainer.EndpointStatusUp ||
+		(endpoint.Type != portainer.DockerEnvironment && endpoint.Type != portainer.AgentOnDockerEnvironment) {
+		return
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		log.Printf("Warning: unable to create a Docker client to verify the webhooks of endpoint %d: %s\n", endpoint.ID, err)
+		return
+	}
+	defer dockerClient.Close()
+
+	for idx := range webhooks {
+		webhook := webhooks[idx]
+
+		_, _, err := dockerClient.ServiceInspectWithRaw(context.Background(), webhook.ResourceID, dockertypes.ServiceInspectOptions{})
+		if err == nil {
+			continue
+		}
+
+		if !client.IsErrNotFound(err) {
+			log.Printf("Warning: unable to verify the webhooks of endpoint %d: %s\n", endpoint.ID, err)
+			return
+		}
+
+		scan.add(orphanWebhookService, fmt.Sprint(webhook.ID), "",
+			fmt.Sprintf("Webhook %d references the missing service %s on endpoint %d", webhook.ID, webhook.ResourceID, endpoint.ID),
+			func(cleanup *orphanCleanup) error {
+				cleanup.delete(webhookbucket.BucketName, int(webhook.ID))
+				return nil
+			})
+	}
+}
+
+func (handler *Handler) scanTeamMembershipOrphans(scan *orphanScan) error {
+	memberships, err := handler.DataStore.TeamMembership().TeamMemberships()
+	if err != nil {
+		return err
+	}
+
+	for idx := range memberships {
+		membership := memberships[idx]
+
+		var description string
+		if !scan.users[membership.UserID] {
+			description = fmt.Sprintf("Team membership %d references the missing user %d", membership.ID, membership.UserID)
+		} else if !scan.teams[membership.TeamID] {
+			description = fmt.Sprintf("Team membership %d references the missing team %d", membership.ID, membership.TeamID)
+		} else {
+			continue
+		}
+
+		scan.add(orphanTeamMembership, fmt.Sprint(membership.ID), "", description,
+			func(cleanup *orphanCleanup) error {
+				cleanup.delete(teammembership.BucketName, int(membership.ID))
+				return nil
+			})
+	}
+
+	return nil
+}
+
+func (handler *Handler) scanAccessPolicyOrphans(scan *orphanScan) error {
+	for _, endpoint := range scan.endpoints {
+		endpointID := endpoint.ID
+		handler.addAccessPolicyOrphans(scan, orphanEndpointAccessPolicy, fmt.Sprintf("endpoint %d", endpointID), fmt.Sprint(endpointID),
+			endpoint.UserAccessPolicies, endpoint.TeamAccessPolicies,
+			func(cleanup *orphanCleanup, update func(portainer.UserAccessPolicies, portainer.TeamAccessPolicies)) error {
+				endpoint, err := cleanup.endpoint(endpointID)
+				if err != nil {
+					return err
+				}
+				update(endpoint.UserAccessPolicies, endpoint.TeamAccessPolicies)
+				cleanup.update(endpointbucket.BucketName, int(endpointID), endpoint)
+				return nil
+			})
+	}
+
+	endpointGroups, err := handler.DataStore.EndpointGroup().EndpointGroups()
+	if err != nil {
+		return err
+	}
+
+	for _, endpointGroup := range endpointGroups {
+		endpointGroupID := endpointGroup.ID
+		handler.addAccessPolicyOrphans(scan, orphanEndpointGroupAccessPolicy, fmt.Sprintf("endpoint group %d", endpointGroupID), fmt.Sprint(endpointGroupID),
+			endpointGroup.UserAccessPolicies, endpointGroup.TeamAccessPolicies,
+			func(cleanup *orphanCleanup, update func(portainer.UserAccessPolicies, portainer.TeamAccessPolicies)) error {
+				endpointGroup, err := cleanup.endpointGroup(endpointGroupID)
+				if err != nil {
+					return err
+				}
+				update(endpointGroup.UserAccessPolicies, endpointGroup.TeamAccessPolicies)
+				cleanup.update(endpointgroup.BucketName, int(endpointGroupID), endpointGroup)
+				return nil
+			})
+	}
+
+	registries, err := handler.DataStore.Registry().Registries()
+	if err != nil {
+		return err
+	}
+
+	for _, registry := range registries {
+		registryID := registry.ID
+		handler.addAccessPolicyOrphans(scan, orphanRegistryAccessPolicy, fmt.Sprintf("registry %d", registryID), fmt.Sprint(registryID),
+			registry.UserAccessPolicies, registry.TeamAccessPolicies,
+			func(cleanup *orphanCleanup, update func(portainer.UserAccessPolicies, portainer.TeamAccessPolicies)) error {
+				registry, err := cleanup.registry(registryID)
+				if err != nil {
+					return err
+				}
+				update(registry.UserAccessPolicies, registry.TeamAccessPolicies)
+				cleanup.update(registrybucket.BucketName, int(registryID), registry)
+				return nil
+			})
+	}
+
+	return nil
+}
+
+// addAccessPolicyOrphans reports the access policies of a resource associated to missing users or teams.
+// The save function loads the resource in the cleanup, applies the update to its access policies and records the change.
+func (handler *Handler) addAccessPolicyOrphans(scan *orphanScan, orphanType, resourceName, resourceID string,
+	userPolicies portainer.UserAccessPolicies, teamPolicies portainer.TeamAccessPolicies,
+	save func(cleanup *orphanCleanup, update func(portainer.UserAccessPolicies, portainer.TeamAccessPolicies)) error) {
+
+	for userID := range userPolicies {
+		if scan.users[userID] {
+			continue
+		}
+
+		userID := userID
+		scan.add(orphanType, resourceID, fmt.Sprintf("user:%d", userID),
+			fmt.Sprintf("Access policy of %s references the missing user %d", resourceName, userID),
+			func(cleanup *orphanCleanup) error {
+				return save(cleanup, func(users portainer.UserAccessPolicies, _ portainer.TeamAccessPolicies) { delete(users, userID) })
+			})
+	}
+
+	for teamID := range teamPolicies {
+		if scan.teams[teamID] {
+			continue
+		}
+
+		teamID := teamID
+		scan.add(orphanType, resourceID, fmt.Sprintf("team:%d", teamID),
+			fmt.Sprintf("Access policy of %s references the missing team %d", resourceName, teamID),
+			func(cleanup *orphanCleanup) error {
+				return save(cleanup, func(_ portainer.UserAccessPolicies, teams portainer.TeamAccessPolicies) { delete(teams, teamID) })
+			})
+	}
+}
@@ -0,0 +1,141 @@
+package system
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	endpointbucket "github.com/portainer/portainer/api/bolt/endpoint"
+	"github.com/portainer/portainer/api/bolt/resourcecontrol"
+	stackbucket "github.com/portainer/portainer/api/bolt/stack"
+	"github.com/portainer/portainer/api/bolt/stackversion"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubFileService struct {
+	portainer.FileService
+	removedDirectories []string
+}
+
+func (service *stubFileService) RemoveDirectory(directoryPath string) error {
+	service.removedDirectories = append(service.removedDirectories, directoryPath)
+	return nil
+}
+
+func newOrphansDatastore() *testhelpers.Datastore {
+	return testhelpers.NewDatastore(
+		testhelpers.WithEndpoints([]portainer.Endpoint{
+			{ID: 1, UserAccessPolicies: portainer.UserAccessPolicies{1: {}, 8: {}, 9: {}}, TeamAccessPolicies: portainer.TeamAccessPolicies{}},
+		}),
+		testhelpers.WithEndpointGroups([]portainer.EndpointGroup{}),
+		testhelpers.WithRegistries([]portainer.Registry{}),
+		testhelpers.WithUsers([]portainer.User{{ID: 1, Username: "admin"}}),
+		testhelpers.WithTeams([]portainer.Team{{ID: 1}}),
+		testhelpers.WithStacks([]portainer.Stack{
+			{ID: 1, Name: "web", EndpointID: 2, ProjectPath: "/data/compose/1"},
+			{ID: 2, Name: "db", EndpointID: 1, Deployments: []portainer.StackDeployment{{EndpointID: 1}, {EndpointID: 3}}},
+		}),
+		testhelpers.WithStackVersions([]portainer.StackVersion{{ID: 4, StackID: 1, Version: 1}}),
+		testhelpers.WithResourceControls([]portainer.ResourceControl{{ID: 5, ResourceID: "2_web", Type: portainer.StackResourceControl}}),
+		testhelpers.WithWebhooks([]portainer.Webhook{{ID: 1, EndpointID: 1}, {ID: 2, EndpointID: 4}}),
+		testhelpers.WithTeamMemberships([]portainer.TeamMembership{{ID: 1, UserID: 1, TeamID: 1}, {ID: 2, UserID: 7, TeamID: 1}}),
+	)
+}
+
+func Test_scanOrphans(t *testing.T) {
+	handler := &Handler{DataStore: newOrphansDatastore()}
+
+	orphans, err := handler.scanOrphans()
+	assert.NoError(t, err)
+
+	ids := make([]string, 0, len(orphans))
+	for _, item := range orphans {
+		ids = append(ids, item.ID)
+	}
+
+	assert.Equal(t, []string{
+		"endpoint_access_policy:1:user:8",
+		"endpoint_access_policy:1:user:9",
+		"stack_deployment_endpoint:2:3",
+		"stack_endpoint:1",
+		"team_membership:2",
+		"webhook_endpoint:2",
+	}, ids)
+}
+
+func Test_orphanCleanup_shouldRemoveTheOrphansInASingleTransaction(t *testing.T) {
+	dataStore := newOrphansDatastore()
+	fileService := &stubFileService{}
+	handler := &Handler{DataStore: dataStore, FileService: fileService}
+
+	body, _ := json.Marshal(orphanCleanupPayload{Orphans: []string{
+		"endpoint_access_policy:1:user:8",
+		"endpoint_access_policy:1:user:9",
+		"stack_endpoint:1",
+		"stack_deployment_endpoint:2:3",
+		"webhook_endpoint:3",
+	}})
+
+	rr := httptest.NewRecorder()
+	handlerErr := handler.orphanCleanup(rr, httptest.NewRequest(http.MethodPost, "/system/orphans/cleanup", bytes.NewReader(body)))
+	assert.Nil(t, handlerErr)
+
+	var result orphanCleanupResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&result))
+	assert.Len(t, result.Removed, 4)
+	assert.Empty(t, result.Failed)
+	assert.Equal(t, []string{"webhook_endpoint:3"}, result.NotFound)
+
+	if !assert.Len(t, dataStore.AppliedChanges, 1) {
+		return
+	}
+	changes := dataStore.AppliedChanges[0]
+	assert.Len(t, changes, 5)
+
+	changesByKey := make(map[string]portainer.DataStoreChange)
+	for _, change := range changes {
+		changesByKey[fmt.Sprintf("%s:%d", change.Bucket, change.ID)] = change
+	}
+
+	endpoint := changesByKey[endpointbucket.BucketName+":1"].Object.(*portainer.Endpoint)
+	assert.Equal(t, portainer.UserAccessPolicies{1: {}}, endpoint.UserAccessPolicies, "both orphaned policies are removed from the same endpoint")
+
+	stack := changesByKey[stackbucket.BucketName+":2"].Object.(*portainer.Stack)
+	assert.Equal(t, []portainer.StackDeployment{{EndpointID: 1}}, stack.Deployments)
+
+	assert.Nil(t, changesByKey[stackbucket.BucketName+":1"].Object)
+	assert.Contains(t, changesByKey, stackversion.BucketName+":4")
+	assert.Contains(t, changesByKey, resourcecontrol.BucketName+":5")
+
+	assert.Equal(t, []string{"/data/compose/1"}, fileService.removedDirectories)
+}
+
+func Test_orphanCleanup_shouldNotApplyChangesDuringADryRun(t *testing.T) {
+	dataStore := newOrphansDatastore()
+	handler := &Handler{DataStore: dataStore, FileService: &stubFileService{}}
+
+	body, _ := json.Marshal(orphanCleanupPayload{Orphans: []string{"team_membership:2"}, DryRun: true})
+
+	rr := httptest.NewRecorder()
+	handlerErr := handler.orphanCleanup(rr, httptest.NewRequest(http.MethodPost, "/system/orphans/cleanup", bytes.NewReader(body)))
+	assert.Nil(t, handlerErr)
+
+	var result orphanCleanupResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&result))
+	assert.Len(t, result.Removed, 1)
+	assert.Empty(t, dataStore.AppliedChanges)
+}
+
+func Test_orphanCleanup_update_shouldNotRestoreARemovedObject(t *testing.T) {
+	cleanup := newOrphanCleanup(nil)
+
+	cleanup.delete(stackbucket.BucketName, 1)
+	cleanup.update(stackbucket.BucketName, 1, &portainer.Stack{ID: 1})
+
+	assert.Equal(t, []portainer.DataStoreChange{{Bucket: stackbucket.BucketName, ID: 1}}, cleanup.changes)
+}
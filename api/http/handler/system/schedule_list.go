@@ -0,0 +1,23 @@
+package system
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id SystemScheduleList
+// @summary List the internal scheduled jobs
+// @description List the background jobs scheduled by Portainer (endpoint snapshots, Edge tunnel verification...)
+// @description with their interval, last run, last result and next run.
+// @description **Access policy**: administrator
+// @tags system
+// @security jwt
+// @produce json
+// @success 200 {array} scheduler.JobStatus "Success"
+// @failure 500 "Server error"
+// @router /system/schedules [get]
+func (handler *Handler) scheduleList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return response.JSON(w, handler.Scheduler.Jobs())
+}
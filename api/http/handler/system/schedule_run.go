@@ -0,0 +1,41 @@
+package system
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+// @id SystemScheduleRun
+// @summary Trigger an immediate run of an internal scheduled job
+// @description Trigger a run of a background job in the background. The next run is scheduled after the job interval.
+// @description **Access policy**: administrator
+// @tags system
+// @security jwt
+// @param id path string true "Job identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Job not found"
+// @failure 409 "Job is already running"
+// @failure 500 "Server error"
+// @router /system/schedules/{id}/run [post]
+func (handler *Handler) scheduleRun(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	jobID, err := request.RetrieveRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid job identifier route variable", err}
+	}
+
+	err = handler.Scheduler.RunNow(jobID)
+	if err == scheduler.ErrJobNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a scheduled job with the specified identifier", err}
+	} else if err == scheduler.ErrJobRunning {
+		return &httperror.HandlerError{http.StatusConflict, "The scheduled job is already running", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to run the scheduled job", err}
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,69 @@
+package system
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+type scheduleUpdatePayload struct {
+	// Duration between each run of the job
+	Interval string `example:"10m" validate:"required"`
+}
+
+func (payload *scheduleUpdatePayload) Validate(r *http.Request) error {
+	interval, err := time.ParseDuration(payload.Interval)
+	if err != nil || interval < scheduler.MinimumInterval {
+		return errors.New("Invalid interval. Must be a valid duration of at least 1s")
+	}
+	return nil
+}
+
+// @id SystemScheduleUpdate
+// @summary Update the interval of an internal scheduled job
+// @description Update the interval of a background job. The next run is scheduled after the new interval.
+// @description **Access policy**: administrator
+// @tags system
+// @security jwt
+// @accept json
+// @produce json
+// @param id path string true "Job identifier"
+// @param body body scheduleUpdatePayload true "Job schedule"
+// @success 200 {object} scheduler.JobStatus "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Job not found"
+// @failure 500 "Server error"
+// @router /system/schedules/{id} [put]
+func (handler *Handler) scheduleUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	jobID, err := request.RetrieveRouteVariableValue(r, "id")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid job identifier route variable", err}
+	}
+
+	var payload scheduleUpdatePayload
+	err = request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	interval, _ := time.ParseDuration(payload.Interval)
+
+	err = handler.Scheduler.UpdateInterval(jobID, interval)
+	if err == scheduler.ErrJobNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a scheduled job with the specified identifier", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to update the job interval", err}
+	}
+
+	job, err := handler.Scheduler.Job(jobID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the scheduled job", err}
+	}
+
+	return response.JSON(w, job)
+}
@@ -0,0 +1,146 @@
+package system
+
+import (
+	"errors"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+type subsystemsPayload struct {
+	// Subsystems to pause or resume (snapshot, auto_update). All the subsystems when empty
+	Subsystems []string `example:"snapshot"`
+}
+
+func (payload *subsystemsPayload) Validate(r *http.Request) error {
+	for _, subsystem := range payload.Subsystems {
+		if !scheduler.IsSubsystem(subsystem) {
+			return errors.New("Invalid subsystem. Must be one of: snapshot, auto_update")
+		}
+	}
+	return nil
+}
+
+type subsystemState struct {
+	// Name of the subsystem
+	Name string `json:"Name" example:"snapshot"`
+	// Whether the scheduled runs of the subsystem are paused
+	Paused bool `json:"Paused" example:"true"`
+}
+
+// @id SystemSubsystemList
+// @summary List the pause state of the background subsystems
+// @description List the background subsystems (endpoint snapshots, auto-updates) and whether their scheduled runs are paused.
+// @description **Access policy**: administrator
+// @tags system
+// @security jwt
+// @produce json
+// @success 200 {array} subsystemState "Success"
+// @failure 500 "Server error"
+// @router /system/subsystems [get]
+func (handler *Handler) subsystemList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return response.JSON(w, handler.subsystemStates())
+}
+
+// @id SystemSubsystemPause
+// @summary Pause background subsystems
+// @description Pause the scheduled runs of background subsystems, for example during a maintenance window.
+// @description The pause is persisted and survives a restart of Portainer.
+// @description **Access policy**: administrator
+// @tags system
+// @security jwt
+// @accept json
+// @produce json
+// @param body body subsystemsPayload false "Subsystems to pause"
+// @success 200 {array} subsystemState "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /system/subsystems/pause [post]
+func (handler *Handler) subsystemPause(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.updateSubsystems(w, r, true)
+}
+
+// @id SystemSubsystemResume
+// @summary Resume background subsystems
+// @description Resume the scheduled runs of paused background subsystems. The jobs of the resumed subsystems
+// @description are run immediately to catch up with the runs skipped during the pause.
+// @description **Access policy**: administrator
+// @tags system
+// @security jwt
+// @accept json
+// @produce json
+// @param body body subsystemsPayload false "Subsystems to resume"
+// @success 200 {array} subsystemState "Success"
+// @failure 400 "Invalid request"
+// @failure 500 "Server error"
+// @router /system/subsystems/resume [post]
+func (handler *Handler) subsystemResume(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	return handler.updateSubsystems(w, r, false)
+}
+
+func (handler *Handler) updateSubsystems(w http.ResponseWriter, r *http.Request, pause bool) *httperror.HandlerError {
+	var payload subsystemsPayload
+	if r.ContentLength != 0 {
+		err := request.DecodeAndValidateJSONPayload(r, &payload)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+		}
+	}
+
+	subsystems := payload.Subsystems
+	if len(subsystems) == 0 {
+		subsystems = scheduler.Subsystems
+	}
+
+	handler.subsystemsMu.Lock()
+	defer handler.subsystemsMu.Unlock()
+
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve the settings from the database", err}
+	}
+
+	paused := make(map[string]bool)
+	for _, subsystem := range settings.PausedSubsystems {
+		paused[subsystem] = true
+	}
+	for _, subsystem := range subsystems {
+		paused[subsystem] = pause
+	}
+
+	settings.PausedSubsystems = []string{}
+	for _, subsystem := range scheduler.Subsystems {
+		if paused[subsystem] {
+			settings.PausedSubsystems = append(settings.PausedSubsystems, subsystem)
+		}
+	}
+
+	err = handler.DataStore.Settings().UpdateSettings(settings)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the settings inside the database", err}
+	}
+
+	for _, subsystem := range subsystems {
+		if pause {
+			err = handler.Scheduler.PauseSubsystem(subsystem)
+		} else {
+			err = handler.Scheduler.ResumeSubsystem(subsystem)
+		}
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to update the subsystem state", err}
+		}
+	}
+
+	return response.JSON(w, handler.subsystemStates())
+}
+
+func (handler *Handler) subsystemStates() []subsystemState {
+	states := make([]subsystemState, 0, len(scheduler.Subsystems))
+	for _, subsystem := range scheduler.Subsystems {
+		states = append(states, subsystemState{Name: subsystem, Paused: handler.Scheduler.IsSubsystemPaused(subsystem)})
+	}
+	return states
+}
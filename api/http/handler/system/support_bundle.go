@@ -0,0 +1,261 @@
+package system
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/archive"
+)
+
+const redactedValue = "<redacted>"
+
+var secretLogPattern = regexp.MustCompile(`(?i)((?:password|passwd|secret|token|key)["']?\s*[:=]\s*["']?)[^\s"'&,]+`)
+
+type (
+	versionInformation struct {
+		APIVersion string
+		DBVersion  int
+		Edition    portainer.SoftwareEdition
+		InstanceID string
+	}
+
+	endpointDiagnostics struct {
+		ID               portainer.EndpointID
+		Name             string
+		Type             portainer.EndpointType
+		URL              string
+		GroupID          portainer.EndpointGroupID
+		Status           portainer.EndpointStatus
+		TLS              bool
+		LastSnapshotTime int64  `json:",omitempty"`
+		LastCheckInDate  int64  `json:",omitempty"`
+		TunnelStatus     string `json:",omitempty"`
+	}
+)
+
+// @id SystemSupportBundle
+// @summary Download a support bundle
+// @description Generate and download a zip archive containing redacted settings, endpoint diagnostics,
+// @description recent server logs, version information and the roles authorizations registry.
+// @description **Access policy**: administrator
+// @tags system
+// @security jwt
+// @produce application/zip
+// @success 200 {file} file "Support bundle archive"
+// @failure 500 "Server error"
+// @router /system/support-bundle [get]
+func (handler *Handler) supportBundle(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	bundlePath, err := handler.FileService.GetTemporaryPath()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create temporary folder", err}
+	}
+
+	err = os.MkdirAll(bundlePath, 0700)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create temporary folder", err}
+	}
+	defer handler.cleanUp(bundlePath)
+
+	err = handler.writeSupportBundleFiles(bundlePath)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to generate support bundle", err}
+	}
+
+	fileName := fmt.Sprintf("portainer-support-bundle-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+
+	// The response headers are already sent at this point, errors can only be logged.
+	err = archive.ZipDirectory(w, bundlePath)
+	if err != nil {
+		log.Printf("http error: Unable to stream support bundle (err=%s)\n", err)
+	}
+
+	return nil
+}
+
+func (handler *Handler) writeSupportBundleFiles(bundlePath string) error {
+	settings, err := handler.DataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	err = handler.FileService.WriteJSONToFile(path.Join(bundlePath, "settings.json"), redactSettings(settings))
+	if err != nil {
+		return err
+	}
+
+	diagnostics, err := handler.endpointsDiagnostics()
+	if err != nil {
+		return err
+	}
+
+	err = handler.FileService.WriteJSONToFile(path.Join(bundlePath, "endpoints.json"), diagnostics)
+	if err != nil {
+		return err
+	}
+
+	version, err := handler.versionInformation()
+	if err != nil {
+		return err
+	}
+
+	err = handler.FileService.WriteJSONToFile(path.Join(bundlePath, "version.json"), version)
+	if err != nil {
+		return err
+	}
+
+	roles, err := handler.DataStore.Role().Roles()
+	if err != nil {
+		return err
+	}
+
+	err = handler.FileService.WriteJSONToFile(path.Join(bundlePath, "roles.json"), roles)
+	if err != nil {
+		return err
+	}
+
+	return handler.writeLogs(path.Join(bundlePath, "portainer.log"))
+}
+
+func (handler *Handler) endpointsDiagnostics() ([]endpointDiagnostics, error) {
+	endpoints, err := handler.DataStore.Endpoint().Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := make([]endpointDiagnostics, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		endpointDiagnostic := endpointDiagnostics{
+			ID:              endpoint.ID,
+			Name:            endpoint.Name,
+			Type:            endpoint.Type,
+			URL:             redactURL(endpoint.URL),
+			GroupID:         endpoint.GroupID,
+			Status:          endpoint.Status,
+			TLS:             endpoint.TLSConfig.TLS,
+			LastCheckInDate: endpoint.LastCheckInDate,
+		}
+
+		if len(endpoint.Snapshots) > 0 {
+			endpointDiagnostic.LastSnapshotTime = endpoint.Snapshots[0].Time
+		} else if len(endpoint.Kubernetes.Snapshots) > 0 {
+			endpointDiagnostic.LastSnapshotTime = endpoint.Kubernetes.Snapshots[0].Time
+		}
+
+		if endpoint.Type == portainer.EdgeAgentOnDockerEnvironment || endpoint.Type == portainer.EdgeAgentOnKubernetesEnvironment {
+			endpointDiagnostic.TunnelStatus = handler.ReverseTunnelService.GetTunnelDetails(endpoint.ID).Status
+		}
+
+		diagnostics = append(diagnostics, endpointDiagnostic)
+	}
+
+	return diagnostics, nil
+}
+
+func (handler *Handler) versionInformation() (*versionInformation, error) {
+	dbVersion, err := handler.DataStore.Version().DBVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	edition, err := handler.DataStore.Version().Edition()
+	if err != nil {
+		return nil, err
+	}
+
+	instanceID, err := handler.DataStore.Version().InstanceID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &versionInformation{
+		APIVersion: portainer.APIVersion,
+		DBVersion:  dbVersion,
+		Edition:    edition,
+		InstanceID: instanceID,
+	}, nil
+}
+
+func (handler *Handler) writeLogs(filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if handler.LogBuffer == nil {
+		return nil
+	}
+
+	for _, entry := range handler.LogBuffer.Entries() {
+		_, err = fmt.Fprintln(file, redactLogEntry(entry))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (handler *Handler) cleanUp(bundlePath string) {
+	err := handler.FileService.RemoveDirectory(bundlePath)
+	if err != nil {
+		log.Printf("http error: Unable to cleanup support bundle (err=%s)\n", err)
+	}
+}
+
+// redactSettings returns a copy of the settings where every credential is replaced.
+func redactSettings(settings *portainer.Settings) portainer.Settings {
+	redacted := *settings
+
+	if redacted.LDAPSettings.Password != "" {
+		redacted.LDAPSettings.Password = redactedValue
+	}
+	if redacted.OAuthSettings.ClientSecret != "" {
+		redacted.OAuthSettings.ClientSecret = redactedValue
+	}
+	if redacted.SMTPSettings.Password != "" {
+		redacted.SMTPSettings.Password = redactedValue
+	}
+	if redacted.MetricsSettings.BearerToken != "" {
+		redacted.MetricsSettings.BearerToken = redactedValue
+	}
+	if redacted.MetricsSettings.BearerTokenHash != "" {
+		redacted.MetricsSettings.BearerTokenHash = redactedValue
+	}
+	redacted.LDAPSettings.URL = redactURL(redacted.LDAPSettings.URL)
+	redacted.OAuthSettings.AccessTokenURI = redactURL(redacted.OAuthSettings.AccessTokenURI)
+	redacted.OAuthSettings.AuthorizationURI = redactURL(redacted.OAuthSettings.AuthorizationURI)
+	redacted.OAuthSettings.ResourceURI = redactURL(redacted.OAuthSettings.ResourceURI)
+
+	return redacted
+}
+
+// redactURL removes the credentials that might be embedded in a URL (user:password@host)
+func redactURL(rawURL string) string {
+	if !strings.Contains(rawURL, "@") {
+		return rawURL
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.User == nil {
+		return rawURL
+	}
+
+	parsedURL.User = url.User(redactedValue)
+	return parsedURL.String()
+}
+
+func redactLogEntry(entry string) string {
+	return secretLogPattern.ReplaceAllString(entry, "${1}"+redactedValue)
+}
@@ -9,14 +9,25 @@ import (
 	"github.com/portainer/libhttp/request"
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/tag"
 )
 
 type tagCreatePayload struct {
-	// Name
-	Name string `validate:"required" example:"org/acme"`
+	// Name, defaults to key=value for a key/value tag
+	Name string `example:"org/acme"`
+	// Key of a key/value tag
+	Key string `example:"environment"`
+	// Value of a key/value tag
+	Value string `example:"production"`
 }
 
 func (payload *tagCreatePayload) Validate(r *http.Request) error {
+	if govalidator.IsNull(payload.Key) && !govalidator.IsNull(payload.Value) {
+		return errors.New("Invalid tag key. A key is required when a value is specified")
+	}
+	if govalidator.IsNull(payload.Name) && !govalidator.IsNull(payload.Key) {
+		payload.Name = tag.KeyValueName(payload.Key, payload.Value)
+	}
 	if govalidator.IsNull(payload.Name) {
 		return errors.New("Invalid tag name")
 	}
@@ -25,7 +36,8 @@ func (payload *tagCreatePayload) Validate(r *http.Request) error {
 
 // @id TagCreate
 // @summary Create a new tag
-// @description Create a new tag.
+// @description Create a new tag. A key/value tag is created when a key is specified, its keys can be required on the endpoints
+// @description by the required endpoint tag keys of the settings.
 // @description **Access policy**: administrator
 // @tags tags
 // @security jwt
@@ -53,16 +65,18 @@ func (handler *Handler) tagCreate(w http.ResponseWriter, r *http.Request) *httpe
 		}
 	}
 
-	tag := &portainer.Tag{
+	endpointTag := &portainer.Tag{
 		Name:           payload.Name,
+		Key:            payload.Key,
+		Value:          payload.Value,
 		EndpointGroups: map[portainer.EndpointGroupID]bool{},
 		Endpoints:      map[portainer.EndpointID]bool{},
 	}
 
-	err = handler.DataStore.Tag().CreateTag(tag)
+	err = handler.DataStore.Tag().CreateTag(endpointTag)
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the tag inside the database", err}
 	}
 
-	return response.JSON(w, tag)
+	return response.JSON(w, endpointTag)
 }
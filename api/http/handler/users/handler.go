@@ -48,6 +48,12 @@ func NewHandler(bouncer *security.RequestBouncer, rateLimiter *security.RateLimi
 		bouncer.AdminAccess(httperror.LoggerHandler(h.userDelete))).Methods(http.MethodDelete)
 	h.Handle("/users/{id}/memberships",
 		bouncer.RestrictedAccess(httperror.LoggerHandler(h.userMemberships))).Methods(http.MethodGet)
+	h.Handle("/users/{id}/preferences",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userPreferencesInspect))).Methods(http.MethodGet)
+	h.Handle("/users/{id}/preferences",
+		bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userPreferencesUpdate))).Methods(http.MethodPut)
+	h.Handle("/users/{id}/quotas",
+		bouncer.RestrictedAccess(httperror.LoggerHandler(h.userQuotas))).Methods(http.MethodGet)
 	h.Handle("/users/{id}/passwd",
 		rateLimiter.LimitAccess(bouncer.AuthenticatedAccess(httperror.LoggerHandler(h.userUpdatePassword)))).Methods(http.MethodPut)
 	h.Handle("/users/admin/check",
@@ -0,0 +1,139 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	httperrors "github.com/portainer/portainer/api/http/errors"
+	"github.com/portainer/portainer/api/http/security"
+)
+
+// localePattern matches a language tag such as en, fr-FR or zh-Hant-TW
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+type userPreferencesUpdatePayload struct {
+	// UI theme, the current theme is kept when not specified
+	Theme *string `enums:"light,dark,auto" example:"dark"`
+	// UI locale as a language tag, the current locale is kept when not specified
+	Locale *string `example:"en-US"`
+}
+
+func (payload *userPreferencesUpdatePayload) Validate(r *http.Request) error {
+	if payload.Theme != nil {
+		switch portainer.UserTheme(*payload.Theme) {
+		case "", portainer.UserThemeLight, portainer.UserThemeDark, portainer.UserThemeAuto:
+		default:
+			return errors.New("Invalid theme. Value must be one of: light, dark or auto")
+		}
+	}
+
+	if payload.Locale != nil && *payload.Locale != "" && !localePattern.MatchString(*payload.Locale) {
+		return errors.New("Invalid locale. Value must be a language tag such as en or en-US")
+	}
+
+	return nil
+}
+
+// @id UserPreferencesInspect
+// @summary Inspect the UI preferences of a user
+// @description Retrieve the UI preferences of a user. A regular user account can only inspect his preferences.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @produce json
+// @param id path int true "User identifier"
+// @success 200 {object} portainer.UserPreferences "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/preferences [get]
+func (handler *Handler) userPreferencesInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, handlerErr := handler.preferencesUserFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	return response.JSON(w, user.Preferences)
+}
+
+// @id UserPreferencesUpdate
+// @summary Update the UI preferences of a user
+// @description Update the UI theme and locale of a user. The preferences are returned when the user authenticates
+// @description and are removed with the user. A regular user account can only update his preferences.
+// @description **Access policy**: authenticated
+// @tags users
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "User identifier"
+// @param body body userPreferencesUpdatePayload true "User preferences"
+// @success 200 {object} portainer.UserPreferences "Success"
+// @failure 400 "Invalid request"
+// @failure 403 "Permission denied"
+// @failure 404 "User not found"
+// @failure 500 "Server error"
+// @router /users/{id}/preferences [put]
+func (handler *Handler) userPreferencesUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	user, handlerErr := handler.preferencesUserFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	var payload userPreferencesUpdatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	if payload.Theme != nil {
+		user.Preferences.Theme = portainer.UserTheme(*payload.Theme)
+	}
+
+	if payload.Locale != nil {
+		user.Preferences.Locale = *payload.Locale
+	}
+
+	err = handler.DataStore.User().UpdateUser(user.ID, user)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist user changes inside the database", err}
+	}
+
+	return response.JSON(w, user.Preferences)
+}
+
+func (handler *Handler) preferencesUserFromRequest(r *http.Request) (*portainer.User, *httperror.HandlerError) {
+	userID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid user identifier route variable", err}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user authentication token", err}
+	}
+
+	return handler.preferencesUser(tokenData, portainer.UserID(userID))
+}
+
+// preferencesUser returns the user whose preferences are accessed, a regular user can only access their own preferences
+func (handler *Handler) preferencesUser(tokenData *portainer.TokenData, userID portainer.UserID) (*portainer.User, *httperror.HandlerError) {
+	if tokenData.Role != portainer.AdministratorRole && tokenData.ID != userID {
+		return nil, &httperror.HandlerError{http.StatusForbidden, "Permission denied to access user preferences", httperrors.ErrUnauthorized}
+	}
+
+	user, err := handler.DataStore.User().User(userID)
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a user with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a user with the specified identifier inside the database", err}
+	}
+
+	return user, nil
+}
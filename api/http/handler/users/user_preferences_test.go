@@ -0,0 +1,56 @@
+package users
+
+import (
+	"net/http"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_userPreferencesUpdatePayload_Validate(t *testing.T) {
+	value := func(s string) *string { return &s }
+
+	valid := []userPreferencesUpdatePayload{
+		{},
+		{Theme: value("dark"), Locale: value("en-US")},
+		{Theme: value(""), Locale: value("")},
+		{Locale: value("zh-Hant-TW")},
+	}
+	for _, payload := range valid {
+		assert.NoError(t, payload.Validate(nil))
+	}
+
+	invalid := []userPreferencesUpdatePayload{
+		{Theme: value("solarized")},
+		{Locale: value("english")},
+		{Locale: value("en_US")},
+		{Locale: value("en-US;rm")},
+	}
+	for _, payload := range invalid {
+		assert.Error(t, payload.Validate(nil))
+	}
+}
+
+func Test_preferencesUser(t *testing.T) {
+	handler := &Handler{DataStore: testhelpers.NewDatastore(testhelpers.WithUsers([]portainer.User{
+		{ID: 1, Username: "admin", Role: portainer.AdministratorRole},
+		{ID: 2, Username: "bob", Role: portainer.StandardUserRole, Preferences: portainer.UserPreferences{Theme: portainer.UserThemeDark}},
+		{ID: 3, Username: "alice", Role: portainer.StandardUserRole},
+	}))}
+
+	user, handlerErr := handler.preferencesUser(&portainer.TokenData{ID: 2, Role: portainer.StandardUserRole}, 2)
+	assert.Nil(t, handlerErr)
+	assert.Equal(t, portainer.UserThemeDark, user.Preferences.Theme)
+
+	_, handlerErr = handler.preferencesUser(&portainer.TokenData{ID: 3, Role: portainer.StandardUserRole}, 2)
+	assert.Equal(t, http.StatusForbidden, handlerErr.StatusCode)
+
+	user, handlerErr = handler.preferencesUser(&portainer.TokenData{ID: 1, Role: portainer.AdministratorRole}, 2)
+	assert.Nil(t, handlerErr)
+	assert.Equal(t, "bob", user.Username)
+
+	_, handlerErr = handler.preferencesUser(&portainer.TokenData{ID: 1, Role: portainer.AdministratorRole}, 4)
+	assert.Equal(t, http.StatusNotFound, handlerErr.StatusCode)
+}
@@ -0,0 +1,50 @@
+package volumebackups
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httperror "github.com/portainer/libhttp/error"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/volumebackup"
+)
+
+// Handler is the HTTP handler used to handle volume backup operations.
+type Handler struct {
+	*mux.Router
+	DataStore           portainer.DataStore
+	DockerClientFactory *docker.ClientFactory
+	VolumeBackupService *volumebackup.Service
+}
+
+// NewHandler creates a handler to manage volume backup operations.
+func NewHandler(bouncer *security.RequestBouncer) *Handler {
+	h := &Handler{
+		Router: mux.NewRouter(),
+	}
+	h.Handle("/volume_backups",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.volumeBackupScheduleCreate))).Methods(http.MethodPost)
+	h.Handle("/volume_backups",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.volumeBackupScheduleList))).Methods(http.MethodGet)
+	h.Handle("/volume_backups/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.volumeBackupScheduleInspect))).Methods(http.MethodGet)
+	h.Handle("/volume_backups/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.volumeBackupScheduleUpdate))).Methods(http.MethodPut)
+	h.Handle("/volume_backups/{id}",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.volumeBackupScheduleDelete))).Methods(http.MethodDelete)
+	h.Handle("/volume_backups/{id}/run",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.volumeBackupScheduleRun))).Methods(http.MethodPost)
+	h.Handle("/volume_backups/{id}/backups/{backupId}/restore",
+		bouncer.AdminAccess(httperror.LoggerHandler(h.volumeBackupRestore))).Methods(http.MethodPost)
+
+	return h
+}
+
+// hideFields removes the encrypted S3 secret access key of a schedule from the responses
+func hideFields(schedule *portainer.VolumeBackupSchedule) {
+	if schedule.Destination.S3 != nil {
+		schedule.Destination.S3.SecretAccessKey = ""
+	}
+}
@@ -0,0 +1,154 @@
+package volumebackups
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/docker/docker/client"
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/volumebackup"
+)
+
+type volumeBackupScheduleCreatePayload struct {
+	// Endpoint where the volume is located
+	EndpointID int `validate:"required" example:"1"`
+	// Name of the volume to back up
+	VolumeName string `validate:"required" example:"myapp_data"`
+	// Cron expression (minute hour day-of-month month day-of-week) in UTC
+	CronExpression string `validate:"required" example:"0 2 * * *"`
+	// Number of backups kept
+	Retention int `validate:"required" example:"7"`
+	// Location where the backups are stored. The S3 secret access key is required for the S3 destinations
+	Destination portainer.VolumeBackupDestination
+	// Email addresses notified when a backup fails
+	NotificationRecipients []string `example:"ops@example.com"`
+}
+
+func (payload *volumeBackupScheduleCreatePayload) Validate(r *http.Request) error {
+	if payload.EndpointID == 0 {
+		return errors.New("Invalid endpoint identifier")
+	}
+	if govalidator.IsNull(payload.VolumeName) {
+		return errors.New("Invalid volume name")
+	}
+	if payload.Retention <= 0 {
+		return errors.New("Invalid retention. Must be a positive number of backups")
+	}
+
+	err := volumebackup.ValidateCronExpression(payload.CronExpression)
+	if err != nil {
+		return err
+	}
+
+	err = volumebackup.ValidateDestination(&payload.Destination)
+	if err != nil {
+		return err
+	}
+	if payload.Destination.Type == portainer.VolumeBackupDestinationS3 && payload.Destination.S3.SecretAccessKey == "" {
+		return errors.New("Invalid S3 destination. Secret access key is required")
+	}
+
+	return validateRecipients(payload.NotificationRecipients)
+}
+
+func validateRecipients(recipients []string) error {
+	for _, recipient := range recipients {
+		if !govalidator.IsEmail(recipient) {
+			return errors.New("Invalid notification recipient. Must be an email address")
+		}
+	}
+	return nil
+}
+
+// @id VolumeBackupScheduleCreate
+// @summary Create a volume backup schedule
+// @description Schedule the recurring backup of the data of a Docker volume. On each run, a helper container mounting
+// @description the volume is created and the volume data is archived and stored locally or in an S3 bucket,
+// @description keeping the last backups up to the retention.
+// @description **Access policy**: administrator
+// @tags volume_backups
+// @security jwt
+// @accept json
+// @produce json
+// @param body body volumeBackupScheduleCreatePayload true "Backup schedule details"
+// @success 200 {object} portainer.VolumeBackupSchedule "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Endpoint or volume not found"
+// @failure 500 "Server error"
+// @router /volume_backups [post]
+func (handler *Handler) volumeBackupScheduleCreate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload volumeBackupScheduleCreatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	endpoint, err := handler.DataStore.Endpoint().Endpoint(portainer.EndpointID(payload.EndpointID))
+	if err == bolterrors.ErrObjectNotFound {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find an endpoint with the specified identifier inside the database", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to find an endpoint with the specified identifier inside the database", err}
+	}
+
+	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to create Docker client", err}
+	}
+	defer dockerClient.Close()
+
+	_, err = dockerClient.VolumeInspect(context.Background(), payload.VolumeName)
+	if client.IsErrNotFound(err) {
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a volume with the specified name on the endpoint", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to inspect volume", err}
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
+	}
+
+	if payload.Destination.Type == portainer.VolumeBackupDestinationS3 {
+		payload.Destination.S3.SecretAccessKey, err = handler.VolumeBackupService.EncryptSecretKey(payload.Destination.S3.SecretAccessKey)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to encrypt the S3 secret access key", err}
+		}
+	} else {
+		payload.Destination.S3 = nil
+	}
+
+	now := time.Now()
+	schedule := &portainer.VolumeBackupSchedule{
+		EndpointID:             endpoint.ID,
+		VolumeName:             payload.VolumeName,
+		CronExpression:         payload.CronExpression,
+		Retention:              payload.Retention,
+		Destination:            payload.Destination,
+		NotificationRecipients: payload.NotificationRecipients,
+		Backups:                []portainer.VolumeBackup{},
+		History:                []portainer.VolumeBackupRun{},
+		CreatedBy:              tokenData.ID,
+		CreationDate:           now.Unix(),
+	}
+
+	err = volumebackup.Schedule(schedule, now)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid cron expression", err}
+	}
+
+	err = handler.DataStore.VolumeBackupSchedule().CreateVolumeBackupSchedule(schedule)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the volume backup schedule inside the database", err}
+	}
+
+	hideFields(schedule)
+	return response.JSON(w, schedule)
+}
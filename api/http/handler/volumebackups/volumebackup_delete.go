@@ -0,0 +1,55 @@
+package volumebackups
+
+import (
+	"fmt"
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id VolumeBackupScheduleDelete
+// @summary Remove a volume backup schedule
+// @description Remove a volume backup schedule. The stored backups are kept unless removeBackups is specified.
+// @description **Access policy**: administrator
+// @tags volume_backups
+// @security jwt
+// @param id path int true "Backup schedule identifier"
+// @param removeBackups query bool false "Remove the stored backups of the schedule"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup schedule not found"
+// @failure 500 "Server error"
+// @router /volume_backups/{id} [delete]
+func (handler *Handler) volumeBackupScheduleDelete(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	removeBackups, _ := request.RetrieveBooleanQueryParameter(r, "removeBackups", true)
+
+	schedule, handlerErr := handler.scheduleFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	if removeBackups {
+		remaining, err := handler.VolumeBackupService.RemoveBackups(schedule)
+		if err != nil {
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the stored backups", err}
+		}
+
+		if len(remaining) > 0 {
+			schedule.Backups = remaining
+			err = handler.DataStore.VolumeBackupSchedule().UpdateVolumeBackupSchedule(schedule.ID, schedule)
+			if err != nil {
+				return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the volume backup schedule changes inside the database", err}
+			}
+			return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the stored backups", fmt.Errorf("%d backups could not be removed, the schedule is kept", len(remaining))}
+		}
+	}
+
+	err := handler.DataStore.VolumeBackupSchedule().DeleteVolumeBackupSchedule(schedule.ID)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to remove the volume backup schedule from the database", err}
+	}
+
+	return response.Empty(w)
+}
@@ -0,0 +1,50 @@
+package volumebackups
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+)
+
+// @id VolumeBackupScheduleInspect
+// @summary Inspect a volume backup schedule
+// @description Retrieve details about a volume backup schedule, including its stored backups and the history of its runs.
+// @description **Access policy**: administrator
+// @tags volume_backups
+// @security jwt
+// @produce json
+// @param id path int true "Backup schedule identifier"
+// @success 200 {object} portainer.VolumeBackupSchedule "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup schedule not found"
+// @failure 500 "Server error"
+// @router /volume_backups/{id} [get]
+func (handler *Handler) volumeBackupScheduleInspect(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	schedule, handlerErr := handler.scheduleFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	hideFields(schedule)
+	return response.JSON(w, schedule)
+}
+
+func (handler *Handler) scheduleFromRequest(r *http.Request) (*portainer.VolumeBackupSchedule, *httperror.HandlerError) {
+	scheduleID, err := request.RetrieveNumericRouteVariableValue(r, "id")
+	if err != nil {
+		return nil, &httperror.HandlerError{http.StatusBadRequest, "Invalid backup schedule identifier route variable", err}
+	}
+
+	schedule, err := handler.DataStore.VolumeBackupSchedule().VolumeBackupSchedule(portainer.VolumeBackupScheduleID(scheduleID))
+	if err == bolterrors.ErrObjectNotFound {
+		return nil, &httperror.HandlerError{http.StatusNotFound, "Unable to find a backup schedule with the specified identifier inside the database", err}
+	} else if err != nil {
+		return nil, &httperror.HandlerError{http.StatusInternalServerError, "Unable to find a backup schedule with the specified identifier inside the database", err}
+	}
+
+	return schedule, nil
+}
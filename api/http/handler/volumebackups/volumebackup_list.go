@@ -0,0 +1,31 @@
+package volumebackups
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+)
+
+// @id VolumeBackupScheduleList
+// @summary List volume backup schedules
+// @description List the volume backup schedules with their stored backups and their history.
+// @description **Access policy**: administrator
+// @tags volume_backups
+// @security jwt
+// @produce json
+// @success 200 {array} portainer.VolumeBackupSchedule "Success"
+// @failure 500 "Server error"
+// @router /volume_backups [get]
+func (handler *Handler) volumeBackupScheduleList(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	schedules, err := handler.DataStore.VolumeBackupSchedule().VolumeBackupSchedules()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve volume backup schedules from the database", err}
+	}
+
+	for idx := range schedules {
+		hideFields(&schedules[idx])
+	}
+
+	return response.JSON(w, schedules)
+}
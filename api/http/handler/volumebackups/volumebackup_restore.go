@@ -0,0 +1,48 @@
+package volumebackups
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/internal/volumebackup"
+)
+
+// @id VolumeBackupRestore
+// @summary Restore a volume backup
+// @description Replace the content of the volume of a schedule by the content of one of its backups.
+// @description The stack or the containers using the volume must be stopped before restoring it.
+// @description **Access policy**: administrator
+// @tags volume_backups
+// @security jwt
+// @param id path int true "Backup schedule identifier"
+// @param backupId path string true "Backup identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup schedule or backup not found"
+// @failure 409 "The volume is in use, or a backup or a restore of the volume is already in progress"
+// @failure 500 "Server error"
+// @router /volume_backups/{id}/backups/{backupId}/restore [post]
+func (handler *Handler) volumeBackupRestore(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	backupID, err := request.RetrieveRouteVariableValue(r, "backupId")
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid backup identifier route variable", err}
+	}
+
+	schedule, handlerErr := handler.scheduleFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	err = handler.VolumeBackupService.Restore(schedule, backupID)
+	switch err {
+	case nil:
+		return response.Empty(w)
+	case volumebackup.ErrBackupNotFound:
+		return &httperror.HandlerError{http.StatusNotFound, "Unable to find a backup with the specified identifier in the schedule", err}
+	case volumebackup.ErrVolumeInUse, volumebackup.ErrBackupInProgress:
+		return &httperror.HandlerError{http.StatusConflict, "Unable to restore the backup", err}
+	}
+	return &httperror.HandlerError{http.StatusInternalServerError, "Unable to restore the backup", err}
+}
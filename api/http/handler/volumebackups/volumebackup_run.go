@@ -0,0 +1,39 @@
+package volumebackups
+
+import (
+	"net/http"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/internal/volumebackup"
+)
+
+// @id VolumeBackupScheduleRun
+// @summary Back up a volume now
+// @description Start a backup of the volume of a schedule in the background, without waiting for the next scheduled run.
+// @description The result of the backup is available in the history of the schedule.
+// @description **Access policy**: administrator
+// @tags volume_backups
+// @security jwt
+// @param id path int true "Backup schedule identifier"
+// @success 204 "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup schedule not found"
+// @failure 409 "A backup or a restore of the volume is already in progress"
+// @failure 500 "Server error"
+// @router /volume_backups/{id}/run [post]
+func (handler *Handler) volumeBackupScheduleRun(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	schedule, handlerErr := handler.scheduleFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	err := handler.VolumeBackupService.RunNow(schedule.ID)
+	if err == volumebackup.ErrBackupInProgress {
+		return &httperror.HandlerError{http.StatusConflict, "Unable to start the backup", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to start the backup", err}
+	}
+
+	return response.Empty(w)
+}
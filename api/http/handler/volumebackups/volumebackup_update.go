@@ -0,0 +1,88 @@
+package volumebackups
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/libhttp/request"
+	"github.com/portainer/libhttp/response"
+	"github.com/portainer/portainer/api/internal/volumebackup"
+)
+
+type volumeBackupScheduleUpdatePayload struct {
+	// Cron expression (minute hour day-of-month month day-of-week) in UTC
+	CronExpression *string `example:"0 3 * * *"`
+	// Number of backups kept, the oldest backups are removed on the next backup
+	Retention *int `example:"14"`
+	// Email addresses notified when a backup fails
+	NotificationRecipients []string `example:"ops@example.com"`
+}
+
+func (payload *volumeBackupScheduleUpdatePayload) Validate(r *http.Request) error {
+	if payload.CronExpression != nil {
+		err := volumebackup.ValidateCronExpression(*payload.CronExpression)
+		if err != nil {
+			return err
+		}
+	}
+	if payload.Retention != nil && *payload.Retention <= 0 {
+		return errors.New("Invalid retention. Must be a positive number of backups")
+	}
+	return validateRecipients(payload.NotificationRecipients)
+}
+
+// @id VolumeBackupScheduleUpdate
+// @summary Update a volume backup schedule
+// @description Update the cron expression, the retention or the notification recipients of a volume backup schedule.
+// @description The destination of a schedule cannot be changed as it holds the stored backups.
+// @description **Access policy**: administrator
+// @tags volume_backups
+// @security jwt
+// @accept json
+// @produce json
+// @param id path int true "Backup schedule identifier"
+// @param body body volumeBackupScheduleUpdatePayload true "Backup schedule details"
+// @success 200 {object} portainer.VolumeBackupSchedule "Success"
+// @failure 400 "Invalid request"
+// @failure 404 "Backup schedule not found"
+// @failure 500 "Server error"
+// @router /volume_backups/{id} [put]
+func (handler *Handler) volumeBackupScheduleUpdate(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
+	var payload volumeBackupScheduleUpdatePayload
+	err := request.DecodeAndValidateJSONPayload(r, &payload)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusBadRequest, "Invalid request payload", err}
+	}
+
+	schedule, handlerErr := handler.scheduleFromRequest(r)
+	if handlerErr != nil {
+		return handlerErr
+	}
+
+	if payload.CronExpression != nil {
+		schedule.CronExpression = *payload.CronExpression
+
+		err = volumebackup.Schedule(schedule, time.Now())
+		if err != nil {
+			return &httperror.HandlerError{http.StatusBadRequest, "Invalid cron expression", err}
+		}
+	}
+
+	if payload.Retention != nil {
+		schedule.Retention = *payload.Retention
+	}
+
+	if payload.NotificationRecipients != nil {
+		schedule.NotificationRecipients = payload.NotificationRecipients
+	}
+
+	err = handler.DataStore.VolumeBackupSchedule().UpdateVolumeBackupSchedule(schedule.ID, schedule)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to persist the volume backup schedule changes inside the database", err}
+	}
+
+	hideFields(schedule)
+	return response.JSON(w, schedule)
+}
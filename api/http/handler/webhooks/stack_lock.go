@@ -0,0 +1,45 @@
+package webhooks
+
+import (
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+const swarmStackLabel = "com.docker.stack.namespace"
+
+// serviceStackLock returns the edit lock of the stack deployed on the endpoint that the service belongs to,
+// nil when the service is not part of a stack or when its stack is not locked
+func serviceStackLock(stacks []portainer.Stack, endpointID portainer.EndpointID, serviceLabels map[string]string, now time.Time) *portainer.StackLock {
+	stackName := serviceLabels[swarmStackLabel]
+	if stackName == "" {
+		return nil
+	}
+
+	for idx := range stacks {
+		stack := &stacks[idx]
+		if stack.Name != stackName || !isDeployedOnEndpoint(stack, endpointID) {
+			continue
+		}
+
+		if lock := stackutils.ActiveLock(stack, now); lock != nil {
+			return lock
+		}
+	}
+
+	return nil
+}
+
+func isDeployedOnEndpoint(stack *portainer.Stack, endpointID portainer.EndpointID) bool {
+	if stack.EndpointID == endpointID {
+		return true
+	}
+
+	for _, deployment := range stack.Deployments {
+		if deployment.EndpointID == endpointID {
+			return true
+		}
+	}
+	return false
+}
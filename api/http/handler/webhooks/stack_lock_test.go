@@ -0,0 +1,32 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_serviceStackLock(t *testing.T) {
+	now := time.Now()
+	lock := &portainer.StackLock{LockedBy: "bob", ExpiryDate: now.Add(time.Hour).Unix()}
+
+	stacks := []portainer.Stack{
+		{ID: 1, Name: "web", EndpointID: 1, Lock: lock},
+		{ID: 2, Name: "db", EndpointID: 1},
+		{ID: 3, Name: "cache", EndpointID: 2, Deployments: []portainer.StackDeployment{{EndpointID: 1}}, Lock: lock},
+		{ID: 4, Name: "expired", EndpointID: 1, Lock: &portainer.StackLock{LockedBy: "bob", ExpiryDate: now.Add(-time.Hour).Unix()}},
+	}
+
+	serviceLabels := func(stackName string) map[string]string {
+		return map[string]string{swarmStackLabel: stackName}
+	}
+
+	assert.Equal(t, lock, serviceStackLock(stacks, 1, serviceLabels("web"), now))
+	assert.Equal(t, lock, serviceStackLock(stacks, 1, serviceLabels("cache"), now))
+	assert.Nil(t, serviceStackLock(stacks, 2, serviceLabels("web"), now))
+	assert.Nil(t, serviceStackLock(stacks, 1, serviceLabels("db"), now))
+	assert.Nil(t, serviceStackLock(stacks, 1, serviceLabels("expired"), now))
+	assert.Nil(t, serviceStackLock(stacks, 1, map[string]string{}, now))
+}
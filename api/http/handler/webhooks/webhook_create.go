@@ -11,6 +11,8 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/quota"
 )
 
 type webhookCreatePayload struct {
@@ -41,6 +43,7 @@ func (payload *webhookCreatePayload) Validate(r *http.Request) error {
 // @param body body webhookCreatePayload true "Webhook data"
 // @success 200 {object} portainer.Webhook
 // @failure 400
+// @failure 403 "Maximum number of created webhooks reached"
 // @failure 409
 // @failure 500
 // @router /webhooks [post]
@@ -59,6 +62,18 @@ func (handler *Handler) webhookCreate(w http.ResponseWriter, r *http.Request) *h
 		return &httperror.HandlerError{http.StatusConflict, "A webhook for this resource already exists", errors.New("A webhook for this resource already exists")}
 	}
 
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve user details from authentication token", err}
+	}
+
+	err = quota.CheckUserWebhookQuota(handler.DataStore, tokenData.ID)
+	if err == quota.ErrQuotaExceeded {
+		return &httperror.HandlerError{http.StatusForbidden, "Maximum number of created webhooks reached", err}
+	} else if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to verify user resource quotas", err}
+	}
+
 	token, err := uuid.NewV4()
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Error creating unique token", err}
@@ -69,6 +84,7 @@ func (handler *Handler) webhookCreate(w http.ResponseWriter, r *http.Request) *h
 		ResourceID:  payload.ResourceID,
 		EndpointID:  portainer.EndpointID(payload.EndpointID),
 		WebhookType: portainer.WebhookType(payload.WebhookType),
+		CreatedBy:   tokenData.ID,
 	}
 
 	err = handler.DataStore.Webhook().CreateWebhook(webhook)
@@ -3,8 +3,10 @@ package webhooks
 import (
 	"context"
 	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	dockertypes "github.com/docker/docker/api/types"
 	httperror "github.com/portainer/libhttp/error"
@@ -12,8 +14,11 @@ import (
 	"github.com/portainer/libhttp/response"
 	portainer "github.com/portainer/portainer/api"
 	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/internal/stackutils"
 )
 
+var errServiceStackLocked = errors.New("The stack of the service is locked by another user")
+
 // @summary Execute a webhook
 // @description Acts on a passed in token UUID to restart the docker service
 // @tags webhooks
@@ -22,6 +27,7 @@ import (
 // @param token path string true "Webhook token"
 // @success 202 "Webhook executed"
 // @failure 400
+// @failure 409 "The stack of the service is locked by a user"
 // @failure 500
 // @router /webhooks/{token} [post]
 func (handler *Handler) webhookExecute(w http.ResponseWriter, r *http.Request) *httperror.HandlerError {
@@ -55,13 +61,23 @@ func (handler *Handler) webhookExecute(w http.ResponseWriter, r *http.Request) *
 
 	switch webhookType {
 	case portainer.ServiceWebhook:
-		return handler.executeServiceWebhook(w, endpoint, resourceID, imageTag)
+		httpErr := handler.executeServiceWebhook(endpoint, resourceID, imageTag)
+		if httpErr != nil {
+			return httpErr
+		}
 	default:
 		return &httperror.HandlerError{http.StatusInternalServerError, "Unsupported webhook type", errors.New("Webhooks for this resource are not currently supported")}
 	}
+
+	err = handler.DataStore.Webhook().RecordWebhookInvocation(webhook.ID, time.Now().Unix())
+	if err != nil {
+		log.Printf("Warning: unable to update the usage of the webhook (id: %d): %s\n", webhook.ID, err)
+	}
+
+	return response.Empty(w)
 }
 
-func (handler *Handler) executeServiceWebhook(w http.ResponseWriter, endpoint *portainer.Endpoint, resourceID string, imageTag string) *httperror.HandlerError {
+func (handler *Handler) executeServiceWebhook(endpoint *portainer.Endpoint, resourceID string, imageTag string) *httperror.HandlerError {
 	dockerClient, err := handler.DockerClientFactory.CreateClient(endpoint, "")
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Error creating docker client", err}
@@ -73,6 +89,16 @@ func (handler *Handler) executeServiceWebhook(w http.ResponseWriter, endpoint *p
 		return &httperror.HandlerError{http.StatusInternalServerError, "Error looking up service", err}
 	}
 
+	stacks, err := handler.DataStore.Stack().Stacks()
+	if err != nil {
+		return &httperror.HandlerError{http.StatusInternalServerError, "Unable to retrieve stacks from the database", err}
+	}
+
+	lock := serviceStackLock(stacks, endpoint.ID, service.Spec.Labels, time.Now())
+	if lock != nil {
+		return &httperror.HandlerError{http.StatusConflict, stackutils.LockedStatus(lock), errServiceStackLocked}
+	}
+
 	service.Spec.TaskTemplate.ForceUpdate++
 
 	if imageTag != "" {
@@ -86,5 +112,5 @@ func (handler *Handler) executeServiceWebhook(w http.ResponseWriter, endpoint *p
 	if err != nil {
 		return &httperror.HandlerError{http.StatusInternalServerError, "Error updating service", err}
 	}
-	return response.Empty(w)
+	return nil
 }
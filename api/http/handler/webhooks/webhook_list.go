@@ -12,6 +12,8 @@ import (
 type webhookListOperationFilters struct {
 	ResourceID string `json:"ResourceID"`
 	EndpointID int    `json:"EndpointID"`
+	// Only return the webhooks created by this user
+	OwnerID int `json:"OwnerID"`
 }
 
 // @summary List webhooks
@@ -43,6 +45,10 @@ func (handler *Handler) webhookList(w http.ResponseWriter, r *http.Request) *htt
 }
 
 func filterWebhooks(webhooks []portainer.Webhook, filters *webhookListOperationFilters) []portainer.Webhook {
+	if filters.OwnerID != 0 {
+		webhooks = filterWebhooksByOwner(webhooks, portainer.UserID(filters.OwnerID))
+	}
+
 	if filters.EndpointID == 0 && filters.ResourceID == "" {
 		return webhooks
 	}
@@ -56,3 +62,14 @@ func filterWebhooks(webhooks []portainer.Webhook, filters *webhookListOperationF
 
 	return filteredWebhooks
 }
+
+func filterWebhooksByOwner(webhooks []portainer.Webhook, ownerID portainer.UserID) []portainer.Webhook {
+	filteredWebhooks := make([]portainer.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.CreatedBy == ownerID {
+			filteredWebhooks = append(filteredWebhooks, webhook)
+		}
+	}
+
+	return filteredWebhooks
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	httperror "github.com/portainer/libhttp/error"
+	"github.com/portainer/portainer/api/http/handler"
+	"github.com/portainer/portainer/api/internal/jsonlimit"
+)
+
+// jsonLimitsHandler rejects with a 400 Bad Request the requests whose JSON body exceeds the nesting depth
+// or the number of tokens allowed, before the body is decoded by the handlers.
+// Every body is checked whatever its content type, as the request payloads are decoded as JSON whatever their
+// content type, except the multipart forms used by the file uploads. The requests forwarded to the endpoints
+// are not checked either, as their bodies are decoded by the Docker or Kubernetes API, which define their own limits.
+func jsonLimitsHandler(next http.Handler, limits jsonlimit.Limits) http.Handler {
+	if !limits.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody || isMultipartContent(r) || handler.IsEndpointProxyRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := jsonlimit.CheckBody(r.Body, limits)
+		if err != nil {
+			httperror.WriteError(w, http.StatusBadRequest, "Invalid request payload", err)
+			return
+		}
+		r.Body = body
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isMultipartContent returns true when the request body is a multipart form. A content type that cannot be parsed
+// is not considered multipart, as the multipart forms cannot be read without a valid boundary parameter.
+func isMultipartContent(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "multipart/")
+}
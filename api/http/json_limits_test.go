@@ -0,0 +1,71 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/portainer/portainer/api/internal/jsonlimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_jsonLimitsHandler_shouldOnlyCheckThePortainerAPIRequests(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	limitsHandler := jsonLimitsHandler(next, jsonlimit.Limits{MaxDepth: 2})
+
+	body := `{"a":{"b":{"c":1}}}`
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/api/stacks", http.StatusBadRequest},
+		{"/api/endpoints/1", http.StatusBadRequest},
+		{"/api/endpoints/1/edge/stacks/1", http.StatusBadRequest},
+		{"/api/endpoints/1/docker/containers/create", http.StatusNoContent},
+		{"/api/endpoints/1/kubernetes/api/v1/namespaces", http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+
+		limitsHandler.ServeHTTP(recorder, request)
+		assert.Equal(t, test.expected, recorder.Code, test.path)
+	}
+}
+
+func Test_jsonLimitsHandler_shouldCheckTheBodiesWhateverTheirContentType(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	limitsHandler := jsonLimitsHandler(next, jsonlimit.Limits{MaxDepth: 2})
+
+	body := `{"a":{"b":{"c":1}}}`
+
+	tests := []struct {
+		contentType string
+		expected    int
+	}{
+		{"application/json", http.StatusBadRequest},
+		{"text/plain", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+		{"application/json; charset", http.StatusBadRequest},
+		{"multipart/form-data; boundary=abc", http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodPost, "/api/stacks", strings.NewReader(body))
+		if test.contentType != "" {
+			request.Header.Set("Content-Type", test.contentType)
+		}
+		recorder := httptest.NewRecorder()
+
+		limitsHandler.ServeHTTP(recorder, request)
+		assert.Equal(t, test.expected, recorder.Code, test.contentType)
+	}
+}
@@ -94,6 +94,7 @@ func (transport *Transport) newResourceControlFromPortainerLabels(labelsObject m
 
 func (transport *Transport) createPrivateResourceControl(resourceIdentifier string, resourceType portainer.ResourceControlType, userID portainer.UserID) (*portainer.ResourceControl, error) {
 	resourceControl := authorization.NewPrivateResourceControl(resourceIdentifier, resourceType, userID)
+	resourceControl.CreatedBy = userID
 
 	err := transport.dataStore.ResourceControl().CreateResourceControl(resourceControl)
 	if err != nil {
@@ -0,0 +1,137 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/containerdefaults"
+)
+
+// injectContainerDefaults updates the body of a container creation request to use the ulimits and sysctls
+// defaults of the endpoint group for each ulimit and sysctl that is not explicitly specified in the request.
+func (transport *Transport) injectContainerDefaults(request *http.Request) error {
+	return transport.injectDefaults(request, applyContainerDefaults)
+}
+
+// injectServiceDefaults updates the body of a service creation request to use the ulimits and sysctls
+// defaults of the endpoint group for each ulimit and sysctl that is not explicitly specified in the request.
+func (transport *Transport) injectServiceDefaults(request *http.Request) error {
+	return transport.injectDefaults(request, applyServiceDefaults)
+}
+
+func (transport *Transport) injectDefaults(request *http.Request, apply func(body []byte, defaults *portainer.EndpointGroupContainerDefaults) ([]byte, error)) error {
+	endpointGroup, err := transport.dataStore.EndpointGroup().EndpointGroup(transport.endpoint.GroupID)
+	if err != nil {
+		return err
+	}
+
+	defaults := endpointGroup.ContainerDefaults
+	if containerdefaults.IsEmpty(&defaults) {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return err
+	}
+
+	body, err = apply(body, &defaults)
+	if err != nil {
+		return err
+	}
+
+	request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	request.ContentLength = int64(len(body))
+	request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+func applyContainerDefaults(body []byte, defaults *portainer.EndpointGroupContainerDefaults) ([]byte, error) {
+	var containerConfig map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err := decoder.Decode(&containerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	hostConfig, ok := containerConfig["HostConfig"].(map[string]interface{})
+	if !ok {
+		hostConfig = map[string]interface{}{}
+		containerConfig["HostConfig"] = hostConfig
+	}
+
+	networkMode, _ := hostConfig["NetworkMode"].(string)
+	ipcMode, _ := hostConfig["IpcMode"].(string)
+
+	applyDefaults(hostConfig, defaults, networkMode, ipcMode)
+
+	return json.Marshal(containerConfig)
+}
+
+// applyServiceDefaults applies the defaults to the container specification of the task template of a service,
+// the services do not share the network or IPC namespace of another container
+func applyServiceDefaults(body []byte, defaults *portainer.EndpointGroupContainerDefaults) ([]byte, error) {
+	var serviceSpec map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err := decoder.Decode(&serviceSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	taskTemplate, _ := serviceSpec["TaskTemplate"].(map[string]interface{})
+	if taskTemplate == nil {
+		return body, nil
+	}
+
+	containerSpec, _ := taskTemplate["ContainerSpec"].(map[string]interface{})
+	if containerSpec == nil {
+		return body, nil
+	}
+
+	applyDefaults(containerSpec, defaults, "", "")
+
+	return json.Marshal(serviceSpec)
+}
+
+// applyDefaults adds the ulimits and sysctls defaults to the Ulimits and Sysctls properties of a container configuration
+func applyDefaults(config map[string]interface{}, defaults *portainer.EndpointGroupContainerDefaults, networkMode, ipcMode string) {
+	ulimits, _ := config["Ulimits"].([]interface{})
+	names := make(map[string]bool)
+	for _, ulimit := range ulimits {
+		if object, ok := ulimit.(map[string]interface{}); ok {
+			name, _ := object["Name"].(string)
+			names[name] = true
+		}
+	}
+
+	for _, ulimit := range defaults.Ulimits {
+		if !names[ulimit.Name] {
+			ulimits = append(ulimits, ulimit)
+		}
+	}
+	if len(ulimits) > 0 {
+		config["Ulimits"] = ulimits
+	}
+
+	sysctls, ok := config["Sysctls"].(map[string]interface{})
+	if !ok {
+		sysctls = map[string]interface{}{}
+	}
+
+	for key, value := range containerdefaults.ApplicableSysctls(defaults.Sysctls, networkMode, ipcMode) {
+		if _, ok := sysctls[key]; !ok {
+			sysctls[key] = value
+		}
+	}
+	if len(sysctls) > 0 {
+		config["Sysctls"] = sysctls
+	}
+}
@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/http/proxy/factory/responseutils"
+)
+
+// verifyContainerMemorySettings validates the memory settings of a container creation request and
+// returns the warnings associated to the settings
+func (transport *Transport) verifyContainerMemorySettings(request *http.Request) ([]string, error) {
+	var partialContainer struct {
+		HostConfig struct {
+			Memory            int64
+			MemorySwap        int64
+			MemoryReservation int64
+			OomKillDisable    *bool
+			OomScoreAdj       int
+		}
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+	err = json.Unmarshal(body, &partialContainer)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := &portainer.ContainerMemorySettings{
+		Memory:            partialContainer.HostConfig.Memory,
+		MemorySwap:        partialContainer.HostConfig.MemorySwap,
+		MemoryReservation: partialContainer.HostConfig.MemoryReservation,
+		OomKillDisable:    partialContainer.HostConfig.OomKillDisable != nil && *partialContainer.HostConfig.OomKillDisable,
+		OomScoreAdj:       partialContainer.HostConfig.OomScoreAdj,
+	}
+
+	err = docker.ValidateMemorySettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return docker.MemorySettingsWarnings(settings), nil
+}
+
+// appendResponseWarnings adds warnings to the Warnings property of a container or service creation response,
+// or of a service update response
+func appendResponseWarnings(response *http.Response, warnings []string) error {
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	responseObject, err := responseutils.GetResponseAsJSONOBject(response)
+	if err != nil {
+		return err
+	}
+
+	existingWarnings, _ := responseObject["Warnings"].([]interface{})
+	for _, warning := range warnings {
+		existingWarnings = append(existingWarnings, warning)
+	}
+	responseObject["Warnings"] = existingWarnings
+
+	return responseutils.RewriteResponse(response, responseObject, response.StatusCode)
+}
+
+// decorateContainerMemorySettings adds the memory settings of a container and the associated warnings
+// to the Portainer metadata of a container inspect response
+func decorateContainerMemorySettings(responseObject map[string]interface{}) {
+	hostConfig := responseutils.GetJSONObject(responseObject, "HostConfig")
+	if hostConfig == nil {
+		return
+	}
+
+	settings := &portainer.ContainerMemorySettings{
+		Memory:            jsonInt64(hostConfig["Memory"]),
+		MemorySwap:        jsonInt64(hostConfig["MemorySwap"]),
+		MemoryReservation: jsonInt64(hostConfig["MemoryReservation"]),
+		OomScoreAdj:       int(jsonInt64(hostConfig["OomScoreAdj"])),
+	}
+	settings.OomKillDisable, _ = hostConfig["OomKillDisable"].(bool)
+
+	if responseObject["Portainer"] == nil {
+		responseObject["Portainer"] = make(map[string]interface{})
+	}
+
+	portainerMetadata := responseObject["Portainer"].(map[string]interface{})
+	portainerMetadata["MemorySettings"] = settings
+	portainerMetadata["MemoryWarnings"] = docker.MemorySettingsWarnings(settings)
+}
+
+func jsonInt64(value interface{}) int64 {
+	number, _ := value.(float64)
+	return int64(number)
+}
@@ -7,12 +7,15 @@ import (
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/docker/docker/client"
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/http/proxy/factory/responseutils"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/quota"
+	"github.com/portainer/portainer/api/internal/secret"
 )
 
 const (
@@ -82,6 +85,8 @@ func (transport *Transport) containerInspectOperation(response *http.Response, e
 		return err
 	}
 
+	decorateContainerMemorySettings(responseObject)
+
 	resourceOperationParameters := &resourceOperationParameters{
 		resourceIdentifierAttribute: containerObjectIdentifier,
 		resourceType:                portainer.ContainerResourceControl,
@@ -151,6 +156,7 @@ func containerHasBlackListedLabel(containerLabels map[string]interface{}, labelB
 
 func (transport *Transport) decorateContainerCreationOperation(request *http.Request, resourceIdentifierAttribute string, resourceType portainer.ResourceControlType) (*http.Response, error) {
 	type PartialContainer struct {
+		Labels     map[string]string `json:"Labels"`
 		HostConfig struct {
 			Privileged bool          `json:"Privileged"`
 			PidMode    string        `json:"PidMode"`
@@ -158,6 +164,9 @@ func (transport *Transport) decorateContainerCreationOperation(request *http.Req
 			CapAdd     []string      `json:"CapAdd"`
 			CapDrop    []string      `json:"CapDrop"`
 			Binds      []string      `json:"Binds"`
+			Mounts     []struct {
+				Source string `json:"Source"`
+			} `json:"Mounts"`
 		} `json:"HostConfig"`
 	}
 
@@ -165,11 +174,22 @@ func (transport *Transport) decorateContainerCreationOperation(request *http.Req
 		StatusCode: http.StatusForbidden,
 	}
 
+	badRequestResponse := &http.Response{
+		StatusCode: http.StatusBadRequest,
+	}
+
 	tokenData, err := security.RetrieveTokenData(request)
 	if err != nil {
 		return nil, err
 	}
 
+	err = quota.CheckUserQuota(transport.dataStore, tokenData.ID, portainer.ContainerResourceControl)
+	if err == quota.ErrQuotaExceeded {
+		return forbiddenResponse, errors.New("maximum number of created containers reached")
+	} else if err != nil {
+		return nil, err
+	}
+
 	isAdminOrEndpointAdmin, err := transport.isAdminOrEndpointAdmin(request)
 	if err != nil {
 		return nil, err
@@ -212,15 +232,65 @@ func (transport *Transport) decorateContainerCreationOperation(request *http.Req
 			return forbiddenResponse, errors.New("forbidden to use bind mounts")
 		}
 
+		for _, label := range []string{secret.SecretsLabel, secret.ProjectLabel, secret.ServiceLabel} {
+			if _, ok := partialContainer.Labels[label]; ok {
+				return forbiddenResponse, errors.New("forbidden to reference secrets")
+			}
+		}
+
+		for _, bind := range partialContainer.HostConfig.Binds {
+			if strings.HasPrefix(bind, secret.ResourcePrefix) {
+				return forbiddenResponse, errors.New("forbidden to mount secrets volumes")
+			}
+		}
+
+		for _, mount := range partialContainer.HostConfig.Mounts {
+			if strings.HasPrefix(mount.Source, secret.ResourcePrefix) {
+				return forbiddenResponse, errors.New("forbidden to mount secrets volumes")
+			}
+		}
+
 		request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 	}
 
+	imageWarnings, err := transport.verifyContainerImage(request)
+	if isImagePolicyViolation(err) {
+		return forbiddenResponse, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = transport.verifyContainerLogConfig(request)
+	if err != nil {
+		return badRequestResponse, err
+	}
+
+	memoryWarnings, err := transport.verifyContainerMemorySettings(request)
+	if err != nil {
+		return badRequestResponse, err
+	}
+
+	err = transport.injectContainerNetworkDefaults(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = transport.injectContainerDefaults(request)
+	if err != nil {
+		return nil, err
+	}
+
 	response, err := transport.executeDockerRequest(request)
 	if err != nil {
 		return response, err
 	}
 
 	if response.StatusCode == http.StatusCreated {
+		err = appendResponseWarnings(response, append(imageWarnings, memoryWarnings...))
+		if err != nil {
+			return response, err
+		}
+
 		err = transport.decorateGenericResourceCreationResponse(response, resourceIdentifierAttribute, resourceType, tokenData.ID)
 	}
 
@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/portainer/portainer/api/internal/imagetrust"
+)
+
+// verifyContainerImage verifies the image of a container creation request against the image trust policy and the image age policy
+func (transport *Transport) verifyContainerImage(request *http.Request) ([]string, error) {
+	return transport.verifyRequestImage(request, "Image")
+}
+
+// verifyServiceImage verifies the image of a service creation or update request against the image trust policy and the image age policy
+func (transport *Transport) verifyServiceImage(request *http.Request) ([]string, error) {
+	return transport.verifyRequestImage(request, "TaskTemplate", "ContainerSpec", "Image")
+}
+
+// verifyRequestImage verifies the image found at the path of the request body. The image of the request is replaced by
+// the image pinned to the digest verified by the image trust policy, so that the image deployed is the image verified.
+// The warnings raised by the image age policy are returned.
+func (transport *Transport) verifyRequestImage(request *http.Request, imagePath ...string) ([]string, error) {
+	if transport.imageVerifier == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+	var object map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err = decoder.Decode(&object)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := object
+	for _, key := range imagePath[:len(imagePath)-1] {
+		parent, _ = parent[key].(map[string]interface{})
+		if parent == nil {
+			return nil, nil
+		}
+	}
+
+	imageKey := imagePath[len(imagePath)-1]
+	image, _ := parent[imageKey].(string)
+	if image == "" {
+		return nil, nil
+	}
+
+	pinned, warnings, err := transport.imageVerifier.VerifyImages([]string{image})
+	if err != nil {
+		return nil, err
+	}
+
+	if pinned[image] == "" || pinned[image] == image {
+		return warnings, nil
+	}
+	parent[imageKey] = pinned[image]
+
+	body, err = json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	request.ContentLength = int64(len(body))
+	request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return warnings, nil
+}
+
+// isImagePolicyViolation returns whether an image is rejected by the image trust policy or the image age policy
+func isImagePolicyViolation(err error) bool {
+	switch err.(type) {
+	case *imagetrust.VerificationError, *imagetrust.AgeError:
+		return true
+	}
+	return false
+}
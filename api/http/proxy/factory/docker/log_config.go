@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logdriver"
+)
+
+// verifyContainerLogConfig validates the logging driver of a container creation request.
+// Containers using the default logging driver of the Docker daemon are not verified.
+func (transport *Transport) verifyContainerLogConfig(request *http.Request) error {
+	var partialContainer struct {
+		HostConfig struct {
+			LogConfig struct {
+				Type   string
+				Config map[string]string
+			}
+		}
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return err
+	}
+	request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+	err = json.Unmarshal(body, &partialContainer)
+	if err != nil {
+		return err
+	}
+
+	if partialContainer.HostConfig.LogConfig.Type == "" {
+		return nil
+	}
+
+	config := &portainer.LogConfig{
+		Driver:  partialContainer.HostConfig.LogConfig.Type,
+		Options: partialContainer.HostConfig.LogConfig.Config,
+	}
+
+	return logdriver.Validate(config)
+}
@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// injectContainerNetworkDefaults updates the body of a container creation request to use the
+// network defaults of the endpoint (DNS servers, DNS search domains and extra hosts)
+// for each of these settings that is not explicitly specified in the request.
+func (transport *Transport) injectContainerNetworkDefaults(request *http.Request) error {
+	endpoint, err := transport.dataStore.Endpoint().Endpoint(transport.endpoint.ID)
+	if err != nil {
+		return err
+	}
+
+	defaults := endpoint.NetworkDefaults
+	if len(defaults.DNS) == 0 && len(defaults.DNSSearch) == 0 && len(defaults.ExtraHosts) == 0 {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return err
+	}
+
+	body, err = applyContainerNetworkDefaults(body, &defaults)
+	if err != nil {
+		return err
+	}
+
+	request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+	request.ContentLength = int64(len(body))
+	return nil
+}
+
+func applyContainerNetworkDefaults(body []byte, defaults *portainer.EndpointNetworkDefaults) ([]byte, error) {
+	var containerConfig map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err := decoder.Decode(&containerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	hostConfig, ok := containerConfig["HostConfig"].(map[string]interface{})
+	if !ok {
+		hostConfig = map[string]interface{}{}
+		containerConfig["HostConfig"] = hostConfig
+	}
+
+	// DNS settings and extra hosts cannot be used when sharing the network stack of the host or of another container
+	networkMode, _ := hostConfig["NetworkMode"].(string)
+	if networkMode == "host" || strings.HasPrefix(networkMode, "container:") {
+		return body, nil
+	}
+
+	setDefaultList(hostConfig, "Dns", defaults.DNS)
+	setDefaultList(hostConfig, "DnsSearch", defaults.DNSSearch)
+	setDefaultList(hostConfig, "ExtraHosts", defaults.ExtraHosts)
+
+	return json.Marshal(containerConfig)
+}
+
+func setDefaultList(object map[string]interface{}, key string, defaultValues []string) {
+	if len(defaultValues) == 0 {
+		return
+	}
+
+	if values, ok := object[key].([]interface{}); ok && len(values) > 0 {
+		return
+	}
+
+	object[key] = defaultValues
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/docker/docker/client"
 
 	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
 	"github.com/portainer/portainer/api/http/proxy/factory/responseutils"
 	"github.com/portainer/portainer/api/internal/authorization"
 )
@@ -90,6 +91,74 @@ func selectorServiceLabels(responseObject map[string]interface{}) map[string]int
 	return nil
 }
 
+// serviceDeletionOperation removes a service alongside its resource control and webhook, if any
+func (transport *Transport) serviceDeletionOperation(request *http.Request, serviceID string) (*http.Response, error) {
+	response, err := transport.executeGenericResourceDeletionOperation(request, serviceID, portainer.ServiceResourceControl)
+	if err != nil {
+		return response, err
+	}
+
+	if response.StatusCode == http.StatusNoContent || response.StatusCode == http.StatusOK {
+		webhook, err := transport.dataStore.Webhook().WebhookByResourceID(serviceID)
+		if err == bolterrors.ErrObjectNotFound {
+			return response, nil
+		} else if err != nil {
+			return response, err
+		}
+
+		err = transport.dataStore.Webhook().DeleteWebhook(webhook.ID)
+		if err != nil {
+			return response, err
+		}
+	}
+
+	return response, nil
+}
+
+// decorateServiceUpdateOperation verifies the image of a service update request against the image trust policy and
+// the image age policy when the image of the service is changed
+func (transport *Transport) decorateServiceUpdateOperation(request *http.Request, serviceID string) (*http.Response, error) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+	var partialService struct {
+		TaskTemplate struct {
+			ContainerSpec struct {
+				Image string
+			}
+		}
+	}
+	err = json.Unmarshal(body, &partialService)
+	if err != nil {
+		return nil, err
+	}
+
+	// the image is verified when the current image of the service cannot be retrieved
+	service, _, err := transport.dockerClient.ServiceInspectWithRaw(context.Background(), serviceID, types.ServiceInspectOptions{})
+	imageChanged := err != nil || service.Spec.TaskTemplate.ContainerSpec == nil ||
+		partialService.TaskTemplate.ContainerSpec.Image != service.Spec.TaskTemplate.ContainerSpec.Image
+
+	var imageWarnings []string
+	if imageChanged {
+		imageWarnings, err = transport.verifyServiceImage(request)
+		if isImagePolicyViolation(err) {
+			return &http.Response{StatusCode: http.StatusForbidden}, err
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := transport.restrictedResourceOperation(request, serviceID, portainer.ServiceResourceControl, false)
+	if err != nil || response.StatusCode != http.StatusOK {
+		return response, err
+	}
+
+	return response, appendResponseWarnings(response, imageWarnings)
+}
+
 func (transport *Transport) decorateServiceCreationOperation(request *http.Request) (*http.Response, error) {
 	type PartialService struct {
 		TaskTemplate struct {
@@ -138,5 +207,22 @@ func (transport *Transport) decorateServiceCreationOperation(request *http.Reque
 		request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 	}
 
-	return transport.replaceRegistryAuthenticationHeader(request)
+	imageWarnings, err := transport.verifyServiceImage(request)
+	if isImagePolicyViolation(err) {
+		return forbiddenResponse, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = transport.injectServiceDefaults(request)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := transport.replaceRegistryAuthenticationHeader(request)
+	if err != nil || response.StatusCode != http.StatusCreated {
+		return response, err
+	}
+
+	return response, appendResponseWarnings(response, imageWarnings)
 }
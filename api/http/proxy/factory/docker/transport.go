@@ -16,6 +16,7 @@ import (
 	"github.com/portainer/portainer/api/http/proxy/factory/responseutils"
 	"github.com/portainer/portainer/api/http/security"
 	"github.com/portainer/portainer/api/internal/authorization"
+	"github.com/portainer/portainer/api/internal/imagetrust"
 )
 
 var apiVersionRe = regexp.MustCompile(`(/v[0-9]\.[0-9]*)?`)
@@ -31,6 +32,7 @@ type (
 		reverseTunnelService portainer.ReverseTunnelService
 		dockerClient         *client.Client
 		dockerClientFactory  *docker.ClientFactory
+		imageVerifier        *imagetrust.Verifier
 	}
 
 	// TransportParameters is used to create a new Transport
@@ -40,6 +42,7 @@ type (
 		SignatureService     portainer.DigitalSignatureService
 		ReverseTunnelService portainer.ReverseTunnelService
 		DockerClientFactory  *docker.ClientFactory
+		ImageVerifier        *imagetrust.Verifier
 	}
 
 	restrictedDockerOperationContext struct {
@@ -70,6 +73,7 @@ func NewTransport(parameters *TransportParameters, httpTransport *http.Transport
 		signatureService:     parameters.SignatureService,
 		reverseTunnelService: parameters.ReverseTunnelService,
 		dockerClientFactory:  parameters.DockerClientFactory,
+		imageVerifier:        parameters.ImageVerifier,
 		HTTPTransport:        httpTransport,
 		dockerClient:         dockerClient,
 	}
@@ -239,6 +243,9 @@ func (transport *Transport) proxyServiceRequest(request *http.Request) (*http.Re
 		if match, _ := path.Match("/services/*/*", requestPath); match {
 			// Handle /services/{id}/{action} requests
 			serviceID := path.Base(path.Dir(requestPath))
+			if path.Base(requestPath) == "update" && request.Method == http.MethodPost {
+				return transport.decorateServiceUpdateOperation(request, serviceID)
+			}
 			return transport.restrictedResourceOperation(request, serviceID, portainer.ServiceResourceControl, false)
 		} else if match, _ := path.Match("/services/*", requestPath); match {
 			// Handle /services/{id} requests
@@ -248,7 +255,7 @@ func (transport *Transport) proxyServiceRequest(request *http.Request) (*http.Re
 			case http.MethodGet:
 				return transport.rewriteOperation(request, transport.serviceInspectOperation)
 			case http.MethodDelete:
-				return transport.executeGenericResourceDeletionOperation(request, serviceID, portainer.ServiceResourceControl)
+				return transport.serviceDeletionOperation(request, serviceID)
 			}
 			return transport.restrictedResourceOperation(request, serviceID, portainer.ServiceResourceControl, false)
 		}
@@ -11,6 +11,7 @@ import (
 	"github.com/portainer/portainer/api/kubernetes/cli"
 
 	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/imagetrust"
 )
 
 const azureAPIBaseURL = "https://management.azure.com"
@@ -24,11 +25,12 @@ type (
 		dockerClientFactory         *docker.ClientFactory
 		kubernetesClientFactory     *cli.ClientFactory
 		kubernetesTokenCacheManager *kubernetes.TokenCacheManager
+		imageVerifier               *imagetrust.Verifier
 	}
 )
 
 // NewProxyFactory returns a pointer to a new instance of a ProxyFactory
-func NewProxyFactory(dataStore portainer.DataStore, signatureService portainer.DigitalSignatureService, tunnelService portainer.ReverseTunnelService, clientFactory *docker.ClientFactory, kubernetesClientFactory *cli.ClientFactory, kubernetesTokenCacheManager *kubernetes.TokenCacheManager) *ProxyFactory {
+func NewProxyFactory(dataStore portainer.DataStore, signatureService portainer.DigitalSignatureService, tunnelService portainer.ReverseTunnelService, clientFactory *docker.ClientFactory, kubernetesClientFactory *cli.ClientFactory, kubernetesTokenCacheManager *kubernetes.TokenCacheManager, imageVerifier *imagetrust.Verifier) *ProxyFactory {
 	return &ProxyFactory{
 		dataStore:                   dataStore,
 		signatureService:            signatureService,
@@ -36,6 +38,7 @@ func NewProxyFactory(dataStore portainer.DataStore, signatureService portainer.D
 		dockerClientFactory:         clientFactory,
 		kubernetesClientFactory:     kubernetesClientFactory,
 		kubernetesTokenCacheManager: kubernetesTokenCacheManager,
+		imageVerifier:               imageVerifier,
 	}
 }
 
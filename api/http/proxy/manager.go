@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/docker/docker/client"
 	"github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
 
 	cmap "github.com/orcaman/concurrent-map"
@@ -12,6 +13,7 @@ import (
 	portainer "github.com/portainer/portainer/api"
 	"github.com/portainer/portainer/api/docker"
 	"github.com/portainer/portainer/api/http/proxy/factory"
+	"github.com/portainer/portainer/api/internal/imagetrust"
 )
 
 // TODO: contain code related to legacy extension management
@@ -27,12 +29,12 @@ type (
 )
 
 // NewManager initializes a new proxy Service
-func NewManager(dataStore portainer.DataStore, signatureService portainer.DigitalSignatureService, tunnelService portainer.ReverseTunnelService, clientFactory *docker.ClientFactory, kubernetesClientFactory *cli.ClientFactory, kubernetesTokenCacheManager *kubernetes.TokenCacheManager) *Manager {
+func NewManager(dataStore portainer.DataStore, signatureService portainer.DigitalSignatureService, tunnelService portainer.ReverseTunnelService, clientFactory *docker.ClientFactory, kubernetesClientFactory *cli.ClientFactory, kubernetesTokenCacheManager *kubernetes.TokenCacheManager, imageVerifier *imagetrust.Verifier) *Manager {
 	return &Manager{
 		endpointProxies:        cmap.New(),
 		legacyExtensionProxies: cmap.New(),
 		k8sClientFactory:       kubernetesClientFactory,
-		proxyFactory:           factory.NewProxyFactory(dataStore, signatureService, tunnelService, clientFactory, kubernetesClientFactory, kubernetesTokenCacheManager),
+		proxyFactory:           factory.NewProxyFactory(dataStore, signatureService, tunnelService, clientFactory, kubernetesClientFactory, kubernetesTokenCacheManager, imageVerifier),
 	}
 }
 
@@ -64,6 +66,22 @@ func (manager *Manager) GetEndpointProxy(endpoint *portainer.Endpoint) http.Hand
 	return proxy.(http.Handler)
 }
 
+// CreateEndpointDockerClient returns a Docker client sending its requests through the proxy of the endpoint.
+// The requests must be sent with the context of a user request: they are subject to the same access control,
+// security settings and policies as the requests sent by the user to the endpoint.
+func (manager *Manager) CreateEndpointDockerClient(endpoint *portainer.Endpoint) (*client.Client, error) {
+	proxy := manager.GetEndpointProxy(endpoint)
+	if proxy == nil {
+		var err error
+		proxy, err = manager.CreateAndRegisterEndpointProxy(endpoint)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return docker.CreateHandlerClient(proxy)
+}
+
 // DeleteEndpointProxy deletes the proxy associated to a key
 // and cleans the k8s endpoint client cache. DeleteEndpointProxy
 // is currently only called for edge connection clean up.
@@ -4,6 +4,31 @@ import (
 	"github.com/portainer/portainer/api"
 )
 
+// Types of the access paths to an endpoint
+const (
+	EndpointAccessAdministrator = "administrator"
+	EndpointAccessUserPolicy    = "user"
+	EndpointAccessTeamPolicy    = "team"
+)
+
+// Resources holding the access policies of an endpoint
+const (
+	EndpointAccessEndpointPolicy = "endpoint"
+	EndpointAccessGroupPolicy    = "endpoint_group"
+)
+
+// EndpointAccessPath represents an access policy granting a user access to an endpoint
+type EndpointAccessPath struct {
+	// Type of access. Valid values are: administrator, user (policy of the user) or team (policy of a team of the user)
+	Type string `json:"Type" example:"team"`
+	// Resource holding the policy, empty for administrators. Valid values are: endpoint or endpoint_group
+	Source string `json:"Source,omitempty" example:"endpoint_group"`
+	// Identifier of the team holding the policy
+	TeamID portainer.TeamID `json:"TeamId,omitempty" example:"2"`
+	// Identifier of the role associated to the policy
+	RoleID portainer.RoleID `json:"RoleId,omitempty" example:"1"`
+}
+
 // AuthorizedResourceControlAccess checks whether the user can alter an existing resource control.
 func AuthorizedResourceControlAccess(resourceControl *portainer.ResourceControl, context *RestrictedRequestContext) bool {
 	if context.IsAdmin || resourceControl.Public {
@@ -95,11 +120,44 @@ func AuthorizedTeamManagement(teamID portainer.TeamID, context *RestrictedReques
 // It will check if the user is part of the authorized users or part of a team that is
 // listed in the authorized teams of the endpoint and the associated group.
 func authorizedEndpointAccess(endpoint *portainer.Endpoint, endpointGroup *portainer.EndpointGroup, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
-	groupAccess := authorizedAccess(userID, memberships, endpointGroup.UserAccessPolicies, endpointGroup.TeamAccessPolicies)
-	if !groupAccess {
-		return authorizedAccess(userID, memberships, endpoint.UserAccessPolicies, endpoint.TeamAccessPolicies)
+	return len(endpointAccessPaths(endpoint, endpointGroup, userID, memberships)) > 0
+}
+
+// EndpointAccessPaths returns the access policies granting the user access to the specified endpoint,
+// directly or through a team, on the endpoint or on its group. Administrators have access to all the endpoints.
+// An empty list means the user cannot access the endpoint.
+func EndpointAccessPaths(endpoint *portainer.Endpoint, endpointGroup *portainer.EndpointGroup, user *portainer.User, memberships []portainer.TeamMembership) []EndpointAccessPath {
+	if user.Role == portainer.AdministratorRole {
+		return []EndpointAccessPath{{Type: EndpointAccessAdministrator}}
+	}
+	return endpointAccessPaths(endpoint, endpointGroup, user.ID, memberships)
+}
+
+func endpointAccessPaths(endpoint *portainer.Endpoint, endpointGroup *portainer.EndpointGroup, userID portainer.UserID, memberships []portainer.TeamMembership) []EndpointAccessPath {
+	paths := make([]EndpointAccessPath, 0)
+
+	if endpointGroup != nil {
+		paths = append(paths, accessPaths(EndpointAccessGroupPolicy, userID, memberships, endpointGroup.UserAccessPolicies, endpointGroup.TeamAccessPolicies)...)
+	}
+
+	return append(paths, accessPaths(EndpointAccessEndpointPolicy, userID, memberships, endpoint.UserAccessPolicies, endpoint.TeamAccessPolicies)...)
+}
+
+// accessPaths returns the policies of the user and of the teams of the user
+func accessPaths(source string, userID portainer.UserID, memberships []portainer.TeamMembership, userAccessPolicies portainer.UserAccessPolicies, teamAccessPolicies portainer.TeamAccessPolicies) []EndpointAccessPath {
+	paths := make([]EndpointAccessPath, 0)
+
+	if policy, ok := userAccessPolicies[userID]; ok {
+		paths = append(paths, EndpointAccessPath{Type: EndpointAccessUserPolicy, Source: source, RoleID: policy.RoleID})
 	}
-	return true
+
+	for _, membership := range memberships {
+		if policy, ok := teamAccessPolicies[membership.TeamID]; ok {
+			paths = append(paths, EndpointAccessPath{Type: EndpointAccessTeamPolicy, Source: source, TeamID: membership.TeamID, RoleID: policy.RoleID})
+		}
+	}
+
+	return paths
 }
 
 // authorizedEndpointGroupAccess ensure that the user can access the specified endpoint group.
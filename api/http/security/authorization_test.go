@@ -0,0 +1,33 @@
+package security
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EndpointAccessPaths(t *testing.T) {
+	endpoint := &portainer.Endpoint{
+		UserAccessPolicies: portainer.UserAccessPolicies{2: {RoleID: 3}},
+		TeamAccessPolicies: portainer.TeamAccessPolicies{},
+	}
+	group := &portainer.EndpointGroup{
+		UserAccessPolicies: portainer.UserAccessPolicies{},
+		TeamAccessPolicies: portainer.TeamAccessPolicies{7: {RoleID: 1}},
+	}
+	memberships := []portainer.TeamMembership{{UserID: 2, TeamID: 7}}
+
+	user := &portainer.User{ID: 2, Role: portainer.StandardUserRole}
+	assert.Equal(t, []EndpointAccessPath{
+		{Type: EndpointAccessTeamPolicy, Source: EndpointAccessGroupPolicy, TeamID: 7, RoleID: 1},
+		{Type: EndpointAccessUserPolicy, Source: EndpointAccessEndpointPolicy, RoleID: 3},
+	}, EndpointAccessPaths(endpoint, group, user, memberships))
+
+	other := &portainer.User{ID: 3, Role: portainer.StandardUserRole}
+	assert.Empty(t, EndpointAccessPaths(endpoint, group, other, nil))
+	assert.False(t, authorizedEndpointAccess(endpoint, group, other.ID, nil))
+
+	admin := &portainer.User{ID: 1, Role: portainer.AdministratorRole}
+	assert.Equal(t, []EndpointAccessPath{{Type: EndpointAccessAdministrator}}, EndpointAccessPaths(endpoint, group, admin, nil))
+}
@@ -0,0 +1,96 @@
+package security
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers used to expose the state of a rate limit to the clients, using the same format as the DockerHub registry
+const (
+	RateLimitLimitHeader     = "RateLimit-Limit"
+	RateLimitRemainingHeader = "RateLimit-Remaining"
+	RateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// RateLimit represents the state of a rate limit as exposed in the RateLimit headers
+type RateLimit struct {
+	// Maximum number of requests allowed by the limit
+	Limit int
+	// Number of requests still allowed before the limit is reached
+	Remaining int
+	// Duration until the limit is reset
+	Reset time.Duration
+	// Duration of the window of the limit, read from the policy of the limit, e.g. "100;w=21600".
+	// It is not written in the headers.
+	Window time.Duration
+}
+
+// WriteRateLimitHeaders adds the RateLimit headers describing the rate limit to the response.
+// The reset duration is rounded up to the second.
+func WriteRateLimitHeaders(w http.ResponseWriter, rateLimit RateLimit) {
+	reset := int(math.Ceil(rateLimit.Reset.Seconds()))
+	if reset < 0 {
+		reset = 0
+	}
+
+	w.Header().Set(RateLimitLimitHeader, strconv.Itoa(rateLimit.Limit))
+	w.Header().Set(RateLimitRemainingHeader, strconv.Itoa(rateLimit.Remaining))
+	w.Header().Set(RateLimitResetHeader, strconv.Itoa(reset))
+}
+
+// ParseRateLimitHeaders reads the RateLimit headers of a response. It returns false when the response
+// does not define a limit and remaining count.
+func ParseRateLimitHeaders(header http.Header) (RateLimit, bool) {
+	limit, ok := parseNumericHeader(header, RateLimitLimitHeader)
+	if !ok {
+		return RateLimit{}, false
+	}
+
+	remaining, ok := parseNumericHeader(header, RateLimitRemainingHeader)
+	if !ok {
+		return RateLimit{}, false
+	}
+
+	rateLimit := RateLimit{Limit: limit, Remaining: remaining, Window: parseWindow(header.Get(RateLimitLimitHeader))}
+	if reset, ok := parseNumericHeader(header, RateLimitResetHeader); ok {
+		rateLimit.Reset = time.Duration(reset) * time.Second
+	}
+
+	return rateLimit, true
+}
+
+// parseNumericHeader returns the numeric value of a header. The policy of the limit following the value,
+// e.g. the window in "100;w=21600", is ignored.
+func parseNumericHeader(header http.Header, key string) (int, bool) {
+	value := header.Get(key)
+	if idx := strings.Index(value, ";"); idx != -1 {
+		value = value[:idx]
+	}
+
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || number < 0 {
+		return 0, false
+	}
+
+	return number, true
+}
+
+// parseWindow returns the window of the policy following the value of a header, 0 when it is not specified
+func parseWindow(value string) time.Duration {
+	for _, parameter := range strings.Split(value, ";")[1:] {
+		parameter = strings.TrimSpace(parameter)
+		if !strings.HasPrefix(parameter, "w=") {
+			continue
+		}
+
+		window, err := strconv.Atoi(strings.TrimPrefix(parameter, "w="))
+		if err == nil && window > 0 {
+			return time.Duration(window) * time.Second
+		}
+	}
+
+	return 0
+}
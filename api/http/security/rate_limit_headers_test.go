@@ -0,0 +1,55 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WriteRateLimitHeaders(t *testing.T) {
+	rr := httptest.NewRecorder()
+	WriteRateLimitHeaders(rr, RateLimit{Limit: 10, Reset: 1500 * time.Millisecond})
+
+	assert.Equal(t, "10", rr.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "0", rr.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "2", rr.Header().Get(RateLimitResetHeader), "the reset is rounded up to the second")
+
+	rateLimit, ok := ParseRateLimitHeaders(rr.Header())
+	assert.True(t, ok)
+	assert.Equal(t, RateLimit{Limit: 10, Reset: 2 * time.Second}, rateLimit)
+}
+
+func Test_ParseRateLimitHeaders(t *testing.T) {
+	header := http.Header{}
+	_, ok := ParseRateLimitHeaders(header)
+	assert.False(t, ok)
+
+	header.Set(RateLimitLimitHeader, "100;w=21600")
+	header.Set(RateLimitRemainingHeader, "76;w=21600")
+	rateLimit, ok := ParseRateLimitHeaders(header)
+	assert.True(t, ok)
+	assert.Equal(t, RateLimit{Limit: 100, Remaining: 76, Window: 6 * time.Hour}, rateLimit)
+
+	header.Set(RateLimitRemainingHeader, "none")
+	_, ok = ParseRateLimitHeaders(header)
+	assert.False(t, ok)
+}
+
+func Test_LimitAccess_shouldWriteRateLimitHeaders(t *testing.T) {
+	rateLimiter := NewRateLimiter(1, time.Second, time.Hour)
+	handler := rateLimiter.LimitAccess(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, rr.Header().Get(RateLimitLimitHeader))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "0", rr.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "3600", rr.Header().Get(RateLimitResetHeader))
+}
@@ -30,6 +30,7 @@ func (limiter *RateLimiter) LimitAccess(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ip := StripAddrPort(r.RemoteAddr)
 		if banned := limiter.Inc(ip); banned == true {
+			WriteRateLimitHeaders(w, limiter.rateLimit(ip))
 			httperror.WriteError(w, http.StatusForbidden, "Access denied", errors.ErrResourceAccessDenied)
 			return
 		}
@@ -37,6 +38,15 @@ func (limiter *RateLimiter) LimitAccess(next http.Handler) http.Handler {
 	})
 }
 
+// rateLimit returns the state of the limit of a banned client, the limit is reset when the ban expires
+func (limiter *RateLimiter) rateLimit(key string) RateLimit {
+	rateLimit := RateLimit{Limit: limiter.Max}
+	if client, ok := limiter.Client(key); ok {
+		rateLimit.Reset = time.Until(client.Expire())
+	}
+	return rateLimit
+}
+
 // StripAddrPort removes port from IP address
 func StripAddrPort(addr string) string {
 	portIndex := strings.LastIndex(addr, ":")
@@ -10,6 +10,7 @@ import (
 	"github.com/portainer/portainer/api/docker"
 	"github.com/portainer/portainer/api/http/handler"
 	"github.com/portainer/portainer/api/http/handler/auth"
+	"github.com/portainer/portainer/api/http/handler/containerjobs"
 	"github.com/portainer/portainer/api/http/handler/customtemplates"
 	"github.com/portainer/portainer/api/http/handler/dockerhub"
 	"github.com/portainer/portainer/api/http/handler/edgegroups"
@@ -21,24 +22,44 @@ import (
 	"github.com/portainer/portainer/api/http/handler/endpointproxy"
 	"github.com/portainer/portainer/api/http/handler/endpoints"
 	"github.com/portainer/portainer/api/http/handler/file"
+	"github.com/portainer/portainer/api/http/handler/metrics"
 	"github.com/portainer/portainer/api/http/handler/motd"
 	"github.com/portainer/portainer/api/http/handler/registries"
 	"github.com/portainer/portainer/api/http/handler/resourcecontrols"
 	"github.com/portainer/portainer/api/http/handler/roles"
+	"github.com/portainer/portainer/api/http/handler/secrets"
 	"github.com/portainer/portainer/api/http/handler/settings"
 	"github.com/portainer/portainer/api/http/handler/stacks"
 	"github.com/portainer/portainer/api/http/handler/status"
+	"github.com/portainer/portainer/api/http/handler/system"
 	"github.com/portainer/portainer/api/http/handler/tags"
 	"github.com/portainer/portainer/api/http/handler/teammemberships"
 	"github.com/portainer/portainer/api/http/handler/teams"
 	"github.com/portainer/portainer/api/http/handler/templates"
 	"github.com/portainer/portainer/api/http/handler/upload"
 	"github.com/portainer/portainer/api/http/handler/users"
+	"github.com/portainer/portainer/api/http/handler/volumebackups"
 	"github.com/portainer/portainer/api/http/handler/webhooks"
 	"github.com/portainer/portainer/api/http/handler/websocket"
 	"github.com/portainer/portainer/api/http/proxy"
 	"github.com/portainer/portainer/api/http/proxy/factory/kubernetes"
 	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/concurrency"
+	"github.com/portainer/portainer/api/internal/containercleanup"
+	"github.com/portainer/portainer/api/internal/containerjob"
+	"github.com/portainer/portainer/api/internal/crashloop"
+	"github.com/portainer/portainer/api/internal/dockerhublimit"
+	"github.com/portainer/portainer/api/internal/imagetrust"
+	"github.com/portainer/portainer/api/internal/jsonlimit"
+	"github.com/portainer/portainer/api/internal/logs"
+	"github.com/portainer/portainer/api/internal/mailer"
+	"github.com/portainer/portainer/api/internal/scheduler"
+	"github.com/portainer/portainer/api/internal/secret"
+	"github.com/portainer/portainer/api/internal/stackdeploy"
+	"github.com/portainer/portainer/api/internal/stackdrift"
+	"github.com/portainer/portainer/api/internal/stackmonitor"
+	"github.com/portainer/portainer/api/internal/streams"
+	"github.com/portainer/portainer/api/internal/volumebackup"
 
 	"github.com/portainer/portainer/api/kubernetes/cli"
 )
@@ -69,6 +90,21 @@ type Server struct {
 	DockerClientFactory         *docker.ClientFactory
 	KubernetesClientFactory     *cli.ClientFactory
 	KubernetesDeployer          portainer.KubernetesDeployer
+	LogBuffer                   *logs.Buffer
+	ImageVerifier               *imagetrust.Verifier
+	Scheduler                   *scheduler.Scheduler
+	Mailer                      *mailer.Service
+	ContainerJobService         *containerjob.Service
+	CrashLoopService            *crashloop.Service
+	ContainerCleanupService     *containercleanup.Service
+	SecretService               *secret.Service
+	VolumeBackupService         *volumebackup.Service
+	StackDeployService          *stackdeploy.Service
+	StackDriftService           *stackdrift.Service
+	StackMonitorService         *stackmonitor.Service
+	DockerHubRateLimitService   *dockerhublimit.Service
+	Flags                       *portainer.CLIFlags
+	JSONLimits                  jsonlimit.Limits
 }
 
 // Start starts the HTTP server
@@ -91,6 +127,11 @@ func (server *Server) Start() error {
 	var roleHandler = roles.NewHandler(requestBouncer)
 	roleHandler.DataStore = server.DataStore
 
+	var containerJobsHandler = containerjobs.NewHandler(requestBouncer)
+	containerJobsHandler.DataStore = server.DataStore
+	containerJobsHandler.ContainerJobService = server.ContainerJobService
+	containerJobsHandler.ProxyManager = server.ProxyManager
+
 	var customTemplatesHandler = customtemplates.NewHandler(requestBouncer)
 	customTemplatesHandler.DataStore = server.DataStore
 	customTemplatesHandler.FileService = server.FileService
@@ -98,6 +139,7 @@ func (server *Server) Start() error {
 
 	var dockerHubHandler = dockerhub.NewHandler(requestBouncer)
 	dockerHubHandler.DataStore = server.DataStore
+	dockerHubHandler.RateLimitService = server.DockerHubRateLimitService
 
 	var edgeGroupsHandler = edgegroups.NewHandler(requestBouncer)
 	edgeGroupsHandler.DataStore = server.DataStore
@@ -115,13 +157,21 @@ func (server *Server) Start() error {
 	var edgeTemplatesHandler = edgetemplates.NewHandler(requestBouncer)
 	edgeTemplatesHandler.DataStore = server.DataStore
 
+	concurrencyLimiter := concurrency.NewLimiter()
+
 	var endpointHandler = endpoints.NewHandler(requestBouncer)
 	endpointHandler.DataStore = server.DataStore
+	endpointHandler.DockerClientFactory = server.DockerClientFactory
 	endpointHandler.FileService = server.FileService
 	endpointHandler.ProxyManager = server.ProxyManager
 	endpointHandler.SnapshotService = server.SnapshotService
 	endpointHandler.ReverseTunnelService = server.ReverseTunnelService
 	endpointHandler.ComposeStackManager = server.ComposeStackManager
+	endpointHandler.KubernetesClientFactory = server.KubernetesClientFactory
+	endpointHandler.ConcurrencyLimiter = concurrencyLimiter
+	endpointHandler.CrashLoopService = server.CrashLoopService
+	endpointHandler.ContainerCleanupService = server.ContainerCleanupService
+	endpointHandler.SecretService = server.SecretService
 
 	var endpointEdgeHandler = endpointedge.NewHandler(requestBouncer)
 	endpointEdgeHandler.DataStore = server.DataStore
@@ -135,9 +185,14 @@ func (server *Server) Start() error {
 	endpointProxyHandler.DataStore = server.DataStore
 	endpointProxyHandler.ProxyManager = server.ProxyManager
 	endpointProxyHandler.ReverseTunnelService = server.ReverseTunnelService
+	endpointProxyHandler.StreamLimiter = streams.NewLimiter()
+	endpointProxyHandler.ConcurrencyLimiter = concurrencyLimiter
 
 	var fileHandler = file.NewHandler(filepath.Join(server.AssetsPath, "public"))
 
+	var metricsHandler = metrics.NewHandler(requestBouncer)
+	metricsHandler.DataStore = server.DataStore
+
 	var motdHandler = motd.NewHandler(requestBouncer)
 
 	var registryHandler = registries.NewHandler(requestBouncer)
@@ -148,12 +203,18 @@ func (server *Server) Start() error {
 	var resourceControlHandler = resourcecontrols.NewHandler(requestBouncer)
 	resourceControlHandler.DataStore = server.DataStore
 
+	var secretHandler = secrets.NewHandler(requestBouncer)
+	secretHandler.DataStore = server.DataStore
+	secretHandler.SecretService = server.SecretService
+
 	var settingsHandler = settings.NewHandler(requestBouncer)
 	settingsHandler.DataStore = server.DataStore
 	settingsHandler.FileService = server.FileService
 	settingsHandler.JWTService = server.JWTService
 	settingsHandler.LDAPService = server.LDAPService
 	settingsHandler.SnapshotService = server.SnapshotService
+	settingsHandler.Mailer = server.Mailer
+	settingsHandler.Flags = server.Flags
 
 	var stackHandler = stacks.NewHandler(requestBouncer)
 	stackHandler.DataStore = server.DataStore
@@ -163,6 +224,11 @@ func (server *Server) Start() error {
 	stackHandler.ComposeStackManager = server.ComposeStackManager
 	stackHandler.KubernetesDeployer = server.KubernetesDeployer
 	stackHandler.GitService = server.GitService
+	stackHandler.ImageVerifier = server.ImageVerifier
+	stackHandler.SecretService = server.SecretService
+	stackHandler.DeployService = server.StackDeployService
+	stackHandler.DriftService = server.StackDriftService
+	stackHandler.MonitorService = server.StackMonitorService
 
 	var tagHandler = tags.NewHandler(requestBouncer)
 	tagHandler.DataStore = server.DataStore
@@ -174,6 +240,15 @@ func (server *Server) Start() error {
 	teamMembershipHandler.DataStore = server.DataStore
 
 	var statusHandler = status.NewHandler(requestBouncer, server.Status)
+	statusHandler.DataStore = server.DataStore
+
+	var systemHandler = system.NewHandler(requestBouncer)
+	systemHandler.DataStore = server.DataStore
+	systemHandler.FileService = server.FileService
+	systemHandler.ReverseTunnelService = server.ReverseTunnelService
+	systemHandler.LogBuffer = server.LogBuffer
+	systemHandler.Scheduler = server.Scheduler
+	systemHandler.DockerClientFactory = server.DockerClientFactory
 
 	var templatesHandler = templates.NewHandler(requestBouncer)
 	templatesHandler.DataStore = server.DataStore
@@ -193,6 +268,11 @@ func (server *Server) Start() error {
 	websocketHandler.ReverseTunnelService = server.ReverseTunnelService
 	websocketHandler.KubernetesClientFactory = server.KubernetesClientFactory
 
+	var volumeBackupHandler = volumebackups.NewHandler(requestBouncer)
+	volumeBackupHandler.DataStore = server.DataStore
+	volumeBackupHandler.DockerClientFactory = server.DockerClientFactory
+	volumeBackupHandler.VolumeBackupService = server.VolumeBackupService
+
 	var webhookHandler = webhooks.NewHandler(requestBouncer)
 	webhookHandler.DataStore = server.DataStore
 	webhookHandler.DockerClientFactory = server.DockerClientFactory
@@ -200,6 +280,7 @@ func (server *Server) Start() error {
 	server.Handler = &handler.Handler{
 		RoleHandler:            roleHandler,
 		AuthHandler:            authHandler,
+		ContainerJobsHandler:   containerJobsHandler,
 		CustomTemplatesHandler: customTemplatesHandler,
 		DockerHubHandler:       dockerHubHandler,
 		EdgeGroupsHandler:      edgeGroupsHandler,
@@ -211,25 +292,29 @@ func (server *Server) Start() error {
 		EndpointEdgeHandler:    endpointEdgeHandler,
 		EndpointProxyHandler:   endpointProxyHandler,
 		FileHandler:            fileHandler,
+		MetricsHandler:         metricsHandler,
 		MOTDHandler:            motdHandler,
 		RegistryHandler:        registryHandler,
 		ResourceControlHandler: resourceControlHandler,
+		SecretHandler:          secretHandler,
 		SettingsHandler:        settingsHandler,
 		StatusHandler:          statusHandler,
 		StackHandler:           stackHandler,
+		SystemHandler:          systemHandler,
 		TagHandler:             tagHandler,
 		TeamHandler:            teamHandler,
 		TeamMembershipHandler:  teamMembershipHandler,
 		TemplatesHandler:       templatesHandler,
 		UploadHandler:          uploadHandler,
 		UserHandler:            userHandler,
+		VolumeBackupHandler:    volumeBackupHandler,
 		WebSocketHandler:       websocketHandler,
 		WebhookHandler:         webhookHandler,
 	}
 
 	httpServer := &http.Server{
 		Addr:    server.BindAddress,
-		Handler: server.Handler,
+		Handler: degradedModeHandler(jsonLimitsHandler(server.Handler, server.JSONLimits), server.DataStore),
 	}
 
 	if server.SSL {
@@ -0,0 +1,126 @@
+package clockskew
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// DefaultThreshold is the maximum difference tolerated between the system time of an endpoint and the Portainer host clock
+// when no threshold is defined on the endpoint
+const DefaultThreshold = 30 * time.Second
+
+var errInvalidThreshold = errors.New("Invalid clock skew threshold. Must be a valid positive duration")
+
+type (
+	// Summary represents the endpoints whose system time differs from the Portainer host clock by more than their threshold
+	Summary struct {
+		// Number of endpoints whose clock skew was measured
+		Checked int `json:"Checked" example:"12"`
+		// Endpoints exceeding their threshold, the largest skew first
+		Offenders []Offender `json:"Offenders"`
+	}
+
+	// Offender represents an endpoint whose clock skew exceeds its threshold
+	Offender struct {
+		EndpointID   portainer.EndpointID `json:"EndpointId" example:"1"`
+		EndpointName string               `json:"EndpointName" example:"my-endpoint"`
+		// Difference in milliseconds, positive when the clock of the endpoint is ahead
+		Skew      int64  `json:"Skew" example:"95000"`
+		Threshold string `json:"Threshold" example:"30s"`
+		// Date of the measure in unix time
+		CheckDate int64 `json:"CheckDate" example:"1640995200"`
+	}
+)
+
+// ValidateThreshold verifies that a threshold is a valid positive duration, an empty threshold is allowed
+func ValidateThreshold(threshold string) error {
+	if threshold == "" {
+		return nil
+	}
+
+	duration, err := time.ParseDuration(threshold)
+	if err != nil || duration <= 0 {
+		return errInvalidThreshold
+	}
+
+	return nil
+}
+
+// Threshold returns the clock skew threshold of the endpoint
+func Threshold(endpoint *portainer.Endpoint) time.Duration {
+	threshold, err := time.ParseDuration(endpoint.ClockSkewThreshold)
+	if err != nil || threshold <= 0 {
+		return DefaultThreshold
+	}
+	return threshold
+}
+
+// UpdateEndpoint records the clock skew measured during the snapshot of the endpoint.
+// The previous measure is kept when the snapshot does not hold any.
+func UpdateEndpoint(endpoint *portainer.Endpoint, snapshot *portainer.DockerSnapshot) {
+	if snapshot == nil || snapshot.ClockSkew == nil {
+		return
+	}
+
+	endpoint.ClockSkew.Skew = *snapshot.ClockSkew
+	endpoint.ClockSkew.CheckDate = snapshot.Time
+	endpoint.ClockSkew.Exceeded = Exceeded(endpoint)
+}
+
+// Exceeded returns whether the last clock skew measured on the endpoint exceeds its threshold
+func Exceeded(endpoint *portainer.Endpoint) bool {
+	if endpoint.ClockSkew.CheckDate == 0 {
+		return false
+	}
+
+	skew := time.Duration(endpoint.ClockSkew.Skew) * time.Millisecond
+	if skew < 0 {
+		skew = -skew
+	}
+
+	return skew > Threshold(endpoint)
+}
+
+// Summarize returns the endpoints whose last clock skew measured exceeds their threshold, the largest skew first
+func Summarize(endpoints []portainer.Endpoint) *Summary {
+	summary := &Summary{
+		Offenders: make([]Offender, 0),
+	}
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if endpoint.ClockSkew.CheckDate == 0 {
+			continue
+		}
+
+		summary.Checked++
+
+		if !Exceeded(endpoint) {
+			continue
+		}
+
+		summary.Offenders = append(summary.Offenders, Offender{
+			EndpointID:   endpoint.ID,
+			EndpointName: endpoint.Name,
+			Skew:         endpoint.ClockSkew.Skew,
+			Threshold:    Threshold(endpoint).String(),
+			CheckDate:    endpoint.ClockSkew.CheckDate,
+		})
+	}
+
+	sort.Slice(summary.Offenders, func(i, j int) bool {
+		return abs(summary.Offenders[i].Skew) > abs(summary.Offenders[j].Skew)
+	})
+
+	return summary
+}
+
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
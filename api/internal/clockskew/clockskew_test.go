@@ -0,0 +1,46 @@
+package clockskew
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UpdateEndpoint(t *testing.T) {
+	endpoint := &portainer.Endpoint{}
+
+	UpdateEndpoint(endpoint, &portainer.DockerSnapshot{Time: 1000})
+	assert.Equal(t, int64(0), endpoint.ClockSkew.CheckDate, "snapshots without system time are ignored")
+
+	skew := int64(-45000)
+	UpdateEndpoint(endpoint, &portainer.DockerSnapshot{Time: 1000, ClockSkew: &skew})
+	assert.Equal(t, portainer.EndpointClockSkew{Skew: -45000, Exceeded: true, CheckDate: 1000}, endpoint.ClockSkew)
+
+	endpoint.ClockSkewThreshold = "1m"
+	assert.False(t, Exceeded(endpoint))
+}
+
+func Test_Summarize(t *testing.T) {
+	endpoints := []portainer.Endpoint{
+		{ID: 1, ClockSkew: portainer.EndpointClockSkew{Skew: 40000, CheckDate: 1000}},
+		{ID: 2, ClockSkew: portainer.EndpointClockSkew{Skew: -90000, CheckDate: 1000}},
+		{ID: 3, ClockSkew: portainer.EndpointClockSkew{Skew: 500, CheckDate: 1000}},
+		{ID: 4, ClockSkew: portainer.EndpointClockSkew{Skew: 40000, CheckDate: 1000}, ClockSkewThreshold: "1m"},
+		{ID: 5},
+	}
+
+	summary := Summarize(endpoints)
+	assert.Equal(t, 4, summary.Checked)
+	assert.Len(t, summary.Offenders, 2)
+	assert.Equal(t, portainer.EndpointID(2), summary.Offenders[0].EndpointID)
+	assert.Equal(t, portainer.EndpointID(1), summary.Offenders[1].EndpointID)
+	assert.Equal(t, "30s", summary.Offenders[1].Threshold)
+}
+
+func Test_ValidateThreshold(t *testing.T) {
+	assert.NoError(t, ValidateThreshold(""))
+	assert.NoError(t, ValidateThreshold("2m"))
+	assert.Error(t, ValidateThreshold("0s"))
+	assert.Error(t, ValidateThreshold("abc"))
+}
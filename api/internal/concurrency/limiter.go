@@ -0,0 +1,126 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// DefaultQueueTimeout is the maximum duration a request waits for a slot when no queue timeout is specified
+const DefaultQueueTimeout = 30 * time.Second
+
+// ErrQueueTimeout is returned when no slot became available before the queue timeout
+var ErrQueueTimeout = errors.New("Too many concurrent requests for this endpoint")
+
+type (
+	// Limiter limits the number of concurrent requests proxied to each endpoint. Requests exceeding
+	// the limit of an endpoint wait in a queue until a slot is released or the queue timeout is reached.
+	Limiter struct {
+		mu         sync.Mutex
+		semaphores map[portainer.EndpointID]*semaphore
+	}
+
+	// Stats represents the concurrent requests of an endpoint
+	Stats struct {
+		// Maximum number of concurrent requests. 0 means unlimited
+		MaxConcurrentRequests int `json:"MaxConcurrentRequests" example:"10"`
+		// Number of requests currently proxied to the endpoint
+		InFlight int `json:"InFlight" example:"10"`
+		// Number of requests waiting for a slot
+		Queued int `json:"Queued" example:"3"`
+	}
+
+	semaphore struct {
+		limit    int
+		slots    chan struct{}
+		inFlight int
+		queued   int
+	}
+)
+
+// NewLimiter creates a new limiter
+func NewLimiter() *Limiter {
+	return &Limiter{
+		semaphores: make(map[portainer.EndpointID]*semaphore),
+	}
+}
+
+// Acquire reserves a request slot for the endpoint, waiting in the queue when the limit is reached.
+// The returned function must be called to release the slot, it can safely be called multiple times.
+func (limiter *Limiter) Acquire(ctx context.Context, endpointID portainer.EndpointID, settings *portainer.EndpointRequestConcurrency) (func(), error) {
+	if settings.MaxConcurrentRequests <= 0 {
+		limiter.mu.Lock()
+		delete(limiter.semaphores, endpointID)
+		limiter.mu.Unlock()
+		return func() {}, nil
+	}
+
+	limiter.mu.Lock()
+	sem, ok := limiter.semaphores[endpointID]
+	if !ok || sem.limit != settings.MaxConcurrentRequests {
+		sem = &semaphore{limit: settings.MaxConcurrentRequests, slots: make(chan struct{}, settings.MaxConcurrentRequests)}
+		limiter.semaphores[endpointID] = sem
+	}
+	sem.queued++
+	limiter.mu.Unlock()
+
+	timer := time.NewTimer(QueueTimeout(settings))
+	defer timer.Stop()
+
+	var err error
+	select {
+	case sem.slots <- struct{}{}:
+	case <-timer.C:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	limiter.mu.Lock()
+	sem.queued--
+	if err == nil {
+		sem.inFlight++
+	}
+	limiter.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			limiter.mu.Lock()
+			sem.inFlight--
+			limiter.mu.Unlock()
+			<-sem.slots
+		})
+	}
+
+	return release, nil
+}
+
+// Stats returns the in-flight and queued requests of the endpoint
+func (limiter *Limiter) Stats(endpointID portainer.EndpointID) Stats {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	sem, ok := limiter.semaphores[endpointID]
+	if !ok {
+		return Stats{}
+	}
+
+	return Stats{MaxConcurrentRequests: sem.limit, InFlight: sem.inFlight, Queued: sem.queued}
+}
+
+// QueueTimeout returns the queue timeout of the settings, using the default timeout when none is specified
+func QueueTimeout(settings *portainer.EndpointRequestConcurrency) time.Duration {
+	timeout, err := time.ParseDuration(settings.QueueTimeout)
+	if err != nil || timeout <= 0 {
+		return DefaultQueueTimeout
+	}
+	return timeout
+}
@@ -0,0 +1,35 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Limiter_Acquire(t *testing.T) {
+	limiter := NewLimiter()
+	settings := &portainer.EndpointRequestConcurrency{MaxConcurrentRequests: 1, QueueTimeout: "50ms"}
+
+	release, err := limiter.Acquire(context.Background(), 1, settings)
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{MaxConcurrentRequests: 1, InFlight: 1}, limiter.Stats(1))
+
+	_, err = limiter.Acquire(context.Background(), 1, settings)
+	assert.Equal(t, ErrQueueTimeout, err)
+
+	_, err = limiter.Acquire(context.Background(), 2, settings)
+	assert.NoError(t, err)
+
+	release()
+	release()
+	assert.Equal(t, Stats{MaxConcurrentRequests: 1}, limiter.Stats(1))
+
+	_, err = limiter.Acquire(context.Background(), 1, settings)
+	assert.NoError(t, err)
+
+	_, err = limiter.Acquire(context.Background(), 1, &portainer.EndpointRequestConcurrency{})
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{}, limiter.Stats(1))
+}
@@ -0,0 +1,254 @@
+package containercleanup
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/scheduler"
+	"github.com/portainer/portainer/api/internal/snapshot"
+)
+
+const (
+	// CleanupJobID is the identifier of the container cleanup job in the scheduler
+	CleanupJobID = "container_cleanup"
+
+	cleanupInterval = 5 * time.Minute
+
+	swarmServiceIDLabel = "com.docker.swarm.service.id"
+)
+
+var (
+	errInvalidMaxAge      = errors.New("Invalid container cleanup maximum age. Must be a valid positive duration")
+	errInvalidLabelFilter = errors.New("Invalid container cleanup label filter. Must use the key or key=value format")
+)
+
+type (
+	// Service removes the exited containers of the endpoints once they are older than the maximum age
+	// of the container cleanup policy of their endpoint.
+	Service struct {
+		dataStore     portainer.DataStore
+		clientFactory *docker.ClientFactory
+		scheduler     *scheduler.Scheduler
+	}
+
+	// Candidate represents an exited container removed by the cleanup policy of its endpoint
+	Candidate struct {
+		ContainerID   string `json:"ContainerId"`
+		ContainerName string `json:"ContainerName"`
+		Image         string `json:"Image"`
+		ExitCode      int    `json:"ExitCode"`
+		// Unix timestamp of the exit of the container
+		FinishedAt int64             `json:"FinishedAt"`
+		Labels     map[string]string `json:"-"`
+	}
+)
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, clientFactory *docker.ClientFactory, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		clientFactory: clientFactory,
+		scheduler:     scheduler,
+	}
+}
+
+// Start registers the container cleanup in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          CleanupJobID,
+		Description: "Remove the exited containers older than the maximum age of the cleanup policy of their endpoint",
+		Interval:    cleanupInterval,
+		Subsystem:   scheduler.SubsystemAutoUpdate,
+		Run:         service.cleanup,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,containercleanup] [message: unable to schedule the container cleanup] [error: %s]", err)
+	}
+}
+
+// ValidatePolicy verifies the maximum age and the label filter of a policy.
+// Both are required when the policy is enabled, so that only opted-in containers are removed.
+func ValidatePolicy(policy *portainer.EndpointContainerCleanupPolicy) error {
+	if policy.MaxAge != "" || policy.Enabled {
+		maxAge, err := time.ParseDuration(policy.MaxAge)
+		if err != nil || maxAge <= 0 {
+			return errInvalidMaxAge
+		}
+	}
+
+	if policy.LabelFilter != "" || policy.Enabled {
+		key := strings.SplitN(policy.LabelFilter, "=", 2)[0]
+		if strings.TrimSpace(key) == "" {
+			return errInvalidLabelFilter
+		}
+	}
+
+	return nil
+}
+
+// Candidates returns the containers of the endpoint that the policy removes, the oldest exited first.
+// The policy must be valid, it is not required to be enabled.
+func (service *Service) Candidates(endpoint *portainer.Endpoint, policy *portainer.EndpointContainerCleanupPolicy) ([]Candidate, error) {
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	return candidates(cli, policy, time.Now())
+}
+
+func (service *Service) cleanup() error {
+	endpoints, err := service.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if !endpoint.ContainerCleanupPolicy.Enabled || endpoint.Status != portainer.EndpointStatusUp {
+			continue
+		}
+
+		if !isDockerEndpoint(endpoint) || !snapshot.SupportDirectSnapshot(endpoint) {
+			continue
+		}
+
+		service.cleanupEndpoint(endpoint)
+	}
+
+	return nil
+}
+
+func (service *Service) cleanupEndpoint(endpoint *portainer.Endpoint) {
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		log.Printf("[ERROR] [internal,containercleanup] [endpoint: %s] [message: unable to create Docker client] [error: %s]", endpoint.Name, err)
+		return
+	}
+	defer cli.Close()
+
+	removable, err := candidates(cli, &endpoint.ContainerCleanupPolicy, time.Now())
+	if err != nil {
+		log.Printf("[ERROR] [internal,containercleanup] [endpoint: %s] [message: unable to list the exited containers] [error: %s]", endpoint.Name, err)
+		return
+	}
+
+	for _, candidate := range removable {
+		err = cli.ContainerRemove(context.Background(), candidate.ContainerID, dockertypes.ContainerRemoveOptions{})
+		if err != nil && !client.IsErrNotFound(err) {
+			log.Printf("[ERROR] [internal,containercleanup] [endpoint: %s] [container: %s] [message: unable to remove the exited container] [error: %s]", endpoint.Name, candidate.ContainerName, err)
+			continue
+		}
+
+		log.Printf("[INFO] [internal,containercleanup] [endpoint: %s] [container: %s] [image: %s] [message: exited container removed] [exited_at: %s]",
+			endpoint.Name, candidate.ContainerName, candidate.Image, time.Unix(candidate.FinishedAt, 0).Format(time.RFC3339))
+
+		err = service.removeResourceControl(candidate.ContainerID)
+		if err != nil {
+			log.Printf("[ERROR] [internal,containercleanup] [container: %s] [message: unable to remove the resource control of the container] [error: %s]", candidate.ContainerName, err)
+		}
+	}
+}
+
+func (service *Service) removeResourceControl(containerID string) error {
+	resourceControl, err := service.dataStore.ResourceControl().ResourceControlByResourceIDAndType(containerID, portainer.ContainerResourceControl)
+	if err != nil || resourceControl == nil {
+		return err
+	}
+
+	return service.dataStore.ResourceControl().DeleteResourceControl(resourceControl.ID)
+}
+
+func candidates(cli *client.Client, policy *portainer.EndpointContainerCleanupPolicy, now time.Time) ([]Candidate, error) {
+	maxAge, err := time.ParseDuration(policy.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := cli.ContainerList(context.Background(), dockertypes.ContainerListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("status", "exited"),
+			filters.Arg("label", policy.LabelFilter),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Candidate, 0)
+	for _, container := range containers {
+		inspected, err := cli.ContainerInspect(context.Background(), container.ID)
+		if client.IsErrNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		candidate, ok := candidateFromContainer(&inspected, maxAge, now)
+		if ok {
+			result = append(result, *candidate)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FinishedAt < result[j].FinishedAt
+	})
+
+	return result, nil
+}
+
+// candidateFromContainer returns whether the container is removable: exited for longer than the maximum age,
+// without a restart policy in effect and not managed by a Swarm service
+func candidateFromContainer(container *dockertypes.ContainerJSON, maxAge time.Duration, now time.Time) (*Candidate, bool) {
+	if container.ContainerJSONBase == nil || container.State == nil || container.State.Status != "exited" {
+		return nil, false
+	}
+
+	if container.HostConfig != nil && !container.HostConfig.RestartPolicy.IsNone() {
+		return nil, false
+	}
+
+	var labels map[string]string
+	image := container.Image
+	if container.Config != nil {
+		labels = container.Config.Labels
+		image = container.Config.Image
+	}
+
+	if _, ok := labels[swarmServiceIDLabel]; ok {
+		return nil, false
+	}
+
+	finishedAt, err := time.Parse(time.RFC3339Nano, container.State.FinishedAt)
+	if err != nil || finishedAt.IsZero() || now.Sub(finishedAt) < maxAge {
+		return nil, false
+	}
+
+	return &Candidate{
+		ContainerID:   container.ID,
+		ContainerName: strings.TrimPrefix(container.Name, "/"),
+		Image:         image,
+		ExitCode:      container.State.ExitCode,
+		FinishedAt:    finishedAt.Unix(),
+		Labels:        labels,
+	}, true
+}
+
+func isDockerEndpoint(endpoint *portainer.Endpoint) bool {
+	switch endpoint.Type {
+	case portainer.DockerEnvironment, portainer.AgentOnDockerEnvironment:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package containercleanup
+
+import (
+	"testing"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func exitedContainer(finishedAt time.Time, restartPolicy string, labels map[string]string) *dockertypes.ContainerJSON {
+	return &dockertypes.ContainerJSON{
+		ContainerJSONBase: &dockertypes.ContainerJSONBase{
+			ID:         "abc",
+			Name:       "/job",
+			State:      &dockertypes.ContainerState{Status: "exited", ExitCode: 1, FinishedAt: finishedAt.Format(time.RFC3339Nano)},
+			HostConfig: &container.HostConfig{RestartPolicy: container.RestartPolicy{Name: restartPolicy}},
+		},
+		Config: &container.Config{Image: "alpine:latest", Labels: labels},
+	}
+}
+
+func Test_candidateFromContainer(t *testing.T) {
+	now := time.Now()
+
+	candidate, ok := candidateFromContainer(exitedContainer(now.Add(-2*time.Hour), "no", nil), time.Hour, now)
+	assert.True(t, ok)
+	assert.Equal(t, "job", candidate.ContainerName)
+	assert.Equal(t, "alpine:latest", candidate.Image)
+	assert.Equal(t, 1, candidate.ExitCode)
+
+	_, ok = candidateFromContainer(exitedContainer(now.Add(-30*time.Minute), "", nil), time.Hour, now)
+	assert.False(t, ok, "containers exited for less than the maximum age are kept")
+
+	_, ok = candidateFromContainer(exitedContainer(now.Add(-2*time.Hour), "on-failure", nil), time.Hour, now)
+	assert.False(t, ok, "containers with a restart policy are kept")
+
+	_, ok = candidateFromContainer(exitedContainer(now.Add(-2*time.Hour), "no", map[string]string{swarmServiceIDLabel: "x"}), time.Hour, now)
+	assert.False(t, ok, "Swarm task containers are kept")
+
+	running := exitedContainer(now.Add(-2*time.Hour), "no", nil)
+	running.State.Status = "running"
+	_, ok = candidateFromContainer(running, time.Hour, now)
+	assert.False(t, ok)
+}
+
+func Test_ValidatePolicy(t *testing.T) {
+	assert.NoError(t, ValidatePolicy(&portainer.EndpointContainerCleanupPolicy{}))
+	assert.NoError(t, ValidatePolicy(&portainer.EndpointContainerCleanupPolicy{Enabled: true, MaxAge: "24h", LabelFilter: "io.portainer.cleanup"}))
+	assert.Error(t, ValidatePolicy(&portainer.EndpointContainerCleanupPolicy{Enabled: true, MaxAge: "24h"}))
+	assert.Error(t, ValidatePolicy(&portainer.EndpointContainerCleanupPolicy{Enabled: true, LabelFilter: "cleanup=true"}))
+	assert.Error(t, ValidatePolicy(&portainer.EndpointContainerCleanupPolicy{MaxAge: "-1h"}))
+	assert.Error(t, ValidatePolicy(&portainer.EndpointContainerCleanupPolicy{LabelFilter: "=true"}))
+}
@@ -0,0 +1,92 @@
+package containerdefaults
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// ulimitNames are the resource limits supported by Docker
+var ulimitNames = map[string]bool{
+	"core": true, "cpu": true, "data": true, "fsize": true, "locks": true, "memlock": true, "msgqueue": true, "nice": true,
+	"nofile": true, "nproc": true, "rss": true, "rtprio": true, "rttime": true, "sigpending": true, "stack": true,
+}
+
+// ipcSysctls are the sysctls of the IPC namespace allowed by Docker, in addition to the fs.mqueue.* sysctls
+var ipcSysctls = map[string]bool{
+	"kernel.msgmax": true, "kernel.msgmnb": true, "kernel.msgmni": true, "kernel.sem": true, "kernel.shmall": true,
+	"kernel.shmmax": true, "kernel.shmmni": true, "kernel.shm_rmid_forced": true,
+}
+
+// Validate verifies the defaults. The ulimits must be supported by Docker with a soft limit lower than the hard limit,
+// the sysctls must be namespaced sysctls allowed by Docker: the IPC sysctls or the network sysctls (net.*).
+func Validate(defaults *portainer.EndpointGroupContainerDefaults) error {
+	names := make(map[string]bool)
+	for _, ulimit := range defaults.Ulimits {
+		if !ulimitNames[ulimit.Name] {
+			return fmt.Errorf("Invalid ulimit %s", ulimit.Name)
+		}
+		if names[ulimit.Name] {
+			return fmt.Errorf("Invalid ulimits. Ulimit %s is specified more than once", ulimit.Name)
+		}
+		names[ulimit.Name] = true
+
+		if ulimit.Soft < -1 || ulimit.Hard < -1 {
+			return fmt.Errorf("Invalid ulimit %s. Limits must be positive or -1 for unlimited", ulimit.Name)
+		}
+		if ulimit.Hard != -1 && (ulimit.Soft == -1 || ulimit.Soft > ulimit.Hard) {
+			return fmt.Errorf("Invalid ulimit %s. Soft limit cannot be greater than the hard limit", ulimit.Name)
+		}
+	}
+
+	keys := make([]string, 0, len(defaults.Sysctls))
+	for key := range defaults.Sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !IsNetworkSysctl(key) && !IsIPCSysctl(key) {
+			return fmt.Errorf("Invalid sysctl %s. Only the namespaced sysctls kernel.msg*, kernel.sem, kernel.shm*, fs.mqueue.* and net.* are allowed", key)
+		}
+		if defaults.Sysctls[key] == "" {
+			return fmt.Errorf("Invalid sysctl %s. Value cannot be empty", key)
+		}
+	}
+
+	return nil
+}
+
+// IsEmpty returns true when there are no defaults to inject
+func IsEmpty(defaults *portainer.EndpointGroupContainerDefaults) bool {
+	return len(defaults.Ulimits) == 0 && len(defaults.Sysctls) == 0
+}
+
+// IsNetworkSysctl returns true for the sysctls of the network namespace, which cannot be set on containers
+// sharing the network stack of the host
+func IsNetworkSysctl(key string) bool {
+	return strings.HasPrefix(key, "net.")
+}
+
+// IsIPCSysctl returns true for the sysctls of the IPC namespace, which cannot be set on containers
+// sharing the IPC namespace of the host
+func IsIPCSysctl(key string) bool {
+	return ipcSysctls[key] || strings.HasPrefix(key, "fs.mqueue.")
+}
+
+// ApplicableSysctls returns the sysctls that can be set on a container using the network and IPC modes
+func ApplicableSysctls(sysctls map[string]string, networkMode, ipcMode string) map[string]string {
+	applicable := make(map[string]string)
+	for key, value := range sysctls {
+		if IsNetworkSysctl(key) && (networkMode == "host" || strings.HasPrefix(networkMode, "container:") || strings.HasPrefix(networkMode, "service:")) {
+			continue
+		}
+		if IsIPCSysctl(key) && (ipcMode == "host" || strings.HasPrefix(ipcMode, "container:") || strings.HasPrefix(ipcMode, "service:")) {
+			continue
+		}
+		applicable[key] = value
+	}
+	return applicable
+}
@@ -0,0 +1,37 @@
+package containerdefaults
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate(t *testing.T) {
+	valid := &portainer.EndpointGroupContainerDefaults{
+		Ulimits: []portainer.Ulimit{{Name: "nofile", Soft: 65536, Hard: 65536}, {Name: "nproc", Soft: -1, Hard: -1}},
+		Sysctls: map[string]string{"net.core.somaxconn": "1024", "kernel.shmmax": "68719476736", "fs.mqueue.msg_max": "100"},
+	}
+	assert.NoError(t, Validate(valid))
+
+	invalids := []*portainer.EndpointGroupContainerDefaults{
+		{Ulimits: []portainer.Ulimit{{Name: "files", Soft: 1, Hard: 1}}},
+		{Ulimits: []portainer.Ulimit{{Name: "nofile", Soft: 1, Hard: 1}, {Name: "nofile", Soft: 2, Hard: 2}}},
+		{Ulimits: []portainer.Ulimit{{Name: "nofile", Soft: 2048, Hard: 1024}}},
+		{Ulimits: []portainer.Ulimit{{Name: "nofile", Soft: -2, Hard: 1024}}},
+		{Sysctls: map[string]string{"vm.max_map_count": "262144"}},
+		{Sysctls: map[string]string{"kernel.pid_max": "4194304"}},
+		{Sysctls: map[string]string{"net.ipv4.ip_forward": ""}},
+	}
+	for _, defaults := range invalids {
+		assert.Error(t, Validate(defaults))
+	}
+}
+
+func Test_ApplicableSysctls(t *testing.T) {
+	sysctls := map[string]string{"net.core.somaxconn": "1024", "kernel.shmmax": "68719476736"}
+
+	assert.Equal(t, sysctls, ApplicableSysctls(sysctls, "bridge", ""))
+	assert.Equal(t, map[string]string{"kernel.shmmax": "68719476736"}, ApplicableSysctls(sysctls, "host", "private"))
+	assert.Equal(t, map[string]string{"net.core.somaxconn": "1024"}, ApplicableSysctls(sysctls, "", "container:db"))
+}
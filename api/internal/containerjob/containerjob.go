@@ -0,0 +1,511 @@
+package containerjob
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/registryclient"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+const (
+	// JanitorJobID is the identifier of the container jobs cleanup job in the scheduler
+	JanitorJobID = "container_job_janitor"
+	// DefaultAutoRemoveDelay is the delay used when no auto-remove delay is defined in the job nor in the settings
+	DefaultAutoRemoveDelay = 15 * time.Minute
+	// DefaultRecordRetention is the retention used when no record retention is defined in the job nor in the settings
+	DefaultRecordRetention = 7 * 24 * time.Hour
+	// JobLabel is the label set on the job containers, its value is the job identifier
+	JobLabel = "io.portainer.job.id"
+
+	janitorInterval = time.Minute
+	// maxLogsSize limits the size of the logs kept with a job record once its container is removed, the end of the logs is kept
+	maxLogsSize = 1024 * 1024
+)
+
+var errInvalidLifecycle = errors.New("Invalid container job lifecycle. Auto-remove delay and record retention must be valid positive durations, and the record retention must not be shorter than the auto-remove delay")
+
+// Service manages the lifecycle of the one-off container jobs: it runs the job containers,
+// removes them once the auto-remove delay is elapsed and purges the job records after the retention period.
+type Service struct {
+	dataStore     portainer.DataStore
+	clientFactory *docker.ClientFactory
+	scheduler     *scheduler.Scheduler
+	mu            sync.Mutex
+}
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, clientFactory *docker.ClientFactory, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		clientFactory: clientFactory,
+		scheduler:     scheduler,
+	}
+}
+
+// Start registers the cleanup of the container jobs in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          JanitorJobID,
+		Description: "Remove the containers of the finished one-off jobs and purge the expired job records",
+		Interval:    janitorInterval,
+		RunOnStart:  true,
+		Run:         service.cleanup,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,containerjob] [message: unable to schedule the container jobs cleanup] [error: %s]", err)
+	}
+}
+
+// ValidateLifecycle verifies that the durations of a lifecycle are valid. Empty values are allowed.
+func ValidateLifecycle(lifecycle *portainer.ContainerJobLifecycle) error {
+	autoRemoveDelay, err := parseDuration(lifecycle.AutoRemoveDelay)
+	if err != nil || autoRemoveDelay < 0 {
+		return errInvalidLifecycle
+	}
+
+	recordRetention, err := parseDuration(lifecycle.RecordRetention)
+	if err != nil || recordRetention < 0 {
+		return errInvalidLifecycle
+	}
+
+	if lifecycle.AutoRemoveDelay != "" && lifecycle.RecordRetention != "" && recordRetention < autoRemoveDelay {
+		return errInvalidLifecycle
+	}
+
+	return nil
+}
+
+// Run saves the job and runs its container with the Docker client. The client is expected to send its requests
+// through the proxy of the job endpoint with the context of the user request, so that the job container is subject
+// to the same access control, security settings and policies as the containers created by the user.
+// A job that cannot be run is saved with a failed status and the error is returned.
+func (service *Service) Run(ctx context.Context, dockerClient *client.Client, job *portainer.ContainerJob) error {
+	job.Status = portainer.ContainerJobRunning
+	job.CreationDate = time.Now().Unix()
+
+	err := service.dataStore.ContainerJob().CreateContainerJob(job)
+	if err != nil {
+		return err
+	}
+
+	job.ContainerID, err = service.startContainer(ctx, dockerClient, job)
+	if err != nil {
+		job.Status = portainer.ContainerJobFailed
+		job.Error = err.Error()
+		job.FinishDate = time.Now().Unix()
+	}
+
+	updateErr := service.dataStore.ContainerJob().UpdateContainerJob(job.ID, job)
+	if err != nil {
+		return err
+	}
+	return updateErr
+}
+
+func (service *Service) startContainer(ctx context.Context, dockerClient *client.Client, job *portainer.ContainerJob) (string, error) {
+	env := make([]string, 0, len(job.Env))
+	for _, pair := range job.Env {
+		env = append(env, pair.Name+"="+pair.Value)
+	}
+
+	config := &container.Config{
+		Image:  job.Image,
+		Cmd:    job.Command,
+		Env:    env,
+		Labels: map[string]string{JobLabel: strconv.Itoa(int(job.ID))},
+	}
+
+	body, err := dockerClient.ContainerCreate(ctx, config, &container.HostConfig{}, nil, "")
+	if client.IsErrNotFound(err) {
+		err = service.pullImage(ctx, dockerClient, job.Image)
+		if err != nil {
+			return "", err
+		}
+
+		body, err = dockerClient.ContainerCreate(ctx, config, &container.HostConfig{}, nil, "")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = dockerClient.ContainerStart(ctx, body.ID, dockertypes.ContainerStartOptions{})
+	if err != nil {
+		dockerClient.ContainerRemove(ctx, body.ID, dockertypes.ContainerRemoveOptions{Force: true})
+		return "", err
+	}
+
+	return body.ID, nil
+}
+
+func (service *Service) pullImage(ctx context.Context, dockerClient *client.Client, image string) error {
+	registryAuth, err := service.registryAuth(image)
+	if err != nil {
+		return err
+	}
+
+	reader, err := dockerClient.ImagePull(ctx, image, dockertypes.ImagePullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	decoder := json.NewDecoder(reader)
+	for {
+		var message jsonmessage.JSONMessage
+		err := decoder.Decode(&message)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if message.Error != nil {
+			return message.Error
+		}
+	}
+}
+
+// registryAuth returns the registry authentication header of an image pull. The header only references the
+// registry hosting the image: the endpoint proxy replaces it with the credentials of the registry, provided that
+// the user is allowed to use the registry. DockerHub is referenced with an empty server address.
+func (service *Service) registryAuth(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+
+	serverAddress := ""
+	if domain := reference.Domain(named); domain != registryclient.DockerHubRegistry {
+		registries, err := service.dataStore.Registry().Registries()
+		if err != nil {
+			return "", err
+		}
+
+		serverAddress = domain
+		for _, registry := range registries {
+			if strings.EqualFold(registryclient.NormalizeRegistry(registry.URL), domain) {
+				serverAddress = registry.URL
+				break
+			}
+		}
+	}
+
+	header, err := json.Marshal(dockertypes.AuthConfig{ServerAddress: serverAddress})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(header), nil
+}
+
+// Logs returns the logs of a job. The logs are retrieved from the job container when it still exists,
+// otherwise the logs collected when the container was removed are returned.
+func (service *Service) Logs(job *portainer.ContainerJob) (string, error) {
+	if job.ContainerID == "" || job.ContainerRemovalDate != 0 {
+		return job.Logs, nil
+	}
+
+	endpoint, err := service.dataStore.Endpoint().Endpoint(job.EndpointID)
+	if err != nil {
+		return "", err
+	}
+
+	dockerClient, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return "", err
+	}
+	defer dockerClient.Close()
+
+	return containerLogs(dockerClient, job.ContainerID)
+}
+
+// Remove removes the job container, if it still exists, and deletes the job record
+func (service *Service) Remove(job *portainer.ContainerJob) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if job.ContainerID != "" && job.ContainerRemovalDate == 0 {
+		endpoint, err := service.dataStore.Endpoint().Endpoint(job.EndpointID)
+		if err != nil && err != bolterrors.ErrObjectNotFound {
+			return err
+		}
+
+		if endpoint != nil {
+			err = service.removeContainer(endpoint, job.ContainerID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return service.dataStore.ContainerJob().DeleteContainerJob(job.ID)
+}
+
+func (service *Service) removeContainer(endpoint *portainer.Endpoint, containerID string) error {
+	dockerClient, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	err = dockerClient.ContainerRemove(context.Background(), containerID, dockertypes.ContainerRemoveOptions{Force: true})
+	if err != nil && !client.IsErrNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// cleanup updates the status of the running jobs, removes the containers of the jobs once their auto-remove
+// delay is elapsed and purges the job records once their retention period is elapsed.
+func (service *Service) cleanup() error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := service.dataStore.ContainerJob().ContainerJobs()
+	if err != nil {
+		return err
+	}
+
+	clients := make(map[portainer.EndpointID]*client.Client)
+	defer func() {
+		for _, dockerClient := range clients {
+			dockerClient.Close()
+		}
+	}()
+
+	for idx := range jobs {
+		job := &jobs[idx]
+
+		err := service.cleanupJob(job, &settings.ContainerJobLifecycle, clients)
+		if err != nil {
+			log.Printf("[ERROR] [internal,containerjob] [message: unable to clean up container job %d] [error: %s]", job.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (service *Service) cleanupJob(job *portainer.ContainerJob, defaults *portainer.ContainerJobLifecycle, clients map[portainer.EndpointID]*client.Client) error {
+	autoRemoveDelay, recordRetention := EffectiveLifecycle(&job.Lifecycle, defaults)
+
+	endpoint, err := service.dataStore.Endpoint().Endpoint(job.EndpointID)
+	if err == bolterrors.ErrObjectNotFound {
+		return service.cleanupJobOfRemovedEndpoint(job, recordRetention)
+	} else if err != nil {
+		return err
+	}
+
+	dockerClient, ok := clients[endpoint.ID]
+	if !ok {
+		dockerClient, err = service.clientFactory.CreateClient(endpoint, "")
+		if err != nil {
+			return err
+		}
+		clients[endpoint.ID] = dockerClient
+	}
+
+	if job.Status == portainer.ContainerJobRunning {
+		finished, err := refreshJobStatus(dockerClient, job)
+		if err != nil || !finished {
+			return err
+		}
+
+		err = service.dataStore.ContainerJob().UpdateContainerJob(job.ID, job)
+		if err != nil {
+			return err
+		}
+	}
+
+	finishDate := time.Unix(job.FinishDate, 0)
+	containerExists := job.ContainerID != "" && job.ContainerRemovalDate == 0
+
+	if time.Since(finishDate) >= recordRetention {
+		if containerExists {
+			err := dockerClient.ContainerRemove(context.Background(), job.ContainerID, dockertypes.ContainerRemoveOptions{Force: true})
+			if err != nil && !client.IsErrNotFound(err) {
+				return err
+			}
+		}
+
+		return service.dataStore.ContainerJob().DeleteContainerJob(job.ID)
+	}
+
+	if containerExists && time.Since(finishDate) >= autoRemoveDelay {
+		logs, err := containerLogs(dockerClient, job.ContainerID)
+		if err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+		job.Logs = logs
+
+		err = dockerClient.ContainerRemove(context.Background(), job.ContainerID, dockertypes.ContainerRemoveOptions{Force: true})
+		if err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+		job.ContainerRemovalDate = time.Now().Unix()
+
+		return service.dataStore.ContainerJob().UpdateContainerJob(job.ID, job)
+	}
+
+	return nil
+}
+
+// cleanupJobOfRemovedEndpoint marks the job as finished when its endpoint was removed,
+// the job record is kept until the end of its retention period.
+func (service *Service) cleanupJobOfRemovedEndpoint(job *portainer.ContainerJob, recordRetention time.Duration) error {
+	now := time.Now().Unix()
+
+	if job.Status == portainer.ContainerJobRunning {
+		job.Status = portainer.ContainerJobFailed
+		job.Error = "The endpoint of the job was removed before the job finished"
+		job.FinishDate = now
+	}
+
+	if time.Since(time.Unix(job.FinishDate, 0)) >= recordRetention {
+		return service.dataStore.ContainerJob().DeleteContainerJob(job.ID)
+	}
+
+	if job.ContainerRemovalDate == 0 {
+		job.ContainerRemovalDate = now
+	}
+
+	return service.dataStore.ContainerJob().UpdateContainerJob(job.ID, job)
+}
+
+// refreshJobStatus updates the status of a running job based on the state of its container.
+// It returns true when the job is finished.
+func refreshJobStatus(dockerClient *client.Client, job *portainer.ContainerJob) (bool, error) {
+	// the job container is being created
+	if job.ContainerID == "" {
+		return false, nil
+	}
+
+	containerJSON, err := dockerClient.ContainerInspect(context.Background(), job.ContainerID)
+	if client.IsErrNotFound(err) {
+		job.Status = portainer.ContainerJobFailed
+		job.Error = "The job container was removed before the job finished"
+		job.FinishDate = time.Now().Unix()
+		job.ContainerRemovalDate = job.FinishDate
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	state := containerJSON.State
+	if state == nil || state.Running || state.Restarting {
+		return false, nil
+	}
+
+	job.Status = portainer.ContainerJobCompleted
+	job.ExitCode = state.ExitCode
+	job.FinishDate = time.Now().Unix()
+
+	finishedAt, err := time.Parse(time.RFC3339Nano, state.FinishedAt)
+	if err == nil && finishedAt.Unix() > 0 {
+		job.FinishDate = finishedAt.Unix()
+	}
+
+	return true, nil
+}
+
+func containerLogs(dockerClient *client.Client, containerID string) (string, error) {
+	reader, err := dockerClient.ContainerLogs(context.Background(), containerID, dockertypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	writer := &tailWriter{size: maxLogsSize}
+
+	_, err = stdcopy.StdCopy(writer, writer, reader)
+	if err != nil {
+		return "", err
+	}
+
+	return writer.String(), nil
+}
+
+// EffectiveLifecycle returns the auto-remove delay and the record retention of a job,
+// falling back to the default lifecycle defined in the settings and then to the default values.
+func EffectiveLifecycle(lifecycle, defaults *portainer.ContainerJobLifecycle) (time.Duration, time.Duration) {
+	autoRemoveDelay := effectiveDuration(lifecycle.AutoRemoveDelay, defaults.AutoRemoveDelay, DefaultAutoRemoveDelay)
+	recordRetention := effectiveDuration(lifecycle.RecordRetention, defaults.RecordRetention, DefaultRecordRetention)
+
+	if recordRetention < autoRemoveDelay {
+		recordRetention = autoRemoveDelay
+	}
+
+	return autoRemoveDelay, recordRetention
+}
+
+func effectiveDuration(value, defaultValue string, fallback time.Duration) time.Duration {
+	for _, candidate := range []string{value, defaultValue} {
+		if candidate == "" {
+			continue
+		}
+
+		duration, err := time.ParseDuration(candidate)
+		if err == nil && duration >= 0 {
+			return duration
+		}
+	}
+
+	return fallback
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// tailWriter keeps the last bytes written, up to a maximum size
+type tailWriter struct {
+	buffer []byte
+	size   int
+}
+
+func (writer *tailWriter) Write(p []byte) (int, error) {
+	writer.buffer = append(writer.buffer, p...)
+
+	// the buffer is compacted once it reaches twice the maximum size to avoid moving the data on each write
+	if len(writer.buffer) >= 2*writer.size {
+		writer.buffer = append(writer.buffer[:0], writer.buffer[len(writer.buffer)-writer.size:]...)
+	}
+
+	return len(p), nil
+}
+
+// String returns the last bytes written
+func (writer *tailWriter) String() string {
+	if len(writer.buffer) > writer.size {
+		return string(writer.buffer[len(writer.buffer)-writer.size:])
+	}
+	return string(writer.buffer)
+}
@@ -0,0 +1,111 @@
+package containerjob
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EffectiveLifecycle(t *testing.T) {
+	tests := []struct {
+		name                    string
+		lifecycle               portainer.ContainerJobLifecycle
+		defaults                portainer.ContainerJobLifecycle
+		expectedAutoRemoveDelay time.Duration
+		expectedRecordRetention time.Duration
+	}{
+		{
+			name:                    "no lifecycle defined uses the default values",
+			expectedAutoRemoveDelay: DefaultAutoRemoveDelay,
+			expectedRecordRetention: DefaultRecordRetention,
+		},
+		{
+			name:                    "settings lifecycle is used when the job does not define one",
+			defaults:                portainer.ContainerJobLifecycle{AutoRemoveDelay: "5m", RecordRetention: "24h"},
+			expectedAutoRemoveDelay: 5 * time.Minute,
+			expectedRecordRetention: 24 * time.Hour,
+		},
+		{
+			name:                    "job lifecycle takes precedence over the settings",
+			lifecycle:               portainer.ContainerJobLifecycle{AutoRemoveDelay: "0s"},
+			defaults:                portainer.ContainerJobLifecycle{AutoRemoveDelay: "5m", RecordRetention: "24h"},
+			expectedAutoRemoveDelay: 0,
+			expectedRecordRetention: 24 * time.Hour,
+		},
+		{
+			name:                    "record retention is never shorter than the auto-remove delay",
+			lifecycle:               portainer.ContainerJobLifecycle{AutoRemoveDelay: "48h"},
+			defaults:                portainer.ContainerJobLifecycle{RecordRetention: "24h"},
+			expectedAutoRemoveDelay: 48 * time.Hour,
+			expectedRecordRetention: 48 * time.Hour,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			autoRemoveDelay, recordRetention := EffectiveLifecycle(&test.lifecycle, &test.defaults)
+			assert.Equal(t, test.expectedAutoRemoveDelay, autoRemoveDelay)
+			assert.Equal(t, test.expectedRecordRetention, recordRetention)
+		})
+	}
+}
+
+func Test_ValidateLifecycle(t *testing.T) {
+	assert.NoError(t, ValidateLifecycle(&portainer.ContainerJobLifecycle{}))
+	assert.NoError(t, ValidateLifecycle(&portainer.ContainerJobLifecycle{AutoRemoveDelay: "10m", RecordRetention: "72h"}))
+	assert.Error(t, ValidateLifecycle(&portainer.ContainerJobLifecycle{AutoRemoveDelay: "ten minutes"}))
+	assert.Error(t, ValidateLifecycle(&portainer.ContainerJobLifecycle{RecordRetention: "-1h"}))
+	assert.Error(t, ValidateLifecycle(&portainer.ContainerJobLifecycle{AutoRemoveDelay: "2h", RecordRetention: "1h"}))
+}
+
+func Test_tailWriter_shouldKeepTheEndOfTheLogs(t *testing.T) {
+	writer := &tailWriter{size: 10}
+
+	for i := 0; i < 5; i++ {
+		writer.Write([]byte(fmt.Sprintf("line %d\n", i)))
+	}
+	assert.Equal(t, " 3\nline 4\n", writer.String())
+
+	writer.Write([]byte(strings.Repeat("x", 25)))
+	assert.Equal(t, strings.Repeat("x", 10), writer.String())
+}
+
+func Test_registryAuth_shouldReferenceTheRegistryOfTheImage(t *testing.T) {
+	dataStore := testhelpers.NewDatastore(testhelpers.WithRegistries([]portainer.Registry{
+		{ID: 1, URL: "https://registry.example.com"},
+	}))
+	service := NewService(dataStore, nil, nil)
+
+	tests := []struct {
+		image                 string
+		expectedServerAddress string
+	}{
+		{image: "alpine:latest", expectedServerAddress: ""},
+		{image: "docker.io/library/alpine", expectedServerAddress: ""},
+		{image: "registry.example.com/team/job:1.0", expectedServerAddress: "https://registry.example.com"},
+		{image: "unknown.example.com/job", expectedServerAddress: "unknown.example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			header, err := service.registryAuth(test.image)
+			assert.NoError(t, err)
+
+			decoded, err := base64.StdEncoding.DecodeString(header)
+			assert.NoError(t, err)
+
+			var authConfig dockertypes.AuthConfig
+			assert.NoError(t, json.Unmarshal(decoded, &authConfig))
+			assert.Equal(t, test.expectedServerAddress, authConfig.ServerAddress)
+			assert.Empty(t, authConfig.Username)
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package crashloop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/mailer"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+const (
+	// DetectionJobID is the identifier of the crash-loop detection job in the scheduler
+	DetectionJobID = "crashloop_detection"
+	// DefaultRestartThreshold is the threshold used when no threshold is defined in the stack nor in the settings
+	DefaultRestartThreshold = 5
+	// DefaultWindow is the window used when no window is defined in the stack nor in the settings
+	DefaultWindow = 10 * time.Minute
+
+	detectionInterval = 30 * time.Second
+	eventsTimeout     = 30 * time.Second
+
+	composeProjectLabel  = "com.docker.compose.project"
+	swarmStackLabel      = "com.docker.stack.namespace"
+	containerNameKey     = "name"
+	containerExitCodeKey = "exitCode"
+	maxTrackedExits      = 1000
+)
+
+var errInvalidPolicy = errors.New("Invalid crash-loop policy. Restart threshold must be positive or 0 for default and window must be a valid positive duration")
+
+type (
+	// Service tracks the exit events of the containers of the Docker endpoints and
+	// notifies when a container exits more often than the threshold of its crash-loop policy.
+	Service struct {
+		dataStore     portainer.DataStore
+		clientFactory *docker.ClientFactory
+		mailer        *mailer.Service
+		scheduler     *scheduler.Scheduler
+		mu            sync.Mutex
+		endpoints     map[portainer.EndpointID]*endpointTracker
+	}
+
+	// Offender represents a container exiting more often than the threshold of its crash-loop policy
+	Offender struct {
+		ContainerID   string `json:"ContainerId"`
+		ContainerName string `json:"ContainerName"`
+		// Name of the stack of the container, empty when the container is not part of a stack
+		Stack string `json:"Stack"`
+		// Number of exits within the window
+		Restarts         int    `json:"Restarts"`
+		RestartThreshold int    `json:"RestartThreshold"`
+		Window           string `json:"Window"`
+		// Average number of exits per minute within the window
+		RestartsPerMinute float64 `json:"RestartsPerMinute"`
+		LastExitCode      int     `json:"LastExitCode"`
+		// Unix timestamp of the last exit
+		LastExitTime int64             `json:"LastExitTime"`
+		Labels       map[string]string `json:"-"`
+	}
+
+	endpointTracker struct {
+		lastPoll   time.Time
+		containers map[string]*containerTracker
+	}
+
+	containerTracker struct {
+		name         string
+		stack        string
+		labels       map[string]string
+		exits        []time.Time
+		lastExitCode int
+		notified     bool
+	}
+)
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, clientFactory *docker.ClientFactory, mailer *mailer.Service, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		clientFactory: clientFactory,
+		mailer:        mailer,
+		scheduler:     scheduler,
+		endpoints:     make(map[portainer.EndpointID]*endpointTracker),
+	}
+}
+
+// Start registers the crash-loop detection in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          DetectionJobID,
+		Description: "Track the exits of the containers and notify when a container is crash-looping",
+		Interval:    detectionInterval,
+		RunOnStart:  true,
+		Subsystem:   scheduler.SubsystemSnapshot,
+		Run:         service.detect,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,crashloop] [message: unable to schedule the crash-loop detection] [error: %s]", err)
+	}
+}
+
+// ValidatePolicy verifies the threshold and the window of a policy. Zero values are allowed.
+func ValidatePolicy(policy *portainer.CrashLoopPolicy) error {
+	if policy.RestartThreshold < 0 {
+		return errInvalidPolicy
+	}
+
+	if policy.Window != "" {
+		window, err := time.ParseDuration(policy.Window)
+		if err != nil || window <= 0 {
+			return errInvalidPolicy
+		}
+	}
+
+	return nil
+}
+
+// Offenders returns the containers of the endpoint that are currently crash-looping,
+// the containers exiting the most often first
+func (service *Service) Offenders(endpointID portainer.EndpointID) ([]Offender, error) {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	stacks, err := service.endpointStacks(endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	offenders := make([]Offender, 0)
+
+	tracker, ok := service.endpoints[endpointID]
+	if !ok {
+		return offenders, nil
+	}
+
+	now := time.Now()
+	for containerID, container := range tracker.containers {
+		threshold, window := effectivePolicy(&settings.CrashLoopSettings.Policy, stacks[container.stack])
+		restarts := container.exitsWithin(now, window)
+		if restarts < threshold {
+			continue
+		}
+
+		offenders = append(offenders, Offender{
+			ContainerID:       containerID,
+			ContainerName:     container.name,
+			Stack:             container.stack,
+			Restarts:          restarts,
+			RestartThreshold:  threshold,
+			Window:            window.String(),
+			RestartsPerMinute: float64(restarts) / window.Minutes(),
+			LastExitCode:      container.lastExitCode,
+			LastExitTime:      container.exits[len(container.exits)-1].Unix(),
+			Labels:            container.labels,
+		})
+	}
+
+	sort.Slice(offenders, func(i, j int) bool {
+		return offenders[i].Restarts > offenders[j].Restarts
+	})
+
+	return offenders, nil
+}
+
+func (service *Service) detect() error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	if !settings.CrashLoopSettings.Enabled {
+		service.mu.Lock()
+		service.endpoints = make(map[portainer.EndpointID]*endpointTracker)
+		service.mu.Unlock()
+		return nil
+	}
+
+	endpoints, err := service.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if endpoint.Type != portainer.DockerEnvironment && endpoint.Type != portainer.AgentOnDockerEnvironment {
+			continue
+		}
+		if endpoint.Status != portainer.EndpointStatusUp {
+			continue
+		}
+
+		err := service.detectEndpoint(endpoint, &settings.CrashLoopSettings)
+		if err != nil {
+			log.Printf("[WARN] [internal,crashloop] [endpoint: %s] [message: unable to track the container exits] [error: %s]", endpoint.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (service *Service) detectEndpoint(endpoint *portainer.Endpoint, settings *portainer.CrashLoopSettings) error {
+	stacks, err := service.endpointStacks(endpoint.ID)
+	if err != nil {
+		return err
+	}
+
+	_, defaultWindow := effectivePolicy(&settings.Policy, nil)
+
+	service.mu.Lock()
+	tracker, ok := service.endpoints[endpoint.ID]
+	if !ok {
+		tracker = &endpointTracker{
+			lastPoll:   time.Now().Add(-defaultWindow),
+			containers: make(map[string]*containerTracker),
+		}
+		service.endpoints[endpoint.ID] = tracker
+	}
+	since := tracker.lastPoll
+	service.mu.Unlock()
+
+	until := time.Now().Truncate(time.Second)
+	exits, err := service.containerExits(endpoint, since, until)
+	if err != nil {
+		return err
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	tracker.lastPoll = until
+	for _, exit := range exits {
+		tracker.record(exit)
+	}
+
+	for containerID, container := range tracker.containers {
+		threshold, window := effectivePolicy(&settings.Policy, stacks[container.stack])
+
+		container.prune(until, window)
+		if len(container.exits) == 0 {
+			delete(tracker.containers, containerID)
+			continue
+		}
+
+		restarts := container.exitsWithin(until, window)
+		if restarts < threshold {
+			container.notified = false
+			continue
+		}
+
+		if !container.notified {
+			container.notified = true
+			service.notify(endpoint, container, restarts, window, settings.NotificationRecipients)
+		}
+	}
+
+	return nil
+}
+
+// containerExits retrieves the die events of the containers of the endpoint between since and until
+func (service *Service) containerExits(endpoint *portainer.Endpoint, since, until time.Time) ([]events.Message, error) {
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventsTimeout)
+	defer cancel()
+
+	eventFilters := filters.NewArgs()
+	eventFilters.Add("type", events.ContainerEventType)
+	eventFilters.Add("event", "die")
+
+	messages, errs := cli.Events(ctx, dockertypes.EventsOptions{
+		Since:   strconv.FormatInt(since.Unix(), 10),
+		Until:   strconv.FormatInt(until.Unix(), 10),
+		Filters: eventFilters,
+	})
+
+	exits := make([]events.Message, 0)
+	for {
+		select {
+		case message := <-messages:
+			exits = append(exits, message)
+		case err := <-errs:
+			if err == nil || err == io.EOF {
+				return exits, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+func (service *Service) endpointStacks(endpointID portainer.EndpointID) (map[string]*portainer.Stack, error) {
+	stacks, err := service.dataStore.Stack().Stacks()
+	if err != nil {
+		return nil, err
+	}
+
+	endpointStacks := make(map[string]*portainer.Stack)
+	for idx := range stacks {
+		if stacks[idx].EndpointID == endpointID {
+			endpointStacks[stacks[idx].Name] = &stacks[idx]
+		}
+	}
+	return endpointStacks, nil
+}
+
+func (service *Service) notify(endpoint *portainer.Endpoint, container *containerTracker, restarts int, window time.Duration, recipients []string) {
+	log.Printf("[WARN] [internal,crashloop] [endpoint: %s] [container: %s] [message: container is crash-looping] [restarts: %d] [window: %s]", endpoint.Name, container.name, restarts, window)
+
+	if len(recipients) == 0 {
+		return
+	}
+
+	body := fmt.Sprintf("Container %s on endpoint %s exited %d times in the last %s.\nLast exit code: %d\n", container.name, endpoint.Name, restarts, window, container.lastExitCode)
+	if container.stack != "" {
+		body += fmt.Sprintf("Stack: %s\n", container.stack)
+	}
+
+	go func() {
+		err := service.mailer.Send(&mailer.Message{
+			To:      recipients,
+			Subject: fmt.Sprintf("Container %s is crash-looping on endpoint %s", container.name, endpoint.Name),
+			Body:    body,
+		})
+		if err != nil {
+			log.Printf("[ERROR] [internal,crashloop] [endpoint: %s] [container: %s] [message: unable to send the crash-loop notification] [error: %s]", endpoint.Name, container.name, err)
+		}
+	}()
+}
+
+// effectivePolicy returns the threshold and the window applied to the containers of the stack,
+// the policy of the stack takes precedence over the policy of the settings
+func effectivePolicy(settingsPolicy *portainer.CrashLoopPolicy, stack *portainer.Stack) (int, time.Duration) {
+	threshold := DefaultRestartThreshold
+	window := DefaultWindow
+
+	policies := []*portainer.CrashLoopPolicy{settingsPolicy}
+	if stack != nil && stack.CrashLoopPolicy != nil {
+		policies = append(policies, stack.CrashLoopPolicy)
+	}
+
+	for _, policy := range policies {
+		if policy.RestartThreshold > 0 {
+			threshold = policy.RestartThreshold
+		}
+		if duration, err := time.ParseDuration(policy.Window); err == nil && duration > 0 {
+			window = duration
+		}
+	}
+
+	return threshold, window
+}
+
+func (tracker *endpointTracker) record(message events.Message) {
+	containerID := message.Actor.ID
+	if containerID == "" {
+		containerID = message.ID
+	}
+
+	container, ok := tracker.containers[containerID]
+	if !ok {
+		container = &containerTracker{}
+		tracker.containers[containerID] = container
+	}
+
+	attributes := message.Actor.Attributes
+	container.name = strings.TrimPrefix(attributes[containerNameKey], "/")
+	container.stack = attributes[composeProjectLabel]
+	if container.stack == "" {
+		container.stack = attributes[swarmStackLabel]
+	}
+	container.labels = attributes
+	container.lastExitCode, _ = strconv.Atoi(attributes[containerExitCodeKey])
+
+	exitTime := time.Unix(0, message.TimeNano)
+	if message.TimeNano == 0 {
+		exitTime = time.Unix(message.Time, 0)
+	}
+	container.exits = append(container.exits, exitTime)
+	if len(container.exits) > maxTrackedExits {
+		container.exits = container.exits[len(container.exits)-maxTrackedExits:]
+	}
+}
+
+func (container *containerTracker) prune(now time.Time, window time.Duration) {
+	idx := 0
+	for idx < len(container.exits) && now.Sub(container.exits[idx]) > window {
+		idx++
+	}
+	container.exits = container.exits[idx:]
+}
+
+func (container *containerTracker) exitsWithin(now time.Time, window time.Duration) int {
+	count := 0
+	for _, exit := range container.exits {
+		if now.Sub(exit) <= window {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,44 @@
+package crashloop
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_effectivePolicy(t *testing.T) {
+	threshold, window := effectivePolicy(&portainer.CrashLoopPolicy{}, nil)
+	assert.Equal(t, DefaultRestartThreshold, threshold)
+	assert.Equal(t, DefaultWindow, window)
+
+	settingsPolicy := &portainer.CrashLoopPolicy{RestartThreshold: 3, Window: "5m"}
+	threshold, window = effectivePolicy(settingsPolicy, &portainer.Stack{})
+	assert.Equal(t, 3, threshold)
+	assert.Equal(t, 5*time.Minute, window)
+
+	stack := &portainer.Stack{CrashLoopPolicy: &portainer.CrashLoopPolicy{Window: "1h"}}
+	threshold, window = effectivePolicy(settingsPolicy, stack)
+	assert.Equal(t, 3, threshold)
+	assert.Equal(t, time.Hour, window)
+}
+
+func Test_containerTracker(t *testing.T) {
+	now := time.Now()
+	container := &containerTracker{
+		exits: []time.Time{now.Add(-20 * time.Minute), now.Add(-5 * time.Minute), now.Add(-time.Minute)},
+	}
+
+	assert.Equal(t, 2, container.exitsWithin(now, 10*time.Minute))
+
+	container.prune(now, 10*time.Minute)
+	assert.Len(t, container.exits, 2)
+}
+
+func Test_ValidatePolicy(t *testing.T) {
+	assert.NoError(t, ValidatePolicy(&portainer.CrashLoopPolicy{}))
+	assert.NoError(t, ValidatePolicy(&portainer.CrashLoopPolicy{RestartThreshold: 3, Window: "15m"}))
+	assert.Error(t, ValidatePolicy(&portainer.CrashLoopPolicy{RestartThreshold: -1}))
+	assert.Error(t, ValidatePolicy(&portainer.CrashLoopPolicy{Window: "abc"}))
+}
@@ -0,0 +1,167 @@
+package dockerhublimit
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/registryclient"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+const (
+	// SampleJobID is the identifier of the DockerHub rate limit sampling job in the scheduler
+	SampleJobID = "dockerhub_rate_limit"
+
+	sampleInterval   = 5 * time.Minute
+	historyRetention = 24 * time.Hour
+	requestTimeout   = 30 * time.Second
+
+	// rateLimitRepository is the repository provided by DockerHub to check the pull rate limit,
+	// the HEAD requests on its manifests are not counted as pulls
+	rateLimitRepository = "ratelimitpreview/test"
+)
+
+// Service records the state of the DockerHub pull rate limit of the DockerHub account configured in Portainer,
+// or of the Portainer instance address when no authentication is configured. The history is persisted in the database
+// so that the forecast survives a restart.
+type Service struct {
+	dataStore  portainer.DataStore
+	scheduler  *scheduler.Scheduler
+	httpClient *http.Client
+	mu         sync.Mutex
+	samples    []portainer.DockerHubRateLimitSample
+}
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:  dataStore,
+		scheduler:  scheduler,
+		httpClient: &http.Client{Timeout: requestTimeout},
+		samples:    make([]portainer.DockerHubRateLimitSample, 0),
+	}
+}
+
+// Start loads the recorded history and registers the DockerHub rate limit sampling in the scheduler
+func (service *Service) Start() {
+	service.load(time.Now())
+
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          SampleJobID,
+		Description: "Record the state of the DockerHub pull rate limit",
+		Interval:    sampleInterval,
+		RunOnStart:  true,
+		Subsystem:   scheduler.SubsystemSnapshot,
+		Run:         service.sample,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,dockerhublimit] [message: unable to schedule the DockerHub rate limit sampling] [error: %s]", err)
+	}
+}
+
+// Samples returns the recorded samples, oldest first
+func (service *Service) Samples() []portainer.DockerHubRateLimitSample {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	return append([]portainer.DockerHubRateLimitSample{}, service.samples...)
+}
+
+// Forecast returns the recent consumption rate of the pull rate limit and the estimated date of its exhaustion
+func (service *Service) Forecast() portainer.DockerHubRateLimitForecast {
+	return forecast(service.Samples(), time.Now())
+}
+
+func (service *Service) sample() error {
+	dockerHub, err := service.dataStore.DockerHub().DockerHub()
+	if err != nil {
+		return err
+	}
+
+	var credentials *registryclient.Credentials
+	if dockerHub.Authentication {
+		credentials = &registryclient.Credentials{Username: dockerHub.Username, Password: dockerHub.Password}
+	}
+
+	client := registryclient.NewClient(service.httpClient, registryclient.DockerHubRegistry, credentials)
+
+	response, err := client.Head("/"+rateLimitRepository+"/manifests/latest", registryclient.RepositoryScope(rateLimitRepository), nil)
+	if err != nil {
+		log.Printf("[WARN] [internal,dockerhublimit] [message: unable to retrieve the DockerHub rate limit] [error: %s]", err)
+		return nil
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusTooManyRequests {
+		log.Printf("[WARN] [internal,dockerhublimit] [message: unable to retrieve the DockerHub rate limit] [error: unexpected response %s]", response.Status)
+		return nil
+	}
+
+	sample := portainer.DockerHubRateLimitSample{Date: time.Now().Unix(), Unlimited: true}
+	if rateLimit, ok := security.ParseRateLimitHeaders(response.Header); ok {
+		sample = portainer.DockerHubRateLimitSample{
+			Date:      sample.Date,
+			Limit:     rateLimit.Limit,
+			Remaining: rateLimit.Remaining,
+			Window:    int64(rateLimit.Window.Seconds()),
+			Reset:     int64(rateLimit.Reset.Seconds()),
+		}
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.samples = record(service.samples, sample)
+
+	err = service.dataStore.DockerHub().UpdateRateLimitSamples(service.samples)
+	if err != nil {
+		log.Printf("[WARN] [internal,dockerhublimit] [message: unable to persist the DockerHub rate limit samples] [error: %s]", err)
+	}
+
+	return nil
+}
+
+// load restores the samples recorded before a restart, without the samples older than the retention
+func (service *Service) load(now time.Time) {
+	samples, err := service.dataStore.DockerHub().RateLimitSamples()
+	if err == bolterrors.ErrObjectNotFound {
+		return
+	} else if err != nil {
+		log.Printf("[WARN] [internal,dockerhublimit] [message: unable to load the DockerHub rate limit samples] [error: %s]", err)
+		return
+	}
+
+	oldest := now.Add(-historyRetention).Unix()
+	for len(samples) > 0 && samples[0].Date < oldest {
+		samples = samples[1:]
+	}
+
+	service.mu.Lock()
+	service.samples = append(make([]portainer.DockerHubRateLimitSample, 0, len(samples)), samples...)
+	service.mu.Unlock()
+}
+
+// record appends the sample to the history and removes the samples older than the retention.
+// The history is restarted when the limit changes, e.g. when the DockerHub credentials are updated.
+func record(samples []portainer.DockerHubRateLimitSample, sample portainer.DockerHubRateLimitSample) []portainer.DockerHubRateLimitSample {
+	if len(samples) > 0 {
+		last := samples[len(samples)-1]
+		if last.Unlimited != sample.Unlimited || last.Limit != sample.Limit || last.Window != sample.Window {
+			samples = samples[:0]
+		}
+	}
+
+	samples = append(samples, sample)
+
+	oldest := sample.Date - int64(historyRetention.Seconds())
+	for len(samples) > 0 && samples[0].Date < oldest {
+		samples = samples[1:]
+	}
+
+	return samples
+}
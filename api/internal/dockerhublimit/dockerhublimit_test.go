@@ -0,0 +1,32 @@
+package dockerhublimit
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_load_shouldRestoreTheRecentSamples(t *testing.T) {
+	now := time.Unix(1587399600, 0)
+	samples := []portainer.DockerHubRateLimitSample{
+		{Date: now.Add(-25 * time.Hour).Unix(), Limit: 100, Remaining: 90},
+		{Date: now.Add(-10 * time.Minute).Unix(), Limit: 100, Remaining: 80},
+		{Date: now.Add(-5 * time.Minute).Unix(), Limit: 100, Remaining: 78},
+	}
+	service := NewService(testhelpers.NewDatastore(testhelpers.WithDockerHubRateLimitSamples(samples)), nil)
+
+	service.load(now)
+
+	assert.Equal(t, samples[1:], service.Samples())
+}
+
+func Test_load_shouldStartWithoutRecordedSamples(t *testing.T) {
+	service := NewService(testhelpers.NewDatastore(testhelpers.WithDockerHub(&portainer.DockerHub{})), nil)
+
+	service.load(time.Unix(1587399600, 0))
+
+	assert.Empty(t, service.Samples())
+}
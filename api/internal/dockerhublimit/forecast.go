@@ -0,0 +1,117 @@
+package dockerhublimit
+
+import (
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// forecastPeriod is the period before the last sample used to compute the consumption rate
+const forecastPeriod = 1 * time.Hour
+
+// forecast computes the consumption rate of the limit over the samples of the forecast period and the date
+// at which the remaining pulls of the last sample are exhausted at this rate. The pulls restored in the
+// meantime, by a reset or when pulls leave the window of the limit, are not counted as consumption.
+func forecast(samples []portainer.DockerHubRateLimitSample, now time.Time) portainer.DockerHubRateLimitForecast {
+	if len(samples) == 0 {
+		return portainer.DockerHubRateLimitForecast{
+			Confidence: portainer.DockerHubForecastConfidenceNone,
+			Message:    "No rate limit sample recorded yet",
+		}
+	}
+
+	last := samples[len(samples)-1]
+	recent := recentSamples(samples, last.Date-int64(forecastPeriod.Seconds()))
+
+	if last.Unlimited {
+		return portainer.DockerHubRateLimitForecast{
+			Unlimited:      true,
+			Confidence:     confidence(recent, now),
+			Message:        "No limit applies to the pulls of this DockerHub account",
+			SampleCount:    len(recent),
+			LastSampleDate: last.Date,
+		}
+	}
+
+	result := portainer.DockerHubRateLimitForecast{
+		Limit:          last.Limit,
+		Remaining:      last.Remaining,
+		Window:         last.Window,
+		LastSampleDate: last.Date,
+		SampleCount:    len(recent),
+		Confidence:     confidence(recent, now),
+	}
+
+	if last.Remaining == 0 {
+		result.ExhaustionDate = last.Date
+		result.ExhaustedBeforeReset = true
+		result.Message = "The limit is exhausted"
+		return result
+	}
+
+	if len(recent) < 2 {
+		result.Message = "Not enough samples to compute the consumption rate"
+		return result
+	}
+
+	consumed := 0
+	for idx := 1; idx < len(recent); idx++ {
+		if pulls := recent[idx-1].Remaining - recent[idx].Remaining; pulls > 0 {
+			consumed += pulls
+		}
+	}
+
+	elapsed := time.Duration(last.Date-recent[0].Date) * time.Second
+	if consumed == 0 || elapsed <= 0 {
+		result.Message = "No pull consumed recently, the limit is not exhausted at the current rate"
+		return result
+	}
+
+	result.ConsumptionRate = float64(consumed) / elapsed.Hours()
+
+	timeToExhaustion := time.Duration(float64(last.Remaining) / result.ConsumptionRate * float64(time.Hour))
+	result.ExhaustionDate = last.Date + int64(timeToExhaustion.Seconds())
+	if remaining := result.ExhaustionDate - now.Unix(); remaining > 0 {
+		result.TimeToExhaustion = remaining
+	}
+
+	// the pulls are restored at the reset when DockerHub specifies it, otherwise when they leave the window of the limit
+	reset := last.Reset
+	if reset == 0 {
+		reset = last.Window
+	}
+	result.ExhaustedBeforeReset = reset == 0 || int64(timeToExhaustion.Seconds()) < reset
+
+	return result
+}
+
+// recentSamples returns the samples recorded since the date
+func recentSamples(samples []portainer.DockerHubRateLimitSample, since int64) []portainer.DockerHubRateLimitSample {
+	for idx, sample := range samples {
+		if sample.Date >= since {
+			return samples[idx:]
+		}
+	}
+	return nil
+}
+
+// confidence rates the samples used by a forecast: the samples must regularly cover the forecast period
+// and the last sample must be recent
+func confidence(samples []portainer.DockerHubRateLimitSample, now time.Time) portainer.DockerHubForecastConfidence {
+	if len(samples) < 2 {
+		return portainer.DockerHubForecastConfidenceNone
+	}
+
+	first, last := samples[0], samples[len(samples)-1]
+	covered := time.Duration(last.Date-first.Date) * time.Second
+	outdated := now.Sub(time.Unix(last.Date, 0)) > 2*sampleInterval
+	expected := int(forecastPeriod / sampleInterval)
+
+	switch {
+	case outdated || len(samples) < expected/3 || covered < forecastPeriod/2:
+		return portainer.DockerHubForecastConfidenceLow
+	case len(samples) < expected*2/3:
+		return portainer.DockerHubForecastConfidenceMedium
+	}
+	return portainer.DockerHubForecastConfidenceHigh
+}
@@ -0,0 +1,96 @@
+package dockerhublimit
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func samplesEvery(start time.Time, interval time.Duration, remainings ...int) []portainer.DockerHubRateLimitSample {
+	samples := make([]portainer.DockerHubRateLimitSample, 0, len(remainings))
+	for idx, remaining := range remainings {
+		samples = append(samples, portainer.DockerHubRateLimitSample{
+			Date:      start.Add(time.Duration(idx) * interval).Unix(),
+			Limit:     100,
+			Remaining: remaining,
+			Window:    21600,
+		})
+	}
+	return samples
+}
+
+func Test_forecast(t *testing.T) {
+	start := time.Unix(1587399600, 0)
+	// 13 samples over an hour, 2 pulls restored by the window at the 7th sample
+	samples := samplesEvery(start, sampleInterval, 90, 88, 86, 84, 82, 80, 82, 80, 78, 76, 74, 72, 70)
+	now := start.Add(time.Hour)
+
+	result := forecast(samples, now)
+
+	assert.Equal(t, portainer.DockerHubForecastConfidenceHigh, result.Confidence)
+	assert.Equal(t, 13, result.SampleCount)
+	assert.Equal(t, 70, result.Remaining)
+	assert.Equal(t, 22.0, result.ConsumptionRate)
+	assert.Equal(t, int64(11454), result.TimeToExhaustion)
+	assert.Equal(t, now.Unix()+11454, result.ExhaustionDate)
+	assert.True(t, result.ExhaustedBeforeReset)
+}
+
+func Test_forecast_shouldLowerTheConfidenceOfSparseSamples(t *testing.T) {
+	start := time.Unix(1587399600, 0)
+	samples := samplesEvery(start, 20*time.Minute, 90, 80)
+
+	result := forecast(samples, start.Add(20*time.Minute))
+	assert.Equal(t, portainer.DockerHubForecastConfidenceLow, result.Confidence)
+	assert.Equal(t, 30.0, result.ConsumptionRate)
+
+	result = forecast(samples[:1], start)
+	assert.Equal(t, portainer.DockerHubForecastConfidenceNone, result.Confidence)
+	assert.Zero(t, result.ConsumptionRate)
+	assert.NotEmpty(t, result.Message)
+}
+
+func Test_forecast_shouldNotExhaustWithoutConsumption(t *testing.T) {
+	start := time.Unix(1587399600, 0)
+	samples := samplesEvery(start, sampleInterval, 80, 80, 82)
+
+	result := forecast(samples, start.Add(10*time.Minute))
+	assert.Zero(t, result.ConsumptionRate)
+	assert.Zero(t, result.ExhaustionDate)
+	assert.False(t, result.ExhaustedBeforeReset)
+}
+
+func Test_forecast_shouldStateThatNoLimitApplies(t *testing.T) {
+	start := time.Unix(1587399600, 0)
+	samples := make([]portainer.DockerHubRateLimitSample, 0)
+	for idx := 0; idx < 13; idx++ {
+		samples = append(samples, portainer.DockerHubRateLimitSample{Date: start.Add(time.Duration(idx) * sampleInterval).Unix(), Unlimited: true})
+	}
+
+	result := forecast(samples, start.Add(time.Hour))
+	assert.True(t, result.Unlimited)
+	assert.Zero(t, result.ConsumptionRate)
+	assert.Contains(t, result.Message, "No limit applies")
+	assert.Equal(t, 13, result.SampleCount)
+	assert.Equal(t, portainer.DockerHubForecastConfidenceHigh, result.Confidence)
+
+	result = forecast(samples[:1], start)
+	assert.True(t, result.Unlimited)
+	assert.Equal(t, 1, result.SampleCount)
+	assert.Equal(t, portainer.DockerHubForecastConfidenceNone, result.Confidence)
+
+	result = forecast(samples, start.Add(24*time.Hour))
+	assert.Equal(t, portainer.DockerHubForecastConfidenceLow, result.Confidence)
+}
+
+func Test_record_shouldRestartTheHistoryWhenTheLimitChanges(t *testing.T) {
+	samples := samplesEvery(time.Unix(1587399600, 0), sampleInterval, 90, 88)
+
+	samples = record(samples, portainer.DockerHubRateLimitSample{Date: 1587400500, Limit: 200, Remaining: 200, Window: 21600})
+	assert.Len(t, samples, 1)
+
+	samples = record(samples, portainer.DockerHubRateLimitSample{Date: 1587400500 + int64(historyRetention.Seconds()) + 1, Limit: 200, Remaining: 190, Window: 21600})
+	assert.Len(t, samples, 1)
+}
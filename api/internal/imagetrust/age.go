@@ -0,0 +1,137 @@
+package imagetrust
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	portainer "github.com/portainer/portainer/api"
+)
+
+var (
+	errInvalidAgePolicy    = errors.New("Invalid image age policy. Max age must be a valid positive duration and mode must be warn or block")
+	errUnsupportedManifest = errors.New("Unsupported image manifest, the creation date of the image is not available")
+)
+
+// AgeError is returned when an image is older than the maximum age of the image age policy,
+// or when the creation date of an image cannot be retrieved while the policy blocks the images of unknown age
+type AgeError struct {
+	Image   string
+	Created time.Time
+	MaxAge  time.Duration
+	Err     error
+}
+
+func (err *AgeError) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("Image %s rejected by the image age policy: unable to retrieve the creation date of the image: %s", err.Image, err.Err)
+	}
+	return fmt.Sprintf("Image %s rejected by the image age policy: created on %s, more than %s ago", err.Image, err.Created.UTC().Format(time.RFC3339), err.MaxAge)
+}
+
+// ValidateAgePolicy verifies the maximum age and the mode of an image age policy.
+// The maximum age is only required when the policy is enabled.
+func ValidateAgePolicy(policy *portainer.ImageAgePolicy) error {
+	if policy.Mode != "" && policy.Mode != portainer.ImageAgePolicyWarn && policy.Mode != portainer.ImageAgePolicyBlock {
+		return errInvalidAgePolicy
+	}
+
+	if !policy.Enabled && policy.MaxAge == "" {
+		return nil
+	}
+
+	maxAge, err := time.ParseDuration(policy.MaxAge)
+	if err != nil || maxAge <= 0 {
+		return errInvalidAgePolicy
+	}
+
+	return nil
+}
+
+// ImageCreated returns the creation date of an image, read from the configuration referenced by its manifest in the registry.
+// The manifest of the linux/amd64 platform is used for multi-platform images.
+func (verifier *Verifier) ImageCreated(image string) (time.Time, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return time.Time{}, err
+	}
+	named = reference.TagNameOnly(named)
+
+	cacheKey := "created:" + named.String()
+	if result := verifier.cachedResult(cacheKey); result != nil {
+		return result.created, nil
+	}
+
+	registry := reference.Domain(named)
+
+	credentials, err := verifier.registryCredentials(registry)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	client := newRegistryClient(verifier.httpClient, registry, reference.Path(named), credentials)
+
+	manifestReference := ""
+	if digested, ok := named.(reference.Digested); ok {
+		manifestReference = digested.Digest().String()
+	} else {
+		manifestReference = named.(reference.Tagged).Tag()
+	}
+
+	created, err := client.imageCreated(manifestReference)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	verifier.store(cacheKey, cachedVerification{created: created})
+
+	return created, nil
+}
+
+// verifyImageAges checks the creation date of each image against the age policy when it is enabled.
+// In block mode, the images older than the maximum age are rejected with an *AgeError, as well as the images whose
+// creation date cannot be retrieved from their registry unless the policy allows them. In warn mode, a warning is
+// returned for each of these images.
+func (verifier *Verifier) verifyImageAges(images []string, policy *portainer.ImageAgePolicy) ([]string, error) {
+	if !policy.Enabled {
+		return nil, nil
+	}
+
+	maxAge, err := time.ParseDuration(policy.MaxAge)
+	if err != nil || maxAge <= 0 {
+		return nil, nil
+	}
+
+	block := policy.Mode == portainer.ImageAgePolicyBlock
+
+	warnings := make([]string, 0)
+	for _, image := range images {
+		created, err := verifier.ImageCreated(image)
+		if err != nil {
+			if block && !policy.AllowUnknownAge {
+				return nil, &AgeError{Image: image, MaxAge: maxAge, Err: err}
+			}
+
+			log.Printf("[WARN] [internal,imagetrust] [image: %s] [message: unable to retrieve the creation date of the image, the image age policy is not applied] [error: %s]", image, err)
+			if !block {
+				warnings = append(warnings, fmt.Sprintf("Unable to retrieve the creation date of image %s, its age was not verified", image))
+			}
+			continue
+		}
+
+		if time.Since(created) <= maxAge {
+			continue
+		}
+
+		if block {
+			return nil, &AgeError{Image: image, Created: created, MaxAge: maxAge}
+		}
+
+		log.Printf("[WARN] [internal,imagetrust] [image: %s] [created: %s] [max_age: %s] [message: deploying an image older than the maximum age of the image age policy]", image, created.UTC().Format(time.RFC3339), maxAge)
+		warnings = append(warnings, fmt.Sprintf("Image %s was created on %s, more than %s ago", image, created.UTC().Format(time.RFC3339), maxAge))
+	}
+
+	return warnings, nil
+}
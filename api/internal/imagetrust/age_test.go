@@ -0,0 +1,83 @@
+package imagetrust
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_registryClient_imageCreated_shouldUseTheLinuxAmd64Manifest(t *testing.T) {
+	config := []byte(`{"created":"2020-04-20T16:20:00Z","architecture":"amd64"}`)
+	configDigest := sha256Digest(config)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/library/nginx/manifests/1.19":
+			fmt.Fprint(w, `{"manifests":[{"digest":"sha256:arm","platform":{"architecture":"arm64","os":"linux"}},{"digest":"sha256:amd","platform":{"architecture":"amd64","os":"linux"}}]}`)
+		case "/v2/library/nginx/manifests/sha256:amd":
+			fmt.Fprintf(w, `{"config":{"digest":"%s"}}`, configDigest)
+		case "/v2/library/nginx/blobs/" + configDigest:
+			w.Write(config)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newRegistryClient(server.Client(), server.URL, "library/nginx", nil)
+
+	created, err := client.imageCreated("1.19")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 4, 20, 16, 20, 0, 0, time.UTC), created.UTC())
+}
+
+func Test_ValidateAgePolicy(t *testing.T) {
+	assert.NoError(t, ValidateAgePolicy(&portainer.ImageAgePolicy{}))
+	assert.NoError(t, ValidateAgePolicy(&portainer.ImageAgePolicy{Enabled: true, MaxAge: "2160h", Mode: portainer.ImageAgePolicyBlock}))
+	assert.Error(t, ValidateAgePolicy(&portainer.ImageAgePolicy{Enabled: true}))
+	assert.Error(t, ValidateAgePolicy(&portainer.ImageAgePolicy{Enabled: true, MaxAge: "2160h", Mode: "deny"}))
+}
+
+func Test_verifyImageAges_shouldReturnAWarningForTheOldImagesInWarnMode(t *testing.T) {
+	verifier := NewVerifier(nil)
+	verifier.store("created:docker.io/library/nginx:1.19", cachedVerification{created: time.Now().Add(-48 * time.Hour)})
+	verifier.store("created:docker.io/library/nginx:latest", cachedVerification{created: time.Now()})
+
+	policy := &portainer.ImageAgePolicy{Enabled: true, MaxAge: "24h", Mode: portainer.ImageAgePolicyWarn}
+
+	warnings, err := verifier.verifyImageAges([]string{"nginx:1.19", "nginx:latest", "INVALID"}, policy)
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 2)
+	assert.Contains(t, warnings[0], "nginx:1.19")
+	assert.Contains(t, warnings[1], "INVALID")
+}
+
+func Test_verifyImageAges_shouldRejectTheImagesOfUnknownAgeInBlockMode(t *testing.T) {
+	verifier := NewVerifier(nil)
+
+	policy := &portainer.ImageAgePolicy{Enabled: true, MaxAge: "24h", Mode: portainer.ImageAgePolicyBlock}
+
+	_, err := verifier.verifyImageAges([]string{"INVALID"}, policy)
+	assert.IsType(t, &AgeError{}, err)
+
+	policy.AllowUnknownAge = true
+
+	warnings, err := verifier.verifyImageAges([]string{"INVALID"}, policy)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func Test_verifyImageAges_shouldRejectTheOldImagesInBlockMode(t *testing.T) {
+	verifier := NewVerifier(nil)
+	verifier.store("created:docker.io/library/nginx:1.19", cachedVerification{created: time.Now().Add(-48 * time.Hour)})
+
+	policy := &portainer.ImageAgePolicy{Enabled: true, MaxAge: "24h", Mode: portainer.ImageAgePolicyBlock}
+
+	_, err := verifier.verifyImageAges([]string{"nginx:1.19"}, policy)
+	assert.IsType(t, &AgeError{}, err)
+}
@@ -0,0 +1,143 @@
+package imagetrust
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/portainer/portainer/api/internal/registryclient"
+)
+
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+var errInvalidPublicKey = errors.New("Invalid public key. Must be a PEM encoded ECDSA, RSA or Ed25519 public key")
+
+type (
+	signatureManifest struct {
+		Layers []struct {
+			Digest      string            `json:"digest"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}
+
+	// simpleSigningPayload is the payload signed by cosign, it references the digest of the signed image manifest
+	simpleSigningPayload struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+			Type string `json:"type"`
+		} `json:"critical"`
+	}
+)
+
+// ParsePublicKeys parses a list of PEM encoded public keys
+func ParsePublicKeys(encodedKeys []string) ([]crypto.PublicKey, error) {
+	keys := make([]crypto.PublicKey, 0, len(encodedKeys))
+
+	for _, encodedKey := range encodedKeys {
+		block, _ := pem.Decode([]byte(strings.TrimSpace(encodedKey)))
+		if block == nil {
+			return nil, errInvalidPublicKey
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errInvalidPublicKey
+		}
+
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+			keys = append(keys, key)
+		default:
+			return nil, errInvalidPublicKey
+		}
+	}
+
+	return keys, nil
+}
+
+// verifyCosignSignature verifies that the image manifest referenced by digest is signed by one of the keys,
+// using the signatures stored by cosign in the repository (sha256-<hex>.sig tag).
+func verifyCosignSignature(client *registryClient, digest string, keys []crypto.PublicKey) error {
+	signatureTag := strings.Replace(digest, ":", "-", 1) + ".sig"
+
+	var manifest signatureManifest
+	err := client.manifest(signatureTag, &manifest)
+	if err == registryclient.ErrNotFound {
+		return ErrImageNotSigned
+	} else if err != nil {
+		return err
+	}
+
+	for _, layer := range manifest.Layers {
+		encodedSignature := layer.Annotations[cosignSignatureAnnotation]
+		if encodedSignature == "" {
+			continue
+		}
+
+		signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+		if err != nil {
+			continue
+		}
+
+		payload, err := client.blob(layer.Digest)
+		if err != nil {
+			return err
+		}
+
+		if !payloadReferencesDigest(payload, digest) {
+			continue
+		}
+
+		for _, key := range keys {
+			if verifySignature(key, payload, signature) {
+				return nil
+			}
+		}
+	}
+
+	return ErrImageNotTrusted
+}
+
+func payloadReferencesDigest(payload []byte, digest string) bool {
+	var signingPayload simpleSigningPayload
+	err := json.Unmarshal(payload, &signingPayload)
+	if err != nil {
+		return false
+	}
+
+	return signingPayload.Critical.Image.DockerManifestDigest == digest
+}
+
+func verifySignature(key crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+
+	switch publicKey := key.(type) {
+	case *ecdsa.PublicKey:
+		var ecdsaSignature struct {
+			R, S *big.Int
+		}
+		_, err := asn1.Unmarshal(signature, &ecdsaSignature)
+		if err != nil || ecdsaSignature.R == nil || ecdsaSignature.S == nil {
+			return false
+		}
+		return ecdsa.Verify(publicKey, hash[:], ecdsaSignature.R, ecdsaSignature.S)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(publicKey, payload, signature)
+	}
+
+	return false
+}
@@ -0,0 +1,260 @@
+package imagetrust
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	godigest "github.com/opencontainers/go-digest"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/registryclient"
+)
+
+const (
+	verificationCacheDuration = 5 * time.Minute
+	registryRequestTimeout    = 30 * time.Second
+	anyRegistry               = "*"
+)
+
+var (
+	// ErrImageNotSigned is returned when no signature can be found for an image
+	ErrImageNotSigned = errors.New("Image is not signed")
+	// ErrImageNotTrusted is returned when none of the signatures of an image is made with a trusted key
+	ErrImageNotTrusted = errors.New("Image is not signed with a trusted key")
+	// ErrNoTrustRoot is returned when no trust root is defined for the registry hosting an image
+	ErrNoTrustRoot = errors.New("No trust root defined for the image registry")
+)
+
+type (
+	// VerificationError is returned when an image is rejected by the trust policy
+	VerificationError struct {
+		Image string
+		Err   error
+	}
+
+	// Verifier verifies the signatures of images against the trust policy defined in the settings.
+	// Verification results are cached per image digest for a short period of time to avoid verifying
+	// the same images on every deployment.
+	Verifier struct {
+		dataStore  portainer.DataStore
+		httpClient *http.Client
+		mu         sync.Mutex
+		cache      map[string]cachedVerification
+	}
+
+	cachedVerification struct {
+		err       error
+		created   time.Time
+		expiresAt time.Time
+	}
+)
+
+func (err *VerificationError) Error() string {
+	return fmt.Sprintf("Image %s rejected by the image trust policy: %s", err.Image, err.Err)
+}
+
+// NewVerifier creates a new image signature verifier
+func NewVerifier(dataStore portainer.DataStore) *Verifier {
+	return &Verifier{
+		dataStore:  dataStore,
+		httpClient: &http.Client{Timeout: registryRequestTimeout},
+		cache:      make(map[string]cachedVerification),
+	}
+}
+
+// VerifyImages verifies each image against the trust policy and the age policy when they are enabled.
+// The images verified by the trust policy are returned pinned to the digest that was verified, per image,
+// so that they can be deployed without the tags being moved to another image after the verification.
+// The warnings raised by the age policy in warn mode are returned with the pinned images.
+// A *VerificationError is returned for the first image rejected by the trust policy,
+// an *AgeError for the first image rejected by the age policy.
+func (verifier *Verifier) VerifyImages(images []string) (map[string]string, []string, error) {
+	settings, err := verifier.dataStore.Settings().Settings()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pinned := make(map[string]string)
+
+	policy := &settings.ImageTrustPolicy
+	if policy.Enabled {
+		for _, image := range images {
+			pinnedImage, err := verifier.verifyImage(image, policy)
+			if err != nil {
+				return nil, nil, &VerificationError{Image: image, Err: err}
+			}
+			pinned[image] = pinnedImage
+		}
+	}
+
+	warnings, err := verifier.verifyImageAges(images, &settings.ImageAgePolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pinned, warnings, nil
+}
+
+// verifyImage verifies the signature of the image and returns the image pinned to the digest that was verified
+func (verifier *Verifier) verifyImage(image string, policy *portainer.ImageTrustPolicy) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+	named = reference.TagNameOnly(named)
+
+	registry := reference.Domain(named)
+
+	trustRoot := findTrustRoot(policy, registry)
+	if trustRoot == nil {
+		return "", ErrNoTrustRoot
+	}
+
+	credentials, err := verifier.registryCredentials(registry)
+	if err != nil {
+		return "", err
+	}
+
+	client := newRegistryClient(verifier.httpClient, registry, reference.Path(named), credentials)
+
+	digest := ""
+	if digested, ok := named.(reference.Digested); ok {
+		digest = digested.Digest().String()
+	} else {
+		digest, err = client.manifestDigest(named.(reference.Tagged).Tag())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	pinnedImage, err := pinImage(named, digest)
+	if err != nil {
+		return "", err
+	}
+
+	cacheKey := verificationCacheKey(reference.TrimNamed(named).String()+"@"+digest, trustRoot)
+	if result := verifier.cachedResult(cacheKey); result != nil {
+		return pinnedImage, result.err
+	}
+
+	keys, err := ParsePublicKeys(trustRoot.PublicKeys)
+	if err != nil {
+		return "", err
+	}
+
+	err = verifyCosignSignature(client, digest, keys)
+	if err == nil || err == ErrImageNotSigned || err == ErrImageNotTrusted {
+		verifier.cacheResult(cacheKey, err)
+	}
+
+	return pinnedImage, err
+}
+
+// pinImage returns the reference of an image pinned to a digest, keeping its tag for readability
+func pinImage(named reference.Named, digest string) (string, error) {
+	if _, ok := named.(reference.Digested); ok {
+		return named.String(), nil
+	}
+
+	parsedDigest, err := godigest.Parse(digest)
+	if err != nil {
+		return "", err
+	}
+
+	pinned, err := reference.WithDigest(named, parsedDigest)
+	if err != nil {
+		return "", err
+	}
+
+	return pinned.String(), nil
+}
+
+func (verifier *Verifier) cachedResult(key string) *cachedVerification {
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+
+	result, ok := verifier.cache[key]
+	if !ok || time.Now().After(result.expiresAt) {
+		delete(verifier.cache, key)
+		return nil
+	}
+
+	return &result
+}
+
+func (verifier *Verifier) cacheResult(key string, err error) {
+	verifier.store(key, cachedVerification{err: err})
+}
+
+func (verifier *Verifier) store(key string, result cachedVerification) {
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+
+	now := time.Now()
+	for cacheKey, result := range verifier.cache {
+		if now.After(result.expiresAt) {
+			delete(verifier.cache, cacheKey)
+		}
+	}
+
+	result.expiresAt = now.Add(verificationCacheDuration)
+	verifier.cache[key] = result
+}
+
+// registryCredentials returns the credentials defined in Portainer for the registry, if any.
+func (verifier *Verifier) registryCredentials(registry string) (*registryclient.Credentials, error) {
+	if registry == registryclient.DockerHubRegistry {
+		dockerhub, err := verifier.dataStore.DockerHub().DockerHub()
+		if err != nil {
+			return nil, err
+		}
+
+		if dockerhub.Authentication {
+			return &registryclient.Credentials{Username: dockerhub.Username, Password: dockerhub.Password}, nil
+		}
+		return nil, nil
+	}
+
+	registries, err := verifier.dataStore.Registry().Registries()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, portainerRegistry := range registries {
+		if portainerRegistry.Authentication && strings.EqualFold(registryclient.NormalizeRegistry(portainerRegistry.URL), registry) {
+			return &registryclient.Credentials{Username: portainerRegistry.Username, Password: portainerRegistry.Password}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func findTrustRoot(policy *portainer.ImageTrustPolicy, registry string) *portainer.ImageTrustRoot {
+	var fallback *portainer.ImageTrustRoot
+
+	for idx := range policy.TrustRoots {
+		trustRoot := &policy.TrustRoots[idx]
+
+		if trustRoot.Registry == anyRegistry {
+			fallback = trustRoot
+		} else if strings.EqualFold(registryclient.NormalizeRegistry(trustRoot.Registry), registry) {
+			return trustRoot
+		}
+	}
+
+	return fallback
+}
+
+// verificationCacheKey identifies a verification result using the image digest and the trust root,
+// so that updating the trust policy invalidates the previous results.
+func verificationCacheKey(image string, trustRoot *portainer.ImageTrustRoot) string {
+	data, _ := json.Marshal(trustRoot)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s@%x", image, sum)
+}
@@ -0,0 +1,83 @@
+package imagetrust
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+
+	"github.com/docker/distribution/reference"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_findTrustRoot(t *testing.T) {
+	policy := &portainer.ImageTrustPolicy{
+		TrustRoots: []portainer.ImageTrustRoot{
+			{Registry: "*"},
+			{Registry: "https://registry.example.com/"},
+			{Registry: "index.docker.io"},
+		},
+	}
+
+	assert.Equal(t, "https://registry.example.com/", findTrustRoot(policy, "registry.example.com").Registry)
+	assert.Equal(t, "index.docker.io", findTrustRoot(policy, "docker.io").Registry)
+	assert.Equal(t, "*", findTrustRoot(policy, "quay.io").Registry)
+
+	policy.TrustRoots = policy.TrustRoots[1:]
+	assert.Nil(t, findTrustRoot(policy, "quay.io"))
+}
+
+func Test_verifySignature_shouldVerifyECDSASignatures(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	encodedKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	assert.NoError(t, err)
+
+	keys, err := ParsePublicKeys([]string{string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: encodedKey}))})
+	assert.NoError(t, err)
+
+	payload := []byte(`{"critical":{"image":{"docker-manifest-digest":"sha256:abc"}}}`)
+	hash := sha256.Sum256(payload)
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, hash[:])
+	assert.NoError(t, err)
+
+	signature, err := asn1.Marshal(struct{ R, S interface{} }{r, s})
+	assert.NoError(t, err)
+
+	assert.True(t, verifySignature(keys[0], payload, signature))
+	assert.False(t, verifySignature(keys[0], []byte("tampered"), signature))
+	assert.True(t, payloadReferencesDigest(payload, "sha256:abc"))
+}
+
+func Test_ParsePublicKeys_shouldRejectInvalidKeys(t *testing.T) {
+	_, err := ParsePublicKeys([]string{"not a key"})
+	assert.Equal(t, errInvalidPublicKey, err)
+}
+
+func Test_pinImage(t *testing.T) {
+	digest := "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"
+
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"nginx:1.19", "docker.io/library/nginx:1.19@" + digest},
+		{"registry.example.com:5000/team/app", "registry.example.com:5000/team/app@" + digest},
+		{"nginx@" + digest, "docker.io/library/nginx@" + digest},
+	}
+
+	for _, test := range tests {
+		named, err := reference.ParseNormalizedNamed(test.image)
+		assert.NoError(t, err)
+
+		pinned, err := pinImage(named, digest)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, pinned, test.image)
+	}
+}
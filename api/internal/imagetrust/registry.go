@@ -0,0 +1,165 @@
+package imagetrust
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/portainer/portainer/api/internal/registryclient"
+)
+
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// imageManifest represents the fields of an image manifest, or of a manifest list, used to find the configuration of an image
+type imageManifest struct {
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []platformManifest `json:"manifests"`
+}
+
+type platformManifest struct {
+	Digest   string `json:"digest"`
+	Platform struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform"`
+}
+
+// registryClient retrieves manifests and blobs of a single repository.
+type registryClient struct {
+	client     *registryclient.Client
+	repository string
+}
+
+func newRegistryClient(httpClient *http.Client, registry, repository string, credentials *registryclient.Credentials) *registryClient {
+	return &registryClient{
+		client:     registryclient.NewClient(httpClient, registry, credentials),
+		repository: repository,
+	}
+}
+
+// manifestDigest returns the digest of the manifest referenced by a tag or a digest.
+func (client *registryClient) manifestDigest(reference string) (string, error) {
+	response, err := client.get("/manifests/"+reference, manifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if digest := response.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	content, err := registryclient.ReadLimited(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return sha256Digest(content), nil
+}
+
+// manifest retrieves and decodes the manifest referenced by a tag or a digest.
+func (client *registryClient) manifest(reference string, manifest interface{}) error {
+	response, err := client.get("/manifests/"+reference, manifestMediaTypes)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	content, err := registryclient.ReadLimited(response.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, manifest)
+}
+
+// imageCreated returns the creation date recorded in the configuration of the image referenced by a tag or a digest
+func (client *registryClient) imageCreated(reference string) (time.Time, error) {
+	var manifest imageManifest
+	err := client.manifest(reference, &manifest)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(manifest.Manifests) > 0 {
+		digest := defaultPlatformManifest(manifest.Manifests)
+
+		manifest = imageManifest{}
+		err = client.manifest(digest, &manifest)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if manifest.Config.Digest == "" {
+		return time.Time{}, errUnsupportedManifest
+	}
+
+	content, err := client.blob(manifest.Config.Digest)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var config struct {
+		Created time.Time `json:"created"`
+	}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if config.Created.IsZero() {
+		return time.Time{}, errUnsupportedManifest
+	}
+
+	return config.Created, nil
+}
+
+// defaultPlatformManifest returns the digest of the linux/amd64 manifest of a manifest list, or of its first manifest
+func defaultPlatformManifest(manifests []platformManifest) string {
+	for _, manifest := range manifests {
+		if manifest.Platform.OS == "linux" && manifest.Platform.Architecture == "amd64" {
+			return manifest.Digest
+		}
+	}
+	return manifests[0].Digest
+}
+
+// blob retrieves a blob and verifies that its content matches its digest.
+func (client *registryClient) blob(digest string) ([]byte, error) {
+	response, err := client.get("/blobs/"+digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	content, err := registryclient.ReadLimited(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if sha256Digest(content) != digest {
+		return nil, fmt.Errorf("Digest mismatch for blob %s", digest)
+	}
+
+	return content, nil
+}
+
+func (client *registryClient) get(path string, accept []string) (*http.Response, error) {
+	return client.client.Get("/"+client.repository+path, registryclient.RepositoryScope(client.repository), accept)
+}
+
+func sha256Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
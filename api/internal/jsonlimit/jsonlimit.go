@@ -0,0 +1,84 @@
+package jsonlimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Limits represents the limits enforced on a JSON document, a limit is disabled when 0
+type Limits struct {
+	// Maximum nesting depth of the objects and arrays
+	MaxDepth int
+	// Maximum number of tokens, each object key, value and delimiter being a token
+	MaxTokens int
+}
+
+// LimitError is returned when a JSON document exceeds one of the limits
+type LimitError struct {
+	Limit string
+	Value int
+}
+
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("JSON payload exceeds the maximum %s of %d", err.Limit, err.Value)
+}
+
+// Enabled returns true when at least one of the limits is enforced
+func (limits Limits) Enabled() bool {
+	return limits.MaxDepth > 0 || limits.MaxTokens > 0
+}
+
+// Check reads the JSON document token by token and returns a *LimitError as soon as one of the limits is exceeded,
+// without deserializing the document. Syntax errors are not reported as they are left to the decoding of the document.
+func Check(reader io.Reader, limits Limits) error {
+	decoder := json.NewDecoder(reader)
+
+	depth := 0
+	tokens := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+
+		tokens++
+		if limits.MaxTokens > 0 && tokens > limits.MaxTokens {
+			return &LimitError{Limit: "number of tokens", Value: limits.MaxTokens}
+		}
+
+		delim, ok := token.(json.Delim)
+		if !ok {
+			continue
+		}
+
+		switch delim {
+		case '{', '[':
+			depth++
+			if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+				return &LimitError{Limit: "nesting depth", Value: limits.MaxDepth}
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+}
+
+// CheckBody checks the JSON document of a request body against the limits while reading it, an abusive body is
+// rejected as soon as a limit is exceeded. It returns a reader replaying the body to use in place of the consumed body.
+func CheckBody(body io.ReadCloser, limits Limits) (io.ReadCloser, error) {
+	var consumed bytes.Buffer
+
+	err := Check(io.TeeReader(body, &consumed), limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &replayedBody{Reader: io.MultiReader(&consumed, body), Closer: body}, nil
+}
+
+type replayedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,35 @@
+package jsonlimit
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Check(t *testing.T) {
+	limits := Limits{MaxDepth: 3, MaxTokens: 20}
+
+	assert.NoError(t, Check(strings.NewReader(`{"Name":"stack","Env":[{"name":"A","value":"1"}]}`), limits))
+
+	err := Check(strings.NewReader(`{"a":{"b":{"c":{"d":1}}}}`), limits)
+	assert.Equal(t, &LimitError{Limit: "nesting depth", Value: 3}, err)
+
+	err = Check(strings.NewReader(`[`+strings.Repeat(`1,`, 30)+`1]`), limits)
+	assert.Equal(t, &LimitError{Limit: "number of tokens", Value: 20}, err)
+
+	assert.NoError(t, Check(strings.NewReader(strings.Repeat("[", 100)), Limits{}), "limits are disabled when 0")
+	assert.NoError(t, Check(strings.NewReader(`{"a":`), limits), "syntax errors are left to the decoding")
+}
+
+func Test_CheckBody_ReplaysBody(t *testing.T) {
+	payload := `{"Name":"stack","StackFileContent":"version: '3'"}`
+
+	body, err := CheckBody(ioutil.NopCloser(strings.NewReader(payload)), Limits{MaxDepth: 2})
+	assert.NoError(t, err)
+
+	content, err := ioutil.ReadAll(body)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, string(content))
+}
@@ -0,0 +1,114 @@
+package logdriver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// commonOptions are the options supported by all the logging drivers shipping the logs
+var commonOptions = []string{"tag", "labels", "labels-regex", "env", "env-regex", "mode", "max-buffer-size"}
+
+// driverOptions are the options supported by each of the built-in logging drivers of Docker
+var driverOptions = map[string][]string{
+	"none":      {},
+	"json-file": {"max-size", "max-file", "compress", "labels", "labels-regex", "env", "env-regex", "tag", "mode", "max-buffer-size"},
+	"local":     {"max-size", "max-file", "compress", "mode", "max-buffer-size"},
+	"journald":  {"tag", "labels", "labels-regex", "env", "env-regex", "mode", "max-buffer-size"},
+	"etwlogs":   {"mode", "max-buffer-size"},
+	"syslog": {"syslog-address", "syslog-facility", "syslog-tls-ca-cert", "syslog-tls-cert", "syslog-tls-key",
+		"syslog-tls-skip-verify", "syslog-format"},
+	"gelf": {"gelf-address", "gelf-compression-type", "gelf-compression-level", "gelf-tcp-max-reconnect",
+		"gelf-tcp-reconnect-delay"},
+	"fluentd": {"fluentd-address", "fluentd-async", "fluentd-async-connect", "fluentd-buffer-limit",
+		"fluentd-retry-wait", "fluentd-max-retries", "fluentd-sub-second-precision", "fluentd-request-ack"},
+	"awslogs": {"awslogs-region", "awslogs-endpoint", "awslogs-group", "awslogs-stream", "awslogs-create-group",
+		"awslogs-datetime-format", "awslogs-multiline-pattern", "awslogs-credentials-endpoint", "awslogs-force-flush-interval-seconds",
+		"awslogs-max-buffered-events"},
+	"splunk": {"splunk-token", "splunk-url", "splunk-source", "splunk-sourcetype", "splunk-index", "splunk-capath",
+		"splunk-caname", "splunk-insecureskipverify", "splunk-format", "splunk-verify-connection", "splunk-gzip",
+		"splunk-gzip-level", "splunk-index-acknowledgment"},
+	"gcplogs":    {"gcp-project", "gcp-log-cmd", "gcp-meta-zone", "gcp-meta-name", "gcp-meta-id"},
+	"logentries": {"logentries-token", "line-only"},
+}
+
+// requiredOptions are the options that must be specified for a logging driver
+var requiredOptions = map[string][]string{
+	"gelf":       {"gelf-address"},
+	"splunk":     {"splunk-token", "splunk-url"},
+	"logentries": {"logentries-token"},
+}
+
+// sensitiveOptions are the options holding credentials, which are redacted when the logging driver of a container is inspected
+var sensitiveOptions = map[string]bool{
+	"splunk-token":     true,
+	"logentries-token": true,
+}
+
+// IsPlugin returns true when the driver is a logging driver plugin, e.g. grafana/loki-docker-driver:latest,
+// the options of the plugins are not validated
+func IsPlugin(driver string) bool {
+	return strings.ContainsAny(driver, "/:")
+}
+
+// Validate verifies that the driver is a known logging driver and that its options are supported by the driver
+func Validate(config *portainer.LogConfig) error {
+	if config.Driver == "" {
+		return errors.New("Invalid logging driver. Driver is required")
+	}
+
+	if IsPlugin(config.Driver) {
+		return nil
+	}
+
+	options, ok := driverOptions[config.Driver]
+	if !ok {
+		return fmt.Errorf("Invalid logging driver %s", config.Driver)
+	}
+
+	supported := make(map[string]bool)
+	for _, option := range options {
+		supported[option] = true
+	}
+	if config.Driver != "none" && config.Driver != "local" && config.Driver != "etwlogs" {
+		for _, option := range commonOptions {
+			supported[option] = true
+		}
+	}
+
+	keys := make([]string, 0, len(config.Options))
+	for key := range config.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !supported[key] {
+			return fmt.Errorf("Invalid option %s for logging driver %s", key, config.Driver)
+		}
+	}
+
+	for _, key := range requiredOptions[config.Driver] {
+		if config.Options[key] == "" {
+			return fmt.Errorf("Invalid logging driver %s. Option %s is required", config.Driver, key)
+		}
+	}
+
+	return nil
+}
+
+// Redact returns a copy of the logging driver where the options holding credentials are redacted
+func Redact(config portainer.LogConfig) portainer.LogConfig {
+	options := make(map[string]string, len(config.Options))
+	for key, value := range config.Options {
+		if sensitiveOptions[key] {
+			value = "********"
+		}
+		options[key] = value
+	}
+	config.Options = options
+	return config
+}
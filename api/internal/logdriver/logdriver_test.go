@@ -0,0 +1,31 @@
+package logdriver
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate(t *testing.T) {
+	valid := []portainer.LogConfig{
+		{Driver: "json-file", Options: map[string]string{"max-size": "10m", "max-file": "3"}},
+		{Driver: "fluentd", Options: map[string]string{"fluentd-address": "fluentd:24224", "tag": "web"}},
+		{Driver: "gelf", Options: map[string]string{"gelf-address": "udp://graylog:12201"}},
+		{Driver: "grafana/loki-docker-driver:latest", Options: map[string]string{"loki-url": "http://loki:3100"}},
+	}
+	for _, config := range valid {
+		assert.NoError(t, Validate(&config), config.Driver)
+	}
+
+	invalid := []portainer.LogConfig{
+		{Driver: ""},
+		{Driver: "unknown"},
+		{Driver: "json-file", Options: map[string]string{"fluentd-address": "fluentd:24224"}},
+		{Driver: "local", Options: map[string]string{"tag": "web"}},
+		{Driver: "gelf"},
+	}
+	for _, config := range invalid {
+		assert.Error(t, Validate(&config), config.Driver)
+	}
+}
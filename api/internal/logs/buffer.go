@@ -0,0 +1,60 @@
+package logs
+
+import (
+	"strings"
+	"sync"
+)
+
+// Buffer is an io.Writer that keeps the most recent log entries in memory.
+// It is meant to be used alongside the standard log output via io.MultiWriter
+// so that recent server logs can be retrieved at runtime.
+type Buffer struct {
+	mu       sync.Mutex
+	entries  []string
+	next     int
+	full     bool
+	capacity int
+}
+
+// NewBuffer creates a new log buffer retaining at most capacity entries
+func NewBuffer(capacity int) *Buffer {
+	return &Buffer{
+		entries:  make([]string, capacity),
+		capacity: capacity,
+	}
+}
+
+// Write stores a log entry in the buffer, overwriting the oldest entry when the buffer is full.
+// The standard logger issues a single Write call per log entry.
+func (buffer *Buffer) Write(p []byte) (int, error) {
+	if buffer.capacity == 0 {
+		return len(p), nil
+	}
+
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	buffer.entries[buffer.next] = strings.TrimSuffix(string(p), "\n")
+	buffer.next = (buffer.next + 1) % buffer.capacity
+	if buffer.next == 0 {
+		buffer.full = true
+	}
+
+	return len(p), nil
+}
+
+// Entries returns the buffered log entries, from the oldest to the most recent
+func (buffer *Buffer) Entries() []string {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	if !buffer.full {
+		entries := make([]string, buffer.next)
+		copy(entries, buffer.entries[:buffer.next])
+		return entries
+	}
+
+	entries := make([]string, 0, buffer.capacity)
+	entries = append(entries, buffer.entries[buffer.next:]...)
+	return append(entries, buffer.entries[:buffer.next]...)
+}
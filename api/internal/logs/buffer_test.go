@@ -0,0 +1,21 @@
+package logs
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffer_Entries(t *testing.T) {
+	buffer := NewBuffer(3)
+	assert.Empty(t, buffer.Entries())
+
+	fmt.Fprintln(buffer, "one")
+	fmt.Fprintln(buffer, "two")
+	assert.Equal(t, []string{"one", "two"}, buffer.Entries())
+
+	fmt.Fprintln(buffer, "three")
+	fmt.Fprintln(buffer, "four")
+	assert.Equal(t, []string{"two", "three", "four"}, buffer.Entries())
+}
@@ -0,0 +1,212 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
+)
+
+const (
+	dialTimeout       = 10 * time.Second
+	connectionTimeout = 30 * time.Second
+)
+
+// ErrNotConfigured is returned when an email is sent while no SMTP server is configured
+var ErrNotConfigured = errors.New("No SMTP server configured")
+
+type (
+	// Service is used to send emails through the SMTP server defined in the settings
+	Service struct {
+		dataStore     portainer.DataStore
+		encryptionKey []byte
+	}
+
+	// Message represents an email
+	Message struct {
+		To      []string
+		Subject string
+		Body    string
+	}
+)
+
+// NewService creates a new mailer. The encryption key is used to encrypt the SMTP password when it is stored.
+func NewService(dataStore portainer.DataStore, encryptionKey []byte) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		encryptionKey: encryptionKey,
+	}
+}
+
+// EncryptPassword encrypts a SMTP password so that it can be stored in the settings
+func (service *Service) EncryptPassword(password string) (string, error) {
+	return crypto.EncryptAES(password, service.encryptionKey)
+}
+
+// Send sends an email using the SMTP settings stored in the settings.
+// ErrNotConfigured is returned when no SMTP server is configured.
+func (service *Service) Send(message *Message) error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	_, err = service.SendWithSettings(&settings.SMTPSettings, message)
+	return err
+}
+
+// SendWithSettings sends an email using the specified SMTP settings, the password of the settings must be encrypted.
+// It returns the response of the SMTP server to the message submission.
+func (service *Service) SendWithSettings(settings *portainer.SMTPSettings, message *Message) (string, error) {
+	if settings.Host == "" {
+		return "", ErrNotConfigured
+	}
+
+	serverResponse, err := service.send(settings, message)
+	if err != nil {
+		log.Printf("[ERROR] [internal,mailer] [host: %s] [message: unable to send email] [error: %s]", settings.Host, err)
+		return "", err
+	}
+
+	return serverResponse, nil
+}
+
+func (service *Service) send(settings *portainer.SMTPSettings, message *Message) (string, error) {
+	client, err := service.connect(settings)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	err = client.Mail(settings.FromAddress)
+	if err != nil {
+		return "", err
+	}
+
+	for _, recipient := range message.To {
+		err = client.Rcpt(recipient)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// the DATA command is sent through the underlying connection to retrieve
+	// the response of the server once the message is submitted
+	id, err := client.Text.Cmd("DATA")
+	if err != nil {
+		return "", err
+	}
+
+	client.Text.StartResponse(id)
+	_, _, err = client.Text.ReadResponse(354)
+	client.Text.EndResponse(id)
+	if err != nil {
+		return "", err
+	}
+
+	writer := client.Text.DotWriter()
+	_, err = writer.Write(buildMessage(settings.FromAddress, message))
+	if err != nil {
+		return "", err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return "", err
+	}
+
+	code, serverResponse, err := client.Text.ReadResponse(250)
+	if err != nil {
+		return "", err
+	}
+
+	err = client.Quit()
+	if err != nil {
+		log.Printf("[WARN] [internal,mailer] [host: %s] [message: unable to close SMTP session] [error: %s]", settings.Host, err)
+	}
+
+	return fmt.Sprintf("%d %s", code, serverResponse), nil
+}
+
+func (service *Service) connect(settings *portainer.SMTPSettings) (*smtp.Client, error) {
+	address := net.JoinHostPort(settings.Host, strconv.Itoa(settings.Port))
+	tlsConfig := &tls.Config{
+		ServerName:         settings.Host,
+		InsecureSkipVerify: settings.TLSSkipVerify,
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	var conn net.Conn
+	var err error
+	if settings.Security == portainer.SMTPSecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.SetDeadline(time.Now().Add(connectionTimeout))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, settings.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if settings.Security == portainer.SMTPSecuritySTARTTLS {
+		err = client.StartTLS(tlsConfig)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	if settings.Authentication {
+		password, err := crypto.DecryptAES(settings.Password, service.encryptionKey)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+
+		err = client.Auth(smtp.PlainAuth("", settings.Username, password, settings.Host))
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+func buildMessage(from string, message *Message) []byte {
+	var buffer bytes.Buffer
+
+	fmt.Fprintf(&buffer, "From: %s\r\n", from)
+	fmt.Fprintf(&buffer, "To: %s\r\n", strings.Join(message.To, ", "))
+	fmt.Fprintf(&buffer, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buffer, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buffer.WriteString("MIME-Version: 1.0\r\n")
+	buffer.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	buffer.WriteString("\r\n")
+	body := strings.Replace(message.Body, "\r\n", "\n", -1)
+	buffer.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+
+	return buffer.Bytes()
+}
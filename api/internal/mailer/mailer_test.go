@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// startFakeSMTPServer starts a minimal SMTP server accepting a single message
+func startFakeSMTPServer(t *testing.T) (int, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	messages := make(chan string, 1)
+
+	go func() {
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "MAIL"), strings.HasPrefix(command, "RCPT"):
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var message strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					message.WriteString(dataLine)
+				}
+				messages <- message.String()
+				reply("250 2.0.0 Ok: queued as 12345")
+			case command == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, messages
+}
+
+func Test_SendWithSettings_shouldReturnServerResponse(t *testing.T) {
+	port, messages := startFakeSMTPServer(t)
+
+	service := NewService(nil, make([]byte, 32))
+	settings := &portainer.SMTPSettings{
+		Host:        "127.0.0.1",
+		Port:        port,
+		Security:    portainer.SMTPSecurityNone,
+		FromAddress: "portainer@example.com",
+	}
+
+	serverResponse, err := service.SendWithSettings(settings, &Message{
+		To:      []string{"admin@example.com"},
+		Subject: "Test",
+		Body:    "Hello",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "250 2.0.0 Ok: queued as 12345", serverResponse)
+
+	message := <-messages
+	assert.Contains(t, message, "To: admin@example.com\r\n")
+	assert.True(t, strings.HasSuffix(message, "\r\nHello\r\n"), strconv.Quote(message))
+}
+
+func Test_SendWithSettings_shouldRequireHost(t *testing.T) {
+	service := NewService(nil, make([]byte, 32))
+
+	_, err := service.SendWithSettings(&portainer.SMTPSettings{}, &Message{})
+	assert.Equal(t, ErrNotConfigured, err)
+}
@@ -0,0 +1,231 @@
+package quota
+
+import (
+	"errors"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// ErrQuotaExceeded is returned when a user or the members of one of its teams already created the maximum
+// amount of resources of a specific type
+var ErrQuotaExceeded = errors.New("Resource quota exceeded")
+
+type (
+	// Usage represents the amount of resources of a specific type created by a user or by the members of a team
+	// and the associated limit. A limit of 0 means unlimited.
+	Usage struct {
+		Used  int `json:"Used" example:"2"`
+		Limit int `json:"Limit" example:"10"`
+	}
+
+	// TeamUsage represents the resource usage of a team
+	TeamUsage struct {
+		TeamID     portainer.TeamID `json:"TeamId" example:"1"`
+		Stacks     Usage            `json:"Stacks"`
+		Containers Usage            `json:"Containers"`
+		Webhooks   Usage            `json:"Webhooks"`
+	}
+
+	// UserUsage represents the resource usage of a user and of the teams the user is part of
+	UserUsage struct {
+		// Whether the user is exempt from quotas (administrators)
+		Exempt     bool        `json:"Exempt" example:"false"`
+		Stacks     Usage       `json:"Stacks"`
+		Containers Usage       `json:"Containers"`
+		Webhooks   Usage       `json:"Webhooks"`
+		Teams      []TeamUsage `json:"Teams"`
+	}
+)
+
+// CheckUserQuota verifies that the specified user can create a new resource of the specified type
+// without exceeding the user quota or the quota of any of the teams the user is part of.
+// Administrators are exempt from quotas. ErrQuotaExceeded is returned when a quota is reached.
+func CheckUserQuota(dataStore portainer.DataStore, userID portainer.UserID, resourceType portainer.ResourceControlType) error {
+	usage, err := GetUserUsage(dataStore, userID)
+	if err != nil {
+		return err
+	}
+
+	if usage.Exempt {
+		return nil
+	}
+
+	selectUsage := func(stacks, containers Usage) Usage {
+		if resourceType == portainer.StackResourceControl {
+			return stacks
+		}
+		return containers
+	}
+
+	if reached(selectUsage(usage.Stacks, usage.Containers)) {
+		return ErrQuotaExceeded
+	}
+
+	for _, teamUsage := range usage.Teams {
+		if reached(selectUsage(teamUsage.Stacks, teamUsage.Containers)) {
+			return ErrQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
+// CheckUserWebhookQuota verifies that the specified user can create a new webhook
+// without exceeding the user quota or the quota of any of the teams the user is part of.
+// Administrators are exempt from quotas. ErrQuotaExceeded is returned when a quota is reached.
+func CheckUserWebhookQuota(dataStore portainer.DataStore, userID portainer.UserID) error {
+	usage, err := GetUserUsage(dataStore, userID)
+	if err != nil {
+		return err
+	}
+
+	if usage.Exempt {
+		return nil
+	}
+
+	if reached(usage.Webhooks) {
+		return ErrQuotaExceeded
+	}
+
+	for _, teamUsage := range usage.Teams {
+		if reached(teamUsage.Webhooks) {
+			return ErrQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
+// GetUserUsage returns the current resource usage of the specified user and of the teams the user is part of,
+// alongside the limits defined in the settings.
+func GetUserUsage(dataStore portainer.DataStore, userID portainer.UserID) (*UserUsage, error) {
+	user, err := dataStore.User().User(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &UserUsage{
+		Teams: make([]TeamUsage, 0),
+	}
+
+	if user.Role == portainer.AdministratorRole {
+		usage.Exempt = true
+		return usage, nil
+	}
+
+	settings, err := dataStore.Settings().Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	resourceControls, err := dataStore.ResourceControl().ResourceControls()
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Stacks = Usage{
+		Used:  countCreatedResources(resourceControls, portainer.StackResourceControl, []portainer.UserID{userID}),
+		Limit: settings.UserResourceQuotas.MaxOwnedStacks,
+	}
+	usage.Containers = Usage{
+		Used:  countCreatedResources(resourceControls, portainer.ContainerResourceControl, []portainer.UserID{userID}),
+		Limit: settings.UserResourceQuotas.MaxOwnedContainers,
+	}
+
+	webhooks, err := dataStore.Webhook().Webhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Webhooks = Usage{
+		Used:  countOwnedWebhooks(webhooks, []portainer.UserID{userID}),
+		Limit: settings.UserResourceQuotas.MaxOwnedWebhooks,
+	}
+
+	memberships, err := dataStore.TeamMembership().TeamMembershipsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, membership := range memberships {
+		teamMemberships, err := dataStore.TeamMembership().TeamMembershipsByTeamID(membership.TeamID)
+		if err != nil {
+			return nil, err
+		}
+
+		memberIDs := make([]portainer.UserID, 0, len(teamMemberships))
+		for _, teamMembership := range teamMemberships {
+			memberIDs = append(memberIDs, teamMembership.UserID)
+		}
+
+		usage.Teams = append(usage.Teams, TeamUsage{
+			TeamID: membership.TeamID,
+			Stacks: Usage{
+				Used:  countCreatedResources(resourceControls, portainer.StackResourceControl, memberIDs),
+				Limit: settings.TeamResourceQuotas.MaxOwnedStacks,
+			},
+			Containers: Usage{
+				Used:  countCreatedResources(resourceControls, portainer.ContainerResourceControl, memberIDs),
+				Limit: settings.TeamResourceQuotas.MaxOwnedContainers,
+			},
+			Webhooks: Usage{
+				Used:  countOwnedWebhooks(webhooks, memberIDs),
+				Limit: settings.TeamResourceQuotas.MaxOwnedWebhooks,
+			},
+		})
+	}
+
+	return usage, nil
+}
+
+func reached(usage Usage) bool {
+	return usage.Limit > 0 && usage.Used >= usage.Limit
+}
+
+// countCreatedResources returns the number of resources of the specified type created by any of the specified users,
+// whatever their ownership or visibility
+func countCreatedResources(resourceControls []portainer.ResourceControl, resourceType portainer.ResourceControlType, userIDs []portainer.UserID) int {
+	count := 0
+	for _, resourceControl := range resourceControls {
+		if resourceControl.Type != resourceType {
+			continue
+		}
+
+		creatorID := resourceCreator(&resourceControl)
+		for _, userID := range userIDs {
+			if creatorID == userID {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// countOwnedWebhooks returns the number of webhooks created by any of the specified users.
+func countOwnedWebhooks(webhooks []portainer.Webhook, userIDs []portainer.UserID) int {
+	count := 0
+	for _, webhook := range webhooks {
+		for _, userID := range userIDs {
+			if webhook.CreatedBy == userID {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// resourceCreator returns the user who created the resource of the resource control. The creator is not recorded
+// in the resource controls created before the creators were recorded, the owner of a private resource is then
+// considered as its creator.
+func resourceCreator(resourceControl *portainer.ResourceControl) portainer.UserID {
+	if resourceControl.CreatedBy != 0 {
+		return resourceControl.CreatedBy
+	}
+
+	if resourceControl.Public || resourceControl.AdministratorsOnly || len(resourceControl.TeamAccesses) > 0 || len(resourceControl.UserAccesses) != 1 {
+		return 0
+	}
+	return resourceControl.UserAccesses[0].UserID
+}
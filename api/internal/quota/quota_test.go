@@ -0,0 +1,53 @@
+package quota
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_countCreatedResources_shouldCountTheResourcesByCreatorWhateverTheirVisibility(t *testing.T) {
+	resourceControls := []portainer.ResourceControl{
+		{Type: portainer.StackResourceControl, CreatedBy: 1, UserAccesses: []portainer.UserResourceAccess{{UserID: 1}}},
+		{Type: portainer.StackResourceControl, CreatedBy: 1, Public: true},
+		{Type: portainer.StackResourceControl, CreatedBy: 1, TeamAccesses: []portainer.TeamResourceAccess{{TeamID: 1}}},
+		{Type: portainer.StackResourceControl, CreatedBy: 2, UserAccesses: []portainer.UserResourceAccess{{UserID: 1}}},
+		{Type: portainer.StackResourceControl, CreatedBy: 3, AdministratorsOnly: true},
+		{Type: portainer.ContainerResourceControl, CreatedBy: 1, Public: true},
+	}
+
+	assert.Equal(t, 3, countCreatedResources(resourceControls, portainer.StackResourceControl, []portainer.UserID{1}))
+	assert.Equal(t, 1, countCreatedResources(resourceControls, portainer.ContainerResourceControl, []portainer.UserID{1}))
+	assert.Equal(t, 4, countCreatedResources(resourceControls, portainer.StackResourceControl, []portainer.UserID{1, 2}))
+	assert.Equal(t, 0, countCreatedResources(resourceControls, portainer.StackResourceControl, []portainer.UserID{4}))
+}
+
+func Test_countCreatedResources_shouldConsiderTheOwnerOfALegacyPrivateResourceAsItsCreator(t *testing.T) {
+	resourceControls := []portainer.ResourceControl{
+		{Type: portainer.StackResourceControl, UserAccesses: []portainer.UserResourceAccess{{UserID: 1}}},
+		{Type: portainer.StackResourceControl, UserAccesses: []portainer.UserResourceAccess{{UserID: 1}}, Public: true},
+		{Type: portainer.StackResourceControl, TeamAccesses: []portainer.TeamResourceAccess{{TeamID: 1}}},
+	}
+
+	assert.Equal(t, 1, countCreatedResources(resourceControls, portainer.StackResourceControl, []portainer.UserID{1}))
+}
+
+func Test_countOwnedWebhooks(t *testing.T) {
+	webhooks := []portainer.Webhook{
+		{CreatedBy: 1},
+		{CreatedBy: 1},
+		{CreatedBy: 2},
+		{},
+	}
+
+	assert.Equal(t, 2, countOwnedWebhooks(webhooks, []portainer.UserID{1}))
+	assert.Equal(t, 3, countOwnedWebhooks(webhooks, []portainer.UserID{1, 2}))
+	assert.Equal(t, 0, countOwnedWebhooks(webhooks, []portainer.UserID{3}))
+}
+
+func Test_reached(t *testing.T) {
+	assert.False(t, reached(Usage{Used: 10, Limit: 0}))
+	assert.False(t, reached(Usage{Used: 1, Limit: 2}))
+	assert.True(t, reached(Usage{Used: 2, Limit: 2}))
+}
@@ -0,0 +1,396 @@
+package registryclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DockerHubRegistry is the name of the DockerHub registry used in image references
+	DockerHubRegistry     = "docker.io"
+	dockerHubRegistryHost = "registry-1.docker.io"
+
+	// maxResponseSize prevents a registry from returning arbitrarily large responses
+	maxResponseSize = 4 * 1024 * 1024
+
+	catalogScope = "registry:catalog:*"
+
+	// maxRepositoryNameLength is the maximum length of a repository name accepted by the registries
+	maxRepositoryNameLength = 255
+)
+
+var (
+	// ErrNotFound is returned when the registry does not know the requested object
+	ErrNotFound = errors.New("Object not found in the registry")
+	// ErrForbidden is returned when the registry denies access to the requested object with the available credentials
+	ErrForbidden = errors.New("Access denied by the registry")
+	// ErrInvalidRepositoryName is returned when a repository name does not match the repository name grammar of the registry API
+	ErrInvalidRepositoryName = errors.New("Invalid repository name")
+
+	challengeParameterRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	nextLinkRe           = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+	// repositoryNameRe is the repository name grammar of the registry API: path components made of lowercase
+	// alphanumeric characters, optionally separated by a period, one or two underscores or dashes, joined by slashes
+	repositoryNameRe = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+)
+
+type (
+	// Credentials represents the credentials used to authenticate against a registry
+	Credentials struct {
+		Username string
+		Password string
+	}
+
+	// Client is a minimal Docker registry HTTP API v2 client. Bearer tokens are requested
+	// per scope when the registry returns an authentication challenge, and shared with the
+	// other clients through the token cache.
+	Client struct {
+		httpClient  *http.Client
+		host        string
+		credentials *Credentials
+		tokens      map[string]string
+		tokenCache  *TokenCache
+	}
+
+	// Page represents a page of a paginated listing, Next is the value to use as the last
+	// element of the next page, empty when there is no more element
+	Page struct {
+		Items []string
+		Next  string
+	}
+)
+
+// NewClient creates a client for the registry, the registry can be an image reference domain or a registry URL
+func NewClient(httpClient *http.Client, registry string, credentials *Credentials) *Client {
+	return &Client{
+		httpClient:  httpClient,
+		host:        registryHost(registry),
+		credentials: credentials,
+		tokens:      make(map[string]string),
+		tokenCache:  sharedTokenCache,
+	}
+}
+
+// NormalizeRegistry removes the scheme and trailing slash of a registry URL and returns
+// DockerHubRegistry for all the DockerHub registry hosts
+func NormalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	registry = strings.TrimSuffix(registry, "/")
+
+	switch registry {
+	case "index.docker.io", dockerHubRegistryHost:
+		return DockerHubRegistry
+	}
+
+	return registry
+}
+
+// RepositoryScope returns the token scope required to pull from a repository
+func RepositoryScope(repository string) string {
+	return fmt.Sprintf("repository:%s:pull", repository)
+}
+
+// ValidateRepositoryName verifies that a repository name matches the repository name grammar of the registry API,
+// so that it cannot alter the path of the requests sent to the registry
+func ValidateRepositoryName(repository string) error {
+	if len(repository) > maxRepositoryNameLength || !repositoryNameRe.MatchString(repository) {
+		return ErrInvalidRepositoryName
+	}
+	return nil
+}
+
+// Get sends a GET request to the path of the registry API (relative to /v2), authenticating with a token
+// of the specified scope when required. The caller must close the body of the response.
+func (client *Client) Get(path, scope string, accept []string) (*http.Response, error) {
+	response, err := client.send(http.MethodGet, path, scope, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return response, nil
+	case http.StatusNotFound:
+		response.Body.Close()
+		return nil, ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		response.Body.Close()
+		return nil, ErrForbidden
+	}
+
+	response.Body.Close()
+	return nil, fmt.Errorf("Unexpected response from registry %s: %s", client.host, response.Status)
+}
+
+// Head sends a HEAD request to the path of the registry API (relative to /v2), authenticating with a token
+// of the specified scope when required. The response is returned whatever its status code, e.g. to read the
+// headers of a rate limited response. The caller must close the body of the response.
+func (client *Client) Head(path, scope string, accept []string) (*http.Response, error) {
+	return client.send(http.MethodHead, path, scope, accept)
+}
+
+// send sends the request and authenticates against the registry when it returns an authentication challenge
+func (client *Client) send(method, path, scope string, accept []string) (*http.Response, error) {
+	requestURL := fmt.Sprintf("https://%s/v2%s", client.host, path)
+
+	response, err := client.do(method, requestURL, scope, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusUnauthorized {
+		challenge := response.Header.Get("WWW-Authenticate")
+		response.Body.Close()
+
+		// the token sent, if any, was rejected
+		delete(client.tokens, scope)
+		client.tokenCache.remove(client.tokenCacheKey(scope))
+
+		err = client.authenticate(challenge, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err = client.do(method, requestURL, scope, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// Ping verifies that the registry accepts the credentials of the client by requesting the base endpoint of the API.
+// ErrForbidden is returned when the credentials are rejected.
+func (client *Client) Ping() error {
+	response, err := client.Get("/", "", nil)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	return nil
+}
+
+// Catalog returns a page of the repositories of the registry, starting after the last repository.
+// A size of 0 lets the registry use its default page size.
+func (client *Client) Catalog(size int, last string) (*Page, error) {
+	var catalog struct {
+		Repositories []string `json:"repositories"`
+	}
+
+	next, err := client.list("/_catalog", catalogScope, size, last, &catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{Items: catalog.Repositories, Next: next}, nil
+}
+
+// Tags returns a page of the tags of a repository, starting after the last tag.
+// A size of 0 lets the registry use its default page size.
+func (client *Client) Tags(repository string, size int, last string) (*Page, error) {
+	err := ValidateRepositoryName(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+
+	next, err := client.list("/"+repository+"/tags/list", RepositoryScope(repository), size, last, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{Items: tags.Tags, Next: next}, nil
+}
+
+func (client *Client) list(path, scope string, size int, last string, result interface{}) (string, error) {
+	query := url.Values{}
+	if size > 0 {
+		query.Set("n", strconv.Itoa(size))
+	}
+	if last != "" {
+		query.Set("last", last)
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	response, err := client.Get(path, scope, nil)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	content, err := ReadLimited(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	err = json.Unmarshal(content, result)
+	if err != nil {
+		return "", err
+	}
+
+	return nextPageMarker(response.Header.Get("Link")), nil
+}
+
+// nextPageMarker extracts the last parameter of the next page link returned by the registry
+func nextPageMarker(link string) string {
+	match := nextLinkRe.FindStringSubmatch(link)
+	if match == nil {
+		return ""
+	}
+
+	nextURL, err := url.Parse(match[1])
+	if err != nil {
+		return ""
+	}
+
+	return nextURL.Query().Get("last")
+}
+
+func (client *Client) do(method, requestURL, scope string, accept []string) (*http.Response, error) {
+	request, err := http.NewRequest(method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accept) > 0 {
+		request.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+
+	token := client.tokens[scope]
+	if token == "" {
+		token = client.tokenCache.get(client.tokenCacheKey(scope))
+	}
+
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	} else if client.credentials != nil {
+		request.SetBasicAuth(client.credentials.Username, client.credentials.Password)
+	}
+
+	return client.httpClient.Do(request)
+}
+
+// authenticate retrieves a bearer token for the scope based on the authentication challenge returned by the registry.
+// Basic authentication challenges are handled directly by sending the credentials on each request.
+func (client *Client) authenticate(challenge, scope string) error {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		if client.credentials == nil {
+			return errors.New("Registry requires authentication")
+		}
+		return nil
+	}
+
+	parameters := map[string]string{}
+	for _, match := range challengeParameterRe.FindAllStringSubmatch(challenge, -1) {
+		parameters[match[1]] = match[2]
+	}
+
+	realm, err := url.Parse(parameters["realm"])
+	if err != nil || realm.Host == "" {
+		return errors.New("Invalid registry authentication challenge")
+	}
+
+	query := realm.Query()
+	if parameters["service"] != "" {
+		query.Set("service", parameters["service"])
+	}
+	if scope != "" {
+		query.Set("scope", scope)
+	}
+	realm.RawQuery = query.Encode()
+
+	token, err := client.tokenCache.token(client.tokenCacheKey(scope), client.host, func() (string, time.Duration, error) {
+		return client.requestToken(realm.String())
+	})
+	if err != nil {
+		return err
+	}
+	client.tokens[scope] = token
+
+	return nil
+}
+
+// requestToken requests a token from the token server and returns it with its lifetime,
+// 0 when the token server does not specify the lifetime of the token
+func (client *Client) requestToken(realm string) (string, time.Duration, error) {
+	request, err := http.NewRequest(http.MethodGet, realm, nil)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if client.credentials != nil {
+		request.SetBasicAuth(client.credentials.Username, client.credentials.Password)
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return "", 0, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", 0, ErrForbidden
+	default:
+		return "", 0, fmt.Errorf("Unable to authenticate against registry %s: %s", client.host, response.Status)
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	content, err := ReadLimited(response.Body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	err = json.Unmarshal(content, &tokenResponse)
+	if err != nil {
+		return "", 0, err
+	}
+
+	token := tokenResponse.Token
+	if token == "" {
+		token = tokenResponse.AccessToken
+	}
+
+	return token, time.Duration(tokenResponse.ExpiresIn) * time.Second, nil
+}
+
+func (client *Client) tokenCacheKey(scope string) string {
+	return tokenCacheKey(client.host, client.credentials, scope)
+}
+
+// ReadLimited reads a registry response, failing when it exceeds the maximum response size
+func ReadLimited(reader io.Reader) ([]byte, error) {
+	content, err := ioutil.ReadAll(io.LimitReader(reader, maxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) > maxResponseSize {
+		return nil, errors.New("Registry response is too large")
+	}
+
+	return content, nil
+}
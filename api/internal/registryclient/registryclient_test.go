@@ -0,0 +1,90 @@
+package registryclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_nextPageMarker(t *testing.T) {
+	assert.Equal(t, "library/nginx", nextPageMarker(`</v2/_catalog?last=library%2Fnginx&n=2>; rel="next"`))
+	assert.Equal(t, "", nextPageMarker(""))
+}
+
+func Test_Client_Catalog(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			assert.Equal(t, catalogScope, r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token":"abc"}`)
+		case "/v2/_catalog":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(t, "2", r.URL.Query().Get("n"))
+			w.Header().Set("Link", `</v2/_catalog?last=b&n=2>; rel="next"`)
+			fmt.Fprint(w, `{"repositories":["a","b"]}`)
+		case "/v2/private/tags/list":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, nil)
+
+	page, err := client.Catalog(2, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, page.Items)
+	assert.Equal(t, "b", page.Next)
+
+	_, err = client.Tags("private", 0, "")
+	assert.Equal(t, ErrForbidden, err)
+}
+
+func Test_ValidateRepositoryName(t *testing.T) {
+	for _, repository := range []string{"nginx", "library/nginx", "my-org/my_app.v2", "a/b/c", "a__b", "a--b"} {
+		assert.NoError(t, ValidateRepositoryName(repository), repository)
+	}
+
+	for _, repository := range []string{"", "Nginx", "../_catalog", "library/../nginx", "library//nginx", "/nginx", "nginx/", "nginx?n=1", "nginx/tags/list#", "a___b", "-nginx", strings.Repeat("a", 256)} {
+		assert.Equal(t, ErrInvalidRepositoryName, ValidateRepositoryName(repository), repository)
+	}
+}
+
+func Test_Client_Ping(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			_, hasScope := r.URL.Query()["scope"]
+			assert.False(t, hasScope)
+			username, password, _ := r.BasicAuth()
+			if username != "user" || password != "new" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"token":"abc"}`)
+		case "/v2/":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, &Credentials{Username: "user", Password: "old"})
+	client.tokenCache = NewTokenCache(0)
+	assert.Equal(t, ErrForbidden, client.Ping())
+
+	client = NewClient(server.Client(), server.URL, &Credentials{Username: "user", Password: "new"})
+	client.tokenCache = NewTokenCache(0)
+	assert.NoError(t, client.Ping())
+}
@@ -0,0 +1,219 @@
+package registryclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTokenCacheSize is the default maximum number of tokens kept by the token cache
+	DefaultTokenCacheSize = 1000
+
+	// defaultTokenLifetime is the lifetime of the tokens returned without expires_in, as defined by the token specification
+	defaultTokenLifetime = 60 * time.Second
+	// tokenExpiryMargin is removed from the lifetime of the tokens so that a token does not expire while a request is sent
+	tokenExpiryMargin = 10 * time.Second
+)
+
+type (
+	// TokenCache keeps the bearer tokens obtained from the registries until they expire. Tokens are cached per
+	// registry, credentials and scope. Concurrent requests of a token that is not cached yet are coalesced into
+	// a single request to the token server.
+	TokenCache struct {
+		mu       sync.Mutex
+		maxSize  int
+		entries  map[string]tokenCacheEntry
+		inflight map[string]*tokenRequest
+		now      func() time.Time
+	}
+
+	tokenCacheEntry struct {
+		registry  string
+		token     string
+		expiresAt time.Time
+	}
+
+	tokenRequest struct {
+		done  chan struct{}
+		token string
+		err   error
+	}
+
+	// tokenFetcher requests a token from the token server and returns it with its lifetime
+	tokenFetcher func() (string, time.Duration, error)
+)
+
+// sharedTokenCache is used by all the clients, so that the catalog and tag listings and the image verification
+// share the tokens of a registry
+var sharedTokenCache = NewTokenCache(DefaultTokenCacheSize)
+
+// NewTokenCache creates a token cache keeping up to maxSize tokens, 0 disables the caching of the tokens
+// while still coalescing the concurrent requests
+func NewTokenCache(maxSize int) *TokenCache {
+	return &TokenCache{
+		maxSize:  maxSize,
+		entries:  make(map[string]tokenCacheEntry),
+		inflight: make(map[string]*tokenRequest),
+		now:      time.Now,
+	}
+}
+
+// SetTokenCacheSize sets the maximum number of tokens kept by the token cache shared by the clients,
+// 0 disables the caching of the tokens
+func SetTokenCacheSize(maxSize int) {
+	sharedTokenCache.mu.Lock()
+	defer sharedTokenCache.mu.Unlock()
+
+	sharedTokenCache.maxSize = maxSize
+	if maxSize == 0 {
+		sharedTokenCache.entries = make(map[string]tokenCacheEntry)
+	}
+}
+
+// InvalidateTokens removes the tokens of a registry from the token cache shared by the clients.
+// It must be called when the credentials of the registry are updated or removed.
+func InvalidateTokens(registry string) {
+	sharedTokenCache.Invalidate(registry)
+}
+
+// Invalidate removes the tokens of a registry, the registry can be an image reference domain or a registry URL
+func (cache *TokenCache) Invalidate(registry string) {
+	host := registryHost(registry)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for key, entry := range cache.entries {
+		if strings.EqualFold(entry.registry, host) {
+			delete(cache.entries, key)
+		}
+	}
+}
+
+// get returns the cached token associated to the key, or an empty string when the token is missing or expired
+func (cache *TokenCache) get(key string) string {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	entry, ok := cache.entries[key]
+	if !ok {
+		return ""
+	}
+
+	if !cache.now().Before(entry.expiresAt) {
+		delete(cache.entries, key)
+		return ""
+	}
+	return entry.token
+}
+
+// remove removes the token associated to the key, used when the registry rejects a cached token
+func (cache *TokenCache) remove(key string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	delete(cache.entries, key)
+}
+
+// token returns the cached token associated to the key or fetches a new token. When a token is already being
+// fetched for the key, the result of that request is returned instead of sending another request.
+func (cache *TokenCache) token(key, registry string, fetch tokenFetcher) (string, error) {
+	cache.mu.Lock()
+
+	if entry, ok := cache.entries[key]; ok && cache.now().Before(entry.expiresAt) {
+		cache.mu.Unlock()
+		return entry.token, nil
+	}
+
+	if request, ok := cache.inflight[key]; ok {
+		cache.mu.Unlock()
+		<-request.done
+		return request.token, request.err
+	}
+
+	request := &tokenRequest{done: make(chan struct{})}
+	cache.inflight[key] = request
+	cache.mu.Unlock()
+
+	token, lifetime, err := fetch()
+	request.token, request.err = token, err
+
+	cache.mu.Lock()
+	delete(cache.inflight, key)
+	if err == nil {
+		cache.store(key, registry, token, lifetime)
+	}
+	cache.mu.Unlock()
+
+	close(request.done)
+
+	return token, err
+}
+
+// store must be called with the lock held
+func (cache *TokenCache) store(key, registry, token string, lifetime time.Duration) {
+	if cache.maxSize <= 0 || token == "" {
+		return
+	}
+
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+	lifetime -= tokenExpiryMargin
+	if lifetime <= 0 {
+		return
+	}
+
+	now := cache.now()
+
+	if len(cache.entries) >= cache.maxSize {
+		for entryKey, entry := range cache.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(cache.entries, entryKey)
+			}
+		}
+	}
+
+	for len(cache.entries) >= cache.maxSize {
+		cache.evictSoonestExpiring()
+	}
+
+	cache.entries[key] = tokenCacheEntry{registry: registry, token: token, expiresAt: now.Add(lifetime)}
+}
+
+func (cache *TokenCache) evictSoonestExpiring() {
+	var evictedKey string
+	var evictedExpiry time.Time
+
+	for key, entry := range cache.entries {
+		if evictedKey == "" || entry.expiresAt.Before(evictedExpiry) {
+			evictedKey, evictedExpiry = key, entry.expiresAt
+		}
+	}
+
+	delete(cache.entries, evictedKey)
+}
+
+// tokenCacheKey identifies a token by registry, credentials and scope. The credentials are hashed so that
+// updated credentials never use the tokens obtained with the previous credentials.
+func tokenCacheKey(registry string, credentials *Credentials, scope string) string {
+	identity := ""
+	if credentials != nil {
+		sum := sha256.Sum256([]byte(credentials.Username + ":" + credentials.Password))
+		identity = hex.EncodeToString(sum[:])
+	}
+
+	return strings.ToLower(registry) + "|" + identity + "|" + scope
+}
+
+// registryHost returns the host used to reach a registry, the registry can be an image reference domain or a registry URL
+func registryHost(registry string) string {
+	host := NormalizeRegistry(registry)
+	if host == DockerHubRegistry {
+		return dockerHubRegistryHost
+	}
+	return host
+}
@@ -0,0 +1,80 @@
+package registryclient
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TokenCache_token(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cache := NewTokenCache(10)
+	cache.now = func() time.Time { return now }
+
+	fetches := 0
+	fetch := func() (string, time.Duration, error) {
+		fetches++
+		return "token", 300 * time.Second, nil
+	}
+
+	key := tokenCacheKey("registry.local", &Credentials{Username: "user", Password: "pass"}, "repository:app:pull")
+
+	token, err := cache.token(key, "registry.local", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "token", token)
+
+	token, err = cache.token(key, "registry.local", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "token", token)
+	assert.Equal(t, 1, fetches)
+
+	now = now.Add(295 * time.Second)
+	assert.Equal(t, "", cache.get(key), "the token must expire before its lifetime minus the margin")
+
+	_, err = cache.token(key, "registry.local", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches)
+
+	cache.Invalidate("https://registry.local/")
+	assert.Equal(t, "", cache.get(key))
+}
+
+func Test_TokenCache_token_coalescesConcurrentRequests(t *testing.T) {
+	cache := NewTokenCache(10)
+
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func() (string, time.Duration, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return "token", 0, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := cache.token("key", "registry.local", fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, "token", token)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func Test_tokenCacheKey(t *testing.T) {
+	previous := tokenCacheKey("registry.local", &Credentials{Username: "user", Password: "old"}, "scope")
+	updated := tokenCacheKey("registry.local", &Credentials{Username: "user", Password: "new"}, "scope")
+
+	assert.NotEqual(t, previous, updated)
+	assert.NotContains(t, updated, "new")
+}
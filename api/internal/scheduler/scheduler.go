@@ -0,0 +1,367 @@
+package scheduler
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// MinimumInterval is the shortest interval that can be used to schedule a job
+	MinimumInterval = time.Second
+
+	// SubsystemSnapshot groups the jobs creating snapshots of the endpoints and recording their state
+	SubsystemSnapshot = "snapshot"
+	// SubsystemAutoUpdate groups the jobs automatically updating or removing deployed resources
+	SubsystemAutoUpdate = "auto_update"
+
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+// Subsystems is the list of the subsystems that can be paused
+var Subsystems = []string{SubsystemSnapshot, SubsystemAutoUpdate}
+
+var (
+	// ErrJobNotFound is returned when no job is registered with the specified identifier
+	ErrJobNotFound = errors.New("No scheduled job found with the specified identifier")
+	// ErrJobAlreadyRegistered is returned when a job is registered twice
+	ErrJobAlreadyRegistered = errors.New("A scheduled job is already registered with the specified identifier")
+	// ErrJobRunning is returned when a run is requested for a job that is currently running
+	ErrJobRunning = errors.New("The scheduled job is currently running")
+	// ErrInvalidInterval is returned when the interval of a job is lower than MinimumInterval
+	ErrInvalidInterval = errors.New("Invalid job interval. Must be at least 1s")
+	// ErrUnknownSubsystem is returned when pausing or resuming a subsystem that does not exist
+	ErrUnknownSubsystem = errors.New("Unknown subsystem")
+)
+
+type (
+	// Job represents a background job executed at a regular interval
+	Job struct {
+		// Unique identifier of the job
+		ID string
+		// Short description of what the job does
+		Description string
+		// Duration between each run
+		Interval time.Duration
+		// Whether the job must be executed as soon as it is registered
+		RunOnStart bool
+		// Function executed on each run
+		Run func() error
+		// Optional function called when the interval is updated through UpdateInterval,
+		// usually used to persist the new interval
+		IntervalUpdated func(interval time.Duration) error
+		// Optional subsystem of the job, the runs of the job are skipped while its subsystem is paused
+		Subsystem string
+	}
+
+	// JobStatus represents the state of a scheduled job
+	JobStatus struct {
+		// Job identifier
+		ID string `json:"ID" example:"endpoint_snapshot"`
+		// Job description
+		Description string `json:"Description" example:"Create a snapshot of each endpoint"`
+		// Duration between each run
+		Interval string `json:"Interval" example:"5m0s"`
+		// Unix timestamp of the start of the last run, 0 if the job never ran
+		LastRun int64 `json:"LastRun" example:"1587399600"`
+		// Result of the last run (success or failure), empty if the job never ran
+		LastResult string `json:"LastResult" example:"success"`
+		// Error returned by the last run, if any
+		LastError string `json:"LastError,omitempty"`
+		// Unix timestamp of the next scheduled run
+		NextRun int64 `json:"NextRun" example:"1587399900"`
+		// Whether the job is currently running
+		Running bool `json:"Running" example:"false"`
+		// Subsystem of the job
+		Subsystem string `json:"Subsystem,omitempty" example:"snapshot"`
+		// Whether the runs of the job are skipped because its subsystem is paused
+		Paused bool `json:"Paused" example:"false"`
+	}
+
+	// Scheduler runs jobs in the background and keeps track of their executions.
+	// Jobs can be inspected, rescheduled or triggered at runtime.
+	Scheduler struct {
+		mu     sync.Mutex
+		jobs   map[string]*scheduledJob
+		paused map[string]bool
+	}
+
+	scheduledJob struct {
+		definition Job
+		lastRun    time.Time
+		lastError  error
+		nextRun    time.Time
+		running    bool
+		trigger    chan struct{}
+		reset      chan struct{}
+	}
+)
+
+// NewScheduler creates a new scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		jobs:   make(map[string]*scheduledJob),
+		paused: make(map[string]bool),
+	}
+}
+
+// Register registers a job and starts its execution loop in the background
+func (scheduler *Scheduler) Register(job Job) error {
+	if job.Interval < MinimumInterval {
+		return ErrInvalidInterval
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if _, ok := scheduler.jobs[job.ID]; ok {
+		return ErrJobAlreadyRegistered
+	}
+
+	scheduled := &scheduledJob{
+		definition: job,
+		trigger:    make(chan struct{}, 1),
+		reset:      make(chan struct{}, 1),
+	}
+	scheduler.jobs[job.ID] = scheduled
+
+	go scheduler.loop(scheduled)
+
+	return nil
+}
+
+// Jobs returns the status of each registered job, sorted by identifier
+func (scheduler *Scheduler) Jobs() []JobStatus {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(scheduler.jobs))
+	for _, job := range scheduler.jobs {
+		statuses = append(statuses, scheduler.jobStatus(job))
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+
+	return statuses
+}
+
+// Job returns the status of a registered job
+func (scheduler *Scheduler) Job(id string) (*JobStatus, error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	job, ok := scheduler.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	status := scheduler.jobStatus(job)
+	return &status, nil
+}
+
+// SetInterval changes the interval of a job. The next run is scheduled after the new interval.
+func (scheduler *Scheduler) SetInterval(id string, interval time.Duration) error {
+	if interval < MinimumInterval {
+		return ErrInvalidInterval
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	job, ok := scheduler.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	job.definition.Interval = interval
+	notify(job.reset)
+
+	return nil
+}
+
+// UpdateInterval changes the interval of a job like SetInterval, after calling the
+// IntervalUpdated function of the job so that the new interval can be persisted.
+func (scheduler *Scheduler) UpdateInterval(id string, interval time.Duration) error {
+	if interval < MinimumInterval {
+		return ErrInvalidInterval
+	}
+
+	scheduler.mu.Lock()
+	job, ok := scheduler.jobs[id]
+	scheduler.mu.Unlock()
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	if job.definition.IntervalUpdated != nil {
+		err := job.definition.IntervalUpdated(interval)
+		if err != nil {
+			return err
+		}
+	}
+
+	return scheduler.SetInterval(id, interval)
+}
+
+// RunNow triggers an immediate run of a job. The next run is scheduled after the job interval.
+func (scheduler *Scheduler) RunNow(id string) error {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	job, ok := scheduler.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	if job.running {
+		return ErrJobRunning
+	}
+
+	notify(job.trigger)
+
+	return nil
+}
+
+// PauseSubsystem pauses a subsystem, the runs of its jobs are skipped until the subsystem is resumed.
+// A subsystem can be paused before its jobs are registered.
+func (scheduler *Scheduler) PauseSubsystem(subsystem string) error {
+	if !IsSubsystem(subsystem) {
+		return ErrUnknownSubsystem
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	scheduler.paused[subsystem] = true
+	return nil
+}
+
+// ResumeSubsystem resumes a paused subsystem and triggers an immediate run of its jobs
+// to catch up with the runs skipped during the pause.
+func (scheduler *Scheduler) ResumeSubsystem(subsystem string) error {
+	if !IsSubsystem(subsystem) {
+		return ErrUnknownSubsystem
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if !scheduler.paused[subsystem] {
+		return nil
+	}
+	delete(scheduler.paused, subsystem)
+
+	for _, job := range scheduler.jobs {
+		if job.definition.Subsystem == subsystem && !job.running {
+			notify(job.trigger)
+		}
+	}
+
+	return nil
+}
+
+// IsSubsystemPaused returns whether a subsystem is paused
+func (scheduler *Scheduler) IsSubsystemPaused(subsystem string) bool {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	return scheduler.paused[subsystem]
+}
+
+// IsSubsystem returns whether subsystem is one of the subsystems that can be paused
+func IsSubsystem(subsystem string) bool {
+	for _, name := range Subsystems {
+		if name == subsystem {
+			return true
+		}
+	}
+	return false
+}
+
+func (scheduler *Scheduler) loop(job *scheduledJob) {
+	if job.definition.RunOnStart {
+		scheduler.execute(job)
+	}
+
+	for {
+		scheduler.mu.Lock()
+		interval := job.definition.Interval
+		job.nextRun = time.Now().Add(interval)
+		scheduler.mu.Unlock()
+
+		timer := time.NewTimer(interval)
+
+		select {
+		case <-timer.C:
+			scheduler.execute(job)
+		case <-job.trigger:
+			timer.Stop()
+			scheduler.execute(job)
+		case <-job.reset:
+			timer.Stop()
+		}
+	}
+}
+
+func (scheduler *Scheduler) execute(job *scheduledJob) {
+	scheduler.mu.Lock()
+	if job.definition.Subsystem != "" && scheduler.paused[job.definition.Subsystem] {
+		scheduler.mu.Unlock()
+		log.Printf("[INFO] [internal,scheduler] [job: %s] [message: subsystem %s is paused, skipping run]", job.definition.ID, job.definition.Subsystem)
+		return
+	}
+	job.running = true
+	job.lastRun = time.Now()
+	scheduler.mu.Unlock()
+
+	err := job.definition.Run()
+	if err != nil {
+		log.Printf("[ERROR] [internal,scheduler] [job: %s] [message: background schedule error] [error: %s]", job.definition.ID, err)
+	}
+
+	scheduler.mu.Lock()
+	job.running = false
+	job.lastError = err
+	scheduler.mu.Unlock()
+}
+
+// jobStatus returns the status of a job, the scheduler lock must be held by the caller
+func (scheduler *Scheduler) jobStatus(job *scheduledJob) JobStatus {
+	status := JobStatus{
+		ID:          job.definition.ID,
+		Description: job.definition.Description,
+		Interval:    job.definition.Interval.String(),
+		NextRun:     job.nextRun.Unix(),
+		Running:     job.running,
+		Subsystem:   job.definition.Subsystem,
+		Paused:      job.definition.Subsystem != "" && scheduler.paused[job.definition.Subsystem],
+	}
+
+	if !job.lastRun.IsZero() {
+		status.LastRun = job.lastRun.Unix()
+
+		if job.running {
+			return status
+		}
+
+		status.LastResult = resultSuccess
+		if job.lastError != nil {
+			status.LastResult = resultFailure
+			status.LastError = job.lastError.Error()
+		}
+	}
+
+	return status
+}
+
+func notify(signal chan struct{}) {
+	select {
+	case signal <- struct{}{}:
+	default:
+	}
+}
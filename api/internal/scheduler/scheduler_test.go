@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Scheduler_RunNow(t *testing.T) {
+	scheduler := NewScheduler()
+
+	runs := make(chan struct{}, 1)
+	err := scheduler.Register(Job{
+		ID:       "test",
+		Interval: time.Hour,
+		Run: func() error {
+			runs <- struct{}{}
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	err = scheduler.RunNow("test")
+	assert.NoError(t, err)
+
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not triggered")
+	}
+
+	assert.Equal(t, ErrJobNotFound, scheduler.RunNow("unknown"))
+	assert.Equal(t, ErrJobAlreadyRegistered, scheduler.Register(Job{ID: "test", Interval: time.Hour}))
+}
+
+func Test_Scheduler_UpdateInterval(t *testing.T) {
+	scheduler := NewScheduler()
+
+	var persisted time.Duration
+	err := scheduler.Register(Job{
+		ID:              "test",
+		Interval:        time.Hour,
+		Run:             func() error { return nil },
+		IntervalUpdated: func(interval time.Duration) error { persisted = interval; return nil },
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrInvalidInterval, scheduler.UpdateInterval("test", time.Millisecond))
+
+	err = scheduler.UpdateInterval("test", 10*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, persisted)
+
+	job, err := scheduler.Job("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "10m0s", job.Interval)
+	assert.Equal(t, "", job.LastResult)
+}
+
+func Test_Scheduler_PauseSubsystem(t *testing.T) {
+	scheduler := NewScheduler()
+
+	runs := make(chan struct{}, 1)
+	err := scheduler.Register(Job{
+		ID:        "test",
+		Interval:  time.Hour,
+		Subsystem: SubsystemSnapshot,
+		Run: func() error {
+			runs <- struct{}{}
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrUnknownSubsystem, scheduler.PauseSubsystem("unknown"))
+	assert.NoError(t, scheduler.PauseSubsystem(SubsystemSnapshot))
+
+	job, err := scheduler.Job("test")
+	assert.NoError(t, err)
+	assert.True(t, job.Paused)
+
+	assert.NoError(t, scheduler.RunNow("test"))
+	select {
+	case <-runs:
+		t.Fatal("paused job was run")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.NoError(t, scheduler.ResumeSubsystem(SubsystemSnapshot))
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not run after resume")
+	}
+}
@@ -0,0 +1,755 @@
+package secret
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+const (
+	// SecretsLabel is the label of the containers listing the secrets materialized into the container,
+	// as a comma-separated list of name:target entries. It is informative only, the secrets of a container
+	// are resolved from the stack of the container.
+	SecretsLabel = "io.portainer.secrets"
+	// ProjectLabel is the label of the holder containers and of the secrets volumes, its value is the name
+	// of the Compose project of the stack
+	ProjectLabel = "io.portainer.secrets.project"
+	// ServiceLabel is the label of the holder containers, its value is the name of the service whose secrets they hold
+	ServiceLabel = "io.portainer.secrets.service"
+	// ResourcePrefix is the prefix of the names of the holder containers and of the secrets volumes
+	ResourcePrefix = "portainer-secrets_"
+	// HolderImage is the image of the holder containers
+	HolderImage = "alpine:latest"
+	// DefaultTargetFolder is the folder of the secret files when no target is specified
+	DefaultTargetFolder = "/run/secrets"
+	// MaterializationJobID is the identifier of the secrets materialization job in the scheduler
+	MaterializationJobID = "secrets_materialization"
+
+	materializationInterval = 5 * time.Second
+	dockerTimeout           = 30 * time.Second
+	composeProjectLabel     = "com.docker.compose.project"
+	composeServiceLabel     = "com.docker.compose.service"
+	// secretFileMode is the mode of the secret files, readable by all the users of the container like
+	// the secrets of Docker Swarm
+	secretFileMode = 0444
+)
+
+var (
+	// ErrSecretNotFound is returned when a container references a secret that does not exist
+	ErrSecretNotFound = errors.New("Secret not found")
+
+	secretNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// Service materializes the secrets of the Compose stacks as files of tmpfs volumes mounted read-only by the containers
+// of their services. The volumes of each service are kept mounted by a holder container, so that the secret files are
+// written before the containers of the service are created and remain available when they restart. The files are
+// written through the archive API of the holder, no process is started in the containers and the value of the secrets
+// is never written on the disk of the host. The holders and the volumes are removed with the stack.
+type Service struct {
+	dataStore     portainer.DataStore
+	clientFactory *docker.ClientFactory
+	encryptionKey []byte
+	scheduler     *scheduler.Scheduler
+	mu            sync.Mutex
+	refresh       bool
+}
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, clientFactory *docker.ClientFactory, encryptionKey []byte, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		clientFactory: clientFactory,
+		encryptionKey: encryptionKey,
+		scheduler:     scheduler,
+	}
+}
+
+// Start registers the materialization of the secrets of the restarted holders in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          MaterializationJobID,
+		Description: "Materialize the secrets in the holder containers emptied by a restart of the endpoint",
+		Interval:    materializationInterval,
+		RunOnStart:  true,
+		Run:         service.materializeHolders,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,secret] [message: unable to schedule the secrets materialization] [error: %s]", err)
+	}
+}
+
+// Refresh writes again the secrets into all the running holders on the next run of the materialization job,
+// used when the value of a secret is updated
+func (service *Service) Refresh() {
+	service.mu.Lock()
+	service.refresh = true
+	service.mu.Unlock()
+}
+
+// EncryptValue encrypts the value of a secret before it is stored in the database
+func (service *Service) EncryptValue(value string) (string, error) {
+	return crypto.EncryptAES(value, service.encryptionKey)
+}
+
+// ValidateName verifies that the name of a secret can be used in the secrets label and as a file name
+func ValidateName(name string) error {
+	if !secretNameRe.MatchString(name) {
+		return errors.New("Invalid secret name. Only letters, digits, dots, dashes and underscores are allowed")
+	}
+	return nil
+}
+
+// ValidateReferences verifies the secret names and targets of the secret references
+func ValidateReferences(references []portainer.SecretReference) error {
+	targets := make(map[string]bool)
+
+	for _, reference := range references {
+		err := ValidateName(reference.Name)
+		if err != nil {
+			return err
+		}
+
+		target := Target(reference)
+		if !path.IsAbs(target) || path.Clean(target) != target || path.Dir(target) == "/" || strings.ContainsAny(target, ",:") {
+			return fmt.Errorf("Invalid target for secret %s. Target must be an absolute file path inside a folder other than /", reference.Name)
+		}
+
+		if targets[target] {
+			return fmt.Errorf("Invalid target for secret %s. Target %s is used by another secret", reference.Name, target)
+		}
+		targets[target] = true
+	}
+
+	return nil
+}
+
+// Target returns the path of the secret file inside the container
+func Target(reference portainer.SecretReference) string {
+	if reference.Target == "" {
+		return path.Join(DefaultTargetFolder, reference.Name)
+	}
+	return reference.Target
+}
+
+// Folders returns the folders of the secret files, sorted by path. Each folder is a secrets volume.
+func Folders(references []portainer.SecretReference) []string {
+	seen := make(map[string]bool)
+	folders := make([]string, 0)
+
+	for _, reference := range references {
+		folder := path.Dir(Target(reference))
+		if !seen[folder] {
+			seen[folder] = true
+			folders = append(folders, folder)
+		}
+	}
+
+	sort.Strings(folders)
+	return folders
+}
+
+// FormatLabel returns the value of the secrets label of the containers referencing the secrets
+func FormatLabel(references []portainer.SecretReference) string {
+	entries := make([]string, 0, len(references))
+	for _, reference := range references {
+		entries = append(entries, reference.Name+":"+Target(reference))
+	}
+	return strings.Join(entries, ",")
+}
+
+// HolderName returns the name of the holder container of the secrets of a service. The name changes with the
+// references, so that the files of the secrets that are no longer referenced are removed with the previous holder.
+func HolderName(project, serviceName string, references []portainer.SecretReference) string {
+	digest := sha256.Sum256([]byte(FormatLabel(references)))
+	return ResourcePrefix + project + "_" + serviceName + "_" + hex.EncodeToString(digest[:])[:12]
+}
+
+// Volumes returns the names of the secrets volumes of a service, per folder
+func Volumes(project, serviceName string, references []portainer.SecretReference) map[string]string {
+	holderName := HolderName(project, serviceName, references)
+
+	volumes := make(map[string]string)
+	for idx, folder := range Folders(references) {
+		volumes[folder] = holderName + "_" + strconv.Itoa(idx)
+	}
+	return volumes
+}
+
+// MaterializeStack writes the secrets of the services of a Compose stack into their secrets volumes on the endpoint,
+// before the containers of the services are created or started by the deployment. The holder and the volumes of
+// a service are created when they do not exist. All the services are materialized when none is specified.
+func (service *Service) MaterializeStack(endpoint *portainer.Endpoint, stack *portainer.Stack, services ...string) error {
+	if len(stack.Secrets) == 0 {
+		return nil
+	}
+
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	for serviceName, references := range stack.Secrets {
+		if len(references) == 0 || (len(services) > 0 && !contains(services, serviceName)) {
+			continue
+		}
+
+		holderID, err := ensureHolder(cli, stack.Name, serviceName, references)
+		if err != nil {
+			return fmt.Errorf("Unable to create the secrets holder of service %s: %s", serviceName, err)
+		}
+
+		err = service.writeSecrets(cli, holderID, references)
+		if err != nil {
+			return fmt.Errorf("Unable to materialize the secrets of service %s: %s", serviceName, err)
+		}
+	}
+
+	return nil
+}
+
+// RemoveStaleResources removes the holders and the volumes of a Compose stack on the endpoint that are no longer
+// used by the secrets of its services. The volumes still mounted by containers that were not recreated are kept
+// and removed by a next deployment.
+func (service *Service) RemoveStaleResources(endpoint *portainer.Endpoint, stack *portainer.Stack) error {
+	current := make(map[string]bool)
+	for serviceName, references := range stack.Secrets {
+		if len(references) == 0 {
+			continue
+		}
+
+		current[HolderName(stack.Name, serviceName, references)] = true
+		for _, volumeName := range Volumes(stack.Name, serviceName, references) {
+			current[volumeName] = true
+		}
+	}
+
+	return service.removeResources(endpoint, stack.Name, current)
+}
+
+// RemoveStack removes the holders and the volumes of a Compose stack on the endpoint,
+// once the containers of the stack are removed
+func (service *Service) RemoveStack(endpoint *portainer.Endpoint, project string) error {
+	return service.removeResources(endpoint, project, nil)
+}
+
+func (service *Service) removeResources(endpoint *portainer.Endpoint, project string, keep map[string]bool) error {
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+
+	projectFilters := filters.NewArgs(filters.Arg("label", ProjectLabel+"="+project))
+
+	holders, err := cli.ContainerList(ctx, dockertypes.ContainerListOptions{All: true, Filters: projectFilters})
+	if err != nil {
+		return err
+	}
+
+	for _, holder := range holders {
+		if len(holder.Names) > 0 && keep[strings.TrimPrefix(holder.Names[0], "/")] {
+			continue
+		}
+
+		err = cli.ContainerRemove(ctx, holder.ID, dockertypes.ContainerRemoveOptions{Force: true})
+		if err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+
+	volumes, err := cli.VolumeList(ctx, projectFilters)
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range volumes.Volumes {
+		if keep[volume.Name] {
+			continue
+		}
+
+		err = cli.VolumeRemove(ctx, volume.Name, false)
+		if err != nil && !client.IsErrNotFound(err) {
+			log.Printf("[WARN] [internal,secret] [project: %s] [volume: %s] [message: unable to remove the secrets volume, it will be removed by the next deployment] [error: %s]", project, volume.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureHolder returns the identifier of the running holder of the secrets of a service,
+// the holder and its volumes are created when they do not exist
+func ensureHolder(cli *client.Client, project, serviceName string, references []portainer.SecretReference) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+
+	name := HolderName(project, serviceName, references)
+
+	holder, err := cli.ContainerInspect(ctx, name)
+	if err == nil {
+		if holder.Config == nil || holder.Config.Labels[ProjectLabel] != project || holder.Config.Labels[ServiceLabel] != serviceName {
+			return "", fmt.Errorf("container %s is not a secrets holder", name)
+		}
+
+		if holder.State == nil || !holder.State.Running {
+			err = cli.ContainerStart(ctx, holder.ID, dockertypes.ContainerStartOptions{})
+			if err != nil {
+				return "", err
+			}
+		}
+
+		return holder.ID, nil
+	}
+	if !client.IsErrNotFound(err) {
+		return "", err
+	}
+
+	volumes := Volumes(project, serviceName, references)
+
+	hostConfig := &container.HostConfig{
+		NetworkMode:   "none",
+		RestartPolicy: container.RestartPolicy{Name: "unless-stopped"},
+	}
+
+	for _, folder := range Folders(references) {
+		err = createVolume(ctx, cli, project, volumes[folder])
+		if err != nil {
+			return "", err
+		}
+
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{Type: mount.TypeVolume, Source: volumes[folder], Target: folder})
+	}
+
+	config := &container.Config{
+		Image:  HolderImage,
+		Cmd:    []string{"sleep", "2147483647"},
+		Labels: map[string]string{ProjectLabel: project, ServiceLabel: serviceName},
+	}
+
+	body, err := cli.ContainerCreate(ctx, config, hostConfig, nil, name)
+	if client.IsErrNotFound(err) {
+		err = pullImage(ctx, cli, HolderImage)
+		if err != nil {
+			return "", err
+		}
+
+		body, err = cli.ContainerCreate(ctx, config, hostConfig, nil, name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = cli.ContainerStart(ctx, body.ID, dockertypes.ContainerStartOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	return body.ID, nil
+}
+
+// createVolume creates a secrets volume backed by a tmpfs. An existing volume with the same name is only used
+// when it is backed by a tmpfs, so that the secrets are never written on the disk of the host.
+func createVolume(ctx context.Context, cli *client.Client, project, name string) error {
+	volume, err := cli.VolumeCreate(ctx, volumetypes.VolumeCreateBody{
+		Name:       name,
+		Driver:     "local",
+		DriverOpts: map[string]string{"type": "tmpfs", "device": "tmpfs", "o": "mode=0755"},
+		Labels:     map[string]string{ProjectLabel: project},
+	})
+	if err != nil {
+		return err
+	}
+
+	if volume.Driver != "local" || volume.Options["type"] != "tmpfs" {
+		return fmt.Errorf("volume %s is not a tmpfs volume", name)
+	}
+
+	return nil
+}
+
+func pullImage(ctx context.Context, cli *client.Client, image string) error {
+	reader, err := cli.ImagePull(ctx, image, dockertypes.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(ioutil.Discard, reader)
+	return err
+}
+
+// writeSecrets writes the secret files into the volumes mounted by the holder, using the archive API of Docker:
+// the archive is streamed to the endpoint and extracted in the volumes, the secrets never appear in a command
+// nor in the environment of a process.
+func (service *Service) writeSecrets(cli *client.Client, holderID string, references []portainer.SecretReference) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+
+	for _, folder := range Folders(references) {
+		files := make(map[string]string)
+		for _, reference := range references {
+			target := Target(reference)
+			if path.Dir(target) != folder {
+				continue
+			}
+
+			value, err := service.secretValue(reference.Name)
+			if err != nil {
+				return fmt.Errorf("secret %s: %s", reference.Name, err)
+			}
+			files[path.Base(target)] = value
+		}
+
+		archive, err := secretsArchive(files, time.Now())
+		if err != nil {
+			return err
+		}
+
+		err = cli.CopyToContainer(ctx, holderID, folder, archive, dockertypes.CopyToContainerOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// secretsArchive returns a tar archive of the secret files, per file name
+func secretsArchive(files map[string]string, modTime time.Time) (io.Reader, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+
+	for _, name := range names {
+		content := files[name]
+
+		err := writer.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     secretFileMode,
+			Size:     int64(len(content)),
+			ModTime:  modTime,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = writer.Write([]byte(content))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &buffer, nil
+}
+
+// ContainerSecrets represents the secrets referenced by a container
+type ContainerSecrets struct {
+	ContainerID   string                      `json:"ContainerId"`
+	ContainerName string                      `json:"ContainerName"`
+	References    []portainer.SecretReference `json:"References"`
+	// Folders of the secret files where the secrets volume is not mounted, the secrets are not available in the
+	// container until it is recreated by a deployment of its stack
+	MissingVolumes []string `json:"MissingVolumes"`
+	// Secrets referenced by the container that do not exist
+	MissingSecrets []string `json:"MissingSecrets"`
+	// Whether the container has the secrets label without being a container of a stack service referencing secrets,
+	// no secret is available in such a container
+	Untrusted bool `json:"Untrusted"`
+}
+
+// InspectContainer returns the secrets referenced by a container of the endpoint and the issues preventing their materialization
+func (service *Service) InspectContainer(cli *client.Client, endpointID portainer.EndpointID, containerID string) (*ContainerSecrets, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+
+	container, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ContainerSecrets{
+		ContainerID:    container.ID,
+		ContainerName:  strings.TrimPrefix(container.Name, "/"),
+		References:     []portainer.SecretReference{},
+		MissingVolumes: []string{},
+		MissingSecrets: []string{},
+	}
+
+	var labels map[string]string
+	if container.Config != nil {
+		labels = container.Config.Labels
+	}
+
+	stacks, err := service.endpointStacks(endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	project, serviceName := labels[composeProjectLabel], labels[composeServiceLabel]
+
+	var references []portainer.SecretReference
+	if stack, ok := stacks[project]; ok {
+		references = stack.Secrets[serviceName]
+	}
+
+	if len(references) == 0 {
+		result.Untrusted = labels[SecretsLabel] != ""
+		return result, nil
+	}
+	result.References = references
+
+	mountedVolumes := make(map[string]string)
+	for _, mountPoint := range container.Mounts {
+		if mountPoint.Type == mount.TypeVolume {
+			mountedVolumes[mountPoint.Destination] = mountPoint.Name
+		}
+	}
+
+	volumes := Volumes(project, serviceName, references)
+	for _, folder := range Folders(references) {
+		if mountedVolumes[folder] != volumes[folder] {
+			result.MissingVolumes = append(result.MissingVolumes, folder)
+		}
+	}
+
+	secrets, err := service.dataStore.Secret().Secrets()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for _, secret := range secrets {
+		existing[secret.Name] = true
+	}
+
+	for _, reference := range references {
+		if !existing[reference.Name] {
+			result.MissingSecrets = append(result.MissingSecrets, reference.Name)
+		}
+	}
+
+	return result, nil
+}
+
+// endpointStacks returns the Compose stacks referencing secrets deployed on the endpoint, per project name
+func (service *Service) endpointStacks(endpointID portainer.EndpointID) (map[string]*portainer.Stack, error) {
+	stacks, err := service.dataStore.Stack().Stacks()
+	if err != nil {
+		return nil, err
+	}
+
+	endpointStacks := make(map[string]*portainer.Stack)
+	for idx := range stacks {
+		stack := &stacks[idx]
+		if stack.Type != portainer.DockerComposeStack || len(stack.Secrets) == 0 {
+			continue
+		}
+
+		if isDeployedOn(stack, endpointID) {
+			endpointStacks[stack.Name] = stack
+		}
+	}
+
+	return endpointStacks, nil
+}
+
+func isDeployedOn(stack *portainer.Stack, endpointID portainer.EndpointID) bool {
+	if stack.EndpointID == endpointID {
+		return true
+	}
+
+	for _, deployment := range stack.Deployments {
+		if deployment.EndpointID == endpointID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (service *Service) secretValue(name string) (string, error) {
+	secrets, err := service.dataStore.Secret().Secrets()
+	if err != nil {
+		return "", err
+	}
+
+	for _, secret := range secrets {
+		if secret.Name == name {
+			return crypto.DecryptAES(secret.Value, service.encryptionKey)
+		}
+	}
+
+	return "", ErrSecretNotFound
+}
+
+func (service *Service) materializeHolders() error {
+	service.mu.Lock()
+	refresh := service.refresh
+	service.refresh = false
+	service.mu.Unlock()
+
+	endpoints, err := service.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if endpoint.Type != portainer.DockerEnvironment && endpoint.Type != portainer.AgentOnDockerEnvironment {
+			continue
+		}
+		if endpoint.Status != portainer.EndpointStatusUp {
+			continue
+		}
+
+		err := service.materializeEndpoint(endpoint, refresh)
+		if err != nil {
+			log.Printf("[WARN] [internal,secret] [endpoint: %s] [message: unable to materialize the secrets of the holders] [error: %s]", endpoint.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// materializeEndpoint writes the secrets into the running holders of the endpoint whose volumes were emptied,
+// as the tmpfs volumes are emptied when the endpoint restarts, and restarts the containers of their service
+// that were started without their secrets. The secrets are written into all the running holders on a refresh.
+func (service *Service) materializeEndpoint(endpoint *portainer.Endpoint, refresh bool) error {
+	stacks, err := service.endpointStacks(endpoint.ID)
+	if err != nil || len(stacks) == 0 {
+		return err
+	}
+
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+
+	holders, err := cli.ContainerList(ctx, dockertypes.ContainerListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", ServiceLabel), filters.Arg("status", "running")),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, holder := range holders {
+		project, serviceName := holder.Labels[ProjectLabel], holder.Labels[ServiceLabel]
+
+		stack, ok := stacks[project]
+		if !ok {
+			continue
+		}
+
+		references := stack.Secrets[serviceName]
+		if len(references) == 0 || len(holder.Names) == 0 || strings.TrimPrefix(holder.Names[0], "/") != HolderName(project, serviceName, references) {
+			continue
+		}
+
+		emptied := !isMaterialized(ctx, cli, holder.ID, references)
+		if !emptied && !refresh {
+			continue
+		}
+
+		err = service.writeSecrets(cli, holder.ID, references)
+		if err != nil {
+			log.Printf("[WARN] [internal,secret] [endpoint: %s] [stack: %s] [service: %s] [message: unable to materialize the secrets of the service] [error: %s]", endpoint.Name, project, serviceName, err)
+			continue
+		}
+
+		if emptied {
+			err = restartServiceContainers(ctx, cli, project, serviceName)
+			if err != nil {
+				log.Printf("[WARN] [internal,secret] [endpoint: %s] [stack: %s] [service: %s] [message: unable to restart the containers started without their secrets] [error: %s]", endpoint.Name, project, serviceName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isMaterialized returns true when all the secret files exist in the volumes mounted by the holder
+func isMaterialized(ctx context.Context, cli *client.Client, holderID string, references []portainer.SecretReference) bool {
+	for _, reference := range references {
+		_, err := cli.ContainerStatPath(ctx, holderID, Target(reference))
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// restartServiceContainers restarts the running containers of a service of a Compose project
+func restartServiceContainers(ctx context.Context, cli *client.Client, project, serviceName string) error {
+	containers, err := cli.ContainerList(ctx, dockertypes.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", composeProjectLabel+"="+project),
+			filters.Arg("label", composeServiceLabel+"="+serviceName),
+			filters.Arg("status", "running"),
+		),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, container := range containers {
+		err = cli.ContainerRestart(ctx, container.ID, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package secret
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateReferences(t *testing.T) {
+	references := []portainer.SecretReference{
+		{Name: "db_password"},
+		{Name: "api_key", Target: "/etc/app/key"},
+	}
+	assert.NoError(t, ValidateReferences(references))
+
+	assert.Equal(t, "db_password:/run/secrets/db_password,api_key:/etc/app/key", FormatLabel(references))
+	assert.Equal(t, []string{"/etc/app", "/run/secrets"}, Folders(references))
+
+	invalids := [][]portainer.SecretReference{
+		{{Name: "../passwd"}},
+		{{Name: "key", Target: "relative/path"}},
+		{{Name: "key", Target: "/key"}},
+		{{Name: "key", Target: "/run/../etc/key"}},
+		{{Name: "a", Target: "/run/secrets/x"}, {Name: "b", Target: "/run/secrets/x"}},
+	}
+	for _, invalid := range invalids {
+		assert.Error(t, ValidateReferences(invalid))
+	}
+}
+
+func Test_HolderName_shouldChangeWithTheReferences(t *testing.T) {
+	references := []portainer.SecretReference{
+		{Name: "db_password"},
+		{Name: "api_key", Target: "/etc/app/key"},
+	}
+
+	holderName := HolderName("app", "web", references)
+	assert.Regexp(t, `^portainer-secrets_app_web_[0-9a-f]{12}$`, holderName)
+	assert.Equal(t, holderName, HolderName("app", "web", references))
+	assert.NotEqual(t, holderName, HolderName("app", "web", references[:1]))
+	assert.NotEqual(t, holderName, HolderName("app", "worker", references))
+
+	assert.Equal(t, map[string]string{
+		"/etc/app":     holderName + "_0",
+		"/run/secrets": holderName + "_1",
+	}, Volumes("app", "web", references))
+}
+
+func Test_secretsArchive(t *testing.T) {
+	archive, err := secretsArchive(map[string]string{"db_password": "s3cr3t", "api_key": "key"}, time.Unix(1587399600, 0))
+	assert.NoError(t, err)
+
+	reader := tar.NewReader(archive)
+	files := make(map[string]string)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, int64(secretFileMode), header.Mode)
+
+		content, err := ioutil.ReadAll(reader)
+		assert.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+
+	assert.Equal(t, map[string]string{"db_password": "s3cr3t", "api_key": "key"}, files)
+}
+
+func Test_endpointStacks_shouldOnlyReturnTheComposeStacksReferencingSecretsOnTheEndpoint(t *testing.T) {
+	references := []portainer.SecretReference{{Name: "db_password"}}
+
+	dataStore := testhelpers.NewDatastore(testhelpers.WithStacks([]portainer.Stack{
+		{ID: 1, Name: "app", EndpointID: 1, Type: portainer.DockerComposeStack, Secrets: map[string][]portainer.SecretReference{"db": references}},
+		{ID: 2, Name: "remote", EndpointID: 2, Type: portainer.DockerComposeStack, Secrets: map[string][]portainer.SecretReference{"db": references},
+			Deployments: []portainer.StackDeployment{{EndpointID: 1}}},
+		{ID: 3, Name: "other", EndpointID: 2, Type: portainer.DockerComposeStack, Secrets: map[string][]portainer.SecretReference{"db": references}},
+		{ID: 4, Name: "plain", EndpointID: 1, Type: portainer.DockerComposeStack},
+		{ID: 5, Name: "swarm", EndpointID: 1, Type: portainer.DockerSwarmStack, Secrets: map[string][]portainer.SecretReference{"db": references}},
+	}))
+
+	service := NewService(dataStore, nil, nil, nil)
+
+	stacks, err := service.endpointStacks(1)
+	assert.NoError(t, err)
+	assert.Len(t, stacks, 2)
+	assert.Equal(t, portainer.StackID(1), stacks["app"].ID)
+	assert.Equal(t, portainer.StackID(2), stacks["remote"].ID)
+}
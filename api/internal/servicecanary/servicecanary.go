@@ -0,0 +1,283 @@
+package servicecanary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/scheduler"
+	"github.com/portainer/portainer/api/internal/snapshot"
+)
+
+const (
+	// CheckJobID is the identifier of the job completing the canary updates at the end of their bake time
+	CheckJobID = "service_canary"
+
+	checkInterval = 15 * time.Second
+
+	// LabelReplicas is set on a service while a canary update is in progress
+	// and holds the number of tasks updated during the canary phase
+	LabelReplicas = "io.portainer.canary.replicas"
+	// LabelUpdateConfig holds the update configuration of the service before the canary update
+	LabelUpdateConfig = "io.portainer.canary.updateconfig"
+	// LabelImage holds the image of the service before the canary update
+	LabelImage = "io.portainer.canary.image"
+	// LabelDeadline holds the unix timestamp of the end of the bake time
+	LabelDeadline = "io.portainer.canary.deadline"
+	// LabelManualPromotion is set when the canary requires a manual promotion
+	LabelManualPromotion = "io.portainer.canary.manual"
+
+	// holdMargin is added to the bake time to compute the update delay holding the rollout after the canary batch,
+	// so that Swarm does not resume the rollout before the canary is completed at the end of the bake time
+	holdMargin = 1 * time.Minute
+)
+
+var (
+	// ErrCanaryInProgress is returned when a canary update is started on a service with a canary update in progress
+	ErrCanaryInProgress = errors.New("A canary update is already in progress for this service")
+	// ErrNoCanaryInProgress is returned when a canary update is completed on a service without canary update in progress
+	ErrNoCanaryInProgress = errors.New("No canary update in progress for this service")
+	// ErrServiceNotReplicated is returned when a canary update is started on a global service
+	ErrServiceNotReplicated = errors.New("Canary updates are only supported for replicated services")
+)
+
+// Service completes the canary updates of the Swarm services at the end of their bake time. The canary tasks are
+// updated with an update delay holding the rest of the rollout, the canary is then promoted, which restores the update
+// configuration of the service for the rest of the rollout, or aborted, which restores the previous image.
+type Service struct {
+	dataStore     portainer.DataStore
+	clientFactory *docker.ClientFactory
+	scheduler     *scheduler.Scheduler
+}
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, clientFactory *docker.ClientFactory, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		clientFactory: clientFactory,
+		scheduler:     scheduler,
+	}
+}
+
+// Start registers the completion of the canary updates in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          CheckJobID,
+		Description: "Promote or abort the canary updates of the Swarm services at the end of their bake time",
+		Interval:    checkInterval,
+		Run:         service.check,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,servicecanary] [message: unable to schedule the completion of the canary updates] [error: %s]", err)
+	}
+}
+
+func (service *Service) check() error {
+	endpoints, err := service.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if !isSwarmEndpoint(endpoint) || endpoint.Status != portainer.EndpointStatusUp {
+			continue
+		}
+
+		err := service.checkEndpoint(endpoint, time.Now())
+		if err != nil {
+			log.Printf("[ERROR] [internal,servicecanary] [endpoint: %s] [message: unable to complete the canary updates] [error: %s]", endpoint.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (service *Service) checkEndpoint(endpoint *portainer.Endpoint, now time.Time) error {
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	services, err := cli.ServiceList(context.Background(), types.ServiceListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", LabelDeadline)),
+	})
+	if err != nil {
+		return err
+	}
+
+	for idx := range services {
+		swarmService := &services[idx]
+
+		deadline, err := strconv.ParseInt(swarmService.Spec.Labels[LabelDeadline], 10, 64)
+		if err != nil || now.Unix() < deadline {
+			continue
+		}
+
+		err = Complete(cli, swarmService)
+		if err != nil {
+			log.Printf("[ERROR] [internal,servicecanary] [service: %s] [message: unable to complete the canary update] [error: %s]", swarmService.Spec.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Complete completes the canary update of a service at the end of its bake time. The canary is promoted unless
+// it requires a manual promotion or the update was paused because of a failure of the canary tasks, it is aborted otherwise.
+func Complete(cli *client.Client, service *swarm.Service) error {
+	if service.Spec.Labels[LabelManualPromotion] == "true" || isUpdatePaused(service) {
+		return Abort(cli, service)
+	}
+	return Promote(cli, service)
+}
+
+// Promote completes the rollout of the canary update with the update configuration of the service
+// defined before the canary update
+func Promote(cli *client.Client, service *swarm.Service) error {
+	spec, err := PromotedSpec(service.Spec)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.ServiceUpdate(context.Background(), service.ID, service.Version, spec, types.ServiceUpdateOptions{})
+	return err
+}
+
+// Abort restores the image of the service defined before the canary update, with the update configuration
+// of the service defined before the canary update
+func Abort(cli *client.Client, service *swarm.Service) error {
+	spec, err := AbortedSpec(service.Spec)
+	if err != nil {
+		return err
+	}
+
+	_, err = cli.ServiceUpdate(context.Background(), service.ID, service.Version, spec, types.ServiceUpdateOptions{})
+	return err
+}
+
+// CanarySpec returns the specification of a service updating the canary tasks to the image. The rollout is held
+// after the canary tasks for the bake time, the previous image, update configuration and the end of the bake time
+// are recorded in the labels of the service.
+func CanarySpec(spec swarm.ServiceSpec, image string, canaryReplicas int, bakeTime time.Duration, manualPromotion bool, now time.Time) (swarm.ServiceSpec, error) {
+	if spec.Labels[LabelReplicas] != "" {
+		return spec, ErrCanaryInProgress
+	}
+
+	if spec.Mode.Replicated == nil || spec.Mode.Replicated.Replicas == nil {
+		return spec, ErrServiceNotReplicated
+	}
+
+	previousUpdateConfig, err := json.Marshal(spec.UpdateConfig)
+	if err != nil {
+		return spec, err
+	}
+
+	labels := make(map[string]string, len(spec.Labels)+5)
+	for key, value := range spec.Labels {
+		labels[key] = value
+	}
+	labels[LabelReplicas] = strconv.Itoa(canaryReplicas)
+	labels[LabelUpdateConfig] = string(previousUpdateConfig)
+	labels[LabelImage] = spec.TaskTemplate.ContainerSpec.Image
+	labels[LabelDeadline] = strconv.FormatInt(now.Add(bakeTime).Unix(), 10)
+	if manualPromotion {
+		labels[LabelManualPromotion] = "true"
+	}
+
+	canaryUpdateConfig := swarm.UpdateConfig{}
+	if spec.UpdateConfig != nil {
+		canaryUpdateConfig = *spec.UpdateConfig
+	}
+	canaryUpdateConfig.Parallelism = uint64(canaryReplicas)
+	canaryUpdateConfig.Delay = bakeTime + holdMargin
+	canaryUpdateConfig.FailureAction = swarm.UpdateFailureActionPause
+
+	containerSpec := *spec.TaskTemplate.ContainerSpec
+	containerSpec.Image = image
+
+	spec.Labels = labels
+	spec.UpdateConfig = &canaryUpdateConfig
+	spec.TaskTemplate.ContainerSpec = &containerSpec
+
+	return spec, nil
+}
+
+// PromotedSpec returns the specification of a service with a canary update in progress where the update configuration
+// and the labels defined before the canary update are restored
+func PromotedSpec(spec swarm.ServiceSpec) (swarm.ServiceSpec, error) {
+	if spec.Labels[LabelReplicas] == "" {
+		return spec, ErrNoCanaryInProgress
+	}
+
+	var previousUpdateConfig *swarm.UpdateConfig
+	err := json.Unmarshal([]byte(spec.Labels[LabelUpdateConfig]), &previousUpdateConfig)
+	if err != nil {
+		return spec, err
+	}
+
+	labels := make(map[string]string, len(spec.Labels))
+	for key, value := range spec.Labels {
+		switch key {
+		case LabelReplicas, LabelUpdateConfig, LabelImage, LabelDeadline, LabelManualPromotion:
+			continue
+		}
+		labels[key] = value
+	}
+
+	spec.Labels = labels
+	spec.UpdateConfig = previousUpdateConfig
+
+	return spec, nil
+}
+
+// AbortedSpec returns the specification of a service with a canary update in progress where the image,
+// the update configuration and the labels defined before the canary update are restored
+func AbortedSpec(spec swarm.ServiceSpec) (swarm.ServiceSpec, error) {
+	previousImage := spec.Labels[LabelImage]
+
+	spec, err := PromotedSpec(spec)
+	if err != nil {
+		return spec, err
+	}
+
+	if previousImage == "" {
+		return spec, errors.New("Unable to retrieve the image of the service before the canary update")
+	}
+
+	containerSpec := *spec.TaskTemplate.ContainerSpec
+	containerSpec.Image = previousImage
+	spec.TaskTemplate.ContainerSpec = &containerSpec
+
+	return spec, nil
+}
+
+// Deadline returns the end of the bake time of the canary update of a service
+func Deadline(spec swarm.ServiceSpec) time.Time {
+	deadline, _ := strconv.ParseInt(spec.Labels[LabelDeadline], 10, 64)
+	return time.Unix(deadline, 0)
+}
+
+func isUpdatePaused(service *swarm.Service) bool {
+	return service.UpdateStatus != nil && service.UpdateStatus.State == swarm.UpdateStatePaused
+}
+
+func isSwarmEndpoint(endpoint *portainer.Endpoint) bool {
+	if endpoint.Type != portainer.DockerEnvironment && endpoint.Type != portainer.AgentOnDockerEnvironment {
+		return false
+	}
+	if !snapshot.SupportDirectSnapshot(endpoint) {
+		return false
+	}
+	return len(endpoint.Snapshots) > 0 && endpoint.Snapshots[0].Swarm
+}
@@ -0,0 +1,85 @@
+package servicecanary
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func serviceSpec(updateConfig *swarm.UpdateConfig) swarm.ServiceSpec {
+	replicas := uint64(10)
+	return swarm.ServiceSpec{
+		Annotations: swarm.Annotations{Name: "web", Labels: map[string]string{"team": "frontend"}},
+		TaskTemplate: swarm.TaskSpec{
+			ContainerSpec: &swarm.ContainerSpec{Image: "nginx:1.18"},
+		},
+		Mode:         swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+		UpdateConfig: updateConfig,
+	}
+}
+
+func Test_CanarySpec_shouldHoldTheRolloutAfterTheCanaryTasks(t *testing.T) {
+	original := serviceSpec(&swarm.UpdateConfig{Parallelism: 2, Delay: 10 * time.Second, FailureAction: swarm.UpdateFailureActionRollback})
+
+	spec, err := CanarySpec(original, "nginx:1.19", 1, 5*time.Minute, false, time.Unix(1000, 0))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "nginx:1.19", spec.TaskTemplate.ContainerSpec.Image)
+	assert.Equal(t, uint64(1), spec.UpdateConfig.Parallelism)
+	assert.Equal(t, 5*time.Minute+holdMargin, spec.UpdateConfig.Delay)
+	assert.Equal(t, swarm.UpdateFailureActionPause, spec.UpdateConfig.FailureAction)
+	assert.Equal(t, "1300", spec.Labels[LabelDeadline])
+	assert.Equal(t, "nginx:1.18", spec.Labels[LabelImage])
+	assert.Empty(t, spec.Labels[LabelManualPromotion])
+
+	assert.Equal(t, "nginx:1.18", original.TaskTemplate.ContainerSpec.Image, "the original specification is not modified")
+	assert.Equal(t, map[string]string{"team": "frontend"}, original.Labels, "the original specification is not modified")
+
+	_, err = CanarySpec(spec, "nginx:1.20", 1, time.Minute, false, time.Unix(1000, 0))
+	assert.Equal(t, ErrCanaryInProgress, err)
+
+	global := serviceSpec(nil)
+	global.Mode = swarm.ServiceMode{Global: &swarm.GlobalService{}}
+	_, err = CanarySpec(global, "nginx:1.19", 1, time.Minute, false, time.Unix(1000, 0))
+	assert.Equal(t, ErrServiceNotReplicated, err)
+}
+
+func Test_PromotedSpec_shouldRestoreTheUpdateConfigurationAndTheLabels(t *testing.T) {
+	for _, updateConfig := range []*swarm.UpdateConfig{nil, {Parallelism: 2, Delay: 10 * time.Second}} {
+		original := serviceSpec(updateConfig)
+
+		canary, err := CanarySpec(original, "nginx:1.19", 1, 5*time.Minute, true, time.Unix(1000, 0))
+		assert.NoError(t, err)
+
+		spec, err := PromotedSpec(canary)
+		assert.NoError(t, err)
+		assert.Equal(t, "nginx:1.19", spec.TaskTemplate.ContainerSpec.Image)
+		assert.Equal(t, original.UpdateConfig, spec.UpdateConfig)
+		assert.Equal(t, original.Labels, spec.Labels)
+	}
+
+	_, err := PromotedSpec(serviceSpec(nil))
+	assert.Equal(t, ErrNoCanaryInProgress, err)
+}
+
+func Test_AbortedSpec_shouldRestoreTheImageTheUpdateConfigurationAndTheLabels(t *testing.T) {
+	original := serviceSpec(&swarm.UpdateConfig{Parallelism: 2, Delay: 10 * time.Second})
+
+	canary, err := CanarySpec(original, "nginx:1.19", 1, 5*time.Minute, false, time.Unix(1000, 0))
+	assert.NoError(t, err)
+
+	spec, err := AbortedSpec(canary)
+	assert.NoError(t, err)
+	assert.Equal(t, original, spec)
+
+	_, err = AbortedSpec(serviceSpec(nil))
+	assert.Equal(t, ErrNoCanaryInProgress, err)
+}
+
+func Test_Deadline(t *testing.T) {
+	canary, err := CanarySpec(serviceSpec(nil), "nginx:1.19", 1, time.Minute, false, time.Unix(1000, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1060, 0), Deadline(canary))
+}
@@ -5,67 +5,78 @@ import (
 	"time"
 
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/clockskew"
+	"github.com/portainer/portainer/api/internal/scheduler"
 )
 
+// JobID is the identifier of the endpoint snapshot job in the scheduler
+const JobID = "endpoint_snapshot"
+
 // Service repesents a service to manage endpoint snapshots.
 // It provides an interface to start background snapshots as well as
 // specific Docker/Kubernetes endpoint snapshot methods.
 type Service struct {
-	dataStore                 portainer.DataStore
-	refreshSignal             chan struct{}
-	snapshotIntervalInSeconds float64
-	dockerSnapshotter         portainer.DockerSnapshotter
-	kubernetesSnapshotter     portainer.KubernetesSnapshotter
+	dataStore             portainer.DataStore
+	scheduler             *scheduler.Scheduler
+	snapshotInterval      time.Duration
+	dockerSnapshotter     portainer.DockerSnapshotter
+	kubernetesSnapshotter portainer.KubernetesSnapshotter
 }
 
 // NewService creates a new instance of a service
-func NewService(snapshotInterval string, dataStore portainer.DataStore, dockerSnapshotter portainer.DockerSnapshotter, kubernetesSnapshotter portainer.KubernetesSnapshotter) (*Service, error) {
+func NewService(snapshotInterval string, dataStore portainer.DataStore, dockerSnapshotter portainer.DockerSnapshotter, kubernetesSnapshotter portainer.KubernetesSnapshotter, scheduler *scheduler.Scheduler) (*Service, error) {
 	snapshotFrequency, err := time.ParseDuration(snapshotInterval)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Service{
-		dataStore:                 dataStore,
-		snapshotIntervalInSeconds: snapshotFrequency.Seconds(),
-		dockerSnapshotter:         dockerSnapshotter,
-		kubernetesSnapshotter:     kubernetesSnapshotter,
+		dataStore:             dataStore,
+		scheduler:             scheduler,
+		snapshotInterval:      snapshotFrequency,
+		dockerSnapshotter:     dockerSnapshotter,
+		kubernetesSnapshotter: kubernetesSnapshotter,
 	}, nil
 }
 
-// Start will start a background routine to execute periodic snapshots of endpoints
+// Start registers the periodic snapshot of endpoints in the scheduler
 func (service *Service) Start() {
-	if service.refreshSignal != nil {
-		return
+	err := service.scheduler.Register(scheduler.Job{
+		ID:              JobID,
+		Description:     "Create a snapshot of each endpoint",
+		Interval:        service.snapshotInterval,
+		RunOnStart:      true,
+		Run:             service.snapshotEndpoints,
+		IntervalUpdated: service.persistSnapshotInterval,
+		Subsystem:       scheduler.SubsystemSnapshot,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,snapshot] [message: unable to schedule endpoint snapshots] [error: %s]", err)
 	}
-
-	service.refreshSignal = make(chan struct{})
-	service.startSnapshotLoop()
 }
 
-func (service *Service) stop() {
-	if service.refreshSignal == nil {
-		return
+// SetSnapshotInterval sets the snapshot interval and reschedules the snapshot job
+func (service *Service) SetSnapshotInterval(snapshotInterval string) error {
+	snapshotFrequency, err := time.ParseDuration(snapshotInterval)
+	if err != nil {
+		return err
 	}
+	service.snapshotInterval = snapshotFrequency
 
-	// clear refreshSignal to mark the service as disabled
-	close(service.refreshSignal)
-	service.refreshSignal = nil
+	return service.scheduler.SetInterval(JobID, snapshotFrequency)
 }
 
-// SetSnapshotInterval sets the snapshot interval and resets the service
-func (service *Service) SetSnapshotInterval(snapshotInterval string) error {
-	service.stop()
-
-	snapshotFrequency, err := time.ParseDuration(snapshotInterval)
+// persistSnapshotInterval saves the snapshot interval in the settings when it is updated through the scheduler
+func (service *Service) persistSnapshotInterval(interval time.Duration) error {
+	settings, err := service.dataStore.Settings().Settings()
 	if err != nil {
 		return err
 	}
-	service.snapshotIntervalInSeconds = snapshotFrequency.Seconds()
 
-	service.Start()
+	settings.SnapshotInterval = interval.String()
+	service.snapshotInterval = interval
 
-	return nil
+	return service.dataStore.Settings().UpdateSettings(settings)
 }
 
 // SupportDirectSnapshot checks whether an endpoint can be used to trigger a direct a snapshot.
@@ -112,38 +123,12 @@ func (service *Service) snapshotDockerEndpoint(endpoint *portainer.Endpoint) err
 
 	if snapshot != nil {
 		endpoint.Snapshots = []portainer.DockerSnapshot{*snapshot}
+		clockskew.UpdateEndpoint(endpoint, snapshot)
 	}
 
 	return nil
 }
 
-func (service *Service) startSnapshotLoop() error {
-	ticker := time.NewTicker(time.Duration(service.snapshotIntervalInSeconds) * time.Second)
-	go func() {
-		err := service.snapshotEndpoints()
-		if err != nil {
-			log.Printf("[ERROR] [internal,snapshot] [message: background schedule error (endpoint snapshot).] [error: %s]", err)
-		}
-
-		for {
-			select {
-			case <-ticker.C:
-				err := service.snapshotEndpoints()
-				if err != nil {
-					log.Printf("[ERROR] [internal,snapshot] [message: background schedule error (endpoint snapshot).] [error: %s]", err)
-				}
-
-			case <-service.refreshSignal:
-				log.Println("[DEBUG] [internal,snapshot] [message: shutting down Snapshot service]")
-				ticker.Stop()
-				return
-			}
-		}
-	}()
-
-	return nil
-}
-
 func (service *Service) snapshotEndpoints() error {
 	endpoints, err := service.dataStore.Endpoint().Endpoints()
 	if err != nil {
@@ -169,8 +154,13 @@ func (service *Service) snapshotEndpoints() error {
 			latestEndpointReference.Status = portainer.EndpointStatusDown
 		}
 
+		if endpoint.ClockSkew.Exceeded && !latestEndpointReference.ClockSkew.Exceeded {
+			log.Printf("[WARN] [internal,snapshot] [endpoint: %s] [message: system time of the endpoint differs from the host clock by more than the threshold] [skew_ms: %d]", endpoint.Name, endpoint.ClockSkew.Skew)
+		}
+
 		latestEndpointReference.Snapshots = endpoint.Snapshots
 		latestEndpointReference.Kubernetes.Snapshots = endpoint.Kubernetes.Snapshots
+		latestEndpointReference.ClockSkew = endpoint.ClockSkew
 
 		err = service.dataStore.Endpoint().UpdateEndpoint(latestEndpointReference.ID, latestEndpointReference)
 		if err != nil {
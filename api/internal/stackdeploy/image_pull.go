@@ -0,0 +1,191 @@
+package stackdeploy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/registryclient"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+// ImagePullError is returned when an image of a stack cannot be pulled while the always pull policy applies
+type ImagePullError struct {
+	Image string
+	Err   error
+}
+
+func (err *ImagePullError) Error() string {
+	return fmt.Sprintf("Unable to pull image %s: %s", err.Image, err.Err)
+}
+
+// pullStackImages pulls the images of the stack before it is deployed when the always pull policy is enabled
+// on the stack or in the settings. When the registry rate limit is exhausted, the image cached on the endpoint
+// is used and the skipped pull is recorded on the stack, the deployment fails when the image is not cached.
+// The images built on the endpoint for the stack are not pulled. It only applies to Compose stacks, a pull on the
+// manager node used to deploy a Swarm stack does not update the images of the other nodes: the Swarm stacks are
+// deployed with their images resolved to their digest on the registry, which each node pulls.
+func (service *Service) pullStackImages(stack *portainer.Stack, endpoint *portainer.Endpoint, dockerhub *portainer.DockerHub, registries []portainer.Registry) error {
+	stack.SkippedImagePulls = nil
+
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	if !stack.AlwaysPullImages && !settings.AlwaysPullImages {
+		return nil
+	}
+
+	stackContent, err := service.fileService.GetFileContent(path.Join(stack.ProjectPath, stack.EntryPoint))
+	if err != nil {
+		return err
+	}
+
+	images, err := stackutils.ComposeFilePullableImages(stackContent, stack.Env)
+	if err != nil {
+		return err
+	}
+	images = excludeLocalImages(images, stack)
+
+	if len(images) == 0 {
+		return nil
+	}
+
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	for _, image := range images {
+		err = pullImage(cli, image, dockerhub, registries)
+		if err == nil {
+			continue
+		}
+
+		if !isRateLimitError(err) {
+			return &ImagePullError{Image: image, Err: err}
+		}
+
+		_, _, inspectErr := cli.ImageInspectWithRaw(context.Background(), image)
+		if inspectErr != nil {
+			return &ImagePullError{Image: image, Err: err}
+		}
+
+		log.Printf("Warning: pull rate limit exhausted for image %s of stack %s, using the cached image: %s\n", image, stack.Name, err)
+
+		stack.SkippedImagePulls = append(stack.SkippedImagePulls, portainer.StackSkippedImagePull{
+			Image:  image,
+			Reason: err.Error(),
+			Date:   time.Now().Unix(),
+		})
+	}
+
+	return nil
+}
+
+// excludeLocalImages returns the images that are not built on the endpoint for the stack
+func excludeLocalImages(images []string, stack *portainer.Stack) []string {
+	if len(stack.LocalImages) == 0 {
+		return images
+	}
+
+	local := make(map[string]bool, len(stack.LocalImages))
+	for _, image := range stack.LocalImages {
+		local[image] = true
+	}
+
+	remote := make([]string, 0, len(images))
+	for _, image := range images {
+		if !local[image] {
+			remote = append(remote, image)
+		}
+	}
+	return remote
+}
+
+func pullImage(cli *client.Client, image string, dockerhub *portainer.DockerHub, registries []portainer.Registry) error {
+	registryAuth, err := imageRegistryAuth(image, dockerhub, registries)
+	if err != nil {
+		return err
+	}
+
+	body, err := cli.ImagePull(context.Background(), image, types.ImagePullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		var message jsonmessage.JSONMessage
+		err := decoder.Decode(&message)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if message.Error != nil {
+			return message.Error
+		}
+	}
+}
+
+// imageRegistryAuth returns the encoded credentials of the registry hosting the image,
+// an empty string is returned when no credentials are defined for the registry
+func imageRegistryAuth(image string, dockerhub *portainer.DockerHub, registries []portainer.Registry) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+
+	var authConfig *types.AuthConfig
+
+	registry := reference.Domain(named)
+	if registry == registryclient.DockerHubRegistry {
+		if dockerhub != nil && dockerhub.Authentication {
+			authConfig = &types.AuthConfig{Username: dockerhub.Username, Password: dockerhub.Password}
+		}
+	} else {
+		for _, portainerRegistry := range registries {
+			if portainerRegistry.Authentication && strings.EqualFold(registryclient.NormalizeRegistry(portainerRegistry.URL), registry) {
+				authConfig = &types.AuthConfig{
+					Username:      portainerRegistry.Username,
+					Password:      portainerRegistry.Password,
+					ServerAddress: portainerRegistry.URL,
+				}
+				break
+			}
+		}
+	}
+
+	if authConfig == nil {
+		return "", nil
+	}
+
+	encoded, err := json.Marshal(authConfig)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(encoded), nil
+}
+
+// isRateLimitError returns whether a pull failed because the registry rate limit is exhausted
+func isRateLimitError(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "toomanyrequests") || strings.Contains(message, "rate limit")
+}
@@ -0,0 +1,447 @@
+package stackdeploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/docker/cli/cli/compose/loader"
+	"github.com/docker/cli/cli/compose/types"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/http/security"
+	"github.com/portainer/portainer/api/internal/imagetrust"
+	"github.com/portainer/portainer/api/internal/secret"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+// ErrStackSecretsAdminOnly is returned when a regular user deploys a stack referencing secrets
+var ErrStackSecretsAdminOnly = errors.New("Only administrators can deploy stacks referencing secrets")
+
+type (
+	// Service deploys the Compose and Swarm stacks. The deployments requested by the users and the deployments
+	// triggered by Portainer, such as the reconciliation of a drifted stack or the revert of a failed update,
+	// go through the same checks, registry authentication and serialization.
+	Service struct {
+		dataStore           portainer.DataStore
+		fileService         portainer.FileService
+		clientFactory       *docker.ClientFactory
+		composeStackManager portainer.ComposeStackManager
+		swarmStackManager   portainer.SwarmStackManager
+		imageVerifier       *imagetrust.Verifier
+		secretService       *secret.Service
+		// TODO: libcompose uses credentials store into a config.json file to pull images from
+		// private registries. Right now the only solution is to re-use the embedded Docker binary
+		// to login/logout, which will generate the required data in the config.json file and then
+		// clean it. Hence the use of the mutex.
+		// We should contribute to libcompose to support authentication without using the config.json file.
+		stackCreationMutex sync.Mutex
+	}
+
+	// Config represents the deployment of a stack on an endpoint on behalf of a user
+	Config struct {
+		Stack      *portainer.Stack
+		Endpoint   *portainer.Endpoint
+		DockerHub  *portainer.DockerHub
+		Registries []portainer.Registry
+		// Remove the services that are no longer referenced by the stack file: the services of a Swarm stack,
+		// the containers of the orphan services of a Compose stack
+		Prune   bool
+		IsAdmin bool
+		User    *portainer.User
+	}
+)
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, fileService portainer.FileService, clientFactory *docker.ClientFactory, composeStackManager portainer.ComposeStackManager, swarmStackManager portainer.SwarmStackManager, imageVerifier *imagetrust.Verifier, secretService *secret.Service) *Service {
+	return &Service{
+		dataStore:           dataStore,
+		fileService:         fileService,
+		clientFactory:       clientFactory,
+		composeStackManager: composeStackManager,
+		swarmStackManager:   swarmStackManager,
+		imageVerifier:       imageVerifier,
+		secretService:       secretService,
+	}
+}
+
+// NewAutomaticConfig returns the configuration of a deployment triggered by Portainer rather than by a user.
+// The stack is deployed with the permissions and the registries of the user who last deployed it.
+func (service *Service) NewAutomaticConfig(stack *portainer.Stack, endpoint *portainer.Endpoint, prune bool) (*Config, error) {
+	username := stack.UpdatedBy
+	if username == "" {
+		username = stack.CreatedBy
+	}
+
+	user, err := service.dataStore.User().UserByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve the user %s who last deployed the stack: %s", username, err)
+	}
+
+	memberships, err := service.dataStore.TeamMembership().TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerhub, err := service.dataStore.DockerHub().DockerHub()
+	if err != nil {
+		return nil, err
+	}
+
+	registries, err := service.dataStore.Registry().Registries()
+	if err != nil {
+		return nil, err
+	}
+
+	securityContext := &security.RestrictedRequestContext{
+		IsAdmin:         user.Role == portainer.AdministratorRole,
+		UserID:          user.ID,
+		UserMemberships: memberships,
+	}
+
+	return &Config{
+		Stack:      stack,
+		Endpoint:   endpoint,
+		DockerHub:  dockerhub,
+		Registries: security.FilterRegistries(registries, securityContext),
+		Prune:      prune,
+		IsAdmin:    securityContext.IsAdmin,
+		User:       user,
+	}, nil
+}
+
+// Validate verifies that the stack can be deployed by the user: the security settings of the endpoint,
+// the Compose policy, the usage of secrets and the image trust policy.
+func (service *Service) Validate(config *Config) error {
+	_, _, err := service.validate(config)
+	return err
+}
+
+// validate verifies the stack like Validate and returns the images verified by the image trust policy,
+// pinned to the digest that was verified, per image, and the warnings raised by the image age policy
+func (service *Service) validate(config *Config) (map[string]string, []string, error) {
+	isAdminOrEndpointAdmin := config.User.Role == portainer.AdministratorRole
+	securitySettings := &config.Endpoint.SecuritySettings
+
+	restricted := !securitySettings.AllowBindMountsForRegularUsers
+	if config.Stack.Type == portainer.DockerComposeStack {
+		restricted = restricted ||
+			!securitySettings.AllowPrivilegedModeForRegularUsers ||
+			!securitySettings.AllowHostNamespaceForRegularUsers ||
+			!securitySettings.AllowDeviceMappingForRegularUsers ||
+			!securitySettings.AllowContainerCapabilitiesForRegularUsers
+	}
+
+	if restricted && !isAdminOrEndpointAdmin {
+		stackContent, err := service.stackFileContent(config.Stack)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = validateStackFile(stackContent, securitySettings)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err := service.CheckComposePolicy(config.Stack, config.IsAdmin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = service.checkSecretsUsage(config.Stack, config.IsAdmin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return service.verifyStackImages(config.Stack)
+}
+
+// DeployComposeStack validates and deploys a Compose stack
+func (service *Service) DeployComposeStack(config *Config) error {
+	pinnedImages, warnings, err := service.validate(config)
+	if err != nil {
+		return err
+	}
+
+	config.Stack.DeploymentWarnings = warnings
+	config.Stack.PinnedImages = pinnedImages
+	defer func() { config.Stack.PinnedImages = nil }()
+
+	err = service.pullStackImages(config.Stack, config.Endpoint, config.DockerHub, config.Registries)
+	if err != nil {
+		return err
+	}
+
+	err = service.composeUp(config.Stack, config.Endpoint, func(services ...string) error {
+		return service.upWithRegistryCredentials(config, services...)
+	})
+	if err != nil {
+		return err
+	}
+
+	if config.Prune {
+		return service.removeOrphanContainers(config.Stack, config.Endpoint)
+	}
+
+	return nil
+}
+
+// upWithRegistryCredentials starts the services of a Compose stack logged in to the registries of the configuration.
+// The stack creation mutex is only held while the services are started, as the credentials are shared by the deployments,
+// it is released while composeUp waits for the startup gates and the update batches so that other stacks can be deployed.
+func (service *Service) upWithRegistryCredentials(config *Config, services ...string) error {
+	service.stackCreationMutex.Lock()
+	defer service.stackCreationMutex.Unlock()
+
+	service.swarmStackManager.Login(config.DockerHub, config.Registries, config.Endpoint)
+
+	err := service.upComposeServices(config.Stack, config.Endpoint, services...)
+	if err != nil {
+		service.swarmStackManager.Logout(config.Endpoint)
+		return err
+	}
+
+	return service.swarmStackManager.Logout(config.Endpoint)
+}
+
+// DeploySwarmStack validates and deploys a Swarm stack
+func (service *Service) DeploySwarmStack(config *Config) error {
+	pinnedImages, warnings, err := service.validate(config)
+	if err != nil {
+		return err
+	}
+
+	config.Stack.DeploymentWarnings = warnings
+	config.Stack.PinnedImages = pinnedImages
+	defer func() { config.Stack.PinnedImages = nil }()
+
+	service.stackCreationMutex.Lock()
+	defer service.stackCreationMutex.Unlock()
+
+	service.swarmStackManager.Login(config.DockerHub, config.Registries, config.Endpoint)
+
+	err = service.swarmStackManager.Deploy(config.Stack, config.Prune, config.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	return service.swarmStackManager.Logout(config.Endpoint)
+}
+
+// Deploy validates and deploys a Compose or a Swarm stack
+func (service *Service) Deploy(config *Config) error {
+	if config.Stack.Type == portainer.DockerSwarmStack {
+		return service.DeploySwarmStack(config)
+	}
+	return service.DeployComposeStack(config)
+}
+
+// ComposeUp starts the services of a Compose stack without verifying the stack, see composeUp
+func (service *Service) ComposeUp(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	return service.composeUp(stack, endpoint, func(services ...string) error {
+		return service.upComposeServices(stack, endpoint, services...)
+	})
+}
+
+// upComposeServices starts the services of a compose stack, all the services when none is specified.
+// The secrets of the services are materialized before their containers are created or started, and the
+// secrets resources that are no longer used are removed once all the services are started.
+func (service *Service) upComposeServices(stack *portainer.Stack, endpoint *portainer.Endpoint, services ...string) error {
+	if service.secretService == nil {
+		return service.composeStackManager.Up(stack, endpoint, services...)
+	}
+
+	err := service.secretService.MaterializeStack(endpoint, stack, services...)
+	if err != nil {
+		return err
+	}
+
+	err = service.composeStackManager.Up(stack, endpoint, services...)
+	if err != nil || len(services) > 0 {
+		return err
+	}
+
+	return service.secretService.RemoveStaleResources(endpoint, stack)
+}
+
+// removeOrphanContainers removes the containers of the services of a Compose stack that are no longer
+// defined in the stack file, the equivalent of docker-compose up --remove-orphans
+func (service *Service) removeOrphanContainers(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	stackContent, err := service.stackFileContent(stack)
+	if err != nil {
+		return err
+	}
+
+	services, err := stackutils.ComposeServicesInDependencyOrder(stackContent)
+	if err != nil {
+		return err
+	}
+
+	defined := make(map[string]bool, len(services))
+	for _, name := range services {
+		defined[name] = true
+	}
+
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	containers, err := cli.ContainerList(context.Background(), dockertypes.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", composeProjectLabel+"="+stack.Name)),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, container := range containers {
+		if defined[container.Labels[composeServiceLabel]] {
+			continue
+		}
+
+		err = cli.ContainerRemove(context.Background(), container.ID, dockertypes.ContainerRemoveOptions{Force: true})
+		if err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (service *Service) stackFileContent(stack *portainer.Stack) ([]byte, error) {
+	return service.fileService.GetFileContent(path.Join(stack.ProjectPath, stack.EntryPoint))
+}
+
+// CheckComposePolicy verifies the stack file against the Compose policy of the settings.
+// The stacks deployed by administrators are not verified.
+func (service *Service) CheckComposePolicy(stack *portainer.Stack, isAdmin bool) error {
+	if isAdmin {
+		return nil
+	}
+
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	if !settings.ComposePolicy.Enabled {
+		return nil
+	}
+
+	stackContent, err := service.stackFileContent(stack)
+	if err != nil {
+		return err
+	}
+
+	return stackutils.CheckComposePolicy(stackContent, stack.Env, &settings.ComposePolicy)
+}
+
+// checkSecretsUsage verifies that the stacks referencing secrets, through the secrets of the stack or
+// the secrets label in the stack file, are only deployed by administrators
+func (service *Service) checkSecretsUsage(stack *portainer.Stack, isAdmin bool) error {
+	if isAdmin {
+		return nil
+	}
+
+	if len(stack.Secrets) > 0 {
+		return ErrStackSecretsAdminOnly
+	}
+
+	stackContent, err := service.stackFileContent(stack)
+	if err != nil {
+		return err
+	}
+
+	usesLabel, err := stackutils.ComposeFileUsesLabel(stackContent, secret.SecretsLabel)
+	if err != nil {
+		return err
+	}
+	if usesLabel {
+		return ErrStackSecretsAdminOnly
+	}
+
+	return nil
+}
+
+// verifyStackImages verifies the images referenced by the stack file against the image trust policy and the image age policy,
+// the images built on the endpoint for the stack are not verified. The verified images are returned
+// pinned to the digest that was verified, per image, with the warnings raised by the image age policy.
+func (service *Service) verifyStackImages(stack *portainer.Stack) (map[string]string, []string, error) {
+	if service.imageVerifier == nil {
+		return nil, nil, nil
+	}
+
+	stackContent, err := service.stackFileContent(stack)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	images, err := stackutils.ComposeFileImages(stackContent, stack.Env)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return service.imageVerifier.VerifyImages(excludeLocalImages(images, stack))
+}
+
+// validateStackFile verifies the services of the stack file against the security settings of the endpoint
+func validateStackFile(stackFileContent []byte, securitySettings *portainer.EndpointSecuritySettings) error {
+	composeConfigYAML, err := loader.ParseYAML(stackFileContent)
+	if err != nil {
+		return err
+	}
+
+	composeConfigFile := types.ConfigFile{
+		Config: composeConfigYAML,
+	}
+
+	composeConfigDetails := types.ConfigDetails{
+		ConfigFiles: []types.ConfigFile{composeConfigFile},
+		Environment: map[string]string{},
+	}
+
+	composeConfig, err := loader.Load(composeConfigDetails, func(options *loader.Options) {
+		options.SkipValidation = true
+		options.SkipInterpolation = true
+	})
+	if err != nil {
+		return err
+	}
+
+	for key := range composeConfig.Services {
+		service := composeConfig.Services[key]
+		if !securitySettings.AllowBindMountsForRegularUsers {
+			for _, volume := range service.Volumes {
+				if volume.Type == "bind" {
+					return errors.New("bind-mount disabled for non administrator users")
+				}
+			}
+		}
+
+		if !securitySettings.AllowPrivilegedModeForRegularUsers && service.Privileged == true {
+			return errors.New("privileged mode disabled for non administrator users")
+		}
+
+		if !securitySettings.AllowHostNamespaceForRegularUsers && service.Pid == "host" {
+			return errors.New("pid host disabled for non administrator users")
+		}
+
+		if !securitySettings.AllowDeviceMappingForRegularUsers && service.Devices != nil && len(service.Devices) > 0 {
+			return errors.New("device mapping disabled for non administrator users")
+		}
+
+		if !securitySettings.AllowContainerCapabilitiesForRegularUsers && (len(service.CapAdd) > 0 || len(service.CapDrop) > 0) {
+			return errors.New("container capabilities disabled for non administrator users")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package stackdeploy
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewAutomaticConfig_shouldUseTheUserWhoLastDeployedTheStack(t *testing.T) {
+	dataStore := testhelpers.NewDatastore(
+		testhelpers.WithUsers([]portainer.User{
+			{ID: 1, Username: "admin", Role: portainer.AdministratorRole},
+			{ID: 2, Username: "creator", Role: portainer.StandardUserRole},
+			{ID: 3, Username: "updater", Role: portainer.StandardUserRole},
+		}),
+		testhelpers.WithTeamMemberships([]portainer.TeamMembership{
+			{ID: 1, UserID: 3, TeamID: 10},
+		}),
+		testhelpers.WithDockerHub(&portainer.DockerHub{}),
+		testhelpers.WithRegistries([]portainer.Registry{
+			{ID: 1, URL: "creator.example.com", UserAccessPolicies: portainer.UserAccessPolicies{2: {}}},
+			{ID: 2, URL: "team.example.com", TeamAccessPolicies: portainer.TeamAccessPolicies{10: {}}},
+			{ID: 3, URL: "private.example.com"},
+		}),
+	)
+	service := NewService(dataStore, nil, nil, nil, nil, nil, nil)
+	endpoint := &portainer.Endpoint{ID: 1}
+
+	config, err := service.NewAutomaticConfig(&portainer.Stack{CreatedBy: "creator", UpdatedBy: "updater"}, endpoint, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "updater", config.User.Username)
+	assert.False(t, config.IsAdmin)
+	if assert.Len(t, config.Registries, 1) {
+		assert.Equal(t, "team.example.com", config.Registries[0].URL)
+	}
+
+	config, err = service.NewAutomaticConfig(&portainer.Stack{CreatedBy: "creator"}, endpoint, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "creator", config.User.Username)
+	assert.True(t, config.Prune)
+	if assert.Len(t, config.Registries, 1) {
+		assert.Equal(t, "creator.example.com", config.Registries[0].URL)
+	}
+
+	config, err = service.NewAutomaticConfig(&portainer.Stack{CreatedBy: "admin"}, endpoint, false)
+	assert.NoError(t, err)
+	assert.True(t, config.IsAdmin)
+	assert.Len(t, config.Registries, 3)
+
+	_, err = service.NewAutomaticConfig(&portainer.Stack{CreatedBy: "removed"}, endpoint, false)
+	assert.Error(t, err)
+}
+
+func Test_validateStackFile(t *testing.T) {
+	stackFile := []byte(`version: "3"
+services:
+  web:
+    image: nginx
+    privileged: true
+`)
+
+	assert.NoError(t, validateStackFile(stackFile, &portainer.EndpointSecuritySettings{AllowPrivilegedModeForRegularUsers: true}))
+	assert.Error(t, validateStackFile(stackFile, &portainer.EndpointSecuritySettings{AllowPrivilegedModeForRegularUsers: false}))
+}
+
+func Test_excludeLocalImages(t *testing.T) {
+	stack := &portainer.Stack{LocalImages: []string{"web_app", "registry.example.com/worker:1.0"}}
+
+	images := excludeLocalImages([]string{"nginx:latest", "registry.example.com/worker:1.0", "web_app"}, stack)
+	assert.Equal(t, []string{"nginx:latest"}, images, "the images built for the stack are neither pulled nor verified")
+
+	images = excludeLocalImages([]string{"nginx:latest"}, &portainer.Stack{})
+	assert.Equal(t, []string{"nginx:latest"}, images)
+}
@@ -0,0 +1,331 @@
+package stackdeploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+var errEndpointHostUnknown = errors.New("the public IP of the endpoint must be set to check the published ports of the containers")
+
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	startupGatePollRate = 2 * time.Second
+)
+
+// upFunc starts the services of a compose stack, all the services when none is specified
+type upFunc func(services ...string) error
+
+// composeUp starts a compose stack with up. When the stack defines a startup order, each group of services is started
+// after the gate of the previous group is met, the services that are not part of a group are started last.
+// When the stack defines an update concurrency, the services are updated in batches, see upServices.
+// The waits for the gates and the batches happen between the calls to up, so that up can hold the locks required
+// to start the services without holding them while waiting.
+func (service *Service) composeUp(stack *portainer.Stack, endpoint *portainer.Endpoint, up upFunc) error {
+	if len(stack.StartupOrder) == 0 && stack.UpdateConcurrency == 0 {
+		return up()
+	}
+
+	composeFilePath := path.Join(stack.ProjectPath, stack.EntryPoint)
+	stackContent, err := service.fileService.GetFileContent(composeFilePath)
+	if err != nil {
+		return err
+	}
+
+	err = stackutils.CheckStartupOrderDependencies(stackContent, stack.StartupOrder)
+	if err != nil {
+		return err
+	}
+
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	grouped := make(map[string]bool)
+	for idx, group := range stack.StartupOrder {
+		err = upServices(cli, stack, endpoint, up, group.Services)
+		if err != nil {
+			return err
+		}
+
+		err = waitForStartupGate(cli, stack, endpoint, &group)
+		if err != nil {
+			return fmt.Errorf("Startup group %d of stack %s is not ready: %s", idx+1, stack.Name, err)
+		}
+
+		for _, service := range group.Services {
+			grouped[service] = true
+		}
+	}
+
+	if stack.UpdateConcurrency > 0 {
+		services, err := stackutils.ComposeServicesInDependencyOrder(stackContent)
+		if err != nil {
+			return err
+		}
+
+		remaining := make([]string, 0, len(services))
+		for _, service := range services {
+			if !grouped[service] {
+				remaining = append(remaining, service)
+			}
+		}
+
+		if len(remaining) > 0 {
+			err = upServices(cli, stack, endpoint, up, remaining)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return up()
+}
+
+// upServices starts the services. When the stack defines an update concurrency, the services are started in batches
+// of at most that many services and each batch must be running, and healthy when a healthcheck is defined,
+// before the next batch is started. This prevents a redeploy from recreating all the services at once.
+func upServices(cli *client.Client, stack *portainer.Stack, endpoint *portainer.Endpoint, up upFunc, services []string) error {
+	if stack.UpdateConcurrency == 0 {
+		return up(services...)
+	}
+
+	for _, batch := range stackutils.ServiceBatches(services, stack.UpdateConcurrency) {
+		err := up(batch...)
+		if err != nil {
+			return err
+		}
+
+		err = waitForUpdatedServices(cli, stack, endpoint, batch)
+		if err != nil {
+			return fmt.Errorf("Update of stack %s stopped, services %s are not ready: %s", stack.Name, strings.Join(batch, ", "), err)
+		}
+	}
+
+	return nil
+}
+
+// waitForUpdatedServices polls the containers of the services until they are running, and healthy when they
+// define a healthcheck. Containers that exited successfully are considered ready. It is called between the calls
+// to up, without holding the stack creation lock, as the wait can last up to the default startup gate timeout.
+func waitForUpdatedServices(cli *client.Client, stack *portainer.Stack, endpoint *portainer.Endpoint, services []string) error {
+	deadline := time.Now().Add(stackutils.DefaultStartupGateTimeout)
+
+	for {
+		ready, err := areUpdatedServicesReady(cli, stack, endpoint, services)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("services not ready after %s", stackutils.DefaultStartupGateTimeout)
+		}
+		time.Sleep(startupGatePollRate)
+	}
+}
+
+func areUpdatedServicesReady(cli *client.Client, stack *portainer.Stack, endpoint *portainer.Endpoint, services []string) (bool, error) {
+	for _, service := range services {
+		containers, err := serviceContainers(cli, stack.Name, service)
+		if err != nil {
+			return false, err
+		}
+		if len(containers) == 0 {
+			return false, nil
+		}
+
+		for _, container := range containers {
+			if container.State != nil && container.State.Status == "exited" && container.State.ExitCode == 0 {
+				continue
+			}
+
+			gate := &portainer.StackStartupGate{}
+			if container.State != nil && container.State.Health != nil {
+				gate.Type = portainer.StackStartupGateHealthy
+			}
+
+			ready, err := isContainerReady(container, endpoint, gate)
+			if err != nil {
+				return false, fmt.Errorf("service %s: %s", service, err)
+			}
+			if !ready {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// waitForStartupGate polls the containers of the services of the group until the gate is met.
+// It fails as soon as a container is reported unhealthy or when the gate timeout is reached.
+func waitForStartupGate(cli *client.Client, stack *portainer.Stack, endpoint *portainer.Endpoint, group *portainer.StackStartupGroup) error {
+	if group.Gate.Type == 0 {
+		return nil
+	}
+
+	timeout := stackutils.StartupGateTimeout(&group.Gate)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		ready, err := isStartupGroupReady(cli, stack, endpoint, group)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("gate not met after %s", timeout)
+		}
+		time.Sleep(startupGatePollRate)
+	}
+}
+
+func isStartupGroupReady(cli *client.Client, stack *portainer.Stack, endpoint *portainer.Endpoint, group *portainer.StackStartupGroup) (bool, error) {
+	for _, service := range group.Services {
+		containers, err := serviceContainers(cli, stack.Name, service)
+		if err != nil {
+			return false, err
+		}
+		if len(containers) == 0 {
+			return false, nil
+		}
+
+		for _, container := range containers {
+			ready, err := isContainerReady(container, endpoint, &group.Gate)
+			if err != nil {
+				return false, fmt.Errorf("service %s: %s", service, err)
+			}
+			if !ready {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func serviceContainers(cli *client.Client, projectName, service string) ([]types.ContainerJSON, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", composeProjectLabel+"="+projectName)
+	filterArgs.Add("label", composeServiceLabel+"="+service)
+
+	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{All: true, Filters: filterArgs})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]types.ContainerJSON, 0, len(containers))
+	for _, container := range containers {
+		containerJSON, err := cli.ContainerInspect(context.Background(), container.ID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, containerJSON)
+	}
+
+	return result, nil
+}
+
+func isContainerReady(container types.ContainerJSON, endpoint *portainer.Endpoint, gate *portainer.StackStartupGate) (bool, error) {
+	if container.State == nil || !container.State.Running {
+		if container.State != nil && (container.State.Dead || container.State.Status == "exited") {
+			return false, fmt.Errorf("container %s is not running", container.Name)
+		}
+		return false, nil
+	}
+
+	switch gate.Type {
+	case portainer.StackStartupGateHealthy:
+		if container.State.Health == nil {
+			return false, fmt.Errorf("container %s does not define a healthcheck", container.Name)
+		}
+		switch container.State.Health.Status {
+		case types.Healthy:
+			return true, nil
+		case types.Unhealthy:
+			return false, fmt.Errorf("container %s is unhealthy", container.Name)
+		}
+		return false, nil
+
+	case portainer.StackStartupGateTCPPort:
+		hostPort, err := publishedTCPPort(container, gate.Port)
+		if err != nil {
+			return false, err
+		}
+
+		host, err := endpointHost(endpoint)
+		if err != nil {
+			return false, err
+		}
+
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, hostPort), startupGatePollRate)
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	}
+
+	return true, nil
+}
+
+func publishedTCPPort(container types.ContainerJSON, port int) (string, error) {
+	containerPort := strconv.Itoa(port) + "/tcp"
+
+	if container.NetworkSettings != nil {
+		for exposedPort, bindings := range container.NetworkSettings.Ports {
+			if string(exposedPort) != containerPort {
+				continue
+			}
+			for _, binding := range bindings {
+				if binding.HostPort != "" {
+					return binding.HostPort, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("port %d of container %s is not published", port, container.Name)
+}
+
+// endpointHost returns the host where the published ports of the endpoint are reachable: the public IP of the endpoint,
+// or the host of its TCP URL. The host of the endpoints reached through a socket or an Edge tunnel is not known,
+// the loopback interface of Portainer is not the one of the endpoint when Portainer runs in a container.
+func endpointHost(endpoint *portainer.Endpoint) (string, error) {
+	if endpoint.PublicURL != "" {
+		host, _, err := net.SplitHostPort(endpoint.PublicURL)
+		if err != nil {
+			return endpoint.PublicURL, nil
+		}
+		return host, nil
+	}
+
+	if endpoint.Type != portainer.EdgeAgentOnDockerEnvironment {
+		endpointURL, err := url.Parse(endpoint.URL)
+		if err == nil && endpointURL.Hostname() != "" && endpointURL.Scheme == "tcp" {
+			return endpointURL.Hostname(), nil
+		}
+	}
+
+	return "", errEndpointHostUnknown
+}
@@ -0,0 +1,100 @@
+package stackdeploy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_endpointHost(t *testing.T) {
+	host, err := endpointHost(&portainer.Endpoint{URL: "unix:///var/run/docker.sock", PublicURL: "10.0.0.5"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", host)
+
+	host, err = endpointHost(&portainer.Endpoint{URL: "tcp://10.0.0.6:2375"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.6", host)
+
+	_, err = endpointHost(&portainer.Endpoint{URL: "unix:///var/run/docker.sock"})
+	assert.Equal(t, errEndpointHostUnknown, err, "the loopback interface of Portainer is not the one of the endpoint")
+
+	_, err = endpointHost(&portainer.Endpoint{Type: portainer.EdgeAgentOnDockerEnvironment, URL: "tcp://portainer.example.com:9000"})
+	assert.Equal(t, errEndpointHostUnknown, err)
+}
+
+type stubComposeStackManager struct {
+	portainer.ComposeStackManager
+	ups [][]string
+}
+
+func (manager *stubComposeStackManager) Up(stack *portainer.Stack, endpoint *portainer.Endpoint, services ...string) error {
+	manager.ups = append(manager.ups, services)
+	return nil
+}
+
+type stubSwarmStackManager struct {
+	portainer.SwarmStackManager
+}
+
+func (manager *stubSwarmStackManager) Login(dockerhub *portainer.DockerHub, registries []portainer.Registry, endpoint *portainer.Endpoint) {
+}
+
+func (manager *stubSwarmStackManager) Logout(endpoint *portainer.Endpoint) error {
+	return nil
+}
+
+func Test_upServices_shouldNotHoldTheStackCreationLockWhileWaitingForTheBatches(t *testing.T) {
+	composeStackManager := &stubComposeStackManager{}
+	service := NewService(nil, nil, nil, composeStackManager, &stubSwarmStackManager{}, nil, nil)
+
+	lockedWhileWaiting := false
+	cli, err := docker.CreateHandlerClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isUnlocked(&service.stackCreationMutex) {
+			lockedWhileWaiting = true
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/containers/json") {
+			json.NewEncoder(w).Encode([]types.Container{{ID: "abc"}})
+			return
+		}
+		json.NewEncoder(w).Encode(types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{ID: "abc", State: &types.ContainerState{Status: "running", Running: true}},
+		})
+	}))
+	assert.NoError(t, err)
+
+	config := &Config{Stack: &portainer.Stack{Name: "stack", UpdateConcurrency: 1}, Endpoint: &portainer.Endpoint{}}
+	up := func(services ...string) error {
+		return service.upWithRegistryCredentials(config, services...)
+	}
+
+	err = upServices(cli, config.Stack, config.Endpoint, up, []string{"db", "web"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"db"}, {"web"}}, composeStackManager.ups)
+	assert.False(t, lockedWhileWaiting)
+}
+
+func isUnlocked(mutex *sync.Mutex) bool {
+	locked := make(chan struct{})
+	go func() {
+		mutex.Lock()
+		mutex.Unlock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
@@ -0,0 +1,363 @@
+package stackdrift
+
+import (
+	"context"
+	"errors"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/scheduler"
+	"github.com/portainer/portainer/api/internal/snapshot"
+	"github.com/portainer/portainer/api/internal/stackdeploy"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+const (
+	// CheckJobID is the identifier of the drift check job in the scheduler
+	CheckJobID = "stack_drift"
+
+	checkInterval = 1 * time.Minute
+
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	swarmStackLabel     = "com.docker.stack.namespace"
+)
+
+// ErrUnsupportedStack is returned when the drift of a Kubernetes stack is checked
+var ErrUnsupportedStack = errors.New("Drift detection is only available for Compose and Swarm stacks")
+
+type (
+	// Service detects the differences between the definition of the stacks and the containers or services
+	// running on their endpoint. The active stacks of an endpoint are checked when the endpoint becomes
+	// reachable again after a downtime, and redeployed when they have automatic reconciliation enabled.
+	Service struct {
+		dataStore     portainer.DataStore
+		clientFactory *docker.ClientFactory
+		fileService   portainer.FileService
+		deployService *stackdeploy.Service
+		scheduler     *scheduler.Scheduler
+		mu            sync.Mutex
+		statuses      map[portainer.EndpointID]portainer.EndpointStatus
+	}
+
+	// runningService represents the containers of a Compose service or a Swarm service found on an endpoint
+	runningService struct {
+		images  []string
+		running bool
+		// whether the containers exited successfully, e.g. one-off services
+		completed bool
+	}
+)
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, clientFactory *docker.ClientFactory, fileService portainer.FileService, deployService *stackdeploy.Service, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		clientFactory: clientFactory,
+		fileService:   fileService,
+		deployService: deployService,
+		scheduler:     scheduler,
+		statuses:      make(map[portainer.EndpointID]portainer.EndpointStatus),
+	}
+}
+
+// Start registers the drift check in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          CheckJobID,
+		Description: "Check the drift of the stacks of the endpoints reachable again after a downtime",
+		Interval:    checkInterval,
+		RunOnStart:  true,
+		Subsystem:   scheduler.SubsystemAutoUpdate,
+		Run:         service.checkReconnectedEndpoints,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,stackdrift] [message: unable to schedule the stack drift check] [error: %s]", err)
+	}
+}
+
+// Detect compares the definition of the stack against the containers (Compose) or the services (Swarm)
+// running on the endpoint
+func (service *Service) Detect(stack *portainer.Stack, endpoint *portainer.Endpoint) (*portainer.StackDrift, error) {
+	if stack.Type != portainer.DockerComposeStack && stack.Type != portainer.DockerSwarmStack {
+		return nil, ErrUnsupportedStack
+	}
+
+	stackContent, err := service.fileService.GetFileContent(path.Join(stack.ProjectPath, stack.EntryPoint))
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := stackutils.ComposeFileServiceImages(stackContent, stack.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	var running map[string]*runningService
+	if stack.Type == portainer.DockerSwarmStack {
+		running, err = swarmServices(cli, stack.Name)
+	} else {
+		running, err = composeServices(cli, stack.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &portainer.StackDrift{
+		CheckDate: time.Now().Unix(),
+		Items:     compare(expected, running),
+	}, nil
+}
+
+// redeploy deploys the stack on its endpoint again, with the checks and the registries
+// of the user who last deployed the stack
+func (service *Service) redeploy(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	config, err := service.deployService.NewAutomaticConfig(stack, endpoint, false)
+	if err != nil {
+		return err
+	}
+	return service.deployService.Deploy(config)
+}
+
+// checkReconnectedEndpoints checks the stacks of the endpoints seen up for the first time or after being down.
+// The status of the endpoints is maintained by the snapshot job.
+func (service *Service) checkReconnectedEndpoints() error {
+	endpoints, err := service.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if !isDockerEndpoint(endpoint) || !snapshot.SupportDirectSnapshot(endpoint) {
+			continue
+		}
+
+		service.mu.Lock()
+		previous, seen := service.statuses[endpoint.ID]
+		service.statuses[endpoint.ID] = endpoint.Status
+		service.mu.Unlock()
+
+		if endpoint.Status != portainer.EndpointStatusUp || (seen && previous == portainer.EndpointStatusUp) {
+			continue
+		}
+
+		service.checkEndpointStacks(endpoint)
+	}
+
+	return nil
+}
+
+func (service *Service) checkEndpointStacks(endpoint *portainer.Endpoint) {
+	stacks, err := service.dataStore.Stack().Stacks()
+	if err != nil {
+		log.Printf("[ERROR] [internal,stackdrift] [message: unable to retrieve the stacks] [error: %s]", err)
+		return
+	}
+
+	for idx := range stacks {
+		stack := &stacks[idx]
+		if stack.EndpointID != endpoint.ID || stack.Status != portainer.StackStatusActive || stack.Type == portainer.KubernetesStack {
+			continue
+		}
+
+		drift, err := service.Detect(stack, endpoint)
+		if err != nil {
+			log.Printf("[ERROR] [internal,stackdrift] [stack: %s] [message: unable to check the stack drift] [error: %s]", stack.Name, err)
+			continue
+		}
+
+		reconciled := false
+		if len(drift.Items) > 0 {
+			log.Printf("[WARN] [internal,stackdrift] [stack: %s] [message: drift detected after endpoint reconnection] [differences: %d]", stack.Name, len(drift.Items))
+
+			if lock := stackutils.ActiveLock(stack, time.Now()); stack.AutoReconcile && lock != nil {
+				drift.ReconcileSkipped = stackutils.LockedStatus(lock)
+				log.Printf("[INFO] [internal,stackdrift] [stack: %s] [message: reconciliation skipped] [reason: %s]", stack.Name, drift.ReconcileSkipped)
+			} else if stack.AutoReconcile {
+				drift = service.reconcile(stack, endpoint, drift)
+				reconciled = true
+			}
+		}
+
+		latestStackReference, err := service.dataStore.Stack().Stack(stack.ID)
+		if err != nil {
+			continue
+		}
+
+		latestStackReference.Drift = drift
+		if reconciled {
+			latestStackReference.SkippedImagePulls = stack.SkippedImagePulls
+		}
+		err = service.dataStore.Stack().UpdateStack(latestStackReference.ID, latestStackReference)
+		if err != nil {
+			log.Printf("[ERROR] [internal,stackdrift] [stack: %s] [message: unable to persist the stack drift] [error: %s]", stack.Name, err)
+		}
+	}
+}
+
+// reconcile redeploys the stack and returns the drift remaining after the redeployment
+func (service *Service) reconcile(stack *portainer.Stack, endpoint *portainer.Endpoint, drift *portainer.StackDrift) *portainer.StackDrift {
+	err := service.redeploy(stack, endpoint)
+	if err != nil {
+		log.Printf("[ERROR] [internal,stackdrift] [stack: %s] [message: unable to reconcile the stack] [error: %s]", stack.Name, err)
+		drift.ReconcileDate = time.Now().Unix()
+		drift.ReconcileError = err.Error()
+		return drift
+	}
+
+	remaining, err := service.Detect(stack, endpoint)
+	if err != nil {
+		remaining = drift
+	}
+	remaining.ReconcileDate = time.Now().Unix()
+	return remaining
+}
+
+func composeServices(cli *client.Client, projectName string) (map[string]*runningService, error) {
+	containers, err := cli.ContainerList(context.Background(), dockertypes.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", composeProjectLabel+"="+projectName)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*runningService)
+	for _, container := range containers {
+		name := container.Labels[composeServiceLabel]
+		if name == "" {
+			continue
+		}
+
+		running, ok := services[name]
+		if !ok {
+			running = &runningService{completed: true}
+			services[name] = running
+		}
+
+		running.images = append(running.images, container.Image)
+
+		switch {
+		case container.State == "running":
+			running.running = true
+		case container.State != "exited" || !strings.HasPrefix(container.Status, "Exited (0)"):
+			running.completed = false
+		}
+	}
+
+	return services, nil
+}
+
+func swarmServices(cli *client.Client, stackName string) (map[string]*runningService, error) {
+	swarmServices, err := cli.ServiceList(context.Background(), dockertypes.ServiceListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", swarmStackLabel+"="+stackName)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]*runningService)
+	for _, swarmService := range swarmServices {
+		name := strings.TrimPrefix(swarmService.Spec.Name, stackName+"_")
+
+		image := ""
+		if swarmService.Spec.TaskTemplate.ContainerSpec != nil {
+			image = swarmService.Spec.TaskTemplate.ContainerSpec.Image
+		}
+
+		// Swarm reschedules the tasks of the services, only the presence and the image of the services are compared
+		services[name] = &runningService{images: []string{image}, running: true}
+	}
+
+	return services, nil
+}
+
+// compare returns the differences between the images of the services of the definition and the running services,
+// sorted by service name. Services built without an image name are only checked for presence.
+func compare(expected map[string]string, running map[string]*runningService) []portainer.StackDriftItem {
+	items := make([]portainer.StackDriftItem, 0)
+
+	for name, image := range expected {
+		actual, ok := running[name]
+		if !ok {
+			items = append(items, portainer.StackDriftItem{Service: name, Type: portainer.StackDriftMissing, Expected: image})
+			continue
+		}
+
+		if image != "" {
+			for _, actualImage := range actual.images {
+				if normalizeImage(actualImage) != normalizeImage(image) {
+					items = append(items, portainer.StackDriftItem{Service: name, Type: portainer.StackDriftImage, Expected: image, Actual: actualImage})
+					break
+				}
+			}
+		}
+
+		if !actual.running && !actual.completed {
+			items = append(items, portainer.StackDriftItem{Service: name, Type: portainer.StackDriftStopped})
+		}
+	}
+
+	for name, actual := range running {
+		if _, ok := expected[name]; !ok {
+			items = append(items, portainer.StackDriftItem{Service: name, Type: portainer.StackDriftUnexpected, Actual: strings.Join(actual.images, ",")})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Service != items[j].Service {
+			return items[i].Service < items[j].Service
+		}
+		return items[i].Type < items[j].Type
+	})
+
+	return items
+}
+
+// normalizeImage returns the familiar name and tag of an image reference, the digest added by Swarm
+// to the image of the services is removed. References that cannot be parsed, e.g. image IDs, are returned as is.
+func normalizeImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+
+	if tagged, ok := named.(reference.Tagged); ok {
+		named, err = reference.WithTag(reference.TrimNamed(named), tagged.Tag())
+		if err != nil {
+			return image
+		}
+		return reference.FamiliarString(named)
+	}
+
+	if _, ok := named.(reference.Digested); ok {
+		return reference.FamiliarString(named)
+	}
+
+	return reference.FamiliarString(reference.TagNameOnly(named))
+}
+
+func isDockerEndpoint(endpoint *portainer.Endpoint) bool {
+	switch endpoint.Type {
+	case portainer.DockerEnvironment, portainer.AgentOnDockerEnvironment:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package stackdrift
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compare(t *testing.T) {
+	expected := map[string]string{
+		"web":     "nginx:1.19",
+		"db":      "postgres:12",
+		"cache":   "redis",
+		"migrate": "myapp:latest",
+		"worker":  "",
+	}
+	running := map[string]*runningService{
+		"web":     {images: []string{"nginx:1.18"}, running: true},
+		"cache":   {images: []string{"docker.io/library/redis:latest@sha256:0000000000000000000000000000000000000000000000000000000000000000"}},
+		"migrate": {images: []string{"myapp"}, completed: true},
+		"worker":  {images: []string{"stack_worker"}, running: true},
+		"legacy":  {images: []string{"busybox"}, running: true},
+	}
+
+	items := compare(expected, running)
+
+	assert.Equal(t, []portainer.StackDriftItem{
+		{Service: "cache", Type: portainer.StackDriftStopped},
+		{Service: "db", Type: portainer.StackDriftMissing, Expected: "postgres:12"},
+		{Service: "legacy", Type: portainer.StackDriftUnexpected, Actual: "busybox"},
+		{Service: "web", Type: portainer.StackDriftImage, Expected: "nginx:1.19", Actual: "nginx:1.18"},
+	}, items)
+}
+
+func Test_normalizeImage(t *testing.T) {
+	assert.Equal(t, "nginx:latest", normalizeImage("nginx"))
+	assert.Equal(t, "nginx:1.19", normalizeImage("docker.io/library/nginx:1.19"))
+	assert.Equal(t, "registry.local:5000/app:v1", normalizeImage("registry.local:5000/app:v1@sha256:0000000000000000000000000000000000000000000000000000000000000000"))
+	assert.Equal(t, "sha256:abc", normalizeImage("sha256:abc"))
+}
@@ -0,0 +1,415 @@
+package stackmonitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/crashloop"
+	"github.com/portainer/portainer/api/internal/mailer"
+	"github.com/portainer/portainer/api/internal/scheduler"
+	"github.com/portainer/portainer/api/internal/stackdeploy"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+const (
+	// CheckJobID is the identifier of the monitoring job in the scheduler
+	CheckJobID = "stack_monitoring"
+
+	checkInterval = 30 * time.Second
+
+	composeProjectLabel = "com.docker.compose.project"
+	swarmStackLabel     = "com.docker.stack.namespace"
+)
+
+var (
+	// ErrNotMonitoring is returned when a stack without an update being monitored is committed
+	ErrNotMonitoring = errors.New("The stack is not within a monitoring window")
+
+	errInvalidPolicy = errors.New("Invalid monitoring policy. Window must be a valid positive duration")
+)
+
+// Service monitors the stacks within the monitoring window following their update. An update is reverted
+// to the previous version of the stack when a container of the stack becomes unhealthy or crash-loops
+// within the window, and promoted once the window passes cleanly. A revert is delayed while the stack is locked for edition.
+// Only the stack endpoint is monitored, a revert is applied on the stack endpoint and on the additional deployments of the stack.
+type Service struct {
+	dataStore        portainer.DataStore
+	clientFactory    *docker.ClientFactory
+	fileService      portainer.FileService
+	deployService    *stackdeploy.Service
+	crashLoopService *crashloop.Service
+	mailer           *mailer.Service
+	scheduler        *scheduler.Scheduler
+	mu               sync.Mutex
+}
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, clientFactory *docker.ClientFactory, fileService portainer.FileService, deployService *stackdeploy.Service, crashLoopService *crashloop.Service, mailer *mailer.Service, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:        dataStore,
+		clientFactory:    clientFactory,
+		fileService:      fileService,
+		deployService:    deployService,
+		crashLoopService: crashLoopService,
+		mailer:           mailer,
+		scheduler:        scheduler,
+	}
+}
+
+// Start registers the monitoring check in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          CheckJobID,
+		Description: "Check the stacks within their monitoring window and revert the failing updates",
+		Interval:    checkInterval,
+		RunOnStart:  true,
+		Run:         service.check,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,stackmonitor] [message: unable to schedule the stack monitoring check] [error: %s]", err)
+	}
+}
+
+// ValidatePolicy verifies the window of a policy
+func ValidatePolicy(policy *portainer.StackMonitoringPolicy) error {
+	window, err := time.ParseDuration(policy.Window)
+	if err != nil || window <= 0 {
+		return errInvalidPolicy
+	}
+	return nil
+}
+
+// Begin returns the monitoring window of an update of a stack deploying version over previousVersion.
+// No window is returned when the stack has no monitoring policy or no previous version to revert to.
+func Begin(policy *portainer.StackMonitoringPolicy, previousVersion, version int, now time.Time) *portainer.StackMonitoring {
+	if policy == nil || previousVersion == 0 {
+		return nil
+	}
+
+	window, err := time.ParseDuration(policy.Window)
+	if err != nil || window <= 0 {
+		return nil
+	}
+
+	return &portainer.StackMonitoring{
+		Status:          portainer.StackMonitoringActive,
+		Version:         version,
+		PreviousVersion: previousVersion,
+		StartDate:       now.Unix(),
+		EndDate:         now.Add(window).Unix(),
+	}
+}
+
+// Commit promotes the update of the stack being monitored before the end of its window
+func (service *Service) Commit(stackID portainer.StackID, username string) (*portainer.Stack, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	stack, err := service.dataStore.Stack().Stack(stackID)
+	if err != nil {
+		return nil, err
+	}
+
+	if stack.Monitoring == nil || stack.Monitoring.Status != portainer.StackMonitoringActive {
+		return nil, ErrNotMonitoring
+	}
+
+	stack.Monitoring.Status = portainer.StackMonitoringPromoted
+	stack.Monitoring.CompletionDate = time.Now().Unix()
+	stack.Monitoring.CommittedBy = username
+
+	err = service.dataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
+
+func (service *Service) check() error {
+	stacks, err := service.dataStore.Stack().Stacks()
+	if err != nil {
+		return err
+	}
+
+	for _, stack := range stacks {
+		if stack.Monitoring == nil || stack.Monitoring.Status != portainer.StackMonitoringActive {
+			continue
+		}
+
+		service.checkStack(stack.ID)
+	}
+
+	return nil
+}
+
+func (service *Service) checkStack(stackID portainer.StackID) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	stack, err := service.dataStore.Stack().Stack(stackID)
+	if err != nil || stack.Monitoring == nil || stack.Monitoring.Status != portainer.StackMonitoringActive {
+		return
+	}
+
+	endpoint, err := service.dataStore.Endpoint().Endpoint(stack.EndpointID)
+	if err != nil {
+		log.Printf("[ERROR] [internal,stackmonitor] [stack: %s] [message: unable to retrieve the stack endpoint] [error: %s]", stack.Name, err)
+		return
+	}
+
+	// the window is extended until the endpoint can be checked again
+	if endpoint.Status != portainer.EndpointStatusUp {
+		return
+	}
+
+	reason, err := service.failure(stack, endpoint)
+	if err != nil {
+		log.Printf("[WARN] [internal,stackmonitor] [stack: %s] [message: unable to check the containers of the stack] [error: %s]", stack.Name, err)
+		return
+	}
+
+	now := time.Now()
+	switch decide(stack.Monitoring, now, reason) {
+	case portainer.StackMonitoringPromoted:
+		stack.Monitoring.Status = portainer.StackMonitoringPromoted
+		log.Printf("[INFO] [internal,stackmonitor] [stack: %s] [message: update promoted] [version: %d]", stack.Name, stack.Monitoring.Version)
+	case portainer.StackMonitoringReverted:
+		// the revert is delayed until the user editing the stack releases its lock
+		if lock := stackutils.ActiveLock(stack, now); lock != nil {
+			log.Printf("[WARN] [internal,stackmonitor] [stack: %s] [message: revert delayed] [reason: %s] [failure: %s]", stack.Name, stackutils.LockedStatus(lock), reason)
+			return
+		}
+
+		stack.Monitoring.Reason = reason
+		stack.Monitoring.Status = portainer.StackMonitoringReverted
+
+		err = service.revert(stack, endpoint)
+		if err != nil {
+			stack.Monitoring.Status = portainer.StackMonitoringRevertFailed
+			stack.Monitoring.Reason = fmt.Sprintf("%s, unable to revert the update: %s", reason, err)
+		}
+
+		service.notify(stack, endpoint)
+	default:
+		return
+	}
+
+	stack.Monitoring.CompletionDate = now.Unix()
+
+	err = service.dataStore.Stack().UpdateStack(stack.ID, stack)
+	if err != nil {
+		log.Printf("[ERROR] [internal,stackmonitor] [stack: %s] [message: unable to persist the stack monitoring status] [error: %s]", stack.Name, err)
+	}
+}
+
+// decide returns the status of the window of an update at the time of a check, reason is the failure
+// detected by the check, empty when the containers of the stack are healthy
+func decide(monitoring *portainer.StackMonitoring, now time.Time, reason string) portainer.StackMonitoringStatus {
+	if reason != "" {
+		return portainer.StackMonitoringReverted
+	}
+
+	if now.Unix() >= monitoring.EndDate {
+		return portainer.StackMonitoringPromoted
+	}
+
+	return portainer.StackMonitoringActive
+}
+
+// failure returns the reason to revert the update of the stack, empty when no container of the stack is
+// unhealthy nor crash-looping since the start of the window
+func (service *Service) failure(stack *portainer.Stack, endpoint *portainer.Endpoint) (string, error) {
+	unhealthy, err := service.unhealthyContainers(stack, endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	if len(unhealthy) > 0 {
+		return fmt.Sprintf("unhealthy containers: %s", strings.Join(unhealthy, ", ")), nil
+	}
+
+	offenders, err := service.crashLoopService.Offenders(endpoint.ID)
+	if err != nil {
+		return "", err
+	}
+
+	crashLooping := make([]string, 0)
+	for _, offender := range offenders {
+		if offender.Stack == stack.Name && offender.LastExitTime >= stack.Monitoring.StartDate {
+			crashLooping = append(crashLooping, offender.ContainerName)
+		}
+	}
+
+	if len(crashLooping) > 0 {
+		return fmt.Sprintf("crash-looping containers: %s", strings.Join(crashLooping, ", ")), nil
+	}
+
+	return "", nil
+}
+
+func (service *Service) unhealthyContainers(stack *portainer.Stack, endpoint *portainer.Endpoint) ([]string, error) {
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	label := composeProjectLabel
+	if stack.Type == portainer.DockerSwarmStack {
+		label = swarmStackLabel
+	}
+
+	containers, err := cli.ContainerList(context.Background(), dockertypes.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", label+"="+stack.Name),
+			filters.Arg("health", dockertypes.Unhealthy),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(containers))
+	for _, container := range containers {
+		if len(container.Names) > 0 {
+			names = append(names, strings.TrimPrefix(container.Names[0], "/"))
+		}
+	}
+
+	return names, nil
+}
+
+// revert redeploys the previous version of the stack, on its endpoint and on the endpoints where it is deployed
+// in addition, and records the rollback in the deployment history of the stack. The services added by the update
+// are removed. When the stack cannot be redeployed on its endpoint, the definition of the update is restored.
+func (service *Service) revert(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	stackVersion, err := service.dataStore.StackVersion().StackVersion(stack.ID, stack.Monitoring.PreviousVersion)
+	if err != nil {
+		return err
+	}
+
+	failedFileContent, err := service.fileService.GetFileContent(path.Join(stack.ProjectPath, stack.EntryPoint))
+	if err != nil {
+		return err
+	}
+	failed := *stack
+
+	stackFolder := strconv.Itoa(int(stack.ID))
+	_, err = service.fileService.StoreStackFileFromBytes(stackFolder, stackVersion.EntryPoint, []byte(stackVersion.FileContent))
+	if err != nil {
+		return err
+	}
+
+	stack.EntryPoint = stackVersion.EntryPoint
+	stack.Env = stackVersion.Env
+	stack.HealthcheckOverrides = stackVersion.HealthcheckOverrides
+	stack.LoggingOverrides = stackVersion.LoggingOverrides
+	stack.StartupOrder = stackVersion.StartupOrder
+
+	err = service.deploy(stack, endpoint)
+	if err != nil {
+		_, restoreErr := service.fileService.StoreStackFileFromBytes(stackFolder, failed.EntryPoint, failedFileContent)
+		if restoreErr != nil {
+			log.Printf("[ERROR] [internal,stackmonitor] [stack: %s] [message: unable to restore the stack file of the update] [error: %s]", stack.Name, restoreErr)
+		}
+		*stack = failed
+		return err
+	}
+
+	service.revertDeployments(stack)
+
+	stack.UpdateDate = time.Now().Unix()
+
+	err = service.dataStore.StackVersion().CreateStackVersion(&portainer.StackVersion{
+		StackID:              stack.ID,
+		EntryPoint:           stackVersion.EntryPoint,
+		FileContent:          stackVersion.FileContent,
+		Env:                  stackVersion.Env,
+		HealthcheckOverrides: stackVersion.HealthcheckOverrides,
+		LoggingOverrides:     stackVersion.LoggingOverrides,
+		StartupOrder:         stackVersion.StartupOrder,
+		RollbackOf:           stackVersion.Version,
+		CreationDate:         stack.UpdateDate,
+	})
+	if err != nil {
+		log.Printf("[WARN] [internal,stackmonitor] [stack: %s] [message: unable to record the reverted version of the stack] [error: %s]", stack.Name, err)
+	}
+
+	return nil
+}
+
+// revertDeployments redeploys the reverted stack on the endpoints where it is deployed in addition to its endpoint.
+// The result of each redeployment is recorded on the deployments of the stack.
+func (service *Service) revertDeployments(stack *portainer.Stack) {
+	for idx := range stack.Deployments {
+		deployment := &stack.Deployments[idx]
+		deployment.DeploymentDate = time.Now().Unix()
+		deployment.Error = ""
+
+		endpoint, err := service.dataStore.Endpoint().Endpoint(deployment.EndpointID)
+		if err != nil {
+			deployment.Error = err.Error()
+			log.Printf("[WARN] [internal,stackmonitor] [stack: %s] [message: unable to retrieve an endpoint of the stack to revert] [endpoint: %d] [error: %s]", stack.Name, deployment.EndpointID, err)
+			continue
+		}
+
+		targetStack := *stack
+		targetStack.EndpointID = endpoint.ID
+		targetStack.Env = stackutils.MergeStackEnv(stack.Env, deployment.Env)
+
+		err = service.deploy(&targetStack, endpoint)
+		if err != nil {
+			deployment.Error = err.Error()
+			log.Printf("[WARN] [internal,stackmonitor] [stack: %s] [message: unable to revert the stack on an endpoint] [endpoint: %s] [error: %s]", stack.Name, endpoint.Name, err)
+		}
+	}
+}
+
+// deploy deploys the stack on an endpoint, with the checks and the registries of the user who last deployed the stack
+func (service *Service) deploy(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+	config, err := service.deployService.NewAutomaticConfig(stack, endpoint, true)
+	if err != nil {
+		return err
+	}
+	return service.deployService.Deploy(config)
+}
+
+func (service *Service) notify(stack *portainer.Stack, endpoint *portainer.Endpoint) {
+	monitoring := stack.Monitoring
+	log.Printf("[WARN] [internal,stackmonitor] [stack: %s] [endpoint: %s] [message: update failed within the monitoring window] [status: %s] [reason: %s]", stack.Name, endpoint.Name, monitoring.Status, monitoring.Reason)
+
+	if stack.MonitoringPolicy == nil || len(stack.MonitoringPolicy.NotificationRecipients) == 0 {
+		return
+	}
+
+	subject := fmt.Sprintf("Update of stack %s reverted to version %d", stack.Name, monitoring.PreviousVersion)
+	if monitoring.Status == portainer.StackMonitoringRevertFailed {
+		subject = fmt.Sprintf("Update of stack %s failed and could not be reverted", stack.Name)
+	}
+
+	body := fmt.Sprintf("Version %d of stack %s on endpoint %s failed within its monitoring window.\nReason: %s\n", monitoring.Version, stack.Name, endpoint.Name, monitoring.Reason)
+
+	recipients := stack.MonitoringPolicy.NotificationRecipients
+	go func() {
+		err := service.mailer.Send(&mailer.Message{
+			To:      recipients,
+			Subject: subject,
+			Body:    body,
+		})
+		if err != nil {
+			log.Printf("[ERROR] [internal,stackmonitor] [stack: %s] [message: unable to send the stack monitoring notification] [error: %s]", stack.Name, err)
+		}
+	}()
+}
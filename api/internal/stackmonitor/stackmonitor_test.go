@@ -0,0 +1,95 @@
+package stackmonitor
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/stackdeploy"
+	"github.com/portainer/portainer/api/internal/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubFileService struct {
+	portainer.FileService
+	files map[string][]byte
+}
+
+func (service *stubFileService) GetFileContent(filePath string) ([]byte, error) {
+	return service.files[filePath], nil
+}
+
+func (service *stubFileService) StoreStackFileFromBytes(stackIdentifier, fileName string, data []byte) (string, error) {
+	service.files[path.Join("/data/compose", stackIdentifier, fileName)] = data
+	return path.Join("/data/compose", stackIdentifier), nil
+}
+
+func Test_Begin(t *testing.T) {
+	now := time.Unix(1000, 0)
+	policy := &portainer.StackMonitoringPolicy{Window: "5m"}
+
+	assert.Nil(t, Begin(nil, 3, 4, now), "stacks without policy are not monitored")
+	assert.Nil(t, Begin(policy, 0, 1, now), "first deployments have no version to revert to")
+
+	monitoring := Begin(policy, 3, 4, now)
+	assert.Equal(t, &portainer.StackMonitoring{
+		Status:          portainer.StackMonitoringActive,
+		Version:         4,
+		PreviousVersion: 3,
+		StartDate:       1000,
+		EndDate:         1300,
+	}, monitoring)
+}
+
+func Test_decide(t *testing.T) {
+	monitoring := &portainer.StackMonitoring{StartDate: 1000, EndDate: 1300}
+
+	assert.Equal(t, portainer.StackMonitoringActive, decide(monitoring, time.Unix(1100, 0), ""))
+	assert.Equal(t, portainer.StackMonitoringReverted, decide(monitoring, time.Unix(1100, 0), "unhealthy containers: web"))
+	assert.Equal(t, portainer.StackMonitoringPromoted, decide(monitoring, time.Unix(1300, 0), ""))
+	assert.Equal(t, portainer.StackMonitoringReverted, decide(monitoring, time.Unix(1400, 0), "crash-looping containers: web"), "failures detected at the end of the window are reverted")
+}
+
+func Test_ValidatePolicy(t *testing.T) {
+	assert.NoError(t, ValidatePolicy(&portainer.StackMonitoringPolicy{Window: "10m"}))
+	assert.Error(t, ValidatePolicy(&portainer.StackMonitoringPolicy{}))
+	assert.Error(t, ValidatePolicy(&portainer.StackMonitoringPolicy{Window: "-1m"}))
+}
+
+func Test_revert_shouldRestoreTheUpdateWhenThePreviousVersionCannotBeDeployed(t *testing.T) {
+	dataStore := testhelpers.NewDatastore(
+		testhelpers.WithStackVersions([]portainer.StackVersion{
+			{StackID: 1, Version: 1, EntryPoint: "docker-compose.yml", FileContent: "version: '3'\nservices:\n  web:\n    image: nginx:1.18\n"},
+		}),
+		testhelpers.WithUsers([]portainer.User{}),
+	)
+	fileService := &stubFileService{files: map[string][]byte{
+		"/data/compose/1/docker-compose.yml": []byte("version: '3'\nservices:\n  web:\n    image: nginx:1.19\n"),
+	}}
+
+	service := &Service{
+		dataStore:     dataStore,
+		fileService:   fileService,
+		deployService: stackdeploy.NewService(dataStore, nil, nil, nil, nil, nil, nil),
+	}
+
+	stack := &portainer.Stack{
+		ID:          1,
+		Name:        "web",
+		EntryPoint:  "docker-compose.yml",
+		ProjectPath: "/data/compose/1",
+		Env:         []portainer.Pair{{Name: "MODE", Value: "update"}},
+		CreatedBy:   "removed-user",
+		Monitoring:  &portainer.StackMonitoring{PreviousVersion: 1, Version: 2},
+	}
+
+	err := service.revert(stack, &portainer.Endpoint{ID: 1})
+	assert.Error(t, err)
+
+	assert.Equal(t, "version: '3'\nservices:\n  web:\n    image: nginx:1.19\n", string(fileService.files["/data/compose/1/docker-compose.yml"]), "the file of the update is restored")
+	assert.Equal(t, []portainer.Pair{{Name: "MODE", Value: "update"}}, stack.Env, "the definition of the update is restored")
+
+	versions, _ := dataStore.StackVersion().StackVersions(1)
+	assert.Len(t, versions, 1, "no reverted version is recorded")
+}
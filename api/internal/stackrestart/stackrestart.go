@@ -0,0 +1,338 @@
+package stackrestart
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/scheduler"
+	"github.com/portainer/portainer/api/internal/stackutils"
+)
+
+const (
+	// CheckJobID is the identifier of the restart dependencies job in the scheduler
+	CheckJobID = "stack_restart_dependencies"
+
+	checkInterval = 30 * time.Second
+	readyPollRate = 2 * time.Second
+
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+type (
+	// Service enforces the restart dependencies of the Compose stacks. When a service of a stack restarts,
+	// the services depending on it are restarted in order, each one once the services it depends on are ready again.
+	// Each step of a cascade is bounded by the timeout of the service, a service that is not ready in time stops the cascade.
+	// The cascades are skipped while the stack is locked for edition.
+	// Only the stack endpoint is watched, the additional deployments of the stack are not.
+	Service struct {
+		dataStore     portainer.DataStore
+		clientFactory *docker.ClientFactory
+		scheduler     *scheduler.Scheduler
+		mu            sync.Mutex
+		observations  map[portainer.StackID]*observation
+		cascading     map[portainer.StackID]bool
+	}
+
+	// observation represents the start dates of the services of a stack seen by a check
+	observation struct {
+		date   time.Time
+		starts map[string]time.Time
+	}
+)
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, clientFactory *docker.ClientFactory, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		clientFactory: clientFactory,
+		scheduler:     scheduler,
+		observations:  make(map[portainer.StackID]*observation),
+		cascading:     make(map[portainer.StackID]bool),
+	}
+}
+
+// Start registers the restart dependencies check in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          CheckJobID,
+		Description: "Cascade the restarts of the services of the stacks to the services depending on them",
+		Interval:    checkInterval,
+		RunOnStart:  true,
+		Subsystem:   scheduler.SubsystemAutoUpdate,
+		Run:         service.check,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,stackrestart] [message: unable to schedule the restart dependencies check] [error: %s]", err)
+	}
+}
+
+func (service *Service) check() error {
+	stacks, err := service.dataStore.Stack().Stacks()
+	if err != nil {
+		return err
+	}
+
+	watched := make(map[portainer.StackID]bool)
+	for idx := range stacks {
+		stack := &stacks[idx]
+		if stack.Type != portainer.DockerComposeStack || stack.Status != portainer.StackStatusActive || len(stack.RestartDependencies) == 0 {
+			continue
+		}
+
+		watched[stack.ID] = true
+		service.checkStack(stack)
+	}
+
+	service.mu.Lock()
+	for stackID := range service.observations {
+		if !watched[stackID] {
+			delete(service.observations, stackID)
+		}
+	}
+	service.mu.Unlock()
+
+	return nil
+}
+
+func (service *Service) checkStack(stack *portainer.Stack) {
+	service.mu.Lock()
+	cascading := service.cascading[stack.ID]
+	service.mu.Unlock()
+
+	if cascading {
+		return
+	}
+
+	endpoint, err := service.dataStore.Endpoint().Endpoint(stack.EndpointID)
+	if err != nil {
+		log.Printf("[ERROR] [internal,stackrestart] [stack: %s] [message: unable to retrieve the stack endpoint] [error: %s]", stack.Name, err)
+		return
+	}
+
+	if endpoint.Status != portainer.EndpointStatusUp {
+		return
+	}
+
+	cli, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		log.Printf("[WARN] [internal,stackrestart] [stack: %s] [message: unable to connect to the stack endpoint] [error: %s]", stack.Name, err)
+		return
+	}
+
+	current, err := observe(cli, stack)
+	if err != nil {
+		cli.Close()
+		log.Printf("[WARN] [internal,stackrestart] [stack: %s] [message: unable to inspect the containers of the stack] [error: %s]", stack.Name, err)
+		return
+	}
+
+	service.mu.Lock()
+	previous := service.observations[stack.ID]
+	service.observations[stack.ID] = current
+	triggers := restartedServices(previous, current, time.Unix(stack.UpdateDate, 0))
+
+	lock := stackutils.ActiveLock(stack, time.Now())
+	if len(triggers) > 0 && lock == nil {
+		service.cascading[stack.ID] = true
+	}
+	service.mu.Unlock()
+
+	if len(triggers) > 0 && lock != nil {
+		log.Printf("[INFO] [internal,stackrestart] [stack: %s] [message: restart cascade skipped] [reason: %s]", stack.Name, stackutils.LockedStatus(lock))
+	}
+
+	if len(triggers) == 0 || lock != nil {
+		cli.Close()
+		return
+	}
+
+	go func() {
+		defer cli.Close()
+		service.cascade(cli, stack, triggers)
+
+		observation, err := observe(cli, stack)
+
+		service.mu.Lock()
+		if err == nil {
+			service.observations[stack.ID] = observation
+		}
+		delete(service.cascading, stack.ID)
+		service.mu.Unlock()
+	}()
+}
+
+// restartedServices returns the services started again since the previous observation, sorted by name.
+// Nothing is returned for the first observation of a stack nor when the stack was deployed since the previous
+// observation, the containers being recreated by the deployment.
+func restartedServices(previous, current *observation, updateDate time.Time) []string {
+	if previous == nil || !updateDate.Before(previous.date) {
+		return nil
+	}
+
+	restarted := make([]string, 0)
+	for name, start := range current.starts {
+		previousStart, ok := previous.starts[name]
+		if ok && start.After(previousStart) {
+			restarted = append(restarted, name)
+		}
+	}
+	sort.Strings(restarted)
+
+	return restarted
+}
+
+// cascade restarts the services depending on each restarted service, the services already restarted
+// by the cascade of a previous service are not restarted again
+func (service *Service) cascade(cli *client.Client, stack *portainer.Stack, triggers []string) {
+	restarted := make(map[string]bool)
+
+	for _, trigger := range triggers {
+		if restarted[trigger] {
+			continue
+		}
+
+		log.Printf("[INFO] [internal,stackrestart] [stack: %s] [service: %s] [message: service restarted, cascading the restart to its dependents]", stack.Name, trigger)
+
+		err := waitForService(cli, stack.Name, trigger, stackutils.RestartStepTimeout(stack.RestartDependencies, trigger))
+		if err != nil {
+			log.Printf("[ERROR] [internal,stackrestart] [stack: %s] [service: %s] [message: restart cascade stopped, service not ready] [error: %s]", stack.Name, trigger, err)
+			continue
+		}
+
+		for _, dependent := range stackutils.RestartCascade(stack.RestartDependencies, trigger) {
+			err = restartService(cli, stack.Name, dependent)
+			if err == nil {
+				restarted[dependent] = true
+				err = waitForService(cli, stack.Name, dependent, stackutils.RestartStepTimeout(stack.RestartDependencies, dependent))
+			}
+			if err != nil {
+				log.Printf("[ERROR] [internal,stackrestart] [stack: %s] [service: %s] [message: restart cascade stopped] [error: %s]", stack.Name, dependent, err)
+				break
+			}
+		}
+	}
+}
+
+// observe returns the last start date of the containers of each service of the restart dependencies of the stack
+func observe(cli *client.Client, stack *portainer.Stack) (*observation, error) {
+	current := &observation{date: time.Now(), starts: make(map[string]time.Time)}
+
+	for _, dependency := range stack.RestartDependencies {
+		containers, err := serviceContainers(cli, stack.Name, dependency.Service)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, container := range containers {
+			if container.State == nil {
+				continue
+			}
+
+			start, err := time.Parse(time.RFC3339Nano, container.State.StartedAt)
+			if err == nil && start.After(current.starts[dependency.Service]) {
+				current.starts[dependency.Service] = start
+			}
+		}
+	}
+
+	return current, nil
+}
+
+func restartService(cli *client.Client, projectName, service string) error {
+	containers, err := serviceContainers(cli, projectName, service)
+	if err != nil {
+		return err
+	}
+
+	for _, container := range containers {
+		err = cli.ContainerRestart(context.Background(), container.ID, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitForService polls the containers of the service until they are running, and healthy when they
+// define a healthcheck. It fails as soon as a container is unhealthy or when the timeout is reached.
+func waitForService(cli *client.Client, projectName, service string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		ready, err := isServiceReady(cli, projectName, service)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service %s not ready after %s", service, timeout)
+		}
+		time.Sleep(readyPollRate)
+	}
+}
+
+func isServiceReady(cli *client.Client, projectName, service string) (bool, error) {
+	containers, err := serviceContainers(cli, projectName, service)
+	if err != nil {
+		return false, err
+	}
+	if len(containers) == 0 {
+		return false, nil
+	}
+
+	for _, container := range containers {
+		if container.State == nil || !container.State.Running {
+			return false, nil
+		}
+
+		if container.State.Health != nil {
+			switch container.State.Health.Status {
+			case dockertypes.Healthy:
+			case dockertypes.Unhealthy:
+				return false, fmt.Errorf("container %s is unhealthy", container.Name)
+			default:
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+func serviceContainers(cli *client.Client, projectName, service string) ([]dockertypes.ContainerJSON, error) {
+	containers, err := cli.ContainerList(context.Background(), dockertypes.ContainerListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", composeProjectLabel+"="+projectName),
+			filters.Arg("label", composeServiceLabel+"="+service),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dockertypes.ContainerJSON, 0, len(containers))
+	for _, container := range containers {
+		containerJSON, err := cli.ContainerInspect(context.Background(), container.ID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, containerJSON)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,23 @@
+package stackrestart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_restartedServices(t *testing.T) {
+	previous := &observation{
+		date:   time.Unix(1000, 0),
+		starts: map[string]time.Time{"db": time.Unix(500, 0), "api": time.Unix(500, 0)},
+	}
+	current := &observation{
+		date:   time.Unix(1030, 0),
+		starts: map[string]time.Time{"db": time.Unix(1010, 0), "api": time.Unix(500, 0), "cache": time.Unix(1020, 0)},
+	}
+
+	assert.Equal(t, []string{"db"}, restartedServices(previous, current, time.Unix(400, 0)))
+	assert.Empty(t, restartedServices(nil, current, time.Unix(400, 0)), "the first observation is only a baseline")
+	assert.Empty(t, restartedServices(previous, current, time.Unix(1005, 0)), "the containers recreated by a deployment are not restarts")
+}
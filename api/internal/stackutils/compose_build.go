@@ -0,0 +1,156 @@
+package stackutils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"gopkg.in/yaml.v2"
+)
+
+// ServiceBuild represents the build section of a service of a Compose file
+type ServiceBuild struct {
+	Service    string
+	Context    string
+	Dockerfile string
+	Target     string
+	Args       map[string]*string
+	// Image is the tag of the built image, the image of the service when specified
+	// or <stack>_<service> like docker-compose does
+	Image string
+}
+
+// ComposeFileBuilds returns the build sections of the services of a Compose file, sorted by service name.
+// Variables used in the build sections are interpolated using the stack environment variables.
+func ComposeFileBuilds(content []byte, stackName string, env []portainer.Pair) ([]ServiceBuild, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, ok := composeFile["services"].(map[interface{}]interface{})
+	if !ok {
+		return []ServiceBuild{}, nil
+	}
+
+	variables := map[string]string{}
+	for _, pair := range env {
+		variables[pair.Name] = pair.Value
+	}
+
+	builds := []ServiceBuild{}
+	for name, definition := range services {
+		service, _ := definition.(map[interface{}]interface{})
+
+		section, ok := service["build"]
+		if !ok {
+			continue
+		}
+
+		build, err := parseServiceBuild(fmt.Sprint(name), section, variables)
+		if err != nil {
+			return nil, err
+		}
+
+		build.Image = fmt.Sprintf("%s_%s", stackName, build.Service)
+		if image, ok := service["image"].(string); ok && image != "" {
+			build.Image = interpolateVariables(image, variables)
+		}
+
+		builds = append(builds, *build)
+	}
+
+	sort.Slice(builds, func(i, j int) bool {
+		return builds[i].Service < builds[j].Service
+	})
+
+	return builds, nil
+}
+
+func parseServiceBuild(service string, section interface{}, variables map[string]string) (*ServiceBuild, error) {
+	build := &ServiceBuild{Service: service, Args: map[string]*string{}}
+
+	switch typedSection := section.(type) {
+	case string:
+		build.Context = interpolateVariables(typedSection, variables)
+	case map[interface{}]interface{}:
+		if context, ok := typedSection["context"]; ok {
+			build.Context = interpolateVariables(fmt.Sprint(context), variables)
+		}
+		if dockerfile, ok := typedSection["dockerfile"]; ok {
+			build.Dockerfile = interpolateVariables(fmt.Sprint(dockerfile), variables)
+		}
+		if target, ok := typedSection["target"]; ok {
+			build.Target = interpolateVariables(fmt.Sprint(target), variables)
+		}
+
+		switch args := typedSection["args"].(type) {
+		case map[interface{}]interface{}:
+			for key, value := range args {
+				build.Args[fmt.Sprint(key)] = buildArgValue(fmt.Sprint(key), value, variables)
+			}
+		case []interface{}:
+			for _, item := range args {
+				parts := strings.SplitN(fmt.Sprint(item), "=", 2)
+				if len(parts) == 2 {
+					value := interpolateVariables(parts[1], variables)
+					build.Args[parts[0]] = &value
+					continue
+				}
+				build.Args[parts[0]] = buildArgValue(parts[0], nil, variables)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Invalid build section for service %s", service)
+	}
+
+	if build.Context == "" {
+		build.Context = "."
+	}
+
+	return build, nil
+}
+
+// buildArgValue returns the value of a build argument, arguments without value are
+// taken from the stack environment variables when defined
+func buildArgValue(name string, value interface{}, variables map[string]string) *string {
+	if value == nil {
+		if variable, ok := variables[name]; ok {
+			return &variable
+		}
+		return nil
+	}
+
+	interpolated := interpolateVariables(fmt.Sprint(value), variables)
+	return &interpolated
+}
+
+// ReplaceComposeFileBuilds returns the Compose file where the build section of the services is replaced
+// by the image built for the service
+func ReplaceComposeFileBuilds(content []byte, builds []ServiceBuild) ([]byte, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, ok := composeFile["services"].(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("Invalid Compose file: no services defined")
+	}
+
+	for _, build := range builds {
+		service, ok := services[build.Service].(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Invalid Compose file: service %s not found", build.Service)
+		}
+
+		delete(service, "build")
+		service["image"] = build.Image
+	}
+
+	return yaml.Marshal(composeFile)
+}
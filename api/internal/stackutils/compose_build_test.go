@@ -0,0 +1,59 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_ComposeFileBuilds(t *testing.T) {
+	content := []byte(`
+version: "3"
+services:
+  web:
+    build: ./web
+  worker:
+    image: myorg/worker:${VERSION}
+    build:
+      context: ./worker
+      dockerfile: Dockerfile.prod
+      target: release
+      args:
+        - COMMIT=abc
+        - VERSION
+  db:
+    image: postgres
+`)
+	env := []portainer.Pair{{Name: "VERSION", Value: "1.2"}}
+
+	builds, err := ComposeFileBuilds(content, "mystack", env)
+	assert.NoError(t, err)
+	assert.Len(t, builds, 2)
+
+	assert.Equal(t, "web", builds[0].Service)
+	assert.Equal(t, "./web", builds[0].Context)
+	assert.Equal(t, "mystack_web", builds[0].Image)
+
+	assert.Equal(t, "worker", builds[1].Service)
+	assert.Equal(t, "./worker", builds[1].Context)
+	assert.Equal(t, "Dockerfile.prod", builds[1].Dockerfile)
+	assert.Equal(t, "release", builds[1].Target)
+	assert.Equal(t, "myorg/worker:1.2", builds[1].Image)
+	assert.Equal(t, "abc", *builds[1].Args["COMMIT"])
+	assert.Equal(t, "1.2", *builds[1].Args["VERSION"])
+
+	replaced, err := ReplaceComposeFileBuilds(content, builds)
+	assert.NoError(t, err)
+
+	var composeFile map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal(replaced, &composeFile))
+	services := composeFile["services"].(map[interface{}]interface{})
+	for _, name := range []string{"web", "worker"} {
+		service := services[name].(map[interface{}]interface{})
+		assert.NotContains(t, service, "build")
+	}
+	assert.Equal(t, "mystack_web", services["web"].(map[interface{}]interface{})["image"])
+	assert.Equal(t, "myorg/worker:1.2", services["worker"].(map[interface{}]interface{})["image"])
+}
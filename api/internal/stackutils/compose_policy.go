@@ -0,0 +1,209 @@
+package stackutils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultComposePolicyRules are the rules denying the options giving access to the host,
+// they are used when the denied rules of the policy are not specified.
+// The net key is the Compose file format v1 equivalent of network_mode.
+var DefaultComposePolicyRules = []portainer.ComposePolicyRule{
+	{Key: "privileged", Values: []string{"true"}},
+	{Key: "cap_add", Values: []string{"ALL", "SYS_ADMIN", "CAP_SYS_ADMIN"}},
+	{Key: "network_mode", Values: []string{"host"}},
+	{Key: "net", Values: []string{"host"}},
+	{Key: "pid", Values: []string{"host"}},
+	{Key: "ipc", Values: []string{"host"}},
+	{Key: "userns_mode", Values: []string{"host"}},
+	{Key: "uts", Values: []string{"host"}},
+	{Key: "devices"},
+	{Key: "security_opt", Values: []string{"seccomp:unconfined", "seccomp=unconfined", "apparmor:unconfined", "apparmor=unconfined", "label:disable", "label=disable"}},
+	{Key: "volumes", Values: []string{"/var/run/docker.sock", "/run/docker.sock", "/"}},
+}
+
+// ComposePolicyViolation is returned when a service of a Compose file uses a key or a value denied by the Compose policy
+type ComposePolicyViolation struct {
+	Service string
+	Key     string
+	Value   string
+}
+
+func (violation *ComposePolicyViolation) Error() string {
+	if violation.Value == "" {
+		return fmt.Sprintf("Service %s uses the key %s which is not allowed by the Compose policy", violation.Service, violation.Key)
+	}
+	return fmt.Sprintf("Service %s uses the value %s for the key %s which is not allowed by the Compose policy", violation.Service, violation.Value, violation.Key)
+}
+
+// ValidateComposePolicy verifies that the keys of the rules of a Compose policy are specified
+func ValidateComposePolicy(policy *portainer.ComposePolicy) error {
+	for _, key := range policy.AllowedKeys {
+		if key == "" {
+			return errors.New("Invalid Compose policy. Allowed keys cannot be empty")
+		}
+	}
+
+	for _, rule := range policy.DeniedRules {
+		if rule.Key == "" {
+			return errors.New("Invalid Compose policy. The key of a denied rule is required")
+		}
+	}
+
+	return nil
+}
+
+// CheckComposePolicy verifies the services of a Compose file against the policy. The values are interpolated
+// with the environment variables of the stack before being evaluated. Host paths denied for the volumes key are
+// also denied when a parent folder is mounted. A *ComposePolicyViolation is returned for the first service using
+// a key or a value that is not allowed.
+func CheckComposePolicy(content []byte, env []portainer.Pair, policy *portainer.ComposePolicy) error {
+	if !policy.Enabled {
+		return nil
+	}
+
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return err
+	}
+
+	services, err := policyServices(composeFile)
+	if err != nil {
+		return err
+	}
+
+	variables := map[string]string{}
+	for _, pair := range env {
+		variables[pair.Name] = pair.Value
+	}
+
+	allowedKeys := map[string]bool{}
+	for _, key := range policy.AllowedKeys {
+		allowedKeys[key] = true
+	}
+
+	deniedRules := policy.DeniedRules
+	if deniedRules == nil {
+		deniedRules = DefaultComposePolicyRules
+	}
+
+	serviceNames := make([]string, 0, len(services))
+	for name := range services {
+		serviceNames = append(serviceNames, fmt.Sprint(name))
+	}
+	sort.Strings(serviceNames)
+
+	for _, name := range serviceNames {
+		service, ok := services[name].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		if len(allowedKeys) > 0 {
+			for key := range service {
+				if !allowedKeys[fmt.Sprint(key)] {
+					return &ComposePolicyViolation{Service: name, Key: fmt.Sprint(key)}
+				}
+			}
+		}
+
+		for _, rule := range deniedRules {
+			value, ok := service[rule.Key]
+			if !ok {
+				continue
+			}
+
+			if len(rule.Values) == 0 {
+				return &ComposePolicyViolation{Service: name, Key: rule.Key}
+			}
+
+			for _, candidate := range ruleCandidates(rule.Key, value, variables) {
+				for _, denied := range rule.Values {
+					if strings.EqualFold(candidate, denied) || (rule.Key == "volumes" && isParentPath(candidate, denied)) {
+						return &ComposePolicyViolation{Service: name, Key: rule.Key, Value: candidate}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// policyServices returns the services of a Compose file. The services of the Compose file format v1,
+// which has no version key, are defined at the top level of the file.
+func policyServices(composeFile map[string]interface{}) (map[interface{}]interface{}, error) {
+	if section, ok := composeFile["services"]; ok {
+		services, ok := section.(map[interface{}]interface{})
+		if !ok && section != nil {
+			return nil, errors.New("Invalid Compose file: invalid services section")
+		}
+		return services, nil
+	}
+
+	if _, ok := composeFile["version"]; ok {
+		return nil, nil
+	}
+
+	services := make(map[interface{}]interface{}, len(composeFile))
+	for name, service := range composeFile {
+		services[name] = service
+	}
+	return services, nil
+}
+
+// ruleCandidates returns the values of a service key compared to the denied values of a rule:
+// the items of a list, the keys of a map and the host path of the volumes
+func ruleCandidates(key string, value interface{}, variables map[string]string) []string {
+	candidates := []string{}
+
+	switch typedValue := value.(type) {
+	case []interface{}:
+		for _, item := range typedValue {
+			if mapping, ok := item.(map[interface{}]interface{}); ok {
+				if source, ok := mapping["source"]; ok {
+					candidates = append(candidates, volumeSource(interpolateVariables(fmt.Sprint(source), variables)))
+				}
+				continue
+			}
+
+			candidate := interpolateVariables(fmt.Sprint(item), variables)
+			if key == "volumes" {
+				candidate = volumeSource(candidate)
+			}
+			candidates = append(candidates, candidate)
+		}
+	case map[interface{}]interface{}:
+		for item := range typedValue {
+			candidates = append(candidates, interpolateVariables(fmt.Sprint(item), variables))
+		}
+	default:
+		candidates = append(candidates, interpolateVariables(fmt.Sprint(typedValue), variables))
+	}
+
+	return candidates
+}
+
+// volumeSource returns the source of a volume short syntax, without the trailing slashes of host paths
+func volumeSource(volume string) string {
+	source := strings.SplitN(volume, ":", 2)[0]
+	if len(source) > 1 {
+		source = strings.TrimRight(source, "/")
+	}
+	return source
+}
+
+// isParentPath returns whether the host path is a parent folder of the denied host path,
+// mounting the parent folder gives access to the denied path
+func isParentPath(path, denied string) bool {
+	if !strings.HasPrefix(path, "/") || !strings.HasPrefix(denied, "/") {
+		return false
+	}
+	return path == "/" || strings.HasPrefix(denied, path+"/")
+}
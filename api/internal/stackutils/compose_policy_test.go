@@ -0,0 +1,88 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CheckComposePolicy(t *testing.T) {
+	policy := &portainer.ComposePolicy{Enabled: true}
+
+	assert.NoError(t, CheckComposePolicy([]byte(`version: "3"
+services:
+  web:
+    image: nginx
+    cap_add:
+      - NET_ADMIN
+    volumes:
+      - ./data:/data
+`), nil, policy))
+
+	err := CheckComposePolicy([]byte(`version: "3"
+services:
+  web:
+    image: nginx
+    network_mode: ${MODE}
+`), []portainer.Pair{{Name: "MODE", Value: "host"}}, policy)
+	assert.Equal(t, &ComposePolicyViolation{Service: "web", Key: "network_mode", Value: "host"}, err)
+
+	err = CheckComposePolicy([]byte(`version: "3"
+services:
+  agent:
+    image: agent
+    volumes:
+      - type: bind
+        source: /var/run/docker.sock
+        target: /var/run/docker.sock
+`), nil, policy)
+	assert.Equal(t, &ComposePolicyViolation{Service: "agent", Key: "volumes", Value: "/var/run/docker.sock"}, err)
+
+	err = CheckComposePolicy([]byte(`version: "3"
+services:
+  web:
+    image: nginx
+    privileged: true
+`), nil, &portainer.ComposePolicy{Enabled: true, AllowedKeys: []string{"image"}, DeniedRules: []portainer.ComposePolicyRule{}})
+	assert.Equal(t, &ComposePolicyViolation{Service: "web", Key: "privileged"}, err)
+
+	assert.NoError(t, CheckComposePolicy([]byte(`version: "3"
+services:
+  web:
+    privileged: true
+`), nil, &portainer.ComposePolicy{}))
+
+	err = CheckComposePolicy([]byte(`version: "3"
+services:
+  agent:
+    image: agent
+    volumes:
+      - /var:/host/var
+`), nil, policy)
+	assert.Equal(t, &ComposePolicyViolation{Service: "agent", Key: "volumes", Value: "/var"}, err, "parent folders of denied host paths are denied")
+
+	err = CheckComposePolicy([]byte(`version: "3"
+services:
+  web:
+    image: nginx
+    security_opt:
+      - seccomp:unconfined
+`), nil, policy)
+	assert.Equal(t, &ComposePolicyViolation{Service: "web", Key: "security_opt", Value: "seccomp:unconfined"}, err)
+
+	err = CheckComposePolicy([]byte(`version: "3"
+services:
+  web:
+    image: nginx
+    devices:
+      - /dev/sda:/dev/sda
+`), nil, policy)
+	assert.Equal(t, &ComposePolicyViolation{Service: "web", Key: "devices"}, err)
+
+	err = CheckComposePolicy([]byte(`web:
+  image: nginx
+  net: host
+`), nil, policy)
+	assert.Equal(t, &ComposePolicyViolation{Service: "web", Key: "net", Value: "host"}, err, "services of Compose file format v1 are verified")
+}
@@ -0,0 +1,123 @@
+package stackutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/containerdefaults"
+	"gopkg.in/yaml.v2"
+)
+
+// EndpointContainerDefaults returns the container defaults of the group of the endpoint, nil when no data store is available
+func EndpointContainerDefaults(dataStore portainer.DataStore, endpoint *portainer.Endpoint) (*portainer.EndpointGroupContainerDefaults, error) {
+	if dataStore == nil {
+		return nil, nil
+	}
+
+	endpointGroup, err := dataStore.EndpointGroup().EndpointGroup(endpoint.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &endpointGroup.ContainerDefaults, nil
+}
+
+// CreateContainerDefaultsOverride creates a compose override file that applies the ulimits and sysctls defaults of an
+// endpoint group to each service of the compose file. The ulimits and sysctls explicitly specified by a service are kept.
+// It returns the path of the override file, which must be removed by the caller once the stack is deployed,
+// or an empty string when there is nothing to override.
+func CreateContainerDefaultsOverride(composeFilePath string, defaults *portainer.EndpointGroupContainerDefaults) (string, error) {
+	if defaults == nil || containerdefaults.IsEmpty(defaults) {
+		return "", nil
+	}
+
+	content, err := ioutil.ReadFile(composeFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	override, err := containerDefaultsOverride(content, defaults)
+	if err != nil || override == nil {
+		return "", err
+	}
+
+	return writeOverrideFile("portainer-container-defaults-*.yml", override)
+}
+
+func containerDefaultsOverride(content []byte, defaults *portainer.EndpointGroupContainerDefaults) ([]byte, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, ok := composeFile["services"].(map[interface{}]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	overrideServices := map[string]interface{}{}
+	for name, definition := range services {
+		service, _ := definition.(map[interface{}]interface{})
+
+		overrideService := map[string]interface{}{}
+
+		ulimits := map[string]interface{}{}
+		for _, ulimit := range defaults.Ulimits {
+			if !serviceDefinesKey(service["ulimits"], ulimit.Name) {
+				ulimits[ulimit.Name] = map[string]int64{"soft": ulimit.Soft, "hard": ulimit.Hard}
+			}
+		}
+		if len(ulimits) > 0 {
+			overrideService["ulimits"] = ulimits
+		}
+
+		networkMode, _ := service["network_mode"].(string)
+		ipcMode, _ := service["ipc"].(string)
+
+		sysctls := map[string]string{}
+		for key, value := range containerdefaults.ApplicableSysctls(defaults.Sysctls, networkMode, ipcMode) {
+			if !serviceDefinesKey(service["sysctls"], key) {
+				sysctls[key] = value
+			}
+		}
+		if len(sysctls) > 0 {
+			overrideService["sysctls"] = sysctls
+		}
+
+		if len(overrideService) > 0 {
+			overrideServices[fmt.Sprint(name)] = overrideService
+		}
+	}
+
+	if len(overrideServices) == 0 {
+		return nil, nil
+	}
+
+	override := map[string]interface{}{
+		"services": overrideServices,
+	}
+	if version, ok := composeFile["version"]; ok {
+		override["version"] = version
+	}
+
+	return yaml.Marshal(override)
+}
+
+// serviceDefinesKey returns whether a service option, using the map or the key=value list syntax, defines the key
+func serviceDefinesKey(option interface{}, key string) bool {
+	switch values := option.(type) {
+	case map[interface{}]interface{}:
+		_, ok := values[key]
+		return ok
+	case []interface{}:
+		for _, value := range values {
+			if strings.SplitN(fmt.Sprint(value), "=", 2)[0] == key {
+				return true
+			}
+		}
+	}
+	return false
+}
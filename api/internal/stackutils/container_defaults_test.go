@@ -0,0 +1,50 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_containerDefaultsOverride(t *testing.T) {
+	content := []byte(`version: "3.7"
+services:
+  db:
+    image: postgres
+    ulimits:
+      nofile: 1024
+    sysctls:
+      - net.core.somaxconn=4096
+  host:
+    image: agent
+    network_mode: host
+    ulimits:
+      nofile: 1024
+`)
+
+	defaults := &portainer.EndpointGroupContainerDefaults{
+		Ulimits: []portainer.Ulimit{{Name: "nofile", Soft: 65536, Hard: 65536}, {Name: "nproc", Soft: 4096, Hard: 8192}},
+		Sysctls: map[string]string{"net.core.somaxconn": "1024", "kernel.shmmax": "68719476736"},
+	}
+
+	override, err := containerDefaultsOverride(content, defaults)
+	assert.NoError(t, err)
+
+	var result struct {
+		Version  string
+		Services map[string]struct {
+			Ulimits map[string]map[string]int64
+			Sysctls map[string]string
+		}
+	}
+	err = yaml.Unmarshal(override, &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "3.7", result.Version)
+	assert.Equal(t, map[string]map[string]int64{"nproc": {"soft": 4096, "hard": 8192}}, result.Services["db"].Ulimits)
+	assert.Equal(t, map[string]string{"kernel.shmmax": "68719476736"}, result.Services["db"].Sysctls)
+	assert.Equal(t, map[string]map[string]int64{"nproc": {"soft": 4096, "hard": 8192}}, result.Services["host"].Ulimits)
+	assert.Equal(t, map[string]string{"kernel.shmmax": "68719476736"}, result.Services["host"].Sysctls)
+}
@@ -0,0 +1,165 @@
+package stackutils
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"gopkg.in/yaml.v2"
+)
+
+// ChangeType represents the type of a change between two stack definitions
+type ChangeType string
+
+const (
+	// ChangeAdded is used when a value is only present in the newest definition
+	ChangeAdded ChangeType = "added"
+	// ChangeRemoved is used when a value is only present in the oldest definition
+	ChangeRemoved ChangeType = "removed"
+	// ChangeModified is used when a value is present in both definitions with a different content
+	ChangeModified ChangeType = "modified"
+)
+
+// Change represents a difference between two stack definitions
+type Change struct {
+	// Path of the value inside the definition
+	Path string `json:"Path" example:"services.web.image"`
+	// Type of the change: added, removed or modified
+	Type ChangeType `json:"Type" example:"modified"`
+	// Previous value
+	From interface{} `json:"From,omitempty" example:"nginx:1.18"`
+	// New value
+	To interface{} `json:"To,omitempty" example:"nginx:1.19"`
+}
+
+// keyValueListKeys contains the compose keys that can be expressed either as a list of key=value or as a map.
+// They are always normalized to maps so that both forms can be compared.
+var keyValueListKeys = map[string]bool{
+	"environment": true,
+	"labels":      true,
+	"args":        true,
+	"sysctls":     true,
+}
+
+// DiffComposeFiles returns a structural diff between two compose files. Both files are normalized
+// before being compared so that formatting, key ordering and the list/map forms of environment
+// variables and labels do not produce changes. Changes are sorted by path.
+func DiffComposeFiles(from, to []byte) ([]Change, error) {
+	fromTree, err := normalizeComposeFile(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toTree, err := normalizeComposeFile(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, 0)
+	diffValues("", fromTree, toTree, &changes)
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// DiffEnv returns the differences between two lists of stack environment variables, sorted by path.
+func DiffEnv(from, to []portainer.Pair) []Change {
+	changes := make([]Change, 0)
+	diffValues("env", pairsToMap(from), pairsToMap(to), &changes)
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+func normalizeComposeFile(content []byte) (interface{}, error) {
+	var tree interface{}
+	err := yaml.Unmarshal(content, &tree)
+	if err != nil {
+		return nil, err
+	}
+
+	return normalizeValue("", tree), nil
+}
+
+func normalizeValue(key string, value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(typedValue))
+		for childKey, childValue := range typedValue {
+			normalizedKey := fmt.Sprint(childKey)
+			normalized[normalizedKey] = normalizeValue(normalizedKey, childValue)
+		}
+		return normalized
+	case []interface{}:
+		if keyValueListKeys[key] {
+			normalized := make(map[string]interface{}, len(typedValue))
+			for _, item := range typedValue {
+				parts := strings.SplitN(fmt.Sprint(item), "=", 2)
+				if len(parts) == 2 {
+					normalized[parts[0]] = parts[1]
+				} else {
+					normalized[parts[0]] = nil
+				}
+			}
+			return normalized
+		}
+
+		normalized := make([]interface{}, len(typedValue))
+		for idx, item := range typedValue {
+			normalized[idx] = normalizeValue("", item)
+		}
+		return normalized
+	}
+
+	return value
+}
+
+func diffValues(path string, from, to interface{}, changes *[]Change) {
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+
+	if fromIsMap && toIsMap {
+		for key, fromValue := range fromMap {
+			toValue, ok := toMap[key]
+			if !ok {
+				*changes = append(*changes, Change{Path: joinPath(path, key), Type: ChangeRemoved, From: fromValue})
+				continue
+			}
+			diffValues(joinPath(path, key), fromValue, toValue, changes)
+		}
+
+		for key, toValue := range toMap {
+			if _, ok := fromMap[key]; !ok {
+				*changes = append(*changes, Change{Path: joinPath(path, key), Type: ChangeAdded, To: toValue})
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, Change{Path: path, Type: ChangeModified, From: from, To: to})
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pairsToMap(pairs []portainer.Pair) map[string]interface{} {
+	values := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		values[pair.Name] = pair.Value
+	}
+	return values
+}
@@ -0,0 +1,53 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DiffComposeFiles(t *testing.T) {
+	from := []byte(`version: "3"
+services:
+  web:
+    image: nginx:1.18
+    environment:
+      - MODE=prod
+      - DEBUG=0
+  cache:
+    image: redis
+`)
+
+	to := []byte(`services:
+  web:
+    environment:
+      DEBUG: "1"
+      MODE: prod
+    image: nginx:1.19
+  db:
+    image: postgres
+version: "3"
+`)
+
+	changes, err := DiffComposeFiles(from, to)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Change{
+		{Path: "services.cache", Type: ChangeRemoved, From: map[string]interface{}{"image": "redis"}},
+		{Path: "services.db", Type: ChangeAdded, To: map[string]interface{}{"image": "postgres"}},
+		{Path: "services.web.environment.DEBUG", Type: ChangeModified, From: "0", To: "1"},
+		{Path: "services.web.image", Type: ChangeModified, From: "nginx:1.18", To: "nginx:1.19"},
+	}, changes)
+}
+
+func Test_DiffEnv(t *testing.T) {
+	from := []portainer.Pair{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}}
+	to := []portainer.Pair{{Name: "B", Value: "3"}, {Name: "C", Value: "4"}}
+
+	assert.Equal(t, []Change{
+		{Path: "env.A", Type: ChangeRemoved, From: "1"},
+		{Path: "env.B", Type: ChangeModified, From: "2", To: "3"},
+		{Path: "env.C", Type: ChangeAdded, To: "4"},
+	}, DiffEnv(from, to))
+}
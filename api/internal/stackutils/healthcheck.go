@@ -0,0 +1,127 @@
+package stackutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"gopkg.in/yaml.v2"
+)
+
+// ValidateHealthcheckOverrides validates the healthcheck overrides of a stack
+func ValidateHealthcheckOverrides(overrides map[string]portainer.HealthcheckOverride) error {
+	for serviceName, override := range overrides {
+		if override.Disable {
+			continue
+		}
+
+		if len(override.Test) < 2 || (override.Test[0] != "CMD" && override.Test[0] != "CMD-SHELL") {
+			return fmt.Errorf("Invalid healthcheck test for service %s. Must be a command starting with CMD or CMD-SHELL", serviceName)
+		}
+
+		for _, duration := range []string{override.Interval, override.Timeout, override.StartPeriod} {
+			if duration == "" {
+				continue
+			}
+
+			value, err := time.ParseDuration(duration)
+			if err != nil || value <= 0 {
+				return fmt.Errorf("Invalid healthcheck duration for service %s: %s", serviceName, duration)
+			}
+		}
+
+		if override.Retries < 0 {
+			return fmt.Errorf("Invalid healthcheck retries for service %s. Must be positive", serviceName)
+		}
+	}
+
+	return nil
+}
+
+// CreateHealthcheckOverride creates a compose override file that replaces the healthcheck of the services
+// of the compose file. Overrides of services that are not part of the compose file are ignored.
+// It returns the path of the override file, which must be removed by the caller once the stack is deployed,
+// or an empty string when there is nothing to override.
+func CreateHealthcheckOverride(composeFilePath string, overrides map[string]portainer.HealthcheckOverride) (string, error) {
+	if len(overrides) == 0 {
+		return "", nil
+	}
+
+	content, err := ioutil.ReadFile(composeFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	override, err := healthcheckOverride(content, overrides)
+	if err != nil || override == nil {
+		return "", err
+	}
+
+	return writeOverrideFile("portainer-healthcheck-*.yml", override)
+}
+
+func healthcheckOverride(content []byte, overrides map[string]portainer.HealthcheckOverride) ([]byte, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, ok := composeFile["services"].(map[interface{}]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	overrideServices := map[string]interface{}{}
+	for name := range services {
+		serviceName := fmt.Sprint(name)
+
+		override, ok := overrides[serviceName]
+		if !ok {
+			continue
+		}
+
+		overrideServices[serviceName] = map[string]interface{}{
+			"healthcheck": composeHealthcheck(&override),
+		}
+	}
+
+	if len(overrideServices) == 0 {
+		return nil, nil
+	}
+
+	result := map[string]interface{}{
+		"services": overrideServices,
+	}
+	if version, ok := composeFile["version"]; ok {
+		result["version"] = version
+	}
+
+	return yaml.Marshal(result)
+}
+
+func composeHealthcheck(override *portainer.HealthcheckOverride) map[string]interface{} {
+	if override.Disable {
+		return map[string]interface{}{"disable": true}
+	}
+
+	healthcheck := map[string]interface{}{
+		"test": override.Test,
+	}
+
+	if override.Interval != "" {
+		healthcheck["interval"] = override.Interval
+	}
+	if override.Timeout != "" {
+		healthcheck["timeout"] = override.Timeout
+	}
+	if override.Retries > 0 {
+		healthcheck["retries"] = override.Retries
+	}
+	if override.StartPeriod != "" {
+		healthcheck["start_period"] = override.StartPeriod
+	}
+
+	return healthcheck
+}
@@ -0,0 +1,55 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_healthcheckOverride(t *testing.T) {
+	content := []byte(`version: "3.7"
+services:
+  web:
+    image: nginx
+  db:
+    image: postgres
+  cache:
+    image: redis
+`)
+
+	overrides := map[string]portainer.HealthcheckOverride{
+		"web":     {Test: []string{"CMD-SHELL", "curl -f http://localhost"}, Interval: "10s", Retries: 5},
+		"db":      {Disable: true},
+		"missing": {Disable: true},
+	}
+
+	override, err := healthcheckOverride(content, overrides)
+	assert.NoError(t, err)
+
+	var result struct {
+		Version  string
+		Services map[string]map[string]map[string]interface{}
+	}
+	err = yaml.Unmarshal(override, &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "3.7", result.Version)
+	assert.Len(t, result.Services, 2)
+	assert.Equal(t, []interface{}{"CMD-SHELL", "curl -f http://localhost"}, result.Services["web"]["healthcheck"]["test"])
+	assert.Equal(t, "10s", result.Services["web"]["healthcheck"]["interval"])
+	assert.Equal(t, 5, result.Services["web"]["healthcheck"]["retries"])
+	assert.NotContains(t, result.Services["web"]["healthcheck"], "timeout")
+	assert.Equal(t, true, result.Services["db"]["healthcheck"]["disable"])
+}
+
+func Test_ValidateHealthcheckOverrides(t *testing.T) {
+	assert.NoError(t, ValidateHealthcheckOverrides(map[string]portainer.HealthcheckOverride{
+		"web": {Test: []string{"CMD", "true"}, Interval: "30s"},
+		"db":  {Disable: true},
+	}))
+	assert.Error(t, ValidateHealthcheckOverrides(map[string]portainer.HealthcheckOverride{"web": {Test: []string{"true"}}}))
+	assert.Error(t, ValidateHealthcheckOverrides(map[string]portainer.HealthcheckOverride{"web": {Test: []string{"CMD", "true"}, Timeout: "abc"}}))
+	assert.Error(t, ValidateHealthcheckOverrides(map[string]portainer.HealthcheckOverride{"web": {Test: []string{"CMD", "true"}, Retries: -1}}))
+}
@@ -0,0 +1,129 @@
+package stackutils
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"gopkg.in/yaml.v2"
+)
+
+var composeVariableRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::?-([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// ComposeFileImages returns the list of images referenced by the services of a compose file.
+// Variables used in the image names are interpolated using the stack environment variables.
+func ComposeFileImages(content []byte, env []portainer.Pair) ([]string, error) {
+	return composeFileImages(content, env, true)
+}
+
+// ComposeFilePullableImages returns the list of images referenced by the services of a compose file,
+// excluding the images built from a build section which are not available on a registry.
+func ComposeFilePullableImages(content []byte, env []portainer.Pair) ([]string, error) {
+	return composeFileImages(content, env, false)
+}
+
+func composeFileImages(content []byte, env []portainer.Pair, includeBuilt bool) ([]string, error) {
+	services, err := composeFileServices(content)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := envVariables(env)
+
+	images := []string{}
+	seen := map[string]bool{}
+	for _, definition := range services {
+		service, _ := definition.(map[interface{}]interface{})
+
+		if _, built := service["build"]; built && !includeBuilt {
+			continue
+		}
+
+		image, ok := service["image"].(string)
+		if !ok || image == "" {
+			continue
+		}
+
+		image = interpolateVariables(image, variables)
+		if !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+
+	sort.Strings(images)
+
+	return images, nil
+}
+
+// ComposeFileServiceImages returns the image of each service of a compose file, per service name.
+// The image is empty for the services built from a build section without an image name.
+func ComposeFileServiceImages(content []byte, env []portainer.Pair) (map[string]string, error) {
+	services, err := composeFileServices(content)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := envVariables(env)
+
+	images := make(map[string]string, len(services))
+	for name, definition := range services {
+		serviceName, ok := name.(string)
+		if !ok {
+			continue
+		}
+
+		service, _ := definition.(map[interface{}]interface{})
+		image, _ := service["image"].(string)
+
+		images[serviceName] = interpolateVariables(image, variables)
+	}
+
+	return images, nil
+}
+
+func composeFileServices(content []byte) (map[interface{}]interface{}, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, _ := composeFile["services"].(map[interface{}]interface{})
+	return services, nil
+}
+
+func envVariables(env []portainer.Pair) map[string]string {
+	variables := map[string]string{}
+	for _, pair := range env {
+		variables[pair.Name] = pair.Value
+	}
+	return variables
+}
+
+// interpolateVariables replaces $VAR, ${VAR} and ${VAR:-default} occurrences using the specified variables,
+// falling back to the environment of the Portainer process like docker-compose does.
+func interpolateVariables(value string, variables map[string]string) string {
+	value = strings.Replace(value, "$$", "\x00", -1)
+
+	value = composeVariableRe.ReplaceAllStringFunc(value, func(match string) string {
+		groups := composeVariableRe.FindStringSubmatch(match)
+
+		name := groups[1]
+		if name == "" {
+			name = groups[3]
+		}
+
+		if variable, ok := variables[name]; ok && variable != "" {
+			return variable
+		}
+		if variable := os.Getenv(name); variable != "" {
+			return variable
+		}
+		return groups[2]
+	})
+
+	return strings.Replace(value, "\x00", "$", -1)
+}
@@ -0,0 +1,65 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ComposeFileImages(t *testing.T) {
+	content := []byte(`
+version: "3"
+services:
+  web:
+    image: nginx:${NGINX_VERSION}
+  db:
+    image: ${DB_IMAGE:-postgres:12}
+  cache:
+    image: $CACHE_IMAGE
+  worker:
+    build: .
+  web-replica:
+    image: nginx:${NGINX_VERSION}
+`)
+	env := []portainer.Pair{
+		{Name: "NGINX_VERSION", Value: "1.19"},
+		{Name: "CACHE_IMAGE", Value: "redis"},
+	}
+
+	images, err := ComposeFileImages(content, env)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nginx:1.19", "postgres:12", "redis"}, images)
+}
+
+func Test_ComposeFilePullableImages(t *testing.T) {
+	content := []byte(`
+version: "3"
+services:
+  web:
+    image: nginx:latest
+  app:
+    image: myapp:dev
+    build: ./app
+`)
+
+	images, err := ComposeFilePullableImages(content, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nginx:latest"}, images)
+}
+
+func Test_ComposeFileServiceImages(t *testing.T) {
+	content := []byte(`
+version: "3"
+services:
+  web:
+    image: nginx:${NGINX_VERSION}
+  worker:
+    build: .
+`)
+	env := []portainer.Pair{{Name: "NGINX_VERSION", Value: "1.19"}}
+
+	images, err := ComposeFileServiceImages(content, env)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"web": "nginx:1.19", "worker": ""}, images)
+}
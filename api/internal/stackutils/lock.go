@@ -0,0 +1,44 @@
+package stackutils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// DefaultLockTimeout is the duration of an edit lock when no timeout is specified
+	DefaultLockTimeout = 30 * time.Minute
+	// MaxLockTimeout is the longest duration of an edit lock
+	MaxLockTimeout = 24 * time.Hour
+)
+
+var errInvalidLockTimeout = errors.New("Invalid lock timeout. Must be a valid positive duration of at most 24h")
+
+// LockTimeout parses the timeout of an edit lock, using the default timeout when none is specified
+func LockTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return DefaultLockTimeout, nil
+	}
+
+	duration, err := time.ParseDuration(timeout)
+	if err != nil || duration <= 0 || duration > MaxLockTimeout {
+		return 0, errInvalidLockTimeout
+	}
+	return duration, nil
+}
+
+// ActiveLock returns the edit lock of the stack, nil when the stack is not locked or its lock expired
+func ActiveLock(stack *portainer.Stack, now time.Time) *portainer.StackLock {
+	if stack.Lock == nil || now.Unix() >= stack.Lock.ExpiryDate {
+		return nil
+	}
+	return stack.Lock
+}
+
+// LockedStatus describes the edit lock of the stack, used to explain why an automated deployment was skipped
+func LockedStatus(lock *portainer.StackLock) string {
+	return fmt.Sprintf("stack locked by %s until %s", lock.LockedBy, time.Unix(lock.ExpiryDate, 0).UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,34 @@
+package stackutils
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LockTimeout(t *testing.T) {
+	timeout, err := LockTimeout("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultLockTimeout, timeout)
+
+	timeout, err = LockTimeout("10m")
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, timeout)
+
+	_, err = LockTimeout("-1m")
+	assert.Error(t, err)
+	_, err = LockTimeout("48h")
+	assert.Error(t, err)
+}
+
+func Test_ActiveLock(t *testing.T) {
+	stack := &portainer.Stack{Lock: &portainer.StackLock{LockedBy: "bob", LockDate: 1000, ExpiryDate: 1600}}
+
+	assert.Equal(t, stack.Lock, ActiveLock(stack, time.Unix(1200, 0)))
+	assert.Nil(t, ActiveLock(stack, time.Unix(1600, 0)), "expired locks are ignored")
+	assert.Nil(t, ActiveLock(&portainer.Stack{}, time.Unix(1200, 0)))
+
+	assert.Equal(t, "stack locked by bob until 1970-01-01T00:26:40Z", LockedStatus(stack.Lock))
+}
@@ -0,0 +1,153 @@
+package stackutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/logdriver"
+	"gopkg.in/yaml.v2"
+)
+
+// ValidateLoggingOverrides validates the logging drivers applied to the services of a stack
+func ValidateLoggingOverrides(overrides map[string]portainer.LogConfig) error {
+	for serviceName, override := range overrides {
+		err := logdriver.Validate(&override)
+		if err != nil {
+			return fmt.Errorf("Invalid logging driver for service %s: %s", serviceName, err)
+		}
+	}
+	return nil
+}
+
+// CreateLoggingOverride creates a compose override file that replaces the logging driver of the services
+// of the compose file. Overrides of services that are not part of the compose file are ignored.
+// It returns the path of the override file, which must be removed by the caller once the stack is deployed,
+// or an empty string when there is nothing to override.
+func CreateLoggingOverride(composeFilePath string, overrides map[string]portainer.LogConfig) (string, error) {
+	if len(overrides) == 0 {
+		return "", nil
+	}
+
+	content, err := ioutil.ReadFile(composeFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	override, err := loggingOverride(content, overrides)
+	if err != nil || override == nil {
+		return "", err
+	}
+
+	return writeOverrideFile("portainer-logging-*.yml", override)
+}
+
+func loggingOverride(content []byte, overrides map[string]portainer.LogConfig) ([]byte, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, ok := composeFile["services"].(map[interface{}]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	overrideServices := map[string]interface{}{}
+	for name := range services {
+		serviceName := fmt.Sprint(name)
+
+		override, ok := overrides[serviceName]
+		if !ok {
+			continue
+		}
+
+		logging := map[string]interface{}{
+			"driver": override.Driver,
+		}
+		if len(override.Options) > 0 {
+			logging["options"] = override.Options
+		}
+
+		overrideServices[serviceName] = map[string]interface{}{
+			"logging": logging,
+		}
+	}
+
+	if len(overrideServices) == 0 {
+		return nil, nil
+	}
+
+	result := map[string]interface{}{
+		"services": overrideServices,
+	}
+	if version, ok := composeFile["version"]; ok {
+		result["version"] = version
+	}
+
+	return yaml.Marshal(result)
+}
+
+// ServiceLogConfigs returns the logging drivers used by the services of a compose file, sorted by service name.
+// The overrides take precedence over the logging section of the services. Logging sections using variables
+// are ignored as their value is only known once interpolated by the deployment.
+func ServiceLogConfigs(content []byte, overrides map[string]portainer.LogConfig) ([]portainer.LogConfig, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, _ := composeFile["services"].(map[interface{}]interface{})
+
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, fmt.Sprint(name))
+	}
+	sort.Strings(names)
+
+	configs := make([]portainer.LogConfig, 0)
+	for _, name := range names {
+		if override, ok := overrides[name]; ok {
+			configs = append(configs, override)
+			continue
+		}
+
+		service, _ := services[name].(map[interface{}]interface{})
+		logging, ok := service["logging"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		config := portainer.LogConfig{
+			Driver:  fmt.Sprint(logging["driver"]),
+			Options: map[string]string{},
+		}
+		if options, ok := logging["options"].(map[interface{}]interface{}); ok {
+			for key, value := range options {
+				config.Options[fmt.Sprint(key)] = fmt.Sprint(value)
+			}
+		}
+
+		if !usesVariables(config) {
+			configs = append(configs, config)
+		}
+	}
+
+	return configs, nil
+}
+
+func usesVariables(config portainer.LogConfig) bool {
+	if strings.Contains(config.Driver, "$") {
+		return true
+	}
+	for _, value := range config.Options {
+		if strings.Contains(value, "$") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ServiceLogConfigs(t *testing.T) {
+	content := []byte(`version: "3.7"
+services:
+  web:
+    image: nginx
+    logging:
+      driver: gelf
+      options:
+        gelf-address: udp://graylog:12201
+  worker:
+    image: worker
+    logging:
+      driver: fluentd
+      options:
+        fluentd-address: ${FLUENTD_ADDRESS}
+  db:
+    image: postgres
+`)
+
+	overrides := map[string]portainer.LogConfig{
+		"db": {Driver: "json-file", Options: map[string]string{"max-size": "10m"}},
+	}
+
+	configs, err := ServiceLogConfigs(content, overrides)
+	assert.NoError(t, err)
+	assert.Equal(t, []portainer.LogConfig{
+		{Driver: "json-file", Options: map[string]string{"max-size": "10m"}},
+		{Driver: "gelf", Options: map[string]string{"gelf-address": "udp://graylog:12201"}},
+	}, configs)
+}
@@ -0,0 +1,90 @@
+package stackutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"gopkg.in/yaml.v2"
+)
+
+// CreateNetworkDefaultsOverride creates a compose override file that applies the network defaults of an endpoint
+// (DNS servers, DNS search domains and extra hosts) to each service of the compose file that does not explicitly
+// specify these settings. It returns the path of the override file, which must be removed by the caller once the
+// stack is deployed, or an empty string when there is nothing to override.
+func CreateNetworkDefaultsOverride(composeFilePath string, defaults *portainer.EndpointNetworkDefaults) (string, error) {
+	if len(defaults.DNS) == 0 && len(defaults.DNSSearch) == 0 && len(defaults.ExtraHosts) == 0 {
+		return "", nil
+	}
+
+	content, err := ioutil.ReadFile(composeFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	override, err := networkDefaultsOverride(content, defaults)
+	if err != nil || override == nil {
+		return "", err
+	}
+
+	return writeOverrideFile("portainer-network-defaults-*.yml", override)
+}
+
+func networkDefaultsOverride(content []byte, defaults *portainer.EndpointNetworkDefaults) ([]byte, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, ok := composeFile["services"].(map[interface{}]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	overrideServices := map[string]interface{}{}
+	for name, definition := range services {
+		service, _ := definition.(map[interface{}]interface{})
+
+		// DNS settings and extra hosts cannot be used when sharing the network stack of the host or of another container
+		networkMode, _ := service["network_mode"].(string)
+		if networkMode == "host" || strings.HasPrefix(networkMode, "container:") || strings.HasPrefix(networkMode, "service:") {
+			continue
+		}
+
+		overrideService := map[string]interface{}{}
+		setDefaultServiceList(overrideService, service, "dns", defaults.DNS)
+		setDefaultServiceList(overrideService, service, "dns_search", defaults.DNSSearch)
+		setDefaultServiceList(overrideService, service, "extra_hosts", defaults.ExtraHosts)
+
+		if len(overrideService) > 0 {
+			overrideServices[fmt.Sprint(name)] = overrideService
+		}
+	}
+
+	if len(overrideServices) == 0 {
+		return nil, nil
+	}
+
+	override := map[string]interface{}{
+		"services": overrideServices,
+	}
+	if version, ok := composeFile["version"]; ok {
+		override["version"] = version
+	}
+
+	return yaml.Marshal(override)
+}
+
+func setDefaultServiceList(overrideService map[string]interface{}, service map[interface{}]interface{}, key string, defaultValues []string) {
+	if len(defaultValues) == 0 {
+		return
+	}
+
+	if _, ok := service[key]; ok {
+		return
+	}
+
+	overrideService[key] = defaultValues
+}
@@ -0,0 +1,58 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_networkDefaultsOverride(t *testing.T) {
+	content := []byte(`version: "3.7"
+services:
+  web:
+    image: nginx
+  api:
+    image: api
+    dns: 8.8.8.8
+  host:
+    image: agent
+    network_mode: host
+`)
+
+	defaults := &portainer.EndpointNetworkDefaults{
+		DNS:        []string{"10.0.0.53"},
+		ExtraHosts: []string{"registry.corp:10.0.0.10"},
+	}
+
+	override, err := networkDefaultsOverride(content, defaults)
+	assert.NoError(t, err)
+
+	var result struct {
+		Version  string
+		Services map[string]map[string][]string
+	}
+	err = yaml.Unmarshal(override, &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "3.7", result.Version)
+	assert.Len(t, result.Services, 2)
+	assert.Equal(t, []string{"10.0.0.53"}, result.Services["web"]["dns"])
+	assert.Equal(t, []string{"registry.corp:10.0.0.10"}, result.Services["web"]["extra_hosts"])
+	assert.NotContains(t, result.Services["api"], "dns")
+	assert.Equal(t, []string{"registry.corp:10.0.0.10"}, result.Services["api"]["extra_hosts"])
+}
+
+func Test_networkDefaultsOverride_shouldReturnNilWhenNothingToOverride(t *testing.T) {
+	content := []byte(`version: "3.7"
+services:
+  web:
+    image: nginx
+    dns_search: corp
+`)
+
+	override, err := networkDefaultsOverride(content, &portainer.EndpointNetworkDefaults{DNSSearch: []string{"mydomain.tld"}})
+	assert.NoError(t, err)
+	assert.Nil(t, override)
+}
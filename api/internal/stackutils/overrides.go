@@ -0,0 +1,75 @@
+package stackutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// CreateDeploymentOverrides creates the compose override files applied on top of the stack file when a stack
+// is deployed on an endpoint. The container defaults of the endpoint group are not applied when nil.
+// It returns the paths of the override files, which must be removed by the caller with RemoveOverrideFiles
+// once the stack is deployed.
+func CreateDeploymentOverrides(stack *portainer.Stack, endpoint *portainer.Endpoint, containerDefaults *portainer.EndpointGroupContainerDefaults) ([]string, error) {
+	composeFilePath := path.Join(stack.ProjectPath, stack.EntryPoint)
+
+	overrideBuilders := []func() (string, error){
+		func() (string, error) {
+			return CreateNetworkDefaultsOverride(composeFilePath, &endpoint.NetworkDefaults)
+		},
+		func() (string, error) {
+			return CreateContainerDefaultsOverride(composeFilePath, containerDefaults)
+		},
+		func() (string, error) {
+			return CreateHealthcheckOverride(composeFilePath, stack.HealthcheckOverrides)
+		},
+		func() (string, error) {
+			return CreateLoggingOverride(composeFilePath, stack.LoggingOverrides)
+		},
+		func() (string, error) {
+			return CreateSecretsOverride(composeFilePath, stack.Name, stack.Secrets)
+		},
+		func() (string, error) {
+			return CreatePinnedImagesOverride(composeFilePath, stack.Env, stack.PinnedImages)
+		},
+	}
+
+	overrideFilePaths := make([]string, 0)
+	for _, build := range overrideBuilders {
+		overrideFilePath, err := build()
+		if err != nil {
+			RemoveOverrideFiles(overrideFilePaths)
+			return nil, err
+		}
+
+		if overrideFilePath != "" {
+			overrideFilePaths = append(overrideFilePaths, overrideFilePath)
+		}
+	}
+
+	return overrideFilePaths, nil
+}
+
+// RemoveOverrideFiles removes the override files created by CreateDeploymentOverrides
+func RemoveOverrideFiles(overrideFilePaths []string) {
+	for _, overrideFilePath := range overrideFilePaths {
+		os.Remove(overrideFilePath)
+	}
+}
+
+func writeOverrideFile(pattern string, override []byte) (string, error) {
+	overrideFile, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer overrideFile.Close()
+
+	_, err = overrideFile.Write(override)
+	if err != nil {
+		return "", err
+	}
+
+	return overrideFile.Name(), nil
+}
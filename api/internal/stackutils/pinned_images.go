@@ -0,0 +1,78 @@
+package stackutils
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	portainer "github.com/portainer/portainer/api"
+	"gopkg.in/yaml.v2"
+)
+
+// CreatePinnedImagesOverride creates a compose override file that replaces the image of the services of the compose file
+// by the image pinned to the digest verified against the image trust policy, so that a tag moved after the verification
+// is not deployed. It returns the path of the override file, which must be removed by the caller once the stack is deployed,
+// or an empty string when there is nothing to override.
+func CreatePinnedImagesOverride(composeFilePath string, env []portainer.Pair, pinnedImages map[string]string) (string, error) {
+	if len(pinnedImages) == 0 {
+		return "", nil
+	}
+
+	content, err := ioutil.ReadFile(composeFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	override, err := pinnedImagesOverride(content, env, pinnedImages)
+	if err != nil || override == nil {
+		return "", err
+	}
+
+	return writeOverrideFile("portainer-pinned-images-*.yml", override)
+}
+
+func pinnedImagesOverride(content []byte, env []portainer.Pair, pinnedImages map[string]string) ([]byte, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, ok := composeFile["services"].(map[interface{}]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	variables := envVariables(env)
+
+	overrideServices := map[string]interface{}{}
+	for name, definition := range services {
+		service, _ := definition.(map[interface{}]interface{})
+
+		image, ok := service["image"].(string)
+		if !ok || image == "" {
+			continue
+		}
+
+		pinnedImage, ok := pinnedImages[interpolateVariables(image, variables)]
+		if !ok {
+			continue
+		}
+
+		overrideServices[fmt.Sprint(name)] = map[string]interface{}{
+			"image": pinnedImage,
+		}
+	}
+
+	if len(overrideServices) == 0 {
+		return nil, nil
+	}
+
+	result := map[string]interface{}{
+		"services": overrideServices,
+	}
+	if version, ok := composeFile["version"]; ok {
+		result["version"] = version
+	}
+
+	return yaml.Marshal(result)
+}
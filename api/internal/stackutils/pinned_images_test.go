@@ -0,0 +1,39 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_pinnedImagesOverride(t *testing.T) {
+	content := []byte(`version: "3.7"
+services:
+  web:
+    image: nginx:${NGINX_VERSION}
+  worker:
+    image: myapp_worker
+    build: ./worker
+  db:
+    image: postgres
+`)
+
+	pinnedImages := map[string]string{
+		"nginx:1.19": "docker.io/library/nginx:1.19@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+	}
+
+	override, err := pinnedImagesOverride(content, []portainer.Pair{{Name: "NGINX_VERSION", Value: "1.19"}}, pinnedImages)
+	assert.NoError(t, err)
+
+	var result map[string]interface{}
+	err = yaml.Unmarshal(override, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, map[interface{}]interface{}{
+		"web": map[interface{}]interface{}{
+			"image": "docker.io/library/nginx:1.19@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+		},
+	}, result["services"])
+	assert.Equal(t, "3.7", result["version"])
+}
@@ -0,0 +1,234 @@
+package stackutils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// DefaultRestartStepTimeout is the maximum duration to wait for a service to be ready during a restart cascade
+// when no timeout is specified
+const DefaultRestartStepTimeout = 2 * time.Minute
+
+// ValidateRestartDependencies validates the restart dependencies of a stack. A service can only be declared once
+// and the dependencies cannot form a cycle, a restart would otherwise be cascaded indefinitely.
+func ValidateRestartDependencies(dependencies []portainer.StackRestartDependency) error {
+	graph := make(map[string][]string)
+
+	for idx, dependency := range dependencies {
+		if dependency.Service == "" {
+			return fmt.Errorf("Invalid restart dependency %d. Service name cannot be empty", idx+1)
+		}
+		if _, ok := graph[dependency.Service]; ok {
+			return fmt.Errorf("Invalid restart dependencies. Service %s is declared more than once", dependency.Service)
+		}
+		if len(dependency.Dependents) == 0 {
+			return fmt.Errorf("Invalid restart dependency %d. At least one dependent service must be specified", idx+1)
+		}
+
+		for _, dependent := range dependency.Dependents {
+			if dependent == "" {
+				return fmt.Errorf("Invalid restart dependency %d. Dependent service names cannot be empty", idx+1)
+			}
+		}
+
+		if dependency.Timeout != "" {
+			timeout, err := time.ParseDuration(dependency.Timeout)
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("Invalid restart dependency %d. Invalid timeout: %s", idx+1, dependency.Timeout)
+			}
+		}
+
+		graph[dependency.Service] = dependency.Dependents
+	}
+
+	cycle := findCycle(graph)
+	if cycle != nil {
+		return fmt.Errorf("Invalid restart dependencies. Cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// findCycle returns the services forming a cycle in the graph, starting and ending with the same service,
+// nil when the graph has no cycle
+func findCycle(graph map[string][]string) []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[string]int)
+	path := make([]string, 0)
+
+	var visit func(service string) []string
+	visit = func(service string) []string {
+		switch state[service] {
+		case visited:
+			return nil
+		case visiting:
+			for idx, candidate := range path {
+				if candidate == service {
+					return append(append([]string{}, path[idx:]...), service)
+				}
+			}
+		}
+
+		state[service] = visiting
+		path = append(path, service)
+
+		for _, dependent := range graph[service] {
+			if cycle := visit(dependent); cycle != nil {
+				return cycle
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[service] = visited
+		return nil
+	}
+
+	for _, service := range sortedKeys(graph) {
+		if cycle := visit(service); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// CheckRestartDependencies verifies that the services of the restart dependencies are defined in the compose file
+func CheckRestartDependencies(content []byte, dependencies []portainer.StackRestartDependency) error {
+	if len(dependencies) == 0 {
+		return nil
+	}
+
+	services, err := composeServiceDependencies(content)
+	if err != nil {
+		return err
+	}
+
+	for _, dependency := range dependencies {
+		for _, service := range append([]string{dependency.Service}, dependency.Dependents...) {
+			if _, ok := services[service]; !ok {
+				return fmt.Errorf("Invalid restart dependencies. Service %s is not defined in the compose file", service)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RestartDependenciesFromCompose returns the restart dependencies matching the depends_on relations of the compose file:
+// a service is restarted after the services it depends on restart.
+func RestartDependenciesFromCompose(content []byte) ([]portainer.StackRestartDependency, error) {
+	services, err := composeServiceDependencies(content)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := make(map[string][]string)
+	for service, dependencies := range services {
+		for _, dependency := range dependencies {
+			dependents[dependency] = append(dependents[dependency], service)
+		}
+	}
+
+	result := make([]portainer.StackRestartDependency, 0, len(dependents))
+	for _, service := range sortedKeys(dependents) {
+		sort.Strings(dependents[service])
+		result = append(result, portainer.StackRestartDependency{Service: service, Dependents: dependents[service]})
+	}
+
+	return result, nil
+}
+
+// RestartCascade returns the services to restart after a restart of the service, in the order of the restarts:
+// a service is restarted once all the restarted services it depends on are ready again.
+// The dependencies must not form a cycle, see ValidateRestartDependencies.
+func RestartCascade(dependencies []portainer.StackRestartDependency, service string) []string {
+	graph := make(map[string][]string)
+	for _, dependency := range dependencies {
+		graph[dependency.Service] = dependency.Dependents
+	}
+
+	reachable := make(map[string]bool)
+	queue := []string{service}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, dependent := range graph[current] {
+			if !reachable[dependent] && dependent != service {
+				reachable[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	pending := make(map[string]int)
+	for current := range reachable {
+		for _, dependent := range graph[current] {
+			if reachable[dependent] {
+				pending[dependent]++
+			}
+		}
+	}
+
+	ready := make([]string, 0)
+	for current := range reachable {
+		if pending[current] == 0 {
+			ready = append(ready, current)
+		}
+	}
+
+	cascade := make([]string, 0, len(reachable))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		current := ready[0]
+		ready = ready[1:]
+		cascade = append(cascade, current)
+
+		for _, dependent := range graph[current] {
+			if !reachable[dependent] {
+				continue
+			}
+
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	return cascade
+}
+
+// RestartStepTimeout returns the maximum duration to wait for the service to be ready during a restart cascade,
+// using the default timeout when none is specified
+func RestartStepTimeout(dependencies []portainer.StackRestartDependency, service string) time.Duration {
+	for _, dependency := range dependencies {
+		if dependency.Service != service {
+			continue
+		}
+
+		timeout, err := time.ParseDuration(dependency.Timeout)
+		if err == nil && timeout > 0 {
+			return timeout
+		}
+	}
+
+	return DefaultRestartStepTimeout
+}
+
+func sortedKeys(graph map[string][]string) []string {
+	keys := make([]string, 0, len(graph))
+	for key := range graph {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
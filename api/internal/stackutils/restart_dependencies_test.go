@@ -0,0 +1,74 @@
+package stackutils
+
+import (
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateRestartDependencies(t *testing.T) {
+	assert.NoError(t, ValidateRestartDependencies([]portainer.StackRestartDependency{
+		{Service: "db", Dependents: []string{"api", "worker"}, Timeout: "1m"},
+		{Service: "api", Dependents: []string{"web"}},
+	}))
+	assert.Error(t, ValidateRestartDependencies([]portainer.StackRestartDependency{{Dependents: []string{"api"}}}))
+	assert.Error(t, ValidateRestartDependencies([]portainer.StackRestartDependency{{Service: "db"}}))
+	assert.Error(t, ValidateRestartDependencies([]portainer.StackRestartDependency{{Service: "db", Dependents: []string{"api"}, Timeout: "abc"}}))
+	assert.Error(t, ValidateRestartDependencies([]portainer.StackRestartDependency{{Service: "db", Dependents: []string{"api"}}, {Service: "db", Dependents: []string{"web"}}}))
+
+	err := ValidateRestartDependencies([]portainer.StackRestartDependency{
+		{Service: "db", Dependents: []string{"api"}},
+		{Service: "api", Dependents: []string{"web"}},
+		{Service: "web", Dependents: []string{"db"}},
+	})
+	assert.EqualError(t, err, "Invalid restart dependencies. Cycle detected: api -> web -> db -> api")
+
+	assert.Error(t, ValidateRestartDependencies([]portainer.StackRestartDependency{{Service: "db", Dependents: []string{"db"}}}))
+}
+
+func Test_RestartDependenciesFromCompose(t *testing.T) {
+	content := []byte(`version: "2.4"
+services:
+  db:
+    image: postgres
+  api:
+    image: api
+    depends_on:
+      - db
+  worker:
+    image: worker
+    depends_on:
+      db:
+        condition: service_healthy
+  web:
+    image: nginx
+    depends_on:
+      - api
+`)
+
+	dependencies, err := RestartDependenciesFromCompose(content)
+	assert.NoError(t, err)
+	assert.Equal(t, []portainer.StackRestartDependency{
+		{Service: "api", Dependents: []string{"web"}},
+		{Service: "db", Dependents: []string{"api", "worker"}},
+	}, dependencies)
+
+	assert.NoError(t, CheckRestartDependencies(content, dependencies))
+	assert.Error(t, CheckRestartDependencies(content, []portainer.StackRestartDependency{{Service: "db", Dependents: []string{"cache"}}}))
+}
+
+func Test_RestartCascade(t *testing.T) {
+	dependencies := []portainer.StackRestartDependency{
+		{Service: "db", Dependents: []string{"web", "api"}, Timeout: "30s"},
+		{Service: "api", Dependents: []string{"web"}},
+	}
+
+	assert.Equal(t, []string{"api", "web"}, RestartCascade(dependencies, "db"), "web waits for api, restarted by db")
+	assert.Equal(t, []string{"web"}, RestartCascade(dependencies, "api"))
+	assert.Empty(t, RestartCascade(dependencies, "web"))
+
+	assert.Equal(t, 30*time.Second, RestartStepTimeout(dependencies, "db"))
+	assert.Equal(t, DefaultRestartStepTimeout, RestartStepTimeout(dependencies, "web"))
+}
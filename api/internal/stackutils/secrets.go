@@ -0,0 +1,122 @@
+package stackutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/secret"
+	"gopkg.in/yaml.v2"
+)
+
+// CreateSecretsOverride creates a compose override file that mounts the secrets volumes of each service read-only
+// on the folders of its secrets and labels the containers with the secrets they reference. The volumes are created
+// by the secret service before the stack is deployed, see secret.Service.MaterializeStack. It returns the path of
+// the override file, which must be removed by the caller once the stack is deployed, or an empty string when there
+// is nothing to override.
+func CreateSecretsOverride(composeFilePath, project string, references map[string][]portainer.SecretReference) (string, error) {
+	if len(references) == 0 {
+		return "", nil
+	}
+
+	content, err := ioutil.ReadFile(composeFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	override, err := secretsOverride(content, project, references)
+	if err != nil || override == nil {
+		return "", err
+	}
+
+	return writeOverrideFile("portainer-secrets-*.yml", override)
+}
+
+func secretsOverride(content []byte, project string, references map[string][]portainer.SecretReference) ([]byte, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	services, _ := composeFile["services"].(map[interface{}]interface{})
+
+	overrideServices := map[string]interface{}{}
+	overrideVolumes := map[string]interface{}{}
+	for service, serviceReferences := range references {
+		if _, ok := services[service]; !ok || len(serviceReferences) == 0 {
+			continue
+		}
+
+		volumes := secret.Volumes(project, service, serviceReferences)
+
+		mounts := make([]string, 0, len(volumes))
+		for _, folder := range secret.Folders(serviceReferences) {
+			mounts = append(mounts, volumes[folder]+":"+folder+":ro")
+			overrideVolumes[volumes[folder]] = map[string]interface{}{"external": true}
+		}
+
+		overrideServices[service] = map[string]interface{}{
+			"volumes": mounts,
+			"labels": map[string]string{
+				secret.SecretsLabel: secret.FormatLabel(serviceReferences),
+			},
+		}
+	}
+
+	if len(overrideServices) == 0 {
+		return nil, nil
+	}
+
+	override := map[string]interface{}{
+		"services": overrideServices,
+		"volumes":  overrideVolumes,
+	}
+	if version, ok := composeFile["version"]; ok {
+		override["version"] = version
+	}
+
+	return yaml.Marshal(override)
+}
+
+// ValidateSecretReferences verifies the secret references of the services of a stack
+func ValidateSecretReferences(references map[string][]portainer.SecretReference) error {
+	for service, serviceReferences := range references {
+		err := secret.ValidateReferences(serviceReferences)
+		if err != nil {
+			return fmt.Errorf("Invalid secrets for service %s: %s", service, err)
+		}
+	}
+	return nil
+}
+
+// ComposeFileUsesLabel returns true when a service of the compose file defines the label,
+// supporting both the list and the map syntaxes
+func ComposeFileUsesLabel(content []byte, label string) (bool, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return false, err
+	}
+
+	services, _ := composeFile["services"].(map[interface{}]interface{})
+	for _, definition := range services {
+		service, _ := definition.(map[interface{}]interface{})
+
+		switch labels := service["labels"].(type) {
+		case map[interface{}]interface{}:
+			if _, ok := labels[label]; ok {
+				return true, nil
+			}
+		case []interface{}:
+			for _, item := range labels {
+				if strings.SplitN(fmt.Sprint(item), "=", 2)[0] == label {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,53 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/secret"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_secretsOverride_shouldMountTheSecretsVolumesReadOnly(t *testing.T) {
+	content := []byte(`version: "3.7"
+services:
+  web:
+    image: nginx
+  db:
+    image: postgres
+`)
+
+	references := map[string][]portainer.SecretReference{
+		"db":      {{Name: "db_password"}, {Name: "api_key", Target: "/etc/app/key"}},
+		"removed": {{Name: "db_password"}},
+	}
+
+	override, err := secretsOverride(content, "app", references)
+	assert.NoError(t, err)
+
+	var composeFile struct {
+		Version  string
+		Services map[string]struct {
+			Volumes []string
+			Labels  map[string]string
+		}
+		Volumes map[string]map[string]bool
+	}
+	err = yaml.Unmarshal(override, &composeFile)
+	assert.NoError(t, err)
+
+	volumes := secret.Volumes("app", "db", references["db"])
+
+	assert.Equal(t, "3.7", composeFile.Version)
+	assert.Len(t, composeFile.Services, 1)
+	assert.Equal(t, []string{
+		volumes["/etc/app"] + ":/etc/app:ro",
+		volumes["/run/secrets"] + ":/run/secrets:ro",
+	}, composeFile.Services["db"].Volumes)
+	assert.Equal(t, "db_password:/run/secrets/db_password,api_key:/etc/app/key", composeFile.Services["db"].Labels[secret.SecretsLabel])
+	assert.Equal(t, map[string]map[string]bool{
+		volumes["/etc/app"]:     {"external": true},
+		volumes["/run/secrets"]: {"external": true},
+	}, composeFile.Volumes)
+}
@@ -10,3 +10,29 @@ import (
 func ResourceControlID(endpointID portainer.EndpointID, name string) string {
 	return fmt.Sprintf("%d_%s", endpointID, name)
 }
+
+// MergeStackEnv returns the stack environment variables with the overrides applied,
+// overrides not defined in the stack environment variables are appended.
+func MergeStackEnv(env, overrides []portainer.Pair) []portainer.Pair {
+	merged := make([]portainer.Pair, 0, len(env)+len(overrides))
+	overridden := make(map[string]bool, len(overrides))
+
+	for _, pair := range env {
+		for _, override := range overrides {
+			if override.Name == pair.Name {
+				pair.Value = override.Value
+				overridden[pair.Name] = true
+			}
+		}
+		merged = append(merged, pair)
+	}
+
+	for _, override := range overrides {
+		if !overridden[override.Name] {
+			merged = append(merged, override)
+			overridden[override.Name] = true
+		}
+	}
+
+	return merged
+}
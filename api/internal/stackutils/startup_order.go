@@ -0,0 +1,136 @@
+package stackutils
+
+import (
+	"fmt"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultStartupGateTimeout is the maximum duration to wait for a startup gate when no timeout is specified
+const DefaultStartupGateTimeout = 5 * time.Minute
+
+// ValidateStartupOrder validates the startup order of a stack. A service can only be part of a single group.
+func ValidateStartupOrder(order []portainer.StackStartupGroup) error {
+	groupOfService := make(map[string]int)
+
+	for idx, group := range order {
+		if len(group.Services) == 0 {
+			return fmt.Errorf("Invalid startup group %d. At least one service must be specified", idx+1)
+		}
+
+		for _, service := range group.Services {
+			if service == "" {
+				return fmt.Errorf("Invalid startup group %d. Service names cannot be empty", idx+1)
+			}
+			if previous, ok := groupOfService[service]; ok {
+				return fmt.Errorf("Invalid startup order. Service %s is part of groups %d and %d", service, previous+1, idx+1)
+			}
+			groupOfService[service] = idx
+		}
+
+		switch group.Gate.Type {
+		case 0, portainer.StackStartupGateHealthy:
+		case portainer.StackStartupGateTCPPort:
+			if group.Gate.Port <= 0 || group.Gate.Port > 65535 {
+				return fmt.Errorf("Invalid startup group %d. TCP port must be between 1 and 65535", idx+1)
+			}
+		default:
+			return fmt.Errorf("Invalid startup group %d. Gate type must be one of: 0 (none), 1 (healthy) or 2 (TCP port)", idx+1)
+		}
+
+		if group.Gate.Timeout != "" {
+			timeout, err := time.ParseDuration(group.Gate.Timeout)
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("Invalid startup group %d. Invalid gate timeout: %s", idx+1, group.Gate.Timeout)
+			}
+		}
+	}
+
+	return nil
+}
+
+// StartupGateTimeout returns the maximum duration to wait for the gate, using the default timeout when none is specified
+func StartupGateTimeout(gate *portainer.StackStartupGate) time.Duration {
+	timeout, err := time.ParseDuration(gate.Timeout)
+	if err != nil || timeout <= 0 {
+		return DefaultStartupGateTimeout
+	}
+	return timeout
+}
+
+// CheckStartupOrderDependencies verifies the startup order against the compose file. The services of the startup order
+// must be defined in the compose file and the depends_on relations are used as a baseline: a service cannot be started
+// in a group preceding the group of one of its dependencies.
+func CheckStartupOrderDependencies(content []byte, order []portainer.StackStartupGroup) error {
+	if len(order) == 0 {
+		return nil
+	}
+
+	dependencies, err := composeServiceDependencies(content)
+	if err != nil {
+		return err
+	}
+
+	groupOfService := make(map[string]int)
+	for idx, group := range order {
+		for _, service := range group.Services {
+			if _, ok := dependencies[service]; !ok {
+				return fmt.Errorf("Invalid startup order. Service %s is not defined in the compose file", service)
+			}
+			groupOfService[service] = idx
+		}
+	}
+
+	for service, group := range groupOfService {
+		for _, dependency := range dependencies[service] {
+			dependencyGroup, ok := groupOfService[dependency]
+			if ok && dependencyGroup > group {
+				return fmt.Errorf("Invalid startup order. Service %s depends on service %s which is started in a later group", service, dependency)
+			}
+		}
+	}
+
+	return nil
+}
+
+// composeServiceDependencies returns the depends_on services of each service of the compose file,
+// supporting both the list and the map syntaxes.
+func composeServiceDependencies(content []byte) (map[string][]string, error) {
+	var composeFile map[string]interface{}
+	err := yaml.Unmarshal(content, &composeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies := make(map[string][]string)
+
+	services, ok := composeFile["services"].(map[interface{}]interface{})
+	if !ok {
+		return dependencies, nil
+	}
+
+	for name, definition := range services {
+		serviceName := fmt.Sprint(name)
+		dependencies[serviceName] = []string{}
+
+		service, ok := definition.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		switch dependsOn := service["depends_on"].(type) {
+		case []interface{}:
+			for _, dependency := range dependsOn {
+				dependencies[serviceName] = append(dependencies[serviceName], fmt.Sprint(dependency))
+			}
+		case map[interface{}]interface{}:
+			for dependency := range dependsOn {
+				dependencies[serviceName] = append(dependencies[serviceName], fmt.Sprint(dependency))
+			}
+		}
+	}
+
+	return dependencies, nil
+}
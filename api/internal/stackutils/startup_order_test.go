@@ -0,0 +1,49 @@
+package stackutils
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ValidateStartupOrder(t *testing.T) {
+	assert.NoError(t, ValidateStartupOrder([]portainer.StackStartupGroup{
+		{Services: []string{"db"}, Gate: portainer.StackStartupGate{Type: portainer.StackStartupGateTCPPort, Port: 5432, Timeout: "1m"}},
+		{Services: []string{"web"}},
+	}))
+	assert.Error(t, ValidateStartupOrder([]portainer.StackStartupGroup{{}}))
+	assert.Error(t, ValidateStartupOrder([]portainer.StackStartupGroup{{Services: []string{"db"}}, {Services: []string{"db"}}}))
+	assert.Error(t, ValidateStartupOrder([]portainer.StackStartupGroup{{Services: []string{"db"}, Gate: portainer.StackStartupGate{Type: portainer.StackStartupGateTCPPort}}}))
+	assert.Error(t, ValidateStartupOrder([]portainer.StackStartupGroup{{Services: []string{"db"}, Gate: portainer.StackStartupGate{Type: 3}}}))
+	assert.Error(t, ValidateStartupOrder([]portainer.StackStartupGroup{{Services: []string{"db"}, Gate: portainer.StackStartupGate{Timeout: "abc"}}}))
+}
+
+func Test_CheckStartupOrderDependencies(t *testing.T) {
+	content := []byte(`version: "2.4"
+services:
+  db:
+    image: postgres
+  api:
+    image: api
+    depends_on:
+      - db
+  web:
+    image: nginx
+    depends_on:
+      api:
+        condition: service_started
+`)
+
+	assert.NoError(t, CheckStartupOrderDependencies(content, []portainer.StackStartupGroup{
+		{Services: []string{"db"}},
+		{Services: []string{"api"}},
+	}))
+	assert.Error(t, CheckStartupOrderDependencies(content, []portainer.StackStartupGroup{
+		{Services: []string{"web"}},
+		{Services: []string{"api"}},
+	}))
+	assert.Error(t, CheckStartupOrderDependencies(content, []portainer.StackStartupGroup{
+		{Services: []string{"cache"}},
+	}))
+}
@@ -0,0 +1,90 @@
+package stackutils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ValidateUpdateConcurrency verifies the number of services updated concurrently during a redeploy, 0 meaning unbounded
+func ValidateUpdateConcurrency(concurrency int) error {
+	if concurrency < 0 {
+		return errors.New("Invalid update concurrency. Value must be 0 (unbounded) or a positive number of services")
+	}
+	return nil
+}
+
+// ComposeServicesInDependencyOrder returns the services of a compose file where each service comes after
+// the services it depends on. Independent services are sorted by name.
+func ComposeServicesInDependencyOrder(content []byte) ([]string, error) {
+	dependencies, err := composeServiceDependencies(content)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	ordered := make([]string, 0, len(names))
+
+	var visit func(service string) error
+	visit = func(service string) error {
+		switch state[service] {
+		case visiting:
+			return fmt.Errorf("Invalid compose file. Circular dependency on service %s", service)
+		case visited:
+			return nil
+		}
+
+		state[service] = visiting
+		serviceDependencies := append([]string{}, dependencies[service]...)
+		sort.Strings(serviceDependencies)
+		for _, dependency := range serviceDependencies {
+			if _, ok := dependencies[dependency]; !ok {
+				continue
+			}
+			err := visit(dependency)
+			if err != nil {
+				return err
+			}
+		}
+		state[service] = visited
+
+		ordered = append(ordered, service)
+		return nil
+	}
+
+	for _, name := range names {
+		err := visit(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// ServiceBatches splits the services in batches of at most size services, a size of 0 returns a single batch
+func ServiceBatches(services []string, size int) [][]string {
+	if size <= 0 || size >= len(services) {
+		return [][]string{services}
+	}
+
+	batches := make([][]string, 0, (len(services)+size-1)/size)
+	for start := 0; start < len(services); start += size {
+		end := start + size
+		if end > len(services) {
+			end = len(services)
+		}
+		batches = append(batches, services[start:end])
+	}
+	return batches
+}
@@ -0,0 +1,39 @@
+package stackutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ComposeServicesInDependencyOrder(t *testing.T) {
+	content := []byte(`
+version: "3"
+services:
+  web:
+    image: nginx
+    depends_on:
+      - api
+  api:
+    image: api
+    depends_on:
+      db:
+        condition: service_healthy
+  db:
+    image: postgres
+  cache:
+    image: redis
+`)
+
+	services, err := ComposeServicesInDependencyOrder(content)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db", "api", "cache", "web"}, services)
+}
+
+func Test_ServiceBatches(t *testing.T) {
+	services := []string{"a", "b", "c", "d", "e"}
+
+	assert.Equal(t, [][]string{services}, ServiceBatches(services, 0))
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, ServiceBatches(services, 2))
+	assert.Equal(t, [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}, ServiceBatches(services, 1))
+}
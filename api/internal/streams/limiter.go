@@ -0,0 +1,71 @@
+package streams
+
+import (
+	"errors"
+	"sync"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+var (
+	// ErrGlobalLimitReached is returned when the maximum number of concurrent streams is reached
+	ErrGlobalLimitReached = errors.New("Maximum number of concurrent streams reached")
+	// ErrEndpointLimitReached is returned when the maximum number of concurrent streams for an endpoint is reached
+	ErrEndpointLimitReached = errors.New("Maximum number of concurrent streams reached for this endpoint")
+)
+
+// Limiter keeps track of the concurrent streaming subscriptions (events, logs, stats), globally and per endpoint.
+type Limiter struct {
+	mu          sync.Mutex
+	total       int
+	perEndpoint map[portainer.EndpointID]int
+}
+
+// NewLimiter creates a new stream limiter
+func NewLimiter() *Limiter {
+	return &Limiter{
+		perEndpoint: make(map[portainer.EndpointID]int),
+	}
+}
+
+// Acquire reserves a stream slot for the endpoint. A limit of 0 means unlimited.
+// The returned function must be called to release the slot, it can safely be called multiple times.
+func (limiter *Limiter) Acquire(endpointID portainer.EndpointID, settings *portainer.StreamSettings) (func(), error) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	if settings.MaxConcurrentStreams > 0 && limiter.total >= settings.MaxConcurrentStreams {
+		return nil, ErrGlobalLimitReached
+	}
+
+	if settings.MaxConcurrentStreamsPerEndpoint > 0 && limiter.perEndpoint[endpointID] >= settings.MaxConcurrentStreamsPerEndpoint {
+		return nil, ErrEndpointLimitReached
+	}
+
+	limiter.total++
+	limiter.perEndpoint[endpointID]++
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			limiter.mu.Lock()
+			defer limiter.mu.Unlock()
+
+			limiter.total--
+			limiter.perEndpoint[endpointID]--
+			if limiter.perEndpoint[endpointID] == 0 {
+				delete(limiter.perEndpoint, endpointID)
+			}
+		})
+	}
+
+	return release, nil
+}
+
+// Count returns the number of active streams globally and for the specified endpoint
+func (limiter *Limiter) Count(endpointID portainer.EndpointID) (int, int) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	return limiter.total, limiter.perEndpoint[endpointID]
+}
@@ -0,0 +1,65 @@
+package streams
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// StreamType represents the kind of streaming subscription proxied to an endpoint
+type StreamType int
+
+const (
+	// NotAStream represents a regular request/response operation
+	NotAStream StreamType = iota
+	// EventStream represents a Docker events subscription
+	EventStream
+	// StatsStream represents a container stats subscription
+	StatsStream
+	// LogStream represents a container, service or task logs subscription
+	LogStream
+)
+
+var (
+	dockerAPIVersionRe = regexp.MustCompile(`^/v[0-9]+\.[0-9]+`)
+	statsPathRe        = regexp.MustCompile(`^/containers/[^/]+/stats$`)
+	logsPathRe         = regexp.MustCompile(`^/(containers|services|tasks)/[^/]+/logs$`)
+)
+
+// DockerStreamType returns the stream type of a request targeting the Docker API.
+// dockerPath must be the path of the request relative to the Docker API root.
+func DockerStreamType(r *http.Request, dockerPath string) StreamType {
+	if r.Method != http.MethodGet {
+		return NotAStream
+	}
+
+	dockerPath = dockerAPIVersionRe.ReplaceAllString(dockerPath, "")
+	query := r.URL.Query()
+
+	switch {
+	case dockerPath == "/events":
+		return EventStream
+	case statsPathRe.MatchString(dockerPath) && isEnabled(query.Get("stream"), true):
+		return StatsStream
+	case logsPathRe.MatchString(dockerPath) && isEnabled(query.Get("follow"), false):
+		return LogStream
+	}
+
+	return NotAStream
+}
+
+// Coalescable returns true when messages of the stream can be dropped under backpressure
+// without corrupting the stream (line delimited JSON messages where only the latest ones matter).
+func (streamType StreamType) Coalescable() bool {
+	return streamType == EventStream || streamType == StatsStream
+}
+
+func isEnabled(value string, defaultValue bool) bool {
+	switch strings.ToLower(value) {
+	case "":
+		return defaultValue
+	case "0", "false":
+		return false
+	}
+	return true
+}
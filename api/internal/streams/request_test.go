@@ -0,0 +1,32 @@
+package streams
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DockerStreamType(t *testing.T) {
+	tests := []struct {
+		method   string
+		url      string
+		expected StreamType
+	}{
+		{"GET", "/v1.41/events", EventStream},
+		{"GET", "/containers/abc/stats", StatsStream},
+		{"GET", "/containers/abc/stats?stream=false", NotAStream},
+		{"GET", "/containers/abc/logs?follow=1", LogStream},
+		{"GET", "/services/abc/logs?follow=true", LogStream},
+		{"GET", "/containers/abc/logs", NotAStream},
+		{"GET", "/containers/json", NotAStream},
+		{"POST", "/containers/abc/stats", NotAStream},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/1/docker"+test.url, nil)
+		path := strings.TrimPrefix(r.URL.Path, "/1/docker")
+		assert.Equal(t, test.expected, DockerStreamType(r, path), test.url)
+	}
+}
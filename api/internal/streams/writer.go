@@ -0,0 +1,240 @@
+package streams
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	// DefaultBufferSize is the size of the buffer of a stream when not specified in the settings
+	DefaultBufferSize = 256 * 1024
+	// DefaultSlowClientThreshold is the lag threshold of a stream when not specified in the settings
+	DefaultSlowClientThreshold = 30 * time.Second
+)
+
+// ErrSlowClient is returned when a client does not consume a stream for longer than the lag threshold
+var ErrSlowClient = errors.New("Stream client is too slow, disconnecting")
+
+// Writer is an http.ResponseWriter decorator used for streaming responses.
+// Data written to the stream is queued in a bounded buffer and sent to the client
+// by a background routine, so that a slow client never blocks the upstream connection.
+// When the buffer is full, the oldest queued messages are dropped for coalescable streams,
+// otherwise the writer waits for the client. The messages of coalescable streams are lines, a partial line
+// is kept until the rest of the line is written or the stream is closed so that only complete lines are dropped. A client that is not able to receive any data
+// for longer than the lag threshold is considered stalled and the stream is closed.
+type Writer struct {
+	http.ResponseWriter
+	mu           sync.Mutex
+	cond         *sync.Cond
+	chunks       [][]byte
+	partial      []byte
+	buffered     int
+	bufferSize   int
+	lagThreshold time.Duration
+	coalesce     bool
+	writing      bool
+	writeStarted time.Time
+	closed       bool
+	err          error
+	dropped      int
+	done         chan struct{}
+}
+
+// NewWriter creates a new stream writer and starts sending queued data to the client
+func NewWriter(w http.ResponseWriter, bufferSize int, lagThreshold time.Duration, coalesce bool) *Writer {
+	writer := &Writer{
+		ResponseWriter: w,
+		bufferSize:     bufferSize,
+		lagThreshold:   lagThreshold,
+		coalesce:       coalesce,
+		done:           make(chan struct{}),
+	}
+	writer.cond = sync.NewCond(&writer.mu)
+
+	go writer.drain()
+
+	return writer
+}
+
+// NewWriterFromSettings creates a new stream writer using the buffer size and lag threshold
+// defined in the stream settings, falling back to the default values
+func NewWriterFromSettings(w http.ResponseWriter, settings *portainer.StreamSettings, coalesce bool) *Writer {
+	bufferSize := DefaultBufferSize
+	if settings.BufferSize > 0 {
+		bufferSize = settings.BufferSize
+	}
+
+	lagThreshold := DefaultSlowClientThreshold
+	if settings.SlowClientThreshold != "" {
+		threshold, err := time.ParseDuration(settings.SlowClientThreshold)
+		if err == nil && threshold > 0 {
+			lagThreshold = threshold
+		}
+	}
+
+	return NewWriter(w, bufferSize, lagThreshold, coalesce)
+}
+
+// Write queues data to be sent to the client
+func (writer *Writer) Write(p []byte) (int, error) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	if writer.err != nil {
+		return 0, writer.err
+	}
+
+	var data []byte
+	if writer.coalesce {
+		data = writer.completeLines(p)
+		if data == nil {
+			return len(p), nil
+		}
+	} else {
+		data = make([]byte, len(p))
+		copy(data, p)
+	}
+
+	err := writer.enqueue(data)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// completeLines appends the data to the partial line and returns the complete lines, the rest is kept as the new
+// partial line. The partial line is returned when it exceeds the buffer size, so that a stream without line breaks
+// is not kept in memory.
+func (writer *Writer) completeLines(p []byte) []byte {
+	writer.partial = append(writer.partial, p...)
+
+	end := bytes.LastIndexByte(writer.partial, '\n') + 1
+	if end == 0 {
+		if len(writer.partial) < writer.bufferSize {
+			return nil
+		}
+		end = len(writer.partial)
+	}
+
+	data := writer.partial[:end]
+	writer.partial = append([]byte{}, writer.partial[end:]...)
+	return data
+}
+
+// enqueue queues data to be sent to the client, waiting for buffer space. It must be called with the lock held.
+func (writer *Writer) enqueue(data []byte) error {
+	deadline := time.Now().Add(writer.lagThreshold)
+	timer := time.AfterFunc(writer.lagThreshold, func() {
+		writer.mu.Lock()
+		writer.cond.Broadcast()
+		writer.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	for {
+		if writer.err != nil {
+			return writer.err
+		}
+
+		if writer.writing && time.Since(writer.writeStarted) > writer.lagThreshold {
+			writer.err = ErrSlowClient
+			writer.cond.Broadcast()
+			return writer.err
+		}
+
+		if writer.buffered == 0 || writer.buffered+len(data) <= writer.bufferSize {
+			break
+		}
+
+		if writer.coalesce && len(writer.chunks) > 0 {
+			writer.buffered -= len(writer.chunks[0])
+			writer.chunks = writer.chunks[1:]
+			writer.dropped++
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			writer.err = ErrSlowClient
+			writer.cond.Broadcast()
+			return writer.err
+		}
+
+		writer.cond.Wait()
+	}
+
+	writer.chunks = append(writer.chunks, data)
+	writer.buffered += len(data)
+	writer.cond.Broadcast()
+
+	return nil
+}
+
+// Flush is a no-op, queued data is flushed to the client as soon as it is sent
+func (writer *Writer) Flush() {}
+
+// Close waits for the queued data, including the partial line of coalescable streams, to be sent to the client.
+// It must be called before the HTTP handler returns as the underlying response writer cannot be used afterwards.
+func (writer *Writer) Close() {
+	writer.mu.Lock()
+	if len(writer.partial) > 0 && writer.err == nil {
+		writer.enqueue(writer.partial)
+		writer.partial = nil
+	}
+	writer.closed = true
+	writer.cond.Broadcast()
+	writer.mu.Unlock()
+
+	<-writer.done
+}
+
+// Dropped returns the number of messages dropped under backpressure
+func (writer *Writer) Dropped() int {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	return writer.dropped
+}
+
+func (writer *Writer) drain() {
+	defer close(writer.done)
+
+	flusher, _ := writer.ResponseWriter.(http.Flusher)
+
+	for {
+		writer.mu.Lock()
+		for len(writer.chunks) == 0 && !writer.closed && writer.err == nil {
+			writer.cond.Wait()
+		}
+
+		if writer.err != nil || len(writer.chunks) == 0 {
+			writer.mu.Unlock()
+			return
+		}
+
+		chunk := writer.chunks[0]
+		writer.chunks = writer.chunks[1:]
+		writer.writing = true
+		writer.writeStarted = time.Now()
+		writer.mu.Unlock()
+
+		_, err := writer.ResponseWriter.Write(chunk)
+		if err == nil && flusher != nil {
+			flusher.Flush()
+		}
+
+		writer.mu.Lock()
+		writer.writing = false
+		writer.buffered -= len(chunk)
+		if err != nil && writer.err == nil {
+			writer.err = err
+		}
+		writer.cond.Broadcast()
+		writer.mu.Unlock()
+	}
+}
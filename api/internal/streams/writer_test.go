@@ -0,0 +1,57 @@
+package streams
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingResponseWriter blocks the writes until it is released, to fill the buffer of a stream
+type blockingResponseWriter struct {
+	*httptest.ResponseRecorder
+	mu      sync.Mutex
+	release chan struct{}
+}
+
+func (w *blockingResponseWriter) Write(p []byte) (int, error) {
+	<-w.release
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseRecorder.Write(p)
+}
+
+func Test_Writer_shouldKeepThePartialLinesOfCoalescableStreams(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := NewWriter(recorder, 1024, time.Second, true)
+
+	writer.Write([]byte(`{"status":`))
+	writer.Write([]byte("\"pulling\"}\n{\"status\":"))
+	writer.Write([]byte(`"done"}`))
+	writer.Close()
+
+	assert.Equal(t, "{\"status\":\"pulling\"}\n{\"status\":\"done\"}", recorder.Body.String(), "the partial line is sent when the stream is closed")
+}
+
+func Test_Writer_shouldOnlyDropCompleteLines(t *testing.T) {
+	responseWriter := &blockingResponseWriter{ResponseRecorder: httptest.NewRecorder(), release: make(chan struct{})}
+	writer := NewWriter(responseWriter, 32, time.Second, true)
+
+	for idx := 0; idx < 20; idx++ {
+		writer.Write([]byte("line-"))
+		writer.Write([]byte("message\n"))
+	}
+	close(responseWriter.release)
+	writer.Close()
+
+	assert.NotZero(t, writer.Dropped())
+
+	output := strings.TrimSuffix(responseWriter.Body.String(), "\n")
+	for _, line := range strings.Split(output, "\n") {
+		assert.Equal(t, "line-message", line)
+	}
+}
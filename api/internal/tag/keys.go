@@ -0,0 +1,50 @@
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+// KeyValueName returns the name of a key/value tag
+func KeyValueName(key, value string) string {
+	return key + "=" + value
+}
+
+// ValidateRequiredKeys verifies the list of tag keys required on the endpoints
+func ValidateRequiredKeys(keys []string) error {
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			return errors.New("Invalid required endpoint tag key. Key cannot be empty")
+		}
+		if seen[key] {
+			return fmt.Errorf("Invalid required endpoint tag key %s. Keys must be unique", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// MissingKeys returns the required keys that are not the key of any of the tags, sorted by key
+func MissingKeys(requiredKeys []string, tags []portainer.Tag) []string {
+	keys := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag.Key != "" {
+			keys[tag.Key] = true
+		}
+	}
+
+	missing := make([]string, 0)
+	for _, key := range requiredKeys {
+		if !keys[key] {
+			missing = append(missing, key)
+		}
+	}
+
+	sort.Strings(missing)
+	return missing
+}
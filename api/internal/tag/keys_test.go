@@ -0,0 +1,19 @@
+package tag
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MissingKeys(t *testing.T) {
+	tags := []portainer.Tag{
+		{Name: "org/acme"},
+		{Name: "environment=production", Key: "environment", Value: "production"},
+	}
+
+	assert.Equal(t, []string{}, MissingKeys(nil, tags))
+	assert.Equal(t, []string{"owner"}, MissingKeys([]string{"owner", "environment"}, tags))
+	assert.Equal(t, []string{"environment", "owner"}, MissingKeys([]string{"owner", "environment"}, nil))
+}
@@ -0,0 +1,430 @@
+package testhelpers
+
+import (
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/bolt/errors"
+)
+
+// Datastore is an in-memory data store used by the tests. Only the services configured with the options are available,
+// calling another service panics.
+type Datastore struct {
+	portainer.DataStore
+	dockerHub       portainer.DockerHubService
+	endpoint        portainer.EndpointService
+	endpointGroup   portainer.EndpointGroupService
+	registry        portainer.RegistryService
+	resourceControl portainer.ResourceControlService
+	secret          portainer.SecretService
+	settings        portainer.SettingsService
+	stack           portainer.StackService
+	stackVersion    portainer.StackVersionService
+	team            portainer.TeamService
+	teamMembership  portainer.TeamMembershipService
+	user            portainer.UserService
+	webhook         portainer.WebhookService
+	// AppliedChanges records the changes applied with ApplyChanges, per call
+	AppliedChanges [][]portainer.DataStoreChange
+}
+
+// DatastoreOption configures a service of a test data store
+type DatastoreOption func(store *Datastore)
+
+// NewDatastore creates a test data store with the services configured by the options
+func NewDatastore(options ...DatastoreOption) *Datastore {
+	store := &Datastore{}
+	for _, option := range options {
+		option(store)
+	}
+	return store
+}
+
+func (store *Datastore) DockerHub() portainer.DockerHubService         { return store.dockerHub }
+func (store *Datastore) Endpoint() portainer.EndpointService           { return store.endpoint }
+func (store *Datastore) EndpointGroup() portainer.EndpointGroupService { return store.endpointGroup }
+func (store *Datastore) Registry() portainer.RegistryService           { return store.registry }
+func (store *Datastore) ResourceControl() portainer.ResourceControlService {
+	return store.resourceControl
+}
+func (store *Datastore) Secret() portainer.SecretService                 { return store.secret }
+func (store *Datastore) Settings() portainer.SettingsService             { return store.settings }
+func (store *Datastore) Stack() portainer.StackService                   { return store.stack }
+func (store *Datastore) StackVersion() portainer.StackVersionService     { return store.stackVersion }
+func (store *Datastore) Team() portainer.TeamService                     { return store.team }
+func (store *Datastore) TeamMembership() portainer.TeamMembershipService { return store.teamMembership }
+func (store *Datastore) User() portainer.UserService                     { return store.user }
+func (store *Datastore) Webhook() portainer.WebhookService               { return store.webhook }
+
+// ApplyChanges records the changes in AppliedChanges
+func (store *Datastore) ApplyChanges(changes []portainer.DataStoreChange) error {
+	store.AppliedChanges = append(store.AppliedChanges, changes)
+	return nil
+}
+
+type stubDockerHubService struct {
+	portainer.DockerHubService
+	dockerHub        *portainer.DockerHub
+	rateLimitSamples []portainer.DockerHubRateLimitSample
+}
+
+// WithDockerHub configures the DockerHub service with the DockerHub details
+func WithDockerHub(dockerHub *portainer.DockerHub) DatastoreOption {
+	return func(store *Datastore) {
+		stubDockerHub(store).dockerHub = dockerHub
+	}
+}
+
+// WithDockerHubRateLimitSamples configures the DockerHub service with the recorded samples of the pull rate limit
+func WithDockerHubRateLimitSamples(samples []portainer.DockerHubRateLimitSample) DatastoreOption {
+	return func(store *Datastore) {
+		stubDockerHub(store).rateLimitSamples = samples
+	}
+}
+
+func stubDockerHub(store *Datastore) *stubDockerHubService {
+	service, ok := store.dockerHub.(*stubDockerHubService)
+	if !ok {
+		service = &stubDockerHubService{}
+		store.dockerHub = service
+	}
+	return service
+}
+
+func (service *stubDockerHubService) DockerHub() (*portainer.DockerHub, error) {
+	return service.dockerHub, nil
+}
+
+func (service *stubDockerHubService) RateLimitSamples() ([]portainer.DockerHubRateLimitSample, error) {
+	if service.rateLimitSamples == nil {
+		return nil, errors.ErrObjectNotFound
+	}
+	return service.rateLimitSamples, nil
+}
+
+func (service *stubDockerHubService) UpdateRateLimitSamples(samples []portainer.DockerHubRateLimitSample) error {
+	service.rateLimitSamples = append([]portainer.DockerHubRateLimitSample{}, samples...)
+	return nil
+}
+
+type stubEndpointService struct {
+	portainer.EndpointService
+	endpoints []portainer.Endpoint
+}
+
+// WithEndpoints configures the endpoint service with the endpoints
+func WithEndpoints(endpoints []portainer.Endpoint) DatastoreOption {
+	return func(store *Datastore) {
+		store.endpoint = &stubEndpointService{endpoints: endpoints}
+	}
+}
+
+func (service *stubEndpointService) Endpoint(ID portainer.EndpointID) (*portainer.Endpoint, error) {
+	for idx := range service.endpoints {
+		if service.endpoints[idx].ID == ID {
+			endpoint := service.endpoints[idx]
+			return &endpoint, nil
+		}
+	}
+	return nil, errors.ErrObjectNotFound
+}
+
+func (service *stubEndpointService) Endpoints() ([]portainer.Endpoint, error) {
+	return service.endpoints, nil
+}
+
+func (service *stubEndpointService) UpdateEndpoint(ID portainer.EndpointID, endpoint *portainer.Endpoint) error {
+	for idx := range service.endpoints {
+		if service.endpoints[idx].ID == ID {
+			service.endpoints[idx] = *endpoint
+			return nil
+		}
+	}
+	return errors.ErrObjectNotFound
+}
+
+type stubEndpointGroupService struct {
+	portainer.EndpointGroupService
+	endpointGroups []portainer.EndpointGroup
+}
+
+// WithEndpointGroups configures the endpoint group service with the endpoint groups
+func WithEndpointGroups(endpointGroups []portainer.EndpointGroup) DatastoreOption {
+	return func(store *Datastore) {
+		store.endpointGroup = &stubEndpointGroupService{endpointGroups: endpointGroups}
+	}
+}
+
+func (service *stubEndpointGroupService) EndpointGroup(ID portainer.EndpointGroupID) (*portainer.EndpointGroup, error) {
+	for idx := range service.endpointGroups {
+		if service.endpointGroups[idx].ID == ID {
+			endpointGroup := service.endpointGroups[idx]
+			return &endpointGroup, nil
+		}
+	}
+	return nil, errors.ErrObjectNotFound
+}
+
+func (service *stubEndpointGroupService) EndpointGroups() ([]portainer.EndpointGroup, error) {
+	return service.endpointGroups, nil
+}
+
+type stubRegistryService struct {
+	portainer.RegistryService
+	registries []portainer.Registry
+}
+
+// WithRegistries configures the registry service with the registries
+func WithRegistries(registries []portainer.Registry) DatastoreOption {
+	return func(store *Datastore) {
+		store.registry = &stubRegistryService{registries: registries}
+	}
+}
+
+func (service *stubRegistryService) Registries() ([]portainer.Registry, error) {
+	return service.registries, nil
+}
+
+func (service *stubRegistryService) Registry(ID portainer.RegistryID) (*portainer.Registry, error) {
+	for idx := range service.registries {
+		if service.registries[idx].ID == ID {
+			registry := service.registries[idx]
+			return &registry, nil
+		}
+	}
+	return nil, errors.ErrObjectNotFound
+}
+
+type stubResourceControlService struct {
+	portainer.ResourceControlService
+	resourceControls []portainer.ResourceControl
+}
+
+// WithResourceControls configures the resource control service with the resource controls
+func WithResourceControls(resourceControls []portainer.ResourceControl) DatastoreOption {
+	return func(store *Datastore) {
+		store.resourceControl = &stubResourceControlService{resourceControls: resourceControls}
+	}
+}
+
+func (service *stubResourceControlService) ResourceControlByResourceIDAndType(resourceID string, resourceType portainer.ResourceControlType) (*portainer.ResourceControl, error) {
+	for idx := range service.resourceControls {
+		if service.resourceControls[idx].ResourceID == resourceID && service.resourceControls[idx].Type == resourceType {
+			resourceControl := service.resourceControls[idx]
+			return &resourceControl, nil
+		}
+	}
+	return nil, nil
+}
+
+type stubSecretService struct {
+	portainer.SecretService
+	secrets []portainer.Secret
+}
+
+// WithSecrets configures the secret service with the secrets
+func WithSecrets(secrets []portainer.Secret) DatastoreOption {
+	return func(store *Datastore) {
+		store.secret = &stubSecretService{secrets: secrets}
+	}
+}
+
+func (service *stubSecretService) Secrets() ([]portainer.Secret, error) {
+	return service.secrets, nil
+}
+
+type stubSettingsService struct {
+	settings *portainer.Settings
+}
+
+// WithSettings configures the settings service with the settings
+func WithSettings(settings *portainer.Settings) DatastoreOption {
+	return func(store *Datastore) {
+		store.settings = &stubSettingsService{settings: settings}
+	}
+}
+
+func (service *stubSettingsService) Settings() (*portainer.Settings, error) {
+	return service.settings, nil
+}
+
+func (service *stubSettingsService) UpdateSettings(settings *portainer.Settings) error {
+	service.settings = settings
+	return nil
+}
+
+type stubStackService struct {
+	portainer.StackService
+	stacks []portainer.Stack
+}
+
+// WithStacks configures the stack service with the stacks
+func WithStacks(stacks []portainer.Stack) DatastoreOption {
+	return func(store *Datastore) {
+		store.stack = &stubStackService{stacks: stacks}
+	}
+}
+
+func (service *stubStackService) Stack(ID portainer.StackID) (*portainer.Stack, error) {
+	for idx := range service.stacks {
+		if service.stacks[idx].ID == ID {
+			stack := service.stacks[idx]
+			return &stack, nil
+		}
+	}
+	return nil, errors.ErrObjectNotFound
+}
+
+func (service *stubStackService) StackByName(name string) (*portainer.Stack, error) {
+	for idx := range service.stacks {
+		if service.stacks[idx].Name == name {
+			stack := service.stacks[idx]
+			return &stack, nil
+		}
+	}
+	return nil, errors.ErrObjectNotFound
+}
+
+func (service *stubStackService) Stacks() ([]portainer.Stack, error) {
+	return service.stacks, nil
+}
+
+func (service *stubStackService) UpdateStack(ID portainer.StackID, stack *portainer.Stack) error {
+	for idx := range service.stacks {
+		if service.stacks[idx].ID == ID {
+			service.stacks[idx] = *stack
+			return nil
+		}
+	}
+	return errors.ErrObjectNotFound
+}
+
+type stubStackVersionService struct {
+	portainer.StackVersionService
+	versions []portainer.StackVersion
+}
+
+// WithStackVersions configures the stack version service with the stack versions
+func WithStackVersions(versions []portainer.StackVersion) DatastoreOption {
+	return func(store *Datastore) {
+		store.stackVersion = &stubStackVersionService{versions: versions}
+	}
+}
+
+func (service *stubStackVersionService) StackVersions(stackID portainer.StackID) ([]portainer.StackVersion, error) {
+	versions := make([]portainer.StackVersion, 0)
+	for _, version := range service.versions {
+		if version.StackID == stackID {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+func (service *stubStackVersionService) StackVersion(stackID portainer.StackID, version int) (*portainer.StackVersion, error) {
+	for idx := range service.versions {
+		if service.versions[idx].StackID == stackID && service.versions[idx].Version == version {
+			stackVersion := service.versions[idx]
+			return &stackVersion, nil
+		}
+	}
+	return nil, errors.ErrObjectNotFound
+}
+
+func (service *stubStackVersionService) CreateStackVersion(stackVersion *portainer.StackVersion) error {
+	stackVersion.Version = len(service.versions) + 1
+	service.versions = append(service.versions, *stackVersion)
+	return nil
+}
+
+type stubTeamService struct {
+	portainer.TeamService
+	teams []portainer.Team
+}
+
+// WithTeams configures the team service with the teams
+func WithTeams(teams []portainer.Team) DatastoreOption {
+	return func(store *Datastore) {
+		store.team = &stubTeamService{teams: teams}
+	}
+}
+
+func (service *stubTeamService) Teams() ([]portainer.Team, error) {
+	return service.teams, nil
+}
+
+type stubTeamMembershipService struct {
+	portainer.TeamMembershipService
+	memberships []portainer.TeamMembership
+}
+
+// WithTeamMemberships configures the team membership service with the team memberships
+func WithTeamMemberships(memberships []portainer.TeamMembership) DatastoreOption {
+	return func(store *Datastore) {
+		store.teamMembership = &stubTeamMembershipService{memberships: memberships}
+	}
+}
+
+func (service *stubTeamMembershipService) TeamMemberships() ([]portainer.TeamMembership, error) {
+	return service.memberships, nil
+}
+
+func (service *stubTeamMembershipService) TeamMembershipsByUserID(userID portainer.UserID) ([]portainer.TeamMembership, error) {
+	memberships := make([]portainer.TeamMembership, 0)
+	for _, membership := range service.memberships {
+		if membership.UserID == userID {
+			memberships = append(memberships, membership)
+		}
+	}
+	return memberships, nil
+}
+
+type stubUserService struct {
+	portainer.UserService
+	users []portainer.User
+}
+
+// WithUsers configures the user service with the users
+func WithUsers(users []portainer.User) DatastoreOption {
+	return func(store *Datastore) {
+		store.user = &stubUserService{users: users}
+	}
+}
+
+func (service *stubUserService) User(ID portainer.UserID) (*portainer.User, error) {
+	for idx := range service.users {
+		if service.users[idx].ID == ID {
+			user := service.users[idx]
+			return &user, nil
+		}
+	}
+	return nil, errors.ErrObjectNotFound
+}
+
+func (service *stubUserService) Users() ([]portainer.User, error) {
+	return service.users, nil
+}
+
+func (service *stubUserService) UserByUsername(username string) (*portainer.User, error) {
+	for idx := range service.users {
+		if service.users[idx].Username == username {
+			user := service.users[idx]
+			return &user, nil
+		}
+	}
+	return nil, errors.ErrObjectNotFound
+}
+
+type stubWebhookService struct {
+	portainer.WebhookService
+	webhooks []portainer.Webhook
+}
+
+// WithWebhooks configures the webhook service with the webhooks
+func WithWebhooks(webhooks []portainer.Webhook) DatastoreOption {
+	return func(store *Datastore) {
+		store.webhook = &stubWebhookService{webhooks: webhooks}
+	}
+}
+
+func (service *stubWebhookService) Webhooks() ([]portainer.Webhook, error) {
+	return service.webhooks, nil
+}
@@ -0,0 +1,256 @@
+package tlsexpiry
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/mailer"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+const (
+	// CheckJobID is the identifier of the TLS certificate expiry check job in the scheduler
+	CheckJobID = "tls_cert_expiry"
+	// DefaultWarningDays is the number of days before the expiry of a certificate from which it is reported as expiring
+	// when no value is defined in the settings
+	DefaultWarningDays = 30
+
+	checkInterval = time.Hour
+)
+
+var errNoCertificate = errors.New("No certificate found in the PEM data")
+
+type (
+	// Service tracks the expiry date of the TLS client certificates of the endpoints and notifies
+	// when a certificate is about to expire
+	Service struct {
+		dataStore portainer.DataStore
+		mailer    *mailer.Service
+		scheduler *scheduler.Scheduler
+		mu        sync.Mutex
+		// expiry date of the certificates already notified, per endpoint
+		notified map[portainer.EndpointID]int64
+	}
+
+	// Summary represents the expiry of the TLS client certificates of the endpoints
+	Summary struct {
+		// Number of days before the expiry of a certificate from which it is reported as expiring
+		WarningDays int `json:"WarningDays" example:"30"`
+		// Number of expired certificates
+		Expired int `json:"Expired" example:"0"`
+		// Number of certificates expiring within the warning days
+		Expiring int `json:"Expiring" example:"1"`
+		// Certificates of the endpoints, the certificates expiring first first
+		Certificates []CertificateExpiry `json:"Certificates"`
+	}
+
+	// CertificateExpiry represents the expiry of the TLS client certificate of an endpoint
+	CertificateExpiry struct {
+		EndpointID   portainer.EndpointID `json:"EndpointId" example:"1"`
+		EndpointName string               `json:"EndpointName" example:"my-endpoint"`
+		// Expiry date of the certificate in unix time
+		NotAfter int64 `json:"NotAfter" example:"1640995200"`
+		// Number of full days before the expiry, negative when the certificate is expired
+		DaysRemaining int  `json:"DaysRemaining" example:"12"`
+		Expired       bool `json:"Expired" example:"false"`
+		Expiring      bool `json:"Expiring" example:"true"`
+	}
+)
+
+// NewService creates a new instance of a service
+func NewService(dataStore portainer.DataStore, mailer *mailer.Service, scheduler *scheduler.Scheduler) *Service {
+	return &Service{
+		dataStore: dataStore,
+		mailer:    mailer,
+		scheduler: scheduler,
+		notified:  make(map[portainer.EndpointID]int64),
+	}
+}
+
+// Start registers the expiry check of the certificates in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          CheckJobID,
+		Description: "Track the expiry of the endpoint TLS client certificates and notify when a certificate is about to expire",
+		Interval:    checkInterval,
+		RunOnStart:  true,
+		Run:         service.check,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,tlsexpiry] [message: unable to schedule the TLS certificate expiry check] [error: %s]", err)
+	}
+}
+
+// CertificateExpiryDate returns the expiry date of the certificate stored in the PEM file in unix time
+func CertificateExpiryDate(certPath string) (int64, error) {
+	data, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return 0, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return 0, errNoCertificate
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return 0, err
+	}
+
+	return certificate.NotAfter.Unix(), nil
+}
+
+// UpdateEndpoint sets the expiry date of the TLS client certificate of the endpoint from its certificate file,
+// the expiry date is reset when the endpoint does not use a client certificate
+func UpdateEndpoint(endpoint *portainer.Endpoint) error {
+	endpoint.TLSCertExpiry = 0
+	if !endpoint.TLSConfig.TLS || endpoint.TLSConfig.TLSCertPath == "" {
+		return nil
+	}
+
+	expiry, err := CertificateExpiryDate(endpoint.TLSConfig.TLSCertPath)
+	if err != nil {
+		return err
+	}
+
+	endpoint.TLSCertExpiry = expiry
+	return nil
+}
+
+// Summarize returns the expiry of the TLS client certificates of the endpoints, the certificates expiring first first
+func Summarize(endpoints []portainer.Endpoint, settings *portainer.TLSCertExpirySettings, now time.Time) *Summary {
+	summary := &Summary{
+		WarningDays:  warningDays(settings),
+		Certificates: make([]CertificateExpiry, 0),
+	}
+
+	for _, endpoint := range endpoints {
+		if endpoint.TLSCertExpiry == 0 {
+			continue
+		}
+
+		certificate := certificateExpiry(&endpoint, summary.WarningDays, now)
+		if certificate.Expired {
+			summary.Expired++
+		} else if certificate.Expiring {
+			summary.Expiring++
+		}
+
+		summary.Certificates = append(summary.Certificates, certificate)
+	}
+
+	sort.Slice(summary.Certificates, func(i, j int) bool {
+		return summary.Certificates[i].NotAfter < summary.Certificates[j].NotAfter
+	})
+
+	return summary
+}
+
+func (service *Service) check() error {
+	settings, err := service.dataStore.Settings().Settings()
+	if err != nil {
+		return err
+	}
+
+	endpoints, err := service.dataStore.Endpoint().Endpoints()
+	if err != nil {
+		return err
+	}
+
+	days := warningDays(&settings.TLSCertExpirySettings)
+	now := time.Now()
+
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+
+		previousExpiry := endpoint.TLSCertExpiry
+		err := UpdateEndpoint(endpoint)
+		if err != nil {
+			log.Printf("[WARN] [internal,tlsexpiry] [endpoint: %s] [message: unable to read the TLS client certificate] [error: %s]", endpoint.Name, err)
+			continue
+		}
+
+		if endpoint.TLSCertExpiry != previousExpiry {
+			err = service.dataStore.Endpoint().UpdateEndpoint(endpoint.ID, endpoint)
+			if err != nil {
+				log.Printf("[WARN] [internal,tlsexpiry] [endpoint: %s] [message: unable to persist the TLS certificate expiry date] [error: %s]", endpoint.Name, err)
+			}
+		}
+
+		if endpoint.TLSCertExpiry == 0 {
+			continue
+		}
+
+		certificate := certificateExpiry(endpoint, days, now)
+		if !certificate.Expired && !certificate.Expiring {
+			continue
+		}
+
+		service.mu.Lock()
+		alreadyNotified := service.notified[endpoint.ID] == endpoint.TLSCertExpiry
+		service.notified[endpoint.ID] = endpoint.TLSCertExpiry
+		service.mu.Unlock()
+
+		if !alreadyNotified {
+			service.notify(&certificate, settings.TLSCertExpirySettings.NotificationRecipients)
+		}
+	}
+
+	return nil
+}
+
+func (service *Service) notify(certificate *CertificateExpiry, recipients []string) {
+	expiry := time.Unix(certificate.NotAfter, 0).UTC().Format(time.RFC1123)
+
+	log.Printf("[WARN] [internal,tlsexpiry] [endpoint: %s] [message: TLS client certificate is about to expire] [not_after: %s]", certificate.EndpointName, expiry)
+
+	if len(recipients) == 0 {
+		return
+	}
+
+	subject := fmt.Sprintf("TLS certificate of endpoint %s expires in %d days", certificate.EndpointName, certificate.DaysRemaining)
+	if certificate.Expired {
+		subject = fmt.Sprintf("TLS certificate of endpoint %s is expired", certificate.EndpointName)
+	}
+
+	go func() {
+		err := service.mailer.Send(&mailer.Message{
+			To:      recipients,
+			Subject: subject,
+			Body:    fmt.Sprintf("The TLS client certificate used to connect to endpoint %s expires on %s.\nRotate the certificate to avoid losing the connection to the endpoint.\n", certificate.EndpointName, expiry),
+		})
+		if err != nil {
+			log.Printf("[ERROR] [internal,tlsexpiry] [endpoint: %s] [message: unable to send the TLS certificate expiry notification] [error: %s]", certificate.EndpointName, err)
+		}
+	}()
+}
+
+func certificateExpiry(endpoint *portainer.Endpoint, days int, now time.Time) CertificateExpiry {
+	notAfter := time.Unix(endpoint.TLSCertExpiry, 0)
+	remaining := notAfter.Sub(now)
+
+	return CertificateExpiry{
+		EndpointID:    endpoint.ID,
+		EndpointName:  endpoint.Name,
+		NotAfter:      endpoint.TLSCertExpiry,
+		DaysRemaining: int(remaining.Hours() / 24),
+		Expired:       remaining <= 0,
+		Expiring:      remaining > 0 && remaining <= time.Duration(days)*24*time.Hour,
+	}
+}
+
+func warningDays(settings *portainer.TLSCertExpirySettings) int {
+	if settings.WarningDays > 0 {
+		return settings.WarningDays
+	}
+	return DefaultWarningDays
+}
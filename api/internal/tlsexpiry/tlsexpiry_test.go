@@ -0,0 +1,72 @@
+package tlsexpiry
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UpdateEndpoint(t *testing.T) {
+	notAfter := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "tlsexpiry")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "cert.pem")
+	err = ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0600)
+	assert.NoError(t, err)
+
+	endpoint := &portainer.Endpoint{TLSConfig: portainer.TLSConfiguration{TLS: true, TLSCertPath: certPath}}
+	assert.NoError(t, UpdateEndpoint(endpoint))
+	assert.Equal(t, notAfter.Unix(), endpoint.TLSCertExpiry)
+
+	endpoint.TLSConfig = portainer.TLSConfiguration{}
+	assert.NoError(t, UpdateEndpoint(endpoint))
+	assert.Equal(t, int64(0), endpoint.TLSCertExpiry)
+}
+
+func Test_Summarize(t *testing.T) {
+	now := time.Now()
+	endpoints := []portainer.Endpoint{
+		{ID: 1, Name: "later", TLSCertExpiry: now.Add(90 * 24 * time.Hour).Unix()},
+		{ID: 2, Name: "no-tls"},
+		{ID: 3, Name: "soon", TLSCertExpiry: now.Add(5*24*time.Hour + time.Hour).Unix()},
+		{ID: 4, Name: "expired", TLSCertExpiry: now.Add(-time.Hour).Unix()},
+	}
+
+	summary := Summarize(endpoints, &portainer.TLSCertExpirySettings{}, now)
+	assert.Equal(t, DefaultWarningDays, summary.WarningDays)
+	assert.Equal(t, 1, summary.Expired)
+	assert.Equal(t, 1, summary.Expiring)
+	assert.Len(t, summary.Certificates, 3)
+
+	assert.Equal(t, "expired", summary.Certificates[0].EndpointName)
+	assert.True(t, summary.Certificates[0].Expired)
+	assert.Equal(t, "soon", summary.Certificates[1].EndpointName)
+	assert.True(t, summary.Certificates[1].Expiring)
+	assert.Equal(t, 5, summary.Certificates[1].DaysRemaining)
+	assert.False(t, summary.Certificates[2].Expiring)
+}
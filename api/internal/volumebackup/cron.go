@@ -0,0 +1,186 @@
+package volumebackup
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule represents a parsed cron expression, each field being the set of the values matched
+type cronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+	// whether the day of month and the day of week are both restricted, the day matches any of them in that case
+	restrictedDays bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// maxCronLookahead bounds the search of the next run of a cron expression, e.g. for 0 0 30 2 *
+const maxCronLookahead = 5 * 366 * 24 * time.Hour
+
+// ValidateCronExpression verifies that the expression is a valid cron expression with 5 fields:
+// minute, hour, day of month, month and day of week. Fields support *, lists, ranges and steps.
+func ValidateCronExpression(expression string) error {
+	schedule, err := parseCron(expression)
+	if err != nil {
+		return err
+	}
+
+	if schedule.next(time.Now().UTC()).IsZero() {
+		return errors.New("Invalid cron expression. The expression never matches")
+	}
+	return nil
+}
+
+// NextRun returns the next time matching the cron expression after the specified time, in UTC
+func NextRun(expression string, after time.Time) (time.Time, error) {
+	schedule, err := parseCron(expression)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := schedule.next(after.UTC())
+	if next.IsZero() {
+		return time.Time{}, errors.New("Invalid cron expression. The expression never matches")
+	}
+	return next, nil
+}
+
+func parseCron(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("Invalid cron expression. Expected %d fields, got %d", len(cronFields), len(fields))
+	}
+
+	values := make([]map[int]bool, len(cronFields))
+	for idx, field := range cronFields {
+		set, err := parseCronField(fields[idx], field)
+		if err != nil {
+			return nil, err
+		}
+		values[idx] = set
+	}
+
+	// 7 is an alias of sunday
+	if values[4][7] {
+		values[4][0] = true
+		delete(values[4], 7)
+	}
+
+	return &cronSchedule{
+		minutes:        values[0],
+		hours:          values[1],
+		daysOfMonth:    values[2],
+		months:         values[3],
+		daysOfWeek:     values[4],
+		restrictedDays: !strings.HasPrefix(fields[2], "*") && !strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(value string, field cronField) (map[int]bool, error) {
+	set := make(map[int]bool)
+
+	for _, part := range strings.Split(value, ",") {
+		rangePart := part
+		step := 1
+
+		if idx := strings.Index(part, "/"); idx != -1 {
+			rangePart = part[:idx]
+			parsedStep, err := strconv.Atoi(part[idx+1:])
+			if err != nil || parsedStep <= 0 {
+				return nil, fmt.Errorf("Invalid cron expression. Invalid step in %s field: %s", field.name, part)
+			}
+			step = parsedStep
+		}
+
+		start, end := field.min, field.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid cron expression. Invalid value in %s field: %s", field.name, part)
+			}
+
+			end = start
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("Invalid cron expression. Invalid value in %s field: %s", field.name, part)
+				}
+			} else if step > 1 {
+				end = field.max
+			}
+		}
+
+		if start < field.min || end > field.max || start > end {
+			return nil, fmt.Errorf("Invalid cron expression. Value out of range in %s field: %s", field.name, part)
+		}
+
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// next returns the first minute matching the schedule strictly after t, or the zero time when none is found
+func (schedule *cronSchedule) next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronLookahead)
+
+	for next.Before(limit) {
+		if !schedule.months[int(next.Month())] {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !schedule.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !schedule.hours[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !schedule.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+
+		return next
+	}
+
+	return time.Time{}
+}
+
+func (schedule *cronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := schedule.daysOfMonth[t.Day()]
+	dayOfWeek := schedule.daysOfWeek[int(t.Weekday())]
+
+	if schedule.restrictedDays {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
@@ -0,0 +1,41 @@
+package volumebackup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NextRun(t *testing.T) {
+	now := time.Date(2021, time.March, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2021, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2021, time.March, 16, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 4 * * 0", time.Date(2021, time.March, 21, 4, 30, 0, 0, time.UTC)},
+		{"30 4 * * 7", time.Date(2021, time.March, 21, 4, 30, 0, 0, time.UTC)},
+		{"0 12 1-5 6 *", time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 20 * 1", time.Date(2021, time.March, 20, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		next, err := NextRun(test.expression, now)
+		assert.NoError(t, err, test.expression)
+		assert.Equal(t, test.expected, next, test.expression)
+	}
+}
+
+func Test_ValidateCronExpression(t *testing.T) {
+	assert.NoError(t, ValidateCronExpression("5,35 */2 * 1-6 1-5"))
+
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *", "0 0 30 2 *"} {
+		assert.Error(t, ValidateCronExpression(expression), expression)
+	}
+}
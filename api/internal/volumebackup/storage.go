@@ -0,0 +1,417 @@
+package volumebackup
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	portainer "github.com/portainer/portainer/api"
+)
+
+const (
+	s3Service         = "s3"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3DateFormat      = "20060102T150405Z"
+	s3RequestTimeout  = 30 * time.Minute
+	// s3PartSize is the size of the parts of the multipart uploads, the archives are buffered in memory
+	// one part at a time. With at most 10000 parts per upload, archives up to 156 GiB can be stored.
+	s3PartSize = 16 << 20
+	s3MaxParts = 10000
+)
+
+// storage stores the backup archives of a destination
+type storage interface {
+	// Store stores the archive read until EOF under the location and returns its size. The archive must not
+	// be visible under the location until it is completely stored, so that a failure, including an error
+	// reading the archive, never leaves a truncated backup behind
+	Store(location string, archive io.Reader) (int64, error)
+	Open(location string) (io.ReadCloser, error)
+	Delete(location string) error
+}
+
+// localStorage stores the archives in a folder of the Portainer data folder
+type localStorage struct {
+	path string
+}
+
+func (storage *localStorage) Store(location string, archive io.Reader) (int64, error) {
+	target := filepath.Join(storage.path, filepath.FromSlash(location))
+
+	err := os.MkdirAll(filepath.Dir(target), 0700)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, archive)
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	return size, os.Rename(tmp.Name(), target)
+}
+
+func (storage *localStorage) Open(location string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(storage.path, filepath.FromSlash(location)))
+}
+
+func (storage *localStorage) Delete(location string) error {
+	err := os.Remove(filepath.Join(storage.path, filepath.FromSlash(location)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// s3Storage stores the archives in an S3 compatible bucket with multipart uploads, so that the archives are
+// streamed without being staged on disk. Objects only become visible once their upload is completed,
+// a failed upload is aborted and never replaces or truncates an existing backup.
+type s3Storage struct {
+	settings  *portainer.VolumeBackupS3Settings
+	secretKey string
+	client    *http.Client
+	partSize  int
+	now       func() time.Time
+}
+
+type (
+	s3InitiateMultipartUploadResult struct {
+		UploadID string `xml:"UploadId"`
+	}
+
+	s3CompletedPart struct {
+		PartNumber int
+		ETag       string
+	}
+
+	s3CompleteMultipartUpload struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}
+)
+
+func newS3Storage(settings *portainer.VolumeBackupS3Settings, secretKey string) *s3Storage {
+	return &s3Storage{
+		settings:  settings,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: s3RequestTimeout},
+		partSize:  s3PartSize,
+		now:       time.Now,
+	}
+}
+
+func (storage *s3Storage) Store(location string, archive io.Reader) (int64, error) {
+	uploadID, err := storage.createMultipartUpload(location)
+	if err != nil {
+		return 0, err
+	}
+
+	parts, size, err := storage.uploadParts(location, uploadID, archive)
+	if err == nil {
+		err = storage.completeMultipartUpload(location, uploadID, parts)
+	}
+
+	if err != nil {
+		abortErr := storage.abortMultipartUpload(location, uploadID)
+		if abortErr != nil {
+			log.Printf("[WARN] [internal,volumebackup] [location: %s] [message: unable to abort the upload of the backup] [error: %s]", location, abortErr)
+		}
+		return 0, err
+	}
+
+	return size, nil
+}
+
+func (storage *s3Storage) createMultipartUpload(location string) (string, error) {
+	req, err := storage.newRequest(http.MethodPost, location, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+
+	resp, err := storage.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result s3InitiateMultipartUploadResult
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return "", err
+	}
+	if result.UploadID == "" {
+		return "", fmt.Errorf("S3 request POST %s did not return an upload identifier", req.URL.Path)
+	}
+
+	return result.UploadID, nil
+}
+
+// uploadParts uploads the archive in parts of the part size, only the last part can be smaller
+func (storage *s3Storage) uploadParts(location, uploadID string, archive io.Reader) ([]s3CompletedPart, int64, error) {
+	parts := make([]s3CompletedPart, 0)
+	buffer := make([]byte, storage.partSize)
+	size := int64(0)
+
+	for partNumber := 1; ; partNumber++ {
+		n, err := io.ReadFull(archive, buffer)
+		if err == io.EOF && partNumber > 1 {
+			return parts, size, nil
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, 0, err
+		}
+
+		if partNumber > s3MaxParts {
+			return nil, 0, fmt.Errorf("The archive exceeds the maximum size of an S3 upload of %d parts of %d bytes", s3MaxParts, storage.partSize)
+		}
+
+		etag, uploadErr := storage.uploadPart(location, uploadID, partNumber, buffer[:n])
+		if uploadErr != nil {
+			return nil, 0, uploadErr
+		}
+		parts = append(parts, s3CompletedPart{PartNumber: partNumber, ETag: etag})
+		size += int64(n)
+
+		if err != nil {
+			return parts, size, nil
+		}
+	}
+}
+
+func (storage *s3Storage) uploadPart(location, uploadID string, partNumber int, part []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+	req, err := storage.newRequest(http.MethodPut, location, query, bytes.NewReader(part))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", hashHex(string(part)))
+
+	resp, err := storage.do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	return resp.Header.Get("ETag"), nil
+}
+
+func (storage *s3Storage) completeMultipartUpload(location, uploadID string, parts []s3CompletedPart) error {
+	body, err := xml.Marshal(s3CompleteMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	req, err := storage.newRequest(http.MethodPost, location, url.Values{"uploadId": {uploadID}}, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", hashHex(string(body)))
+
+	resp, err := storage.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// the completion can fail after the response status is sent, the error is then returned in the body
+	result, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(result, []byte("<Error>")) {
+		return fmt.Errorf("S3 request POST %s failed: %s", req.URL.Path, strings.TrimSpace(string(result)))
+	}
+
+	return nil
+}
+
+func (storage *s3Storage) abortMultipartUpload(location, uploadID string) error {
+	req, err := storage.newRequest(http.MethodDelete, location, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := storage.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (storage *s3Storage) Open(location string) (io.ReadCloser, error) {
+	req, err := storage.newRequest(http.MethodGet, location, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := storage.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (storage *s3Storage) Delete(location string) error {
+	req, err := storage.newRequest(http.MethodDelete, location, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := storage.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// newRequest creates a path-style request on an object of the bucket
+func (storage *s3Storage) newRequest(method, location string, query url.Values, body io.Reader) (*http.Request, error) {
+	endpoint := storage.settings.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", storage.settings.Region)
+	}
+
+	objectURL, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	objectURL.Path += "/" + storage.settings.Bucket + "/" + storage.settings.Prefix + location
+	// the query is sent as it is signed
+	objectURL.RawQuery = canonicalQuery(query)
+
+	return http.NewRequest(method, objectURL.String(), body)
+}
+
+func (storage *s3Storage) do(req *http.Request) (*http.Response, error) {
+	signS3Request(req, storage.settings.Region, storage.settings.AccessKeyID, storage.secretKey, storage.now().UTC())
+
+	resp, err := storage.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("S3 request %s %s failed with status %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	return resp, nil
+}
+
+// signS3Request signs a request with AWS Signature Version 4. The payload is only signed when its hash is set
+// in the X-Amz-Content-Sha256 header, the archives downloaded for a restore are not, the transport security
+// is expected to be provided by HTTPS. The signature is verified against the examples of the AWS documentation.
+func signS3Request(req *http.Request, region, accessKeyID, secretAccessKey string, now time.Time) {
+	date := now.Format(s3DateFormat)
+	day := date[:8]
+
+	req.Header.Set("X-Amz-Date", date)
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "x-amz-date" || name == "x-amz-content-sha256" || name == "range" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := day + "/" + region + "/" + s3Service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + date + "\n" + scope + "\n" + hashHex(canonicalRequest)
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s", accessKeyID, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range values[key] {
+			parts = append(parts, s3QueryEscape(key)+"="+s3QueryEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3QueryEscape escapes a query component as required by the signature, where spaces are encoded as %20
+func s3QueryEscape(value string) string {
+	return strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
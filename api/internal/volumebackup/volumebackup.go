@@ -0,0 +1,599 @@
+package volumebackup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	portainer "github.com/portainer/portainer/api"
+	bolterrors "github.com/portainer/portainer/api/bolt/errors"
+	"github.com/portainer/portainer/api/crypto"
+	"github.com/portainer/portainer/api/docker"
+	"github.com/portainer/portainer/api/internal/mailer"
+	"github.com/portainer/portainer/api/internal/scheduler"
+)
+
+const (
+	// RunJobID is the identifier of the job running the volume backup schedules in the scheduler
+	RunJobID = "volume_backups"
+	// HelperImage is the image of the helper containers mounting the volumes to back up or restore
+	HelperImage = "alpine:latest"
+	// HelperLabel is the label set on the helper containers, its value is the schedule identifier
+	HelperLabel = "io.portainer.volumebackup.id"
+	// BackupFolder is the folder of the data folder where the local backups are stored
+	BackupFolder = "volume_backups"
+
+	runInterval = time.Minute
+	// historySize is the number of runs kept in the history of a schedule
+	historySize = 20
+	// volumeMountPath is the path of the volume in the helper containers
+	volumeMountPath = "/volume"
+	// restoreStagingPath is the folder of the restore helper container where the archive is extracted
+	// before replacing the content of the volume
+	restoreStagingPath = "/tmp"
+)
+
+// restoreScript replaces the content of the volume mounted in the restore helper container by the backup extracted
+// in the staging folder. The backup is first copied into a folder of the volume, the volume is left untouched when the
+// copy fails, for instance when the volume is full. The content of the volume is then moved aside and swapped with the
+// copy using renames on the volume filesystem, it is moved back when the swap fails.
+// The script exits with 1 when the copy fails, 2 when the swap fails and 3 when a previous restore was interrupted.
+const restoreScript = `
+set -u
+cd %[1]s || exit 1
+new=.portainer-restore-new
+old=.portainer-restore-old
+entries() { find "$1" -mindepth 1 -maxdepth 1 ! -name "$new" ! -name "$old"; }
+move() {
+	entries "$1" | while IFS= read -r entry; do mv "$entry" "$2"/ || return 1; done
+	[ -z "$(entries "$1")" ]
+}
+if [ -e "$old" ]; then exit 3; fi
+rm -rf "$new"
+if ! mkdir "$new" || ! cp -a %[2]s%[1]s/. "$new"/; then rm -rf "$new"; exit 1; fi
+mkdir "$old" || { rm -rf "$new"; exit 1; }
+if ! move . "$old"; then
+	move "$old" . && rm -rf "$old" "$new"
+	exit 2
+fi
+if ! move "$new" .; then
+	entries . | while IFS= read -r entry; do rm -rf "$entry"; done
+	move "$old" . && rm -rf "$old" "$new"
+	exit 2
+fi
+rm -rf "$new" "$old"
+`
+
+var (
+	// ErrBackupInProgress is returned when a backup or a restore of the schedule volume is already running
+	ErrBackupInProgress = errors.New("A backup or a restore of the volume is already in progress")
+	// ErrBackupNotFound is returned when restoring a backup that is not part of the schedule backups
+	ErrBackupNotFound = errors.New("No backup found with the specified identifier")
+	// ErrVolumeInUse is returned when restoring a volume used by running containers
+	ErrVolumeInUse = errors.New("The volume is used by running containers. Stop the stack or the containers using the volume before restoring it")
+)
+
+// Service runs the volume backup schedules: on each schedule run, a helper container mounting the volume
+// is created and the volume data is archived and streamed to the schedule destination.
+// The destinations only make the archives visible once completely stored, so that a failed backup never
+// replaces a stored backup.
+type Service struct {
+	dataStore     portainer.DataStore
+	clientFactory *docker.ClientFactory
+	mailer        *mailer.Service
+	scheduler     *scheduler.Scheduler
+	encryptionKey []byte
+	backupPath    string
+	mu            sync.Mutex
+	running       map[portainer.VolumeBackupScheduleID]bool
+}
+
+// NewService creates a new instance of a service, the local backups are stored in the data folder
+func NewService(dataStore portainer.DataStore, clientFactory *docker.ClientFactory, mailer *mailer.Service, scheduler *scheduler.Scheduler, encryptionKey []byte, dataPath string) *Service {
+	return &Service{
+		dataStore:     dataStore,
+		clientFactory: clientFactory,
+		mailer:        mailer,
+		scheduler:     scheduler,
+		encryptionKey: encryptionKey,
+		backupPath:    filepath.Join(dataPath, BackupFolder),
+		running:       make(map[portainer.VolumeBackupScheduleID]bool),
+	}
+}
+
+// Start registers the volume backup schedules runner in the scheduler
+func (service *Service) Start() {
+	err := service.scheduler.Register(scheduler.Job{
+		ID:          RunJobID,
+		Description: "Back up the data of the volumes with a backup schedule when their schedule is due",
+		Interval:    runInterval,
+		RunOnStart:  true,
+		Run:         service.runDueSchedules,
+	})
+	if err != nil && err != scheduler.ErrJobAlreadyRegistered {
+		log.Printf("[ERROR] [internal,volumebackup] [message: unable to schedule the volume backups] [error: %s]", err)
+	}
+}
+
+// EncryptSecretKey encrypts an S3 secret access key so that it can be stored in a schedule
+func (service *Service) EncryptSecretKey(secretKey string) (string, error) {
+	return crypto.EncryptAES(secretKey, service.encryptionKey)
+}
+
+// ValidateDestination verifies the settings of a backup destination
+func ValidateDestination(destination *portainer.VolumeBackupDestination) error {
+	switch destination.Type {
+	case portainer.VolumeBackupDestinationLocal:
+		return nil
+	case portainer.VolumeBackupDestinationS3:
+		s3 := destination.S3
+		if s3 == nil || s3.Bucket == "" || s3.Region == "" || s3.AccessKeyID == "" {
+			return errors.New("Invalid S3 destination. Bucket, region and access key identifier are required")
+		}
+		return nil
+	}
+	return errors.New("Invalid destination type. Value must be one of: 1 (local) or 2 (S3)")
+}
+
+// Schedule computes the next run of a schedule from its cron expression
+func Schedule(schedule *portainer.VolumeBackupSchedule, now time.Time) error {
+	next, err := NextRun(schedule.CronExpression, now)
+	if err != nil {
+		return err
+	}
+	schedule.NextRun = next.Unix()
+	return nil
+}
+
+// RunNow starts a backup of the schedule volume in the background
+func (service *Service) RunNow(scheduleID portainer.VolumeBackupScheduleID) error {
+	if !service.acquire(scheduleID) {
+		return ErrBackupInProgress
+	}
+
+	go func() {
+		defer service.release(scheduleID)
+		service.run(scheduleID)
+	}()
+
+	return nil
+}
+
+// Restore replaces the content of the schedule volume by the content of one of its backups.
+// The volume must not be used by running containers. The backup is extracted in a helper container
+// before the content of the volume is replaced, the volume is left untouched when the backup cannot be read
+// or copied, and restored when its content cannot be replaced, see restoreScript.
+func (service *Service) Restore(schedule *portainer.VolumeBackupSchedule, backupID string) error {
+	var backup *portainer.VolumeBackup
+	for idx := range schedule.Backups {
+		if schedule.Backups[idx].ID == backupID {
+			backup = &schedule.Backups[idx]
+		}
+	}
+	if backup == nil {
+		return ErrBackupNotFound
+	}
+
+	if !service.acquire(schedule.ID) {
+		return ErrBackupInProgress
+	}
+	defer service.release(schedule.ID)
+
+	endpoint, err := service.dataStore.Endpoint().Endpoint(schedule.EndpointID)
+	if err != nil {
+		return err
+	}
+
+	dockerClient, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return err
+	}
+	defer dockerClient.Close()
+
+	inUse, err := volumeInUse(dockerClient, schedule.VolumeName)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrVolumeInUse
+	}
+
+	storage, err := service.storage(&schedule.Destination)
+	if err != nil {
+		return err
+	}
+
+	archive, err := storage.Open(backup.Location)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	content, err := gzip.NewReader(archive)
+	if err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf(restoreScript, volumeMountPath, restoreStagingPath)
+	containerID, err := createHelperContainer(dockerClient, schedule, []string{"sh", "-c", script}, false)
+	if err != nil {
+		return err
+	}
+	defer removeHelperContainer(dockerClient, containerID)
+
+	err = dockerClient.CopyToContainer(context.Background(), containerID, restoreStagingPath, content, dockertypes.CopyToContainerOptions{})
+	if err != nil {
+		return fmt.Errorf("Unable to extract the backup: %s", err)
+	}
+
+	err = dockerClient.ContainerStart(context.Background(), containerID, dockertypes.ContainerStartOptions{})
+	if err != nil {
+		return err
+	}
+
+	statusCh, errCh := dockerClient.ContainerWait(context.Background(), containerID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return err
+	case status := <-statusCh:
+		return restoreError(status.StatusCode)
+	}
+}
+
+// restoreError returns the error of a restore from the exit code of the restore script
+func restoreError(exitCode int64) error {
+	switch exitCode {
+	case 0:
+		return nil
+	case 1:
+		return errors.New("Unable to copy the backup into the volume, the content of the volume was not modified")
+	case 2:
+		return errors.New("Unable to replace the content of the volume, its previous content was restored")
+	case 3:
+		return errors.New("A previous restore of the volume was interrupted, the previous content of the volume is in its .portainer-restore-old folder")
+	}
+	return fmt.Errorf("Unable to replace the content of the volume, the restore container exited with code %d", exitCode)
+}
+
+// RemoveBackups removes the stored backups of a schedule, the backups that cannot be removed are returned
+func (service *Service) RemoveBackups(schedule *portainer.VolumeBackupSchedule) ([]portainer.VolumeBackup, error) {
+	storage, err := service.storage(&schedule.Destination)
+	if err != nil {
+		return schedule.Backups, err
+	}
+
+	remaining := make([]portainer.VolumeBackup, 0)
+	for _, backup := range schedule.Backups {
+		err := storage.Delete(backup.Location)
+		if err != nil {
+			log.Printf("[WARN] [internal,volumebackup] [schedule: %d] [message: unable to remove backup %s] [error: %s]", schedule.ID, backup.ID, err)
+			remaining = append(remaining, backup)
+		}
+	}
+
+	return remaining, nil
+}
+
+func (service *Service) runDueSchedules() error {
+	schedules, err := service.dataStore.VolumeBackupSchedule().VolumeBackupSchedules()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, schedule := range schedules {
+		if schedule.NextRun == 0 || schedule.NextRun > now {
+			continue
+		}
+
+		err := service.RunNow(schedule.ID)
+		if err == ErrBackupInProgress {
+			log.Printf("[INFO] [internal,volumebackup] [schedule: %d] [message: previous backup still running, skipping run]", schedule.ID)
+		}
+	}
+
+	return nil
+}
+
+// run backs up the schedule volume and records the result of the run in the schedule
+func (service *Service) run(scheduleID portainer.VolumeBackupScheduleID) {
+	schedule, err := service.dataStore.VolumeBackupSchedule().VolumeBackupSchedule(scheduleID)
+	if err != nil {
+		log.Printf("[ERROR] [internal,volumebackup] [schedule: %d] [message: unable to retrieve the backup schedule] [error: %s]", scheduleID, err)
+		return
+	}
+
+	start := time.Now()
+	run := portainer.VolumeBackupRun{Date: start.Unix()}
+
+	backup, err := service.backup(schedule, start)
+	if err != nil {
+		run.Error = err.Error()
+		service.notifyFailure(schedule, err)
+	} else {
+		run.BackupID = backup.ID
+	}
+
+	err = service.record(scheduleID, backup, run)
+	if err != nil {
+		log.Printf("[ERROR] [internal,volumebackup] [schedule: %d] [message: unable to record the backup run] [error: %s]", scheduleID, err)
+	}
+}
+
+func (service *Service) backup(schedule *portainer.VolumeBackupSchedule, start time.Time) (*portainer.VolumeBackup, error) {
+	storage, err := service.storage(&schedule.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := service.dataStore.Endpoint().Endpoint(schedule.EndpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	dockerClient, err := service.clientFactory.CreateClient(endpoint, "")
+	if err != nil {
+		return nil, err
+	}
+	defer dockerClient.Close()
+
+	// the data of the volume is copied from a created container that is never started
+	containerID, err := createHelperContainer(dockerClient, schedule, []string{"true"}, true)
+	if err != nil {
+		return nil, err
+	}
+	defer removeHelperContainer(dockerClient, containerID)
+
+	backupID := start.UTC().Format(s3DateFormat)
+	backup := &portainer.VolumeBackup{
+		ID:       backupID,
+		Date:     start.Unix(),
+		Location: strconv.Itoa(int(schedule.ID)) + "/" + backupID + ".tar.gz",
+	}
+
+	// the archive is streamed to the destination, an error archiving the volume fails the storage of the archive
+	reader, writer := io.Pipe()
+	archived := make(chan struct{})
+	go func() {
+		defer close(archived)
+		writer.CloseWithError(archiveVolume(dockerClient, containerID, writer))
+	}()
+
+	backup.Size, err = storage.Store(backup.Location, reader)
+	reader.Close()
+	<-archived
+	if err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+// archiveVolume writes a gzip compressed tar archive of the volume mounted in the helper container.
+// The archive is copied entry by entry so that an archive truncated by the endpoint fails the backup.
+func archiveVolume(dockerClient *client.Client, containerID string, archive io.Writer) error {
+	content, _, err := dockerClient.CopyFromContainer(context.Background(), containerID, volumeMountPath)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	writer := gzip.NewWriter(archive)
+
+	err = copyTarArchive(tar.NewWriter(writer), tar.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+func copyTarArchive(writer *tar.Writer, reader *tar.Reader) error {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return writer.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		err = writer.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(writer, reader)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// record adds a run to the history of the schedule, stores the backup created by the run and removes
+// the backups exceeding the retention. The schedule is reloaded as it may have been updated during the run.
+func (service *Service) record(scheduleID portainer.VolumeBackupScheduleID, backup *portainer.VolumeBackup, run portainer.VolumeBackupRun) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	schedule, err := service.dataStore.VolumeBackupSchedule().VolumeBackupSchedule(scheduleID)
+	if err == bolterrors.ErrObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	schedule.History = append([]portainer.VolumeBackupRun{run}, schedule.History...)
+	if len(schedule.History) > historySize {
+		schedule.History = schedule.History[:historySize]
+	}
+
+	if backup != nil {
+		schedule.Backups = append(schedule.Backups, *backup)
+		schedule.Backups = service.prune(schedule)
+	}
+
+	err = Schedule(schedule, time.Now())
+	if err != nil {
+		schedule.NextRun = 0
+	}
+
+	return service.dataStore.VolumeBackupSchedule().UpdateVolumeBackupSchedule(schedule.ID, schedule)
+}
+
+// prune removes the oldest backups exceeding the retention of the schedule and returns the remaining backups.
+// Backups that cannot be removed from the destination are kept in the list.
+func (service *Service) prune(schedule *portainer.VolumeBackupSchedule) []portainer.VolumeBackup {
+	excess := len(schedule.Backups) - schedule.Retention
+	if schedule.Retention <= 0 || excess <= 0 {
+		return schedule.Backups
+	}
+
+	storage, err := service.storage(&schedule.Destination)
+	if err != nil {
+		log.Printf("[WARN] [internal,volumebackup] [schedule: %d] [message: unable to remove the expired backups] [error: %s]", schedule.ID, err)
+		return schedule.Backups
+	}
+
+	remaining := make([]portainer.VolumeBackup, 0, schedule.Retention)
+	for idx, backup := range schedule.Backups {
+		if idx >= excess {
+			remaining = append(remaining, backup)
+			continue
+		}
+
+		err := storage.Delete(backup.Location)
+		if err != nil {
+			log.Printf("[WARN] [internal,volumebackup] [schedule: %d] [message: unable to remove expired backup %s] [error: %s]", schedule.ID, backup.ID, err)
+			remaining = append(remaining, backup)
+		}
+	}
+
+	return remaining
+}
+
+func (service *Service) storage(destination *portainer.VolumeBackupDestination) (storage, error) {
+	if destination.Type != portainer.VolumeBackupDestinationS3 {
+		return &localStorage{path: service.backupPath}, nil
+	}
+
+	secretKey, err := crypto.DecryptAES(destination.S3.SecretAccessKey, service.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return newS3Storage(destination.S3, secretKey), nil
+}
+
+func (service *Service) notifyFailure(schedule *portainer.VolumeBackupSchedule, backupErr error) {
+	log.Printf("[ERROR] [internal,volumebackup] [schedule: %d] [volume: %s] [message: volume backup failed] [error: %s]", schedule.ID, schedule.VolumeName, backupErr)
+
+	if len(schedule.NotificationRecipients) == 0 {
+		return
+	}
+
+	go func() {
+		err := service.mailer.Send(&mailer.Message{
+			To:      schedule.NotificationRecipients,
+			Subject: fmt.Sprintf("Backup of volume %s failed", schedule.VolumeName),
+			Body:    fmt.Sprintf("The scheduled backup of volume %s failed: %s\nThe previous backups of the volume are left untouched.\n", schedule.VolumeName, backupErr),
+		})
+		if err != nil {
+			log.Printf("[ERROR] [internal,volumebackup] [schedule: %d] [message: unable to send the backup failure notification] [error: %s]", schedule.ID, err)
+		}
+	}()
+}
+
+func (service *Service) acquire(scheduleID portainer.VolumeBackupScheduleID) bool {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.running[scheduleID] {
+		return false
+	}
+	service.running[scheduleID] = true
+	return true
+}
+
+func (service *Service) release(scheduleID portainer.VolumeBackupScheduleID) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	delete(service.running, scheduleID)
+}
+
+func createHelperContainer(dockerClient *client.Client, schedule *portainer.VolumeBackupSchedule, cmd []string, readOnly bool) (string, error) {
+	config := &container.Config{
+		Image:  HelperImage,
+		Cmd:    cmd,
+		Labels: map[string]string{HelperLabel: strconv.Itoa(int(schedule.ID))},
+	}
+
+	hostConfig := &container.HostConfig{
+		Mounts: []mount.Mount{
+			{Type: mount.TypeVolume, Source: schedule.VolumeName, Target: volumeMountPath, ReadOnly: readOnly},
+		},
+	}
+
+	_, _, err := dockerClient.VolumeInspectWithRaw(context.Background(), schedule.VolumeName)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := dockerClient.ContainerCreate(context.Background(), config, hostConfig, nil, "")
+	if client.IsErrNotFound(err) {
+		err = pullImage(dockerClient, HelperImage)
+		if err != nil {
+			return "", err
+		}
+
+		body, err = dockerClient.ContainerCreate(context.Background(), config, hostConfig, nil, "")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return body.ID, nil
+}
+
+func removeHelperContainer(dockerClient *client.Client, containerID string) {
+	err := dockerClient.ContainerRemove(context.Background(), containerID, dockertypes.ContainerRemoveOptions{Force: true})
+	if err != nil && !client.IsErrNotFound(err) {
+		log.Printf("[WARN] [internal,volumebackup] [container: %s] [message: unable to remove the helper container] [error: %s]", containerID, err)
+	}
+}
+
+func pullImage(dockerClient *client.Client, image string) error {
+	reader, err := dockerClient.ImagePull(context.Background(), image, dockertypes.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(ioutil.Discard, reader)
+	return err
+}
+
+func volumeInUse(dockerClient *client.Client, volumeName string) (bool, error) {
+	containers, err := dockerClient.ContainerList(context.Background(), dockertypes.ContainerListOptions{
+		Filters: filters.NewArgs(filters.Arg("volume", volumeName), filters.Arg("status", "running")),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(containers) > 0, nil
+}
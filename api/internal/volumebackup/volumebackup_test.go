@@ -0,0 +1,68 @@
+package volumebackup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runRestoreScript runs the restore script on a volume folder with a backup extracted in a staging folder,
+// it returns the exit code of the script
+func runRestoreScript(t *testing.T, volumePath, stagingPath string) int {
+	err := exec.Command("sh", "-c", fmt.Sprintf(restoreScript, volumePath, stagingPath)).Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode()
+	}
+	assert.NoError(t, err)
+	return 0
+}
+
+func Test_restoreScript_shouldReplaceTheContentOfTheVolume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "volumebackup")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	volumePath := filepath.Join(dir, "volume")
+	stagingPath := filepath.Join(dir, "staging")
+	backupPath := filepath.Join(stagingPath, volumePath)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(volumePath, "current"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(volumePath, ".current"), []byte("current"), 0600))
+	assert.NoError(t, os.MkdirAll(filepath.Join(backupPath, "restored dir"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(backupPath, "restored dir", "file"), []byte("restored"), 0600))
+
+	assert.Equal(t, 0, runRestoreScript(t, volumePath, stagingPath))
+
+	entries, err := ioutil.ReadDir(volumePath)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "restored dir", entries[0].Name())
+	}
+	content, err := ioutil.ReadFile(filepath.Join(volumePath, "restored dir", "file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "restored", string(content))
+}
+
+func Test_restoreScript_shouldNotModifyTheVolumeWhenTheBackupCannotBeCopied(t *testing.T) {
+	dir, err := ioutil.TempDir("", "volumebackup")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	volumePath := filepath.Join(dir, "volume")
+	assert.NoError(t, os.MkdirAll(volumePath, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(volumePath, "current"), []byte("current"), 0600))
+
+	assert.Equal(t, 1, runRestoreScript(t, volumePath, filepath.Join(dir, "missing")))
+	assert.Error(t, restoreError(1))
+
+	entries, err := ioutil.ReadDir(volumePath)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "current", entries[0].Name())
+	}
+}
@@ -17,12 +17,34 @@ type (
 	namespaceAccessPolicies map[string]accessPolicies
 )
 
-func (kcl *KubeClient) setupNamespaceAccesses(userID int, teamIDs []int, serviceAccountName string) error {
+// HasNamespaceAccess returns whether the user or one of its teams has access to the namespace.
+// Every user has access to the default namespace.
+func (kcl *KubeClient) HasNamespaceAccess(userID int, teamIDs []int, namespace string) (bool, error) {
+	if namespace == defaultNamespace {
+		return true, nil
+	}
+
+	accessPolicies, found, err := kcl.namespaceAccessPolicies()
+	if err != nil || !found {
+		return false, err
+	}
+
+	policies, ok := accessPolicies[namespace]
+	if !ok {
+		return false, nil
+	}
+
+	return hasUserAccessToNamespace(userID, teamIDs, policies), nil
+}
+
+// namespaceAccessPolicies returns the access policies stored in the Portainer config map
+// and whether the config map exists
+func (kcl *KubeClient) namespaceAccessPolicies() (namespaceAccessPolicies, bool, error) {
 	configMap, err := kcl.cli.CoreV1().ConfigMaps(portainerNamespace).Get(portainerConfigMapName, metav1.GetOptions{})
 	if k8serrors.IsNotFound(err) {
-		return nil
+		return nil, false, nil
 	} else if err != nil {
-		return err
+		return nil, false, err
 	}
 
 	accessData := configMap.Data[portainerConfigMapAccessPoliciesKey]
@@ -30,6 +52,15 @@ func (kcl *KubeClient) setupNamespaceAccesses(userID int, teamIDs []int, service
 	var accessPolicies namespaceAccessPolicies
 	err = json.Unmarshal([]byte(accessData), &accessPolicies)
 	if err != nil {
+		return nil, false, err
+	}
+
+	return accessPolicies, true, nil
+}
+
+func (kcl *KubeClient) setupNamespaceAccesses(userID int, teamIDs []int, serviceAccountName string) error {
+	accessPolicies, found, err := kcl.namespaceAccessPolicies()
+	if err != nil || !found {
 		return err
 	}
 
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"sort"
+
+	portainer "github.com/portainer/portainer/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetConfigMaps returns the config maps of the namespace, sorted by name
+func (kcl *KubeClient) GetConfigMaps(namespace string) ([]portainer.KubernetesConfigMap, error) {
+	configMaps, err := kcl.cli.CoreV1().ConfigMaps(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]portainer.KubernetesConfigMap, 0, len(configMaps.Items))
+	for idx := range configMaps.Items {
+		result = append(result, *parseConfigMap(&configMaps.Items[idx]))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// GetConfigMap returns a config map of the namespace
+func (kcl *KubeClient) GetConfigMap(namespace, name string) (*portainer.KubernetesConfigMap, error) {
+	configMap, err := kcl.cli.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseConfigMap(configMap), nil
+}
+
+// CreateConfigMap creates a config map inside the namespace of the config map
+func (kcl *KubeClient) CreateConfigMap(configMap *portainer.KubernetesConfigMap) error {
+	_, err := kcl.cli.CoreV1().ConfigMaps(configMap.Namespace).Create(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   configMap.Name,
+			Labels: configMap.Labels,
+		},
+		Data: configMap.Data,
+	})
+	return err
+}
+
+// UpdateConfigMap replaces the data and the labels of an existing config map
+func (kcl *KubeClient) UpdateConfigMap(configMap *portainer.KubernetesConfigMap) error {
+	configMaps := kcl.cli.CoreV1().ConfigMaps(configMap.Namespace)
+
+	existing, err := configMaps.Get(configMap.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	existing.Labels = configMap.Labels
+	existing.Data = configMap.Data
+
+	_, err = configMaps.Update(existing)
+	return err
+}
+
+// DeleteConfigMap removes a config map of the namespace
+func (kcl *KubeClient) DeleteConfigMap(namespace, name string) error {
+	return kcl.cli.CoreV1().ConfigMaps(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+func parseConfigMap(configMap *v1.ConfigMap) *portainer.KubernetesConfigMap {
+	return &portainer.KubernetesConfigMap{
+		Name:         configMap.Name,
+		Namespace:    configMap.Namespace,
+		Labels:       configMap.Labels,
+		Data:         configMap.Data,
+		CreationDate: configMap.CreationTimestamp.Unix(),
+	}
+}
@@ -11,6 +11,9 @@ const (
 	portainerRBPrefix                   = "portainer-rb"
 	portainerConfigMapName              = "portainer-config"
 	portainerConfigMapAccessPoliciesKey = "NamespaceAccessPolicies"
+	portainerResourceQuotaName          = "portainer-rq"
+	portainerLimitRangeName             = "portainer-lr"
+	portainerResourceTemplateAnnotation = "io.portainer.kubernetes.resourcetemplate"
 )
 
 func userServiceAccountName(userID int, instanceID string) string {
@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"io"
+
+	portainer "github.com/portainer/portainer/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetPodContainers returns the names of the containers of a pod, the init containers excluded
+func (kcl *KubeClient) GetPodContainers(namespace, podName string) ([]string, error) {
+	pod, err := kcl.cli.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]string, 0, len(pod.Spec.Containers))
+	for _, container := range pod.Spec.Containers {
+		containers = append(containers, container.Name)
+	}
+
+	return containers, nil
+}
+
+// maxLogLineSize is the size from which a log line is split in several lines
+const maxLogLineSize = 1024 * 1024
+
+// StreamPodLogs calls onLine for each log line of a container of a pod, until the end of the logs or,
+// when following the logs, until the context is cancelled
+func (kcl *KubeClient) StreamPodLogs(ctx context.Context, namespace, podName, containerName string, options *portainer.KubernetesPodLogOptions, onLine func(line string)) error {
+	logOptions := &v1.PodLogOptions{
+		Container:  containerName,
+		Follow:     options.Follow,
+		Previous:   options.Previous,
+		Timestamps: options.Timestamps,
+	}
+
+	if options.TailLines > 0 {
+		logOptions.TailLines = &options.TailLines
+	}
+
+	if options.SinceSeconds > 0 {
+		logOptions.SinceSeconds = &options.SinceSeconds
+	}
+
+	if options.LimitBytes > 0 {
+		logOptions.LimitBytes = &options.LimitBytes
+	}
+
+	stream, err := kcl.cli.CoreV1().Pods(namespace).GetLogs(podName, logOptions).Context(ctx).Stream()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	err = readLogLines(stream, maxLogLineSize, onLine)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// readLogLines calls onLine for each line of the reader, the lines longer than maxLineSize are split
+// in several lines of at most maxLineSize bytes
+func readLogLines(reader io.Reader, maxLineSize int, onLine func(line string)) error {
+	bufferedReader := bufio.NewReaderSize(reader, maxLineSize)
+	for {
+		line, _, err := bufferedReader.ReadLine()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		onLine(string(line))
+	}
+}
@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_readLogLines_shouldSplitTheLinesLongerThanTheMaximumSize(t *testing.T) {
+	logs := "first\r\n" + strings.Repeat("a", 40) + "\nlast"
+
+	lines := make([]string, 0)
+	err := readLogLines(strings.NewReader(logs), 16, func(line string) {
+		lines = append(lines, line)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", strings.Repeat("a", 16), strings.Repeat("a", 16), strings.Repeat("a", 8), "last"}, lines)
+}
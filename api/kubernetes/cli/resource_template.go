@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"fmt"
+
+	portainer "github.com/portainer/portainer/api"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValidateResourceTemplate verifies that the quantities and the limit types of a resource template are valid.
+func ValidateResourceTemplate(template *portainer.KubernetesResourceTemplate) error {
+	_, err := resourceQuotaSpec(template)
+	if err != nil {
+		return err
+	}
+
+	_, err = limitRangeSpec(template)
+	return err
+}
+
+// ApplyResourceTemplate creates or updates the resource quota and the limit range managed by Portainer
+// inside the namespace so that they match the template, and records the template name on the namespace.
+// Objects that are not defined in the template are removed.
+func (kcl *KubeClient) ApplyResourceTemplate(namespace string, template *portainer.KubernetesResourceTemplate) error {
+	quotaSpec, err := resourceQuotaSpec(template)
+	if err != nil {
+		return err
+	}
+
+	limitRangeSpec, err := limitRangeSpec(template)
+	if err != nil {
+		return err
+	}
+
+	err = kcl.applyResourceQuota(namespace, template.Name, quotaSpec)
+	if err != nil {
+		return err
+	}
+
+	err = kcl.applyLimitRange(namespace, template.Name, limitRangeSpec)
+	if err != nil {
+		return err
+	}
+
+	ns, err := kcl.cli.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if ns.Annotations == nil {
+		ns.Annotations = make(map[string]string)
+	}
+	ns.Annotations[portainerResourceTemplateAnnotation] = template.Name
+
+	_, err = kcl.cli.CoreV1().Namespaces().Update(ns)
+	return err
+}
+
+func (kcl *KubeClient) applyResourceQuota(namespace, templateName string, spec *v1.ResourceQuotaSpec) error {
+	quotas := kcl.cli.CoreV1().ResourceQuotas(namespace)
+
+	existing, err := quotas.Get(portainerResourceQuotaName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if spec == nil {
+		if exists {
+			return quotas.Delete(portainerResourceQuotaName, &metav1.DeleteOptions{})
+		}
+		return nil
+	}
+
+	if exists {
+		existing.Spec = *spec
+		setTemplateAnnotation(&existing.ObjectMeta, templateName)
+		_, err = quotas.Update(existing)
+		return err
+	}
+
+	quota := &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: portainerResourceQuotaName},
+		Spec:       *spec,
+	}
+	setTemplateAnnotation(&quota.ObjectMeta, templateName)
+
+	_, err = quotas.Create(quota)
+	return err
+}
+
+func (kcl *KubeClient) applyLimitRange(namespace, templateName string, spec *v1.LimitRangeSpec) error {
+	limitRanges := kcl.cli.CoreV1().LimitRanges(namespace)
+
+	existing, err := limitRanges.Get(portainerLimitRangeName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if spec == nil {
+		if exists {
+			return limitRanges.Delete(portainerLimitRangeName, &metav1.DeleteOptions{})
+		}
+		return nil
+	}
+
+	if exists {
+		existing.Spec = *spec
+		setTemplateAnnotation(&existing.ObjectMeta, templateName)
+		_, err = limitRanges.Update(existing)
+		return err
+	}
+
+	limitRange := &v1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: portainerLimitRangeName},
+		Spec:       *spec,
+	}
+	setTemplateAnnotation(&limitRange.ObjectMeta, templateName)
+
+	_, err = limitRanges.Create(limitRange)
+	return err
+}
+
+func setTemplateAnnotation(meta *metav1.ObjectMeta, templateName string) {
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[portainerResourceTemplateAnnotation] = templateName
+}
+
+// resourceQuotaSpec returns the resource quota defined in the template, nil when the template does not define one.
+func resourceQuotaSpec(template *portainer.KubernetesResourceTemplate) (*v1.ResourceQuotaSpec, error) {
+	if len(template.ResourceQuota) == 0 {
+		return nil, nil
+	}
+
+	hard, err := resourceList(template.ResourceQuota, "resource quota")
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ResourceQuotaSpec{Hard: hard}, nil
+}
+
+// limitRangeSpec returns the limit range defined in the template, nil when the template does not define one.
+func limitRangeSpec(template *portainer.KubernetesResourceTemplate) (*v1.LimitRangeSpec, error) {
+	if len(template.LimitRange) == 0 {
+		return nil, nil
+	}
+
+	spec := &v1.LimitRangeSpec{
+		Limits: make([]v1.LimitRangeItem, 0, len(template.LimitRange)),
+	}
+
+	for _, item := range template.LimitRange {
+		limitType := v1.LimitType(item.Type)
+		switch limitType {
+		case v1.LimitTypeContainer, v1.LimitTypePod, v1.LimitTypePersistentVolumeClaim:
+		default:
+			return nil, fmt.Errorf("Invalid limit range type %q. Valid values are: Container, Pod or PersistentVolumeClaim", item.Type)
+		}
+
+		limit := v1.LimitRangeItem{Type: limitType}
+
+		fields := []struct {
+			name   string
+			values map[string]string
+			target *v1.ResourceList
+		}{
+			{"max", item.Max, &limit.Max},
+			{"min", item.Min, &limit.Min},
+			{"default", item.Default, &limit.Default},
+			{"default request", item.DefaultRequest, &limit.DefaultRequest},
+			{"max limit/request ratio", item.MaxLimitRequestRatio, &limit.MaxLimitRequestRatio},
+		}
+
+		for _, field := range fields {
+			if len(field.values) == 0 {
+				continue
+			}
+
+			list, err := resourceList(field.values, fmt.Sprintf("%s %s limit", item.Type, field.name))
+			if err != nil {
+				return nil, err
+			}
+			*field.target = list
+		}
+
+		spec.Limits = append(spec.Limits, limit)
+	}
+
+	return spec, nil
+}
+
+func resourceList(values map[string]string, description string) (v1.ResourceList, error) {
+	list := make(v1.ResourceList, len(values))
+
+	for name, value := range values {
+		if name == "" {
+			return nil, fmt.Errorf("Invalid %s: empty resource name", description)
+		}
+
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s for %s: %q is not a valid quantity", description, name, value)
+		}
+
+		list[v1.ResourceName(name)] = quantity
+	}
+
+	return list, nil
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_ValidateResourceTemplate(t *testing.T) {
+	template := &portainer.KubernetesResourceTemplate{
+		Name:          "small",
+		ResourceQuota: map[string]string{"requests.cpu": "2", "requests.memory": "4Gi"},
+		LimitRange:    []portainer.KubernetesLimitRangeItem{{Type: "Container", Default: map[string]string{"cpu": "500m"}}},
+	}
+	assert.NoError(t, ValidateResourceTemplate(template))
+
+	template.ResourceQuota["requests.memory"] = "4 gigabytes"
+	assert.Error(t, ValidateResourceTemplate(template))
+
+	template.ResourceQuota["requests.memory"] = "4Gi"
+	template.LimitRange[0].Type = "Namespace"
+	assert.Error(t, ValidateResourceTemplate(template))
+
+	template.LimitRange[0].Type = "Container"
+	template.LimitRange[0].Max = map[string]string{"": "1"}
+	assert.Error(t, ValidateResourceTemplate(template))
+}
+
+func Test_ApplyResourceTemplate_shouldCreateTheQuotaAndTheLimitRange(t *testing.T) {
+	kcl := &KubeClient{cli: fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}})}
+
+	template := &portainer.KubernetesResourceTemplate{
+		Name:          "small",
+		ResourceQuota: map[string]string{"requests.cpu": "2"},
+		LimitRange:    []portainer.KubernetesLimitRangeItem{{Type: "Container", Default: map[string]string{"memory": "256Mi"}}},
+	}
+	assert.NoError(t, kcl.ApplyResourceTemplate("team-a", template))
+
+	quota, err := kcl.cli.CoreV1().ResourceQuotas("team-a").Get(portainerResourceQuotaName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, resource.MustParse("2"), quota.Spec.Hard[v1.ResourceName("requests.cpu")])
+	assert.Equal(t, "small", quota.Annotations[portainerResourceTemplateAnnotation])
+
+	limitRange, err := kcl.cli.CoreV1().LimitRanges("team-a").Get(portainerLimitRangeName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, v1.LimitTypeContainer, limitRange.Spec.Limits[0].Type)
+	assert.Equal(t, resource.MustParse("256Mi"), limitRange.Spec.Limits[0].Default[v1.ResourceMemory])
+
+	namespace, err := kcl.cli.CoreV1().Namespaces().Get("team-a", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "small", namespace.Annotations[portainerResourceTemplateAnnotation])
+}
+
+func Test_ApplyResourceTemplate_shouldReplaceThePreviousTemplate(t *testing.T) {
+	kcl := &KubeClient{cli: fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Annotations: map[string]string{portainerResourceTemplateAnnotation: "large"}}},
+		&v1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: portainerResourceQuotaName, Namespace: "team-a"},
+			Spec:       v1.ResourceQuotaSpec{Hard: v1.ResourceList{v1.ResourceName("requests.cpu"): resource.MustParse("8")}},
+		},
+		&v1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: portainerLimitRangeName, Namespace: "team-a"}},
+	)}
+
+	template := &portainer.KubernetesResourceTemplate{Name: "small", ResourceQuota: map[string]string{"requests.cpu": "2"}}
+	assert.NoError(t, kcl.ApplyResourceTemplate("team-a", template))
+
+	quota, err := kcl.cli.CoreV1().ResourceQuotas("team-a").Get(portainerResourceQuotaName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, resource.MustParse("2"), quota.Spec.Hard[v1.ResourceName("requests.cpu")])
+	assert.Equal(t, "small", quota.Annotations[portainerResourceTemplateAnnotation])
+
+	_, err = kcl.cli.CoreV1().LimitRanges("team-a").Get(portainerLimitRangeName, metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the limit range is removed when not defined in the template")
+
+	namespace, err := kcl.cli.CoreV1().Namespaces().Get("team-a", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "small", namespace.Annotations[portainerResourceTemplateAnnotation])
+}
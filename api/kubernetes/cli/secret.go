@@ -1,9 +1,14 @@
 package cli
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
+	portainer "github.com/portainer/portainer/api"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"k8s.io/api/core/v1"
@@ -72,3 +77,201 @@ func (kcl *KubeClient) getServiceAccountToken(serviceAccountName string) (string
 
 	return "", errors.New("unable to find secret token associated to user service account")
 }
+
+// ValidateSecretData verifies that the secret type is supported and that the data holds the keys required by the type
+func ValidateSecretData(secretType portainer.KubernetesSecretType, data map[string]string) error {
+	switch secretType {
+	case portainer.KubernetesSecretOpaque:
+	case portainer.KubernetesSecretDockerConfigJSON:
+		var dockerConfig map[string]interface{}
+		err := json.Unmarshal([]byte(data[v1.DockerConfigJsonKey]), &dockerConfig)
+		if err != nil {
+			return fmt.Errorf("Invalid secret data. The %s key must hold a valid Docker configuration", v1.DockerConfigJsonKey)
+		}
+	case portainer.KubernetesSecretTLS:
+		_, err := tls.X509KeyPair([]byte(data[v1.TLSCertKey]), []byte(data[v1.TLSPrivateKeyKey]))
+		if err != nil {
+			return fmt.Errorf("Invalid secret data. The %s and %s keys must hold a valid PEM encoded certificate and key", v1.TLSCertKey, v1.TLSPrivateKeyKey)
+		}
+	default:
+		return fmt.Errorf("Invalid secret type %q. Valid values are: %s, %s or %s", secretType, portainer.KubernetesSecretOpaque, portainer.KubernetesSecretDockerConfigJSON, portainer.KubernetesSecretTLS)
+	}
+
+	for key := range data {
+		if key == "" {
+			return errors.New("Invalid secret data. Keys cannot be empty")
+		}
+	}
+
+	return nil
+}
+
+// GetSecrets returns the metadata of the secrets of the namespace, sorted by name.
+// The service account tokens are not returned.
+func (kcl *KubeClient) GetSecrets(namespace string) ([]portainer.KubernetesSecret, error) {
+	secrets, err := kcl.cli.CoreV1().Secrets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]portainer.KubernetesSecret, 0, len(secrets.Items))
+	for idx := range secrets.Items {
+		if secrets.Items[idx].Type == v1.SecretTypeServiceAccountToken {
+			continue
+		}
+		result = append(result, *parseSecret(&secrets.Items[idx]))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// GetSecret returns the metadata of a secret of the namespace. The service account tokens are reported as not found.
+func (kcl *KubeClient) GetSecret(namespace, name string) (*portainer.KubernetesSecret, error) {
+	secret, err := kcl.getSecret(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSecret(secret), nil
+}
+
+// getSecret retrieves a secret of the namespace, a service account token is reported as not found
+// as it is managed by Kubernetes and holds the credentials of a service account
+func (kcl *KubeClient) getSecret(namespace, name string) (*v1.Secret, error) {
+	secret, err := kcl.cli.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if secret.Type == v1.SecretTypeServiceAccountToken {
+		return nil, k8serrors.NewNotFound(v1.Resource("secrets"), name)
+	}
+
+	return secret, nil
+}
+
+// CreateSecret creates a secret holding the data inside the namespace of the secret
+func (kcl *KubeClient) CreateSecret(secret *portainer.KubernetesSecret, data map[string]string) error {
+	_, err := kcl.cli.CoreV1().Secrets(secret.Namespace).Create(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   secret.Name,
+			Labels: secret.Labels,
+		},
+		Type:       v1.SecretType(secret.Type),
+		StringData: data,
+	})
+	return err
+}
+
+// UpdateSecret replaces the labels of an existing secret, and its data when data is not nil.
+// The type of a secret cannot be updated, the service account tokens cannot be updated.
+func (kcl *KubeClient) UpdateSecret(secret *portainer.KubernetesSecret, data map[string]string) error {
+	existing, err := kcl.getSecret(secret.Namespace, secret.Name)
+	if err != nil {
+		return err
+	}
+
+	existing.Labels = secret.Labels
+	if data != nil {
+		existing.Data = nil
+		existing.StringData = data
+	}
+
+	_, err = kcl.cli.CoreV1().Secrets(secret.Namespace).Update(existing)
+	return err
+}
+
+// DeleteSecret removes a secret of the namespace, the service account tokens cannot be removed
+func (kcl *KubeClient) DeleteSecret(namespace, name string) error {
+	_, err := kcl.getSecret(namespace, name)
+	if err != nil {
+		return err
+	}
+
+	return kcl.cli.CoreV1().Secrets(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+// GetSecretConsumers returns the names of the pods of the namespace that are not terminated and reference the secret
+// through a volume, an environment variable or an image pull secret
+func (kcl *KubeClient) GetSecretConsumers(namespace, name string) ([]string, error) {
+	pods, err := kcl.cli.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	consumers := make([]string, 0)
+	for idx := range pods.Items {
+		pod := &pods.Items[idx]
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+
+		if podReferencesSecret(&pod.Spec, name) {
+			consumers = append(consumers, pod.Name)
+		}
+	}
+
+	sort.Strings(consumers)
+
+	return consumers, nil
+}
+
+func podReferencesSecret(spec *v1.PodSpec, name string) bool {
+	for _, pullSecret := range spec.ImagePullSecrets {
+		if pullSecret.Name == name {
+			return true
+		}
+	}
+
+	for _, volume := range spec.Volumes {
+		if volume.Secret != nil && volume.Secret.SecretName == name {
+			return true
+		}
+
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil && source.Secret.Name == name {
+					return true
+				}
+			}
+		}
+	}
+
+	containers := append(append([]v1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil && envFrom.SecretRef.Name == name {
+				return true
+			}
+		}
+
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func parseSecret(secret *v1.Secret) *portainer.KubernetesSecret {
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return &portainer.KubernetesSecret{
+		Name:         secret.Name,
+		Namespace:    secret.Namespace,
+		Type:         portainer.KubernetesSecretType(secret.Type),
+		Labels:       secret.Labels,
+		Keys:         keys,
+		CreationDate: secret.CreationTimestamp.Unix(),
+	}
+}
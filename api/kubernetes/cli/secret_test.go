@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"testing"
+
+	portainer "github.com/portainer/portainer/api"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newSecretsKubeClient() *KubeClient {
+	return &KubeClient{
+		cli: fake.NewSimpleClientset(
+			&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "default-token", Namespace: "default"}, Type: v1.SecretTypeServiceAccountToken},
+			&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}, Type: v1.SecretTypeOpaque, Data: map[string][]byte{"password": []byte("secret")}},
+		),
+	}
+}
+
+func Test_GetSecrets_shouldNotReturnTheServiceAccountTokens(t *testing.T) {
+	kcl := newSecretsKubeClient()
+
+	secrets, err := kcl.GetSecrets("default")
+	assert.NoError(t, err)
+	assert.Len(t, secrets, 1)
+	assert.Equal(t, "app", secrets[0].Name)
+	assert.Equal(t, []string{"password"}, secrets[0].Keys)
+}
+
+func Test_GetSecret_shouldReportTheServiceAccountTokensAsNotFound(t *testing.T) {
+	kcl := newSecretsKubeClient()
+
+	_, err := kcl.GetSecret("default", "default-token")
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	secret, err := kcl.GetSecret("default", "app")
+	assert.NoError(t, err)
+	assert.Equal(t, portainer.KubernetesSecretOpaque, secret.Type)
+}
+
+func Test_UpdateSecret_shouldRejectTheServiceAccountTokens(t *testing.T) {
+	kcl := newSecretsKubeClient()
+
+	err := kcl.UpdateSecret(&portainer.KubernetesSecret{Name: "default-token", Namespace: "default", Labels: map[string]string{"app": "web"}}, nil)
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	token, err := kcl.cli.CoreV1().Secrets("default").Get("default-token", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, token.Labels)
+
+	err = kcl.UpdateSecret(&portainer.KubernetesSecret{Name: "app", Namespace: "default", Labels: map[string]string{"app": "web"}}, nil)
+	assert.NoError(t, err)
+}
+
+func Test_DeleteSecret_shouldRejectTheServiceAccountTokens(t *testing.T) {
+	kcl := newSecretsKubeClient()
+
+	err := kcl.DeleteSecret("default", "default-token")
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	_, err = kcl.cli.CoreV1().Secrets("default").Get("default-token", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	err = kcl.DeleteSecret("default", "app")
+	assert.NoError(t, err)
+}
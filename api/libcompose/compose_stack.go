@@ -14,6 +14,7 @@ import (
 	"github.com/portainer/libcompose/project"
 	"github.com/portainer/libcompose/project/options"
 	portainer "github.com/portainer/portainer/api"
+	"github.com/portainer/portainer/api/internal/stackutils"
 )
 
 const (
@@ -25,13 +26,15 @@ const (
 type ComposeStackManager struct {
 	dataPath             string
 	reverseTunnelService portainer.ReverseTunnelService
+	dataStore            portainer.DataStore
 }
 
 // NewComposeStackManager initializes a new ComposeStackManager service.
-func NewComposeStackManager(dataPath string, reverseTunnelService portainer.ReverseTunnelService) *ComposeStackManager {
+func NewComposeStackManager(dataPath string, reverseTunnelService portainer.ReverseTunnelService, dataStore portainer.DataStore) *ComposeStackManager {
 	return &ComposeStackManager{
 		dataPath:             dataPath,
 		reverseTunnelService: reverseTunnelService,
+		dataStore:            dataStore,
 	}
 }
 
@@ -64,8 +67,9 @@ func (manager *ComposeStackManager) ComposeSyntaxMaxVersion() string {
 	return composeSyntaxMaxVersion
 }
 
-// Up will deploy a compose stack (equivalent of docker-compose up)
-func (manager *ComposeStackManager) Up(stack *portainer.Stack, endpoint *portainer.Endpoint) error {
+// Up will deploy a compose stack (equivalent of docker-compose up).
+// When services are specified, only these services and their dependencies are deployed
+func (manager *ComposeStackManager) Up(stack *portainer.Stack, endpoint *portainer.Endpoint, services ...string) error {
 
 	clientFactory, err := manager.createClient(endpoint)
 	if err != nil {
@@ -78,10 +82,25 @@ func (manager *ComposeStackManager) Up(stack *portainer.Stack, endpoint *portain
 	}
 
 	composeFilePath := path.Join(stack.ProjectPath, stack.EntryPoint)
+	composeFiles := []string{composeFilePath}
+
+	containerDefaults, err := stackutils.EndpointContainerDefaults(manager.dataStore, endpoint)
+	if err != nil {
+		return err
+	}
+
+	overrideFilePaths, err := stackutils.CreateDeploymentOverrides(stack, endpoint, containerDefaults)
+	if err != nil {
+		return err
+	}
+	defer stackutils.RemoveOverrideFiles(overrideFilePaths)
+
+	composeFiles = append(composeFiles, overrideFilePaths...)
+
 	proj, err := docker.NewProject(&ctx.Context{
 		ConfigDir: manager.dataPath,
 		Context: project.Context{
-			ComposeFiles: []string{composeFilePath},
+			ComposeFiles: composeFiles,
 			EnvironmentLookup: &lookup.ComposableEnvLookup{
 				Lookups: []config.EnvironmentLookup{
 					&lookup.EnvfileLookup{
@@ -100,7 +119,7 @@ func (manager *ComposeStackManager) Up(stack *portainer.Stack, endpoint *portain
 		return err
 	}
 
-	return proj.Up(context.Background(), options.Up{})
+	return proj.Up(context.Background(), options.Up{}, services...)
 }
 
 // Down will shutdown a compose stack (equivalent of docker-compose down)
@@ -0,0 +1,41 @@
+package portainer
+
+// SettingsDefault returns the settings stored in the database on the first start of the instance
+func SettingsDefault() *Settings {
+	return &Settings{
+		AuthenticationMethod: AuthenticationInternal,
+		BlackListedLabels:    make([]Pair, 0),
+		LDAPSettings: LDAPSettings{
+			AnonymousMode:   true,
+			AutoCreateUsers: true,
+			TLSConfig:       TLSConfiguration{},
+			SearchSettings: []LDAPSearchSettings{
+				LDAPSearchSettings{},
+			},
+			GroupSearchSettings: []LDAPGroupSearchSettings{
+				LDAPGroupSearchSettings{},
+			},
+		},
+		OAuthSettings: OAuthSettings{},
+
+		EdgeAgentCheckinInterval: DefaultEdgeAgentCheckinIntervalInSeconds,
+		TemplatesURL:             DefaultTemplatesURL,
+		UserSessionTimeout:       DefaultUserSessionTimeout,
+	}
+}
+
+// ApplyFlagsToSettings updates the settings overwritten by the CLI flags on each start of the instance
+func ApplyFlagsToSettings(settings *Settings, flags *CLIFlags) {
+	settings.LogoURL = *flags.Logo
+	settings.SnapshotInterval = *flags.SnapshotInterval
+	settings.EnableEdgeComputeFeatures = *flags.EnableEdgeComputeFeatures
+	settings.EnableTelemetry = true
+
+	if *flags.Templates != "" {
+		settings.TemplatesURL = *flags.Templates
+	}
+
+	if *flags.Labels != nil {
+		settings.BlackListedLabels = *flags.Labels
+	}
+}